@@ -0,0 +1,47 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package api
+
+import "context"
+
+// CancelHandle can be used to cancel network operations. It's passed in to the APIs that support cancellation via
+// their options objects. Once Cancel is called, any in-progress HTTP requests and DID resolutions made by those APIs
+// are aborted, and any later calls that need the network will fail straight away. A CancelHandle can't be reused
+// after it's been cancelled, so a new one should be created for each flow that may need to be cancelled.
+type CancelHandle struct {
+	ctx    context.Context //nolint:containedctx // The handle exists to carry a cancellable context across the API.
+	cancel context.CancelFunc
+}
+
+// NewCancelHandle returns a new CancelHandle.
+func NewCancelHandle() *CancelHandle {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &CancelHandle{ctx: ctx, cancel: cancel}
+}
+
+// Cancel cancels any in-progress and future network operations that use this CancelHandle.
+// Calling Cancel more than once has no further effect.
+func (c *CancelHandle) Cancel() {
+	c.cancel()
+}
+
+// IsCancelled indicates whether Cancel has been called.
+func (c *CancelHandle) IsCancelled() bool {
+	return c.ctx.Err() != nil
+}
+
+// Context returns the context that's cancelled when Cancel is called. If c is nil, then a context that's never
+// cancelled is returned.
+// This method is not compatible with gomobile and so will not be available in the generated bindings.
+func (c *CancelHandle) Context() context.Context {
+	if c == nil {
+		return context.Background()
+	}
+
+	return c.ctx
+}
@@ -0,0 +1,36 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package api_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/api"
+)
+
+func TestCancelHandle(t *testing.T) {
+	cancelHandle := api.NewCancelHandle()
+	require.False(t, cancelHandle.IsCancelled())
+	require.NoError(t, cancelHandle.Context().Err())
+
+	cancelHandle.Cancel()
+	require.True(t, cancelHandle.IsCancelled())
+	require.ErrorIs(t, cancelHandle.Context().Err(), context.Canceled)
+
+	// Cancelling again has no further effect.
+	cancelHandle.Cancel()
+	require.True(t, cancelHandle.IsCancelled())
+}
+
+func TestCancelHandle_Context_Nil(t *testing.T) {
+	var cancelHandle *api.CancelHandle
+
+	require.Equal(t, context.Background(), cancelHandle.Context())
+}
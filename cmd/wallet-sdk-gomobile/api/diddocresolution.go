@@ -11,6 +11,7 @@ import (
 
 	diddoc "github.com/hyperledger/aries-framework-go/component/models/did"
 
+	"github.com/trustbloc/wallet-sdk/pkg/common"
 	"github.com/trustbloc/wallet-sdk/pkg/models"
 )
 
@@ -35,6 +36,13 @@ func NewVerificationMethod(keyID, vmType string) *VerificationMethod {
 	return &VerificationMethod{ID: keyID, Type: vmType}
 }
 
+// NewVerificationMethodFromJWK creates a VerificationMethod for a key that isn't associated with a DID, such as a key
+// created using localkms.KMS. It can be used to sign with the key directly, e.g. when binding credentials to the key
+// instead of a DID.
+func NewVerificationMethodFromJWK(key *JSONWebKey) *VerificationMethod {
+	return &VerificationMethod{Type: common.JSONWebKey2020, Key: models.VerificationKey{JSONWebKey: key.JWK}}
+}
+
 // DIDDocResolution represents a DID document resolution object.
 type DIDDocResolution struct {
 	// Content is the full marshalled DID doc resolution object.
@@ -72,3 +72,13 @@ func TestDIDDocResolution_AssertionMethod(t *testing.T) {
 		require.Empty(t, vm)
 	})
 }
+
+func TestNewVerificationMethodFromJWK(t *testing.T) {
+	key, err := api.ParseJSONWebKey(`{"kty":"OKP","crv":"Ed25519","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}`)
+	require.NoError(t, err)
+
+	vm := api.NewVerificationMethodFromJWK(key)
+	require.Empty(t, vm.ID)
+	require.Equal(t, "JsonWebKey2020", vm.Type)
+	require.Equal(t, key.JWK, vm.Key.JSONWebKey)
+}
@@ -0,0 +1,45 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package credential
+
+import (
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/verifiable"
+	"github.com/trustbloc/wallet-sdk/pkg/credentialquery"
+)
+
+// DCQLMatches contains the VCs that matched each credential query of a DCQL query.
+type DCQLMatches struct {
+	wrapped *credentialquery.DCQLMatches
+}
+
+// DCQLCredentialQueryMatch contains the VCs that matched a credential query of a DCQL query.
+type DCQLCredentialQueryMatch struct {
+	ID         string
+	MatchedVCs *verifiable.CredentialsArray
+}
+
+// Length returns the number of credential queries, which is the same as the number of credential queries in the
+// DCQL query.
+func (d *DCQLMatches) Length() int {
+	return len(d.wrapped.CredentialQueries)
+}
+
+// AtIndex returns the matches for the credential query at the given index, in the same order as the DCQL query.
+func (d *DCQLMatches) AtIndex(index int) *DCQLCredentialQueryMatch {
+	credentialQuery := d.wrapped.CredentialQueries[index]
+
+	match := &DCQLCredentialQueryMatch{
+		ID:         credentialQuery.ID,
+		MatchedVCs: verifiable.NewCredentialsArray(),
+	}
+
+	for _, cred := range credentialQuery.MatchedVCs {
+		match.MatchedVCs.Add(verifiable.NewCredential(cred))
+	}
+
+	return match
+}
@@ -94,6 +94,28 @@ func (c *Inquirer) GetSubmissionRequirements(query []byte, credentials *verifiab
 	return &SubmissionRequirementArray{wrapped: requirements}, nil
 }
 
+// GetDCQLMatches returns the VCs that match each credential query of the given DCQL query. The query is the one
+// returned by the openid4vp.Interaction's GetQuery method when its QueryLanguage is "dcql". If the credentials can't
+// satisfy the query, then a NO_CREDENTIAL_SATISFY_REQUIREMENTS error is returned.
+func (c *Inquirer) GetDCQLMatches(query []byte, credentials *verifiable.CredentialsArray) (*DCQLMatches, error) {
+	if credentials == nil {
+		return nil, errors.New("credentials must be provided")
+	}
+
+	dcqlQuery, err := credentialquery.ParseDCQLQuery(query)
+	if err != nil {
+		return nil, wrapper.ToMobileError(err)
+	}
+
+	matches, err := c.goAPICredentialQuery.GetDCQLMatches(dcqlQuery,
+		credentialquery.WithCredentialsArray(unwrapVCs(credentials)))
+	if err != nil {
+		return nil, wrapper.ToMobileError(err)
+	}
+
+	return &DCQLMatches{wrapped: matches}, nil
+}
+
 func unwrapQuery(query []byte) (*presexch.PresentationDefinition, error) {
 	pdQuery := &presexch.PresentationDefinition{}
 
@@ -106,6 +106,10 @@ func TestInstance_GetSubmissionRequirements(t *testing.T) {
 		require.Equal(t, desc1.Name, "Verified Employee")
 		require.Equal(t, desc1.Purpose, "test purpose")
 		require.Equal(t, desc1.MatchedVCs.Length(), 1)
+
+		require.Len(t, requirements.GoAPISubmissionRequirements(), 1)
+		require.Equal(t, desc1.MatchedVCs.AtIndex(0).VC,
+			requirements.GoAPISubmissionRequirements()[0].Descriptors[0].MatchedVCs[0])
 	})
 
 	t.Run("Success nested requirements", func(t *testing.T) {
@@ -206,6 +210,64 @@ func TestInstance_GetSubmissionRequirementsCitizenship(t *testing.T) {
 	})
 }
 
+func TestInquirer_GetDCQLMatches(t *testing.T) {
+	contents := [][]byte{
+		universityDegreeVCJWT,
+		permanentResidentCardVC,
+		driverLicenseVC,
+		verifiedEmployeeVC,
+	}
+
+	opts := credential.NewInquirerOpts().SetDocumentLoader(&documentLoaderReverseWrapper{
+		DocumentLoader: testutil.DocumentLoader(t),
+	})
+
+	inquirer, err := credential.NewInquirer(opts)
+	require.NoError(t, err)
+
+	t.Run("Success", func(t *testing.T) {
+		matches, err := inquirer.GetDCQLMatches([]byte(`{"credentials": [
+			{"id": "license", "format": "jwt_vc_json", "meta": {"type_values": [["DriversLicense"]]}},
+			{"id": "employee", "format": "jwt_vc_json",
+				"claims": [{"path": ["credentialSubject", "givenName"], "values": ["John"]}]}
+		]}`), createCredJSONArray(t, contents))
+		require.NoError(t, err)
+		require.Equal(t, 2, matches.Length())
+
+		license := matches.AtIndex(0)
+		require.Equal(t, "license", license.ID)
+		require.Equal(t, 1, license.MatchedVCs.Length())
+		require.Contains(t, license.MatchedVCs.AtIndex(0).VC.Types, "DriversLicense")
+
+		employee := matches.AtIndex(1)
+		require.Equal(t, "employee", employee.ID)
+		require.Equal(t, 1, employee.MatchedVCs.Length())
+		require.Contains(t, employee.MatchedVCs.AtIndex(0).VC.Types, "VerifiedEmployee")
+	})
+
+	t.Run("Invalid query", func(t *testing.T) {
+		matches, err := inquirer.GetDCQLMatches([]byte(`{"credentials": []}`), createCredJSONArray(t, contents))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "INVALID_DCQL_QUERY")
+		require.Nil(t, matches)
+	})
+
+	t.Run("Query can't be satisfied", func(t *testing.T) {
+		matches, err := inquirer.GetDCQLMatches([]byte(`{"credentials": [
+			{"id": "passport", "format": "jwt_vc_json", "meta": {"type_values": [["Passport"]]}}
+		]}`), createCredJSONArray(t, contents))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "NO_CREDENTIAL_SATISFY_REQUIREMENTS")
+		require.Nil(t, matches)
+	})
+
+	t.Run("Nil credentials", func(t *testing.T) {
+		matches, err := inquirer.GetDCQLMatches(nil, nil)
+		require.EqualError(t, err, "credentials must be provided")
+		require.Nil(t, matches)
+	})
+}
+
 func createCredJSONArray(t *testing.T, creds [][]byte) *verifiable.CredentialsArray {
 	t.Helper()
 
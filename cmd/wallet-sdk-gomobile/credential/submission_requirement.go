@@ -102,3 +102,9 @@ func (s *SubmissionRequirement) NestedRequirementLength() int {
 func (s *SubmissionRequirement) NestedRequirementAtIndex(index int) *SubmissionRequirement {
 	return &SubmissionRequirement{wrapped: s.wrapped.Nested[index]}
 }
+
+// GoAPISubmissionRequirements returns the underlying submission requirements, for passing them back to the Go SDK.
+// This method will not be accessible directly in the bindings (will be "skipped").
+func (s *SubmissionRequirementArray) GoAPISubmissionRequirements() []*presexch.MatchedSubmissionRequirement {
+	return s.wrapped
+}
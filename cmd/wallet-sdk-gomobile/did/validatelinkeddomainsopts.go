@@ -7,11 +7,16 @@ SPDX-License-Identifier: Apache-2.0
 
 package did
 
-import "time"
+import (
+	"time"
+
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/api"
+)
 
 // ValidateLinkedDomainsOpts contains all optional arguments that can be passed into the ValidateLinkedDomains function.
 type ValidateLinkedDomainsOpts struct {
-	httpTimeout *time.Duration
+	httpTimeout  *time.Duration
+	cancelHandle *api.CancelHandle
 }
 
 // NewValidateLinkedDomainsOpts returns a new ValidateLinkedDomainsOpts object.
@@ -27,3 +32,11 @@ func (o *ValidateLinkedDomainsOpts) SetHTTPTimeoutNanoseconds(timeout int64) *Va
 
 	return o
 }
+
+// SetCancelHandle sets a CancelHandle that can be used to abort the DID resolution and the request for the
+// DID configuration.
+func (o *ValidateLinkedDomainsOpts) SetCancelHandle(cancelHandle *api.CancelHandle) *ValidateLinkedDomainsOpts {
+	o.cancelHandle = cancelHandle
+
+	return o
+}
@@ -8,6 +8,7 @@ SPDX-License-Identifier: Apache-2.0
 package did
 
 import (
+	"context"
 	"errors"
 
 	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/api"
@@ -36,10 +37,10 @@ func ValidateLinkedDomains(did string, resolver api.DIDResolver,
 
 	httpClient := wrapper.NewHTTPClient(opts.httpTimeout, api.Headers{}, false)
 
-	return validateLinkedDomains(did, resolver, httpClient)
+	return validateLinkedDomains(opts.cancelHandle.Context(), did, resolver, httpClient)
 }
 
-func validateLinkedDomains(did string, resolver api.DIDResolver,
+func validateLinkedDomains(ctx context.Context, did string, resolver api.DIDResolver,
 	client goapiwellknown.HTTPClient,
 ) (*ValidationResult, error) {
 	if resolver == nil {
@@ -48,7 +49,7 @@ func validateLinkedDomains(did string, resolver api.DIDResolver,
 
 	vdrWrapper := &wrapper.VDRResolverWrapper{DIDResolver: resolver}
 
-	valid, uri, err := goapiwellknown.ValidateLinkedDomains(did, vdrWrapper, client)
+	valid, uri, err := goapiwellknown.ValidateLinkedDomainsContext(ctx, did, vdrWrapper, client)
 	if err != nil {
 		return nil, wrapper.ToMobileError(err)
 	}
@@ -7,6 +7,7 @@ package did //nolint:testpackage // uses internal implementation details so the
 
 import (
 	"bytes"
+	"context"
 	_ "embed"
 	"fmt"
 	"io"
@@ -17,6 +18,8 @@ import (
 	"github.com/hyperledger/aries-framework-go/component/models/did"
 	"github.com/hyperledger/aries-framework-go/component/vdr/httpbinding"
 	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/api"
 )
 
 //go:embed test_data/didconfig.json
@@ -133,7 +136,8 @@ func TestValidate(t *testing.T) {
 		resolver, err := httpbinding.New(testServer.URL)
 		require.NoError(t, err)
 
-		validationResult, err := validateLinkedDomains(testDID, &resolverWrapper{vdr: resolver}, httpClient)
+		validationResult, err := validateLinkedDomains(context.Background(), testDID, &resolverWrapper{vdr: resolver},
+			httpClient)
 		require.NoError(t, err)
 		require.True(t, validationResult.IsValid)
 		require.Equal(t, "https://did.rohitgulati.com", validationResult.ServiceURL)
@@ -157,6 +161,15 @@ func TestValidate(t *testing.T) {
 		requireErrorContains(t, err, "DOMAIN_AND_DID_VERIFICATION_FAILED")
 		require.Nil(t, validationResult)
 	})
+	t.Run("Cancelled", func(t *testing.T) {
+		cancelHandle := api.NewCancelHandle()
+		cancelHandle.Cancel()
+
+		validationResult, err := ValidateLinkedDomains(testDID, newMockResolver("https://example.com"),
+			NewValidateLinkedDomainsOpts().SetCancelHandle(cancelHandle))
+		requireErrorContains(t, err, "context canceled")
+		require.Nil(t, validationResult)
+	})
 }
 
 type resolverWrapper struct {
@@ -19,6 +19,14 @@ type Data struct {
 	resolvedDisplayData *goapicredentialschema.ResolvedDisplayData
 }
 
+// NewData creates a new display Data object.
+// This function is only used internally in wallet-sdk-gomobile and is not available in the bindings due to it using
+// unsupported types.
+// To create a display Data object from a serialized format via the bindings, see the ParseData function.
+func NewData(resolvedDisplayData *goapicredentialschema.ResolvedDisplayData) *Data {
+	return &Data{resolvedDisplayData: resolvedDisplayData}
+}
+
 // ParseData parses the given serialized display data and returns a display Data object.
 func ParseData(displayData string) (*Data, error) {
 	var parsedDisplayData goapicredentialschema.ResolvedDisplayData
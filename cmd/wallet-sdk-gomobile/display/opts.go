@@ -19,6 +19,7 @@ type Opts struct {
 	additionalHeaders                api.Headers
 	httpTimeout                      *time.Duration
 	disableHTTPClientTLSVerification bool
+	cancelHandle                     *api.CancelHandle
 }
 
 // NewOpts returns a new Opts object.
@@ -69,3 +70,10 @@ func (o *Opts) DisableHTTPClientTLSVerify() *Opts {
 
 	return o
 }
+
+// SetCancelHandle sets a CancelHandle that can be used to abort the request for the issuer's metadata.
+func (o *Opts) SetCancelHandle(cancelHandle *api.CancelHandle) *Opts {
+	o.cancelHandle = cancelHandle
+
+	return o
+}
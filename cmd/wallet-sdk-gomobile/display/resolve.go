@@ -23,12 +23,16 @@ import (
 // same order.
 // This method requires one or more VCs and the issuer's base URI.
 func Resolve(vcs *verifiable.CredentialsArray, issuerURI string, opts *Opts) (*Data, error) {
+	if opts == nil {
+		opts = NewOpts()
+	}
+
 	goAPIOpts, err := generateGoAPIOpts(vcs, issuerURI, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	resolvedDisplayData, err := goapicredentialschema.Resolve(goAPIOpts...)
+	resolvedDisplayData, err := goapicredentialschema.ResolveContext(opts.cancelHandle.Context(), goAPIOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -110,6 +110,18 @@ func TestResolve(t *testing.T) {
 		require.EqualError(t, err, "no issuer URI specified")
 		require.Nil(t, resolvedDisplayData)
 	})
+	t.Run("Cancelled", func(t *testing.T) {
+		server := httptest.NewServer(&mockIssuerServerHandler{t: t, issuerMetadata: string(sampleIssuerMetadata)})
+		defer server.Close()
+
+		cancelHandle := api.NewCancelHandle()
+		cancelHandle.Cancel()
+
+		resolvedDisplayData, err := display.Resolve(verifiable.NewCredentialsArray(), server.URL,
+			display.NewOpts().SetCancelHandle(cancelHandle))
+		require.Contains(t, err.Error(), "context canceled")
+		require.Nil(t, resolvedDisplayData)
+	})
 	t.Run("Malformed issuer URI", func(t *testing.T) {
 		opts := display.NewOpts()
 
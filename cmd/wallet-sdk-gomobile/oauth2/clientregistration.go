@@ -30,7 +30,7 @@ func RegisterClient(registrationEndpoint string, clientMetadata *ClientMetadata,
 
 	httpClient := wrapper.NewHTTPClient(opts.httpTimeout, opts.additionalHeaders, opts.disableHTTPClientTLSVerification)
 
-	registerClientResponse, err := goapioauth2.RegisterClient(registrationEndpoint,
+	registerClientResponse, err := goapioauth2.RegisterClientContext(opts.cancelHandle.Context(), registrationEndpoint,
 		clientMetadata.goAPIClientMetadata,
 		goapioauth2.WithInitialAccessBearerToken(opts.initialAccessBearerToken),
 		goapioauth2.WithHTTPClient(httpClient))
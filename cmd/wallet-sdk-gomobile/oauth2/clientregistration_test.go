@@ -135,6 +135,15 @@ func TestRegisterClient(t *testing.T) {
 			require.NoError(t, err)
 			require.NotEmpty(t, response)
 		})
+		t.Run("Cancelled", func(t *testing.T) {
+			cancelHandle := api.NewCancelHandle()
+			cancelHandle.Cancel()
+
+			response, err := oauth2.RegisterClient(server.URL, &oauth2.ClientMetadata{},
+				oauth2.NewRegisterClientOpts().SetCancelHandle(cancelHandle))
+			require.Contains(t, err.Error(), "context canceled")
+			require.Nil(t, response)
+		})
 	})
 	t.Run("Blank registration endpoint", func(t *testing.T) {
 		response, err := oauth2.RegisterClient("", nil, nil)
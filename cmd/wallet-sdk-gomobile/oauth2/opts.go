@@ -19,6 +19,7 @@ type RegisterClientOpts struct {
 	additionalHeaders                api.Headers
 	disableHTTPClientTLSVerification bool
 	httpTimeout                      *time.Duration
+	cancelHandle                     *api.CancelHandle
 }
 
 // NewRegisterClientOpts returns a new RegisterClientOpts object.
@@ -67,3 +68,10 @@ func (o *RegisterClientOpts) DisableHTTPClientTLSVerify() *RegisterClientOpts {
 
 	return o
 }
+
+// SetCancelHandle sets a CancelHandle that can be used to abort the client registration request.
+func (o *RegisterClientOpts) SetCancelHandle(cancelHandle *api.CancelHandle) *RegisterClientOpts {
+	o.cancelHandle = cancelHandle
+
+	return o
+}
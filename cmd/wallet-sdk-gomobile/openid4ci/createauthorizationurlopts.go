@@ -11,7 +11,8 @@ import "github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/api"
 // CreateAuthorizationURLOpts contains all optional arguments that can be passed into the
 // CreateAuthorizationURL method.
 type CreateAuthorizationURLOpts struct {
-	scopes *api.StringArray
+	scopes                        *api.StringArray
+	usePushedAuthorizationRequest bool
 }
 
 // NewCreateAuthorizationURLOpts returns a new CreateAuthorizationURLOpts object.
@@ -26,3 +27,13 @@ func (c *CreateAuthorizationURLOpts) SetScopes(scopes *api.StringArray) *CreateA
 
 	return c
 }
+
+// UsePushedAuthorizationRequest forces the CreateAuthorizationURL method to send the authorization request parameters
+// to the authorization server using a pushed authorization request (RFC 9126). Without this option, a pushed
+// authorization request is still used if the authorization server advertises support for it, but a regular
+// authorization URL is created otherwise. With this option, an error is returned instead.
+func (c *CreateAuthorizationURLOpts) UsePushedAuthorizationRequest() *CreateAuthorizationURLOpts {
+	c.usePushedAuthorizationRequest = true
+
+	return c
+}
@@ -0,0 +1,90 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/otel"
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/walleterror"
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/wrapper"
+	openid4cigoapi "github.com/trustbloc/wallet-sdk/pkg/openid4ci"
+)
+
+// CredentialError describes an offered credential that the issuer didn't issue.
+type CredentialError struct {
+	offerIndex  int
+	walletError *walleterror.Error
+}
+
+// OfferIndex returns the index of the credential in the credential offer.
+func (c *CredentialError) OfferIndex() int {
+	return c.offerIndex
+}
+
+// WalletError returns the reason why the credential wasn't issued.
+func (c *CredentialError) WalletError() *walleterror.Error {
+	return c.walletError
+}
+
+// CredentialErrorsArray represents an array of CredentialErrors.
+// Since arrays and slices are not compatible with gomobile, this type acts as a wrapper around a Go array.
+type CredentialErrorsArray struct {
+	credentialErrors []*CredentialError
+}
+
+// Length returns the number of CredentialErrors contained within this CredentialErrorsArray.
+func (c *CredentialErrorsArray) Length() int {
+	return len(c.credentialErrors)
+}
+
+// AtIndex returns the CredentialError at the given index.
+// If the index passed in is out of bounds, then nil is returned.
+func (c *CredentialErrorsArray) AtIndex(index int) *CredentialError {
+	maxIndex := len(c.credentialErrors) - 1
+	if index > maxIndex || index < 0 {
+		return nil
+	}
+
+	return c.credentialErrors[index]
+}
+
+// splitPartialIssuanceError returns the errors for the credentials that weren't issued if the given error is a
+// partial issuance error. Otherwise, the error is returned as-is.
+func splitPartialIssuanceError(err error, trace *otel.Trace) (*CredentialErrorsArray, error) {
+	credentialErrors := &CredentialErrorsArray{}
+
+	if err == nil {
+		return credentialErrors, nil
+	}
+
+	var partialIssuanceErr *openid4cigoapi.PartialIssuanceError
+
+	if !errors.As(err, &partialIssuanceErr) {
+		return nil, err
+	}
+
+	offerIndexes := make([]int, 0, len(partialIssuanceErr.CredentialErrors))
+
+	for offerIndex := range partialIssuanceErr.CredentialErrors {
+		offerIndexes = append(offerIndexes, offerIndex)
+	}
+
+	sort.Ints(offerIndexes)
+
+	for _, offerIndex := range offerIndexes {
+		mobileErr := wrapper.ToMobileErrorWithTrace(partialIssuanceErr.CredentialErrors[offerIndex], trace)
+
+		credentialErrors.credentialErrors = append(credentialErrors.credentialErrors, &CredentialError{
+			offerIndex:  offerIndex,
+			walletError: walleterror.Parse(mobileErr.Error()),
+		})
+	}
+
+	return credentialErrors, nil
+}
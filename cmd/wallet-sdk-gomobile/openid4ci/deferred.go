@@ -0,0 +1,153 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/api"
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/verifiable"
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/wrapper"
+	openid4cigoapi "github.com/trustbloc/wallet-sdk/pkg/openid4ci"
+)
+
+// DeferredCredential is a handle for a credential that an issuer has deferred. It can be serialized and stored,
+// and then later passed in to RequestDeferredCredential to retrieve the credential once the issuer has issued it.
+// The serialized form should be treated as sensitive data, as it may contain an access token.
+type DeferredCredential struct {
+	goAPIDeferredCredential *openid4cigoapi.DeferredCredential
+}
+
+// ParseDeferredCredential parses the given serialized deferred credential and returns a DeferredCredential object.
+func ParseDeferredCredential(deferredCredential string) (*DeferredCredential, error) {
+	var parsedDeferredCredential openid4cigoapi.DeferredCredential
+
+	err := json.Unmarshal([]byte(deferredCredential), &parsedDeferredCredential)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DeferredCredential{goAPIDeferredCredential: &parsedDeferredCredential}, nil
+}
+
+// Serialize serializes this DeferredCredential object into JSON.
+func (d *DeferredCredential) Serialize() (string, error) {
+	deferredCredentialBytes, err := json.Marshal(d.goAPIDeferredCredential)
+
+	return string(deferredCredentialBytes), err
+}
+
+// IssuerURI returns the URI of the issuer that deferred the credential.
+func (d *DeferredCredential) IssuerURI() string {
+	return d.goAPIDeferredCredential.IssuerURI
+}
+
+// Format returns the format of the deferred credential.
+func (d *DeferredCredential) Format() string {
+	return d.goAPIDeferredCredential.Format
+}
+
+// Types returns the types of the deferred credential.
+func (d *DeferredCredential) Types() *api.StringArray {
+	return &api.StringArray{Strings: d.goAPIDeferredCredential.Types}
+}
+
+// DeferredCredentialsArray represents an array of DeferredCredentials.
+// Since arrays and slices are not compatible with gomobile, this type acts as a wrapper around a Go array.
+type DeferredCredentialsArray struct {
+	deferredCredentials []*DeferredCredential
+}
+
+// Length returns the number of DeferredCredentials contained within this DeferredCredentialsArray.
+func (d *DeferredCredentialsArray) Length() int {
+	return len(d.deferredCredentials)
+}
+
+// AtIndex returns the DeferredCredential at the given index.
+// If the index passed in is out of bounds, then nil is returned.
+func (d *DeferredCredentialsArray) AtIndex(index int) *DeferredCredential {
+	maxIndex := len(d.deferredCredentials) - 1
+	if index > maxIndex || index < 0 {
+		return nil
+	}
+
+	return d.deferredCredentials[index]
+}
+
+// DeferredCredentialResult is the result of an attempt to retrieve a deferred credential.
+type DeferredCredentialResult struct {
+	goAPIDeferredCredentialResult *openid4cigoapi.DeferredCredentialResult
+}
+
+// IssuancePending indicates whether the issuer still hasn't issued the credential.
+// If true, then the caller should wait for at least the amount of time given by RetryAfterNanoseconds
+// before trying again.
+func (d *DeferredCredentialResult) IssuancePending() bool {
+	return d.goAPIDeferredCredentialResult.IssuancePending
+}
+
+// RetryAfterNanoseconds returns the amount of time (in nanoseconds) that the issuer asked the caller to wait
+// before trying again. It's only set if IssuancePending returns true.
+func (d *DeferredCredentialResult) RetryAfterNanoseconds() int64 {
+	return d.goAPIDeferredCredentialResult.RetryAfter.Nanoseconds()
+}
+
+// Credential returns the issued credential. It's nil if IssuancePending returns true.
+func (d *DeferredCredentialResult) Credential() *verifiable.Credential {
+	if d.goAPIDeferredCredentialResult.Credential == nil {
+		return nil
+	}
+
+	return verifiable.NewCredential(d.goAPIDeferredCredentialResult.Credential)
+}
+
+// RequestDeferredCredential attempts to retrieve a credential that was previously deferred by an issuer.
+// If the issuer reports that the credential still isn't ready, then no error is returned. Instead, the returned
+// DeferredCredentialResult will indicate that issuance is still pending and how long to wait before trying again.
+// The options are used in the same way as they are in NewInteraction.
+func RequestDeferredCredential(deferredCredential *DeferredCredential, didResolver api.DIDResolver,
+	opts *InteractionOpts,
+) (*DeferredCredentialResult, error) {
+	if deferredCredential == nil {
+		return nil, errors.New("deferred credential must be provided")
+	}
+
+	if opts == nil {
+		opts = NewInteractionOpts()
+	}
+
+	oTel, err := createOTelTrace(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	goAPIClientConfig, err := createGoAPIClientConfig(didResolver, opts)
+	if err != nil {
+		return nil, wrapper.ToMobileErrorWithTrace(err, oTel)
+	}
+
+	goAPIDeferredCredentialResult, err := openid4cigoapi.RequestDeferredCredentialContext(opts.cancelHandle.Context(),
+		deferredCredential.goAPIDeferredCredential, goAPIClientConfig)
+	if err != nil {
+		return nil, wrapper.ToMobileErrorWithTrace(err, oTel)
+	}
+
+	return &DeferredCredentialResult{goAPIDeferredCredentialResult: goAPIDeferredCredentialResult}, nil
+}
+
+func toGomobileDeferredCredentials(
+	goAPIDeferredCredentials []*openid4cigoapi.DeferredCredential,
+) *DeferredCredentialsArray {
+	deferredCredentials := make([]*DeferredCredential, len(goAPIDeferredCredentials))
+
+	for i := range goAPIDeferredCredentials {
+		deferredCredentials[i] = &DeferredCredential{goAPIDeferredCredential: goAPIDeferredCredentials[i]}
+	}
+
+	return &DeferredCredentialsArray{deferredCredentials: deferredCredentials}
+}
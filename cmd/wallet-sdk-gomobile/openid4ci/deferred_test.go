@@ -0,0 +1,119 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci_test
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	arieskms "github.com/hyperledger/aries-framework-go/spi/kms"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/api"
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/localkms"
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/openid4ci"
+	"github.com/trustbloc/wallet-sdk/pkg/models"
+	goapiopenid4ci "github.com/trustbloc/wallet-sdk/pkg/openid4ci"
+)
+
+func TestDeferredCredentials(t *testing.T) {
+	issuerServerHandler := &mockIssuerServerHandler{
+		t:                  t,
+		credentialResponse: []byte(`{"acceptance_token":"acceptanceToken"}`),
+	}
+	server := httptest.NewServer(issuerServerHandler)
+
+	defer server.Close()
+
+	issuerServerHandler.openIDConfig = &goapiopenid4ci.OpenIDConfig{
+		TokenEndpoint: fmt.Sprintf("%s/oidc/token", server.URL),
+	}
+
+	issuerServerHandler.issuerMetadata = fmt.Sprintf(`{"credential_endpoint":"%s/credential",`+
+		`"deferred_credential_endpoint":"%s/deferred_credential"}`, server.URL, server.URL)
+
+	kms, err := localkms.NewKMS(localkms.NewMemKMSStore())
+	require.NoError(t, err)
+
+	interaction := createInteraction(t, kms, nil, createCredentialOfferIssuanceURI(t, server.URL, false),
+		nil, false)
+
+	keyHandle, err := kms.Create(arieskms.ED25519)
+	require.NoError(t, err)
+
+	pkBytes, err := keyHandle.JWK.PublicKeyBytes()
+	require.NoError(t, err)
+
+	credentials, err := interaction.RequestCredentialWithPreAuth(&api.VerificationMethod{
+		ID:   "did:example:12345#testId",
+		Type: "Ed25519VerificationKey2018",
+		Key:  models.VerificationKey{Raw: pkBytes},
+	}, openid4ci.NewRequestCredentialWithPreAuthOpts().SetPIN("1234"))
+	require.NoError(t, err)
+	require.Equal(t, 0, credentials.Length())
+
+	deferredCredentials := interaction.DeferredCredentials()
+	require.Equal(t, 1, deferredCredentials.Length())
+	require.Nil(t, deferredCredentials.AtIndex(1))
+
+	deferredCredential := deferredCredentials.AtIndex(0)
+	require.Equal(t, server.URL, deferredCredential.IssuerURI())
+	require.Equal(t, "jwt_vc_json", deferredCredential.Format())
+	require.Equal(t, 2, deferredCredential.Types().Length())
+
+	serializedDeferredCredential, err := deferredCredential.Serialize()
+	require.NoError(t, err)
+
+	deferredCredential, err = openid4ci.ParseDeferredCredential(serializedDeferredCredential)
+	require.NoError(t, err)
+
+	_, opts := getTestArgs(t, "", kms, nil, nil, false)
+
+	t.Run("Issuance pending", func(t *testing.T) {
+		issuerServerHandler.deferredCredentialIssuancePending = true
+
+		result, err := openid4ci.RequestDeferredCredential(deferredCredential, &mockResolver{keyWriter: kms}, opts)
+		require.NoError(t, err)
+		require.True(t, result.IssuancePending())
+		require.Equal(t, (10 * time.Second).Nanoseconds(), result.RetryAfterNanoseconds())
+		require.Nil(t, result.Credential())
+	})
+	t.Run("Credential issued", func(t *testing.T) {
+		issuerServerHandler.deferredCredentialIssuancePending = false
+
+		result, err := openid4ci.RequestDeferredCredential(deferredCredential, &mockResolver{keyWriter: kms}, opts)
+		require.NoError(t, err)
+		require.False(t, result.IssuancePending())
+		require.NotNil(t, result.Credential())
+	})
+}
+
+func TestRequestDeferredCredential_Failures(t *testing.T) {
+	t.Run("Deferred credential not provided", func(t *testing.T) {
+		result, err := openid4ci.RequestDeferredCredential(nil, nil, nil)
+		require.EqualError(t, err, "deferred credential must be provided")
+		require.Nil(t, result)
+	})
+	t.Run("Invalid deferred credential", func(t *testing.T) {
+		deferredCredential, err := openid4ci.ParseDeferredCredential(`{}`)
+		require.NoError(t, err)
+
+		kms, err := localkms.NewKMS(localkms.NewMemKMSStore())
+		require.NoError(t, err)
+
+		result, err := openid4ci.RequestDeferredCredential(deferredCredential, &mockResolver{keyWriter: kms}, nil)
+		require.Contains(t, err.Error(), "INVALID_DEFERRED_CREDENTIAL")
+		require.Nil(t, result)
+	})
+	t.Run("Fail to parse deferred credential", func(t *testing.T) {
+		deferredCredential, err := openid4ci.ParseDeferredCredential("invalid")
+		require.Error(t, err)
+		require.Nil(t, deferredCredential)
+	})
+}
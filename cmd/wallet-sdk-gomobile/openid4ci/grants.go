@@ -16,6 +16,46 @@ func (p *PreAuthorizedCodeGrantParams) PINRequired() bool {
 	return p.goAPIPreAuthorizedCodeGrantParams.PINRequired()
 }
 
+// HasTxCode indicates whether the issuer provided hints about the PIN (called a transaction code in newer versions of
+// the spec) that it requires. Issuers following older versions of the spec never do.
+func (p *PreAuthorizedCodeGrantParams) HasTxCode() bool {
+	return p.goAPIPreAuthorizedCodeGrantParams.TxCode() != nil
+}
+
+// TxCode returns hints about the PIN that the issuer requires, which can be used to show an appropriate input field
+// to the user. The HasTxCode method should be called first to ensure this PreAuthorizedCodeGrantParams object has
+// them before calling this method.
+// This method returns an error if (and only if) HasTxCode returns false.
+func (p *PreAuthorizedCodeGrantParams) TxCode() (*TxCode, error) {
+	goAPITxCode := p.goAPIPreAuthorizedCodeGrantParams.TxCode()
+	if goAPITxCode == nil {
+		return nil, errors.New("pre-authorized code grant params does not specify a tx code")
+	}
+
+	return &TxCode{goAPITxCode: goAPITxCode}, nil
+}
+
+// TxCode contains hints about the PIN (transaction code) that an issuer requires.
+type TxCode struct {
+	goAPITxCode *openid4cigoapi.TxCode
+}
+
+// InputMode returns either "numeric" or "text", indicating the kind of characters the PIN consists of.
+func (t *TxCode) InputMode() string {
+	return t.goAPITxCode.InputMode
+}
+
+// Length returns the length of the PIN. It returns 0 if the issuer didn't specify it.
+func (t *TxCode) Length() int {
+	return t.goAPITxCode.Length
+}
+
+// Description returns guidance for the user on how to obtain the PIN. It returns an empty string if the issuer
+// didn't provide any.
+func (t *TxCode) Description() string {
+	return t.goAPITxCode.Description
+}
+
 // AuthorizationCodeGrantParams represents an issuer's authorization code grant parameters.
 type AuthorizationCodeGrantParams struct {
 	goAPIAuthorizationCodeGrantParams *openid4cigoapi.AuthorizationCodeGrantParams
@@ -15,6 +15,7 @@ import (
 	"github.com/hyperledger/aries-framework-go/component/storageutil/mem"
 
 	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/api"
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/display"
 	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/otel"
 	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/verifiable"
 	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/wrapper"
@@ -31,6 +32,8 @@ type Interaction struct {
 	goAPIInteraction *openid4cigoapi.Interaction
 	crypto           api.Crypto
 	oTel             *otel.Trace
+	cancelHandle     *api.CancelHandle
+	credentialErrors *CredentialErrorsArray
 }
 
 // NewInteraction creates a new OpenID4CI Interaction.
@@ -43,25 +46,54 @@ func NewInteraction(args *InteractionArgs, opts *InteractionOpts) (*Interaction,
 		opts = NewInteractionOpts()
 	}
 
-	var oTel *otel.Trace
+	oTel, err := createOTelTrace(opts)
+	if err != nil {
+		return nil, err
+	}
 
-	if !opts.disableOpenTelemetry {
-		var err error
+	goAPIClientConfig, err := createGoAPIClientConfig(args.didResolver, opts)
+	if err != nil {
+		return nil, wrapper.ToMobileErrorWithTrace(err, oTel)
+	}
 
-		oTel, err = otel.NewTrace()
-		if err != nil {
-			return nil, wrapper.ToMobileError(err)
-		}
+	goAPIInteraction, err := openid4cigoapi.NewInteractionContext(opts.cancelHandle.Context(),
+		args.initiateIssuanceURI, goAPIClientConfig)
+	if err != nil {
+		return nil, wrapper.ToMobileErrorWithTrace(err, oTel)
+	}
+
+	return &Interaction{
+		crypto:           args.crypto,
+		goAPIInteraction: goAPIInteraction,
+		oTel:             oTel,
+		cancelHandle:     opts.cancelHandle,
+	}, nil
+}
+
+// ResumeInteraction recreates an Interaction from state previously produced by the Serialize method.
+// This allows an authorization code flow to be completed even if the app was restarted after CreateAuthorizationURL
+// was called. The options must include the same interaction state key that was used when the state was serialized.
+// An error is returned if the state has been modified or has expired.
+func ResumeInteraction(args *ResumeInteractionArgs, opts *InteractionOpts) (*Interaction, error) {
+	if args == nil {
+		return nil, errors.New("args object must be provided")
+	}
+
+	if opts == nil {
+		opts = NewInteractionOpts()
+	}
 
-		opts.AddHeader(oTel.TraceHeader())
+	oTel, err := createOTelTrace(opts)
+	if err != nil {
+		return nil, err
 	}
 
-	goAPIClientConfig, err := createGoAPIClientConfig(args, opts)
+	goAPIClientConfig, err := createGoAPIClientConfig(args.didResolver, opts)
 	if err != nil {
 		return nil, wrapper.ToMobileErrorWithTrace(err, oTel)
 	}
 
-	goAPIInteraction, err := openid4cigoapi.NewInteraction(args.initiateIssuanceURI, goAPIClientConfig)
+	goAPIInteraction, err := openid4cigoapi.ResumeInteraction(args.state, goAPIClientConfig)
 	if err != nil {
 		return nil, wrapper.ToMobileErrorWithTrace(err, oTel)
 	}
@@ -70,14 +102,30 @@ func NewInteraction(args *InteractionArgs, opts *InteractionOpts) (*Interaction,
 		crypto:           args.crypto,
 		goAPIInteraction: goAPIInteraction,
 		oTel:             oTel,
+		cancelHandle:     opts.cancelHandle,
 	}, nil
 }
 
+// Serialize serializes the state of this Interaction so that it can be stored and later resumed using
+// ResumeInteraction, even in a different process. This is intended to be used after CreateAuthorizationURL.
+// An interaction state key must have been set using the InteractionOpts.
+// The serialized state contains the PKCE code verifier, so it should be stored securely.
+func (i *Interaction) Serialize() (string, error) {
+	state, err := i.goAPIInteraction.Serialize()
+	if err != nil {
+		return "", wrapper.ToMobileErrorWithTrace(err, i.oTel)
+	}
+
+	return state, nil
+}
+
 // CreateAuthorizationURL creates an authorization URL that can be opened in a browser to proceed to the login page.
 // It is the first step in the authorization code flow.
 // It creates the authorization URL that can be opened in a browser to proceed to the login page.
 // This method can only be used if the issuer supports authorization code grants.
 // Check the issuer's capabilities first using the Capabilities method.
+// If the authorization server supports pushed authorization requests (or the UsePushedAuthorizationRequest option is
+// set), then the returned URL only contains the client ID and a request URI from the authorization server.
 func (i *Interaction) CreateAuthorizationURL(clientID, redirectURI string,
 	opts *CreateAuthorizationURLOpts,
 ) (string, error) {
@@ -89,8 +137,14 @@ func (i *Interaction) CreateAuthorizationURL(clientID, redirectURI string,
 		opts.scopes = api.NewStringArray()
 	}
 
-	return i.goAPIInteraction.CreateAuthorizationURL(clientID, redirectURI,
-		openid4cigoapi.WithScopes(opts.scopes.Strings))
+	goAPIOpts := []openid4cigoapi.CreateAuthorizationURLOpt{openid4cigoapi.WithScopes(opts.scopes.Strings)}
+
+	if opts.usePushedAuthorizationRequest {
+		goAPIOpts = append(goAPIOpts, openid4cigoapi.WithPushedAuthorizationRequest())
+	}
+
+	return i.goAPIInteraction.CreateAuthorizationURLContext(i.cancelHandle.Context(), clientID, redirectURI,
+		goAPIOpts...)
 }
 
 // RequestCredentialWithPreAuth requests credential(s) from the issuer. This method can only be used for the
@@ -98,6 +152,10 @@ func (i *Interaction) CreateAuthorizationURL(clientID, redirectURI string,
 // For the equivalent method for the authorization code flow, see RequestCredentialWithAuth instead.
 // If a PIN is required (which can be checked via the Capabilities method), then it must be passed
 // into this method via the SetPIN method on the RequestCredentialWithPreAuthOpts object.
+// If the issuer deferred any of the credentials, then they won't be in the returned array. Use the
+// DeferredCredentials method afterwards to get the handles needed to retrieve them later.
+// If some, but not all, of the offered credentials can't be issued, then the ones that were issued are returned
+// without an error. Use the CredentialErrors method afterwards to find out which ones weren't issued and why.
 func (i *Interaction) RequestCredentialWithPreAuth(
 	vm *api.VerificationMethod, opts *RequestCredentialWithPreAuthOpts,
 ) (*verifiable.CredentialsArray, error) {
@@ -105,12 +163,15 @@ func (i *Interaction) RequestCredentialWithPreAuth(
 		opts = NewRequestCredentialWithPreAuthOpts()
 	}
 
-	signer, err := i.createSigner(vm)
+	signer, err := i.createHolderSigner(vm, &opts.keyBinding)
 	if err != nil {
 		return nil, err
 	}
 
-	credentials, err := i.goAPIInteraction.RequestCredentialWithPreAuth(signer, openid4cigoapi.WithPIN(opts.pin))
+	credentials, err := i.goAPIInteraction.RequestCredentialWithPreAuthContext(i.cancelHandle.Context(), signer,
+		openid4cigoapi.WithPIN(opts.pin))
+
+	i.credentialErrors, err = splitPartialIssuanceError(err, i.oTel)
 	if err != nil {
 		return nil, wrapper.ToMobileErrorWithTrace(err, i.oTel)
 	}
@@ -125,15 +186,26 @@ func (i *Interaction) RequestCredentialWithPreAuth(
 // RequestCredentialWithAuth should be called only once all authorization pre-requisite steps have been completed.
 // The redirect URI that you pass in here should look like the redirect URI that you passed in to the
 // CreateAuthorizationURL, except that now it has some URL query parameters appended to it.
+// If the issuer deferred any of the credentials, then they won't be in the returned array. Use the
+// DeferredCredentials method afterwards to get the handles needed to retrieve them later.
+// If some, but not all, of the offered credentials can't be issued, then the ones that were issued are returned
+// without an error. Use the CredentialErrors method afterwards to find out which ones weren't issued and why.
 func (i *Interaction) RequestCredentialWithAuth(vm *api.VerificationMethod,
 	redirectURIWithAuthCode string, opts *RequestCredentialWithAuthOpts,
 ) (*verifiable.CredentialsArray, error) {
-	signer, err := i.createSigner(vm)
+	if opts == nil {
+		opts = NewRequestCredentialWithAuthOpts()
+	}
+
+	signer, err := i.createHolderSigner(vm, &opts.keyBinding)
 	if err != nil {
 		return nil, err
 	}
 
-	credentials, err := i.goAPIInteraction.RequestCredentialWithAuth(signer, redirectURIWithAuthCode)
+	credentials, err := i.goAPIInteraction.RequestCredentialWithAuthContext(i.cancelHandle.Context(), signer,
+		redirectURIWithAuthCode)
+
+	i.credentialErrors, err = splitPartialIssuanceError(err, i.oTel)
 	if err != nil {
 		return nil, wrapper.ToMobileErrorWithTrace(err, i.oTel)
 	}
@@ -160,6 +232,68 @@ func (i *Interaction) RequestCredentialWithPIN(
 	return i.RequestCredentialWithPreAuth(vm, NewRequestCredentialWithPreAuthOpts().SetPIN(pin))
 }
 
+// DeferredCredentials returns handles for the credentials that the issuer deferred during the last credential
+// request. Each one can be serialized, stored, and later passed in to RequestDeferredCredential to retrieve the
+// credential once the issuer has issued it.
+func (i *Interaction) DeferredCredentials() *DeferredCredentialsArray {
+	return toGomobileDeferredCredentials(i.goAPIInteraction.DeferredCredentials())
+}
+
+// CredentialErrors returns an error for each offered credential that the issuer didn't issue during the last
+// credential request, in the order the credentials were offered. The array is empty if they were all issued.
+func (i *Interaction) CredentialErrors() *CredentialErrorsArray {
+	if i.credentialErrors == nil {
+		return &CredentialErrorsArray{}
+	}
+
+	return i.credentialErrors
+}
+
+// ReissuanceTokens returns reissuance tokens for the credentials that were issued during the last credential
+// request, in the same order as the returned credentials. The array is empty if the issuer didn't provide a refresh
+// token. Each one can be serialized, stored alongside its credential, and later passed in to ReissueCredential.
+func (i *Interaction) ReissuanceTokens() *ReissuanceTokensArray {
+	return toGomobileReissuanceTokens(i.goAPIInteraction.ReissuanceTokens())
+}
+
+// NotificationHandles returns notification handles for the credentials that were issued during the last credential
+// request that the issuer wants to be notified about. Use CredentialID to match them up with the credentials.
+// The array is empty if the issuer doesn't support notifications.
+func (i *Interaction) NotificationHandles() *NotificationHandlesArray {
+	return toGomobileNotificationHandles(i.goAPIInteraction.NotificationHandles())
+}
+
+// SendNotification notifies the issuer about what happened to the credential that the given NotificationHandle is
+// bound to. The event must be one of the NotificationEvent constants. The event description is optional.
+func (i *Interaction) SendNotification(notificationHandle *NotificationHandle, event, eventDescription string,
+) error {
+	if notificationHandle == nil {
+		return errors.New("notification handle must be provided")
+	}
+
+	err := i.goAPIInteraction.SendNotificationContext(i.cancelHandle.Context(),
+		notificationHandle.goAPINotificationHandle, event, eventDescription)
+	if err != nil {
+		return wrapper.ToMobileErrorWithTrace(err, i.oTel)
+	}
+
+	return nil
+}
+
+// OfferedCredentialsDisplay returns display data for the issuer and the offered credentials, based on the issuer's
+// metadata, so that they can be shown to the user before any credentials are requested. The credential displays are
+// in the same order as the credentials in the credential offer. Since the credentials haven't been issued yet, the
+// claims only contain labels, without values. The preferred locale is optional.
+func (i *Interaction) OfferedCredentialsDisplay(preferredLocale string) (*display.Data, error) {
+	resolvedDisplayData, err := i.goAPIInteraction.OfferedCredentialsDisplayContext(i.cancelHandle.Context(),
+		preferredLocale)
+	if err != nil {
+		return nil, wrapper.ToMobileErrorWithTrace(err, i.oTel)
+	}
+
+	return display.NewData(resolvedDisplayData), nil
+}
+
 // IssuerURI returns the issuer's URI from the initiation request. It's useful to store this somewhere in case
 // there's a later need to refresh credential display data using the latest display information from the issuer.
 func (i *Interaction) IssuerURI() string {
@@ -208,7 +342,7 @@ func (i *Interaction) AuthorizationCodeGrantParams() (*AuthorizationCodeGrantPar
 
 // DynamicClientRegistrationSupported indicates whether the issuer supports dynamic client registration.
 func (i *Interaction) DynamicClientRegistrationSupported() (bool, error) {
-	return i.goAPIInteraction.DynamicClientRegistrationSupported()
+	return i.goAPIInteraction.DynamicClientRegistrationSupportedContext(i.cancelHandle.Context())
 }
 
 // DynamicClientRegistrationEndpoint returns the issuer's dynamic client registration endpoint.
@@ -216,7 +350,20 @@ func (i *Interaction) DynamicClientRegistrationSupported() (bool, error) {
 // if DynamicClientRegistrationSupported returns true.
 // This method will return an error if the issuer does not support dynamic client registration.
 func (i *Interaction) DynamicClientRegistrationEndpoint() (string, error) {
-	return i.goAPIInteraction.DynamicClientRegistrationEndpoint()
+	return i.goAPIInteraction.DynamicClientRegistrationEndpointContext(i.cancelHandle.Context())
+}
+
+// ProofRequirements returns the DID methods and proof signing algorithms that the issuer accepts for the offered
+// credentials. It can be used to pick (or create) a suitable DID before requesting credentials. If the verification
+// method passed in to one of the RequestCredential methods doesn't meet these requirements, then an
+// INCOMPATIBLE_SIGNER error is returned before any requests are made to the issuer's token or credential endpoints.
+func (i *Interaction) ProofRequirements() (*ProofRequirements, error) {
+	goAPIProofRequirements, err := i.goAPIInteraction.ProofRequirementsContext(i.cancelHandle.Context())
+	if err != nil {
+		return nil, wrapper.ToMobileErrorWithTrace(err, i.oTel)
+	}
+
+	return &ProofRequirements{goAPIProofRequirements: goAPIProofRequirements}, nil
 }
 
 // OTelTraceID returns open telemetry trace id.
@@ -242,7 +389,53 @@ func (i *Interaction) createSigner(vm *api.VerificationMethod) (*common.JWSSigne
 	return signer, nil
 }
 
-func createGoAPIClientConfig(config *InteractionArgs,
+// createHolderSigner creates the signer for the proof sent with credential requests. Unless a key binding option was
+// used, the verification method is expected to belong to a DID.
+func (i *Interaction) createHolderSigner(vm *api.VerificationMethod,
+	keyBinding *holderKeyBinding,
+) (*common.JWSSigner, error) {
+	if vm == nil {
+		return nil, errors.New("verification method must be provided")
+	}
+
+	var (
+		signer *common.JWSSigner
+		err    error
+	)
+
+	switch {
+	case keyBinding.certificateChain != nil:
+		signer, err = common.NewX5CBoundSigner(vm.ToSDKVerificationMethod(), keyBinding.certificateChain.Strings,
+			i.crypto)
+	case keyBinding.useJWK:
+		signer, err = common.NewJWKBoundSigner(vm.ToSDKVerificationMethod(), i.crypto)
+	default:
+		return i.createSigner(vm)
+	}
+
+	if err != nil {
+		return nil, wrapper.ToMobileErrorWithTrace(err, i.oTel)
+	}
+
+	return signer, nil
+}
+
+func createOTelTrace(opts *InteractionOpts) (*otel.Trace, error) {
+	if opts.disableOpenTelemetry {
+		return nil, nil //nolint:nilnil // A nil trace means that open telemetry is disabled.
+	}
+
+	oTel, err := otel.NewTrace()
+	if err != nil {
+		return nil, wrapper.ToMobileError(err)
+	}
+
+	opts.AddHeader(oTel.TraceHeader())
+
+	return oTel, nil
+}
+
+func createGoAPIClientConfig(didResolver api.DIDResolver,
 	opts *InteractionOpts,
 ) (*openid4cigoapi.ClientConfig, error) {
 	activityLogger := createGoAPIActivityLogger(opts.activityLogger)
@@ -250,12 +443,42 @@ func createGoAPIClientConfig(config *InteractionArgs,
 	httpClient := wrapper.NewHTTPClient(opts.httpTimeout, opts.additionalHeaders, opts.disableHTTPClientTLSVerification)
 
 	goAPIClientConfig := &openid4cigoapi.ClientConfig{
-		DIDResolver:                      &wrapper.VDRResolverWrapper{DIDResolver: config.didResolver},
+		DIDResolver:                      &wrapper.VDRResolverWrapper{DIDResolver: didResolver},
 		ActivityLogger:                   activityLogger,
 		MetricsLogger:                    &wrapper.MobileMetricsLoggerWrapper{MobileAPIMetricsLogger: opts.metricsLogger},
 		DisableVCProofChecks:             opts.disableVCProofChecks,
 		NetworkDocumentLoaderHTTPTimeout: opts.httpTimeout,
 		HTTPClient:                       httpClient,
+		InteractionStateKey:              opts.interactionStateKey,
+		InteractionStateLifetime:         opts.interactionStateLifetime,
+	}
+
+	if opts.dpopVerificationMethod != nil {
+		var err error
+
+		goAPIClientConfig.DPoP, err = createGoAPIDPoPConfig(opts.dpopVerificationMethod, opts.dpopCrypto)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.clientAttestation != "" {
+		var err error
+
+		goAPIClientConfig.ClientAttestation, err = createGoAPIClientAttestationConfig(opts.clientAttestation,
+			opts.clientAttestationVM, opts.clientAttestationCrypto)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.clientAuthentication != nil {
+		var err error
+
+		goAPIClientConfig.ClientAuthentication, err = createGoAPIClientAuthentication(opts.clientAuthentication)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	if opts.documentLoader != nil {
@@ -277,6 +500,54 @@ func createGoAPIClientConfig(config *InteractionArgs,
 	return goAPIClientConfig, nil
 }
 
+func createGoAPIDPoPConfig(vm *api.VerificationMethod, crypto api.Crypto) (*openid4cigoapi.DPoPConfig, error) {
+	signer, err := common.NewJWSSigner(vm.ToSDKVerificationMethod(), crypto)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, err := common.PublicKeyJWK(vm.ToSDKVerificationMethod())
+	if err != nil {
+		return nil, err
+	}
+
+	return &openid4cigoapi.DPoPConfig{Signer: signer, PublicKey: publicKey}, nil
+}
+
+func createGoAPIClientAttestationConfig(attestation string, vm *api.VerificationMethod, crypto api.Crypto,
+) (*openid4cigoapi.ClientAttestationConfig, error) {
+	if vm == nil {
+		return nil, errors.New("a verification method must be provided for client attestation")
+	}
+
+	signer, err := common.NewJWSSigner(vm.ToSDKVerificationMethod(), crypto)
+	if err != nil {
+		return nil, err
+	}
+
+	return &openid4cigoapi.ClientAttestationConfig{Attestation: attestation, Signer: signer}, nil
+}
+
+func createGoAPIClientAuthentication(opts *clientAuthenticationOpts,
+) (*openid4cigoapi.ClientAuthentication, error) {
+	clientAuthentication := &openid4cigoapi.ClientAuthentication{
+		ClientID:     opts.clientID,
+		Method:       opts.tokenEndpointAuthMethod,
+		ClientSecret: opts.clientSecret,
+	}
+
+	if opts.vm != nil {
+		signer, err := common.NewJWSSigner(opts.vm.ToSDKVerificationMethod(), opts.crypto)
+		if err != nil {
+			return nil, err
+		}
+
+		clientAuthentication.Signer = signer
+	}
+
+	return clientAuthentication, nil
+}
+
 func createGoAPIActivityLogger(mobileAPIActivityLogger api.ActivityLogger) goapi.ActivityLogger {
 	if mobileAPIActivityLogger == nil {
 		return nil // Will result in activity logging being disabled in the OpenID4CI Interaction object.
@@ -9,8 +9,10 @@ package openid4ci_test
 
 import (
 	_ "embed"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -124,8 +126,14 @@ type mockIssuerServerHandler struct {
 	tokenRequestShouldGiveUnmarshallableResponse      bool
 	credentialRequestShouldFail                       bool
 	credentialRequestShouldGiveUnmarshallableResponse bool
+	firstCredentialRequestShouldFail                  bool
+	credentialRequestsReceived                        int
 	credentialResponse                                []byte
+	deferredCredentialIssuancePending                 bool
+	tokenResponse                                     string
 	headersToCheck                                    *api.Headers
+	receivedDPoPProofs                                []string
+	receivedNotificationEvents                        []string
 }
 
 func (m *mockIssuerServerHandler) ServeHTTP(writer http.ResponseWriter, //nolint: gocyclo // test file
@@ -141,6 +149,10 @@ func (m *mockIssuerServerHandler) ServeHTTP(writer http.ResponseWriter, //nolint
 		}
 	}
 
+	if dpopProof := request.Header.Get("DPoP"); dpopProof != "" {
+		m.receivedDPoPProofs = append(m.receivedDPoPProofs, dpopProof)
+	}
+
 	switch request.URL.Path {
 	case "/.well-known/openid-configuration":
 		var openIDConfigBytes []byte
@@ -161,11 +173,19 @@ func (m *mockIssuerServerHandler) ServeHTTP(writer http.ResponseWriter, //nolint
 		case m.tokenRequestShouldGiveUnmarshallableResponse:
 			_, err = writer.Write([]byte("invalid"))
 		default:
-			_, err = writer.Write([]byte(sampleTokenResponse))
+			tokenResponse := sampleTokenResponse
+			if m.tokenResponse != "" {
+				tokenResponse = m.tokenResponse
+			}
+
+			writer.Header().Set("Content-Type", "application/json")
+			_, err = writer.Write([]byte(tokenResponse))
 		}
 	case "/credential":
+		m.credentialRequestsReceived++
+
 		switch {
-		case m.credentialRequestShouldFail:
+		case m.credentialRequestShouldFail, m.firstCredentialRequestShouldFail && m.credentialRequestsReceived == 1:
 			writer.WriteHeader(http.StatusInternalServerError)
 			_, err = writer.Write([]byte("test failure"))
 		case m.credentialRequestShouldGiveUnmarshallableResponse:
@@ -173,6 +193,26 @@ func (m *mockIssuerServerHandler) ServeHTTP(writer http.ResponseWriter, //nolint
 		default:
 			_, err = writer.Write(m.credentialResponse)
 		}
+	case "/deferred_credential":
+		if m.deferredCredentialIssuancePending {
+			writer.WriteHeader(http.StatusBadRequest)
+			_, err = writer.Write([]byte(`{"error":"issuance_pending","interval":10}`))
+		} else {
+			_, err = writer.Write(sampleCredentialResponse)
+		}
+	case "/notification":
+		var notificationRequest struct {
+			Event string `json:"event"`
+		}
+
+		err = json.NewDecoder(request.Body).Decode(&notificationRequest)
+		if err != nil {
+			break
+		}
+
+		m.receivedNotificationEvents = append(m.receivedNotificationEvents, notificationRequest.Event)
+
+		writer.WriteHeader(http.StatusNoContent)
 	}
 
 	require.NoError(m.t, err)
@@ -188,6 +228,32 @@ func TestInteraction_CreateAuthorizationURL(t *testing.T) {
 	authorizationLink, err := interaction.CreateAuthorizationURL("clientID", "redirectURI", nil)
 	require.EqualError(t, err, "issuer does not support the authorization code grant type")
 	require.Empty(t, authorizationLink)
+
+	t.Run("Pushed authorization request required but not supported", func(t *testing.T) {
+		issuerServerHandler := &mockIssuerServerHandler{t: t}
+		server := httptest.NewServer(issuerServerHandler)
+
+		defer server.Close()
+
+		issuerServerHandler.openIDConfig = &goapiopenid4ci.OpenIDConfig{
+			TokenEndpoint: fmt.Sprintf("%s/oidc/token", server.URL),
+		}
+
+		issuerServerHandler.issuerMetadata = fmt.Sprintf(`{"credential_endpoint":"%[1]s/credential",`+
+			`"authorization_server":"%[1]s/oidc/authorize"}`, server.URL)
+
+		interaction := createInteraction(t, kms, nil, createCredentialOfferIssuanceURI(t, server.URL, true),
+			nil, false)
+
+		authorizationLink, err := interaction.CreateAuthorizationURL("clientID", "redirectURI",
+			openid4ci.NewCreateAuthorizationURLOpts().UsePushedAuthorizationRequest())
+		requireErrorContains(t, err, "PUSHED_AUTHORIZATION_REQUEST_FAILED")
+		require.Empty(t, authorizationLink)
+
+		authorizationLink, err = interaction.CreateAuthorizationURL("clientID", "redirectURI", nil)
+		require.NoError(t, err)
+		require.Contains(t, authorizationLink, "authorization_details=")
+	})
 }
 
 func TestInteraction_RequestCredential(t *testing.T) {
@@ -252,6 +318,142 @@ func TestInteraction_RequestCredential(t *testing.T) {
 		require.NoError(t, err)
 		require.NotNil(t, result)
 	})
+	t.Run("Some of the offered credentials aren't issued", func(t *testing.T) {
+		issuerServerHandler := &mockIssuerServerHandler{
+			t:                                t,
+			credentialResponse:               sampleCredentialResponse,
+			firstCredentialRequestShouldFail: true,
+		}
+		server := httptest.NewServer(issuerServerHandler)
+
+		issuerServerHandler.openIDConfig = &goapiopenid4ci.OpenIDConfig{
+			TokenEndpoint: fmt.Sprintf("%s/oidc/token", server.URL),
+		}
+
+		issuerServerHandler.issuerMetadata = fmt.Sprintf(`{"credential_endpoint":"%s/credential"}`, server.URL)
+
+		defer server.Close()
+
+		credentialOffer := createCredentialOffer(t, server.URL, false)
+		credentialOffer.Credentials = append(credentialOffer.Credentials, credentialOffer.Credentials[0])
+
+		credentialOfferBytes, err := json.Marshal(credentialOffer)
+		require.NoError(t, err)
+
+		kms, err := localkms.NewKMS(localkms.NewMemKMSStore())
+		require.NoError(t, err)
+
+		interactionRequiredArgs, interactionOptionalArgs := getTestArgs(t,
+			"openid-vc://?credential_offer="+url.QueryEscape(string(credentialOfferBytes)), kms, nil, nil, false)
+
+		interaction, err := openid4ci.NewInteraction(interactionRequiredArgs, interactionOptionalArgs)
+		require.NoError(t, err)
+
+		require.Zero(t, interaction.CredentialErrors().Length())
+
+		keyHandle, err := kms.Create(arieskms.ED25519)
+		require.NoError(t, err)
+
+		verificationMethod := &api.VerificationMethod{
+			ID:   mockKeyID,
+			Type: creator.JSONWebKey2020,
+			Key:  models.VerificationKey{JSONWebKey: keyHandle.JWK},
+		}
+
+		result, err := interaction.RequestCredentialWithPreAuth(verificationMethod,
+			openid4ci.NewRequestCredentialWithPreAuthOpts().SetPIN("1234"))
+		require.NoError(t, err)
+		require.Equal(t, 1, result.Length())
+
+		credentialErrors := interaction.CredentialErrors()
+		require.Equal(t, 1, credentialErrors.Length())
+		require.Nil(t, credentialErrors.AtIndex(1))
+
+		credentialError := credentialErrors.AtIndex(0)
+		require.Equal(t, 0, credentialError.OfferIndex())
+		require.Equal(t, "OCI1-0010", credentialError.WalletError().Code)
+		require.Equal(t, "CREDENTIAL_FETCH_FAILED", credentialError.WalletError().Category)
+		require.Contains(t, credentialError.WalletError().Details, "received status code [500]")
+	})
+	t.Run("Success with key binding instead of a DID", func(t *testing.T) {
+		issuerServerHandler := &mockIssuerServerHandler{
+			t:                  t,
+			credentialResponse: sampleCredentialResponse,
+		}
+		server := httptest.NewServer(issuerServerHandler)
+
+		issuerServerHandler.openIDConfig = &goapiopenid4ci.OpenIDConfig{
+			TokenEndpoint: fmt.Sprintf("%s/oidc/token", server.URL),
+		}
+
+		issuerServerHandler.issuerMetadata = fmt.Sprintf(`{"credential_endpoint":"%s/credential",`+
+			`"credentials_supported":[{"format":"jwt_vc_json","types":["VerifiableCredential","VerifiedEmployee"],`+
+			`"cryptographic_binding_methods_supported":["jwk"]}]}`, server.URL)
+
+		defer server.Close()
+
+		kms, err := localkms.NewKMS(localkms.NewMemKMSStore())
+		require.NoError(t, err)
+
+		keyHandle, err := kms.Create(arieskms.ED25519)
+		require.NoError(t, err)
+
+		verificationMethod := api.NewVerificationMethodFromJWK(keyHandle)
+
+		testCases := []struct {
+			name string
+			opts *openid4ci.RequestCredentialWithPreAuthOpts
+		}{
+			{
+				name: "JWK",
+				opts: openid4ci.NewRequestCredentialWithPreAuthOpts().SetPIN("1234").UseJWKBinding(),
+			},
+			{
+				name: "x5c",
+				opts: openid4ci.NewRequestCredentialWithPreAuthOpts().SetPIN("1234").
+					SetX5CBinding(api.NewStringArray().Append("MIIBtjCCAVugAwIBAgITBmyf1XSXNmY")),
+			},
+		}
+
+		for _, testCase := range testCases {
+			t.Run(testCase.name, func(t *testing.T) {
+				interaction := createInteraction(t, kms, nil, createCredentialOfferIssuanceURI(t, server.URL, false),
+					nil, false)
+
+				result, err := interaction.RequestCredentialWithPreAuth(verificationMethod, testCase.opts)
+				require.NoError(t, err)
+				require.NotNil(t, result)
+			})
+		}
+
+		t.Run("Without a key binding option, a DID is required", func(t *testing.T) {
+			interaction := createInteraction(t, kms, nil, createCredentialOfferIssuanceURI(t, server.URL, false),
+				nil, false)
+
+			result, err := interaction.RequestCredentialWithPreAuth(verificationMethod,
+				openid4ci.NewRequestCredentialWithPreAuthOpts().SetPIN("1234"))
+			requireErrorContains(t, err, "KEY_ID_NOT_CONTAIN_DID_PART")
+			require.Nil(t, result)
+		})
+		t.Run("Empty certificate chain", func(t *testing.T) {
+			interaction := createInteraction(t, kms, nil, createCredentialOfferIssuanceURI(t, server.URL, false),
+				nil, false)
+
+			result, err := interaction.RequestCredentialWithAuth(verificationMethod, "",
+				openid4ci.NewRequestCredentialWithAuthOpts().SetX5CBinding(api.NewStringArray()))
+			requireErrorContains(t, err, "NO_CERTIFICATE_CHAIN_PROVIDED")
+			require.Nil(t, result)
+		})
+		t.Run("Unsupported verification method", func(t *testing.T) {
+			interaction := createInteraction(t, kms, nil, createCredentialOfferIssuanceURI(t, server.URL, false),
+				nil, false)
+
+			result, err := interaction.RequestCredentialWithAuth(&api.VerificationMethod{Type: "Invalid"}, "",
+				openid4ci.NewRequestCredentialWithAuthOpts().UseJWKBinding())
+			requireErrorContains(t, err, "UNSUPPORTED_ALGORITHM")
+			require.Nil(t, result)
+		})
+	})
 	t.Run("Fail to sign", func(t *testing.T) {
 		issuerServerHandler := &mockIssuerServerHandler{t: t}
 		server := httptest.NewServer(issuerServerHandler)
@@ -360,6 +562,11 @@ func TestInteraction_GrantTypes(t *testing.T) {
 	require.NotNil(t, preAuthorizedCodeGrantParams)
 
 	require.True(t, preAuthorizedCodeGrantParams.PINRequired())
+	require.False(t, preAuthorizedCodeGrantParams.HasTxCode())
+
+	txCode, err := preAuthorizedCodeGrantParams.TxCode()
+	require.EqualError(t, err, "pre-authorized code grant params does not specify a tx code")
+	require.Nil(t, txCode)
 
 	require.False(t, interaction.AuthorizationCodeGrantTypeSupported())
 
@@ -381,6 +588,26 @@ func TestInteraction_GrantTypes(t *testing.T) {
 	issuerState, err := authorizationCodeGrantParams.IssuerState()
 	require.NoError(t, err)
 	require.Equal(t, "1234", issuerState)
+
+	credentialOffer := createCredentialOffer(t, "example.com", false)
+	credentialOffer.Grants["urn:ietf:params:oauth:grant-type:pre-authorized_code"]["tx_code"] =
+		map[string]interface{}{"input_mode": "text", "length": 6, "description": "Check your email"}
+
+	credentialOfferBytes, err := json.Marshal(credentialOffer)
+	require.NoError(t, err)
+
+	interaction = createInteraction(t, kms, nil,
+		"openid-vc://?credential_offer="+url.QueryEscape(string(credentialOfferBytes)), nil, false)
+
+	preAuthorizedCodeGrantParams, err = interaction.PreAuthorizedCodeGrantParams()
+	require.NoError(t, err)
+	require.True(t, preAuthorizedCodeGrantParams.HasTxCode())
+
+	txCode, err = preAuthorizedCodeGrantParams.TxCode()
+	require.NoError(t, err)
+	require.Equal(t, "text", txCode.InputMode())
+	require.Equal(t, 6, txCode.Length())
+	require.Equal(t, "Check your email", txCode.Description())
 }
 
 func TestInteraction_DynamicClientRegistration(t *testing.T) {
@@ -403,7 +630,182 @@ func TestInteraction_DynamicClientRegistration(t *testing.T) {
 	require.Empty(t, endpoint)
 }
 
+func TestInteraction_ProofRequirements(t *testing.T) {
+	kms, err := localkms.NewKMS(localkms.NewMemKMSStore())
+	require.NoError(t, err)
+
+	t.Run("Success", func(t *testing.T) {
+		issuerServerHandler := &mockIssuerServerHandler{t: t}
+		server := httptest.NewServer(issuerServerHandler)
+
+		defer server.Close()
+
+		issuerServerHandler.issuerMetadata = fmt.Sprintf(`{"credential_endpoint":"%s/credential",`+
+			`"credentials_supported":[{"format":"jwt_vc_json","types":["VerifiableCredential","VerifiedEmployee"],`+
+			`"cryptographic_binding_methods_supported":["did:key"],`+
+			`"cryptographic_suites_supported":["EdDSA","ES256"]}]}`, server.URL)
+
+		interaction := createInteraction(t, kms, nil, createCredentialOfferIssuanceURI(t, server.URL, false),
+			nil, false)
+
+		proofRequirements, err := interaction.ProofRequirements()
+		require.NoError(t, err)
+
+		require.Equal(t, 1, proofRequirements.BindingMethods().Length())
+		require.Equal(t, "did:key", proofRequirements.BindingMethods().AtIndex(0))
+		require.Equal(t, 2, proofRequirements.SigningAlgorithms().Length())
+		require.True(t, proofRequirements.SupportsDIDMethod("key"))
+		require.False(t, proofRequirements.SupportsDIDMethod("example"))
+		require.True(t, proofRequirements.SupportsAlgorithm("ES256"))
+		require.False(t, proofRequirements.SupportsAlgorithm("ES384"))
+		require.Equal(t, "key", proofRequirements.DIDMethod())
+
+		keyType, err := proofRequirements.KeyType()
+		require.NoError(t, err)
+		require.Equal(t, arieskms.ED25519, keyType)
+
+		keyHandle, err := kms.Create(arieskms.ED25519)
+		require.NoError(t, err)
+
+		pkBytes, err := keyHandle.JWK.PublicKeyBytes()
+		require.NoError(t, err)
+
+		result, err := interaction.RequestCredentialWithPIN(&api.VerificationMethod{
+			ID:   "did:example:12345#testId",
+			Type: "Ed25519VerificationKey2018",
+			Key:  models.VerificationKey{Raw: pkBytes},
+		}, "1234")
+		requireErrorContains(t, err, "INCOMPATIBLE_SIGNER")
+		require.Nil(t, result)
+	})
+	t.Run("Fail to fetch issuer metadata", func(t *testing.T) {
+		interaction := createInteraction(t, kms, nil, createCredentialOfferIssuanceURI(t, "example.com", false),
+			nil, false)
+
+		proofRequirements, err := interaction.ProofRequirements()
+		requireErrorContains(t, err, "METADATA_FETCH_FAILED")
+		require.Nil(t, proofRequirements)
+	})
+}
+
+func TestInteraction_OfferedCredentialsDisplay(t *testing.T) {
+	kms, err := localkms.NewKMS(localkms.NewMemKMSStore())
+	require.NoError(t, err)
+
+	t.Run("Success", func(t *testing.T) {
+		issuerServerHandler := &mockIssuerServerHandler{t: t}
+		server := httptest.NewServer(issuerServerHandler)
+
+		defer server.Close()
+
+		issuerServerHandler.issuerMetadata = fmt.Sprintf(`{"credential_endpoint":"%s/credential",`+
+			`"display":[{"name":"Example Issuer","locale":"en-US"}],`+
+			`"credentials_supported":[{"format":"jwt_vc_json","types":["VerifiableCredential","VerifiedEmployee"],`+
+			`"display":[{"name":"Verified Employee","locale":"en-US","background_color":"#12107c"}],`+
+			`"credentialSubject":{"displayName":{"display":[{"name":"Employee","locale":"en-US"}]}}}]}`,
+			server.URL)
+
+		interaction := createInteraction(t, kms, nil, createCredentialOfferIssuanceURI(t, server.URL, false),
+			nil, false)
+
+		displayData, err := interaction.OfferedCredentialsDisplay("en-US")
+		require.NoError(t, err)
+
+		require.Equal(t, "Example Issuer", displayData.IssuerDisplay().Name())
+		require.Equal(t, 1, displayData.CredentialDisplaysLength())
+
+		credentialDisplay := displayData.CredentialDisplayAtIndex(0)
+		require.Equal(t, "Verified Employee", credentialDisplay.Overview().Name())
+		require.Equal(t, "#12107c", credentialDisplay.Overview().BackgroundColor())
+		require.Equal(t, 1, credentialDisplay.ClaimsLength())
+		require.Equal(t, "Employee", credentialDisplay.ClaimAtIndex(0).Label())
+		require.Empty(t, credentialDisplay.ClaimAtIndex(0).RawValue())
+	})
+	t.Run("Fail to fetch issuer metadata", func(t *testing.T) {
+		interaction := createInteraction(t, kms, nil, createCredentialOfferIssuanceURI(t, "example.com", false),
+			nil, false)
+
+		displayData, err := interaction.OfferedCredentialsDisplay("")
+		requireErrorContains(t, err, "METADATA_FETCH_FAILED")
+		require.Nil(t, displayData)
+	})
+}
+
 //nolint:thelper // Not a test helper function
+func TestInteraction_CancelHandle(t *testing.T) {
+	kms, err := localkms.NewKMS(localkms.NewMemKMSStore())
+	require.NoError(t, err)
+
+	keyHandle, err := kms.Create(arieskms.ED25519)
+	require.NoError(t, err)
+
+	verificationMethod := &api.VerificationMethod{
+		ID:   mockKeyID,
+		Type: creator.JSONWebKey2020,
+		Key:  models.VerificationKey{JSONWebKey: keyHandle.JWK},
+	}
+
+	cancelHandle := api.NewCancelHandle()
+
+	issuerServerHandler := &mockIssuerServerHandler{t: t, credentialResponse: sampleCredentialResponse}
+
+	// The token request is held until the client gives up on it, which only happens once the handle is cancelled.
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.URL.Path != "/oidc/token" {
+			issuerServerHandler.ServeHTTP(writer, request)
+
+			return
+		}
+
+		// The server only notices that the client has gone away once the request body has been read.
+		_, err := io.Copy(io.Discard, request.Body)
+		require.NoError(t, err)
+
+		cancelHandle.Cancel()
+
+		<-request.Context().Done()
+	}))
+	defer server.Close()
+
+	issuerServerHandler.openIDConfig = &goapiopenid4ci.OpenIDConfig{
+		TokenEndpoint: fmt.Sprintf("%s/oidc/token", server.URL),
+	}
+	issuerServerHandler.issuerMetadata = fmt.Sprintf(`{"credential_endpoint":"%s/credential"}`, server.URL)
+
+	requiredArgs, opts := getTestArgs(t, createCredentialOfferIssuanceURI(t, server.URL, false), kms, nil, nil,
+		false)
+	opts.SetCancelHandle(cancelHandle)
+
+	interaction, err := openid4ci.NewInteraction(requiredArgs, opts)
+	require.NoError(t, err)
+
+	result, err := interaction.RequestCredentialWithPreAuth(verificationMethod,
+		openid4ci.NewRequestCredentialWithPreAuthOpts().SetPIN("1234"))
+	requireErrorContains(t, err, "TOKEN_FETCH_FAILED")
+	requireErrorContains(t, err, "context canceled")
+	require.Nil(t, result)
+	require.True(t, cancelHandle.IsCancelled())
+
+	// Once cancelled, nothing created with the same options can make any further network calls.
+	interaction, err = openid4ci.NewInteraction(requiredArgs, opts)
+	require.NoError(t, err)
+
+	proofRequirements, err := interaction.ProofRequirements()
+	requireErrorContains(t, err, "METADATA_FETCH_FAILED")
+	requireErrorContains(t, err, "context canceled")
+	require.Nil(t, proofRequirements)
+
+	deferredCredential, err := openid4ci.ParseDeferredCredential(fmt.Sprintf(
+		`{"deferred_credential_endpoint":"%s/deferred_credential","acceptance_token":"acceptanceToken"}`, server.URL))
+	require.NoError(t, err)
+
+	deferredCredentialResult, err := openid4ci.RequestDeferredCredential(deferredCredential,
+		&mockResolver{keyWriter: kms}, opts)
+	requireErrorContains(t, err, "DEFERRED_CREDENTIAL_FETCH_FAILED")
+	requireErrorContains(t, err, "context canceled")
+	require.Nil(t, deferredCredentialResult)
+}
+
 func doRequestCredentialTest(t *testing.T, additionalHeaders *api.Headers,
 	disableTLSVerification bool,
 ) {
@@ -482,6 +884,225 @@ func doRequestCredentialTest(t *testing.T, additionalHeaders *api.Headers,
 	require.Equal(t, "did:orb:uAAA:EiARTvvCsWFTSCc35447YpI2MJpFAaJZtFlceVz9lcMYVw", subjectID)
 }
 
+func TestInteraction_DPoP(t *testing.T) {
+	issuerServerHandler := &mockIssuerServerHandler{
+		t:                  t,
+		credentialResponse: sampleCredentialResponse,
+	}
+	server := httptest.NewServer(issuerServerHandler)
+
+	defer server.Close()
+
+	issuerServerHandler.openIDConfig = &goapiopenid4ci.OpenIDConfig{
+		TokenEndpoint: fmt.Sprintf("%s/oidc/token", server.URL),
+	}
+
+	issuerServerHandler.issuerMetadata = fmt.Sprintf(`{"credential_endpoint":"%s/credential"}`, server.URL)
+
+	kms, err := localkms.NewKMS(localkms.NewMemKMSStore())
+	require.NoError(t, err)
+
+	keyHandle, err := kms.Create(arieskms.ED25519)
+	require.NoError(t, err)
+
+	pkBytes, err := keyHandle.JWK.PublicKeyBytes()
+	require.NoError(t, err)
+
+	vm := &api.VerificationMethod{
+		ID:   "did:example:12345#testId",
+		Type: "Ed25519VerificationKey2018",
+		Key:  models.VerificationKey{Raw: pkBytes},
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		requiredArgs, opts := getTestArgs(t, createCredentialOfferIssuanceURI(t, server.URL, false), kms, nil, nil,
+			false)
+		opts.EnableDPoP(vm, kms.GetCrypto())
+
+		interaction, err := openid4ci.NewInteraction(requiredArgs, opts)
+		require.NoError(t, err)
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(vm,
+			openid4ci.NewRequestCredentialWithPreAuthOpts().SetPIN("1234"))
+		require.NoError(t, err)
+		require.Equal(t, 1, credentials.Length())
+
+		// One DPoP proof for the token request and one for the credential request.
+		require.Len(t, issuerServerHandler.receivedDPoPProofs, 2)
+	})
+	t.Run("Unsupported verification method", func(t *testing.T) {
+		requiredArgs, opts := getTestArgs(t, createCredentialOfferIssuanceURI(t, server.URL, false), kms, nil, nil,
+			false)
+		opts.EnableDPoP(&api.VerificationMethod{ID: "did:example:12345#testId", Type: "UnsupportedType"},
+			kms.GetCrypto())
+
+		interaction, err := openid4ci.NewInteraction(requiredArgs, opts)
+		requireErrorContains(t, err, "UNSUPPORTED_ALGORITHM")
+		require.Nil(t, interaction)
+	})
+}
+
+func TestInteraction_ClientAuthentication(t *testing.T) {
+	issuerServerHandler := &mockIssuerServerHandler{
+		t:                  t,
+		credentialResponse: sampleCredentialResponse,
+	}
+	server := httptest.NewServer(issuerServerHandler)
+
+	defer server.Close()
+
+	issuerServerHandler.openIDConfig = &goapiopenid4ci.OpenIDConfig{
+		TokenEndpoint: fmt.Sprintf("%s/oidc/token", server.URL),
+	}
+
+	issuerServerHandler.issuerMetadata = fmt.Sprintf(`{"credential_endpoint":"%s/credential"}`, server.URL)
+
+	kms, err := localkms.NewKMS(localkms.NewMemKMSStore())
+	require.NoError(t, err)
+
+	keyHandle, err := kms.Create(arieskms.ED25519)
+	require.NoError(t, err)
+
+	pkBytes, err := keyHandle.JWK.PublicKeyBytes()
+	require.NoError(t, err)
+
+	vm := &api.VerificationMethod{
+		ID:   "did:example:12345#testId",
+		Type: "Ed25519VerificationKey2018",
+		Key:  models.VerificationKey{Raw: pkBytes},
+	}
+
+	t.Run("client_secret_post", func(t *testing.T) {
+		requiredArgs, opts := getTestArgs(t, createCredentialOfferIssuanceURI(t, server.URL, false), kms, nil, nil,
+			false)
+		opts.SetClientAuthentication("clientID", "client_secret_post", "clientSecret", nil, nil)
+
+		interaction, err := openid4ci.NewInteraction(requiredArgs, opts)
+		require.NoError(t, err)
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(vm,
+			openid4ci.NewRequestCredentialWithPreAuthOpts().SetPIN("1234"))
+		require.NoError(t, err)
+		require.Equal(t, 1, credentials.Length())
+	})
+	t.Run("private_key_jwt", func(t *testing.T) {
+		requiredArgs, opts := getTestArgs(t, createCredentialOfferIssuanceURI(t, server.URL, false), kms, nil, nil,
+			false)
+		opts.SetClientAuthentication("clientID", "private_key_jwt", "", vm, kms.GetCrypto())
+
+		interaction, err := openid4ci.NewInteraction(requiredArgs, opts)
+		require.NoError(t, err)
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(vm,
+			openid4ci.NewRequestCredentialWithPreAuthOpts().SetPIN("1234"))
+		require.NoError(t, err)
+		require.Equal(t, 1, credentials.Length())
+	})
+	t.Run("Unsupported method", func(t *testing.T) {
+		requiredArgs, opts := getTestArgs(t, createCredentialOfferIssuanceURI(t, server.URL, false), kms, nil, nil,
+			false)
+		opts.SetClientAuthentication("clientID", "tls_client_auth", "", nil, nil)
+
+		interaction, err := openid4ci.NewInteraction(requiredArgs, opts)
+		requireErrorContains(t, err, "INVALID_CLIENT_AUTHENTICATION")
+		require.Nil(t, interaction)
+	})
+	t.Run("Invalid client authentication in options", func(t *testing.T) {
+		requiredArgs, opts := getTestArgs(t, createCredentialOfferIssuanceURI(t, server.URL, false), kms, nil, nil,
+			false)
+		opts.SetClientAuthentication("clientID", "private_key_jwt", "", nil, nil)
+
+		interaction, err := openid4ci.NewInteraction(requiredArgs, opts)
+		requireErrorContains(t, err, "INVALID_CLIENT_AUTHENTICATION")
+		require.Nil(t, interaction)
+
+		requiredArgs, opts = getTestArgs(t, createCredentialOfferIssuanceURI(t, server.URL, false), kms, nil, nil,
+			false)
+		opts.SetClientAuthentication("clientID", "private_key_jwt", "",
+			&api.VerificationMethod{ID: "did:example:12345#testId", Type: "UnsupportedType"}, kms.GetCrypto())
+
+		interaction, err = openid4ci.NewInteraction(requiredArgs, opts)
+		requireErrorContains(t, err, "UNSUPPORTED_ALGORITHM")
+		require.Nil(t, interaction)
+	})
+}
+
+func TestInteraction_ClientAttestation(t *testing.T) {
+	issuerServerHandler := &mockIssuerServerHandler{
+		t:                  t,
+		credentialResponse: sampleCredentialResponse,
+	}
+	server := httptest.NewServer(issuerServerHandler)
+
+	defer server.Close()
+
+	issuerServerHandler.openIDConfig = &goapiopenid4ci.OpenIDConfig{
+		TokenEndpoint: fmt.Sprintf("%s/oidc/token", server.URL),
+	}
+
+	issuerServerHandler.issuerMetadata = fmt.Sprintf(`{"credential_endpoint":"%s/credential"}`, server.URL)
+
+	kms, err := localkms.NewKMS(localkms.NewMemKMSStore())
+	require.NoError(t, err)
+
+	keyHandle, err := kms.Create(arieskms.ED25519)
+	require.NoError(t, err)
+
+	pkBytes, err := keyHandle.JWK.PublicKeyBytes()
+	require.NoError(t, err)
+
+	vm := &api.VerificationMethod{
+		ID:   "did:example:12345#testId",
+		Type: "Ed25519VerificationKey2018",
+		Key:  models.VerificationKey{Raw: pkBytes},
+	}
+
+	attestation := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"EdDSA"}`)) + "." +
+		base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"walletInstanceID"}`)) + ".c2lnbmF0dXJl"
+
+	t.Run("Success", func(t *testing.T) {
+		requiredArgs, opts := getTestArgs(t, createCredentialOfferIssuanceURI(t, server.URL, false), kms, nil, nil,
+			false)
+		opts.SetClientAttestation(attestation, vm, kms.GetCrypto())
+
+		interaction, err := openid4ci.NewInteraction(requiredArgs, opts)
+		require.NoError(t, err)
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(vm,
+			openid4ci.NewRequestCredentialWithPreAuthOpts().SetPIN("1234"))
+		require.NoError(t, err)
+		require.Equal(t, 1, credentials.Length())
+	})
+	t.Run("Missing verification method", func(t *testing.T) {
+		requiredArgs, opts := getTestArgs(t, createCredentialOfferIssuanceURI(t, server.URL, false), kms, nil, nil,
+			false)
+		opts.SetClientAttestation(attestation, nil, kms.GetCrypto())
+
+		interaction, err := openid4ci.NewInteraction(requiredArgs, opts)
+		requireErrorContains(t, err, "a verification method must be provided for client attestation")
+		require.Nil(t, interaction)
+	})
+	t.Run("Combined with client authentication", func(t *testing.T) {
+		requiredArgs, opts := getTestArgs(t, createCredentialOfferIssuanceURI(t, server.URL, false), kms, nil, nil,
+			false)
+		opts.SetClientAttestation(attestation, vm, kms.GetCrypto()).
+			SetClientAuthentication("clientID", "none", "", nil, nil)
+
+		interaction, err := openid4ci.NewInteraction(requiredArgs, opts)
+		requireErrorContains(t, err, "client authentication and client attestation can't both be set")
+		require.Nil(t, interaction)
+	})
+	t.Run("Invalid attestation", func(t *testing.T) {
+		requiredArgs, opts := getTestArgs(t, createCredentialOfferIssuanceURI(t, server.URL, false), kms, nil, nil,
+			false)
+		opts.SetClientAttestation("invalid", vm, kms.GetCrypto())
+
+		interaction, err := openid4ci.NewInteraction(requiredArgs, opts)
+		requireErrorContains(t, err, "INVALID_CLIENT_AUTHENTICATION")
+		require.Nil(t, interaction)
+	})
+}
+
 func createInteraction(t *testing.T, kms *localkms.KMS, activityLogger api.ActivityLogger, requestURI string,
 	additionalHeaders *api.Headers, disableTLSVerification bool,
 ) *openid4ci.Interaction {
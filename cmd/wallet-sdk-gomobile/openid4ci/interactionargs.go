@@ -23,3 +23,38 @@ func NewInteractionArgs(initiateIssuanceURI string, crypto api.Crypto, didResolv
 		didResolver:         didResolver,
 	}
 }
+
+// ResumeInteractionArgs contains the required parameters for resuming an Interaction.
+type ResumeInteractionArgs struct {
+	state       string
+	crypto      api.Crypto
+	didResolver api.DIDResolver
+}
+
+// NewResumeInteractionArgs creates a new ResumeInteractionArgs object. All parameters are mandatory.
+// The state must have been created by the Serialize method on an Interaction.
+func NewResumeInteractionArgs(state string, crypto api.Crypto, didResolver api.DIDResolver) *ResumeInteractionArgs {
+	return &ResumeInteractionArgs{
+		state:       state,
+		crypto:      crypto,
+		didResolver: didResolver,
+	}
+}
+
+// ReissueCredentialArgs contains the required parameters for reissuing a credential.
+type ReissueCredentialArgs struct {
+	reissuanceToken *ReissuanceToken
+	crypto          api.Crypto
+	didResolver     api.DIDResolver
+}
+
+// NewReissueCredentialArgs creates a new ReissueCredentialArgs object. All parameters are mandatory.
+func NewReissueCredentialArgs(reissuanceToken *ReissuanceToken, crypto api.Crypto,
+	didResolver api.DIDResolver,
+) *ReissueCredentialArgs {
+	return &ReissueCredentialArgs{
+		reissuanceToken: reissuanceToken,
+		crypto:          crypto,
+		didResolver:     didResolver,
+	}
+}
@@ -22,6 +22,23 @@ type InteractionOpts struct {
 	documentLoader                   api.LDDocumentLoader
 	disableOpenTelemetry             bool
 	httpTimeout                      *time.Duration
+	interactionStateKey              []byte
+	interactionStateLifetime         *time.Duration
+	dpopVerificationMethod           *api.VerificationMethod
+	dpopCrypto                       api.Crypto
+	clientAttestation                string
+	clientAttestationVM              *api.VerificationMethod
+	clientAttestationCrypto          api.Crypto
+	clientAuthentication             *clientAuthenticationOpts
+	cancelHandle                     *api.CancelHandle
+}
+
+type clientAuthenticationOpts struct {
+	clientID                string
+	tokenEndpointAuthMethod string
+	clientSecret            string
+	vm                      *api.VerificationMethod
+	crypto                  api.Crypto
 }
 
 // NewInteractionOpts returns a new InteractionOpts object.
@@ -99,3 +116,80 @@ func (o *InteractionOpts) DisableOpenTelemetry() *InteractionOpts {
 
 	return o
 }
+
+// SetInteractionStateKey sets the secret key used to protect serialized Interaction state from tampering.
+// It must be at least 32 bytes long, and the same key must be set when resuming an Interaction.
+// This option is only needed if Interaction.Serialize or ResumeInteraction is used.
+func (o *InteractionOpts) SetInteractionStateKey(key []byte) *InteractionOpts {
+	o.interactionStateKey = key
+
+	return o
+}
+
+// SetInteractionStateLifetimeNanoseconds sets how long (in nanoseconds) serialized Interaction state remains valid for.
+// If not set, then a default of one hour is used.
+func (o *InteractionOpts) SetInteractionStateLifetimeNanoseconds(lifetime int64) *InteractionOpts {
+	lifetimeDuration := time.Duration(lifetime)
+	o.interactionStateLifetime = &lifetimeDuration
+
+	return o
+}
+
+// EnableDPoP enables DPoP (RFC 9449), which binds the access tokens issued during the OpenID4CI flow to the given
+// verification method's key. DPoP proofs will be signed using the given crypto implementation.
+// This should only be used with issuers that issue DPoP-bound access tokens.
+func (o *InteractionOpts) EnableDPoP(vm *api.VerificationMethod, crypto api.Crypto) *InteractionOpts {
+	o.dpopVerificationMethod = vm
+	o.dpopCrypto = crypto
+
+	return o
+}
+
+// SetClientAttestation enables attestation-based client authentication at the issuer's token endpoint, which some
+// issuers require so that they only issue credentials to certified wallet instances. attestation is the wallet
+// instance attestation JWT (as issued by the wallet provider's backend), and its sub claim is used as the client ID.
+// For the authorization code flow, the same client ID must be passed in to CreateAuthorizationURL.
+// The proof-of-possession JWTs are signed with the given verification method's key using the given crypto
+// implementation, so the verification method must refer to the wallet instance key that the attestation is bound to.
+func (o *InteractionOpts) SetClientAttestation(attestation string, vm *api.VerificationMethod,
+	crypto api.Crypto,
+) *InteractionOpts {
+	o.clientAttestation = attestation
+	o.clientAttestationVM = vm
+	o.clientAttestationCrypto = crypto
+
+	return o
+}
+
+// SetClientAuthentication sets the client authentication to use at the issuer's token endpoint. It's only needed for
+// wallets that are confidential clients, such as ones that registered themselves using dynamic client registration.
+// tokenEndpointAuthMethod must be one of "none", "client_secret_basic", "client_secret_post" or "private_key_jwt".
+// For clients registered using dynamic client registration, it should be the registered token endpoint
+// authentication method ("client_secret_basic" if the registration response doesn't specify one).
+// clientSecret is only required for the client_secret_basic and client_secret_post methods, and vm and crypto (which
+// are used to sign client assertions) are only required for the private_key_jwt method. For the authorization code
+// flow, clientID must match the client ID passed in to CreateAuthorizationURL.
+// Client authentication set here is also used when resuming an interaction and when reissuing a credential.
+// It can't be combined with SetClientAttestation.
+func (o *InteractionOpts) SetClientAuthentication(clientID, tokenEndpointAuthMethod, clientSecret string,
+	vm *api.VerificationMethod, crypto api.Crypto,
+) *InteractionOpts {
+	o.clientAuthentication = &clientAuthenticationOpts{
+		clientID:                clientID,
+		tokenEndpointAuthMethod: tokenEndpointAuthMethod,
+		clientSecret:            clientSecret,
+		vm:                      vm,
+		crypto:                  crypto,
+	}
+
+	return o
+}
+
+// SetCancelHandle sets a CancelHandle that can be used to abort the network operations made using these options.
+// Cancelling it aborts any in-progress HTTP requests and DID resolutions. Once it's been cancelled, any Interaction
+// created with these options can no longer make network calls, so a new one must be created to try again.
+func (o *InteractionOpts) SetCancelHandle(cancelHandle *api.CancelHandle) *InteractionOpts {
+	o.cancelHandle = cancelHandle
+
+	return o
+}
@@ -0,0 +1,111 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci_test
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	arieskms "github.com/hyperledger/aries-framework-go/spi/kms"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/api"
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/localkms"
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/openid4ci"
+	"github.com/trustbloc/wallet-sdk/pkg/models"
+	goapiopenid4ci "github.com/trustbloc/wallet-sdk/pkg/openid4ci"
+)
+
+var testInteractionStateKey = []byte("0123456789abcdef0123456789abcdef")
+
+func TestInteraction_SerializeAndResume(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		issuerServerHandler := &mockIssuerServerHandler{
+			t:                  t,
+			credentialResponse: sampleCredentialResponse,
+		}
+		server := httptest.NewServer(issuerServerHandler)
+
+		defer server.Close()
+
+		issuerServerHandler.openIDConfig = &goapiopenid4ci.OpenIDConfig{
+			TokenEndpoint: fmt.Sprintf("%s/oidc/token", server.URL),
+		}
+
+		issuerServerHandler.issuerMetadata = fmt.Sprintf(`{"credential_endpoint":"%s/credential"}`, server.URL)
+
+		kms, err := localkms.NewKMS(localkms.NewMemKMSStore())
+		require.NoError(t, err)
+
+		args, opts := getTestArgs(t, createCredentialOfferIssuanceURI(t, server.URL, true), kms, nil, nil, false)
+		opts.SetInteractionStateKey(testInteractionStateKey)
+		opts.SetInteractionStateLifetimeNanoseconds(time.Minute.Nanoseconds())
+
+		interaction, err := openid4ci.NewInteraction(args, opts)
+		require.NoError(t, err)
+
+		authURL, err := interaction.CreateAuthorizationURL("clientID", "redirectURI", nil)
+		require.NoError(t, err)
+
+		state, err := interaction.Serialize()
+		require.NoError(t, err)
+
+		_, resumeOpts := getTestArgs(t, "", kms, nil, nil, false)
+		resumeOpts.SetInteractionStateKey(testInteractionStateKey)
+
+		resumedInteraction, err := openid4ci.ResumeInteraction(
+			openid4ci.NewResumeInteractionArgs(state, kms.GetCrypto(), &mockResolver{keyWriter: kms}), resumeOpts)
+		require.NoError(t, err)
+
+		keyHandle, err := kms.Create(arieskms.ED25519)
+		require.NoError(t, err)
+
+		pkBytes, err := keyHandle.JWK.PublicKeyBytes()
+		require.NoError(t, err)
+
+		parsedAuthURL, err := url.Parse(authURL)
+		require.NoError(t, err)
+
+		credentials, err := resumedInteraction.RequestCredentialWithAuth(&api.VerificationMethod{
+			ID:   "did:example:12345#testId",
+			Type: "Ed25519VerificationKey2018",
+			Key:  models.VerificationKey{Raw: pkBytes},
+		}, "redirectURI?code=1234&state="+parsedAuthURL.Query().Get("state"), nil)
+		require.NoError(t, err)
+		require.Equal(t, 1, credentials.Length())
+	})
+	t.Run("Missing interaction state key", func(t *testing.T) {
+		kms, err := localkms.NewKMS(localkms.NewMemKMSStore())
+		require.NoError(t, err)
+
+		interaction := createInteraction(t, kms, nil, createCredentialOfferIssuanceURI(t, "example.com", false),
+			nil, false)
+
+		state, err := interaction.Serialize()
+		requireErrorContains(t, err, "an interaction state key of at least 32 bytes must be provided")
+		require.Empty(t, state)
+	})
+	t.Run("Args not provided", func(t *testing.T) {
+		resumedInteraction, err := openid4ci.ResumeInteraction(nil, nil)
+		require.EqualError(t, err, "args object must be provided")
+		require.Nil(t, resumedInteraction)
+	})
+	t.Run("Invalid state", func(t *testing.T) {
+		kms, err := localkms.NewKMS(localkms.NewMemKMSStore())
+		require.NoError(t, err)
+
+		opts := openid4ci.NewInteractionOpts().SetInteractionStateKey(testInteractionStateKey)
+
+		resumedInteraction, err := openid4ci.ResumeInteraction(
+			openid4ci.NewResumeInteractionArgs("invalid", kms.GetCrypto(), &mockResolver{keyWriter: kms}), opts)
+		requireErrorContains(t, err, "INVALID_INTERACTION_STATE")
+		require.Nil(t, resumedInteraction)
+	})
+}
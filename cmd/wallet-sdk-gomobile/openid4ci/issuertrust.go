@@ -0,0 +1,68 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci
+
+import (
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/wrapper"
+	openid4cigoapi "github.com/trustbloc/wallet-sdk/pkg/openid4ci"
+)
+
+// The possible outcomes of an issuer trust check. See IssuerTrustInfo.Status.
+const (
+	// IssuerTrustStatusVerified indicates that the issuer's DID is linked to the issuer's domain.
+	IssuerTrustStatusVerified = openid4cigoapi.IssuerTrustStatusVerified
+	// IssuerTrustStatusDomainMismatch indicates that the issuer's DID is linked to a domain, but it's not the
+	// issuer's domain.
+	IssuerTrustStatusDomainMismatch = openid4cigoapi.IssuerTrustStatusDomainMismatch
+	// IssuerTrustStatusNoLinkedDomains indicates that the issuer's DID document has no Linked Domains service (or the
+	// issuer isn't identified by a DID).
+	IssuerTrustStatusNoLinkedDomains = openid4cigoapi.IssuerTrustStatusNoLinkedDomains
+	// IssuerTrustStatusLinkedDomainUnverified indicates that the issuer's DID document has Linked Domains services,
+	// but none of their domains' DID configurations confirm the link.
+	IssuerTrustStatusLinkedDomainUnverified = openid4cigoapi.IssuerTrustStatusLinkedDomainUnverified
+)
+
+// IssuerTrustInfo is the result of checking whether the DID that issued the credentials is linked to the domain of
+// the issuer that the credential offer came from.
+type IssuerTrustInfo struct {
+	goAPIIssuerTrustInfo *openid4cigoapi.IssuerTrustInfo
+}
+
+// DID returns the ID of the issuer of the received credentials.
+func (i *IssuerTrustInfo) DID() string {
+	return i.goAPIIssuerTrustInfo.DID
+}
+
+// IssuerDomain returns the domain of the issuer URI from the credential offer.
+func (i *IssuerTrustInfo) IssuerDomain() string {
+	return i.goAPIIssuerTrustInfo.IssuerDomain
+}
+
+// LinkedDomain returns the domain from the DID's Linked Domains services that was verified, if any. If the issuer's
+// domain is one of them, then that's the one returned.
+func (i *IssuerTrustInfo) LinkedDomain() string {
+	return i.goAPIIssuerTrustInfo.LinkedDomain
+}
+
+// Status returns the outcome of the check. It's one of the IssuerTrustStatus constants.
+func (i *IssuerTrustInfo) Status() string {
+	return i.goAPIIssuerTrustInfo.Status
+}
+
+// IssuerTrustInfo checks whether the DID that signed the credentials received from the last credential request is
+// linked to the domain of the issuer URI from the credential offer, using the DID's Linked Domains services.
+// An error is returned if the credentials weren't all issued by the same DID.
+// The result can be used to show the user whether the issuer can be trusted (e.g. a "verified issuer" badge or a
+// warning).
+func (i *Interaction) IssuerTrustInfo() (*IssuerTrustInfo, error) {
+	goAPIIssuerTrustInfo, err := i.goAPIInteraction.IssuerTrustInfoContext(i.cancelHandle.Context())
+	if err != nil {
+		return nil, wrapper.ToMobileErrorWithTrace(err, i.oTel)
+	}
+
+	return &IssuerTrustInfo{goAPIIssuerTrustInfo: goAPIIssuerTrustInfo}, nil
+}
@@ -0,0 +1,70 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci_test
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	arieskms "github.com/hyperledger/aries-framework-go/spi/kms"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/api"
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/localkms"
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/openid4ci"
+	"github.com/trustbloc/wallet-sdk/pkg/models"
+	goapiopenid4ci "github.com/trustbloc/wallet-sdk/pkg/openid4ci"
+)
+
+func TestInteraction_IssuerTrustInfo(t *testing.T) {
+	issuerServerHandler := &mockIssuerServerHandler{
+		t:                  t,
+		credentialResponse: sampleCredentialResponse,
+	}
+	server := httptest.NewServer(issuerServerHandler)
+
+	defer server.Close()
+
+	issuerServerHandler.openIDConfig = &goapiopenid4ci.OpenIDConfig{
+		TokenEndpoint: fmt.Sprintf("%s/oidc/token", server.URL),
+	}
+
+	issuerServerHandler.issuerMetadata = fmt.Sprintf(`{"credential_endpoint":"%s/credential"}`, server.URL)
+
+	kms, err := localkms.NewKMS(localkms.NewMemKMSStore())
+	require.NoError(t, err)
+
+	interaction := createInteraction(t, kms, nil, createCredentialOfferIssuanceURI(t, server.URL, false),
+		nil, false)
+
+	trustInfo, err := interaction.IssuerTrustInfo()
+	requireErrorContains(t, err, "ISSUER_TRUST_CHECK_FAILED")
+	require.Nil(t, trustInfo)
+
+	keyHandle, err := kms.Create(arieskms.ED25519)
+	require.NoError(t, err)
+
+	pkBytes, err := keyHandle.JWK.PublicKeyBytes()
+	require.NoError(t, err)
+
+	credentials, err := interaction.RequestCredentialWithPreAuth(&api.VerificationMethod{
+		ID:   "did:example:12345#testId",
+		Type: "Ed25519VerificationKey2018",
+		Key:  models.VerificationKey{Raw: pkBytes},
+	}, openid4ci.NewRequestCredentialWithPreAuthOpts().SetPIN("1234"))
+	require.NoError(t, err)
+	require.Equal(t, 1, credentials.Length())
+
+	// The mock DID resolver returns DID documents without any services.
+	trustInfo, err = interaction.IssuerTrustInfo()
+	require.NoError(t, err)
+	require.Equal(t, credentials.AtIndex(0).IssuerID(), trustInfo.DID())
+	require.Equal(t, server.Listener.Addr().String(), trustInfo.IssuerDomain())
+	require.Empty(t, trustInfo.LinkedDomain())
+	require.Equal(t, openid4ci.IssuerTrustStatusNoLinkedDomains, trustInfo.Status())
+}
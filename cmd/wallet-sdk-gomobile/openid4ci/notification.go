@@ -0,0 +1,134 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/wrapper"
+	openid4cigoapi "github.com/trustbloc/wallet-sdk/pkg/openid4ci"
+)
+
+// The events that can be sent to an issuer using SendNotification.
+const (
+	// NotificationEventCredentialAccepted indicates that the credential was successfully stored by the wallet.
+	NotificationEventCredentialAccepted = openid4cigoapi.NotificationEventCredentialAccepted
+	// NotificationEventCredentialDeleted indicates that the credential was rejected or deleted by the user.
+	NotificationEventCredentialDeleted = openid4cigoapi.NotificationEventCredentialDeleted
+	// NotificationEventCredentialFailure indicates that the credential couldn't be stored for any other reason.
+	NotificationEventCredentialFailure = openid4cigoapi.NotificationEventCredentialFailure
+)
+
+// NotificationHandle holds what's needed to notify an issuer about what happened to a credential it issued, bound to
+// that credential and issuer. It can be serialized and stored alongside the credential, and then later passed in to
+// SendNotification. The serialized form should be treated as sensitive data, as it contains an access token.
+type NotificationHandle struct {
+	goAPINotificationHandle *openid4cigoapi.NotificationHandle
+}
+
+// ParseNotificationHandle parses the given serialized notification handle and returns a NotificationHandle object.
+func ParseNotificationHandle(notificationHandle string) (*NotificationHandle, error) {
+	var parsedNotificationHandle openid4cigoapi.NotificationHandle
+
+	err := json.Unmarshal([]byte(notificationHandle), &parsedNotificationHandle)
+	if err != nil {
+		return nil, err
+	}
+
+	return &NotificationHandle{goAPINotificationHandle: &parsedNotificationHandle}, nil
+}
+
+// Serialize serializes this NotificationHandle object into JSON.
+func (n *NotificationHandle) Serialize() (string, error) {
+	notificationHandleBytes, err := json.Marshal(n.goAPINotificationHandle)
+
+	return string(notificationHandleBytes), err
+}
+
+// CredentialID returns the ID of the credential that this NotificationHandle is bound to.
+func (n *NotificationHandle) CredentialID() string {
+	return n.goAPINotificationHandle.CredentialID
+}
+
+// IssuerURI returns the URI of the issuer that issued the credential.
+func (n *NotificationHandle) IssuerURI() string {
+	return n.goAPINotificationHandle.IssuerURI
+}
+
+// NotificationID returns the ID that the issuer assigned to the credential for notification purposes.
+func (n *NotificationHandle) NotificationID() string {
+	return n.goAPINotificationHandle.NotificationID
+}
+
+// NotificationHandlesArray represents an array of NotificationHandles.
+// Since arrays and slices are not compatible with gomobile, this type acts as a wrapper around a Go array.
+type NotificationHandlesArray struct {
+	notificationHandles []*NotificationHandle
+}
+
+// Length returns the number of NotificationHandles contained within this NotificationHandlesArray.
+func (n *NotificationHandlesArray) Length() int {
+	return len(n.notificationHandles)
+}
+
+// AtIndex returns the NotificationHandle at the given index.
+// If the index passed in is out of bounds, then nil is returned.
+func (n *NotificationHandlesArray) AtIndex(index int) *NotificationHandle {
+	maxIndex := len(n.notificationHandles) - 1
+	if index > maxIndex || index < 0 {
+		return nil
+	}
+
+	return n.notificationHandles[index]
+}
+
+// SendNotification notifies the issuer about what happened to the credential that the given NotificationHandle is
+// bound to. It can be used once the Interaction that created the NotificationHandle is gone.
+// The event must be one of the NotificationEvent constants. The event description is optional.
+// The options are used in the same way as they are in NewInteraction.
+func SendNotification(notificationHandle *NotificationHandle, event, eventDescription string,
+	opts *InteractionOpts,
+) error {
+	if notificationHandle == nil {
+		return errors.New("notification handle must be provided")
+	}
+
+	if opts == nil {
+		opts = NewInteractionOpts()
+	}
+
+	oTel, err := createOTelTrace(opts)
+	if err != nil {
+		return err
+	}
+
+	goAPIClientConfig, err := createGoAPIClientConfig(nil, opts)
+	if err != nil {
+		return wrapper.ToMobileErrorWithTrace(err, oTel)
+	}
+
+	err = openid4cigoapi.SendNotificationContext(opts.cancelHandle.Context(),
+		notificationHandle.goAPINotificationHandle, event, eventDescription, goAPIClientConfig)
+	if err != nil {
+		return wrapper.ToMobileErrorWithTrace(err, oTel)
+	}
+
+	return nil
+}
+
+func toGomobileNotificationHandles(
+	goAPINotificationHandles []*openid4cigoapi.NotificationHandle,
+) *NotificationHandlesArray {
+	notificationHandles := make([]*NotificationHandle, len(goAPINotificationHandles))
+
+	for i := range goAPINotificationHandles {
+		notificationHandles[i] = &NotificationHandle{goAPINotificationHandle: goAPINotificationHandles[i]}
+	}
+
+	return &NotificationHandlesArray{notificationHandles: notificationHandles}
+}
@@ -0,0 +1,117 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	arieskms "github.com/hyperledger/aries-framework-go/spi/kms"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/api"
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/localkms"
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/openid4ci"
+	"github.com/trustbloc/wallet-sdk/pkg/models"
+	goapiopenid4ci "github.com/trustbloc/wallet-sdk/pkg/openid4ci"
+)
+
+func TestSendNotification(t *testing.T) {
+	var credentialResponse map[string]interface{}
+
+	require.NoError(t, json.Unmarshal(sampleCredentialResponse, &credentialResponse))
+
+	credentialResponse["notification_id"] = "notificationID"
+
+	credentialResponseBytes, err := json.Marshal(credentialResponse)
+	require.NoError(t, err)
+
+	issuerServerHandler := &mockIssuerServerHandler{
+		t:                  t,
+		credentialResponse: credentialResponseBytes,
+	}
+	server := httptest.NewServer(issuerServerHandler)
+
+	defer server.Close()
+
+	issuerServerHandler.openIDConfig = &goapiopenid4ci.OpenIDConfig{
+		TokenEndpoint: fmt.Sprintf("%s/oidc/token", server.URL),
+	}
+
+	issuerServerHandler.issuerMetadata = fmt.Sprintf(`{"credential_endpoint":"%[1]s/credential",`+
+		`"notification_endpoint":"%[1]s/notification"}`, server.URL)
+
+	kms, err := localkms.NewKMS(localkms.NewMemKMSStore())
+	require.NoError(t, err)
+
+	interaction := createInteraction(t, kms, nil, createCredentialOfferIssuanceURI(t, server.URL, false),
+		nil, false)
+
+	keyHandle, err := kms.Create(arieskms.ED25519)
+	require.NoError(t, err)
+
+	pkBytes, err := keyHandle.JWK.PublicKeyBytes()
+	require.NoError(t, err)
+
+	vm := &api.VerificationMethod{
+		ID:   "did:example:12345#testId",
+		Type: "Ed25519VerificationKey2018",
+		Key:  models.VerificationKey{Raw: pkBytes},
+	}
+
+	credentials, err := interaction.RequestCredentialWithPreAuth(vm,
+		openid4ci.NewRequestCredentialWithPreAuthOpts().SetPIN("1234"))
+	require.NoError(t, err)
+	require.Equal(t, 1, credentials.Length())
+
+	notificationHandles := interaction.NotificationHandles()
+	require.Equal(t, 1, notificationHandles.Length())
+	require.Nil(t, notificationHandles.AtIndex(1))
+
+	notificationHandle := notificationHandles.AtIndex(0)
+	require.Equal(t, credentials.AtIndex(0).ID(), notificationHandle.CredentialID())
+	require.Equal(t, server.URL, notificationHandle.IssuerURI())
+	require.Equal(t, "notificationID", notificationHandle.NotificationID())
+
+	serializedNotificationHandle, err := notificationHandle.Serialize()
+	require.NoError(t, err)
+
+	notificationHandle, err = openid4ci.ParseNotificationHandle(serializedNotificationHandle)
+	require.NoError(t, err)
+
+	t.Run("Using the interaction", func(t *testing.T) {
+		err = interaction.SendNotification(notificationHandle, openid4ci.NotificationEventCredentialAccepted, "")
+		require.NoError(t, err)
+	})
+	t.Run("Standalone", func(t *testing.T) {
+		err = openid4ci.SendNotification(notificationHandle, openid4ci.NotificationEventCredentialDeleted,
+			"deleted by the user", nil)
+		require.NoError(t, err)
+	})
+
+	require.Equal(t, []string{"credential_accepted", "credential_deleted"},
+		issuerServerHandler.receivedNotificationEvents)
+
+	t.Run("Unsupported event", func(t *testing.T) {
+		err = openid4ci.SendNotification(notificationHandle, "credential_lost", "", nil)
+		requireErrorContains(t, err, "INVALID_NOTIFICATION")
+	})
+	t.Run("Notification handle not provided", func(t *testing.T) {
+		err = openid4ci.SendNotification(nil, openid4ci.NotificationEventCredentialAccepted, "", nil)
+		require.EqualError(t, err, "notification handle must be provided")
+
+		err = interaction.SendNotification(nil, openid4ci.NotificationEventCredentialAccepted, "")
+		require.EqualError(t, err, "notification handle must be provided")
+	})
+	t.Run("Invalid serialized notification handle", func(t *testing.T) {
+		parsedNotificationHandle, err := openid4ci.ParseNotificationHandle("invalid")
+		require.Error(t, err)
+		require.Nil(t, parsedNotificationHandle)
+	})
+}
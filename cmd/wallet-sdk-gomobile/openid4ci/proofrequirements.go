@@ -0,0 +1,67 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci
+
+import (
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/api"
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/wrapper"
+	openid4cigoapi "github.com/trustbloc/wallet-sdk/pkg/openid4ci"
+)
+
+// ProofRequirements describes the cryptographic binding methods and proof signing algorithms that an issuer accepts
+// for the offered credentials.
+type ProofRequirements struct {
+	goAPIProofRequirements *openid4cigoapi.ProofRequirements
+}
+
+// BindingMethods returns the accepted cryptographic binding methods, e.g. "did:key", "did:ion" or "jwk".
+// "did" means that any DID method is accepted. An empty array means that the issuer didn't restrict them.
+func (p *ProofRequirements) BindingMethods() *api.StringArray {
+	return &api.StringArray{Strings: p.goAPIProofRequirements.BindingMethods}
+}
+
+// SigningAlgorithms returns the accepted proof signing algorithms, e.g. "EdDSA" or "ES256".
+// An empty array means that the issuer didn't restrict them.
+func (p *ProofRequirements) SigningAlgorithms() *api.StringArray {
+	return &api.StringArray{Strings: p.goAPIProofRequirements.SigningAlgorithms}
+}
+
+// SupportsDIDMethod indicates whether the given DID method (e.g. "key" or "ion") is accepted.
+func (p *ProofRequirements) SupportsDIDMethod(didMethod string) bool {
+	return p.goAPIProofRequirements.SupportsDIDMethod(didMethod)
+}
+
+// SupportsBindingMethod indicates whether the given cryptographic binding method (e.g. "did:key" or "jwk") is
+// accepted. Use this to check whether credentials can be bound to a key instead of a DID
+// (see RequestCredentialWithPreAuthOpts.UseJWKBinding).
+func (p *ProofRequirements) SupportsBindingMethod(bindingMethod string) bool {
+	return p.goAPIProofRequirements.SupportsBindingMethod(bindingMethod)
+}
+
+// SupportsAlgorithm indicates whether the given proof signing algorithm (e.g. "EdDSA" or "ES256") is accepted.
+func (p *ProofRequirements) SupportsAlgorithm(algorithm string) bool {
+	return p.goAPIProofRequirements.SupportsAlgorithm(algorithm)
+}
+
+// DIDMethod returns a DID method that's accepted and that can be created using a DID Creator, or an empty string if
+// there's no such method.
+func (p *ProofRequirements) DIDMethod() string {
+	return p.goAPIProofRequirements.DIDMethod()
+}
+
+// KeyType returns a key type that can be passed in (via CreateOpts.SetKeyType) when creating a DID using a DID
+// Creator, so that the DID's key is accepted for signing proofs. An empty string is returned if the issuer didn't
+// restrict the algorithms, in which case the DID Creator's default is fine.
+// An error is returned if none of the accepted algorithms are supported.
+func (p *ProofRequirements) KeyType() (string, error) {
+	keyType, err := p.goAPIProofRequirements.KeyType()
+	if err != nil {
+		return "", wrapper.ToMobileError(err)
+	}
+
+	return string(keyType), nil
+}
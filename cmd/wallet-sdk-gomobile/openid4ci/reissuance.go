@@ -0,0 +1,156 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/api"
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/verifiable"
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/wrapper"
+	"github.com/trustbloc/wallet-sdk/pkg/common"
+	openid4cigoapi "github.com/trustbloc/wallet-sdk/pkg/openid4ci"
+)
+
+// ReissuanceToken holds a refresh token that an issuer provided along with a credential, bound to that credential
+// and issuer. It can be serialized and stored alongside the credential, and then later passed in to ReissueCredential
+// to get a new copy of the credential without needing a new credential offer.
+// The serialized form should be treated as sensitive data, as it contains a refresh token.
+type ReissuanceToken struct {
+	goAPIReissuanceToken *openid4cigoapi.ReissuanceToken
+}
+
+// ParseReissuanceToken parses the given serialized reissuance token and returns a ReissuanceToken object.
+func ParseReissuanceToken(reissuanceToken string) (*ReissuanceToken, error) {
+	var parsedReissuanceToken openid4cigoapi.ReissuanceToken
+
+	err := json.Unmarshal([]byte(reissuanceToken), &parsedReissuanceToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ReissuanceToken{goAPIReissuanceToken: &parsedReissuanceToken}, nil
+}
+
+// Serialize serializes this ReissuanceToken object into JSON.
+func (r *ReissuanceToken) Serialize() (string, error) {
+	reissuanceTokenBytes, err := json.Marshal(r.goAPIReissuanceToken)
+
+	return string(reissuanceTokenBytes), err
+}
+
+// CredentialID returns the ID of the credential that this ReissuanceToken is bound to.
+func (r *ReissuanceToken) CredentialID() string {
+	return r.goAPIReissuanceToken.CredentialID
+}
+
+// IssuerURI returns the URI of the issuer that issued the credential.
+func (r *ReissuanceToken) IssuerURI() string {
+	return r.goAPIReissuanceToken.IssuerURI
+}
+
+// ReissuanceTokensArray represents an array of ReissuanceTokens.
+// Since arrays and slices are not compatible with gomobile, this type acts as a wrapper around a Go array.
+type ReissuanceTokensArray struct {
+	reissuanceTokens []*ReissuanceToken
+}
+
+// Length returns the number of ReissuanceTokens contained within this ReissuanceTokensArray.
+func (r *ReissuanceTokensArray) Length() int {
+	return len(r.reissuanceTokens)
+}
+
+// AtIndex returns the ReissuanceToken at the given index.
+// If the index passed in is out of bounds, then nil is returned.
+func (r *ReissuanceTokensArray) AtIndex(index int) *ReissuanceToken {
+	maxIndex := len(r.reissuanceTokens) - 1
+	if index > maxIndex || index < 0 {
+		return nil
+	}
+
+	return r.reissuanceTokens[index]
+}
+
+// ReissueCredentialResult is the result of a successful credential reissuance.
+type ReissueCredentialResult struct {
+	credential      *verifiable.Credential
+	reissuanceToken *ReissuanceToken
+}
+
+// Credential returns the reissued credential.
+func (r *ReissueCredentialResult) Credential() *verifiable.Credential {
+	return r.credential
+}
+
+// ReissuanceToken returns the ReissuanceToken to use for the next reissuance. It's bound to the newly issued
+// credential and should replace the one that was used, since issuers may rotate refresh tokens.
+func (r *ReissueCredentialResult) ReissuanceToken() *ReissuanceToken {
+	return r.reissuanceToken
+}
+
+// ReissueCredential uses the refresh token in the given ReissuanceToken to get a new copy of the credential
+// it's bound to. The verification method is used to sign the proof of possession, in the same way as
+// when the credential was first requested.
+// The options are used in the same way as they are in NewInteraction.
+func ReissueCredential(args *ReissueCredentialArgs, vm *api.VerificationMethod,
+	opts *InteractionOpts,
+) (*ReissueCredentialResult, error) {
+	if args == nil {
+		return nil, errors.New("args object must be provided")
+	}
+
+	if args.reissuanceToken == nil {
+		return nil, errors.New("reissuance token must be provided")
+	}
+
+	if vm == nil {
+		return nil, errors.New("verification method must be provided")
+	}
+
+	if opts == nil {
+		opts = NewInteractionOpts()
+	}
+
+	oTel, err := createOTelTrace(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	goAPIClientConfig, err := createGoAPIClientConfig(args.didResolver, opts)
+	if err != nil {
+		return nil, wrapper.ToMobileErrorWithTrace(err, oTel)
+	}
+
+	signer, err := common.NewJWSSigner(vm.ToSDKVerificationMethod(), args.crypto)
+	if err != nil {
+		return nil, wrapper.ToMobileErrorWithTrace(err, oTel)
+	}
+
+	credential, goAPIReissuanceToken, err := openid4cigoapi.ReissueCredentialContext(opts.cancelHandle.Context(),
+		args.reissuanceToken.goAPIReissuanceToken, signer, goAPIClientConfig)
+	if err != nil {
+		return nil, wrapper.ToMobileErrorWithTrace(err, oTel)
+	}
+
+	return &ReissueCredentialResult{
+		credential:      verifiable.NewCredential(credential),
+		reissuanceToken: &ReissuanceToken{goAPIReissuanceToken: goAPIReissuanceToken},
+	}, nil
+}
+
+func toGomobileReissuanceTokens(
+	goAPIReissuanceTokens []*openid4cigoapi.ReissuanceToken,
+) *ReissuanceTokensArray {
+	reissuanceTokens := make([]*ReissuanceToken, len(goAPIReissuanceTokens))
+
+	for i := range goAPIReissuanceTokens {
+		reissuanceTokens[i] = &ReissuanceToken{goAPIReissuanceToken: goAPIReissuanceTokens[i]}
+	}
+
+	return &ReissuanceTokensArray{reissuanceTokens: reissuanceTokens}
+}
@@ -0,0 +1,124 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci_test
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	arieskms "github.com/hyperledger/aries-framework-go/spi/kms"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/api"
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/localkms"
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/openid4ci"
+	"github.com/trustbloc/wallet-sdk/pkg/models"
+	goapiopenid4ci "github.com/trustbloc/wallet-sdk/pkg/openid4ci"
+)
+
+func TestReissueCredential(t *testing.T) {
+	issuerServerHandler := &mockIssuerServerHandler{
+		t:                  t,
+		credentialResponse: sampleCredentialResponse,
+		tokenResponse: `{"access_token":"eyJhbGciOiJSUzI1NiIsInR5cCI6Ikp..sHQ","token_type":"bearer",` +
+			`"expires_in":86400,"c_nonce":"tZignsnFbp","c_nonce_expires_in":86400,"refresh_token":"refreshToken"}`,
+	}
+	server := httptest.NewServer(issuerServerHandler)
+
+	defer server.Close()
+
+	issuerServerHandler.openIDConfig = &goapiopenid4ci.OpenIDConfig{
+		TokenEndpoint: fmt.Sprintf("%s/oidc/token", server.URL),
+	}
+
+	issuerServerHandler.issuerMetadata = fmt.Sprintf(`{"credential_endpoint":"%s/credential"}`, server.URL)
+
+	kms, err := localkms.NewKMS(localkms.NewMemKMSStore())
+	require.NoError(t, err)
+
+	interaction := createInteraction(t, kms, nil, createCredentialOfferIssuanceURI(t, server.URL, false),
+		nil, false)
+
+	keyHandle, err := kms.Create(arieskms.ED25519)
+	require.NoError(t, err)
+
+	pkBytes, err := keyHandle.JWK.PublicKeyBytes()
+	require.NoError(t, err)
+
+	vm := &api.VerificationMethod{
+		ID:   "did:example:12345#testId",
+		Type: "Ed25519VerificationKey2018",
+		Key:  models.VerificationKey{Raw: pkBytes},
+	}
+
+	credentials, err := interaction.RequestCredentialWithPreAuth(vm,
+		openid4ci.NewRequestCredentialWithPreAuthOpts().SetPIN("1234"))
+	require.NoError(t, err)
+	require.Equal(t, 1, credentials.Length())
+
+	reissuanceTokens := interaction.ReissuanceTokens()
+	require.Equal(t, 1, reissuanceTokens.Length())
+	require.Nil(t, reissuanceTokens.AtIndex(1))
+
+	reissuanceToken := reissuanceTokens.AtIndex(0)
+	require.Equal(t, credentials.AtIndex(0).ID(), reissuanceToken.CredentialID())
+	require.Equal(t, server.URL, reissuanceToken.IssuerURI())
+
+	serializedReissuanceToken, err := reissuanceToken.Serialize()
+	require.NoError(t, err)
+
+	reissuanceToken, err = openid4ci.ParseReissuanceToken(serializedReissuanceToken)
+	require.NoError(t, err)
+
+	_, opts := getTestArgs(t, "", kms, nil, nil, false)
+
+	t.Run("Success", func(t *testing.T) {
+		result, err := openid4ci.ReissueCredential(
+			openid4ci.NewReissueCredentialArgs(reissuanceToken, kms.GetCrypto(), &mockResolver{keyWriter: kms}),
+			vm, opts)
+		require.NoError(t, err)
+		require.NotNil(t, result.Credential())
+		require.Equal(t, result.Credential().ID(), result.ReissuanceToken().CredentialID())
+	})
+	t.Run("Refresh token rejected", func(t *testing.T) {
+		issuerServerHandler.tokenRequestShouldFail = true
+
+		defer func() {
+			issuerServerHandler.tokenRequestShouldFail = false
+		}()
+
+		result, err := openid4ci.ReissueCredential(
+			openid4ci.NewReissueCredentialArgs(reissuanceToken, kms.GetCrypto(), &mockResolver{keyWriter: kms}),
+			vm, opts)
+		requireErrorContains(t, err, "TOKEN_FETCH_FAILED")
+		require.Nil(t, result)
+	})
+	t.Run("Args not provided", func(t *testing.T) {
+		result, err := openid4ci.ReissueCredential(nil, vm, nil)
+		require.EqualError(t, err, "args object must be provided")
+		require.Nil(t, result)
+	})
+	t.Run("Reissuance token not provided", func(t *testing.T) {
+		result, err := openid4ci.ReissueCredential(
+			openid4ci.NewReissueCredentialArgs(nil, kms.GetCrypto(), &mockResolver{keyWriter: kms}), vm, nil)
+		require.EqualError(t, err, "reissuance token must be provided")
+		require.Nil(t, result)
+	})
+	t.Run("Verification method not provided", func(t *testing.T) {
+		result, err := openid4ci.ReissueCredential(
+			openid4ci.NewReissueCredentialArgs(reissuanceToken, kms.GetCrypto(), &mockResolver{keyWriter: kms}),
+			nil, nil)
+		require.EqualError(t, err, "verification method must be provided")
+		require.Nil(t, result)
+	})
+	t.Run("Invalid serialized reissuance token", func(t *testing.T) {
+		parsedReissuanceToken, err := openid4ci.ParseReissuanceToken("invalid")
+		require.Error(t, err)
+		require.Nil(t, parsedReissuanceToken)
+	})
+}
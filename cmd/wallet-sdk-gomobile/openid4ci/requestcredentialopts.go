@@ -6,10 +6,13 @@ SPDX-License-Identifier: Apache-2.0
 
 package openid4ci
 
+import "github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/api"
+
 // RequestCredentialWithPreAuthOpts contains all optional arguments that can be passed into the
 // RequestCredentialWithPreAuth method.
 type RequestCredentialWithPreAuthOpts struct {
-	pin string
+	pin        string
+	keyBinding holderKeyBinding
 }
 
 // NewRequestCredentialWithPreAuthOpts returns a new RequestCredentialWithPreAuthOpts object.
@@ -25,6 +28,59 @@ func (r *RequestCredentialWithPreAuthOpts) SetPIN(pin string) *RequestCredential
 	return r
 }
 
+// UseJWKBinding is an option for the RequestCredentialWithPreAuth method that binds the credential(s) to the
+// verification method's key instead of a DID. The public key is sent to the issuer as a JWK in the proof JWT header,
+// so the verification method doesn't need to belong to a DID (see api.NewVerificationMethodFromJWK).
+func (r *RequestCredentialWithPreAuthOpts) UseJWKBinding() *RequestCredentialWithPreAuthOpts {
+	r.keyBinding.useJWK = true
+
+	return r
+}
+
+// SetX5CBinding is an option for the RequestCredentialWithPreAuth method that binds the credential(s) to the
+// verification method's key instead of a DID. The given X.509 certificate chain (base64-encoded DER certificates,
+// starting with the one that contains the key) is sent to the issuer in the proof JWT header.
+func (r *RequestCredentialWithPreAuthOpts) SetX5CBinding(
+	certificateChain *api.StringArray,
+) *RequestCredentialWithPreAuthOpts {
+	r.keyBinding.certificateChain = certificateChain
+
+	return r
+}
+
 // RequestCredentialWithAuthOpts contains all optional arguments that can be passed into the
 // RequestCredentialWithAuth method.
-type RequestCredentialWithAuthOpts struct{}
+type RequestCredentialWithAuthOpts struct {
+	keyBinding holderKeyBinding
+}
+
+// NewRequestCredentialWithAuthOpts returns a new RequestCredentialWithAuthOpts object.
+func NewRequestCredentialWithAuthOpts() *RequestCredentialWithAuthOpts {
+	return &RequestCredentialWithAuthOpts{}
+}
+
+// UseJWKBinding is an option for the RequestCredentialWithAuth method that binds the credential(s) to the
+// verification method's key instead of a DID. The public key is sent to the issuer as a JWK in the proof JWT header,
+// so the verification method doesn't need to belong to a DID (see api.NewVerificationMethodFromJWK).
+func (r *RequestCredentialWithAuthOpts) UseJWKBinding() *RequestCredentialWithAuthOpts {
+	r.keyBinding.useJWK = true
+
+	return r
+}
+
+// SetX5CBinding is an option for the RequestCredentialWithAuth method that binds the credential(s) to the
+// verification method's key instead of a DID. The given X.509 certificate chain (base64-encoded DER certificates,
+// starting with the one that contains the key) is sent to the issuer in the proof JWT header.
+func (r *RequestCredentialWithAuthOpts) SetX5CBinding(
+	certificateChain *api.StringArray,
+) *RequestCredentialWithAuthOpts {
+	r.keyBinding.certificateChain = certificateChain
+
+	return r
+}
+
+// holderKeyBinding holds the options for binding credentials to a key instead of a DID.
+type holderKeyBinding struct {
+	useJWK           bool
+	certificateChain *api.StringArray
+}
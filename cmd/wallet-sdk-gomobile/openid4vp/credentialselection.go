@@ -0,0 +1,48 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4vp
+
+import (
+	afgoverifiable "github.com/hyperledger/aries-framework-go/component/models/verifiable"
+
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/verifiable"
+)
+
+// CredentialSelection holds the credential that the user chose for each input descriptor.
+// It's used with the Interaction.PresentCredentialWithSelection method. For DCQL queries, it holds the credential
+// chosen for each credential query instead, and is used with the Interaction.PresentDCQLCredentials method.
+type CredentialSelection struct {
+	selection map[string]*afgoverifiable.Credential
+}
+
+// NewCredentialSelection returns a new, empty CredentialSelection.
+func NewCredentialSelection() *CredentialSelection {
+	return &CredentialSelection{selection: map[string]*afgoverifiable.Credential{}}
+}
+
+// Select sets the credential to present for the input descriptor with the given ID. The credential must be one of
+// the input descriptor's MatchedVCs, as returned by the credential.Inquirer. Selecting a credential for an input
+// descriptor that already has one replaces the earlier selection. For DCQL queries, pass in a credential query ID
+// instead.
+func (c *CredentialSelection) Select(inputDescriptorID string,
+	credential *verifiable.Credential,
+) *CredentialSelection {
+	var vc *afgoverifiable.Credential
+
+	if credential != nil {
+		vc = credential.VC
+	}
+
+	c.selection[inputDescriptorID] = vc
+
+	return c
+}
+
+// Length returns the number of input descriptors that have a credential selected.
+func (c *CredentialSelection) Length() int {
+	return len(c.selection)
+}
@@ -9,7 +9,9 @@ SPDX-License-Identifier: Apache-2.0
 package openid4vp
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/hyperledger/aries-framework-go/component/models/jwt"
@@ -24,13 +26,19 @@ import (
 	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/verifiable"
 	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/wrapper"
 	"github.com/trustbloc/wallet-sdk/pkg/common"
+	"github.com/trustbloc/wallet-sdk/pkg/credentialquery"
 	"github.com/trustbloc/wallet-sdk/pkg/openid4vp"
 )
 
 type goAPIOpenID4VP interface {
-	GetQuery() (*presexch.PresentationDefinition, error)
-	PresentCredential(credentials []*afgoverifiable.Credential) error
+	GetQueryContext(ctx context.Context) (*presexch.PresentationDefinition, error)
+	PresentCredentialContext(ctx context.Context, credentials []*afgoverifiable.Credential) error
+	PresentCredentialWithSelectionContext(ctx context.Context,
+		requirements []*presexch.MatchedSubmissionRequirement, selection map[string]*afgoverifiable.Credential) error
 	VerifierDisplayData() (*openid4vp.VerifierDisplayData, error)
+	QueryLanguage() string
+	DCQLQuery() *credentialquery.DCQLQuery
+	PresentDCQLCredentialsContext(ctx context.Context, selection map[string]*afgoverifiable.Credential) error
 }
 
 // Interaction represents a single OpenID4VP interaction between a wallet and a verifier. The methods defined on this
@@ -42,6 +50,7 @@ type Interaction struct {
 	didResolver      api.DIDResolver
 	inquirer         *credential.Inquirer
 	oTel             *otel.Trace
+	cancelHandle     *api.CancelHandle
 }
 
 // NewInteraction creates a new OpenID4VP Interaction.
@@ -87,6 +96,25 @@ func NewInteraction(args *Args, opts *Opts) (*Interaction, error) { //nolint:fun
 		goAPIOpts = append(goAPIOpts, openid4vp.WithMetricsLogger(mobileMetricsLoggerWrapper))
 	}
 
+	if opts.holderKey != nil {
+		holderSigner, err := common.NewJWKBoundSigner(api.NewVerificationMethodFromJWK(opts.holderKey).
+			ToSDKVerificationMethod(), args.crypto)
+		if err != nil {
+			return nil, wrapper.ToMobileErrorWithTrace(err, oTel)
+		}
+
+		goAPIOpts = append(goAPIOpts, openid4vp.WithHolderSigner(holderSigner))
+	}
+
+	if len(opts.x509TrustAnchors) > 0 {
+		trustAnchors, err := parseX509TrustAnchors(opts.x509TrustAnchors)
+		if err != nil {
+			return nil, wrapper.ToMobileErrorWithTrace(err, oTel)
+		}
+
+		goAPIOpts = append(goAPIOpts, openid4vp.WithX509TrustAnchors(trustAnchors...))
+	}
+
 	var goAPIDocumentLoader ld.DocumentLoader
 
 	if opts.documentLoader != nil {
@@ -122,19 +150,32 @@ func NewInteraction(args *Args, opts *Opts) (*Interaction, error) { //nolint:fun
 			goAPIDocumentLoader,
 			goAPIOpts...,
 		),
-		didResolver: args.didRes,
-		inquirer:    inquirer,
-		oTel:        oTel,
+		didResolver:  args.didRes,
+		inquirer:     inquirer,
+		oTel:         oTel,
+		cancelHandle: opts.cancelHandle,
 	}, nil
 }
 
-// GetQuery creates query based on authorization request data.
+// GetQuery creates query based on authorization request data. The query is a presentation definition, unless the
+// verifier sent a DCQL query instead, in which case the DCQL query is returned. Use QueryLanguage to find out which
+// one was returned.
 func (o *Interaction) GetQuery() ([]byte, error) {
-	presentationDefinition, err := o.goAPIOpenID4VP.GetQuery()
+	presentationDefinition, err := o.goAPIOpenID4VP.GetQueryContext(o.cancelHandle.Context())
 	if err != nil {
 		return nil, wrapper.ToMobileErrorWithTrace(err, o.oTel)
 	}
 
+	if o.goAPIOpenID4VP.QueryLanguage() == openid4vp.QueryLanguageDCQL {
+		dcqlQueryBytes, marshalErr := json.Marshal(o.goAPIOpenID4VP.DCQLQuery())
+		if marshalErr != nil {
+			return nil, wrapper.ToMobileErrorWithTrace(
+				fmt.Errorf("DCQL query marshal: %w", marshalErr), o.oTel)
+		}
+
+		return dcqlQueryBytes, nil
+	}
+
 	pdBytes, err := json.Marshal(presentationDefinition)
 	if err != nil {
 		return nil, wrapper.ToMobileErrorWithTrace(
@@ -144,6 +185,14 @@ func (o *Interaction) GetQuery() ([]byte, error) {
 	return pdBytes, nil
 }
 
+// QueryLanguage returns the query language of the query returned by GetQuery: either "presentation_exchange" for a
+// presentation definition (which is used with credential.Inquirer.GetSubmissionRequirements and PresentCredential), or
+// "dcql" for a DCQL query (which is used with credential.Inquirer.GetDCQLMatches and PresentDCQLCredentials).
+// It returns an empty string if GetQuery hasn't been called yet.
+func (o *Interaction) QueryLanguage() string {
+	return o.goAPIOpenID4VP.QueryLanguage()
+}
+
 // VerifierDisplayData returns display information about verifier.
 func (o *Interaction) VerifierDisplayData() (*VerifierDisplayData, error) {
 	displayData, err := o.goAPIOpenID4VP.VerifierDisplayData()
@@ -156,7 +205,45 @@ func (o *Interaction) VerifierDisplayData() (*VerifierDisplayData, error) {
 
 // PresentCredential presents credentials to redirect uri from request object.
 func (o *Interaction) PresentCredential(credentials *verifiable.CredentialsArray) error {
-	return wrapper.ToMobileErrorWithTrace(o.goAPIOpenID4VP.PresentCredential(unwrapVCs(credentials)), o.oTel)
+	err := o.goAPIOpenID4VP.PresentCredentialContext(o.cancelHandle.Context(), unwrapVCs(credentials))
+
+	return wrapper.ToMobileErrorWithTrace(err, o.oTel)
+}
+
+// PresentCredentialWithSelection presents credentials to redirect uri from request object, using the credential that
+// the user chose for each input descriptor. The requirements must be the ones returned from
+// credential.Inquirer.GetSubmissionRequirements for the query from GetQuery, and each selected credential must be
+// one of the matched VCs for its input descriptor. If the selection doesn't satisfy the submission requirements,
+// then an INVALID_CREDENTIAL_SELECTION error is returned.
+func (o *Interaction) PresentCredentialWithSelection(requirements *credential.SubmissionRequirementArray,
+	selection *CredentialSelection,
+) error {
+	if requirements == nil {
+		return wrapper.ToMobileErrorWithTrace(errors.New("submission requirements must be provided"), o.oTel)
+	}
+
+	if selection == nil {
+		return wrapper.ToMobileErrorWithTrace(errors.New("credential selection must be provided"), o.oTel)
+	}
+
+	err := o.goAPIOpenID4VP.PresentCredentialWithSelectionContext(o.cancelHandle.Context(),
+		requirements.GoAPISubmissionRequirements(), selection.selection)
+
+	return wrapper.ToMobileErrorWithTrace(err, o.oTel)
+}
+
+// PresentDCQLCredentials presents credentials in response to a DCQL query. The selection holds the credential that
+// the user chose for each credential query, keyed by credential query ID, where each credential is one of the
+// MatchedVCs returned by credential.Inquirer.GetDCQLMatches. If the selection doesn't satisfy the DCQL query, then
+// an INVALID_CREDENTIAL_SELECTION error is returned.
+func (o *Interaction) PresentDCQLCredentials(selection *CredentialSelection) error {
+	if selection == nil {
+		return wrapper.ToMobileErrorWithTrace(errors.New("credential selection must be provided"), o.oTel)
+	}
+
+	err := o.goAPIOpenID4VP.PresentDCQLCredentialsContext(o.cancelHandle.Context(), selection.selection)
+
+	return wrapper.ToMobileErrorWithTrace(err, o.oTel)
 }
 
 // OTelTraceID returns open telemetry trace id.
@@ -8,26 +8,38 @@ SPDX-License-Identifier: Apache-2.0
 package openid4vp //nolint: testpackage
 
 import (
+	"context"
+	"crypto/ecdsa"
 	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	_ "embed" //nolint:gci // required for go:embed
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/hyperledger/aries-framework-go/component/models/did"
 	"github.com/hyperledger/aries-framework-go/component/models/presexch"
 	afgoverifiable "github.com/hyperledger/aries-framework-go/component/models/verifiable"
+	arieskms "github.com/hyperledger/aries-framework-go/spi/kms"
 	"github.com/piprate/json-gold/ld"
 	"github.com/stretchr/testify/require"
 
 	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/api"
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/credential"
+	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/localkms"
 	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/verifiable"
 	"github.com/trustbloc/wallet-sdk/internal/testutil"
+	"github.com/trustbloc/wallet-sdk/pkg/credentialquery"
 	"github.com/trustbloc/wallet-sdk/pkg/models"
 	"github.com/trustbloc/wallet-sdk/pkg/openid4vp"
 )
@@ -111,6 +123,44 @@ func TestOpenID4VP_GetQuery(t *testing.T) {
 			require.NotNil(t, instance)
 			require.NotEmpty(t, instance.OTelTraceID())
 		})
+		t.Run("With holder key", func(t *testing.T) {
+			kms, err := localkms.NewKMS(localkms.NewMemKMSStore())
+			require.NoError(t, err)
+
+			holderKey, err := kms.Create(arieskms.ED25519)
+			require.NoError(t, err)
+
+			instance, err := NewInteraction(NewArgs(requestObjectJWT, kms.GetCrypto(), &mocksDIDResolver{}),
+				NewOpts().SetHolderKey(holderKey))
+			require.NoError(t, err)
+			require.NotNil(t, instance)
+		})
+		t.Run("With X.509 trust anchor", func(t *testing.T) {
+			instance, err := NewInteraction(NewArgs(requestObjectJWT, &mockCrypto{}, &mocksDIDResolver{}),
+				NewOpts().AddX509TrustAnchor(newTestRootCertificate(t)))
+			require.NoError(t, err)
+			require.NotNil(t, instance)
+		})
+	})
+	t.Run("NewInteraction failure: invalid holder key", func(t *testing.T) {
+		instance, err := NewInteraction(NewArgs(requestObjectJWT, &mockCrypto{}, &mocksDIDResolver{}),
+			NewOpts().SetHolderKey(&api.JSONWebKey{}))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "UNSUPPORTED_ALGORITHM")
+		require.Nil(t, instance)
+	})
+	t.Run("NewInteraction failure: invalid X.509 trust anchor", func(t *testing.T) {
+		instance, err := NewInteraction(NewArgs(requestObjectJWT, &mockCrypto{}, &mocksDIDResolver{}),
+			NewOpts().AddX509TrustAnchor(newTestRootCertificate(t)).AddX509TrustAnchor("not base64"))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "decode X.509 trust anchor 1")
+		require.Nil(t, instance)
+
+		instance, err = NewInteraction(NewArgs(requestObjectJWT, &mockCrypto{}, &mocksDIDResolver{}),
+			NewOpts().AddX509TrustAnchor(base64.StdEncoding.EncodeToString([]byte("not a certificate"))))
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "parse X.509 trust anchor 0")
+		require.Nil(t, instance)
 	})
 
 	t.Run("GetQuery success", func(t *testing.T) {
@@ -174,6 +224,23 @@ func TestOpenID4VP_GetQuery(t *testing.T) {
 			`parse JWT: JWT of compacted JWS form is supported only"`)
 		require.Nil(t, query)
 	})
+
+	t.Run("Cancelled", func(t *testing.T) {
+		testServer := httptest.NewServer(&mockVerifierServerHandler{t: t})
+		defer testServer.Close()
+
+		cancelHandle := api.NewCancelHandle()
+		cancelHandle.Cancel()
+
+		instance, err := NewInteraction(
+			NewArgs("openid-vc://?request_uri="+testServer.URL, &mockCrypto{}, &mocksDIDResolver{}),
+			NewOpts().SetCancelHandle(cancelHandle))
+		require.NoError(t, err)
+
+		query, err := instance.GetQuery()
+		require.Contains(t, err.Error(), "context canceled")
+		require.Nil(t, query)
+	})
 }
 
 func TestOpenID4VP_PresentCredential(t *testing.T) {
@@ -232,15 +299,141 @@ func TestOpenID4VP_PresentCredential(t *testing.T) {
 	})
 }
 
+func TestOpenID4VP_PresentCredentialWithSelection(t *testing.T) {
+	credentials := verifiable.NewCredentialsArray()
+
+	credentialData := []json.RawMessage{}
+
+	require.NoError(t, json.Unmarshal(credentialsJSONLD, &credentialData))
+
+	for _, credBytes := range credentialData {
+		cred, err := afgoverifiable.ParseCredential(credBytes,
+			afgoverifiable.WithDisabledProofCheck(), afgoverifiable.WithCredDisableValidation())
+		require.NoError(t, err)
+
+		credentials.Add(verifiable.NewCredential(cred))
+	}
+
+	inquirerOpts := credential.NewInquirerOpts()
+	inquirerOpts.SetDocumentLoader(&documentLoaderWrapper{goAPIDocumentLoader: testutil.DocumentLoader(t)})
+
+	inquirer, err := credential.NewInquirer(inquirerOpts)
+	require.NoError(t, err)
+
+	requirements, err := inquirer.GetSubmissionRequirements(
+		[]byte(`{"id":"pd","input_descriptors":[{"id":"any","constraints":{"fields":[{"path":["$.id"]}]}}]}`),
+		credentials)
+	require.NoError(t, err)
+
+	chosenVC := requirements.AtIndex(0).DescriptorAtIndex(0).MatchedVCs.AtIndex(0)
+
+	t.Run("Success", func(t *testing.T) {
+		goAPIInteraction := &mocGoAPIInteraction{}
+
+		instance := &Interaction{goAPIOpenID4VP: goAPIInteraction}
+
+		selection := NewCredentialSelection().Select("any", chosenVC)
+		require.Equal(t, 1, selection.Length())
+
+		err := instance.PresentCredentialWithSelection(requirements, selection)
+		require.NoError(t, err)
+		require.Equal(t, requirements.GoAPISubmissionRequirements(), goAPIInteraction.ReceivedRequirements)
+		require.Equal(t, map[string]*afgoverifiable.Credential{"any": chosenVC.VC}, goAPIInteraction.ReceivedSelection)
+	})
+
+	t.Run("Present credentials failed", func(t *testing.T) {
+		instance := &Interaction{goAPIOpenID4VP: &mocGoAPIInteraction{
+			PresentCredentialErr: errors.New("present credentials failed"),
+		}}
+
+		err := instance.PresentCredentialWithSelection(requirements, NewCredentialSelection().Select("any", chosenVC))
+		require.Contains(t, err.Error(), "present credentials failed")
+	})
+
+	t.Run("Missing arguments", func(t *testing.T) {
+		instance := &Interaction{goAPIOpenID4VP: &mocGoAPIInteraction{}}
+
+		err := instance.PresentCredentialWithSelection(nil, NewCredentialSelection())
+		require.Contains(t, err.Error(), "submission requirements must be provided")
+
+		err = instance.PresentCredentialWithSelection(requirements, nil)
+		require.Contains(t, err.Error(), "credential selection must be provided")
+	})
+}
+
+func TestOpenID4VP_DCQL(t *testing.T) {
+	dcqlQuery := &credentialquery.DCQLQuery{Credentials: []*credentialquery.DCQLCredentialQuery{
+		{ID: "degree", Format: credentialquery.DCQLFormatJWTVCJSON},
+	}}
+
+	t.Run("GetQuery returns the DCQL query", func(t *testing.T) {
+		instance := &Interaction{goAPIOpenID4VP: &mocGoAPIInteraction{
+			QueryLanguageRes: openid4vp.QueryLanguageDCQL,
+			DCQLQueryRes:     dcqlQuery,
+		}}
+
+		query, err := instance.GetQuery()
+		require.NoError(t, err)
+		require.Equal(t, openid4vp.QueryLanguageDCQL, instance.QueryLanguage())
+
+		parsedQuery, err := credentialquery.ParseDCQLQuery(query)
+		require.NoError(t, err)
+		require.Equal(t, dcqlQuery, parsedQuery)
+	})
+
+	t.Run("GetQuery returns the presentation definition", func(t *testing.T) {
+		instance := &Interaction{goAPIOpenID4VP: &mocGoAPIInteraction{
+			QueryLanguageRes: openid4vp.QueryLanguagePresentationExchange,
+			GetQueryResult:   &presexch.PresentationDefinition{ID: "pd"},
+		}}
+
+		query, err := instance.GetQuery()
+		require.NoError(t, err)
+		require.Equal(t, openid4vp.QueryLanguagePresentationExchange, instance.QueryLanguage())
+		require.Contains(t, string(query), `"id":"pd"`)
+	})
+
+	t.Run("PresentDCQLCredentials", func(t *testing.T) {
+		credentialData := []json.RawMessage{}
+
+		require.NoError(t, json.Unmarshal(credentialsJSONLD, &credentialData))
+
+		cred, err := afgoverifiable.ParseCredential(credentialData[0],
+			afgoverifiable.WithDisabledProofCheck(), afgoverifiable.WithCredDisableValidation())
+		require.NoError(t, err)
+
+		goAPIInteraction := &mocGoAPIInteraction{}
+
+		instance := &Interaction{goAPIOpenID4VP: goAPIInteraction}
+
+		err = instance.PresentDCQLCredentials(
+			NewCredentialSelection().Select("degree", verifiable.NewCredential(cred)))
+		require.NoError(t, err)
+		require.Equal(t, map[string]*afgoverifiable.Credential{"degree": cred}, goAPIInteraction.ReceivedSelection)
+
+		err = instance.PresentDCQLCredentials(nil)
+		require.Contains(t, err.Error(), "credential selection must be provided")
+
+		instance = &Interaction{goAPIOpenID4VP: &mocGoAPIInteraction{
+			PresentCredentialErr: errors.New("present credentials failed"),
+		}}
+
+		err = instance.PresentDCQLCredentials(NewCredentialSelection())
+		require.Contains(t, err.Error(), "present credentials failed")
+	})
+}
+
 func TestInteraction_VerifierDisplayData(t *testing.T) {
 	t.Run("Success", func(t *testing.T) {
 		instance := &Interaction{
 			goAPIOpenID4VP: &mocGoAPIInteraction{
 				VerifierDisplayDataRes: &openid4vp.VerifierDisplayData{
-					DID:     "DID",
-					Name:    "testName",
-					Purpose: "purpose",
-					LogoURI: "logoURI",
+					DID:              "DID",
+					Name:             "testName",
+					Purpose:          "purpose",
+					LogoURI:          "logoURI",
+					ClientIDScheme:   "did",
+					ClientIDVerified: true,
 				},
 			},
 		}
@@ -252,6 +445,8 @@ func TestInteraction_VerifierDisplayData(t *testing.T) {
 		require.Equal(t, "testName", data.Name())
 		require.Equal(t, "purpose", data.Purpose())
 		require.Equal(t, "logoURI", data.LogoURI())
+		require.Equal(t, "did", data.ClientIDScheme())
+		require.True(t, data.ClientIDVerified())
 	})
 
 	t.Run("Error", func(t *testing.T) {
@@ -309,13 +504,26 @@ type mocGoAPIInteraction struct {
 	PresentCredentialErr     error
 	VerifierDisplayDataRes   *openid4vp.VerifierDisplayData
 	VerifierDisplayDataError error
+	ReceivedRequirements     []*presexch.MatchedSubmissionRequirement
+	ReceivedSelection        map[string]*afgoverifiable.Credential
+	QueryLanguageRes         string
+	DCQLQueryRes             *credentialquery.DCQLQuery
 }
 
-func (o *mocGoAPIInteraction) GetQuery() (*presexch.PresentationDefinition, error) {
+func (o *mocGoAPIInteraction) GetQueryContext(context.Context) (*presexch.PresentationDefinition, error) {
 	return o.GetQueryResult, o.GetQueryError
 }
 
-func (o *mocGoAPIInteraction) PresentCredential(credentials []*afgoverifiable.Credential) error {
+func (o *mocGoAPIInteraction) PresentCredentialContext(context.Context, []*afgoverifiable.Credential) error {
+	return o.PresentCredentialErr
+}
+
+func (o *mocGoAPIInteraction) PresentCredentialWithSelectionContext(_ context.Context,
+	requirements []*presexch.MatchedSubmissionRequirement, selection map[string]*afgoverifiable.Credential,
+) error {
+	o.ReceivedRequirements = requirements
+	o.ReceivedSelection = selection
+
 	return o.PresentCredentialErr
 }
 
@@ -323,6 +531,22 @@ func (o *mocGoAPIInteraction) VerifierDisplayData() (*openid4vp.VerifierDisplayD
 	return o.VerifierDisplayDataRes, o.VerifierDisplayDataError
 }
 
+func (o *mocGoAPIInteraction) QueryLanguage() string {
+	return o.QueryLanguageRes
+}
+
+func (o *mocGoAPIInteraction) DCQLQuery() *credentialquery.DCQLQuery {
+	return o.DCQLQueryRes
+}
+
+func (o *mocGoAPIInteraction) PresentDCQLCredentialsContext(_ context.Context,
+	selection map[string]*afgoverifiable.Credential,
+) error {
+	o.ReceivedSelection = selection
+
+	return o.PresentCredentialErr
+}
+
 type mocksDIDResolver struct {
 	ResolveDocBytes []byte
 	ResolveErr      error
@@ -380,3 +604,26 @@ func mockResolution(t *testing.T, vm *api.VerificationMethod) []byte {
 
 	return docBytes
 }
+
+// newTestRootCertificate returns a self-signed CA certificate as a base64-encoded DER string.
+func newTestRootCertificate(t *testing.T) string {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test Root CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+
+	certificateDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	return base64.StdEncoding.EncodeToString(certificateDER)
+}
@@ -7,6 +7,9 @@ SPDX-License-Identifier: Apache-2.0
 package openid4vp
 
 import (
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
 	"time"
 
 	"github.com/trustbloc/wallet-sdk/cmd/wallet-sdk-gomobile/api"
@@ -21,6 +24,9 @@ type Opts struct {
 	disableHTTPClientTLSVerification bool
 	disableOpenTelemetry             bool
 	httpTimeout                      *time.Duration
+	holderKey                        *api.JSONWebKey
+	cancelHandle                     *api.CancelHandle
+	x509TrustAnchors                 []string
 }
 
 // NewOpts returns a new Opts object.
@@ -49,6 +55,16 @@ func (o *Opts) SetMetricsLogger(metricsLogger api.MetricsLogger) *Opts {
 	return o
 }
 
+// SetHolderKey sets a key (e.g. one created using localkms.KMS) to sign with when presenting credentials that are
+// bound to a key rather than a DID, i.e. credentials that were issued using the UseJWKBinding or SetX5CBinding options
+// of the OpenID4CI RequestCredential methods. The public key is sent to the verifier as a JWK. Credentials that are
+// bound to a DID are still signed using the DID's key.
+func (o *Opts) SetHolderKey(key *api.JSONWebKey) *Opts {
+	o.holderKey = key
+
+	return o
+}
+
 // SetHTTPTimeoutNanoseconds sets the timeout (in nanoseconds) for HTTP calls.
 // Passing in 0 will disable timeouts.
 func (o *Opts) SetHTTPTimeoutNanoseconds(timeout int64) *Opts {
@@ -89,3 +105,39 @@ func (o *Opts) DisableOpenTelemetry() *Opts {
 
 	return o
 }
+
+// SetCancelHandle sets a CancelHandle that can be used to abort the network operations made during the OpenID4VP
+// flow. Cancelling it aborts any in-progress HTTP requests and DID resolutions. Once it's been cancelled, the
+// Interaction can no longer make network calls, so a new one must be created to try again.
+func (o *Opts) SetCancelHandle(cancelHandle *api.CancelHandle) *Opts {
+	o.cancelHandle = cancelHandle
+
+	return o
+}
+
+// AddX509TrustAnchor adds a root certificate (a base64-encoded DER certificate) to trust when verifying the X.509
+// certificate chains of verifiers that use the x509_san_dns, x509_san_uri or verifier_attestation client ID schemes.
+// Authorization requests from such verifiers are rejected if no trust anchors are added.
+func (o *Opts) AddX509TrustAnchor(certificate string) *Opts {
+	o.x509TrustAnchors = append(o.x509TrustAnchors, certificate)
+
+	return o
+}
+
+func parseX509TrustAnchors(certificates []string) ([]*x509.Certificate, error) {
+	trustAnchors := make([]*x509.Certificate, len(certificates))
+
+	for i, certificate := range certificates {
+		certificateDER, err := base64.StdEncoding.DecodeString(certificate)
+		if err != nil {
+			return nil, fmt.Errorf("decode X.509 trust anchor %d: %w", i, err)
+		}
+
+		trustAnchors[i], err = x509.ParseCertificate(certificateDER)
+		if err != nil {
+			return nil, fmt.Errorf("parse X.509 trust anchor %d: %w", i, err)
+		}
+	}
+
+	return trustAnchors, nil
+}
@@ -32,3 +32,15 @@ func (v *VerifierDisplayData) Purpose() string {
 func (v *VerifierDisplayData) LogoURI() string {
 	return v.displayData.LogoURI
 }
+
+// ClientIDScheme returns the client ID scheme that the verifier used (e.g. "did" or "x509_san_dns"). It's empty if the
+// verifier didn't specify one.
+func (v *VerifierDisplayData) ClientIDScheme() string {
+	return v.displayData.ClientIDScheme
+}
+
+// ClientIDVerified indicates whether the verifier's client ID was proven by the request object's signature. It's
+// always false for the redirect_uri client ID scheme, since those requests are unsigned.
+func (v *VerifierDisplayData) ClientIDVerified() bool {
+	return v.displayData.ClientIDVerified
+}
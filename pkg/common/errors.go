@@ -9,9 +9,10 @@ package common
 // Constants' names and reasons are obvious so they do not require additional comments.
 // nolint:golint,nolintlint
 const (
-	module                    = "COM"
-	UnsupportedAlgorithmError = "UNSUPPORTED_ALGORITHM"
-	NoCryptoProvidedError     = "NO_CRYPTO_PROVIDED"
+	module                          = "COM"
+	UnsupportedAlgorithmError       = "UNSUPPORTED_ALGORITHM"
+	NoCryptoProvidedError           = "NO_CRYPTO_PROVIDED"
+	NoCertificateChainProvidedError = "NO_CERTIFICATE_CHAIN_PROVIDED"
 )
 
 // Constants' names and reasons are obvious so they do not require additional comments.
@@ -19,4 +20,5 @@ const (
 const (
 	UnsupportedAlgorithmCode = iota
 	NoCryptoProvidedCode
+	NoCertificateChainProvidedCode
 )
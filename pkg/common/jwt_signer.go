@@ -8,12 +8,14 @@ package common
 
 import (
 	cryptolib "crypto"
+	"crypto/ed25519"
 	"encoding/base64"
 	"errors"
 	"fmt"
 
 	"github.com/hyperledger/aries-framework-go/component/kmscrypto/doc/jose"
 	"github.com/hyperledger/aries-framework-go/component/kmscrypto/doc/jose/jwk"
+	"github.com/hyperledger/aries-framework-go/component/kmscrypto/doc/jose/jwk/jwksupport"
 	"github.com/hyperledger/aries-framework-go/component/kmscrypto/doc/util/jwkkid"
 	"github.com/hyperledger/aries-framework-go/component/models/verifiable"
 	"github.com/hyperledger/aries-framework-go/spi/kms"
@@ -40,6 +42,10 @@ type JWSSigner struct {
 	algorithm string
 	cryptoKID string
 	crypto    api.Crypto
+
+	// Set for signers whose key isn't associated with a DID. See NewJWKBoundSigner and NewX5CBoundSigner.
+	publicKeyJWK     *jwk.JWK
+	certificateChain []string
 }
 
 // NewJWSSigner creates jwt signer.
@@ -71,6 +77,78 @@ func NewJWSSigner(vm *models.VerificationMethod, crypto api.Crypto) (*JWSSigner,
 	}, nil
 }
 
+// NewJWKBoundSigner creates a jwt signer for a key that isn't associated with a DID (e.g. a bare key created using
+// localkms.LocalKMS). Instead of a DID URL key ID, the public key is put in the JWS header as a JWK, which allows
+// credentials to be bound directly to the key. The verification method's ID is ignored, and the signer's key ID
+// is the JWK thumbprint of the key.
+func NewJWKBoundSigner(vm *models.VerificationMethod, crypto api.Crypto) (*JWSSigner, error) {
+	publicKeyJWK, err := PublicKeyJWK(vm)
+	if err != nil {
+		return nil, walleterror.NewValidationError(
+			module,
+			UnsupportedAlgorithmCode,
+			UnsupportedAlgorithmError,
+			err,
+		)
+	}
+
+	signer, err := NewJWSSigner(vm, crypto)
+	if err != nil {
+		return nil, err
+	}
+
+	signer.keyID = signer.cryptoKID
+	signer.publicKeyJWK = publicKeyJWK
+
+	return signer, nil
+}
+
+// NewX5CBoundSigner creates a jwt signer for a key that isn't associated with a DID but is certified by the given
+// X.509 certificate chain. The chain (base64-encoded DER certificates, starting with the one containing the key) is
+// put in the JWS header as x5c instead of a key ID. The verification method's ID is ignored, and the signer's key ID
+// is the JWK thumbprint of the key.
+func NewX5CBoundSigner(vm *models.VerificationMethod, certificateChain []string, crypto api.Crypto,
+) (*JWSSigner, error) {
+	if len(certificateChain) == 0 {
+		return nil, walleterror.NewValidationError(
+			module,
+			NoCertificateChainProvidedCode,
+			NoCertificateChainProvidedError,
+			errors.New("certificate chain should be provided"),
+		)
+	}
+
+	signer, err := NewJWKBoundSigner(vm, crypto)
+	if err != nil {
+		return nil, err
+	}
+
+	signer.certificateChain = certificateChain
+
+	return signer, nil
+}
+
+// PublicKeyJWK returns the public key of the given verification method as a JWK. The same verification method
+// types as NewJWSSigner are supported.
+func PublicKeyJWK(vm *models.VerificationMethod) (*jwk.JWK, error) {
+	switch vm.Type {
+	case Ed25519VerificationKey2018:
+		if len(vm.Key.Raw) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid raw public key for %s verification method", Ed25519VerificationKey2018)
+		}
+
+		return jwksupport.JWKFromKey(ed25519.PublicKey(vm.Key.Raw))
+	case JSONWebKey2020:
+		if vm.Key.JSONWebKey == nil {
+			return nil, fmt.Errorf("missing jwk for %s verification method", JSONWebKey2020)
+		}
+
+		return vm.Key.JSONWebKey, nil
+	default:
+		return nil, fmt.Errorf("verification method type '%s' not supported", vm.Type)
+	}
+}
+
 // returns: alg, thumbprint, error
 func algAndThumbprint(vm *models.VerificationMethod) (string, string, error) {
 	if vm.Type == Ed25519VerificationKey2018 {
@@ -129,8 +207,23 @@ func (s *JWSSigner) Sign(data []byte) ([]byte, error) {
 	return s.crypto.Sign(data, s.cryptoKID)
 }
 
-// Headers provides JWS headers.
+// Headers provides JWS headers. For signers whose key isn't associated with a DID, the header identifies the key
+// using x5c or jwk instead of kid.
 func (s *JWSSigner) Headers() jose.Headers {
+	if len(s.certificateChain) > 0 {
+		return jose.Headers{
+			jose.HeaderX509CertificateChain: s.certificateChain,
+			jose.HeaderAlgorithm:            s.algorithm,
+		}
+	}
+
+	if s.publicKeyJWK != nil {
+		return jose.Headers{
+			jose.HeaderJSONWebKey: s.publicKeyJWK,
+			jose.HeaderAlgorithm:  s.algorithm,
+		}
+	}
+
 	return jose.Headers{
 		jose.HeaderKeyID:     s.keyID,
 		jose.HeaderAlgorithm: s.algorithm,
@@ -14,6 +14,7 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/hyperledger/aries-framework-go/component/kmscrypto/doc/jose"
 	"github.com/hyperledger/aries-framework-go/component/kmscrypto/doc/jose/jwk"
 	"github.com/hyperledger/aries-framework-go/component/kmscrypto/doc/jose/jwk/jwksupport"
 	"github.com/hyperledger/aries-framework-go/component/kmscrypto/doc/util/jwkkid"
@@ -187,6 +188,117 @@ func TestJWSSigner_Sign(t *testing.T) {
 	})
 }
 
+func TestNewJWKBoundSigner(t *testing.T) {
+	mockKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	t.Run("Success", func(t *testing.T) {
+		signer, err := common.NewJWKBoundSigner(
+			&models.VerificationMethod{
+				Type: common.Ed25519VerificationKey2018,
+				Key:  models.VerificationKey{Raw: mockKey},
+			},
+			&cryptoMock{})
+		require.NoError(t, err)
+
+		thumbprint, err := jwkkid.CreateKID(mockKey, kms.ED25519Type)
+		require.NoError(t, err)
+
+		require.Equal(t, thumbprint, signer.GetKeyID())
+		require.NotContains(t, signer.Headers(), "kid")
+		require.Equal(t, "EdDSA", signer.Headers()["alg"])
+
+		publicKeyJWK, ok := signer.Headers()["jwk"].(*jwk.JWK)
+		require.True(t, ok)
+		require.Equal(t, ed25519.PublicKey(mockKey), publicKeyJWK.Key)
+	})
+	t.Run("Unsupported verification method", func(t *testing.T) {
+		signer, err := common.NewJWKBoundSigner(&models.VerificationMethod{Type: "Invalid"}, &cryptoMock{})
+		require.EqualError(t, err, "UNSUPPORTED_ALGORITHM(COM0-0000):verification method type 'Invalid' not supported")
+		require.Nil(t, signer)
+	})
+	t.Run("Missing crypto", func(t *testing.T) {
+		signer, err := common.NewJWKBoundSigner(
+			&models.VerificationMethod{
+				Type: common.Ed25519VerificationKey2018,
+				Key:  models.VerificationKey{Raw: mockKey},
+			},
+			nil)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "NO_CRYPTO_PROVIDED")
+		require.Nil(t, signer)
+	})
+}
+
+func TestNewX5CBoundSigner(t *testing.T) {
+	vm := &models.VerificationMethod{Type: common.JSONWebKey2020, Key: models.VerificationKey{JSONWebKey: getECKey(t)}}
+
+	t.Run("Success", func(t *testing.T) {
+		signer, err := common.NewX5CBoundSigner(vm, []string{"leafCert", "intermediateCert"}, &cryptoMock{})
+		require.NoError(t, err)
+
+		require.NotEmpty(t, signer.GetKeyID())
+		require.Equal(t, jose.Headers{
+			"x5c": []string{"leafCert", "intermediateCert"},
+			"alg": "ES384",
+		}, signer.Headers())
+	})
+	t.Run("Missing certificate chain", func(t *testing.T) {
+		signer, err := common.NewX5CBoundSigner(vm, nil, &cryptoMock{})
+		require.EqualError(t, err, "NO_CERTIFICATE_CHAIN_PROVIDED(COM0-0002):certificate chain should be provided")
+		require.Nil(t, signer)
+	})
+	t.Run("Unsupported verification method", func(t *testing.T) {
+		signer, err := common.NewX5CBoundSigner(&models.VerificationMethod{Type: common.JSONWebKey2020},
+			[]string{"leafCert"}, &cryptoMock{})
+		require.EqualError(t, err, "UNSUPPORTED_ALGORITHM(COM0-0000):missing jwk for JsonWebKey2020 verification method")
+		require.Nil(t, signer)
+	})
+}
+
+func TestPublicKeyJWK(t *testing.T) {
+	mockKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	t.Run("VM type "+common.Ed25519VerificationKey2018, func(t *testing.T) {
+		publicJWK, err := common.PublicKeyJWK(&models.VerificationMethod{
+			Type: common.Ed25519VerificationKey2018,
+			Key:  models.VerificationKey{Raw: mockKey},
+		})
+		require.NoError(t, err)
+		require.Equal(t, ed25519.PublicKey(mockKey), publicJWK.Key)
+	})
+	t.Run("VM type "+common.JSONWebKey2020, func(t *testing.T) {
+		mockJWK, err := jwkkid.BuildJWK(mockKey, kms.ED25519Type)
+		require.NoError(t, err)
+
+		publicJWK, err := common.PublicKeyJWK(&models.VerificationMethod{
+			Type: common.JSONWebKey2020,
+			Key:  models.VerificationKey{JSONWebKey: mockJWK},
+		})
+		require.NoError(t, err)
+		require.Equal(t, mockJWK, publicJWK)
+	})
+	t.Run("Invalid raw key", func(t *testing.T) {
+		publicJWK, err := common.PublicKeyJWK(&models.VerificationMethod{
+			Type: common.Ed25519VerificationKey2018,
+			Key:  models.VerificationKey{Raw: []byte("invalid")},
+		})
+		require.EqualError(t, err, "invalid raw public key for Ed25519VerificationKey2018 verification method")
+		require.Nil(t, publicJWK)
+	})
+	t.Run("Missing JWK", func(t *testing.T) {
+		publicJWK, err := common.PublicKeyJWK(&models.VerificationMethod{Type: common.JSONWebKey2020})
+		require.EqualError(t, err, "missing jwk for JsonWebKey2020 verification method")
+		require.Nil(t, publicJWK)
+	})
+	t.Run("Unsupported VM type", func(t *testing.T) {
+		publicJWK, err := common.PublicKeyJWK(&models.VerificationMethod{Type: "UnsupportedType"})
+		require.EqualError(t, err, "verification method type 'UnsupportedType' not supported")
+		require.Nil(t, publicJWK)
+	})
+}
+
 func getECKey(t *testing.T) *jwk.JWK {
 	t.Helper()
 
@@ -4,7 +4,7 @@ Copyright Avast Software. All Rights Reserved.
 SPDX-License-Identifier: Apache-2.0
 */
 
-// Package credentialquery allows querying credentials using presentation definition.
+// Package credentialquery allows querying credentials using presentation definition or DCQL.
 package credentialquery
 
 import (
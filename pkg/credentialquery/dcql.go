@@ -0,0 +1,581 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package credentialquery
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+
+	"github.com/hyperledger/aries-framework-go/component/models/verifiable"
+
+	"github.com/trustbloc/wallet-sdk/pkg/walleterror"
+)
+
+// The credential formats that can be used in DCQL credential queries, and that the SDK's credentials can have.
+const (
+	DCQLFormatJWTVCJSON = "jwt_vc_json"
+	DCQLFormatLDPVC     = "ldp_vc"
+	DCQLFormatVCSDJWT   = "vc+sd-jwt"
+	DCQLFormatDCSDJWT   = "dc+sd-jwt"
+)
+
+var dcqlIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`) //nolint:gochecknoglobals // read-only
+
+// DCQLQuery is a Digital Credentials Query Language (DCQL) query, which is what newer OpenID4VP verifiers use
+// (in the dcql_query parameter) instead of a presentation definition.
+type DCQLQuery struct {
+	Credentials    []*DCQLCredentialQuery    `json:"credentials"`
+	CredentialSets []*DCQLCredentialSetQuery `json:"credential_sets,omitempty"` //nolint: tagliatelle
+}
+
+// DCQLCredentialQuery asks for one credential. A credential matches if it has the given format, satisfies the meta
+// constraints, and has the requested claims. If there are claim sets, then it only needs to have the claims of one of
+// the sets.
+type DCQLCredentialQuery struct {
+	ID        string             `json:"id"`
+	Format    string             `json:"format"`
+	Meta      *DCQLMeta          `json:"meta,omitempty"`
+	Claims    []*DCQLClaimsQuery `json:"claims,omitempty"`
+	ClaimSets [][]string         `json:"claim_sets,omitempty"` //nolint: tagliatelle
+}
+
+// DCQLMeta holds the format-specific constraints of a credential query. TypeValues is used for W3C credentials
+// (jwt_vc_json and ldp_vc): the credential's types must include all the types of at least one of the entries.
+// VCTValues is used for SD-JWT credentials: the credential's vct must be one of the values.
+type DCQLMeta struct {
+	TypeValues   [][]string `json:"type_values,omitempty"`   //nolint: tagliatelle
+	VCTValues    []string   `json:"vct_values,omitempty"`    //nolint: tagliatelle
+	DoctypeValue string     `json:"doctype_value,omitempty"` //nolint: tagliatelle
+}
+
+// DCQLClaimsQuery asks for a claim. Path is a list of object keys (strings), array indexes (non-negative integers)
+// and nulls (meaning all elements of an array) that points to the claim from the root of the credential. If Values
+// is set, then the claim must have one of those values.
+type DCQLClaimsQuery struct {
+	ID     string        `json:"id,omitempty"`
+	Path   []interface{} `json:"path"`
+	Values []interface{} `json:"values,omitempty"`
+}
+
+// DCQLCredentialSetQuery says which combinations of credential queries can be used together. Each option is a list
+// of credential query IDs. If the set is required, then all the credentials of at least one option must be
+// presented. A set is required unless Required is explicitly set to false.
+type DCQLCredentialSetQuery struct {
+	Options  [][]string  `json:"options"`
+	Required *bool       `json:"required,omitempty"`
+	Purpose  interface{} `json:"purpose,omitempty"`
+}
+
+// DCQLMatches holds the credentials that match each credential query of a DCQL query, in the same order as the
+// query's credentials.
+type DCQLMatches struct {
+	CredentialQueries []*DCQLMatchedCredentialQuery
+}
+
+// DCQLMatchedCredentialQuery holds the credentials that match a credential query.
+type DCQLMatchedCredentialQuery struct {
+	ID         string
+	MatchedVCs []*verifiable.Credential
+}
+
+// ParseDCQLQuery parses and validates a DCQL query.
+func ParseDCQLQuery(query []byte) (*DCQLQuery, error) {
+	dcqlQuery := &DCQLQuery{}
+
+	err := json.Unmarshal(query, dcqlQuery)
+	if err != nil {
+		return nil, walleterror.NewValidationError(
+			module,
+			InvalidDCQLQueryCode,
+			InvalidDCQLQueryError,
+			fmt.Errorf("unmarshal DCQL query: %w", err))
+	}
+
+	err = dcqlQuery.Validate()
+	if err != nil {
+		return nil, walleterror.NewValidationError(
+			module,
+			InvalidDCQLQueryCode,
+			InvalidDCQLQueryError,
+			err)
+	}
+
+	return dcqlQuery, nil
+}
+
+// Validate checks that the query is well-formed: that IDs are valid and unique, that claim sets and credential sets
+// only refer to IDs that exist, and that claim paths and values have the right types.
+func (q *DCQLQuery) Validate() error {
+	if len(q.Credentials) == 0 {
+		return errors.New("DCQL query has no credential queries")
+	}
+
+	credentialIDs := map[string]bool{}
+
+	for i, credentialQuery := range q.Credentials {
+		if credentialQuery == nil {
+			return fmt.Errorf("credential query %d is null", i)
+		}
+
+		if !dcqlIDPattern.MatchString(credentialQuery.ID) {
+			return fmt.Errorf("invalid credential query ID: %q", credentialQuery.ID)
+		}
+
+		if credentialIDs[credentialQuery.ID] {
+			return fmt.Errorf("duplicate credential query ID: %s", credentialQuery.ID)
+		}
+
+		credentialIDs[credentialQuery.ID] = true
+
+		err := credentialQuery.validate()
+		if err != nil {
+			return fmt.Errorf("credential query %s: %w", credentialQuery.ID, err)
+		}
+	}
+
+	for i, credentialSet := range q.CredentialSets {
+		if credentialSet == nil {
+			return fmt.Errorf("credential set %d is null", i)
+		}
+
+		if len(credentialSet.Options) == 0 {
+			return fmt.Errorf("credential set %d has no options", i)
+		}
+
+		for _, option := range credentialSet.Options {
+			if len(option) == 0 {
+				return fmt.Errorf("credential set %d has an empty option", i)
+			}
+
+			for _, credentialID := range option {
+				if !credentialIDs[credentialID] {
+					return fmt.Errorf("credential set %d refers to an unknown credential query: %s", i,
+						credentialID)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (q *DCQLCredentialQuery) validate() error {
+	if q.Format == "" {
+		return errors.New("format is missing")
+	}
+
+	claimIDs := map[string]bool{}
+
+	for i, claim := range q.Claims {
+		if claim == nil {
+			return fmt.Errorf("claim %d is null", i)
+		}
+
+		if claim.ID != "" {
+			if !dcqlIDPattern.MatchString(claim.ID) {
+				return fmt.Errorf("invalid claim ID: %q", claim.ID)
+			}
+
+			if claimIDs[claim.ID] {
+				return fmt.Errorf("duplicate claim ID: %s", claim.ID)
+			}
+
+			claimIDs[claim.ID] = true
+		} else if len(q.ClaimSets) > 0 {
+			return fmt.Errorf("claim %d has no ID, which is required when there are claim sets", i)
+		}
+
+		err := claim.validate()
+		if err != nil {
+			return fmt.Errorf("claim %d: %w", i, err)
+		}
+	}
+
+	if len(q.ClaimSets) > 0 && len(q.Claims) == 0 {
+		return errors.New("claim sets can't be used without claims")
+	}
+
+	for _, claimSet := range q.ClaimSets {
+		for _, claimID := range claimSet {
+			if !claimIDs[claimID] {
+				return fmt.Errorf("claim set refers to an unknown claim: %s", claimID)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (q *DCQLClaimsQuery) validate() error {
+	if len(q.Path) == 0 {
+		return errors.New("path is missing")
+	}
+
+	for _, component := range q.Path {
+		switch c := component.(type) {
+		case string, nil:
+		case float64:
+			if c < 0 || c != math.Trunc(c) {
+				return fmt.Errorf("invalid array index in path: %v", c)
+			}
+		default:
+			return fmt.Errorf("invalid path component: %v", c)
+		}
+	}
+
+	for _, value := range q.Values {
+		switch value.(type) {
+		case string, float64, bool:
+		default:
+			return fmt.Errorf("invalid value: %v", value)
+		}
+	}
+
+	return nil
+}
+
+// GetDCQLMatches returns the credentials that match each of the query's credential queries. If the matching
+// credentials can't satisfy the query (i.e. a credential query or a required credential set can't be fulfilled),
+// then a NO_CREDENTIAL_SATISFY_REQUIREMENTS error is returned.
+func (c *Instance) GetDCQLMatches(query *DCQLQuery, opts ...QueryOpt) (*DCQLMatches, error) {
+	qOpts := &queryOpts{}
+	for _, opt := range opts {
+		opt(qOpts)
+	}
+
+	credentials, err := getCredentials(qOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := &DCQLMatches{}
+	matchedIDs := map[string]bool{}
+
+	for _, credentialQuery := range query.Credentials {
+		matchedCredentialQuery := &DCQLMatchedCredentialQuery{ID: credentialQuery.ID}
+
+		for _, credential := range credentials {
+			matched, matchErr := credentialQuery.Matches(credential)
+			if matchErr != nil {
+				return nil, walleterror.NewValidationError(
+					module,
+					FailToGetMatchRequirementsResultsCode,
+					FailToGetMatchRequirementsResultsError,
+					matchErr)
+			}
+
+			if matched {
+				matchedCredentialQuery.MatchedVCs = append(matchedCredentialQuery.MatchedVCs, credential)
+			}
+		}
+
+		matchedIDs[credentialQuery.ID] = len(matchedCredentialQuery.MatchedVCs) > 0
+		matches.CredentialQueries = append(matches.CredentialQueries, matchedCredentialQuery)
+	}
+
+	err = query.checkSatisfied(matchedIDs)
+	if err != nil {
+		return nil, walleterror.NewValidationError(
+			module,
+			NoCredentialSatisfyRequirementsCode,
+			NoCredentialSatisfyRequirementsError,
+			err)
+	}
+
+	return matches, nil
+}
+
+// ValidateSelection checks that the given selection, which maps credential query IDs to the credential chosen for
+// that query, can be presented in response to the query: each credential must match its credential query, and the
+// selection must satisfy the query's credential sets (or include every credential query, if there are no sets).
+func (q *DCQLQuery) ValidateSelection(selection map[string]*verifiable.Credential) error {
+	selectedIDs := map[string]bool{}
+
+	for credentialID, credential := range selection {
+		credentialQuery := q.credentialQuery(credentialID)
+		if credentialQuery == nil {
+			return fmt.Errorf("the DCQL query has no credential query with ID %s", credentialID)
+		}
+
+		if credential == nil {
+			return fmt.Errorf("no credential was selected for credential query %s", credentialID)
+		}
+
+		matched, err := credentialQuery.Matches(credential)
+		if err != nil {
+			return err
+		}
+
+		if !matched {
+			return fmt.Errorf("the credential selected for credential query %s doesn't match it", credentialID)
+		}
+
+		selectedIDs[credentialID] = true
+	}
+
+	return q.checkSatisfied(selectedIDs)
+}
+
+// checkSatisfied checks whether the query can be fulfilled using credentials for the given credential query IDs.
+func (q *DCQLQuery) checkSatisfied(availableIDs map[string]bool) error {
+	if len(q.CredentialSets) == 0 {
+		for _, credentialQuery := range q.Credentials {
+			if !availableIDs[credentialQuery.ID] {
+				return fmt.Errorf("no credential for credential query %s", credentialQuery.ID)
+			}
+		}
+
+		return nil
+	}
+
+	for i, credentialSet := range q.CredentialSets {
+		if credentialSet.Required != nil && !*credentialSet.Required {
+			continue
+		}
+
+		if !credentialSet.hasAvailableOption(availableIDs) {
+			return fmt.Errorf("none of the options of required credential set %d can be fulfilled", i)
+		}
+	}
+
+	return nil
+}
+
+func (s *DCQLCredentialSetQuery) hasAvailableOption(availableIDs map[string]bool) bool {
+	for _, option := range s.Options {
+		available := true
+
+		for _, credentialID := range option {
+			if !availableIDs[credentialID] {
+				available = false
+
+				break
+			}
+		}
+
+		if available {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (q *DCQLQuery) credentialQuery(id string) *DCQLCredentialQuery {
+	for _, credentialQuery := range q.Credentials {
+		if credentialQuery.ID == id {
+			return credentialQuery
+		}
+	}
+
+	return nil
+}
+
+// Matches returns whether the given credential matches the credential query.
+func (q *DCQLCredentialQuery) Matches(credential *verifiable.Credential) (bool, error) {
+	format := credentialFormat(credential)
+
+	if q.Format != format && !(isSDJWTFormat(q.Format) && isSDJWTFormat(format)) {
+		return false, nil
+	}
+
+	claims, err := credentialClaims(credential)
+	if err != nil {
+		return false, fmt.Errorf("get claims of credential %s: %w", credential.ID, err)
+	}
+
+	if !q.matchesMeta(credential, claims) {
+		return false, nil
+	}
+
+	if len(q.ClaimSets) == 0 {
+		for _, claim := range q.Claims {
+			if !claim.matches(claims) {
+				return false, nil
+			}
+		}
+
+		return true, nil
+	}
+
+	for _, claimSet := range q.ClaimSets {
+		if q.matchesClaimSet(claimSet, claims) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (q *DCQLCredentialQuery) matchesMeta(credential *verifiable.Credential, claims map[string]interface{}) bool {
+	if q.Meta == nil {
+		return true
+	}
+
+	if len(q.Meta.TypeValues) > 0 && !hasAnyTypeValues(credential.Types, q.Meta.TypeValues) {
+		return false
+	}
+
+	if len(q.Meta.VCTValues) > 0 {
+		vct, _ := claims["vct"].(string) //nolint:errcheck // a missing vct doesn't match
+
+		if !contains(q.Meta.VCTValues, vct) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (q *DCQLCredentialQuery) matchesClaimSet(claimSet []string, claims map[string]interface{}) bool {
+	for _, claimID := range claimSet {
+		for _, claim := range q.Claims {
+			if claim.ID == claimID && !claim.matches(claims) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func (q *DCQLClaimsQuery) matches(claims map[string]interface{}) bool {
+	selected := selectClaims(claims, q.Path)
+
+	if len(q.Values) == 0 {
+		return len(selected) > 0
+	}
+
+	for _, value := range selected {
+		for _, expectedValue := range q.Values {
+			if claimValueEquals(value, expectedValue) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// selectClaims returns the values that the claims path points to.
+func selectClaims(claims map[string]interface{}, path []interface{}) []interface{} {
+	selected := []interface{}{claims}
+
+	for _, component := range path {
+		var next []interface{}
+
+		for _, value := range selected {
+			switch c := component.(type) {
+			case string:
+				if object, ok := value.(map[string]interface{}); ok {
+					if element, exists := object[c]; exists {
+						next = append(next, element)
+					}
+				}
+			case float64:
+				if array, ok := value.([]interface{}); ok && int(c) < len(array) {
+					next = append(next, array[int(c)])
+				}
+			case nil:
+				if array, ok := value.([]interface{}); ok {
+					next = append(next, array...)
+				}
+			}
+		}
+
+		if len(next) == 0 {
+			return nil
+		}
+
+		selected = next
+	}
+
+	return selected
+}
+
+func claimValueEquals(value, expectedValue interface{}) bool {
+	switch value.(type) {
+	case string, float64, bool:
+		return value == expectedValue
+	default:
+		return false
+	}
+}
+
+// credentialFormat returns the DCQL format of the given credential.
+func credentialFormat(credential *verifiable.Credential) string {
+	switch {
+	case credential.JWT != "" && credential.SDJWTHashAlg != "":
+		return DCQLFormatVCSDJWT
+	case credential.JWT != "":
+		return DCQLFormatJWTVCJSON
+	default:
+		return DCQLFormatLDPVC
+	}
+}
+
+func isSDJWTFormat(format string) bool {
+	return format == DCQLFormatVCSDJWT || format == DCQLFormatDCSDJWT
+}
+
+// credentialClaims returns the credential as a JSON object, with all selectively disclosable claims disclosed.
+func credentialClaims(credential *verifiable.Credential) (map[string]interface{}, error) {
+	if credential.SDJWTHashAlg != "" {
+		return credential.CreateDisplayCredentialMap(verifiable.DisplayAllDisclosures())
+	}
+
+	// A JWT credential marshals to its JWT, so the JWT is removed to get the credential's JSON form.
+	credentialCopy := *credential
+	credentialCopy.JWT = ""
+
+	credentialBytes, err := credentialCopy.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	var claims map[string]interface{}
+
+	err = json.Unmarshal(credentialBytes, &claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+func hasAnyTypeValues(types []string, typeValues [][]string) bool {
+	for _, requiredTypes := range typeValues {
+		hasAll := true
+
+		for _, requiredType := range requiredTypes {
+			if !contains(types, requiredType) {
+				hasAll = false
+
+				break
+			}
+		}
+
+		if hasAll {
+			return true
+		}
+	}
+
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
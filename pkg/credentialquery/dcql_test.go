@@ -0,0 +1,344 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package credentialquery_test
+
+import (
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/component/models/verifiable"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/wallet-sdk/internal/testutil"
+	"github.com/trustbloc/wallet-sdk/pkg/credentialquery"
+)
+
+const testDCQLQuery = `{
+  "credentials": [
+    {
+      "id": "degree",
+      "format": "jwt_vc_json",
+      "meta": {"type_values": [["VerifiableCredential", "UniversityDegreeCredential"]]},
+      "claims": [
+        {"path": ["credentialSubject", "degree", "type"], "values": ["BachelorDegree", "MasterDegree"]}
+      ]
+    },
+    {
+      "id": "license",
+      "format": "jwt_vc_json",
+      "meta": {"type_values": [["DriversLicense"]]},
+      "claims": [
+        {"id": "given_name", "path": ["credentialSubject", "given_name"]},
+        {"id": "family_name", "path": ["credentialSubject", "family_name"]},
+        {"id": "nickname", "path": ["credentialSubject", "nickname"]}
+      ],
+      "claim_sets": [["nickname"], ["given_name", "family_name"]]
+    },
+    {
+      "id": "employee",
+      "format": "jwt_vc_json",
+      "meta": {"type_values": [["VerifiedEmployee"]]}
+    },
+    {
+      "id": "citizenship",
+      "format": "ldp_vc",
+      "claims": [
+        {"path": ["credentialSubject", "nationalities", null], "values": ["FR"]}
+      ]
+    }
+  ],
+  "credential_sets": [
+    {"options": [["license"], ["degree", "employee"]]},
+    {"options": [["citizenship"]], "required": false}
+  ]
+}`
+
+func TestParseDCQLQuery(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		query, err := credentialquery.ParseDCQLQuery([]byte(testDCQLQuery))
+		require.NoError(t, err)
+		require.Len(t, query.Credentials, 4)
+		require.Len(t, query.CredentialSets, 2)
+		require.Equal(t, []string{"VerifiableCredential", "UniversityDegreeCredential"},
+			query.Credentials[0].Meta.TypeValues[0])
+		require.Equal(t, [][]string{{"nickname"}, {"given_name", "family_name"}}, query.Credentials[1].ClaimSets)
+		require.False(t, *query.CredentialSets[1].Required)
+	})
+
+	t.Run("Failures", func(t *testing.T) {
+		testCases := []struct {
+			name        string
+			query       string
+			expectedErr string
+		}{
+			{
+				name:        "Invalid JSON",
+				query:       `{`,
+				expectedErr: "unmarshal DCQL query",
+			},
+			{
+				name:        "No credential queries",
+				query:       `{"credentials": []}`,
+				expectedErr: "DCQL query has no credential queries",
+			},
+			{
+				name:        "Null credential query",
+				query:       `{"credentials": [null]}`,
+				expectedErr: "credential query 0 is null",
+			},
+			{
+				name:        "Null claim",
+				query:       `{"credentials": [{"id": "a", "format": "ldp_vc", "claims": [null]}]}`,
+				expectedErr: "credential query a: claim 0 is null",
+			},
+			{
+				name:        "Null credential set",
+				query:       `{"credentials": [{"id": "a", "format": "ldp_vc"}], "credential_sets": [null]}`,
+				expectedErr: "credential set 0 is null",
+			},
+			{
+				name:        "Invalid credential query ID",
+				query:       `{"credentials": [{"id": "a b", "format": "ldp_vc"}]}`,
+				expectedErr: `invalid credential query ID: "a b"`,
+			},
+			{
+				name: "Duplicate credential query ID",
+				query: `{"credentials": [{"id": "a", "format": "ldp_vc"},
+					{"id": "a", "format": "ldp_vc"}]}`,
+				expectedErr: "duplicate credential query ID: a",
+			},
+			{
+				name:        "Missing format",
+				query:       `{"credentials": [{"id": "a"}]}`,
+				expectedErr: "credential query a: format is missing",
+			},
+			{
+				name:        "Missing claim path",
+				query:       `{"credentials": [{"id": "a", "format": "ldp_vc", "claims": [{}]}]}`,
+				expectedErr: "credential query a: claim 0: path is missing",
+			},
+			{
+				name:        "Invalid array index",
+				query:       `{"credentials": [{"id": "a", "format": "ldp_vc", "claims": [{"path": ["x", -1]}]}]}`,
+				expectedErr: "invalid array index in path: -1",
+			},
+			{
+				name:        "Invalid path component",
+				query:       `{"credentials": [{"id": "a", "format": "ldp_vc", "claims": [{"path": [true]}]}]}`,
+				expectedErr: "invalid path component: true",
+			},
+			{
+				name: "Invalid value",
+				query: `{"credentials": [{"id": "a", "format": "ldp_vc",
+					"claims": [{"path": ["x"], "values": [{}]}]}]}`,
+				expectedErr: "invalid value",
+			},
+			{
+				name: "Duplicate claim ID",
+				query: `{"credentials": [{"id": "a", "format": "ldp_vc",
+					"claims": [{"id": "x", "path": ["x"]}, {"id": "x", "path": ["y"]}]}]}`,
+				expectedErr: "duplicate claim ID: x",
+			},
+			{
+				name: "Claim without ID when there are claim sets",
+				query: `{"credentials": [{"id": "a", "format": "ldp_vc",
+					"claims": [{"path": ["x"]}], "claim_sets": [["x"]]}]}`,
+				expectedErr: "claim 0 has no ID, which is required when there are claim sets",
+			},
+			{
+				name: "Claim set refers to an unknown claim",
+				query: `{"credentials": [{"id": "a", "format": "ldp_vc",
+					"claims": [{"id": "x", "path": ["x"]}], "claim_sets": [["y"]]}]}`,
+				expectedErr: "claim set refers to an unknown claim: y",
+			},
+			{
+				name:        "Claim sets without claims",
+				query:       `{"credentials": [{"id": "a", "format": "ldp_vc", "claim_sets": [["y"]]}]}`,
+				expectedErr: "claim sets can't be used without claims",
+			},
+			{
+				name: "Credential set refers to an unknown credential query",
+				query: `{"credentials": [{"id": "a", "format": "ldp_vc"}],
+					"credential_sets": [{"options": [["b"]]}]}`,
+				expectedErr: "credential set 0 refers to an unknown credential query: b",
+			},
+			{
+				name: "Credential set without options",
+				query: `{"credentials": [{"id": "a", "format": "ldp_vc"}],
+					"credential_sets": [{"options": []}]}`,
+				expectedErr: "credential set 0 has no options",
+			},
+			{
+				name: "Credential set with an empty option",
+				query: `{"credentials": [{"id": "a", "format": "ldp_vc"}],
+					"credential_sets": [{"options": [[]]}]}`,
+				expectedErr: "credential set 0 has an empty option",
+			},
+		}
+
+		for _, testCase := range testCases {
+			t.Run(testCase.name, func(t *testing.T) {
+				query, err := credentialquery.ParseDCQLQuery([]byte(testCase.query))
+				testutil.RequireErrorContains(t, err, "INVALID_DCQL_QUERY")
+				testutil.RequireErrorContains(t, err, testCase.expectedErr)
+				require.Nil(t, query)
+			})
+		}
+	})
+}
+
+func TestInstance_GetDCQLMatches(t *testing.T) {
+	credentials := parseTestCredentials(t, universityDegreeVC, permanentResidentCardVC, driverLicenseVC,
+		verifiedEmployeeVC)
+	citizenshipVC := newTestCitizenshipCredential("DE", "FR")
+
+	credentials = append(credentials, citizenshipVC)
+
+	instance := credentialquery.NewInstance(testutil.DocumentLoader(t))
+
+	t.Run("Success", func(t *testing.T) {
+		query, err := credentialquery.ParseDCQLQuery([]byte(testDCQLQuery))
+		require.NoError(t, err)
+
+		matches, err := instance.GetDCQLMatches(query, credentialquery.WithCredentialsArray(credentials))
+		require.NoError(t, err)
+		require.Len(t, matches.CredentialQueries, 4)
+
+		for i, expectedMatch := range []*verifiable.Credential{
+			credentials[0], credentials[2], credentials[3], citizenshipVC,
+		} {
+			require.Equal(t, query.Credentials[i].ID, matches.CredentialQueries[i].ID)
+			require.Equal(t, []*verifiable.Credential{expectedMatch}, matches.CredentialQueries[i].MatchedVCs)
+		}
+	})
+
+	t.Run("Optional credential set can't be fulfilled", func(t *testing.T) {
+		query, err := credentialquery.ParseDCQLQuery([]byte(testDCQLQuery))
+		require.NoError(t, err)
+
+		matches, err := instance.GetDCQLMatches(query,
+			credentialquery.WithCredentialsArray([]*verifiable.Credential{credentials[2]}))
+		require.NoError(t, err)
+		require.Len(t, matches.CredentialQueries[1].MatchedVCs, 1)
+		require.Empty(t, matches.CredentialQueries[3].MatchedVCs)
+	})
+
+	t.Run("Required credential set can't be fulfilled", func(t *testing.T) {
+		query, err := credentialquery.ParseDCQLQuery([]byte(testDCQLQuery))
+		require.NoError(t, err)
+
+		matches, err := instance.GetDCQLMatches(query,
+			credentialquery.WithCredentialsArray([]*verifiable.Credential{credentials[0], citizenshipVC}))
+		testutil.RequireErrorContains(t, err, "NO_CREDENTIAL_SATISFY_REQUIREMENTS")
+		testutil.RequireErrorContains(t, err, "none of the options of required credential set 0 can be fulfilled")
+		require.Nil(t, matches)
+	})
+
+	t.Run("Credential query without credential sets can't be fulfilled", func(t *testing.T) {
+		query, err := credentialquery.ParseDCQLQuery([]byte(`{"credentials": [
+			{"id": "degree", "format": "jwt_vc_json",
+				"claims": [{"path": ["credentialSubject", "degree", "type"], "values": ["MasterDegree"]}]},
+			{"id": "citizenship", "format": "ldp_vc",
+				"claims": [{"path": ["credentialSubject", "nationalities", 0], "values": ["DE"]}]}
+		]}`))
+		require.NoError(t, err)
+
+		matches, err := instance.GetDCQLMatches(query, credentialquery.WithCredentialsArray(credentials))
+		testutil.RequireErrorContains(t, err, "NO_CREDENTIAL_SATISFY_REQUIREMENTS")
+		testutil.RequireErrorContains(t, err, "no credential for credential query degree")
+		require.Nil(t, matches)
+	})
+
+	t.Run("Credential reader not set", func(t *testing.T) {
+		query, err := credentialquery.ParseDCQLQuery([]byte(testDCQLQuery))
+		require.NoError(t, err)
+
+		matches, err := instance.GetDCQLMatches(query)
+		testutil.RequireErrorContains(t, err, "CREDENTIAL_READER_NOT_SET")
+		require.Nil(t, matches)
+	})
+}
+
+func TestDCQLQuery_ValidateSelection(t *testing.T) {
+	credentials := parseTestCredentials(t, universityDegreeVC, driverLicenseVC, verifiedEmployeeVC)
+
+	query, err := credentialquery.ParseDCQLQuery([]byte(testDCQLQuery))
+	require.NoError(t, err)
+
+	t.Run("Success", func(t *testing.T) {
+		require.NoError(t, query.ValidateSelection(map[string]*verifiable.Credential{
+			"license": credentials[1],
+		}))
+		require.NoError(t, query.ValidateSelection(map[string]*verifiable.Credential{
+			"degree":   credentials[0],
+			"employee": credentials[2],
+		}))
+	})
+
+	t.Run("Failures", func(t *testing.T) {
+		testCases := []struct {
+			name        string
+			selection   map[string]*verifiable.Credential
+			expectedErr string
+		}{
+			{
+				name:        "Unknown credential query",
+				selection:   map[string]*verifiable.Credential{"passport": credentials[1]},
+				expectedErr: "the DCQL query has no credential query with ID passport",
+			},
+			{
+				name:        "Nil credential",
+				selection:   map[string]*verifiable.Credential{"license": nil},
+				expectedErr: "no credential was selected for credential query license",
+			},
+			{
+				name:        "Credential doesn't match its query",
+				selection:   map[string]*verifiable.Credential{"license": credentials[0]},
+				expectedErr: "the credential selected for credential query license doesn't match it",
+			},
+			{
+				name:        "Credential sets aren't satisfied",
+				selection:   map[string]*verifiable.Credential{"degree": credentials[0]},
+				expectedErr: "none of the options of required credential set 0 can be fulfilled",
+			},
+		}
+
+		for _, testCase := range testCases {
+			t.Run(testCase.name, func(t *testing.T) {
+				testutil.RequireErrorContains(t, query.ValidateSelection(testCase.selection), testCase.expectedErr)
+			})
+		}
+	})
+}
+
+func parseTestCredentials(t *testing.T, contents ...[]byte) []*verifiable.Credential {
+	t.Helper()
+
+	credentials := make([]*verifiable.Credential, len(contents))
+
+	for i, content := range contents {
+		credential, err := verifiable.ParseCredential(content, verifiable.WithDisabledProofCheck(),
+			verifiable.WithJSONLDDocumentLoader(testutil.DocumentLoader(t)))
+		require.NoError(t, err)
+
+		credentials[i] = credential
+	}
+
+	return credentials
+}
+
+func newTestCitizenshipCredential(nationalities ...interface{}) *verifiable.Credential {
+	return &verifiable.Credential{
+		Context: []string{verifiable.ContextURI},
+		ID:      "http://example.com/credentials/citizenship",
+		Types:   []string{verifiable.VCType},
+		Issuer:  verifiable.Issuer{ID: "did:example:issuer"},
+		Subject: []verifiable.Subject{{
+			ID:           "did:example:holder",
+			CustomFields: verifiable.CustomFields{"nationalities": nationalities},
+		}},
+	}
+}
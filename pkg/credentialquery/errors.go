@@ -15,6 +15,7 @@ const (
 	CreateVPFailedError                    = "CREATE_VP_FAILED"
 	NoCredentialSatisfyRequirementsError   = "NO_CREDENTIAL_SATISFY_REQUIREMENTS" //nolint:gosec //false positive
 	FailToGetMatchRequirementsResultsError = "FAIL_TO_GET_MATCH_REQUIREMENTS_RESULTS"
+	InvalidDCQLQueryError                  = "INVALID_DCQL_QUERY"
 )
 
 // Constants' names and reasons are obvious so they do not require additional comments.
@@ -25,4 +26,5 @@ const (
 	CreateVPFailedCode
 	NoCredentialSatisfyRequirementsCode
 	FailToGetMatchRequirementsResultsCode
+	InvalidDCQLQueryCode
 )
@@ -131,6 +131,46 @@ func buildDefaultCredentialDisplay(vcID string, subject *verifiable.Subject) *Cr
 	return &CredentialDisplay{Overview: &credentialOverview, Claims: claims}
 }
 
+func buildOfferedCredentialDisplay(supportedCredential *issuer.SupportedCredential,
+	preferredLocale string,
+) *CredentialDisplay {
+	if len(supportedCredential.Overview) == 0 {
+		// Like with issued credentials, if the issuer's metadata doesn't contain display info for this credential,
+		// then a generic name is used instead. The most specific type is assumed to be the last one.
+		var name string
+
+		if len(supportedCredential.Types) > 0 {
+			name = supportedCredential.Types[len(supportedCredential.Types)-1]
+		}
+
+		return &CredentialDisplay{Overview: &CredentialOverview{Name: name}}
+	}
+
+	var claims []ResolvedClaim
+
+	for fieldName, claim := range supportedCredential.CredentialSubject {
+		if len(claim.Displays) == 0 {
+			continue
+		}
+
+		claim := claim // Resolves implicit memory aliasing warning from linter
+
+		label, labelLocale := getLocalizedLabel(preferredLocale, &claim)
+
+		claims = append(claims, ResolvedClaim{
+			RawID:     fieldName,
+			Label:     label,
+			ValueType: claim.ValueType,
+			Order:     claim.Order,
+			Pattern:   claim.Pattern,
+			Mask:      claim.Mask,
+			Locale:    labelLocale,
+		})
+	}
+
+	return &CredentialDisplay{Overview: getOverviewDisplay(supportedCredential, preferredLocale), Claims: claims}
+}
+
 func getSubject(vc *verifiable.Credential) (*verifiable.Subject, error) {
 	credentialSubjects, ok := vc.Subject.([]verifiable.Subject)
 	if !ok {
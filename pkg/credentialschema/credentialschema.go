@@ -4,15 +4,26 @@ Copyright Avast Software. All Rights Reserved.
 SPDX-License-Identifier: Apache-2.0
 */
 
-// Package credentialschema contains a function that can be used to resolve display values per the OpenID4CI spec.
+// Package credentialschema contains functions that can be used to resolve display values per the OpenID4CI spec.
 package credentialschema
 
+import (
+	"context"
+
+	"github.com/trustbloc/wallet-sdk/pkg/models/issuer"
+)
+
 // Resolve resolves display information for some issued credentials based on an issuer's metadata.
 // The CredentialDisplays in the returned ResolvedDisplayData object correspond to the VCs passed in and are in the
 // same order.
 // This method requires one VC source and one issuer metadata source. See opts.go for more information.
 func Resolve(opts ...ResolveOpt) (*ResolvedDisplayData, error) {
-	vcs, metadata, preferredLocale, err := processOpts(opts)
+	return ResolveContext(context.Background(), opts...)
+}
+
+// ResolveContext is the same as Resolve, except that the given context is used when fetching the issuer's metadata.
+func ResolveContext(ctx context.Context, opts ...ResolveOpt) (*ResolvedDisplayData, error) {
+	vcs, metadata, preferredLocale, err := processOpts(ctx, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -29,3 +40,23 @@ func Resolve(opts ...ResolveOpt) (*ResolvedDisplayData, error) {
 		CredentialDisplays: credentialDisplays,
 	}, nil
 }
+
+// ResolveOffered resolves display information for credentials that have been offered, but not yet issued, based on
+// an issuer's metadata. Each offered credential is described by the issuer's metadata for it. Since there are no
+// claim values yet, the resolved claims only contain labels and formatting information.
+// The CredentialDisplays in the returned ResolvedDisplayData object correspond to the offered credentials passed in
+// and are in the same order.
+func ResolveOffered(metadata *issuer.Metadata, offeredCredentials []issuer.SupportedCredential,
+	preferredLocale string,
+) *ResolvedDisplayData {
+	credentialDisplays := make([]CredentialDisplay, len(offeredCredentials))
+
+	for i := range offeredCredentials {
+		credentialDisplays[i] = *buildOfferedCredentialDisplay(&offeredCredentials[i], preferredLocale)
+	}
+
+	return &ResolvedDisplayData{
+		IssuerDisplay:      getIssuerDisplay(metadata.IssuerDisplays, preferredLocale),
+		CredentialDisplays: credentialDisplays,
+	}
+}
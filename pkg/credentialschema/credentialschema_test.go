@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package credentialschema_test
 
 import (
+	"context"
 	_ "embed"
 	"encoding/json"
 	"net/http"
@@ -26,6 +27,9 @@ var (
 	//go:embed testdata/issuer_metadata.json
 	sampleIssuerMetadata []byte
 
+	//go:embed testdata/issuer_metadata_draft13.json
+	sampleIssuerMetadataDraft13 []byte
+
 	//go:embed testdata/issuer_metadata_without_claims_display.json
 	issuerMetadataWithoutClaimsDisplay []byte
 
@@ -97,6 +101,22 @@ func TestResolve(t *testing.T) {
 					checkSuccessCaseMatchedDisplayData(t, resolvedDisplayData)
 				})
 			})
+			t.Run("With draft 13 issuer metadata", func(t *testing.T) {
+				var issuerMetadataDraft13 issuer.Metadata
+
+				err = json.Unmarshal(sampleIssuerMetadataDraft13, &issuerMetadataDraft13)
+				require.NoError(t, err)
+
+				resolvedDisplayData, errResolve := credentialschema.Resolve(
+					credentialschema.WithCredentials([]*verifiable.Credential{credential}),
+					credentialschema.WithIssuerMetadata(&issuerMetadataDraft13))
+				require.NoError(t, errResolve)
+
+				checkSuccessCaseMatchedDisplayData(t, resolvedDisplayData)
+
+				// The caller's metadata object shouldn't have been modified.
+				require.Empty(t, issuerMetadataDraft13.CredentialsSupported)
+			})
 			t.Run("With credential reader instead of directly passing in VC", func(t *testing.T) {
 				memStorageProvider := memstorage.NewProvider()
 
@@ -336,6 +356,19 @@ func TestResolve(t *testing.T) {
 				` dial tcp: lookup BadURL`)
 			require.Nil(t, resolvedDisplayData)
 		})
+		t.Run("Using issuer URI option, but the context is cancelled", func(t *testing.T) {
+			server := httptest.NewServer(&mockIssuerServerHandler{})
+			defer server.Close()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			resolvedDisplayData, err := credentialschema.ResolveContext(ctx,
+				credentialschema.WithCredentials([]*verifiable.Credential{{}}),
+				credentialschema.WithIssuerURI(server.URL))
+			require.ErrorIs(t, err, context.Canceled)
+			require.Nil(t, resolvedDisplayData)
+		})
 	})
 	t.Run("Unsupported VC", func(t *testing.T) {
 		t.Run("Unsupported subject type", func(t *testing.T) {
@@ -400,6 +433,39 @@ func TestResolve(t *testing.T) {
 	})
 }
 
+func TestResolveOffered(t *testing.T) {
+	var issuerMetadata issuer.Metadata
+
+	require.NoError(t, json.Unmarshal(sampleIssuerMetadata, &issuerMetadata))
+
+	offeredCredentials := []issuer.SupportedCredential{
+		issuerMetadata.CredentialsSupported[0],
+		{Format: "jwt_vc_json", Types: []string{"VerifiableCredential", "DriversLicense"}},
+	}
+
+	resolvedDisplayData := credentialschema.ResolveOffered(&issuerMetadata, offeredCredentials, "jp-JA")
+
+	require.Equal(t, &credentialschema.ResolvedIssuerDisplay{Name: "サンプル大学", Locale: "jp-JA"},
+		resolvedDisplayData.IssuerDisplay)
+	require.Len(t, resolvedDisplayData.CredentialDisplays, 2)
+
+	credentialDisplay := resolvedDisplayData.CredentialDisplays[0]
+	require.Equal(t, "University Credential", credentialDisplay.Overview.Name)
+	require.Equal(t, "https://exampleuniversity.com/public/logo.png", credentialDisplay.Overview.Logo.URL)
+	require.Equal(t, "#12107c", credentialDisplay.Overview.BackgroundColor)
+	require.Len(t, credentialDisplay.Claims, len(issuerMetadata.CredentialsSupported[0].CredentialSubject))
+
+	for _, claim := range credentialDisplay.Claims {
+		require.NotEmpty(t, claim.Label)
+		require.Empty(t, claim.RawValue)
+		require.Empty(t, claim.Value)
+	}
+
+	require.Equal(t, credentialschema.CredentialDisplay{
+		Overview: &credentialschema.CredentialOverview{Name: "DriversLicense"},
+	}, resolvedDisplayData.CredentialDisplays[1])
+}
+
 func checkSuccessCaseMatchedDisplayData(t *testing.T, resolvedDisplayData *credentialschema.ResolvedDisplayData) {
 	t.Helper()
 
@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package credentialschema
 
 import (
+	"context"
 	"errors"
 	"net/http"
 
@@ -120,7 +121,7 @@ func WithHTTPClient(httpClient httpClient) ResolveOpt {
 	}
 }
 
-func processOpts(opts []ResolveOpt) ([]*verifiable.Credential, *issuer.Metadata, string, error) {
+func processOpts(ctx context.Context, opts []ResolveOpt) ([]*verifiable.Credential, *issuer.Metadata, string, error) {
 	mergedOpts := mergeOpts(opts)
 
 	err := validateOpts(mergedOpts)
@@ -128,7 +129,7 @@ func processOpts(opts []ResolveOpt) ([]*verifiable.Credential, *issuer.Metadata,
 		return nil, nil, "", err
 	}
 
-	return processValidatedOpts(mergedOpts)
+	return processValidatedOpts(ctx, mergedOpts)
 }
 
 func mergeOpts(opts []ResolveOpt) *resolveOpts {
@@ -174,7 +175,8 @@ func validateIssuerMetadataOpts(issuerMetadataSource *issuerMetadataSource) erro
 	return nil
 }
 
-func processValidatedOpts(opts *resolveOpts) ([]*verifiable.Credential, *issuer.Metadata, string, error) {
+func processValidatedOpts(ctx context.Context, opts *resolveOpts,
+) ([]*verifiable.Credential, *issuer.Metadata, string, error) {
 	vcs, err := processVCOpts(&opts.credentialSource)
 	if err != nil {
 		return nil, nil, "", err
@@ -192,7 +194,7 @@ func processValidatedOpts(opts *resolveOpts) ([]*verifiable.Credential, *issuer.
 		opts.httpClient = &http.Client{Timeout: api.DefaultHTTPTimeout}
 	}
 
-	issuerMetadata, err := processIssuerMetadataOpts(&opts.issuerMetadataSource, opts.httpClient, metricsLogger)
+	issuerMetadata, err := processIssuerMetadataOpts(ctx, &opts.issuerMetadataSource, opts.httpClient, metricsLogger)
 	if err != nil {
 		return nil, nil, "", err
 	}
@@ -219,14 +221,19 @@ func processVCOpts(credentialSource *credentialSource) ([]*verifiable.Credential
 	return vcs, nil
 }
 
-func processIssuerMetadataOpts(issuerMetadataSource *issuerMetadataSource, httpClient httpClient,
-	metricsLogger api.MetricsLogger,
+func processIssuerMetadataOpts(ctx context.Context, issuerMetadataSource *issuerMetadataSource,
+	httpClient httpClient, metricsLogger api.MetricsLogger,
 ) (*issuer.Metadata, error) {
 	if issuerMetadataSource.metadata != nil {
-		return issuerMetadataSource.metadata, nil
+		// A copy is normalized so that the caller's metadata object doesn't get modified.
+		metadata := *issuerMetadataSource.metadata
+
+		metadata.Normalize()
+
+		return &metadata, nil
 	}
 
-	metadata, err := metadatafetcher.Get(issuerMetadataSource.issuerURI,
+	metadata, err := metadatafetcher.Get(ctx, issuerMetadataSource.issuerURI,
 		httpClient, metricsLogger, "Resolve display")
 	if err != nil {
 		return nil, err
@@ -9,6 +9,7 @@ SPDX-License-Identifier: Apache-2.0
 package credentialstatus
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 
@@ -20,6 +21,7 @@ import (
 	vdrspi "github.com/hyperledger/aries-framework-go/spi/vdr"
 
 	"github.com/trustbloc/wallet-sdk/pkg/api"
+	"github.com/trustbloc/wallet-sdk/pkg/internal/contextbound"
 )
 
 // Config holds parameters for initializing a Verifier.
@@ -30,7 +32,7 @@ type Config struct {
 
 // Verifier verifies Credential Status.
 type Verifier struct {
-	client statusClient
+	newClient func(ctx context.Context) statusClient
 }
 
 type statusClient interface {
@@ -39,24 +41,32 @@ type statusClient interface {
 
 // NewVerifier creates a Credential Status Verifier.
 func NewVerifier(config *Config) (*Verifier, error) {
-	client := &status.Client{
-		ValidatorGetter: validator.GetValidator,
-		Resolver: resolver.NewResolver(
-			config.HTTPClient,
-			&wrapResolver{resolver: config.DIDResolver},
-			"",
-		),
+	newClient := func(ctx context.Context) statusClient {
+		return &status.Client{
+			ValidatorGetter: validator.GetValidator,
+			Resolver: resolver.NewResolver(
+				contextbound.HTTPClient(ctx, config.HTTPClient),
+				&wrapResolver{resolver: contextbound.DIDResolver(ctx, config.DIDResolver)},
+				"",
+			),
+		}
 	}
 
 	return &Verifier{
-		client: client,
+		newClient: newClient,
 	}, nil
 }
 
 // Verify checks the Credential Status, returning an error if the status field is invalid, the status is revoked, or if
 // it isn't possible to verify the credential's status.
 func (v *Verifier) Verify(vc *verifiable.Credential) error {
-	err := v.client.VerifyStatus(vc)
+	return v.VerifyContext(context.Background(), vc)
+}
+
+// VerifyContext is the same as Verify, except that the given context is used for fetching the status list and
+// resolving any DIDs needed to verify it.
+func (v *Verifier) VerifyContext(ctx context.Context, vc *verifiable.Credential) error {
+	err := v.newClient(ctx).VerifyStatus(vc)
 	if err != nil {
 		return fmt.Errorf("status verification failed: %w", err)
 	}
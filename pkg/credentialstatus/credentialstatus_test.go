@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package credentialstatus //nolint:testpackage // access internal fields
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"testing"
@@ -28,7 +29,9 @@ func TestNewVerifier(t *testing.T) {
 func TestVerifier_Verify(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		v := &Verifier{
-			client: &mockStatusClient{},
+			newClient: func(context.Context) statusClient {
+				return &mockStatusClient{}
+			},
 		}
 
 		err := v.Verify(&verifiable.Credential{})
@@ -39,8 +42,10 @@ func TestVerifier_Verify(t *testing.T) {
 		expectErr := errors.New("expected error")
 
 		v := &Verifier{
-			client: &mockStatusClient{
-				verifyErr: expectErr,
+			newClient: func(context.Context) statusClient {
+				return &mockStatusClient{
+					verifyErr: expectErr,
+				}
 			},
 		}
 
@@ -48,6 +53,29 @@ func TestVerifier_Verify(t *testing.T) {
 		require.Error(t, err)
 		require.ErrorIs(t, err, expectErr)
 	})
+
+	t.Run("context cancelled", func(t *testing.T) {
+		v, err := NewVerifier(&Config{
+			HTTPClient: http.DefaultClient,
+		})
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err = v.VerifyContext(ctx, &verifiable.Credential{
+			Status: &verifiable.TypedID{
+				ID:   "https://example.com/status/1#1",
+				Type: "StatusList2021Entry",
+				CustomFields: map[string]interface{}{
+					"statusListIndex":      "1",
+					"statusListCredential": "https://example.com/status/1",
+					"statusPurpose":        "revocation",
+				},
+			},
+		})
+		require.ErrorIs(t, err, context.Canceled)
+	})
 }
 
 type mockStatusClient struct {
@@ -9,6 +9,8 @@ SPDX-License-Identifier: Apache-2.0
 package wellknown
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
@@ -20,11 +22,16 @@ import (
 
 	"github.com/trustbloc/wallet-sdk/pkg/api"
 	diderrors "github.com/trustbloc/wallet-sdk/pkg/did"
+	"github.com/trustbloc/wallet-sdk/pkg/internal/contextbound"
 	"github.com/trustbloc/wallet-sdk/pkg/walleterror"
 )
 
 const linkedDomainsServiceType = "LinkedDomains"
 
+// ErrNoLinkedDomainsService is returned by ValidateLinkedDomains if the DID document doesn't have a Linked Domains
+// service. It can be checked for using errors.Is.
+var ErrNoLinkedDomainsService = errors.New("resolved DID document has no Linked Domains services specified")
+
 // HTTPClient represents an HTTP client.
 type HTTPClient interface {
 	Do(req *http.Request) (*http.Response, error)
@@ -37,6 +44,14 @@ type HTTPClient interface {
 // The HTTP client parameter is optional. If not provided, then a default client will be used.
 func ValidateLinkedDomains(did string, resolver api.DIDResolver,
 	httpClient HTTPClient,
+) (bool, string, error) {
+	return ValidateLinkedDomainsContext(context.Background(), did, resolver, httpClient)
+}
+
+// ValidateLinkedDomainsContext is the same as ValidateLinkedDomains, except that the given context is used for
+// resolving the DID and fetching the DID configuration.
+func ValidateLinkedDomainsContext(ctx context.Context, did string, resolver api.DIDResolver,
+	httpClient HTTPClient,
 ) (bool, string, error) {
 	if resolver == nil {
 		return false, "",
@@ -51,6 +66,8 @@ func ValidateLinkedDomains(did string, resolver api.DIDResolver,
 		httpClient = &http.Client{Timeout: api.DefaultHTTPTimeout}
 	}
 
+	resolver = contextbound.DIDResolver(ctx, resolver)
+
 	didDocResolution, err := resolver.Resolve(did)
 	if err != nil {
 		return false, "", walleterror.NewExecutionError(
@@ -65,7 +82,7 @@ func ValidateLinkedDomains(did string, resolver api.DIDResolver,
 		return false, "", err
 	}
 
-	client := didconfig.New(didconfig.WithHTTPClient(httpClient),
+	client := didconfig.New(didconfig.WithHTTPClient(contextbound.HTTPDoer(ctx, httpClient)),
 		didconfig.WithVDRegistry(&didResolverWrapper{didResolver: resolver}))
 
 	// Note that in the case of multiple origins, this method will only return the first one.
@@ -89,6 +106,136 @@ func ValidateLinkedDomains(did string, resolver api.DIDResolver,
 	return true, uri, nil
 }
 
+// LinkedDomainOriginsContext resolves the given DID and returns the origins from all of its Linked Domains services,
+// in the order in which they appear in the DID document. ErrNoLinkedDomainsService is returned if there aren't any.
+// Unlike ValidateLinkedDomains, the origins aren't validated. Use VerifyLinkedDomainContext for that.
+func LinkedDomainOriginsContext(ctx context.Context, did string, resolver api.DIDResolver) ([]string, error) {
+	if resolver == nil {
+		return nil, walleterror.NewExecutionError(
+			diderrors.Module,
+			diderrors.WellknownInitializationCode,
+			diderrors.WellknownInitializationFailed,
+			errors.New("no resolver provided"))
+	}
+
+	didDocResolution, err := contextbound.DIDResolver(ctx, resolver).Resolve(did)
+	if err != nil {
+		return nil, walleterror.NewExecutionError(
+			diderrors.Module,
+			diderrors.WellknownInitializationCode,
+			diderrors.WellknownInitializationFailed,
+			fmt.Errorf("failed to resolve DID: %w", err))
+	}
+
+	var origins []string
+
+	for i := range didDocResolution.DIDDocument.Service {
+		service := &didDocResolution.DIDDocument.Service[i]
+
+		serviceType, isString := service.Type.(string)
+		if !isString || !strings.EqualFold(serviceType, linkedDomainsServiceType) {
+			continue
+		}
+
+		serviceOrigins, errOrigins := getOrigins(service)
+		if errOrigins != nil {
+			return nil, walleterror.NewExecutionError(
+				diderrors.Module,
+				diderrors.WellknownInitializationCode,
+				diderrors.WellknownInitializationFailed,
+				fmt.Errorf("invalid Linked Domains service at index %d: %w", i, errOrigins))
+		}
+
+		origins = append(origins, serviceOrigins...)
+	}
+
+	if len(origins) == 0 {
+		return nil, ErrNoLinkedDomainsService
+	}
+
+	return origins, nil
+}
+
+// VerifyLinkedDomainContext validates the given origin (which should be one of the origins returned by
+// LinkedDomainOriginsContext) against its well-known DID configuration, to check that it confirms the link to the
+// given DID. The HTTP client parameter is optional. If not provided, then a default client will be used.
+func VerifyLinkedDomainContext(ctx context.Context, did, origin string, resolver api.DIDResolver,
+	httpClient HTTPClient,
+) error {
+	if resolver == nil {
+		return walleterror.NewExecutionError(
+			diderrors.Module,
+			diderrors.WellknownInitializationCode,
+			diderrors.WellknownInitializationFailed,
+			errors.New("no resolver provided"))
+	}
+
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: api.DefaultHTTPTimeout}
+	}
+
+	client := didconfig.New(didconfig.WithHTTPClient(contextbound.HTTPDoer(ctx, httpClient)),
+		didconfig.WithVDRegistry(&didResolverWrapper{didResolver: contextbound.DIDResolver(ctx, resolver)}))
+
+	err := client.VerifyDIDAndDomain(did, strings.TrimSuffix(origin, "/"))
+	if err != nil {
+		return walleterror.NewExecutionError(
+			diderrors.Module,
+			diderrors.DomainAndDidVerificationCode,
+			diderrors.DomainAndDidVerificationFailed,
+			fmt.Errorf("DID service validation failed: %w", err))
+	}
+
+	return nil
+}
+
+// getOrigins returns the origins from a Linked Domains service endpoint, which is either a single origin, an array of
+// origins, or an object with an "origins" array.
+func getOrigins(service *diddoc.Service) ([]string, error) {
+	serviceEndpointBytes, err := service.ServiceEndpoint.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	var serviceEndpoint interface{}
+
+	err = json.Unmarshal(serviceEndpointBytes, &serviceEndpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	if endpointObject, isObject := serviceEndpoint.(map[string]interface{}); isObject {
+		serviceEndpoint = endpointObject["origins"]
+	}
+
+	switch endpoint := serviceEndpoint.(type) {
+	case string:
+		return []string{endpoint}, nil
+	case []interface{}:
+		origins := make([]string, 0, len(endpoint))
+
+		for _, entry := range endpoint {
+			switch origin := entry.(type) {
+			case string:
+				origins = append(origins, origin)
+			case map[string]interface{}:
+				uri, isString := origin["uri"].(string)
+				if !isString {
+					return nil, errors.New("service endpoint entry has no URI")
+				}
+
+				origins = append(origins, uri)
+			default:
+				return nil, fmt.Errorf("unrecognized service endpoint entry %v", entry)
+			}
+		}
+
+		return origins, nil
+	default:
+		return nil, fmt.Errorf("unrecognized service endpoint %s", serviceEndpointBytes)
+	}
+}
+
 func getLinkedDomainsService(didDoc *diddoc.Doc) (*diddoc.Service, error) {
 	var linkedDomainsService *diddoc.Service
 
@@ -109,7 +256,7 @@ func getLinkedDomainsService(didDoc *diddoc.Doc) (*diddoc.Service, error) {
 	}
 
 	if linkedDomainsService == nil {
-		return nil, errors.New("resolved DID document has no Linked Domains services specified")
+		return nil, ErrNoLinkedDomainsService
 	}
 
 	return linkedDomainsService, nil
@@ -8,6 +8,7 @@ package wellknown_test
 
 import (
 	"bytes"
+	"context"
 	_ "embed"
 	"fmt"
 	"io"
@@ -158,6 +159,20 @@ func TestValidate(t *testing.T) {
 		require.False(t, valid)
 		require.Empty(t, domain)
 	})
+	t.Run("Context cancelled", func(t *testing.T) {
+		didResolver, err := resolver.NewDIDResolver()
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		valid, domain, err := wellknown.ValidateLinkedDomainsContext(ctx,
+			"did:key:z6MkoTHsgNNrby8JzCNQ1iRLyW5QQ6R8Xuu6AA8igGrMVPUM", didResolver, nil)
+		testutil.RequireErrorContains(t, err, "WELLKNOWN_INITIALIZATION_FAILED")
+		testutil.RequireErrorContains(t, err, context.Canceled.Error())
+		require.False(t, valid)
+		require.Empty(t, domain)
+	})
 	t.Run("Resolved DID document has no services", func(t *testing.T) {
 		didResolver, err := resolver.NewDIDResolver()
 		require.NoError(t, err)
@@ -166,6 +181,7 @@ func TestValidate(t *testing.T) {
 
 		valid, domain, err := wellknown.ValidateLinkedDomains(sampleDIDWithoutServices, didResolver, nil)
 		testutil.RequireErrorContains(t, err, "resolved DID document has no Linked Domains services specified")
+		require.ErrorIs(t, err, wellknown.ErrNoLinkedDomainsService)
 		require.False(t, valid)
 		require.Empty(t, domain)
 	})
@@ -281,6 +297,123 @@ func TestValidate(t *testing.T) {
 	})
 }
 
+func TestLinkedDomainOriginsContext(t *testing.T) {
+	didDocTemplate := `{
+  "@context": ["https://www.w3.org/ns/did/v1","https://identity.foundation/.well-known/did-configuration/v1"],
+  "id": "did:example:123",
+  "service": [%s]
+}`
+
+	t.Run("Origins from every Linked Domains service", func(t *testing.T) {
+		didDoc := fmt.Sprintf(didDocTemplate, `
+    {
+      "id":"did:example:123#foo",
+      "type": "LinkedDomains",
+      "serviceEndpoint": {
+        "origins": ["https://foo.example.com", "https://bar.example.com"]
+      }
+    },
+    {
+      "id":"did:example:123#hub",
+      "type": "IdentityHub",
+      "serviceEndpoint": "https://hub.example.com"
+    },
+    {
+      "id":"did:example:123#baz",
+      "type": "LinkedDomains",
+      "serviceEndpoint": "https://baz.example.com"
+    },
+    {
+      "id":"did:example:123#qux",
+      "type": "LinkedDomains",
+      "serviceEndpoint": [{"uri": "https://qux.example.com"}]
+    }`)
+
+		origins, err := wellknown.LinkedDomainOriginsContext(context.Background(), "DID", newMockResolver(didDoc))
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			"https://foo.example.com", "https://bar.example.com", "https://baz.example.com",
+			"https://qux.example.com",
+		}, origins)
+	})
+	t.Run("No Linked Domains services", func(t *testing.T) {
+		didDoc := fmt.Sprintf(didDocTemplate, `
+    {
+      "id":"did:example:123#hub",
+      "type": "IdentityHub",
+      "serviceEndpoint": "https://hub.example.com"
+    }`)
+
+		origins, err := wellknown.LinkedDomainOriginsContext(context.Background(), "DID", newMockResolver(didDoc))
+		require.ErrorIs(t, err, wellknown.ErrNoLinkedDomainsService)
+		require.Nil(t, origins)
+	})
+	t.Run("Unrecognized service endpoint", func(t *testing.T) {
+		didDoc := fmt.Sprintf(didDocTemplate, `
+    {
+      "id":"did:example:123#foo",
+      "type": "LinkedDomains",
+      "serviceEndpoint": {
+        "origins": [1]
+      }
+    }`)
+
+		origins, err := wellknown.LinkedDomainOriginsContext(context.Background(), "DID", newMockResolver(didDoc))
+		testutil.RequireErrorContains(t, err, "invalid Linked Domains service at index 0: "+
+			"unrecognized service endpoint")
+		require.Nil(t, origins)
+	})
+	t.Run("No resolver provided", func(t *testing.T) {
+		origins, err := wellknown.LinkedDomainOriginsContext(context.Background(), testDID, nil)
+		testutil.RequireErrorContains(t, err, "no resolver provided")
+		require.Nil(t, origins)
+	})
+	t.Run("Fail to resolve DID (invalid DID format)", func(t *testing.T) {
+		didResolver, err := resolver.NewDIDResolver()
+		require.NoError(t, err)
+
+		origins, err := wellknown.LinkedDomainOriginsContext(context.Background(), "InvalidDID", didResolver)
+		testutil.RequireErrorContains(t, err, "WELLKNOWN_INITIALIZATION_FAILED")
+		require.Nil(t, origins)
+	})
+}
+
+func TestVerifyLinkedDomainContext(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		httpClient := &mockHTTPClient{
+			DoFunc: func(req *http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(bytes.NewReader([]byte(didCfg))),
+				}, nil
+			},
+		}
+
+		err := wellknown.VerifyLinkedDomainContext(context.Background(), testDID, "https://did.rohitgulati.com",
+			newMockResolver(doc), httpClient)
+		require.NoError(t, err)
+	})
+	t.Run("Origin doesn't confirm the link", func(t *testing.T) {
+		httpClient := &mockHTTPClient{
+			DoFunc: func(req *http.Request) (*http.Response, error) {
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(bytes.NewReader([]byte(didCfg))),
+				}, nil
+			},
+		}
+
+		err := wellknown.VerifyLinkedDomainContext(context.Background(), testDID, "https://other.example.com",
+			newMockResolver(doc), httpClient)
+		testutil.RequireErrorContains(t, err, "DOMAIN_AND_DID_VERIFICATION_FAILED")
+	})
+	t.Run("No resolver provided", func(t *testing.T) {
+		err := wellknown.VerifyLinkedDomainContext(context.Background(), testDID, "https://did.rohitgulati.com",
+			nil, nil)
+		testutil.RequireErrorContains(t, err, "no resolver provided")
+	})
+}
+
 type resolverWrapper struct {
 	vdr *httpbinding.VDR
 }
@@ -0,0 +1,103 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package contextbound contains wrappers that bind a context to HTTP clients and DID resolvers, for use with code
+// that makes network calls but doesn't accept a context itself.
+package contextbound
+
+import (
+	"context"
+	"net/http"
+
+	diddoc "github.com/hyperledger/aries-framework-go/component/models/did"
+
+	"github.com/trustbloc/wallet-sdk/pkg/api"
+)
+
+// Doer represents an HTTP client.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// HTTPClient returns a copy of the given HTTP client that sends every request using the given context.
+// If httpClient is nil, then a client with the default timeout is used.
+func HTTPClient(ctx context.Context, httpClient *http.Client) *http.Client {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: api.DefaultHTTPTimeout}
+	}
+
+	transport := httpClient.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	boundHTTPClient := *httpClient
+	boundHTTPClient.Transport = &roundTripper{ctx: ctx, transport: transport}
+
+	return &boundHTTPClient
+}
+
+// HTTPDoer returns a Doer that sends every request through the given Doer using the given context.
+func HTTPDoer(ctx context.Context, doer Doer) Doer {
+	return &contextDoer{ctx: ctx, doer: doer}
+}
+
+// DIDResolver returns a DID resolver that stops waiting for the given resolver once the given context is done.
+// Since api.DIDResolver doesn't accept a context, an in-progress resolution can't be aborted. Instead, its result
+// is discarded and the context's error is returned straight away.
+func DIDResolver(ctx context.Context, resolver api.DIDResolver) api.DIDResolver {
+	return &didResolver{ctx: ctx, resolver: resolver}
+}
+
+type roundTripper struct {
+	ctx       context.Context //nolint:containedctx // The whole point of this type is to carry the context.
+	transport http.RoundTripper
+}
+
+func (r *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return r.transport.RoundTrip(req.WithContext(r.ctx))
+}
+
+type contextDoer struct {
+	ctx  context.Context //nolint:containedctx // The whole point of this type is to carry the context.
+	doer Doer
+}
+
+func (c *contextDoer) Do(req *http.Request) (*http.Response, error) {
+	return c.doer.Do(req.WithContext(c.ctx))
+}
+
+type didResolver struct {
+	ctx      context.Context //nolint:containedctx // The whole point of this type is to carry the context.
+	resolver api.DIDResolver
+}
+
+type resolutionResult struct {
+	docResolution *diddoc.DocResolution
+	err           error
+}
+
+func (d *didResolver) Resolve(did string) (*diddoc.DocResolution, error) {
+	err := d.ctx.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	resultChan := make(chan resolutionResult, 1)
+
+	go func() {
+		docResolution, resolveErr := d.resolver.Resolve(did)
+
+		resultChan <- resolutionResult{docResolution: docResolution, err: resolveErr}
+	}()
+
+	select {
+	case result := <-resultChan:
+		return result.docResolution, result.err
+	case <-d.ctx.Done():
+		return nil, d.ctx.Err()
+	}
+}
@@ -0,0 +1,131 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package contextbound_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	diddoc "github.com/hyperledger/aries-framework-go/component/models/did"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/wallet-sdk/pkg/internal/contextbound"
+)
+
+func TestHTTPClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		writer.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	t.Run("Context not cancelled", func(t *testing.T) {
+		httpClient := contextbound.HTTPClient(context.Background(), http.DefaultClient)
+
+		response, err := httpClient.Get(server.URL) //nolint:noctx // The context is bound to the client.
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, response.StatusCode)
+		require.NoError(t, response.Body.Close())
+
+		require.Nil(t, http.DefaultClient.Transport, "the original client must be left untouched")
+	})
+	t.Run("Context cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		httpClient := contextbound.HTTPClient(ctx, nil)
+
+		response, err := httpClient.Get(server.URL) //nolint:noctx,bodyclose // The context is bound to the client.
+		require.ErrorIs(t, err, context.Canceled)
+		require.Nil(t, response)
+	})
+}
+
+func TestHTTPDoer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		writer.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	request, err := http.NewRequestWithContext(context.Background(), http.MethodGet, server.URL, http.NoBody)
+	require.NoError(t, err)
+
+	response, err := contextbound.HTTPDoer(ctx, http.DefaultClient).Do(request) //nolint:bodyclose // nil response
+	require.ErrorIs(t, err, context.Canceled)
+	require.Nil(t, response)
+}
+
+func TestDIDResolver(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		resolver := contextbound.DIDResolver(context.Background(), &mockDIDResolver{})
+
+		docResolution, err := resolver.Resolve("did:example:12345")
+		require.NoError(t, err)
+		require.Equal(t, "did:example:12345", docResolution.DIDDocument.ID)
+	})
+	t.Run("Resolver error", func(t *testing.T) {
+		resolver := contextbound.DIDResolver(context.Background(),
+			&mockDIDResolver{err: errors.New("resolution failed")})
+
+		docResolution, err := resolver.Resolve("did:example:12345")
+		require.EqualError(t, err, "resolution failed")
+		require.Nil(t, docResolution)
+	})
+	t.Run("Context already cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		mockResolver := &mockDIDResolver{}
+
+		docResolution, err := contextbound.DIDResolver(ctx, mockResolver).Resolve("did:example:12345")
+		require.ErrorIs(t, err, context.Canceled)
+		require.Nil(t, docResolution)
+		require.False(t, mockResolver.called)
+	})
+	t.Run("Context cancelled during resolution", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		unblock := make(chan struct{})
+		defer close(unblock)
+
+		resolver := contextbound.DIDResolver(ctx, &mockDIDResolver{
+			onResolve: func() {
+				cancel()
+				<-unblock
+			},
+		})
+
+		docResolution, err := resolver.Resolve("did:example:12345")
+		require.ErrorIs(t, err, context.Canceled)
+		require.Nil(t, docResolution)
+	})
+}
+
+type mockDIDResolver struct {
+	onResolve func()
+	err       error
+	called    bool
+}
+
+func (m *mockDIDResolver) Resolve(did string) (*diddoc.DocResolution, error) {
+	m.called = true
+
+	if m.onResolve != nil {
+		m.onResolve()
+	}
+
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return &diddoc.DocResolution{DIDDocument: &diddoc.Doc{ID: did}}, nil
+}
@@ -12,6 +12,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/trustbloc/wallet-sdk/pkg/api"
@@ -35,11 +37,41 @@ func New(httpClient httpClient, metricsLogger api.MetricsLogger) *Request {
 	}
 }
 
-// Do executes request in background context and read response body.
-func (r *Request) Do(method, endpointURL, contentType string, body io.Reader,
+// Do executes request using the given context and read response body.
+func (r *Request) Do(ctx context.Context, method, endpointURL, contentType string, body io.Reader,
 	event, parentEvent string,
 ) ([]byte, error) {
-	req, err := http.NewRequestWithContext(context.Background(), method, endpointURL, body)
+	return r.DoWithHeaders(ctx, method, endpointURL, contentType, nil, body, event, parentEvent)
+}
+
+// DoWithHeaders is the same as Do, except that the given additional headers are also set on the request.
+func (r *Request) DoWithHeaders(ctx context.Context, method, endpointURL, contentType string,
+	additionalHeaders http.Header, body io.Reader, event, parentEvent string,
+) ([]byte, error) {
+	return r.do(ctx, method, endpointURL, contentType, additionalHeaders, body, []int{http.StatusOK}, event,
+		parentEvent)
+}
+
+// DoWithExpectedStatusCodes is the same as Do, except that the request is considered successful if the response
+// has any of the given status codes (instead of only 200).
+func (r *Request) DoWithExpectedStatusCodes(ctx context.Context, method, endpointURL, contentType string,
+	body io.Reader, expectedStatusCodes []int, event, parentEvent string,
+) ([]byte, error) {
+	return r.do(ctx, method, endpointURL, contentType, nil, body, expectedStatusCodes, event, parentEvent)
+}
+
+// DoWithHeadersAndExpectedStatusCodes combines DoWithHeaders and DoWithExpectedStatusCodes.
+func (r *Request) DoWithHeadersAndExpectedStatusCodes(ctx context.Context, method, endpointURL, contentType string,
+	additionalHeaders http.Header, body io.Reader, expectedStatusCodes []int, event, parentEvent string,
+) ([]byte, error) {
+	return r.do(ctx, method, endpointURL, contentType, additionalHeaders, body, expectedStatusCodes, event,
+		parentEvent)
+}
+
+func (r *Request) do(ctx context.Context, method, endpointURL, contentType string, additionalHeaders http.Header,
+	body io.Reader, expectedStatusCodes []int, event, parentEvent string,
+) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, endpointURL, body)
 	if err != nil {
 		return nil, err
 	}
@@ -48,6 +80,12 @@ func (r *Request) Do(method, endpointURL, contentType string, body io.Reader,
 		req.Header.Add("Content-Type", contentType)
 	}
 
+	for name, values := range additionalHeaders {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+
 	timeStartHTTPRequest := time.Now()
 
 	resp, err := r.httpClient.Do(req)
@@ -76,11 +114,19 @@ func (r *Request) Do(method, endpointURL, contentType string, body io.Reader,
 		return nil, err
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf(
-			"expected status code %d but got status code %d with response body %s instead",
-			http.StatusOK, resp.StatusCode, respBytes)
+	for _, expectedStatusCode := range expectedStatusCodes {
+		if resp.StatusCode == expectedStatusCode {
+			return respBytes, nil
+		}
+	}
+
+	expectedStatusCodesAsStrings := make([]string, len(expectedStatusCodes))
+
+	for i, expectedStatusCode := range expectedStatusCodes {
+		expectedStatusCodesAsStrings[i] = strconv.Itoa(expectedStatusCode)
 	}
 
-	return respBytes, nil
+	return nil, fmt.Errorf(
+		"expected status code %s but got status code %d with response body %s instead",
+		strings.Join(expectedStatusCodesAsStrings, " or "), resp.StatusCode, respBytes)
 }
@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package httprequest_test
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
@@ -23,21 +24,21 @@ import (
 func Test_doHTTPRequest(t *testing.T) {
 	t.Run("Invalid http method", func(t *testing.T) {
 		r := httprequest.New(&mock.HTTPClientMock{StatusCode: 200}, noop.NewMetricsLogger())
-		_, err := r.Do(http.MethodGet, "url", "test", nil,
+		_, err := r.Do(context.Background(), http.MethodGet, "url", "test", nil,
 			"", "")
 		require.NoError(t, err)
 	})
 
 	t.Run("Invalid http method", func(t *testing.T) {
 		r := httprequest.New(&mock.HTTPClientMock{}, noop.NewMetricsLogger())
-		_, err := r.Do("\n\n", "url", "", nil,
+		_, err := r.Do(context.Background(), "\n\n", "url", "", nil,
 			"", "")
 		require.Contains(t, err.Error(), "invalid method")
 	})
 
 	t.Run("Failing metric logger", func(t *testing.T) {
 		r := httprequest.New(&mock.HTTPClientMock{}, &failingMetricsLogger{})
-		_, err := r.Do(http.MethodGet, "url", "test", nil,
+		_, err := r.Do(context.Background(), http.MethodGet, "url", "test", nil,
 			"", "")
 		require.Contains(t, err.Error(), "failed to log event (Event=)")
 	})
@@ -45,7 +46,7 @@ func Test_doHTTPRequest(t *testing.T) {
 	t.Run("Invalid http code", func(t *testing.T) {
 		r := httprequest.New(&mock.HTTPClientMock{}, noop.NewMetricsLogger())
 
-		_, err := r.Do(http.MethodGet, "url", "", nil,
+		_, err := r.Do(context.Background(), http.MethodGet, "url", "", nil,
 			"", "")
 		require.Contains(t, err.Error(), "expected status code 200")
 	})
@@ -55,10 +56,47 @@ func Test_doHTTPRequest(t *testing.T) {
 			StatusCode: 200, Err: errors.New("request err"),
 		}, noop.NewMetricsLogger())
 
-		_, err := r.Do(http.MethodGet, "url", "", nil,
+		_, err := r.Do(context.Background(), http.MethodGet, "url", "", nil,
 			"", "")
 		require.Contains(t, err.Error(), "request err")
 	})
+
+	t.Run("Expected status codes", func(t *testing.T) {
+		r := httprequest.New(&mock.HTTPClientMock{StatusCode: http.StatusCreated}, noop.NewMetricsLogger())
+
+		_, err := r.DoWithExpectedStatusCodes(context.Background(), http.MethodPost, "url", "", nil,
+			[]int{http.StatusOK, http.StatusCreated}, "", "")
+		require.NoError(t, err)
+
+		_, err = r.Do(context.Background(), http.MethodPost, "url", "", nil, "", "")
+		require.Contains(t, err.Error(), "expected status code 200 but got status code 201")
+
+		_, err = r.DoWithExpectedStatusCodes(context.Background(), http.MethodPost, "url", "", nil,
+			[]int{http.StatusOK, http.StatusAccepted}, "", "")
+		require.Contains(t, err.Error(), "expected status code 200 or 202 but got status code 201")
+	})
+
+	t.Run("Additional headers", func(t *testing.T) {
+		httpClient := &mock.HTTPClientMock{StatusCode: 200}
+
+		r := httprequest.New(httpClient, noop.NewMetricsLogger())
+
+		_, err := r.DoWithHeaders(context.Background(), http.MethodGet, "url", "test",
+			http.Header{"Authorization": {"Bearer token"}}, nil, "", "")
+		require.NoError(t, err)
+		require.Equal(t, "Bearer token", httpClient.SentHeaders.Get("Authorization"))
+		require.Equal(t, "test", httpClient.SentHeaders.Get("Content-Type"))
+	})
+
+	t.Run("Context cancelled", func(t *testing.T) {
+		r := httprequest.New(&http.Client{}, noop.NewMetricsLogger())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := r.Do(ctx, http.MethodGet, "https://example.com", "", nil, "", "")
+		require.ErrorIs(t, err, context.Canceled)
+	})
 }
 
 type failingMetricsLogger struct{}
@@ -8,6 +8,7 @@ SPDX-License-Identifier: Apache-2.0
 package issuermetadata
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -26,7 +27,9 @@ type httpClient interface {
 
 // Get gets an issuer's metadata by doing a lookup on its OpenID configuration endpoint.
 // issuerURI is expected to be the base URL for the issuer.
-func Get(issuerURI string, httpClient httpClient, metricsLogger api.MetricsLogger, parentEvent string,
+// Metadata that follows OpenID4CI draft 13 (or later) is normalized into the draft 11 form.
+func Get(ctx context.Context, issuerURI string, httpClient httpClient, metricsLogger api.MetricsLogger,
+	parentEvent string,
 ) (*issuer.Metadata, error) {
 	if metricsLogger == nil {
 		metricsLogger = noop.NewMetricsLogger()
@@ -38,7 +41,7 @@ func Get(issuerURI string, httpClient httpClient, metricsLogger api.MetricsLogge
 
 	metadataEndpoint := issuerURI + "/.well-known/openid-credential-issuer"
 
-	responseBytes, err := httprequest.New(httpClient, metricsLogger).Do(
+	responseBytes, err := httprequest.New(httpClient, metricsLogger).Do(ctx,
 		http.MethodGet, metadataEndpoint, "", nil,
 		fmt.Sprintf(fetchIssuerMetadataViaGETReqEventText, metadataEndpoint), parentEvent)
 	if err != nil {
@@ -53,5 +56,7 @@ func Get(issuerURI string, httpClient httpClient, metricsLogger api.MetricsLogge
 			"OpenID configuration endpoint: %w", err)
 	}
 
-	return &metadata, err
+	metadata.Normalize()
+
+	return &metadata, nil
 }
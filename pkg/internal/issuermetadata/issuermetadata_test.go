@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package issuermetadata_test
 
 import (
+	"context"
 	_ "embed"
 	"fmt"
 	"net/http"
@@ -19,8 +20,12 @@ import (
 	"github.com/trustbloc/wallet-sdk/pkg/internal/issuermetadata"
 )
 
-//go:embed testdata/sample_issuer_metadata.json
-var sampleIssuerMetadata string
+var (
+	//go:embed testdata/sample_issuer_metadata.json
+	sampleIssuerMetadata string
+	//go:embed testdata/sample_issuer_metadata_draft13.json
+	sampleIssuerMetadataDraft13 string
+)
 
 type mockIssuerServerHandler struct {
 	issuerMetadata            string
@@ -55,12 +60,39 @@ func TestGet(t *testing.T) {
 
 		defer server.Close()
 
-		issuerMetadata, err := issuermetadata.Get(server.URL, http.DefaultClient, nil, "")
+		issuerMetadata, err := issuermetadata.Get(context.Background(), server.URL, http.DefaultClient, nil, "")
 		require.NoError(t, err)
 		require.NotNil(t, issuerMetadata)
 	})
+	t.Run("Success - draft 13 metadata gets normalized", func(t *testing.T) {
+		issuerServerHandler := &mockIssuerServerHandler{issuerMetadata: sampleIssuerMetadataDraft13}
+		server := httptest.NewServer(issuerServerHandler)
+
+		defer server.Close()
+
+		issuerMetadata, err := issuermetadata.Get(context.Background(), server.URL, http.DefaultClient, nil, "")
+		require.NoError(t, err)
+		require.Equal(t, "https://server.example.com/oidc/authorize", issuerMetadata.AuthorizationServer)
+		require.Len(t, issuerMetadata.CredentialsSupported, 2)
+
+		supportedCredential, found := issuerMetadata.SupportedCredential("UniversityDegreeCredential")
+		require.True(t, found)
+		require.Equal(t, "jwt_vc_json", supportedCredential.Format)
+		require.Equal(t, []string{"VerifiableCredential", "UniversityDegreeCredential"}, supportedCredential.Types)
+		require.Equal(t, []string{"ES256K"}, supportedCredential.CryptographicSuitesSupported)
+		require.Contains(t, supportedCredential.CredentialSubject, "given_name")
+		require.Equal(t, "University Credential", supportedCredential.Overview[0].Name)
+
+		supportedCredential, found = issuerMetadata.SupportedCredential("EmployeeCredential")
+		require.True(t, found)
+		require.Equal(t, []string{"VerifiableCredential", "EmployeeCredential"}, supportedCredential.Types)
+
+		supportedCredential, found = issuerMetadata.SupportedCredential("UnknownCredential")
+		require.False(t, found)
+		require.Nil(t, supportedCredential)
+	})
 	t.Run("Fail to reach issuer OpenID config endpoint", func(t *testing.T) {
-		issuerMetadata, err := issuermetadata.Get("http://BadURL", http.DefaultClient, nil, "")
+		issuerMetadata, err := issuermetadata.Get(context.Background(), "http://BadURL", http.DefaultClient, nil, "")
 		require.Contains(t, err.Error(), `Get "http://BadURL/.well-known/openid-credential-issuer":`+
 			` dial tcp: lookup BadURL`)
 		require.Nil(t, issuerMetadata)
@@ -71,7 +103,7 @@ func TestGet(t *testing.T) {
 
 		defer server.Close()
 
-		issuerMetadata, err := issuermetadata.Get(server.URL, http.DefaultClient, nil, "")
+		issuerMetadata, err := issuermetadata.Get(context.Background(), server.URL, http.DefaultClient, nil, "")
 		require.Contains(t, err.Error(), "openid configuration endpoint: "+
 			"expected status code 200 but got status code 500 with response body test failure instead")
 		require.Nil(t, issuerMetadata)
@@ -82,7 +114,7 @@ func TestGet(t *testing.T) {
 
 		defer server.Close()
 
-		issuerMetadata, err := issuermetadata.Get(server.URL, http.DefaultClient, nil, "")
+		issuerMetadata, err := issuermetadata.Get(context.Background(), server.URL, http.DefaultClient, nil, "")
 		require.Contains(t, err.Error(), "failed to unmarshal response from the issuer's OpenID "+
 			"configuration endpoint: invalid character 'i' looking for beginning of value")
 		require.Nil(t, issuerMetadata)
@@ -93,7 +125,8 @@ func TestGet(t *testing.T) {
 
 		defer server.Close()
 
-		issuerMetadata, err := issuermetadata.Get(server.URL, http.DefaultClient, &failingMetricsLogger{}, "")
+		issuerMetadata, err := issuermetadata.Get(context.Background(), server.URL, http.DefaultClient,
+			&failingMetricsLogger{}, "")
 		require.Contains(t, err.Error(), "failed to log event (Event=Fetch issuer metadata via an HTTP GET "+
 			"request to http://127.0.0.1:")
 		require.Nil(t, issuerMetadata)
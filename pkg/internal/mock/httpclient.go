@@ -21,6 +21,7 @@ type HTTPClientMock struct {
 	Err              error
 	ExpectedEndpoint string
 	SentBody         []byte
+	SentHeaders      http.Header
 }
 
 // Do mocks call to http client Do function.
@@ -29,6 +30,8 @@ func (c *HTTPClientMock) Do(req *http.Request) (*http.Response, error) {
 		return nil, fmt.Errorf("requested endpoint %s does not match %s", req.URL.String(), c.ExpectedEndpoint)
 	}
 
+	c.SentHeaders = req.Header
+
 	if req.Body != nil {
 		respBytes, err := io.ReadAll(req.Body)
 		if err != nil {
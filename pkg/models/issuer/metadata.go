@@ -7,14 +7,40 @@ SPDX-License-Identifier: Apache-2.0
 // Package issuer contains models for representing an issuer's metadata.
 package issuer
 
+import "sort"
+
 // Metadata represents metadata about an issuer as obtained from their .well-known OpenID configuration.
 type Metadata struct {
-	CredentialIssuer     string                `json:"credential_issuer,omitempty"`
-	AuthorizationServer  string                `json:"authorization_server,omitempty"`
-	CredentialEndpoint   string                `json:"credential_endpoint,omitempty"`
-	CredentialsSupported []SupportedCredential `json:"credentials_supported,omitempty"`
+	CredentialIssuer           string                `json:"credential_issuer,omitempty"`
+	AuthorizationServer        string                `json:"authorization_server,omitempty"`
+	AuthorizationServers       []string              `json:"authorization_servers,omitempty"`
+	CredentialEndpoint         string                `json:"credential_endpoint,omitempty"`
+	BatchCredentialEndpoint    string                `json:"batch_credential_endpoint,omitempty"`
+	DeferredCredentialEndpoint string                `json:"deferred_credential_endpoint,omitempty"`
+	NotificationEndpoint       string                `json:"notification_endpoint,omitempty"`
+	CredentialsSupported       []SupportedCredential `json:"credentials_supported,omitempty"`
+	// CredentialConfigurationsSupported is used by OpenID4CI draft 13 and later in place of CredentialsSupported.
+	// The keys are the credential configuration IDs that credential offers refer to.
+	CredentialConfigurationsSupported map[string]SupportedCredential `json:"credential_configurations_supported,omitempty"`
+	// GrantTypesSupported isn't defined by the OpenID4CI spec, but some issuers include it in their metadata.
+	// Prefer the authorization server's metadata when determining supported grant types.
+	GrantTypesSupported []string `json:"grant_types_supported,omitempty"`
 	// IssuerDisplays represents display information for the issuer's name in various locales.
 	IssuerDisplays []Display `json:"display,omitempty"`
+	// The fields below describe the issuer's support for encrypted credential responses.
+	CredentialResponseEncryptionAlgValuesSupported []string `json:"credential_response_encryption_alg_values_supported,omitempty"` //nolint:lll
+	CredentialResponseEncryptionEncValuesSupported []string `json:"credential_response_encryption_enc_values_supported,omitempty"` //nolint:lll
+	RequireCredentialResponseEncryption            bool     `json:"require_credential_response_encryption,omitempty"`
+	// CredentialResponseEncryption is used by OpenID4CI draft 13 and later in place of the three fields above.
+	CredentialResponseEncryption *CredentialResponseEncryption `json:"credential_response_encryption,omitempty"`
+}
+
+// CredentialResponseEncryption describes the issuer's support for encrypted credential responses.
+// It's used by OpenID4CI draft 13 and later.
+type CredentialResponseEncryption struct {
+	AlgValuesSupported []string `json:"alg_values_supported,omitempty"`
+	EncValuesSupported []string `json:"enc_values_supported,omitempty"`
+	EncryptionRequired bool     `json:"encryption_required,omitempty"`
 }
 
 // SupportedCredential represents metadata about a credential type that a credential issuer can issue.
@@ -26,6 +52,24 @@ type SupportedCredential struct {
 	CredentialSubject                    map[string]Claim     `json:"credentialSubject,omitempty"`
 	CryptographicBindingMethodsSupported []string             `json:"cryptographic_binding_methods_supported,omitempty"`
 	CryptographicSuitesSupported         []string             `json:"cryptographic_suites_supported,omitempty"`
+	// The fields below are only used by OpenID4CI draft 13 and later.
+	Scope                               string                `json:"scope,omitempty"`
+	CredentialDefinition                *CredentialDefinition `json:"credential_definition,omitempty"`
+	CredentialSigningAlgValuesSupported []string              `json:"credential_signing_alg_values_supported,omitempty"`
+	// ProofTypesSupported is keyed by proof type (e.g. "jwt").
+	ProofTypesSupported map[string]ProofTypeSupported `json:"proof_types_supported,omitempty"`
+}
+
+// ProofTypeSupported describes the key proofs of a given type that the issuer accepts for a credential.
+// It's used by OpenID4CI draft 13 and later.
+type ProofTypeSupported struct {
+	ProofSigningAlgValuesSupported []string `json:"proof_signing_alg_values_supported,omitempty"`
+}
+
+// CredentialDefinition describes the types and claims of a credential. It's used by OpenID4CI draft 13 and later.
+type CredentialDefinition struct {
+	Types             []string         `json:"type,omitempty"`
+	CredentialSubject map[string]Claim `json:"credentialSubject,omitempty"`
 }
 
 // CredentialOverview represents display data for a credential as a whole.
@@ -61,3 +105,71 @@ type Display struct {
 	Name   string `json:"name,omitempty"`
 	Locale string `json:"locale,omitempty"`
 }
+
+// Normalize converts metadata that follows OpenID4CI draft 13 (or later) into the draft 11 form that the rest of
+// Wallet-SDK works with. CredentialsSupported is populated from CredentialConfigurationsSupported (with each
+// credential configuration ID used as the ID), AuthorizationServer is populated from AuthorizationServers and the
+// credential response encryption fields are populated from CredentialResponseEncryption.
+// Fields that are already set are left as-is, so calling this on draft 11 metadata has no effect.
+func (m *Metadata) Normalize() {
+	if m.AuthorizationServer == "" && len(m.AuthorizationServers) > 0 {
+		m.AuthorizationServer = m.AuthorizationServers[0]
+	}
+
+	if m.CredentialResponseEncryption != nil && len(m.CredentialResponseEncryptionAlgValuesSupported) == 0 {
+		m.CredentialResponseEncryptionAlgValuesSupported = m.CredentialResponseEncryption.AlgValuesSupported
+		m.CredentialResponseEncryptionEncValuesSupported = m.CredentialResponseEncryption.EncValuesSupported
+		m.RequireCredentialResponseEncryption = m.CredentialResponseEncryption.EncryptionRequired
+	}
+
+	if len(m.CredentialsSupported) > 0 || len(m.CredentialConfigurationsSupported) == 0 {
+		return
+	}
+
+	credentialConfigurationIDs := make([]string, 0, len(m.CredentialConfigurationsSupported))
+
+	for credentialConfigurationID := range m.CredentialConfigurationsSupported {
+		credentialConfigurationIDs = append(credentialConfigurationIDs, credentialConfigurationID)
+	}
+
+	// Sorted so that the order is deterministic, since map iteration order isn't.
+	sort.Strings(credentialConfigurationIDs)
+
+	m.CredentialsSupported = make([]SupportedCredential, len(credentialConfigurationIDs))
+
+	for i, credentialConfigurationID := range credentialConfigurationIDs {
+		supportedCredential := m.CredentialConfigurationsSupported[credentialConfigurationID]
+
+		if supportedCredential.ID == "" {
+			supportedCredential.ID = credentialConfigurationID
+		}
+
+		if supportedCredential.CredentialDefinition != nil {
+			if len(supportedCredential.Types) == 0 {
+				supportedCredential.Types = supportedCredential.CredentialDefinition.Types
+			}
+
+			if supportedCredential.CredentialSubject == nil {
+				supportedCredential.CredentialSubject = supportedCredential.CredentialDefinition.CredentialSubject
+			}
+		}
+
+		if len(supportedCredential.CryptographicSuitesSupported) == 0 {
+			supportedCredential.CryptographicSuitesSupported = supportedCredential.CredentialSigningAlgValuesSupported
+		}
+
+		m.CredentialsSupported[i] = supportedCredential
+	}
+}
+
+// SupportedCredential returns the supported credential with the given ID. For draft 13 (and later) metadata, the ID
+// is the credential configuration ID. Normalize must be called first for draft 13 metadata.
+func (m *Metadata) SupportedCredential(id string) (*SupportedCredential, bool) {
+	for i := range m.CredentialsSupported {
+		if m.CredentialsSupported[i].ID == id {
+			return &m.CredentialsSupported[i], true
+		}
+	}
+
+	return nil, false
+}
@@ -10,6 +10,7 @@ package oauth2
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -21,6 +22,14 @@ import (
 // If the server requires an initial access token, then use the WithInitialAccessBearerToken option.
 func RegisterClient(registrationEndpoint string, clientMetadata *ClientMetadata,
 	opts ...Opt,
+) (*RegisterClientResponse, error) {
+	return RegisterClientContext(context.Background(), registrationEndpoint, clientMetadata, opts...)
+}
+
+// RegisterClientContext is the same as RegisterClient, except that the given context is used for the
+// registration request.
+func RegisterClientContext(ctx context.Context, registrationEndpoint string, clientMetadata *ClientMetadata,
+	opts ...Opt,
 ) (*RegisterClientResponse, error) {
 	if registrationEndpoint == "" {
 		return nil, errors.New("registration endpoint cannot be blank")
@@ -39,7 +48,7 @@ func RegisterClient(registrationEndpoint string, clientMetadata *ClientMetadata,
 		return nil, err
 	}
 
-	respBody, err := getRawResponse(clientMetadataBytes, registrationEndpoint, processedOpts)
+	respBody, err := getRawResponse(ctx, clientMetadataBytes, registrationEndpoint, processedOpts)
 	if err != nil {
 		return nil, err
 	}
@@ -54,9 +63,8 @@ func RegisterClient(registrationEndpoint string, clientMetadata *ClientMetadata,
 	return response, nil
 }
 
-func getRawResponse(requestBytes []byte, registrationEndpoint string, opts *opts) ([]byte, error) {
-	httpReq, err := http.NewRequest( //nolint: noctx // Timeout expected to be set in HTTP client already
-		http.MethodPost, registrationEndpoint, bytes.NewReader(requestBytes))
+func getRawResponse(ctx context.Context, requestBytes []byte, registrationEndpoint string, opts *opts) ([]byte, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, registrationEndpoint, bytes.NewReader(requestBytes))
 	if err != nil {
 		return nil, err
 	}
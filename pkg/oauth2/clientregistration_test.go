@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package oauth2_test
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -108,4 +109,15 @@ func TestRegisterClient(t *testing.T) {
 		require.EqualError(t, err, `parse "%": invalid URL escape "%"`)
 		require.Nil(t, response)
 	})
+	t.Run("Context cancelled", func(t *testing.T) {
+		server := httptest.NewServer(&mockIssuerServerHandler{t: t})
+		defer server.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		response, err := oauth2.RegisterClientContext(ctx, server.URL, nil)
+		require.ErrorIs(t, err, context.Canceled)
+		require.Nil(t, response)
+	})
 }
@@ -0,0 +1,160 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/trustbloc/wallet-sdk/pkg/internal/httprequest"
+)
+
+const (
+	openIDConfigurationWellKnownPath      = "/.well-known/openid-configuration"
+	oAuthAuthorizationServerWellKnownPath = "/.well-known/oauth-authorization-server"
+)
+
+// authorizationServerMetadataLocation is a location where an authorization server's metadata may be found.
+type authorizationServerMetadataLocation struct {
+	url string
+	// issuer is the identifier of the authorization server whose metadata is expected at this location. If set, then
+	// the issuer in the fetched metadata must match it, as required by
+	// https://datatracker.ietf.org/doc/html/rfc8414#section-3.3.
+	issuer string
+}
+
+// getOpenIDConfig returns the metadata of the authorization server that's used for the issuer. The metadata is
+// discovered by trying the following locations in order:
+//  1. If the issuer's metadata names a separate authorization server, then that authorization server's
+//     OAuth 2.0 authorization server metadata (RFC 8414) and OpenID configuration locations are tried first.
+//  2. The issuer's own OpenID configuration and OAuth 2.0 authorization server metadata locations.
+//
+// Metadata fetched from an RFC 8414 location is only used if its issuer matches the authorization server it was
+// fetched for. If none of the locations work, then the error from the first location is returned.
+// Once fetched, the metadata is kept for the rest of the interaction.
+func (i *Interaction) getOpenIDConfig(ctx context.Context) (*OpenIDConfig, error) {
+	if i.openIDConfig != nil {
+		return i.openIDConfig, nil
+	}
+
+	var firstErr error
+
+	for _, metadataLocation := range i.authorizationServerMetadataLocations() {
+		config, err := i.fetchAuthorizationServerMetadata(ctx, metadataLocation)
+		if err == nil {
+			return config, nil
+		}
+
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return nil, firstErr
+}
+
+// authorizationServerMetadataLocations returns the locations where the authorization server's metadata may be found,
+// in order of preference.
+func (i *Interaction) authorizationServerMetadataLocations() []authorizationServerMetadataLocation {
+	var metadataLocations []authorizationServerMetadataLocation
+
+	if i.issuerMetadata != nil && i.issuerMetadata.AuthorizationServer != "" &&
+		strings.TrimSuffix(i.issuerMetadata.AuthorizationServer, "/") != strings.TrimSuffix(i.issuerURI, "/") {
+		authorizationServer := strings.TrimSuffix(i.issuerMetadata.AuthorizationServer, "/")
+
+		metadataLocations = append(metadataLocations,
+			authorizationServerMetadataLocation{
+				url:    rfc8414WellKnownURL(authorizationServer, oAuthAuthorizationServerWellKnownPath),
+				issuer: authorizationServer,
+			},
+			authorizationServerMetadataLocation{url: authorizationServer + openIDConfigurationWellKnownPath})
+	}
+
+	issuerURI := strings.TrimSuffix(i.issuerURI, "/")
+
+	return append(metadataLocations,
+		authorizationServerMetadataLocation{url: issuerURI + openIDConfigurationWellKnownPath},
+		authorizationServerMetadataLocation{
+			url:    rfc8414WellKnownURL(issuerURI, oAuthAuthorizationServerWellKnownPath),
+			issuer: issuerURI,
+		})
+}
+
+func (i *Interaction) fetchAuthorizationServerMetadata(ctx context.Context,
+	metadataLocation authorizationServerMetadataLocation,
+) (*OpenIDConfig, error) {
+	responseBytes, err := httprequest.New(i.httpClient, i.metricsLogger).Do(ctx,
+		http.MethodGet, metadataLocation.url, "", nil,
+		fmt.Sprintf(fetchOpenIDConfigViaGETReqEventText, metadataLocation.url), requestCredentialEventText)
+	if err != nil {
+		return nil, fmt.Errorf("openid configuration endpoint: %w", err)
+	}
+
+	var config OpenIDConfig
+
+	err = json.Unmarshal(responseBytes, &config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response from the issuer's "+
+			"OpenID configuration endpoint: %w", err)
+	}
+
+	// Identifiers are compared without any trailing slash, since the identifiers from the issuer's metadata are
+	// normalized that way too.
+	if metadataLocation.issuer != "" && strings.TrimSuffix(config.Issuer, "/") != metadataLocation.issuer {
+		return nil, fmt.Errorf("issuer %q in the authorization server metadata from %s doesn't match the "+
+			"authorization server %q", config.Issuer, metadataLocation.url, metadataLocation.issuer)
+	}
+
+	return &config, nil
+}
+
+// rfc8414WellKnownURL inserts the given well-known path between the host and path components of the given
+// identifier, as described in https://datatracker.ietf.org/doc/html/rfc8414#section-3.1.
+// For an identifier without a path component, this is the same as appending the well-known path.
+func rfc8414WellKnownURL(identifier, wellKnownPath string) string {
+	parsedIdentifier, err := url.Parse(identifier)
+	if err != nil || parsedIdentifier.Host == "" {
+		return identifier + wellKnownPath
+	}
+
+	parsedIdentifier.Path = wellKnownPath + strings.TrimSuffix(parsedIdentifier.Path, "/")
+	parsedIdentifier.RawPath = ""
+
+	return parsedIdentifier.String()
+}
+
+// DPoPSupported indicates whether the authorization server advertises support for DPoP (RFC 9449).
+func (o *OpenIDConfig) DPoPSupported() bool {
+	return len(o.DPoPSigningAlgValuesSupported) > 0
+}
+
+// TokenEndpointAuthMethodSupported indicates whether the authorization server supports the given client
+// authentication method at its token endpoint. Per RFC 8414, if the authorization server doesn't specify which
+// methods it supports, then only client_secret_basic is supported.
+func (o *OpenIDConfig) TokenEndpointAuthMethodSupported(authMethod string) bool {
+	if len(o.TokenEndpointAuthMethodsSupported) == 0 {
+		return authMethod == "client_secret_basic"
+	}
+
+	for _, supportedAuthMethod := range o.TokenEndpointAuthMethodsSupported {
+		if supportedAuthMethod == authMethod {
+			return true
+		}
+	}
+
+	return false
+}
+
+// PushedAuthorizationRequestsSupported indicates whether the authorization server supports pushed authorization
+// requests (RFC 9126).
+func (o *OpenIDConfig) PushedAuthorizationRequestsSupported() bool {
+	return o.PushedAuthorizationRequestEndpoint != ""
+}
@@ -0,0 +1,289 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/wallet-sdk/internal/testutil"
+	"github.com/trustbloc/wallet-sdk/pkg/openid4ci"
+)
+
+// mockAuthorizationServerHandler mimics an authorization server that's separate from the issuer and that only
+// publishes OAuth 2.0 authorization server metadata (RFC 8414). The authorization server's identifier has a path
+// component (/tenant), so the metadata is served from /.well-known/oauth-authorization-server/tenant.
+type mockAuthorizationServerHandler struct {
+	t                     *testing.T
+	metadataRequestPaths  []string
+	receivedTokenRequests int
+	// issuer overrides the issuer in the metadata, which is otherwise the authorization server's identifier.
+	issuer string
+}
+
+func (m *mockAuthorizationServerHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	var err error
+
+	switch request.URL.Path {
+	case "/.well-known/oauth-authorization-server/tenant":
+		m.metadataRequestPaths = append(m.metadataRequestPaths, request.URL.Path)
+
+		issuer := m.issuer
+		if issuer == "" {
+			issuer = "http://" + request.Host + "/tenant"
+		}
+
+		_, err = fmt.Fprintf(writer, `{"issuer":"%[2]s","authorization_endpoint":"%[1]s/tenant/authorize",`+
+			`"token_endpoint":"%[1]s/tenant/token"}`, "http://"+request.Host, issuer)
+	case "/tenant/token":
+		m.receivedTokenRequests++
+
+		writer.Header().Set("Content-Type", "application/json")
+		_, err = writer.Write([]byte(`{"access_token":"accessToken","token_type":"bearer","c_nonce":"nonce"}`))
+	default:
+		m.metadataRequestPaths = append(m.metadataRequestPaths, request.URL.Path)
+
+		writer.WriteHeader(http.StatusNotFound)
+	}
+
+	require.NoError(m.t, err)
+}
+
+// mockIssuerWithSeparateAuthorizationServerHandler mimics an issuer whose metadata names a separate authorization
+// server. The issuer itself doesn't publish any authorization server metadata unless issuerOpenIDConfig is set.
+type mockIssuerWithSeparateAuthorizationServerHandler struct {
+	t                      *testing.T
+	authorizationServerURL string
+	issuerOpenIDConfig     string
+	issuerASMetadata       string
+}
+
+func (m *mockIssuerWithSeparateAuthorizationServerHandler) ServeHTTP(writer http.ResponseWriter,
+	request *http.Request,
+) {
+	var err error
+
+	switch request.URL.Path {
+	case "/.well-known/openid-credential-issuer":
+		issuerMetadata := strings.Replace(sampleDraft13IssuerMetadata, "%[1]s/oidc/authorize", "%[2]s", 1)
+
+		_, err = fmt.Fprintf(writer, issuerMetadata, "http://"+request.Host, m.authorizationServerURL)
+	case "/.well-known/openid-configuration":
+		if m.issuerOpenIDConfig == "" {
+			writer.WriteHeader(http.StatusNotFound)
+
+			break
+		}
+
+		_, err = fmt.Fprintf(writer, m.issuerOpenIDConfig, "http://"+request.Host)
+	case "/.well-known/oauth-authorization-server":
+		if m.issuerASMetadata == "" {
+			writer.WriteHeader(http.StatusNotFound)
+
+			break
+		}
+
+		_, err = fmt.Fprintf(writer, m.issuerASMetadata, "http://"+request.Host)
+	case "/oidc/token":
+		writer.Header().Set("Content-Type", "application/json")
+		_, err = writer.Write([]byte(`{"access_token":"accessToken","token_type":"bearer","c_nonce":"nonce"}`))
+	case "/credential":
+		_, err = writer.Write(sampleCredentialResponse)
+	default:
+		writer.WriteHeader(http.StatusNotFound)
+	}
+
+	require.NoError(m.t, err)
+}
+
+func TestInteraction_AuthorizationServerDiscovery(t *testing.T) {
+	t.Run("Separate authorization server that only publishes RFC 8414 metadata", func(t *testing.T) {
+		authorizationServerHandler := &mockAuthorizationServerHandler{t: t}
+
+		authorizationServer := httptest.NewServer(authorizationServerHandler)
+		defer authorizationServer.Close()
+
+		issuer := httptest.NewServer(&mockIssuerWithSeparateAuthorizationServerHandler{
+			t:                      t,
+			authorizationServerURL: authorizationServer.URL + "/tenant",
+		})
+		defer issuer.Close()
+
+		t.Run("Pre-auth flow", func(t *testing.T) {
+			interaction, err := openid4ci.NewInteraction(createDraft13CredentialOfferIssuanceURI(t, issuer.URL, nil),
+				getTestClientConfig(t))
+			require.NoError(t, err)
+
+			credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID})
+			require.NoError(t, err)
+			require.Len(t, credentials, 1)
+
+			require.Equal(t, 1, authorizationServerHandler.receivedTokenRequests)
+			require.Equal(t, []string{"/.well-known/oauth-authorization-server/tenant"},
+				authorizationServerHandler.metadataRequestPaths)
+		})
+		t.Run("Auth flow", func(t *testing.T) {
+			interaction := newInteraction(t, toCredentialOfferIssuanceURI(t, createDraft13AuthCodeCredentialOffer(
+				issuer.URL)))
+
+			authURL, err := interaction.CreateAuthorizationURL("clientID", "redirectURI")
+			require.NoError(t, err)
+
+			parsedAuthURL, err := url.Parse(authURL)
+			require.NoError(t, err)
+			require.Equal(t, authorizationServer.URL+"/tenant/authorize",
+				parsedAuthURL.Scheme+"://"+parsedAuthURL.Host+parsedAuthURL.Path)
+		})
+	})
+	t.Run("Falls back to the issuer's own OpenID configuration", func(t *testing.T) {
+		authorizationServer := httptest.NewServer(http.NotFoundHandler())
+		defer authorizationServer.Close()
+
+		issuer := httptest.NewServer(&mockIssuerWithSeparateAuthorizationServerHandler{
+			t:                      t,
+			authorizationServerURL: authorizationServer.URL,
+			issuerOpenIDConfig:     `{"token_endpoint":"%[1]s/oidc/token"}`,
+		})
+		defer issuer.Close()
+
+		interaction, err := openid4ci.NewInteraction(createDraft13CredentialOfferIssuanceURI(t, issuer.URL, nil),
+			getTestClientConfig(t))
+		require.NoError(t, err)
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID})
+		require.NoError(t, err)
+		require.Len(t, credentials, 1)
+	})
+	t.Run("Falls back to the issuer's own RFC 8414 metadata", func(t *testing.T) {
+		authorizationServer := httptest.NewServer(http.NotFoundHandler())
+		defer authorizationServer.Close()
+
+		issuer := httptest.NewServer(&mockIssuerWithSeparateAuthorizationServerHandler{
+			t:                      t,
+			authorizationServerURL: authorizationServer.URL,
+			issuerASMetadata:       `{"issuer":"%[1]s","token_endpoint":"%[1]s/oidc/token"}`,
+		})
+		defer issuer.Close()
+
+		interaction, err := openid4ci.NewInteraction(createDraft13CredentialOfferIssuanceURI(t, issuer.URL, nil),
+			getTestClientConfig(t))
+		require.NoError(t, err)
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID})
+		require.NoError(t, err)
+		require.Len(t, credentials, 1)
+	})
+	t.Run("RFC 8414 metadata with a mismatched issuer", func(t *testing.T) {
+		authorizationServer := httptest.NewServer(&mockAuthorizationServerHandler{
+			t:      t,
+			issuer: "https://attacker.example.com",
+		})
+		defer authorizationServer.Close()
+
+		t.Run("Falls back to the issuer's own OpenID configuration", func(t *testing.T) {
+			issuer := httptest.NewServer(&mockIssuerWithSeparateAuthorizationServerHandler{
+				t:                      t,
+				authorizationServerURL: authorizationServer.URL + "/tenant",
+				issuerOpenIDConfig:     `{"token_endpoint":"%[1]s/oidc/token"}`,
+			})
+			defer issuer.Close()
+
+			interaction, err := openid4ci.NewInteraction(createDraft13CredentialOfferIssuanceURI(t, issuer.URL, nil),
+				getTestClientConfig(t))
+			require.NoError(t, err)
+
+			credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID})
+			require.NoError(t, err)
+			require.Len(t, credentials, 1)
+		})
+		t.Run("No other metadata available", func(t *testing.T) {
+			issuer := httptest.NewServer(&mockIssuerWithSeparateAuthorizationServerHandler{
+				t:                      t,
+				authorizationServerURL: authorizationServer.URL + "/tenant",
+			})
+			defer issuer.Close()
+
+			interaction, err := openid4ci.NewInteraction(createDraft13CredentialOfferIssuanceURI(t, issuer.URL, nil),
+				getTestClientConfig(t))
+			require.NoError(t, err)
+
+			credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID})
+			testutil.RequireErrorContains(t, err, "ISSUER_OPENID_FETCH_FAILED")
+			testutil.RequireErrorContains(t, err, `issuer "https://attacker.example.com" in the authorization `+
+				"server metadata from "+authorizationServer.URL+"/.well-known/oauth-authorization-server/tenant "+
+				`doesn't match the authorization server "`+authorizationServer.URL+`/tenant"`)
+			require.Nil(t, credentials)
+		})
+	})
+	t.Run("Issuer's own RFC 8414 metadata without an issuer", func(t *testing.T) {
+		authorizationServer := httptest.NewServer(http.NotFoundHandler())
+		defer authorizationServer.Close()
+
+		issuer := httptest.NewServer(&mockIssuerWithSeparateAuthorizationServerHandler{
+			t:                      t,
+			authorizationServerURL: authorizationServer.URL,
+			issuerASMetadata:       `{"token_endpoint":"%[1]s/oidc/token"}`,
+		})
+		defer issuer.Close()
+
+		interaction, err := openid4ci.NewInteraction(createDraft13CredentialOfferIssuanceURI(t, issuer.URL, nil),
+			getTestClientConfig(t))
+		require.NoError(t, err)
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID})
+		testutil.RequireErrorContains(t, err, "ISSUER_OPENID_FETCH_FAILED")
+		require.Nil(t, credentials)
+	})
+	t.Run("No authorization server metadata found anywhere", func(t *testing.T) {
+		authorizationServer := httptest.NewServer(http.NotFoundHandler())
+		defer authorizationServer.Close()
+
+		issuer := httptest.NewServer(&mockIssuerWithSeparateAuthorizationServerHandler{
+			t:                      t,
+			authorizationServerURL: authorizationServer.URL,
+		})
+		defer issuer.Close()
+
+		interaction, err := openid4ci.NewInteraction(createDraft13CredentialOfferIssuanceURI(t, issuer.URL, nil),
+			getTestClientConfig(t))
+		require.NoError(t, err)
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID})
+		testutil.RequireErrorContains(t, err, "ISSUER_OPENID_FETCH_FAILED")
+		testutil.RequireErrorContains(t, err, "expected status code 200 but got status code 404")
+		require.Nil(t, credentials)
+	})
+}
+
+func TestOpenIDConfig_Capabilities(t *testing.T) {
+	t.Run("Capabilities advertised", func(t *testing.T) {
+		config := &openid4ci.OpenIDConfig{
+			PushedAuthorizationRequestEndpoint: "https://example.com/par",
+			DPoPSigningAlgValuesSupported:      []string{"ES256"},
+			TokenEndpointAuthMethodsSupported:  []string{"none", "private_key_jwt"},
+		}
+
+		require.True(t, config.PushedAuthorizationRequestsSupported())
+		require.True(t, config.DPoPSupported())
+		require.True(t, config.TokenEndpointAuthMethodSupported("private_key_jwt"))
+		require.False(t, config.TokenEndpointAuthMethodSupported("client_secret_basic"))
+	})
+	t.Run("Nothing advertised", func(t *testing.T) {
+		config := &openid4ci.OpenIDConfig{}
+
+		require.False(t, config.PushedAuthorizationRequestsSupported())
+		require.False(t, config.DPoPSupported())
+		require.True(t, config.TokenEndpointAuthMethodSupported("client_secret_basic"))
+		require.False(t, config.TokenEndpointAuthMethodSupported("private_key_jwt"))
+	})
+}
@@ -0,0 +1,108 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/trustbloc/wallet-sdk/pkg/api"
+	"github.com/trustbloc/wallet-sdk/pkg/walleterror"
+)
+
+// ClientAttestationConfig contains what's needed for OAuth 2.0 attestation-based client authentication, as defined in
+// https://datatracker.ietf.org/doc/html/draft-ietf-oauth-attestation-based-client-auth. It's used by issuers that
+// only issue credentials to certified wallet instances.
+// When it's set, a proof-of-possession JWT is created for every token request, and it's sent along with the client
+// attestation as the client assertion.
+type ClientAttestationConfig struct {
+	// Attestation is the client (wallet instance) attestation JWT, as issued by the wallet provider's backend.
+	// Its sub claim is used as the wallet's client ID.
+	Attestation string
+	// Signer signs the proof-of-possession JWTs. It must use the wallet instance key that the attestation is bound to
+	// (via its cnf claim).
+	Signer api.JWTSigner
+}
+
+// clientAuthenticationFromConfig returns the client authentication that the given config specifies (if any).
+func clientAuthenticationFromConfig(config *ClientConfig) (*ClientAuthentication, error) {
+	if config.ClientAuthentication != nil {
+		if config.ClientAttestation != nil {
+			return nil, walleterror.NewValidationError(
+				module,
+				InvalidClientAuthenticationCode,
+				InvalidClientAuthenticationError,
+				errors.New("client authentication and client attestation can't both be set"))
+		}
+
+		err := config.ClientAuthentication.validate()
+		if err != nil {
+			return nil, err
+		}
+
+		return config.ClientAuthentication, nil
+	}
+
+	if config.ClientAttestation == nil {
+		return nil, nil //nolint:nilnil // No client authentication is a valid outcome.
+	}
+
+	clientID, err := clientAttestationSubject(config.ClientAttestation.Attestation)
+	if err != nil {
+		return nil, walleterror.NewValidationError(
+			module,
+			InvalidClientAuthenticationCode,
+			InvalidClientAuthenticationError,
+			fmt.Errorf("invalid client attestation: %w", err))
+	}
+
+	clientAuthentication := &ClientAuthentication{
+		ClientID:          clientID,
+		Method:            ClientAuthMethodAttestJWTClientAuth,
+		Signer:            config.ClientAttestation.Signer,
+		ClientAttestation: config.ClientAttestation.Attestation,
+	}
+
+	err = clientAuthentication.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return clientAuthentication, nil
+}
+
+// clientAttestationSubject returns the sub claim from the given client attestation. The attestation's signature isn't
+// checked, since that's up to the authorization server.
+func clientAttestationSubject(attestation string) (string, error) {
+	parts := strings.Split(attestation, ".")
+	if len(parts) != 3 { //nolint:gomnd // A JWS in compact serialization has three parts.
+		return "", errors.New("client attestation must be a JWT in compact serialization")
+	}
+
+	claimsBytes, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("failed to decode client attestation claims: %w", err)
+	}
+
+	var claims struct {
+		Subject string `json:"sub"`
+	}
+
+	err = json.Unmarshal(claimsBytes, &claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to unmarshal client attestation claims: %w", err)
+	}
+
+	if claims.Subject == "" {
+		return "", errors.New("client attestation is missing the sub claim")
+	}
+
+	return claims.Subject, nil
+}
@@ -0,0 +1,159 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci_test
+
+import (
+	"encoding/base64"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/wallet-sdk/internal/testutil"
+	"github.com/trustbloc/wallet-sdk/pkg/openid4ci"
+)
+
+const sampleWalletInstanceID = "https://wallet-provider.example.com/instance/1234"
+
+func TestInteraction_ClientAttestation(t *testing.T) {
+	attestation := createTestClientAttestation(t, `{"iss":"https://wallet-provider.example.com",`+
+		`"sub":"`+sampleWalletInstanceID+`","cnf":{"jwk":{"kty":"OKP","crv":"Ed25519","x":"abc"}}}`)
+
+	t.Run("Pre-auth flow", func(t *testing.T) {
+		handler := &mockClientAuthIssuerServerHandler{t: t}
+
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		config := getTestClientConfig(t)
+		config.ClientAttestation = &openid4ci.ClientAttestationConfig{
+			Attestation: attestation,
+			Signer:      &jwtSignerMock{keyID: mockKeyID},
+		}
+
+		interaction, err := openid4ci.NewInteraction(createDraft13CredentialOfferIssuanceURI(t, server.URL, nil),
+			config)
+		require.NoError(t, err)
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID})
+		require.NoError(t, err)
+		require.Len(t, credentials, 1)
+
+		require.Len(t, handler.receivedTokenRequests, 1)
+		requireValidClientAttestationAssertion(t, handler.receivedTokenRequests[0].form, attestation,
+			server.URL+"/oidc/token")
+	})
+	t.Run("Auth flow", func(t *testing.T) {
+		handler := &mockClientAuthIssuerServerHandler{t: t}
+
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		config := getTestClientConfig(t)
+		config.ClientAttestation = &openid4ci.ClientAttestationConfig{
+			Attestation: attestation,
+			Signer:      &jwtSignerMock{keyID: mockKeyID},
+		}
+
+		interaction, err := openid4ci.NewInteraction(toCredentialOfferIssuanceURI(t,
+			createDraft13AuthCodeCredentialOffer(server.URL)), config)
+		require.NoError(t, err)
+
+		authURL, err := interaction.CreateAuthorizationURL(sampleWalletInstanceID, "redirectURI")
+		require.NoError(t, err)
+
+		parsedAuthURL, err := url.Parse(authURL)
+		require.NoError(t, err)
+
+		credentials, err := interaction.RequestCredentialWithAuth(&jwtSignerMock{keyID: mockKeyID},
+			"redirectURI?code=1234&state="+parsedAuthURL.Query().Get("state"))
+		require.NoError(t, err)
+		require.Len(t, credentials, 1)
+
+		require.Len(t, handler.receivedTokenRequests, 1)
+		requireValidClientAttestationAssertion(t, handler.receivedTokenRequests[0].form, attestation,
+			server.URL+"/oidc/token")
+		require.NotEmpty(t, handler.receivedTokenRequests[0].form.Get("code_verifier"))
+	})
+	t.Run("Invalid client attestation config", func(t *testing.T) {
+		testCases := []struct {
+			name        string
+			config      *openid4ci.ClientAttestationConfig
+			expectedErr string
+		}{
+			{
+				name:        "Attestation isn't a JWT",
+				config:      &openid4ci.ClientAttestationConfig{Attestation: "invalid", Signer: &jwtSignerMock{}},
+				expectedErr: "invalid client attestation: client attestation must be a JWT in compact serialization",
+			},
+			{
+				name: "Attestation claims can't be decoded",
+				config: &openid4ci.ClientAttestationConfig{
+					Attestation: "header.!!!.signature",
+					Signer:      &jwtSignerMock{},
+				},
+				expectedErr: "invalid client attestation: failed to decode client attestation claims",
+			},
+			{
+				name: "Attestation is missing the sub claim",
+				config: &openid4ci.ClientAttestationConfig{
+					Attestation: createTestClientAttestation(t, `{"iss":"https://wallet-provider.example.com"}`),
+					Signer:      &jwtSignerMock{},
+				},
+				expectedErr: "invalid client attestation: client attestation is missing the sub claim",
+			},
+			{
+				name:        "Missing signer",
+				config:      &openid4ci.ClientAttestationConfig{Attestation: attestation},
+				expectedErr: "a client attestation and a signer are required for the attest_jwt_client_auth method",
+			},
+		}
+
+		for _, testCase := range testCases {
+			t.Run(testCase.name, func(t *testing.T) {
+				config := getTestClientConfig(t)
+				config.ClientAttestation = testCase.config
+
+				interaction, err := openid4ci.NewInteraction(
+					createCredentialOfferIssuanceURI(t, "example.com", false), config)
+				testutil.RequireErrorContains(t, err,
+					"INVALID_CLIENT_AUTHENTICATION(OCI0-0021):"+testCase.expectedErr)
+				require.Nil(t, interaction)
+			})
+		}
+	})
+}
+
+func createTestClientAttestation(t *testing.T, claims string) string {
+	t.Helper()
+
+	return base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"ES256","typ":"wallet-attestation+jwt"}`)) + "." +
+		base64.RawURLEncoding.EncodeToString([]byte(claims)) + ".c2lnbmF0dXJl"
+}
+
+func requireValidClientAttestationAssertion(t *testing.T, tokenRequestForm url.Values, expectedAttestation,
+	expectedAudience string,
+) {
+	t.Helper()
+
+	require.Equal(t, sampleWalletInstanceID, tokenRequestForm.Get("client_id"))
+	require.Equal(t, "urn:ietf:params:oauth:client-assertion-type:jwt-client-attestation",
+		tokenRequestForm.Get("client_assertion_type"))
+
+	assertionParts := strings.Split(tokenRequestForm.Get("client_assertion"), "~")
+	require.Len(t, assertionParts, 2)
+	require.Equal(t, expectedAttestation, assertionParts[0])
+
+	proofOfPossession := parseDPoPProof(t, assertionParts[1])
+	require.Equal(t, "oauth-client-attestation-pop+jwt", proofOfPossession.headers["typ"])
+	require.Equal(t, sampleWalletInstanceID, proofOfPossession.claims["iss"])
+	require.Equal(t, expectedAudience, proofOfPossession.claims["aud"])
+	require.NotEmpty(t, proofOfPossession.claims["jti"])
+	require.NotEmpty(t, proofOfPossession.claims["exp"])
+}
@@ -0,0 +1,299 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hyperledger/aries-framework-go/component/kmscrypto/doc/jose"
+	"github.com/hyperledger/aries-framework-go/component/models/jwt"
+	"golang.org/x/oauth2"
+
+	"github.com/trustbloc/wallet-sdk/pkg/api"
+	goapioauth2 "github.com/trustbloc/wallet-sdk/pkg/oauth2"
+	"github.com/trustbloc/wallet-sdk/pkg/walleterror"
+)
+
+// Token endpoint client authentication methods, as registered in
+// https://www.iana.org/assignments/oauth-parameters/oauth-parameters.xhtml#token-endpoint-auth-method.
+const (
+	ClientAuthMethodNone              = "none"
+	ClientAuthMethodClientSecretBasic = "client_secret_basic"
+	ClientAuthMethodClientSecretPost  = "client_secret_post"
+	ClientAuthMethodPrivateKeyJWT     = "private_key_jwt"
+	// ClientAuthMethodAttestJWTClientAuth is OAuth 2.0 attestation-based client authentication, as defined in
+	// https://datatracker.ietf.org/doc/html/draft-ietf-oauth-attestation-based-client-auth.
+	ClientAuthMethodAttestJWTClientAuth = "attest_jwt_client_auth"
+)
+
+const (
+	clientAssertionType            = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+	clientAttestationAssertionType = "urn:ietf:params:oauth:client-assertion-type:jwt-client-attestation"
+	clientAttestationPoPHeaderType = "oauth-client-attestation-pop+jwt"
+	clientAssertionLifetime        = 5 * time.Minute
+)
+
+// ClientAuthentication specifies how a wallet authenticates itself at the issuer's token endpoint.
+// Wallets that are public clients don't need this. Confidential wallets (e.g. ones that registered themselves using
+// dynamic client registration) can set it using ClientConfig.ClientAuthentication.
+type ClientAuthentication struct {
+	// ClientID is the wallet's client ID at the issuer's authorization server. For the authorization code flow,
+	// it must match the client ID passed in to Interaction.CreateAuthorizationURL.
+	ClientID string
+	// Method is the token endpoint authentication method. It must be one of the ClientAuthMethod constants.
+	Method string
+	// ClientSecret is required for the client_secret_basic and client_secret_post methods.
+	ClientSecret string
+	// Signer is used to sign client assertions. It's required for the private_key_jwt method.
+	// For the attest_jwt_client_auth method, it's used to sign the client attestation proof-of-possession JWT, and so
+	// it must use the wallet instance key that the client attestation is bound to.
+	Signer api.JWTSigner
+	// ClientAttestation is the client (wallet instance) attestation JWT. It's required for the
+	// attest_jwt_client_auth method.
+	ClientAttestation string
+}
+
+type clientAssertionClaims struct {
+	Issuer    string `json:"iss"`
+	Subject   string `json:"sub"`
+	Audience  string `json:"aud"`
+	ID        string `json:"jti"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// NewClientAuthentication creates a ClientAuthentication object for a client that was registered using
+// dynamic client registration. The method is taken from the registered token endpoint authentication method, which
+// per RFC 7591 is client_secret_basic if the registration response doesn't specify one.
+// The signer is only needed (and may otherwise be nil) if the registered method is private_key_jwt.
+func NewClientAuthentication(registration *goapioauth2.RegisterClientResponse, signer api.JWTSigner,
+) (*ClientAuthentication, error) {
+	if registration == nil {
+		return nil, walleterror.NewValidationError(
+			module,
+			InvalidClientAuthenticationCode,
+			InvalidClientAuthenticationError,
+			errors.New("registration response must be provided"))
+	}
+
+	method := ClientAuthMethodClientSecretBasic
+
+	if registration.ClientMetadata != nil && registration.TokenEndpointAuthMethod != "" {
+		method = registration.TokenEndpointAuthMethod
+	}
+
+	clientAuthentication := &ClientAuthentication{
+		ClientID:     registration.ClientID,
+		Method:       method,
+		ClientSecret: registration.ClientSecret,
+		Signer:       signer,
+	}
+
+	err := clientAuthentication.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return clientAuthentication, nil
+}
+
+func (c *ClientAuthentication) validate() error {
+	var err error
+
+	switch {
+	case c.ClientID == "":
+		err = errors.New("client ID must be provided")
+	case c.Method == ClientAuthMethodNone:
+	case c.Method == ClientAuthMethodClientSecretBasic || c.Method == ClientAuthMethodClientSecretPost:
+		if c.ClientSecret == "" {
+			err = fmt.Errorf("a client secret is required for the %s method", c.Method)
+		}
+	case c.Method == ClientAuthMethodPrivateKeyJWT:
+		if c.Signer == nil {
+			err = fmt.Errorf("a signer is required for the %s method", c.Method)
+		}
+	case c.Method == ClientAuthMethodAttestJWTClientAuth:
+		if c.ClientAttestation == "" || c.Signer == nil {
+			err = fmt.Errorf("a client attestation and a signer are required for the %s method", c.Method)
+		}
+	default:
+		err = fmt.Errorf("unsupported token endpoint authentication method: %s", c.Method)
+	}
+
+	if err != nil {
+		return walleterror.NewValidationError(
+			module,
+			InvalidClientAuthenticationCode,
+			InvalidClientAuthenticationError,
+			err)
+	}
+
+	return nil
+}
+
+// checkSupported fails if the authorization server's metadata lists the token endpoint authentication methods that
+// it supports and this client authentication's method isn't one of them. Many authorization servers don't publish
+// that list, so a missing list isn't treated as an error.
+func (c *ClientAuthentication) checkSupported(openIDConfig *OpenIDConfig) error {
+	if len(openIDConfig.TokenEndpointAuthMethodsSupported) == 0 ||
+		openIDConfig.TokenEndpointAuthMethodSupported(c.Method) {
+		return nil
+	}
+
+	return walleterror.NewValidationError(
+		module,
+		InvalidClientAuthenticationCode,
+		InvalidClientAuthenticationError,
+		fmt.Errorf("the authorization server doesn't support the %s token endpoint authentication method "+
+			"(supported methods: %s)", c.Method, strings.Join(openIDConfig.TokenEndpointAuthMethodsSupported, ", ")))
+}
+
+// addToTokenRequest adds client authentication to a token request that's made without the OAuth2 library. It's also
+// used for pushed authorization requests, which per RFC 9126 use the same client authentication as token requests.
+func (c *ClientAuthentication) addToTokenRequest(params url.Values, headers http.Header,
+	openIDConfig *OpenIDConfig,
+) error {
+	err := c.checkSupported(openIDConfig)
+	if err != nil {
+		return err
+	}
+
+	switch c.Method {
+	case ClientAuthMethodClientSecretBasic:
+		// Per https://datatracker.ietf.org/doc/html/rfc6749#section-2.3.1, the credentials are form-encoded first.
+		credentials := url.QueryEscape(c.ClientID) + ":" + url.QueryEscape(c.ClientSecret)
+
+		headers.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(credentials)))
+	case ClientAuthMethodClientSecretPost:
+		params.Set("client_id", c.ClientID)
+		params.Set("client_secret", c.ClientSecret)
+	case ClientAuthMethodPrivateKeyJWT, ClientAuthMethodAttestJWTClientAuth:
+		assertionType, assertion, err := c.createAssertion(openIDConfig)
+		if err != nil {
+			return err
+		}
+
+		params.Set("client_id", c.ClientID)
+		params.Set("client_assertion_type", assertionType)
+		params.Set("client_assertion", assertion)
+	default:
+		params.Set("client_id", c.ClientID)
+	}
+
+	return nil
+}
+
+// configureOAuth2Exchange sets up the given OAuth2 config so that the OAuth2 library uses this client authentication
+// for the token request. Any additional token request parameters that are needed are returned.
+func (c *ClientAuthentication) configureOAuth2Exchange(config *oauth2.Config, openIDConfig *OpenIDConfig,
+) ([]oauth2.AuthCodeOption, error) {
+	if config.ClientID != c.ClientID {
+		return nil, walleterror.NewValidationError(
+			module,
+			InvalidClientAuthenticationCode,
+			InvalidClientAuthenticationError,
+			errors.New("client authentication's client ID doesn't match the client ID used "+
+				"to create the authorization URL"))
+	}
+
+	err := c.checkSupported(openIDConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	config.ClientSecret = c.ClientSecret
+	config.Endpoint.AuthStyle = oauth2.AuthStyleInParams
+
+	switch c.Method {
+	case ClientAuthMethodClientSecretBasic:
+		config.Endpoint.AuthStyle = oauth2.AuthStyleInHeader
+	case ClientAuthMethodPrivateKeyJWT, ClientAuthMethodAttestJWTClientAuth:
+		config.ClientSecret = ""
+
+		assertionType, assertion, err := c.createAssertion(openIDConfig)
+		if err != nil {
+			return nil, err
+		}
+
+		return []oauth2.AuthCodeOption{
+			oauth2.SetAuthURLParam("client_assertion_type", assertionType),
+			oauth2.SetAuthURLParam("client_assertion", assertion),
+		}, nil
+	case ClientAuthMethodNone:
+		config.ClientSecret = ""
+	}
+
+	return nil, nil
+}
+
+// createAssertion returns the client assertion type and client assertion for the private_key_jwt and
+// attest_jwt_client_auth methods.
+func (c *ClientAuthentication) createAssertion(openIDConfig *OpenIDConfig) (string, string, error) {
+	if c.Method == ClientAuthMethodPrivateKeyJWT {
+		clientAssertion, err := c.createClientAssertion(openIDConfig.TokenEndpoint, jose.Headers{})
+
+		return clientAssertionType, clientAssertion, err
+	}
+
+	// The proof-of-possession JWT's audience is the authorization server's issuer identifier. Not all authorization
+	// servers publish one, in which case the token endpoint is used instead.
+	audience := openIDConfig.Issuer
+	if audience == "" {
+		audience = openIDConfig.TokenEndpoint
+	}
+
+	proofOfPossession, err := c.createClientAssertion(audience,
+		jose.Headers{jose.HeaderType: clientAttestationPoPHeaderType})
+	if err != nil {
+		return "", "", err
+	}
+
+	return clientAttestationAssertionType, c.ClientAttestation + "~" + proofOfPossession, nil
+}
+
+// createClientAssertion creates a client assertion as defined in
+// https://datatracker.ietf.org/doc/html/rfc7523#section-3. The client attestation proof-of-possession JWT uses the
+// same claims.
+func (c *ClientAuthentication) createClientAssertion(audience string, headers jose.Headers) (string, error) {
+	now := time.Now()
+
+	claims := &clientAssertionClaims{
+		Issuer:    c.ClientID,
+		Subject:   c.ClientID,
+		Audience:  audience,
+		ID:        uuid.NewString(),
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(clientAssertionLifetime).Unix(),
+	}
+
+	token, err := jwt.NewSigned(claims, headers, c.Signer)
+	if err != nil {
+		return "", walleterror.NewExecutionError(
+			module,
+			JWTSigningFailedCode,
+			JWTSigningFailedError,
+			fmt.Errorf("failed to sign client assertion: %w", err))
+	}
+
+	clientAssertion, err := token.Serialize(false)
+	if err != nil {
+		return "", walleterror.NewExecutionError(
+			module,
+			JWTSigningFailedCode,
+			JWTSigningFailedError,
+			fmt.Errorf("failed to serialize client assertion: %w", err))
+	}
+
+	return clientAssertion, nil
+}
@@ -0,0 +1,352 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci_test
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/wallet-sdk/internal/testutil"
+	"github.com/trustbloc/wallet-sdk/pkg/oauth2"
+	"github.com/trustbloc/wallet-sdk/pkg/openid4ci"
+)
+
+type receivedTokenRequest struct {
+	form                url.Values
+	authorizationHeader string
+}
+
+type mockClientAuthIssuerServerHandler struct {
+	t                     *testing.T
+	receivedTokenRequests []*receivedTokenRequest
+}
+
+func (m *mockClientAuthIssuerServerHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	var err error
+
+	switch request.URL.Path {
+	case "/.well-known/openid-configuration":
+		_, err = fmt.Fprintf(writer, `{"token_endpoint":"http://%s/oidc/token"}`, request.Host)
+	case "/.well-known/openid-credential-issuer":
+		_, err = fmt.Fprintf(writer, sampleDraft13IssuerMetadata, "http://"+request.Host)
+	case "/oidc/token":
+		require.NoError(m.t, request.ParseForm())
+
+		m.receivedTokenRequests = append(m.receivedTokenRequests, &receivedTokenRequest{
+			form:                request.PostForm,
+			authorizationHeader: request.Header.Get("Authorization"),
+		})
+
+		writer.Header().Set("Content-Type", "application/json")
+		_, err = writer.Write([]byte(`{"access_token":"accessToken","token_type":"bearer","c_nonce":"nonce"}`))
+	case "/credential":
+		_, err = writer.Write(sampleCredentialResponse)
+	}
+
+	require.NoError(m.t, err)
+}
+
+func TestInteraction_ClientAuthentication(t *testing.T) {
+	const (
+		clientID     = "client ID"
+		clientSecret = "client:secret"
+	)
+
+	expectedBasicAuthHeader := "Basic " + base64.StdEncoding.EncodeToString(
+		[]byte(url.QueryEscape(clientID)+":"+url.QueryEscape(clientSecret)))
+
+	t.Run("Pre-auth flow", func(t *testing.T) {
+		t.Run("client_secret_basic", func(t *testing.T) {
+			tokenRequest := doPreAuthFlowWithClientAuth(t, &openid4ci.ClientAuthentication{
+				ClientID:     clientID,
+				Method:       openid4ci.ClientAuthMethodClientSecretBasic,
+				ClientSecret: clientSecret,
+			})
+
+			require.Equal(t, expectedBasicAuthHeader, tokenRequest.authorizationHeader)
+			require.False(t, tokenRequest.form.Has("client_secret"))
+		})
+		t.Run("client_secret_post", func(t *testing.T) {
+			tokenRequest := doPreAuthFlowWithClientAuth(t, &openid4ci.ClientAuthentication{
+				ClientID:     clientID,
+				Method:       openid4ci.ClientAuthMethodClientSecretPost,
+				ClientSecret: clientSecret,
+			})
+
+			require.Empty(t, tokenRequest.authorizationHeader)
+			require.Equal(t, clientID, tokenRequest.form.Get("client_id"))
+			require.Equal(t, clientSecret, tokenRequest.form.Get("client_secret"))
+		})
+		t.Run("private_key_jwt", func(t *testing.T) {
+			tokenRequest := doPreAuthFlowWithClientAuth(t, &openid4ci.ClientAuthentication{
+				ClientID: clientID,
+				Method:   openid4ci.ClientAuthMethodPrivateKeyJWT,
+				Signer:   &jwtSignerMock{keyID: mockKeyID},
+			})
+
+			require.Empty(t, tokenRequest.authorizationHeader)
+			require.Equal(t, clientID, tokenRequest.form.Get("client_id"))
+			require.False(t, tokenRequest.form.Has("client_secret"))
+			require.Equal(t, "urn:ietf:params:oauth:client-assertion-type:jwt-bearer",
+				tokenRequest.form.Get("client_assertion_type"))
+
+			assertion := parseDPoPProof(t, tokenRequest.form.Get("client_assertion"))
+			require.Equal(t, clientID, assertion.claims["iss"])
+			require.Equal(t, clientID, assertion.claims["sub"])
+			require.Contains(t, assertion.claims["aud"], "/oidc/token")
+			require.NotEmpty(t, assertion.claims["jti"])
+			require.Greater(t, assertion.claims["exp"], assertion.claims["iat"])
+		})
+		t.Run("none", func(t *testing.T) {
+			tokenRequest := doPreAuthFlowWithClientAuth(t, &openid4ci.ClientAuthentication{
+				ClientID: clientID,
+				Method:   openid4ci.ClientAuthMethodNone,
+			})
+
+			require.Empty(t, tokenRequest.authorizationHeader)
+			require.Equal(t, clientID, tokenRequest.form.Get("client_id"))
+		})
+		t.Run("Fail to sign client assertion", func(t *testing.T) {
+			server := httptest.NewServer(&mockClientAuthIssuerServerHandler{t: t})
+			defer server.Close()
+
+			interaction := newInteractionWithClientAuth(t, createDraft13CredentialOfferIssuanceURI(t, server.URL, nil),
+				&openid4ci.ClientAuthentication{
+					ClientID: clientID,
+					Method:   openid4ci.ClientAuthMethodPrivateKeyJWT,
+					Signer:   &jwtSignerMock{keyID: mockKeyID, Err: errors.New("signing failure")},
+				})
+
+			credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID})
+			testutil.RequireErrorContains(t, err, "JWT_SIGNING_FAILED")
+			testutil.RequireErrorContains(t, err, "failed to sign client assertion")
+			require.Nil(t, credentials)
+		})
+	})
+	t.Run("Auth flow", func(t *testing.T) {
+		t.Run("client_secret_basic", func(t *testing.T) {
+			tokenRequest := doAuthFlowWithClientAuth(t, clientID, &openid4ci.ClientAuthentication{
+				ClientID:     clientID,
+				Method:       openid4ci.ClientAuthMethodClientSecretBasic,
+				ClientSecret: clientSecret,
+			})
+
+			require.Equal(t, expectedBasicAuthHeader, tokenRequest.authorizationHeader)
+			require.False(t, tokenRequest.form.Has("client_secret"))
+		})
+		t.Run("client_secret_post", func(t *testing.T) {
+			tokenRequest := doAuthFlowWithClientAuth(t, clientID, &openid4ci.ClientAuthentication{
+				ClientID:     clientID,
+				Method:       openid4ci.ClientAuthMethodClientSecretPost,
+				ClientSecret: clientSecret,
+			})
+
+			require.Empty(t, tokenRequest.authorizationHeader)
+			require.Equal(t, clientID, tokenRequest.form.Get("client_id"))
+			require.Equal(t, clientSecret, tokenRequest.form.Get("client_secret"))
+		})
+		t.Run("private_key_jwt", func(t *testing.T) {
+			tokenRequest := doAuthFlowWithClientAuth(t, clientID, &openid4ci.ClientAuthentication{
+				ClientID: clientID,
+				Method:   openid4ci.ClientAuthMethodPrivateKeyJWT,
+				Signer:   &jwtSignerMock{keyID: mockKeyID},
+			})
+
+			require.Empty(t, tokenRequest.authorizationHeader)
+			require.Equal(t, clientID, tokenRequest.form.Get("client_id"))
+			require.False(t, tokenRequest.form.Has("client_secret"))
+			require.NotEmpty(t, tokenRequest.form.Get("code_verifier"))
+			require.Equal(t, "urn:ietf:params:oauth:client-assertion-type:jwt-bearer",
+				tokenRequest.form.Get("client_assertion_type"))
+
+			assertion := parseDPoPProof(t, tokenRequest.form.Get("client_assertion"))
+			require.Equal(t, clientID, assertion.claims["sub"])
+		})
+		t.Run("Client ID mismatch", func(t *testing.T) {
+			server := httptest.NewServer(&mockClientAuthIssuerServerHandler{t: t})
+			defer server.Close()
+
+			interaction := newInteractionWithClientAuth(t,
+				toCredentialOfferIssuanceURI(t, createDraft13AuthCodeCredentialOffer(server.URL)),
+				&openid4ci.ClientAuthentication{
+					ClientID:     clientID,
+					Method:       openid4ci.ClientAuthMethodClientSecretPost,
+					ClientSecret: clientSecret,
+				})
+
+			authURL, err := interaction.CreateAuthorizationURL("other client ID", "redirectURI")
+			require.NoError(t, err)
+
+			parsedAuthURL, err := url.Parse(authURL)
+			require.NoError(t, err)
+
+			credentials, err := interaction.RequestCredentialWithAuth(&jwtSignerMock{keyID: mockKeyID},
+				"redirectURI?code=1234&state="+parsedAuthURL.Query().Get("state"))
+			require.EqualError(t, err, "INVALID_CLIENT_AUTHENTICATION(OCI0-0021):client authentication's "+
+				"client ID doesn't match the client ID used to create the authorization URL")
+			require.Nil(t, credentials)
+		})
+	})
+	t.Run("Invalid client authentication", func(t *testing.T) {
+		testCases := []struct {
+			name                 string
+			clientAuthentication *openid4ci.ClientAuthentication
+			expectedErr          string
+		}{
+			{
+				name:                 "Missing client ID",
+				clientAuthentication: &openid4ci.ClientAuthentication{Method: openid4ci.ClientAuthMethodNone},
+				expectedErr:          "client ID must be provided",
+			},
+			{
+				name: "Missing client secret",
+				clientAuthentication: &openid4ci.ClientAuthentication{
+					ClientID: clientID,
+					Method:   openid4ci.ClientAuthMethodClientSecretBasic,
+				},
+				expectedErr: "a client secret is required for the client_secret_basic method",
+			},
+			{
+				name: "Missing signer",
+				clientAuthentication: &openid4ci.ClientAuthentication{
+					ClientID: clientID,
+					Method:   openid4ci.ClientAuthMethodPrivateKeyJWT,
+				},
+				expectedErr: "a signer is required for the private_key_jwt method",
+			},
+			{
+				name: "Unsupported method",
+				clientAuthentication: &openid4ci.ClientAuthentication{
+					ClientID: clientID,
+					Method:   "tls_client_auth",
+				},
+				expectedErr: "unsupported token endpoint authentication method: tls_client_auth",
+			},
+		}
+
+		for _, testCase := range testCases {
+			t.Run(testCase.name, func(t *testing.T) {
+				config := getTestClientConfig(t)
+				config.ClientAuthentication = testCase.clientAuthentication
+
+				interaction, err := openid4ci.NewInteraction(createCredentialOfferIssuanceURI(t, "example.com", false),
+					config)
+				require.EqualError(t, err, "INVALID_CLIENT_AUTHENTICATION(OCI0-0021):"+testCase.expectedErr)
+				require.Nil(t, interaction)
+			})
+		}
+	})
+}
+
+func TestNewClientAuthentication(t *testing.T) {
+	t.Run("Method taken from registration", func(t *testing.T) {
+		clientAuthentication, err := openid4ci.NewClientAuthentication(&oauth2.RegisterClientResponse{
+			ClientID:       "clientID",
+			ClientSecret:   "clientSecret",
+			ClientMetadata: &oauth2.ClientMetadata{TokenEndpointAuthMethod: "client_secret_post"},
+		}, nil)
+		require.NoError(t, err)
+		require.Equal(t, &openid4ci.ClientAuthentication{
+			ClientID:     "clientID",
+			Method:       openid4ci.ClientAuthMethodClientSecretPost,
+			ClientSecret: "clientSecret",
+		}, clientAuthentication)
+	})
+	t.Run("Method defaults to client_secret_basic", func(t *testing.T) {
+		clientAuthentication, err := openid4ci.NewClientAuthentication(&oauth2.RegisterClientResponse{
+			ClientID:     "clientID",
+			ClientSecret: "clientSecret",
+		}, nil)
+		require.NoError(t, err)
+		require.Equal(t, openid4ci.ClientAuthMethodClientSecretBasic, clientAuthentication.Method)
+	})
+	t.Run("private_key_jwt without a signer", func(t *testing.T) {
+		clientAuthentication, err := openid4ci.NewClientAuthentication(&oauth2.RegisterClientResponse{
+			ClientID:       "clientID",
+			ClientMetadata: &oauth2.ClientMetadata{TokenEndpointAuthMethod: "private_key_jwt"},
+		}, nil)
+		require.EqualError(t, err, "INVALID_CLIENT_AUTHENTICATION(OCI0-0021):a signer is required for the "+
+			"private_key_jwt method")
+		require.Nil(t, clientAuthentication)
+	})
+	t.Run("Missing registration", func(t *testing.T) {
+		clientAuthentication, err := openid4ci.NewClientAuthentication(nil, nil)
+		require.EqualError(t, err, "INVALID_CLIENT_AUTHENTICATION(OCI0-0021):registration response must be "+
+			"provided")
+		require.Nil(t, clientAuthentication)
+	})
+}
+
+func doPreAuthFlowWithClientAuth(t *testing.T, clientAuthentication *openid4ci.ClientAuthentication,
+) *receivedTokenRequest {
+	t.Helper()
+
+	handler := &mockClientAuthIssuerServerHandler{t: t}
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	interaction := newInteractionWithClientAuth(t, createDraft13CredentialOfferIssuanceURI(t, server.URL, nil),
+		clientAuthentication)
+
+	credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID})
+	require.NoError(t, err)
+	require.Len(t, credentials, 1)
+
+	require.Len(t, handler.receivedTokenRequests, 1)
+
+	return handler.receivedTokenRequests[0]
+}
+
+func doAuthFlowWithClientAuth(t *testing.T, clientID string, clientAuthentication *openid4ci.ClientAuthentication,
+) *receivedTokenRequest {
+	t.Helper()
+
+	handler := &mockClientAuthIssuerServerHandler{t: t}
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	interaction := newInteractionWithClientAuth(t,
+		toCredentialOfferIssuanceURI(t, createDraft13AuthCodeCredentialOffer(server.URL)), clientAuthentication)
+
+	authURL, err := interaction.CreateAuthorizationURL(clientID, "redirectURI")
+	require.NoError(t, err)
+
+	parsedAuthURL, err := url.Parse(authURL)
+	require.NoError(t, err)
+
+	credentials, err := interaction.RequestCredentialWithAuth(&jwtSignerMock{keyID: mockKeyID},
+		"redirectURI?code=1234&state="+parsedAuthURL.Query().Get("state"))
+	require.NoError(t, err)
+	require.Len(t, credentials, 1)
+
+	require.Len(t, handler.receivedTokenRequests, 1)
+
+	return handler.receivedTokenRequests[0]
+}
+
+func newInteractionWithClientAuth(t *testing.T, requestURI string,
+	clientAuthentication *openid4ci.ClientAuthentication,
+) *openid4ci.Interaction {
+	t.Helper()
+
+	config := getTestClientConfig(t)
+	config.ClientAuthentication = clientAuthentication
+
+	interaction, err := openid4ci.NewInteraction(requestURI, config)
+	require.NoError(t, err)
+
+	return interaction
+}
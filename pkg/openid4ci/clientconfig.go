@@ -44,6 +44,22 @@ type ClientConfig struct {
 	DocumentLoader                   ld.DocumentLoader // If not specified, then a network-based loader will be used.
 	NetworkDocumentLoaderHTTPTimeout *time.Duration    // Only used if the default network-based loader is used.
 	HTTPClient                       *http.Client
+	// InteractionStateKey is the secret key used to protect serialized Interaction state from tampering.
+	// It must be at least 32 bytes long, and must be the same key when resuming an Interaction.
+	// It's only needed if Interaction.Serialize or ResumeInteraction is used.
+	InteractionStateKey []byte
+	// InteractionStateLifetime is how long serialized Interaction state remains valid for.
+	// If not specified, then a default of one hour is used.
+	InteractionStateLifetime *time.Duration
+	// DPoP enables DPoP (RFC 9449) for this interaction. If not specified, then plain bearer tokens are used.
+	DPoP *DPoPConfig
+	// ClientAttestation enables attestation-based client authentication at the issuer's token endpoint.
+	// If not specified, then client authentication is only done if ClientAuthentication is set.
+	ClientAttestation *ClientAttestationConfig
+	// ClientAuthentication is the client authentication to use at the issuer's token endpoint for wallets that are
+	// confidential clients. It's also used when resuming an interaction and when reissuing a credential.
+	// It can't be combined with ClientAttestation.
+	ClientAuthentication *ClientAuthentication
 }
 
 func validateRequiredParameters(config *ClientConfig) error {
@@ -63,6 +79,25 @@ func validateRequiredParameters(config *ClientConfig) error {
 			errors.New("no DID resolver provided"))
 	}
 
+	if config.DPoP != nil && (config.DPoP.Signer == nil || config.DPoP.PublicKey == nil) {
+		return walleterror.NewValidationError(
+			module,
+			InvalidDPoPConfigCode,
+			InvalidDPoPConfigError,
+			errors.New("DPoP config must specify both a signer and a public key"))
+	}
+
+	if config.DPoP != nil {
+		err := config.DPoP.checkKeyMatchesSigner()
+		if err != nil {
+			return walleterror.NewValidationError(
+				module,
+				InvalidDPoPConfigCode,
+				InvalidDPoPConfigError,
+				err)
+		}
+	}
+
 	return nil
 }
 
@@ -90,4 +125,9 @@ func setDefaults(config *ClientConfig) {
 	if config.HTTPClient == nil {
 		config.HTTPClient = &http.Client{Timeout: api.DefaultHTTPTimeout}
 	}
+
+	if config.InteractionStateLifetime == nil {
+		defaultInteractionStateLifetime := time.Hour
+		config.InteractionStateLifetime = &defaultInteractionStateLifetime
+	}
 }
@@ -0,0 +1,157 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/wallet-sdk/internal/testutil"
+	"github.com/trustbloc/wallet-sdk/pkg/openid4ci"
+)
+
+// cancellingHandler cancels a context once a request for the given path is received, and then waits for the client
+// to give up on the request before returning.
+type cancellingHandler struct {
+	http.Handler
+	cancelOnPath string
+	cancel       context.CancelFunc
+}
+
+func (c *cancellingHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if request.URL.Path != c.cancelOnPath {
+		c.Handler.ServeHTTP(writer, request)
+
+		return
+	}
+
+	// The server only notices that the client has gone away once the request body has been read.
+	_, err := io.Copy(io.Discard, request.Body)
+	if err != nil {
+		return
+	}
+
+	c.cancel()
+
+	<-request.Context().Done()
+}
+
+func TestNewInteractionContext(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		interaction, err := openid4ci.NewInteractionContext(context.Background(),
+			createCredentialOfferIssuanceURI(t, "https://example.com", false), getTestClientConfig(t))
+		require.NoError(t, err)
+		require.NotNil(t, interaction)
+	})
+	t.Run("Context cancelled while fetching the credential offer", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		interaction, err := openid4ci.NewInteractionContext(ctx,
+			"openid-credential-offer://?credential_offer_uri=https://example.com/credential-offer",
+			getTestClientConfig(t))
+		testutil.RequireErrorContains(t, err, "INVALID_CREDENTIAL_OFFER")
+		testutil.RequireErrorContains(t, err, context.Canceled.Error())
+		require.Nil(t, interaction)
+	})
+}
+
+func TestInteraction_ContextCancellation(t *testing.T) {
+	issuerServerHandler := &mockIssuerServerHandler{
+		t:                  t,
+		credentialResponse: sampleCredentialResponse,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := httptest.NewServer(&cancellingHandler{
+		Handler:      issuerServerHandler,
+		cancelOnPath: "/oidc/token",
+		cancel:       cancel,
+	})
+	defer server.Close()
+
+	issuerServerHandler.openIDConfig = &openid4ci.OpenIDConfig{
+		TokenEndpoint: fmt.Sprintf("%s/oidc/token", server.URL),
+	}
+
+	issuerServerHandler.issuerMetadata = fmt.Sprintf(`{"credential_endpoint":"%s/credential"}`, server.URL)
+
+	interaction := newInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, false))
+
+	t.Run("Cancelled while a request is in flight", func(t *testing.T) {
+		credentials, err := interaction.RequestCredentialWithPreAuthContext(ctx, &jwtSignerMock{keyID: mockKeyID},
+			openid4ci.WithPIN("1234"))
+		testutil.RequireErrorContains(t, err, "TOKEN_FETCH_FAILED")
+		testutil.RequireErrorContains(t, err, context.Canceled.Error())
+		require.Nil(t, credentials)
+	})
+	t.Run("Cancelled before any requests are made", func(t *testing.T) {
+		// The interaction above already fetched the issuer's metadata, so a new one is needed.
+		interaction = newInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, false))
+
+		proofRequirements, err := interaction.ProofRequirementsContext(ctx)
+		testutil.RequireErrorContains(t, err, "METADATA_FETCH_FAILED")
+		testutil.RequireErrorContains(t, err, context.Canceled.Error())
+		require.Nil(t, proofRequirements)
+
+		offeredCredentialsDisplay, err := interaction.OfferedCredentialsDisplayContext(ctx, "")
+		testutil.RequireErrorContains(t, err, "METADATA_FETCH_FAILED")
+		require.Nil(t, offeredCredentialsDisplay)
+
+		supported, err := interaction.DynamicClientRegistrationSupportedContext(ctx)
+		testutil.RequireErrorContains(t, err, "ISSUER_OPENID_FETCH_FAILED")
+		testutil.RequireErrorContains(t, err, context.Canceled.Error())
+		require.False(t, supported)
+
+		endpoint, err := interaction.DynamicClientRegistrationEndpointContext(ctx)
+		testutil.RequireErrorContains(t, err, "ISSUER_OPENID_FETCH_FAILED")
+		require.Empty(t, endpoint)
+
+		err = interaction.SendNotificationContext(ctx, createTestNotificationHandle(server.URL),
+			openid4ci.NotificationEventCredentialAccepted, "")
+		testutil.RequireErrorContains(t, err, "NOTIFICATION_FAILED")
+		testutil.RequireErrorContains(t, err, context.Canceled.Error())
+	})
+}
+
+func TestStandaloneFunctions_ContextCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	t.Run("RequestDeferredCredentialContext", func(t *testing.T) {
+		result, err := openid4ci.RequestDeferredCredentialContext(ctx, &openid4ci.DeferredCredential{
+			DeferredCredentialEndpoint: "https://example.com/deferred_credential",
+			AcceptanceToken:            "acceptanceToken",
+		}, getTestClientConfig(t))
+		testutil.RequireErrorContains(t, err, "DEFERRED_CREDENTIAL_FETCH_FAILED")
+		testutil.RequireErrorContains(t, err, context.Canceled.Error())
+		require.Nil(t, result)
+	})
+	t.Run("ReissueCredentialContext", func(t *testing.T) {
+		vc, reissuanceToken, err := openid4ci.ReissueCredentialContext(ctx,
+			createTestReissuanceToken("https://example.com"), &jwtSignerMock{keyID: mockKeyID},
+			getTestClientConfig(t))
+		testutil.RequireErrorContains(t, err, "TOKEN_FETCH_FAILED")
+		testutil.RequireErrorContains(t, err, context.Canceled.Error())
+		require.Nil(t, vc)
+		require.Nil(t, reissuanceToken)
+	})
+	t.Run("SendNotificationContext", func(t *testing.T) {
+		err := openid4ci.SendNotificationContext(ctx, createTestNotificationHandle("https://example.com"),
+			openid4ci.NotificationEventCredentialAccepted, "", getTestClientConfig(t))
+		testutil.RequireErrorContains(t, err, "NOTIFICATION_FAILED")
+		testutil.RequireErrorContains(t, err, context.Canceled.Error())
+	})
+}
@@ -7,7 +7,8 @@ SPDX-License-Identifier: Apache-2.0
 package openid4ci
 
 type createAuthorizationURLOpts struct {
-	scopes []string
+	scopes                        []string
+	usePushedAuthorizationRequest bool
 }
 
 // CreateAuthorizationURLOpt is an option for the CreateAuthorizationURL method.
@@ -20,6 +21,16 @@ func WithScopes(scopes []string) CreateAuthorizationURLOpt {
 	}
 }
 
+// WithPushedAuthorizationRequest is an option for the CreateAuthorizationURL method that forces the use of a
+// pushed authorization request (RFC 9126). Without this option, a pushed authorization request is still used if the
+// authorization server's metadata specifies a pushed authorization request endpoint, but a regular authorization URL
+// is created otherwise. With this option, an error is returned instead.
+func WithPushedAuthorizationRequest() CreateAuthorizationURLOpt {
+	return func(opts *createAuthorizationURLOpts) {
+		opts.usePushedAuthorizationRequest = true
+	}
+}
+
 func processCreateAuthorizationURLOpts(opts []CreateAuthorizationURLOpt) *createAuthorizationURLOpts {
 	processedOpts := &createAuthorizationURLOpts{}
 
@@ -0,0 +1,161 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	metadatafetcher "github.com/trustbloc/wallet-sdk/pkg/internal/issuermetadata"
+	"github.com/trustbloc/wallet-sdk/pkg/walleterror"
+)
+
+// resolveCredentialConfigurationIDs determines the credential types and formats for a credential offer that follows
+// draft 13 (or later) by looking up each credential configuration ID in the issuer's metadata.
+// The fetched metadata is kept so that later steps in the flow don't need to fetch it again.
+func (i *Interaction) resolveCredentialConfigurationIDs(ctx context.Context, credentialConfigurationIDs []string,
+) error {
+	err := i.fetchIssuerMetadataIfNeeded(ctx)
+	if err != nil {
+		return err
+	}
+
+	i.credentialConfigurationIDs = credentialConfigurationIDs
+	i.credentialTypes = make([][]string, len(credentialConfigurationIDs))
+	i.credentialFormats = make([]string, len(credentialConfigurationIDs))
+
+	for index, credentialConfigurationID := range credentialConfigurationIDs {
+		supportedCredential, found := i.issuerMetadata.SupportedCredential(credentialConfigurationID)
+		if !found {
+			return walleterror.NewValidationError(
+				module,
+				InvalidCredentialOfferCode,
+				InvalidCredentialOfferError,
+				fmt.Errorf("credential configuration ID (%s) at index %d of credential_configuration_ids object "+
+					"not found in the issuer's metadata", credentialConfigurationID, index))
+		}
+
+		err = validateCredentialFormat(supportedCredential.Format, index, "credential_configuration_ids")
+		if err != nil {
+			return err
+		}
+
+		i.credentialTypes[index] = supportedCredential.Types
+		i.credentialFormats[index] = supportedCredential.Format
+	}
+
+	return nil
+}
+
+func (i *Interaction) fetchIssuerMetadataIfNeeded(ctx context.Context) error {
+	if i.issuerMetadata != nil {
+		return nil
+	}
+
+	var err error
+
+	i.issuerMetadata, err = metadatafetcher.Get(ctx, i.issuerURI, i.httpClient, i.metricsLogger,
+		newInteractionEventText)
+	if err != nil {
+		return walleterror.NewExecutionError(
+			module,
+			MetadataFetchFailedCode,
+			MetadataFetchFailedError,
+			fmt.Errorf("failed to get issuer metadata: %w", err))
+	}
+
+	return nil
+}
+
+// credentialConfigurationID returns the credential configuration ID for the credential at the given index in the
+// credential offer, or an empty string if the credential offer follows draft 11.
+func (i *Interaction) credentialConfigurationID(index int) string {
+	if len(i.credentialConfigurationIDs) == 0 {
+		return ""
+	}
+
+	return i.credentialConfigurationIDs[index]
+}
+
+// credentialIdentifier returns the credential identifier that the issuer assigned (in the token response) to the
+// credential at the given index in the credential offer, or an empty string if there isn't one.
+func (i *Interaction) credentialIdentifier(index int) string {
+	credentialIdentifiers := i.credentialIdentifiers[i.credentialConfigurationID(index)]
+	if len(credentialIdentifiers) == 0 {
+		return ""
+	}
+
+	return credentialIdentifiers[0]
+}
+
+// setCredentialIdentifiers stores the credential identifiers from the given token response authorization details,
+// keyed by credential configuration ID. Only issuers following draft 13 (or later) return these.
+func (i *Interaction) setCredentialIdentifiers(authorizationDetails []tokenAuthorizationDetails) {
+	i.credentialIdentifiers = make(map[string][]string)
+
+	for _, authorizationDetail := range authorizationDetails {
+		if authorizationDetail.CredentialConfigurationID == "" {
+			continue
+		}
+
+		i.credentialIdentifiers[authorizationDetail.CredentialConfigurationID] = append(
+			i.credentialIdentifiers[authorizationDetail.CredentialConfigurationID],
+			authorizationDetail.CredentialIdentifiers...)
+	}
+}
+
+// authorizationDetailsFromOAuth2Token gets the authorization details (if any) from the token response obtained
+// via the authorization code flow. Since the OAuth2 library only exposes additional fields as raw values,
+// they're converted to the expected type by going through JSON.
+func (i *Interaction) authorizationDetailsFromOAuth2Token() ([]tokenAuthorizationDetails, error) {
+	rawAuthorizationDetails := i.authTokenResponse.Extra("authorization_details")
+	if rawAuthorizationDetails == nil {
+		return nil, nil
+	}
+
+	authorizationDetailsBytes, err := json.Marshal(rawAuthorizationDetails)
+	if err != nil {
+		return nil, err
+	}
+
+	var authorizationDetails []tokenAuthorizationDetails
+
+	err = json.Unmarshal(authorizationDetailsBytes, &authorizationDetails)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal authorization details from token response: %w", err)
+	}
+
+	return authorizationDetails, nil
+}
+
+// newCredentialRequest creates a credential request in the form expected by the version of the spec that the issuer
+// follows. If the issuer assigned a credential identifier, then that's used to identify the credential. Otherwise,
+// the format and types are used, either directly (draft 11) or in a credential definition (draft 13 and later).
+func newCredentialRequest(format string, types []string, credentialConfigurationID, credentialIdentifier,
+	proofJWT string,
+) *credentialRequest {
+	request := &credentialRequest{
+		Proof: proof{
+			ProofType: "jwt", // TODO: https://github.com/trustbloc/wallet-sdk/issues/159 support other proof types
+			JWT:       proofJWT,
+		},
+	}
+
+	switch {
+	case credentialIdentifier != "":
+		request.CredentialIdentifier = credentialIdentifier
+	case credentialConfigurationID != "":
+		request.Format = format
+		request.CredentialDefinition = &credentialDefinition{Types: types}
+	default:
+		request.Format = format
+		request.Types = types
+	}
+
+	return request
+}
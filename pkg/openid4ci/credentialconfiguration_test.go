@@ -0,0 +1,260 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/wallet-sdk/internal/testutil"
+	"github.com/trustbloc/wallet-sdk/pkg/openid4ci"
+)
+
+const (
+	sampleCredentialConfigurationID = "VerifiedEmployee_JWT"
+	sampleDraft13IssuerMetadata     = `{"credential_issuer":"%[1]s","credential_endpoint":"%[1]s/credential",` +
+		`"authorization_servers":["%[1]s/oidc/authorize"],` +
+		`"credential_configurations_supported":{"VerifiedEmployee_JWT":{"format":"jwt_vc_json",` +
+		`"credential_definition":{"type":["VerifiableCredential","VerifiedEmployee"]}},` +
+		`"UnsupportedFormat":{"format":"mso_mdoc"}}}`
+)
+
+type mockDraft13IssuerServerHandler struct {
+	t                          *testing.T
+	tokenResponse              string
+	receivedTokenRequestForm   url.Values
+	receivedCredentialRequests []map[string]interface{}
+}
+
+func (m *mockDraft13IssuerServerHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	var err error
+
+	switch request.URL.Path {
+	case "/.well-known/openid-configuration":
+		_, err = fmt.Fprintf(writer, `{"token_endpoint":"http://%s/oidc/token"}`, request.Host)
+	case "/.well-known/openid-credential-issuer":
+		_, err = fmt.Fprintf(writer, sampleDraft13IssuerMetadata, "http://"+request.Host)
+	case "/oidc/token":
+		require.NoError(m.t, request.ParseForm())
+
+		m.receivedTokenRequestForm = request.PostForm
+
+		writer.Header().Set("Content-Type", "application/json")
+		_, err = writer.Write([]byte(m.tokenResponse))
+	case "/credential":
+		var credentialRequest map[string]interface{}
+
+		require.NoError(m.t, json.NewDecoder(request.Body).Decode(&credentialRequest))
+
+		m.receivedCredentialRequests = append(m.receivedCredentialRequests, credentialRequest)
+
+		_, err = writer.Write(sampleCredentialResponse)
+	}
+
+	require.NoError(m.t, err)
+}
+
+func TestInteraction_Draft13CredentialOffer(t *testing.T) {
+	t.Run("Pre-auth flow", func(t *testing.T) {
+		t.Run("Issuer provides credential identifiers", func(t *testing.T) {
+			handler := &mockDraft13IssuerServerHandler{
+				t: t,
+				tokenResponse: `{"access_token":"accessToken","c_nonce":"nonce","authorization_details":` +
+					`[{"type":"openid_credential","credential_configuration_id":"VerifiedEmployee_JWT",` +
+					`"credential_identifiers":["VerifiedEmployee_1"]}]}`,
+			}
+
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			interaction := newInteraction(t, createDraft13CredentialOfferIssuanceURI(t, server.URL,
+				map[string]interface{}{"input_mode": "text", "length": 6, "description": "Check your email"}))
+
+			require.True(t, interaction.PreAuthorizedCodeGrantTypeSupported())
+
+			preAuthorizedCodeGrantParams, err := interaction.PreAuthorizedCodeGrantParams()
+			require.NoError(t, err)
+			require.True(t, preAuthorizedCodeGrantParams.PINRequired())
+			require.Equal(t, &openid4ci.TxCode{
+				InputMode:   "text",
+				Length:      6,
+				Description: "Check your email",
+			}, preAuthorizedCodeGrantParams.TxCode())
+
+			credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+				openid4ci.WithPIN("123456"))
+			require.NoError(t, err)
+			require.Len(t, credentials, 1)
+
+			require.Equal(t, "123456", handler.receivedTokenRequestForm.Get("tx_code"))
+			require.False(t, handler.receivedTokenRequestForm.Has("user_pin"))
+
+			require.Len(t, handler.receivedCredentialRequests, 1)
+			require.Equal(t, "VerifiedEmployee_1", handler.receivedCredentialRequests[0]["credential_identifier"])
+			require.NotContains(t, handler.receivedCredentialRequests[0], "format")
+		})
+		t.Run("Issuer doesn't provide credential identifiers", func(t *testing.T) {
+			handler := &mockDraft13IssuerServerHandler{
+				t:             t,
+				tokenResponse: `{"access_token":"accessToken","c_nonce":"nonce"}`,
+			}
+
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			interaction := newInteraction(t, createDraft13CredentialOfferIssuanceURI(t, server.URL,
+				map[string]interface{}{}))
+
+			preAuthorizedCodeGrantParams, err := interaction.PreAuthorizedCodeGrantParams()
+			require.NoError(t, err)
+			require.True(t, preAuthorizedCodeGrantParams.PINRequired())
+			require.Equal(t, &openid4ci.TxCode{InputMode: "numeric"}, preAuthorizedCodeGrantParams.TxCode())
+
+			credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+				openid4ci.WithPIN("1234"))
+			require.NoError(t, err)
+			require.Len(t, credentials, 1)
+
+			require.Len(t, handler.receivedCredentialRequests, 1)
+			require.Equal(t, "jwt_vc_json", handler.receivedCredentialRequests[0]["format"])
+			require.Equal(t, map[string]interface{}{
+				"type": []interface{}{"VerifiableCredential", "VerifiedEmployee"},
+			}, handler.receivedCredentialRequests[0]["credential_definition"])
+			require.NotContains(t, handler.receivedCredentialRequests[0], "types")
+		})
+		t.Run("Transaction code not required", func(t *testing.T) {
+			server := httptest.NewServer(&mockDraft13IssuerServerHandler{t: t})
+			defer server.Close()
+
+			interaction := newInteraction(t, createDraft13CredentialOfferIssuanceURI(t, server.URL, nil))
+
+			preAuthorizedCodeGrantParams, err := interaction.PreAuthorizedCodeGrantParams()
+			require.NoError(t, err)
+			require.False(t, preAuthorizedCodeGrantParams.PINRequired())
+			require.Nil(t, preAuthorizedCodeGrantParams.TxCode())
+		})
+	})
+	t.Run("Auth flow", func(t *testing.T) {
+		handler := &mockDraft13IssuerServerHandler{
+			t: t,
+			tokenResponse: `{"access_token":"accessToken","token_type":"bearer","c_nonce":"nonce",` +
+				`"authorization_details":[{"type":"openid_credential",` +
+				`"credential_configuration_id":"VerifiedEmployee_JWT","credential_identifiers":["VerifiedEmployee_1"]}]}`,
+		}
+
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		credentialOffer := createDraft13CredentialOffer(server.URL, nil)
+		credentialOffer.Grants = map[string]map[string]interface{}{"authorization_code": {}}
+
+		interaction := newInteraction(t, toCredentialOfferIssuanceURI(t, credentialOffer))
+
+		authURL, err := interaction.CreateAuthorizationURL("clientID", "redirectURI")
+		require.NoError(t, err)
+
+		parsedAuthURL, err := url.Parse(authURL)
+		require.NoError(t, err)
+		require.Equal(t, server.URL+"/oidc/authorize", parsedAuthURL.Scheme+"://"+parsedAuthURL.Host+
+			parsedAuthURL.Path)
+		require.Equal(t, `[{"type":"openid_credential","locations":["`+server.URL+`"],`+
+			`"credential_configuration_id":"VerifiedEmployee_JWT"}]`, parsedAuthURL.Query().Get("authorization_details"))
+
+		credentials, err := interaction.RequestCredentialWithAuth(&jwtSignerMock{keyID: mockKeyID},
+			"redirectURI?code=1234&state="+parsedAuthURL.Query().Get("state"))
+		require.NoError(t, err)
+		require.Len(t, credentials, 1)
+
+		require.Len(t, handler.receivedCredentialRequests, 1)
+		require.Equal(t, "VerifiedEmployee_1", handler.receivedCredentialRequests[0]["credential_identifier"])
+	})
+	t.Run("Credential configuration ID not found in issuer metadata", func(t *testing.T) {
+		handler := &mockDraft13IssuerServerHandler{t: t}
+
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		credentialOffer := createDraft13CredentialOffer(server.URL, nil)
+		credentialOffer.CredentialConfigurationIDs = []string{"UnknownCredential"}
+
+		interaction, err := openid4ci.NewInteraction(toCredentialOfferIssuanceURI(t, credentialOffer),
+			getTestClientConfig(t))
+		require.EqualError(t, err, "INVALID_CREDENTIAL_OFFER(OCI0-0003):credential configuration ID "+
+			"(UnknownCredential) at index 0 of credential_configuration_ids object not found in the issuer's metadata")
+		require.Nil(t, interaction)
+	})
+	t.Run("Unsupported credential format", func(t *testing.T) {
+		handler := &mockDraft13IssuerServerHandler{t: t}
+
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		credentialOffer := createDraft13CredentialOffer(server.URL, nil)
+		credentialOffer.CredentialConfigurationIDs = []string{"UnsupportedFormat"}
+
+		interaction, err := openid4ci.NewInteraction(toCredentialOfferIssuanceURI(t, credentialOffer),
+			getTestClientConfig(t))
+		require.EqualError(t, err, "UNSUPPORTED_CREDENTIAL_TYPE_IN_OFFER(OCI0-0004):unsupported credential type "+
+			"(mso_mdoc) in credential offer at index 0 of credential_configuration_ids object "+
+			"(must be jwt_vc_json or jwt_vc_json-ld)")
+		require.Nil(t, interaction)
+	})
+	t.Run("Fail to get issuer metadata", func(t *testing.T) {
+		interaction, err := openid4ci.NewInteraction(
+			createDraft13CredentialOfferIssuanceURI(t, "example.com", nil), getTestClientConfig(t))
+		testutil.RequireErrorContains(t, err, "METADATA_FETCH_FAILED(OCI1-0007):failed to get issuer metadata")
+		require.Nil(t, interaction)
+	})
+	t.Run("Invalid tx_code", func(t *testing.T) {
+		server := httptest.NewServer(&mockDraft13IssuerServerHandler{t: t})
+		defer server.Close()
+
+		interaction, err := openid4ci.NewInteraction(
+			createDraft13CredentialOfferIssuanceURI(t, server.URL, "invalid"), getTestClientConfig(t))
+		testutil.RequireErrorContains(t, err, "tx_code field value is invalid")
+		require.Nil(t, interaction)
+	})
+}
+
+// createDraft13CredentialOffer creates a credential offer that follows draft 13. If txCode is nil, then the
+// tx_code field is omitted from the pre-authorized code grant.
+func createDraft13CredentialOffer(issuerURL string, txCode interface{}) *openid4ci.CredentialOffer {
+	preAuthorizedCodeGrant := map[string]interface{}{"pre-authorized_code": "preAuthorizedCode"}
+
+	if txCode != nil {
+		preAuthorizedCodeGrant["tx_code"] = txCode
+	}
+
+	return &openid4ci.CredentialOffer{
+		CredentialIssuer:           issuerURL,
+		CredentialConfigurationIDs: []string{sampleCredentialConfigurationID},
+		Grants: map[string]map[string]interface{}{
+			"urn:ietf:params:oauth:grant-type:pre-authorized_code": preAuthorizedCodeGrant,
+		},
+	}
+}
+
+func createDraft13CredentialOfferIssuanceURI(t *testing.T, issuerURL string, txCode interface{}) string {
+	t.Helper()
+
+	return toCredentialOfferIssuanceURI(t, createDraft13CredentialOffer(issuerURL, txCode))
+}
+
+func toCredentialOfferIssuanceURI(t *testing.T, credentialOffer *openid4ci.CredentialOffer) string {
+	t.Helper()
+
+	credentialOfferBytes, err := json.Marshal(credentialOffer)
+	require.NoError(t, err)
+
+	return "openid-credential-offer://?credential_offer=" + url.QueryEscape(string(credentialOfferBytes))
+}
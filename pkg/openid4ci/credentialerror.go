@@ -0,0 +1,216 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/trustbloc/wallet-sdk/pkg/api"
+	"github.com/trustbloc/wallet-sdk/pkg/walleterror"
+)
+
+// Error codes that the issuer's credential (and batch credential) endpoints may return, as defined in
+// https://openid.net/specs/openid-4-verifiable-credential-issuance-1_0.html#name-credential-error-response.
+const (
+	credentialErrorInvalidRequest              = "invalid_request"
+	credentialErrorInvalidToken                = "invalid_token"
+	credentialErrorInvalidCredentialRequest    = "invalid_credential_request"
+	credentialErrorUnsupportedCredentialType   = "unsupported_credential_type"
+	credentialErrorUnsupportedCredentialFormat = "unsupported_credential_format"
+	credentialErrorInvalidProof                = "invalid_proof"
+	credentialErrorInvalidEncryptionParameters = "invalid_encryption_parameters"
+)
+
+// maxInvalidProofRetries is the number of times a credential request is retried with a re-signed proof after the
+// issuer rejects the proof and provides a fresh c_nonce.
+const maxInvalidProofRetries = 2
+
+// credentialRequestError is returned when the issuer's credential (or batch credential) endpoint responds with
+// something other than a 200 status. If the response body is an OAuth 2.0/OpenID4CI error response, then its fields
+// are populated. It's also used for error responses for individual credentials within a batch credential response,
+// in which case the status code is 0.
+type credentialRequestError struct {
+	StatusCode       int    `json:"-"`
+	Body             string `json:"-"`
+	ErrorCode        string `json:"error,omitempty"`
+	ErrorDescription string `json:"error_description,omitempty"`
+	CNonce           string `json:"c_nonce,omitempty"`
+	CNonceExpiresIn  int    `json:"c_nonce_expires_in,omitempty"`
+}
+
+func newCredentialRequestError(statusCode int, responseBytes []byte) *credentialRequestError {
+	requestErr := &credentialRequestError{}
+
+	// The body isn't necessarily an error response, in which case only the status code and body are reported.
+	if json.Unmarshal(responseBytes, requestErr) != nil {
+		requestErr = &credentialRequestError{}
+	}
+
+	requestErr.StatusCode = statusCode
+	requestErr.Body = string(responseBytes)
+
+	return requestErr
+}
+
+func (e *credentialRequestError) Error() string {
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("received error response [%s] from issuer's batch credential endpoint", e.Body)
+	}
+
+	return fmt.Sprintf("received status code [%d] with body [%s] from issuer's credential endpoint",
+		e.StatusCode, e.Body)
+}
+
+// credentialProof is the proof of possession sent with credential requests, along with what's needed to re-sign it
+// if the issuer rejects it.
+type credentialProof struct {
+	jwt       string
+	issuerURI string
+	clientID  string
+	signer    api.JWTSigner
+}
+
+// sign (re-)creates the proof JWT using the given nonce.
+func (p *credentialProof) sign(nonce interface{}) error {
+	proofJWT, err := createProofJWT(p.issuerURI, p.clientID, nonce, p.signer)
+	if err != nil {
+		return err
+	}
+
+	p.jwt = proofJWT
+
+	return nil
+}
+
+// doWithProofRetry calls sendRequest, which must use the current proof JWT. If the issuer rejects the proof with an
+// invalid_proof error and provides a fresh c_nonce, then the proof is re-signed using that nonce and the request is
+// sent again, up to maxInvalidProofRetries times.
+func doWithProofRetry(proof *credentialProof, sendRequest func() ([]byte, error)) ([]byte, error) {
+	for attempt := 0; ; attempt++ {
+		responseBytes, err := sendRequest()
+
+		var requestErr *credentialRequestError
+
+		if err == nil || attempt == maxInvalidProofRetries || !errors.As(err, &requestErr) ||
+			requestErr.ErrorCode != credentialErrorInvalidProof || requestErr.CNonce == "" {
+			return responseBytes, err
+		}
+
+		err = proof.sign(requestErr.CNonce)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// PartialIssuanceError is returned by the RequestCredential methods when some, but not all, of the offered
+// credentials could be issued. The credentials that were issued are still returned along with it, and the
+// Interaction's DeferredCredentials, ReissuanceTokens and NotificationHandles methods cover them as usual.
+type PartialIssuanceError struct {
+	// CredentialErrors maps the index of each offered credential that wasn't issued (in the order the credentials
+	// were offered) to the reason why.
+	CredentialErrors map[int]error
+}
+
+// newPartialIssuanceError combines the errors for the credentials that couldn't be fetched or parsed into a
+// PartialIssuanceError, or returns nil if there weren't any.
+func newPartialIssuanceError(fetchErrs, parseErrs map[int]error) *PartialIssuanceError {
+	if len(fetchErrs) == 0 && len(parseErrs) == 0 {
+		return nil
+	}
+
+	credentialErrs := make(map[int]error, len(fetchErrs)+len(parseErrs))
+
+	for index, err := range fetchErrs {
+		credentialErrs[index] = newCredentialFetchError(err)
+	}
+
+	for index, err := range parseErrs {
+		credentialErrs[index] = walleterror.NewExecutionError(
+			module,
+			CredentialParseFailedCode,
+			CredentialParseError,
+			fmt.Errorf("failed to parse credential: %w", err))
+	}
+
+	return &PartialIssuanceError{CredentialErrors: credentialErrs}
+}
+
+func (e *PartialIssuanceError) Error() string {
+	return fmt.Sprintf("%d of the offered credentials couldn't be issued: %s", len(e.CredentialErrors),
+		joinCredentialErrors(e.CredentialErrors, "credential at index %d: %w"))
+}
+
+// Unwrap returns the errors for the credentials that weren't issued, in the order the credentials were offered.
+func (e *PartialIssuanceError) Unwrap() []error {
+	errs := make([]error, 0, len(e.CredentialErrors))
+
+	for _, index := range sortedIndexes(e.CredentialErrors) {
+		errs = append(errs, e.CredentialErrors[index])
+	}
+
+	return errs
+}
+
+// joinCredentialErrors joins the given errors in index order, formatting each one with its index using the given
+// format string.
+func joinCredentialErrors(credentialErrs map[int]error, format string) error {
+	errs := make([]error, 0, len(credentialErrs))
+
+	for _, index := range sortedIndexes(credentialErrs) {
+		errs = append(errs, fmt.Errorf(format, index, credentialErrs[index]))
+	}
+
+	return errors.Join(errs...)
+}
+
+func sortedIndexes(credentialErrs map[int]error) []int {
+	indexes := make([]int, 0, len(credentialErrs))
+
+	for index := range credentialErrs {
+		indexes = append(indexes, index)
+	}
+
+	sort.Ints(indexes)
+
+	return indexes
+}
+
+// newCredentialFetchError wraps an error that occurred while fetching credentials. If the issuer responded with one
+// of the error codes defined by OpenID4CI, then the corresponding wallet error is used. Otherwise, a generic
+// CREDENTIAL_FETCH_FAILED error is returned.
+func newCredentialFetchError(err error) error {
+	code, errorName := CredentialFetchFailedCode, CredentialFetchFailedError
+
+	var requestErr *credentialRequestError
+
+	if errors.As(err, &requestErr) {
+		switch requestErr.ErrorCode {
+		case credentialErrorInvalidToken:
+			code, errorName = InvalidTokenCode, InvalidTokenError
+		case credentialErrorInvalidRequest, credentialErrorInvalidCredentialRequest:
+			code, errorName = InvalidCredentialRequestCode, InvalidCredentialRequestError
+		case credentialErrorUnsupportedCredentialType:
+			code, errorName = UnsupportedCredentialTypeCode, UnsupportedCredentialTypeError
+		case credentialErrorUnsupportedCredentialFormat:
+			code, errorName = UnsupportedCredentialFormatCode, UnsupportedCredentialFormatError
+		case credentialErrorInvalidProof:
+			code, errorName = InvalidProofCode, InvalidProofError
+		case credentialErrorInvalidEncryptionParameters:
+			code, errorName = InvalidEncryptionParametersCode, InvalidEncryptionParametersError
+		}
+	}
+
+	return walleterror.NewExecutionError(
+		module,
+		code,
+		errorName,
+		fmt.Errorf("failed to get credential response: %w", err))
+}
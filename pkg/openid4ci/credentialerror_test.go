@@ -0,0 +1,468 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/wallet-sdk/internal/testutil"
+	"github.com/trustbloc/wallet-sdk/pkg/openid4ci"
+	"github.com/trustbloc/wallet-sdk/pkg/walleterror"
+)
+
+// mockCredentialErrorIssuerServerHandler responds to the first numberOfErrorResponses credential (or batch
+// credential) requests with the given error response, and to any requests after that with a successful response.
+// If unparsableFirstCredential is set, then the first successful response has a credential that can't be parsed.
+type mockCredentialErrorIssuerServerHandler struct {
+	t                         *testing.T
+	issuerMetadata            string
+	errorResponse             string
+	numberOfErrorResponses    int
+	unparsableFirstCredential bool
+	receivedProofNonces       []interface{}
+	receivedProofHeaders      []map[string]interface{}
+}
+
+func (m *mockCredentialErrorIssuerServerHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	var err error
+
+	switch request.URL.Path {
+	case "/.well-known/openid-configuration":
+		_, err = fmt.Fprintf(writer, `{"token_endpoint":"http://%s/oidc/token"}`, request.Host)
+	case "/.well-known/openid-credential-issuer":
+		_, err = fmt.Fprintf(writer, m.issuerMetadata, "http://"+request.Host)
+	case "/oidc/token":
+		writer.Header().Set("Content-Type", "application/json")
+		_, err = writer.Write([]byte(sampleTokenResponse))
+	case "/credential", "/batch_credential":
+		var credentialRequest struct {
+			Proof struct {
+				JWT string `json:"jwt"`
+			} `json:"proof"`
+			CredentialRequests []struct {
+				Proof struct {
+					JWT string `json:"jwt"`
+				} `json:"proof"`
+			} `json:"credential_requests"`
+		}
+
+		require.NoError(m.t, json.NewDecoder(request.Body).Decode(&credentialRequest))
+
+		proofJWT := credentialRequest.Proof.JWT
+		if len(credentialRequest.CredentialRequests) > 0 {
+			proofJWT = credentialRequest.CredentialRequests[0].Proof.JWT
+		}
+
+		proof := parseDPoPProof(m.t, proofJWT)
+
+		m.receivedProofNonces = append(m.receivedProofNonces, proof.claims["nonce"])
+		m.receivedProofHeaders = append(m.receivedProofHeaders, proof.headers)
+
+		switch {
+		case len(m.receivedProofNonces) <= m.numberOfErrorResponses:
+			writer.Header().Set("Content-Type", "application/json")
+			writer.WriteHeader(http.StatusBadRequest)
+			_, err = writer.Write([]byte(m.errorResponse))
+		case request.URL.Path == "/batch_credential":
+			_, err = writer.Write(createBatchCredentialResponse(m.t, len(credentialRequest.CredentialRequests)))
+		case m.unparsableFirstCredential && len(m.receivedProofNonces) == m.numberOfErrorResponses+1:
+			_, err = writer.Write([]byte(`{"credential":"invalid"}`))
+		default:
+			_, err = writer.Write(sampleCredentialResponse)
+		}
+	}
+
+	require.NoError(m.t, err)
+}
+
+func TestInteraction_CredentialErrorResponses(t *testing.T) {
+	const issuerMetadata = `{"credential_endpoint":"%[1]s/credential"}`
+
+	t.Run("Invalid proof with a fresh c_nonce", func(t *testing.T) {
+		t.Run("Credential endpoint", func(t *testing.T) {
+			handler := &mockCredentialErrorIssuerServerHandler{
+				t:                      t,
+				issuerMetadata:         issuerMetadata,
+				errorResponse:          `{"error":"invalid_proof","c_nonce":"freshNonce","c_nonce_expires_in":86400}`,
+				numberOfErrorResponses: 1,
+			}
+
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			interaction := newInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, false))
+
+			credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+				openid4ci.WithPIN("1234"))
+			require.NoError(t, err)
+			require.Len(t, credentials, 1)
+
+			require.Equal(t, []interface{}{"tZignsnFbp", "freshNonce"}, handler.receivedProofNonces)
+		})
+		t.Run("Batch credential endpoint", func(t *testing.T) {
+			handler := &mockCredentialErrorIssuerServerHandler{
+				t: t,
+				issuerMetadata: `{"credential_endpoint":"%[1]s/credential",` +
+					`"batch_credential_endpoint":"%[1]s/batch_credential"}`,
+				errorResponse:          `{"error":"invalid_proof","c_nonce":"freshNonce"}`,
+				numberOfErrorResponses: 1,
+			}
+
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			interaction := newInteraction(t, createMultiCredentialOfferIssuanceURI(t, server.URL, false))
+
+			credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+				openid4ci.WithPIN("1234"))
+			require.NoError(t, err)
+			require.Len(t, credentials, 2)
+
+			require.Equal(t, []interface{}{"tZignsnFbp", "freshNonce"}, handler.receivedProofNonces)
+		})
+		t.Run("Retries are exhausted", func(t *testing.T) {
+			handler := &mockCredentialErrorIssuerServerHandler{
+				t:                      t,
+				issuerMetadata:         issuerMetadata,
+				errorResponse:          `{"error":"invalid_proof","c_nonce":"freshNonce"}`,
+				numberOfErrorResponses: 10,
+			}
+
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			interaction := newInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, false))
+
+			credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+				openid4ci.WithPIN("1234"))
+			testutil.RequireErrorContains(t, err, "INVALID_PROOF(OCI1-0026):failed to get credential response: "+
+				"credential at index 0: received status code [400]")
+			require.Nil(t, credentials)
+
+			require.Len(t, handler.receivedProofNonces, 3)
+		})
+		t.Run("Fail to re-sign the proof", func(t *testing.T) {
+			handler := &mockCredentialErrorIssuerServerHandler{
+				t:                      t,
+				issuerMetadata:         issuerMetadata,
+				errorResponse:          `{"error":"invalid_proof","c_nonce":"freshNonce"}`,
+				numberOfErrorResponses: 1,
+			}
+
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			interaction := newInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, false))
+
+			signer := &failAfterFirstSignatureSigner{jwtSignerMock: jwtSignerMock{keyID: mockKeyID}}
+
+			credentials, err := interaction.RequestCredentialWithPreAuth(signer, openid4ci.WithPIN("1234"))
+			testutil.RequireErrorContains(t, err, "JWT_SIGNING_FAILED")
+			require.Nil(t, credentials)
+		})
+		t.Run("Reissuance", func(t *testing.T) {
+			handler := &mockCredentialErrorIssuerServerHandler{
+				t:                      t,
+				issuerMetadata:         issuerMetadata,
+				errorResponse:          `{"error":"invalid_proof","c_nonce":"freshNonce"}`,
+				numberOfErrorResponses: 1,
+			}
+
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			vc, _, err := openid4ci.ReissueCredential(createTestReissuanceToken(server.URL),
+				&jwtSignerMock{keyID: mockKeyID}, getTestClientConfig(t))
+			require.NoError(t, err)
+			require.NotNil(t, vc)
+
+			require.Equal(t, []interface{}{"tZignsnFbp", "freshNonce"}, handler.receivedProofNonces)
+		})
+		t.Run("Reissuance retries are exhausted", func(t *testing.T) {
+			handler := &mockCredentialErrorIssuerServerHandler{
+				t:                      t,
+				issuerMetadata:         issuerMetadata,
+				errorResponse:          `{"error":"invalid_proof","c_nonce":"freshNonce"}`,
+				numberOfErrorResponses: 10,
+			}
+
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			vc, _, err := openid4ci.ReissueCredential(createTestReissuanceToken(server.URL),
+				&jwtSignerMock{keyID: mockKeyID}, getTestClientConfig(t))
+			testutil.RequireErrorContains(t, err, "INVALID_PROOF(OCI1-0026):failed to get credential response: "+
+				"received status code [400]")
+			require.Nil(t, vc)
+
+			require.Len(t, handler.receivedProofNonces, 3)
+		})
+	})
+	t.Run("Invalid proof without a fresh c_nonce", func(t *testing.T) {
+		handler := &mockCredentialErrorIssuerServerHandler{
+			t:                      t,
+			issuerMetadata:         issuerMetadata,
+			errorResponse:          `{"error":"invalid_proof","error_description":"signature check failed"}`,
+			numberOfErrorResponses: 1,
+		}
+
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		interaction := newInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, false))
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+			openid4ci.WithPIN("1234"))
+		testutil.RequireErrorContains(t, err, "INVALID_PROOF(OCI1-0026)")
+		testutil.RequireErrorContains(t, err, "signature check failed")
+		require.Nil(t, credentials)
+
+		require.Len(t, handler.receivedProofNonces, 1)
+	})
+	t.Run("Error codes are mapped to wallet errors", func(t *testing.T) {
+		testCases := []struct {
+			errorCode     string
+			expectedError string
+		}{
+			{errorCode: "invalid_token", expectedError: "INVALID_TOKEN(OCI1-0022)"},
+			{errorCode: "invalid_request", expectedError: "INVALID_CREDENTIAL_REQUEST(OCI1-0023)"},
+			{errorCode: "invalid_credential_request", expectedError: "INVALID_CREDENTIAL_REQUEST(OCI1-0023)"},
+			{errorCode: "unsupported_credential_type", expectedError: "UNSUPPORTED_CREDENTIAL_TYPE(OCI1-0024)"},
+			{errorCode: "unsupported_credential_format", expectedError: "UNSUPPORTED_CREDENTIAL_FORMAT(OCI1-0025)"},
+			{errorCode: "invalid_encryption_parameters", expectedError: "INVALID_ENCRYPTION_PARAMETERS(OCI1-0027)"},
+			{errorCode: "server_error", expectedError: "CREDENTIAL_FETCH_FAILED(OCI1-0010)"},
+		}
+
+		for _, testCase := range testCases {
+			t.Run(testCase.errorCode, func(t *testing.T) {
+				handler := &mockCredentialErrorIssuerServerHandler{
+					t:                      t,
+					issuerMetadata:         issuerMetadata,
+					errorResponse:          `{"error":"` + testCase.errorCode + `"}`,
+					numberOfErrorResponses: 1,
+				}
+
+				server := httptest.NewServer(handler)
+				defer server.Close()
+
+				interaction := newInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, false))
+
+				credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+					openid4ci.WithPIN("1234"))
+				testutil.RequireErrorContains(t, err, testCase.expectedError+":failed to get credential response: "+
+					"credential at index 0: received status code [400] with body [{\"error\":\""+
+					testCase.errorCode+"\"}]")
+				require.Nil(t, credentials)
+
+				require.Len(t, handler.receivedProofNonces, 1)
+			})
+		}
+	})
+}
+
+func TestInteraction_PartialIssuance(t *testing.T) {
+	const issuerMetadata = `{"credential_endpoint":"%[1]s/credential"}`
+
+	t.Run("One credential can't be fetched", func(t *testing.T) {
+		handler := &mockCredentialErrorIssuerServerHandler{
+			t:                      t,
+			issuerMetadata:         issuerMetadata,
+			errorResponse:          `{"error":"unsupported_credential_type"}`,
+			numberOfErrorResponses: 1,
+		}
+
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		interaction := newInteraction(t, createMultiCredentialOfferIssuanceURI(t, server.URL, false))
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+			openid4ci.WithPIN("1234"))
+		require.Len(t, credentials, 1)
+		testutil.RequireErrorContains(t, err, "1 of the offered credentials couldn't be issued: credential at "+
+			"index 0: UNSUPPORTED_CREDENTIAL_TYPE(OCI1-0024):failed to get credential response: received status code "+
+			"[400]")
+
+		var partialIssuanceErr *openid4ci.PartialIssuanceError
+
+		require.ErrorAs(t, err, &partialIssuanceErr)
+		require.Len(t, partialIssuanceErr.CredentialErrors, 1)
+		testutil.RequireErrorContains(t, partialIssuanceErr.CredentialErrors[0], "UNSUPPORTED_CREDENTIAL_TYPE")
+
+		var walletErr *walleterror.Error
+
+		require.ErrorAs(t, err, &walletErr)
+		require.Equal(t, "OCI1-0024", walletErr.Code)
+	})
+	t.Run("One credential can't be parsed", func(t *testing.T) {
+		handler := &mockCredentialErrorIssuerServerHandler{
+			t:                         t,
+			issuerMetadata:            issuerMetadata,
+			unparsableFirstCredential: true,
+		}
+
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		interaction := newInteraction(t, createMultiCredentialOfferIssuanceURI(t, server.URL, false))
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+			openid4ci.WithPIN("1234"))
+		require.Len(t, credentials, 1)
+
+		var partialIssuanceErr *openid4ci.PartialIssuanceError
+
+		require.ErrorAs(t, err, &partialIssuanceErr)
+		require.Len(t, partialIssuanceErr.CredentialErrors, 1)
+		testutil.RequireErrorContains(t, partialIssuanceErr.CredentialErrors[0],
+			"CREDENTIAL_PARSE_FAILED(OCI1-0012):failed to parse credential")
+	})
+	t.Run("No credentials can be fetched or parsed", func(t *testing.T) {
+		handler := &mockCredentialErrorIssuerServerHandler{
+			t:                         t,
+			issuerMetadata:            issuerMetadata,
+			errorResponse:             `{"error":"server_error"}`,
+			numberOfErrorResponses:    1,
+			unparsableFirstCredential: true,
+		}
+
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		interaction := newInteraction(t, createMultiCredentialOfferIssuanceURI(t, server.URL, false))
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+			openid4ci.WithPIN("1234"))
+		testutil.RequireErrorContains(t, err, "CREDENTIAL_FETCH_FAILED(OCI1-0010):failed to get credential response: "+
+			"credential at index 0: received status code [400]")
+		testutil.RequireErrorContains(t, err, "credential at index 1: failed to parse credential")
+		require.Nil(t, credentials)
+	})
+	t.Run("Batch credential endpoint returns an error for one credential", func(t *testing.T) {
+		for _, testCase := range []struct {
+			name                  string
+			failedCredentialEntry string
+			expectedErr           string
+		}{
+			{
+				name:                  "Error response",
+				failedCredentialEntry: `{"error":"unsupported_credential_type"}`,
+				expectedErr: "UNSUPPORTED_CREDENTIAL_TYPE(OCI1-0024):failed to get credential response: received " +
+					`error response [{"error":"unsupported_credential_type"}] from issuer's batch credential endpoint`,
+			},
+			{
+				name:                  "No credential or transaction ID",
+				failedCredentialEntry: `{"format":"jwt_vc_json"}`,
+				expectedErr: "CREDENTIAL_FETCH_FAILED(OCI1-0010):failed to get credential response: the issuer's " +
+					"batch credential endpoint didn't return a credential",
+			},
+		} {
+			t.Run(testCase.name, func(t *testing.T) {
+				batchCredentialResponse, err := json.Marshal(map[string]interface{}{
+					"credential_responses": []json.RawMessage{
+						json.RawMessage(testCase.failedCredentialEntry), sampleCredentialResponse,
+					},
+				})
+				require.NoError(t, err)
+
+				issuerServerHandler := &mockIssuerServerHandler{
+					t:                           t,
+					batchCredentialResponse:     batchCredentialResponse,
+					credentialRequestShouldFail: true,
+				}
+
+				server := httptest.NewServer(issuerServerHandler)
+				defer server.Close()
+
+				issuerServerHandler.openIDConfig = &openid4ci.OpenIDConfig{TokenEndpoint: server.URL + "/oidc/token"}
+				issuerServerHandler.issuerMetadata = fmt.Sprintf(`{"credential_endpoint":"%[1]s/credential",`+
+					`"batch_credential_endpoint":"%[1]s/batch_credential"}`, server.URL)
+
+				interaction := newInteraction(t, createMultiCredentialOfferIssuanceURI(t, server.URL, false))
+
+				credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+					openid4ci.WithPIN("1234"))
+				require.Len(t, credentials, 1)
+
+				var partialIssuanceErr *openid4ci.PartialIssuanceError
+
+				require.ErrorAs(t, err, &partialIssuanceErr)
+				require.Len(t, partialIssuanceErr.CredentialErrors, 1)
+				require.EqualError(t, partialIssuanceErr.CredentialErrors[0], testCase.expectedErr)
+			})
+		}
+	})
+	t.Run("Batch credential endpoint returns errors for every credential", func(t *testing.T) {
+		issuerServerHandler := &mockIssuerServerHandler{
+			t:                           t,
+			batchCredentialResponse:     []byte(`{"credential_responses":[{"error":"invalid_proof"},{}]}`),
+			credentialRequestShouldFail: true,
+		}
+
+		server := httptest.NewServer(issuerServerHandler)
+		defer server.Close()
+
+		issuerServerHandler.openIDConfig = &openid4ci.OpenIDConfig{TokenEndpoint: server.URL + "/oidc/token"}
+		issuerServerHandler.issuerMetadata = fmt.Sprintf(`{"credential_endpoint":"%[1]s/credential",`+
+			`"batch_credential_endpoint":"%[1]s/batch_credential"}`, server.URL)
+
+		interaction := newInteraction(t, createMultiCredentialOfferIssuanceURI(t, server.URL, false))
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+			openid4ci.WithPIN("1234"))
+		testutil.RequireErrorContains(t, err, "credential at index 0: received error response "+
+			`[{"error":"invalid_proof"}] from issuer's batch credential endpoint`)
+		testutil.RequireErrorContains(t, err, "credential at index 1: the issuer's batch credential endpoint "+
+			"didn't return a credential")
+		require.Nil(t, credentials)
+	})
+	t.Run("No credentials can be parsed", func(t *testing.T) {
+		handler := &mockCredentialErrorIssuerServerHandler{
+			t:                         t,
+			issuerMetadata:            issuerMetadata,
+			unparsableFirstCredential: true,
+		}
+
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		interaction := newInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, false))
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+			openid4ci.WithPIN("1234"))
+		testutil.RequireErrorContains(t, err, "CREDENTIAL_PARSE_FAILED(OCI1-0012):failed to parse credential from "+
+			"credential response at index 0")
+		require.Nil(t, credentials)
+
+		var partialIssuanceErr *openid4ci.PartialIssuanceError
+
+		require.False(t, errors.As(err, &partialIssuanceErr))
+	})
+}
+
+// failAfterFirstSignatureSigner behaves like jwtSignerMock for the first signature, and fails after that.
+type failAfterFirstSignatureSigner struct {
+	jwtSignerMock
+	signatures int
+}
+
+func (s *failAfterFirstSignatureSigner) Sign(data []byte) ([]byte, error) {
+	s.signatures++
+
+	if s.signatures > 1 {
+		return nil, fmt.Errorf("signing failed")
+	}
+
+	return s.jwtSignerMock.Sign(data)
+}
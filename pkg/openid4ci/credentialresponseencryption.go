@@ -0,0 +1,179 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/google/uuid"
+
+	"github.com/trustbloc/wallet-sdk/pkg/models/issuer"
+)
+
+// The key management algorithms and content encryption algorithms that the wallet supports for encrypted credential
+// responses, in order of preference.
+var (
+	supportedCredentialResponseEncryptionAlgs = []string{ //nolint:gochecknoglobals // read-only
+		string(jose.ECDH_ES), string(jose.ECDH_ES_A256KW), string(jose.ECDH_ES_A192KW), string(jose.ECDH_ES_A128KW),
+	}
+	supportedCredentialResponseEncryptionEncs = []string{ //nolint:gochecknoglobals // read-only
+		string(jose.A256GCM), string(jose.A192GCM), string(jose.A128GCM),
+		string(jose.A256CBC_HS512), string(jose.A192CBC_HS384), string(jose.A128CBC_HS256),
+	}
+)
+
+// defaultCredentialResponseEncryptionEnc is the content encryption algorithm to use if the issuer's metadata
+// doesn't list any, as specified by OpenID4CI.
+const defaultCredentialResponseEncryptionEnc = string(jose.A256GCM)
+
+// credentialResponseDecrypter holds the ephemeral key that the issuer uses to encrypt credential responses.
+// A new one is created for every request to the issuer's credential, batch credential or deferred credential
+// endpoints, so that the key is never reused across requests.
+type credentialResponseDecrypter struct {
+	privateKey *ecdsa.PrivateKey
+	publicJWK  []byte
+	alg        string
+	enc        string
+}
+
+// credentialResponseEncryption returns the issuer's support for encrypted credential responses, or nil if the
+// issuer's metadata doesn't mention encryption at all.
+func (i *Interaction) credentialResponseEncryption() *issuer.CredentialResponseEncryption {
+	if len(i.issuerMetadata.CredentialResponseEncryptionAlgValuesSupported) == 0 &&
+		!i.issuerMetadata.RequireCredentialResponseEncryption {
+		return nil
+	}
+
+	return &issuer.CredentialResponseEncryption{
+		AlgValuesSupported: i.issuerMetadata.CredentialResponseEncryptionAlgValuesSupported,
+		EncValuesSupported: i.issuerMetadata.CredentialResponseEncryptionEncValuesSupported,
+		EncryptionRequired: i.issuerMetadata.RequireCredentialResponseEncryption,
+	}
+}
+
+// newCredentialResponseDecrypter returns a decrypter if the given issuer encryption support includes any algorithms,
+// or nil if credential responses should be sent in cleartext. An error is returned if the issuer requires encryption
+// but doesn't support any of the algorithms that the wallet supports.
+func newCredentialResponseDecrypter(encryption *issuer.CredentialResponseEncryption,
+) (*credentialResponseDecrypter, error) {
+	if encryption == nil {
+		return nil, nil //nolint:nilnil // A nil decrypter means that credential responses aren't encrypted.
+	}
+
+	if len(encryption.AlgValuesSupported) == 0 {
+		if encryption.EncryptionRequired {
+			return nil, errors.New("the issuer requires credential response encryption but doesn't specify " +
+				"any supported algorithms")
+		}
+
+		return nil, nil //nolint:nilnil // A nil decrypter means that credential responses aren't encrypted.
+	}
+
+	alg := firstSupported(supportedCredentialResponseEncryptionAlgs, encryption.AlgValuesSupported)
+
+	enc := defaultCredentialResponseEncryptionEnc
+
+	if len(encryption.EncValuesSupported) > 0 {
+		enc = firstSupported(supportedCredentialResponseEncryptionEncs, encryption.EncValuesSupported)
+	}
+
+	if alg == "" || enc == "" {
+		if encryption.EncryptionRequired {
+			return nil, fmt.Errorf("the issuer requires credential response encryption, but none of its "+
+				"supported algorithms (alg: %v, enc: %v) are supported by this wallet",
+				encryption.AlgValuesSupported, encryption.EncValuesSupported)
+		}
+
+		return nil, nil //nolint:nilnil // A nil decrypter means that credential responses aren't encrypted.
+	}
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate credential response encryption key: %w", err)
+	}
+
+	publicJWK, err := (&jose.JSONWebKey{
+		Key:       &privateKey.PublicKey,
+		KeyID:     uuid.NewString(),
+		Algorithm: alg,
+		Use:       "enc",
+	}).MarshalJSON()
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal credential response encryption key: %w", err)
+	}
+
+	return &credentialResponseDecrypter{privateKey: privateKey, publicJWK: publicJWK, alg: alg, enc: enc}, nil
+}
+
+// addToCredentialRequest adds the parameters that request an encrypted response to the given credential request.
+func (d *credentialResponseDecrypter) addToCredentialRequest(request *credentialRequest, draft13 bool) {
+	if draft13 {
+		request.CredentialResponseEncryption = d.encryptionRequest()
+
+		return
+	}
+
+	request.CredentialEncryptionJWK = d.publicJWK
+	request.CredentialResponseEncryptionAlg = d.alg
+	request.CredentialResponseEncryptionEnc = d.enc
+}
+
+// encryptionRequest returns the draft 13 (and later) parameters that request an encrypted response.
+func (d *credentialResponseDecrypter) encryptionRequest() *credentialResponseEncryptionRequest {
+	return &credentialResponseEncryptionRequest{
+		JWK: d.publicJWK,
+		Alg: d.alg,
+		Enc: d.enc,
+	}
+}
+
+// decrypt decrypts an encrypted credential response. Since an encrypted response was requested, a cleartext
+// response is treated as an error.
+func (d *credentialResponseDecrypter) decrypt(responseBytes []byte) ([]byte, error) {
+	compactJWE := string(bytes.TrimSpace(responseBytes))
+
+	encryptedResponse, err := jose.ParseEncrypted(compactJWE)
+	if err != nil {
+		return nil, fmt.Errorf("expected an encrypted credential response: %w", err)
+	}
+
+	if encryptedResponse.Header.Algorithm != d.alg {
+		return nil, fmt.Errorf("credential response is encrypted using %s but %s was requested",
+			encryptedResponse.Header.Algorithm, d.alg)
+	}
+
+	if enc, _ := encryptedResponse.Header.ExtraHeaders[jose.HeaderKey("enc")].(string); enc != d.enc {
+		return nil, fmt.Errorf("credential response content is encrypted using %s but %s was requested", enc, d.enc)
+	}
+
+	decryptedResponse, err := encryptedResponse.Decrypt(d.privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt credential response: %w", err)
+	}
+
+	return decryptedResponse, nil
+}
+
+// firstSupported returns the first value in preferredValues that's also in supportedValues, or an empty string if
+// there's no such value.
+func firstSupported(preferredValues, supportedValues []string) string {
+	for _, preferredValue := range preferredValues {
+		for _, supportedValue := range supportedValues {
+			if preferredValue == supportedValue {
+				return preferredValue
+			}
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,380 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/wallet-sdk/internal/testutil"
+	"github.com/trustbloc/wallet-sdk/pkg/models/issuer"
+	"github.com/trustbloc/wallet-sdk/pkg/openid4ci"
+)
+
+type mockEncryptingIssuerServerHandler struct {
+	t                           *testing.T
+	issuerMetadata              string
+	sendCleartextResponse       bool
+	overrideEnc                 string
+	receivedCredentialRequests  []map[string]interface{}
+	receivedBatchCredentialJWKs []string
+	receivedDeferredRequest     map[string]interface{}
+}
+
+func (m *mockEncryptingIssuerServerHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	var err error
+
+	switch request.URL.Path {
+	case "/.well-known/openid-configuration":
+		_, err = fmt.Fprintf(writer, `{"token_endpoint":"http://%s/oidc/token"}`, request.Host)
+	case "/.well-known/openid-credential-issuer":
+		_, err = fmt.Fprintf(writer, m.issuerMetadata, "http://"+request.Host)
+	case "/oidc/token":
+		writer.Header().Set("Content-Type", "application/json")
+		_, err = writer.Write([]byte(sampleTokenResponse))
+	case "/credential":
+		var credentialRequest map[string]interface{}
+
+		require.NoError(m.t, json.NewDecoder(request.Body).Decode(&credentialRequest))
+
+		m.receivedCredentialRequests = append(m.receivedCredentialRequests, credentialRequest)
+
+		_, err = writer.Write(m.encrypt(credentialRequest, sampleCredentialResponse))
+	case "/deferred_credential":
+		require.NoError(m.t, json.NewDecoder(request.Body).Decode(&m.receivedDeferredRequest))
+
+		_, err = writer.Write(m.encrypt(m.receivedDeferredRequest, sampleCredentialResponse))
+	case "/batch_credential":
+		var batchRequest struct {
+			CredentialRequests []map[string]interface{} `json:"credential_requests"`
+		}
+
+		require.NoError(m.t, json.NewDecoder(request.Body).Decode(&batchRequest))
+
+		for _, credentialRequest := range batchRequest.CredentialRequests {
+			jwk, marshalErr := json.Marshal(credentialRequest["credential_encryption_jwk"])
+			require.NoError(m.t, marshalErr)
+
+			m.receivedBatchCredentialJWKs = append(m.receivedBatchCredentialJWKs, string(jwk))
+		}
+
+		_, err = writer.Write(m.encrypt(batchRequest.CredentialRequests[0],
+			createBatchCredentialResponse(m.t, len(batchRequest.CredentialRequests))))
+	}
+
+	require.NoError(m.t, err)
+}
+
+// encrypt encrypts the given response using the key and algorithms from the given credential request, using either
+// the draft 13 or the draft 11 request parameters (whichever are present).
+func (m *mockEncryptingIssuerServerHandler) encrypt(credentialRequest map[string]interface{}, response []byte,
+) []byte {
+	if m.sendCleartextResponse {
+		return response
+	}
+
+	jwkValue, alg, enc := credentialRequest["credential_encryption_jwk"],
+		credentialRequest["credential_response_encryption_alg"], credentialRequest["credential_response_encryption_enc"]
+
+	if encryptionParams, ok := credentialRequest["credential_response_encryption"].(map[string]interface{}); ok {
+		jwkValue, alg, enc = encryptionParams["jwk"], encryptionParams["alg"], encryptionParams["enc"]
+	}
+
+	if m.overrideEnc != "" {
+		enc = m.overrideEnc
+	}
+
+	jwkBytes, err := json.Marshal(jwkValue)
+	require.NoError(m.t, err)
+
+	var jwk jose.JSONWebKey
+
+	require.NoError(m.t, jwk.UnmarshalJSON(jwkBytes))
+
+	encrypter, err := jose.NewEncrypter(jose.ContentEncryption(enc.(string)),
+		jose.Recipient{Algorithm: jose.KeyAlgorithm(alg.(string)), Key: jwk.Key}, nil)
+	require.NoError(m.t, err)
+
+	encryptedResponse, err := encrypter.Encrypt(response)
+	require.NoError(m.t, err)
+
+	compactJWE, err := encryptedResponse.CompactSerialize()
+	require.NoError(m.t, err)
+
+	return []byte(compactJWE)
+}
+
+func TestInteraction_CredentialResponseEncryption(t *testing.T) {
+	const draft11IssuerMetadata = `{"credential_endpoint":"%[1]s/credential",` +
+		`"credential_response_encryption_alg_values_supported":["RSA-OAEP","ECDH-ES+A128KW","ECDH-ES"],` +
+		`"credential_response_encryption_enc_values_supported":["A128GCM","A256GCM"],` +
+		`"require_credential_response_encryption":true}`
+
+	t.Run("Draft 11 issuer metadata", func(t *testing.T) {
+		handler := &mockEncryptingIssuerServerHandler{t: t, issuerMetadata: draft11IssuerMetadata}
+
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		interaction := newInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, false))
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+			openid4ci.WithPIN("1234"))
+		require.NoError(t, err)
+		require.Len(t, credentials, 1)
+
+		require.Len(t, handler.receivedCredentialRequests, 1)
+		require.Equal(t, "ECDH-ES", handler.receivedCredentialRequests[0]["credential_response_encryption_alg"])
+		require.Equal(t, "A256GCM", handler.receivedCredentialRequests[0]["credential_response_encryption_enc"])
+		require.NotContains(t, handler.receivedCredentialRequests[0], "credential_response_encryption")
+
+		jwk, ok := handler.receivedCredentialRequests[0]["credential_encryption_jwk"].(map[string]interface{})
+		require.True(t, ok)
+		require.Equal(t, "EC", jwk["kty"])
+		require.Equal(t, "P-256", jwk["crv"])
+		require.NotContains(t, jwk, "d")
+	})
+	t.Run("Draft 13 issuer metadata", func(t *testing.T) {
+		handler := &mockEncryptingIssuerServerHandler{
+			t: t,
+			issuerMetadata: sampleDraft13IssuerMetadata[:len(sampleDraft13IssuerMetadata)-1] +
+				`,"credential_response_encryption":{"alg_values_supported":["ECDH-ES+A256KW"],` +
+				`"encryption_required":true}}`,
+		}
+
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		interaction := newInteraction(t, createDraft13CredentialOfferIssuanceURI(t, server.URL, nil))
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID})
+		require.NoError(t, err)
+		require.Len(t, credentials, 1)
+
+		require.Len(t, handler.receivedCredentialRequests, 1)
+		require.NotContains(t, handler.receivedCredentialRequests[0], "credential_encryption_jwk")
+
+		credentialRequest := handler.receivedCredentialRequests[0]
+
+		encryptionParams, ok := credentialRequest["credential_response_encryption"].(map[string]interface{})
+		require.True(t, ok)
+		require.Equal(t, "ECDH-ES+A256KW", encryptionParams["alg"])
+		// The issuer doesn't list any enc values, so the default is used.
+		require.Equal(t, "A256GCM", encryptionParams["enc"])
+		require.NotEmpty(t, encryptionParams["jwk"])
+	})
+	t.Run("Batch credential endpoint", func(t *testing.T) {
+		handler := &mockEncryptingIssuerServerHandler{
+			t: t,
+			issuerMetadata: draft11IssuerMetadata[:len(draft11IssuerMetadata)-1] +
+				`,"batch_credential_endpoint":"%[1]s/batch_credential"}`,
+		}
+
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		interaction := newInteraction(t, createMultiCredentialOfferIssuanceURI(t, server.URL, false))
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+			openid4ci.WithPIN("1234"))
+		require.NoError(t, err)
+		require.Len(t, credentials, 2)
+
+		require.Len(t, handler.receivedBatchCredentialJWKs, 2)
+		require.Equal(t, handler.receivedBatchCredentialJWKs[0], handler.receivedBatchCredentialJWKs[1])
+	})
+	t.Run("A new key is used for each credential request", func(t *testing.T) {
+		handler := &mockEncryptingIssuerServerHandler{t: t, issuerMetadata: draft11IssuerMetadata}
+
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		interaction := newInteraction(t, createMultiCredentialOfferIssuanceURI(t, server.URL, false))
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+			openid4ci.WithPIN("1234"))
+		require.NoError(t, err)
+		require.Len(t, credentials, 2)
+
+		require.Len(t, handler.receivedCredentialRequests, 2)
+		require.NotEqual(t, handler.receivedCredentialRequests[0]["credential_encryption_jwk"],
+			handler.receivedCredentialRequests[1]["credential_encryption_jwk"])
+	})
+	t.Run("Issuer doesn't support any of the wallet's algorithms", func(t *testing.T) {
+		t.Run("Encryption is optional", func(t *testing.T) {
+			handler := &mockEncryptingIssuerServerHandler{
+				t: t,
+				issuerMetadata: `{"credential_endpoint":"%[1]s/credential",` +
+					`"credential_response_encryption_alg_values_supported":["RSA-OAEP"]}`,
+				sendCleartextResponse: true,
+			}
+
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			interaction := newInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, false))
+
+			credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+				openid4ci.WithPIN("1234"))
+			require.NoError(t, err)
+			require.Len(t, credentials, 1)
+
+			require.Len(t, handler.receivedCredentialRequests, 1)
+			require.NotContains(t, handler.receivedCredentialRequests[0], "credential_encryption_jwk")
+		})
+		t.Run("Encryption is required", func(t *testing.T) {
+			handler := &mockEncryptingIssuerServerHandler{
+				t: t,
+				issuerMetadata: `{"credential_endpoint":"%[1]s/credential",` +
+					`"credential_response_encryption_alg_values_supported":["RSA-OAEP"],` +
+					`"require_credential_response_encryption":true}`,
+			}
+
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			interaction := newInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, false))
+
+			credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+				openid4ci.WithPIN("1234"))
+			testutil.RequireErrorContains(t, err, "CREDENTIAL_FETCH_FAILED")
+			testutil.RequireErrorContains(t, err, "the issuer requires credential response encryption, but "+
+				"none of its supported algorithms (alg: [RSA-OAEP], enc: []) are supported by this wallet")
+			require.Nil(t, credentials)
+			require.Empty(t, handler.receivedCredentialRequests)
+		})
+	})
+	t.Run("Issuer sends a cleartext response", func(t *testing.T) {
+		handler := &mockEncryptingIssuerServerHandler{
+			t:                     t,
+			issuerMetadata:        draft11IssuerMetadata,
+			sendCleartextResponse: true,
+		}
+
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		interaction := newInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, false))
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+			openid4ci.WithPIN("1234"))
+		testutil.RequireErrorContains(t, err, "expected an encrypted credential response")
+		require.Nil(t, credentials)
+	})
+	t.Run("Issuer uses a different content encryption algorithm", func(t *testing.T) {
+		handler := &mockEncryptingIssuerServerHandler{
+			t:              t,
+			issuerMetadata: draft11IssuerMetadata,
+			overrideEnc:    "A128GCM",
+		}
+
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		interaction := newInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, false))
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+			openid4ci.WithPIN("1234"))
+		testutil.RequireErrorContains(t, err,
+			"credential response content is encrypted using A128GCM but A256GCM was requested")
+		require.Nil(t, credentials)
+	})
+	t.Run("Reissuance", func(t *testing.T) {
+		handler := &mockEncryptingIssuerServerHandler{t: t}
+
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		reissuanceToken := createTestReissuanceToken(server.URL)
+		reissuanceToken.CredentialConfigurationID = "VerifiedEmployee_JWT"
+		reissuanceToken.CredentialResponseEncryption = &issuer.CredentialResponseEncryption{
+			AlgValuesSupported: []string{"ECDH-ES+A256KW"},
+			EncValuesSupported: []string{"A128GCM"},
+			EncryptionRequired: true,
+		}
+
+		vc, _, err := openid4ci.ReissueCredential(reissuanceToken, &jwtSignerMock{keyID: mockKeyID},
+			getTestClientConfig(t))
+		require.NoError(t, err)
+		require.NotNil(t, vc)
+
+		require.Len(t, handler.receivedCredentialRequests, 1)
+
+		encryptionParams, ok :=
+			handler.receivedCredentialRequests[0]["credential_response_encryption"].(map[string]interface{})
+		require.True(t, ok)
+		require.Equal(t, "ECDH-ES+A256KW", encryptionParams["alg"])
+		require.Equal(t, "A128GCM", encryptionParams["enc"])
+	})
+	t.Run("Reissuance with a cleartext response", func(t *testing.T) {
+		handler := &mockEncryptingIssuerServerHandler{t: t, sendCleartextResponse: true}
+
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		reissuanceToken := createTestReissuanceToken(server.URL)
+		reissuanceToken.CredentialResponseEncryption = &issuer.CredentialResponseEncryption{
+			AlgValuesSupported: []string{"ECDH-ES"},
+		}
+
+		vc, _, err := openid4ci.ReissueCredential(reissuanceToken, &jwtSignerMock{keyID: mockKeyID},
+			getTestClientConfig(t))
+		testutil.RequireErrorContains(t, err, "expected an encrypted credential response")
+		require.Nil(t, vc)
+	})
+	t.Run("Deferred credential", func(t *testing.T) {
+		handler := &mockEncryptingIssuerServerHandler{t: t}
+
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		result, err := openid4ci.RequestDeferredCredential(&openid4ci.DeferredCredential{
+			IssuerURI:                  server.URL,
+			DeferredCredentialEndpoint: server.URL + "/deferred_credential",
+			AcceptanceToken:            "acceptanceToken",
+			CredentialResponseEncryption: &issuer.CredentialResponseEncryption{
+				AlgValuesSupported: []string{"ECDH-ES"},
+				EncryptionRequired: true,
+			},
+		}, getTestClientConfig(t))
+		require.NoError(t, err)
+		require.NotNil(t, result.Credential)
+
+		encryptionParams, ok :=
+			handler.receivedDeferredRequest["credential_response_encryption"].(map[string]interface{})
+		require.True(t, ok)
+		require.Equal(t, "ECDH-ES", encryptionParams["alg"])
+		require.Equal(t, "A256GCM", encryptionParams["enc"])
+		require.NotContains(t, handler.receivedDeferredRequest, "transaction_id")
+	})
+	t.Run("Deferred credential with a cleartext response", func(t *testing.T) {
+		handler := &mockEncryptingIssuerServerHandler{t: t, sendCleartextResponse: true}
+
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		result, err := openid4ci.RequestDeferredCredential(&openid4ci.DeferredCredential{
+			IssuerURI:                  server.URL,
+			DeferredCredentialEndpoint: server.URL + "/deferred_credential",
+			TransactionID:              "transactionID",
+			AccessToken:                "accessToken",
+			CredentialResponseEncryption: &issuer.CredentialResponseEncryption{
+				AlgValuesSupported: []string{"ECDH-ES"},
+			},
+		}, getTestClientConfig(t))
+		testutil.RequireErrorContains(t, err, "DEFERRED_CREDENTIAL_FETCH_FAILED(OCI1-0015):failed to get deferred "+
+			"credential response: expected an encrypted credential response")
+		require.Nil(t, result)
+		require.Equal(t, "transactionID", handler.receivedDeferredRequest["transaction_id"])
+	})
+}
@@ -0,0 +1,351 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hyperledger/aries-framework-go/component/models/verifiable"
+
+	"github.com/trustbloc/wallet-sdk/pkg/api"
+	"github.com/trustbloc/wallet-sdk/pkg/models/issuer"
+	"github.com/trustbloc/wallet-sdk/pkg/walleterror"
+)
+
+const (
+	requestDeferredCredentialEventText = "Request deferred credential from issuer"
+	//nolint:gosec //false positive
+	fetchDeferredCredentialViaPOSTReqEventText = "Fetch deferred credential via an HTTP POST request to %s"
+
+	issuancePendingErrorCode = "issuance_pending"
+
+	// The default polling interval (in seconds) to use if an issuer doesn't specify one
+	// in an issuance_pending response.
+	defaultDeferredCredentialPollingInterval = 5
+)
+
+// DeferredCredential is a handle for a credential that an issuer has deferred.
+// It contains everything needed to retrieve the credential from the issuer later on, and so can be persisted
+// (e.g. serialized to JSON) by the caller and used after the Interaction that created it is gone.
+// It should be treated as sensitive data, as it may contain the access token used for retrieving the credential.
+type DeferredCredential struct {
+	IssuerURI                  string   `json:"issuer_uri,omitempty"`
+	DeferredCredentialEndpoint string   `json:"deferred_credential_endpoint,omitempty"`
+	Format                     string   `json:"format,omitempty"`
+	Types                      []string `json:"types,omitempty"`
+	// AcceptanceToken is used as the bearer token for the deferred credential request if the issuer provided one.
+	AcceptanceToken string `json:"acceptance_token,omitempty"`
+	// TransactionID is sent in the deferred credential request along with AccessToken if the issuer provided one.
+	TransactionID string `json:"transaction_id,omitempty"`
+	AccessToken   string `json:"access_token,omitempty"`
+	// CredentialResponseEncryption is the issuer's support for encrypted credential responses at the time the
+	// credential was deferred. It's nil if the issuer didn't advertise any.
+	CredentialResponseEncryption *issuer.CredentialResponseEncryption `json:"credential_response_encryption,omitempty"`
+}
+
+// DeferredCredentialResult is the result of an attempt to retrieve a deferred credential.
+// If the issuer has issued the credential, then Credential will be set.
+// Otherwise, IssuancePending will be true, and the caller should wait for at least RetryAfter before trying again.
+type DeferredCredentialResult struct {
+	Credential      *verifiable.Credential
+	IssuancePending bool
+	RetryAfter      time.Duration
+}
+
+// RequestDeferredCredential attempts to retrieve a credential that was previously deferred by an issuer.
+// If the issuer reports that the credential still isn't ready, then no error is returned. Instead, the returned
+// DeferredCredentialResult will indicate that issuance is still pending and how long to wait before trying again.
+// The given ClientConfig is used in the same way as in NewInteraction.
+func RequestDeferredCredential(deferredCredential *DeferredCredential, config *ClientConfig,
+) (*DeferredCredentialResult, error) {
+	return RequestDeferredCredentialContext(context.Background(), deferredCredential, config)
+}
+
+// RequestDeferredCredentialContext is the same as RequestDeferredCredential, except that the given context is used
+// for the request to the issuer and for resolving DIDs while verifying the received credential.
+func RequestDeferredCredentialContext(ctx context.Context, deferredCredential *DeferredCredential,
+	config *ClientConfig,
+) (*DeferredCredentialResult, error) {
+	timeStartRequestDeferredCredential := time.Now()
+
+	err := validateRequiredParameters(config)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validateDeferredCredential(deferredCredential)
+	if err != nil {
+		return nil, err
+	}
+
+	setDefaults(config)
+
+	credentialResponse, retryAfter, err := getDeferredCredentialResponse(ctx, deferredCredential, config)
+	if err != nil {
+		return nil, walleterror.NewExecutionError(
+			module,
+			DeferredCredentialFetchFailedCode,
+			DeferredCredentialFetchFailedError,
+			fmt.Errorf("failed to get deferred credential response: %w", err))
+	}
+
+	if credentialResponse == nil {
+		return &DeferredCredentialResult{IssuancePending: true, RetryAfter: retryAfter}, nil
+	}
+
+	vc, err := parseCredentialFromCredentialResponse(credentialResponse,
+		credentialParseOpts(ctx, config.DIDResolver, config.DocumentLoader, config.DisableVCProofChecks))
+	if err != nil {
+		return nil, walleterror.NewExecutionError(
+			module,
+			CredentialParseFailedCode,
+			CredentialParseError,
+			fmt.Errorf("failed to parse deferred credential: %w", err))
+	}
+
+	subjectIDs, err := getSubjectIDs([]*verifiable.Credential{vc})
+	if err != nil {
+		return nil, err
+	}
+
+	err = config.MetricsLogger.Log(&api.MetricsEvent{
+		Event:    requestDeferredCredentialEventText,
+		Duration: time.Since(timeStartRequestDeferredCredential),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &DeferredCredentialResult{Credential: vc}, config.ActivityLogger.Log(&api.Activity{
+		ID:   uuid.New(),
+		Type: api.LogTypeCredentialActivity,
+		Time: time.Now(),
+		Data: api.Data{
+			Client:    deferredCredential.IssuerURI,
+			Operation: activityLogOperation,
+			Status:    api.ActivityLogStatusSuccess,
+			Params:    map[string]interface{}{"subjectIDs": subjectIDs},
+		},
+	})
+}
+
+func (i *Interaction) getDeferredCredentials(credentialResponses []CredentialResponse, accessToken string,
+) ([]*DeferredCredential, error) {
+	var deferredCredentials []*DeferredCredential
+
+	for index := range credentialResponses {
+		if !credentialResponses[index].isDeferred() {
+			continue
+		}
+
+		if i.issuerMetadata.DeferredCredentialEndpoint == "" {
+			return nil, fmt.Errorf("the issuer deferred the credential at index %d, but their metadata "+
+				"doesn't specify a deferred credential endpoint", index)
+		}
+
+		deferredCredential := &DeferredCredential{
+			IssuerURI:                    i.issuerURI,
+			DeferredCredentialEndpoint:   i.issuerMetadata.DeferredCredentialEndpoint,
+			Format:                       i.credentialFormats[index],
+			Types:                        i.credentialTypes[index],
+			AcceptanceToken:              credentialResponses[index].AcceptanceToken,
+			TransactionID:                credentialResponses[index].TransactionID,
+			CredentialResponseEncryption: i.credentialResponseEncryption(),
+		}
+
+		// The access token is only needed if a transaction ID is being used.
+		// Otherwise, the acceptance token is used in its place.
+		if deferredCredential.TransactionID != "" {
+			deferredCredential.AccessToken = accessToken
+		}
+
+		deferredCredentials = append(deferredCredentials, deferredCredential)
+	}
+
+	return deferredCredentials, nil
+}
+
+func (i *Interaction) accessToken(grantType string) string {
+	if grantType == preAuthorizedGrantType {
+		return i.preAuthTokenResponse.AccessToken
+	}
+
+	return i.authTokenResponse.AccessToken
+}
+
+func validateDeferredCredential(deferredCredential *DeferredCredential) error {
+	var err error
+
+	switch {
+	case deferredCredential == nil:
+		err = errors.New("no deferred credential provided")
+	case deferredCredential.DeferredCredentialEndpoint == "":
+		err = errors.New("deferred credential endpoint missing")
+	case deferredCredential.AcceptanceToken == "" && deferredCredential.TransactionID == "":
+		err = errors.New("either an acceptance token or a transaction ID must be provided")
+	case deferredCredential.TransactionID != "" && deferredCredential.AccessToken == "":
+		err = errors.New("an access token must be provided along with the transaction ID")
+	}
+
+	if err != nil {
+		return walleterror.NewValidationError(
+			module,
+			InvalidDeferredCredentialCode,
+			InvalidDeferredCredentialError,
+			err)
+	}
+
+	return nil
+}
+
+// getDeferredCredentialResponse returns the credential response from the issuer's deferred credential endpoint.
+// If the issuer indicates that issuance is still pending, then a nil credential response is returned along with
+// the amount of time to wait before trying again.
+func getDeferredCredentialResponse(ctx context.Context, deferredCredential *DeferredCredential, config *ClientConfig,
+) (*CredentialResponse, time.Duration, error) {
+	decrypter, err := newCredentialResponseDecrypter(deferredCredential.CredentialResponseEncryption)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	request, err := createDeferredCredentialHTTPRequest(ctx, deferredCredential, decrypter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	timeStartHTTPRequest := time.Now()
+
+	response, err := newDPoPHTTPClient(config).Do(request)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	defer func() {
+		errClose := response.Body.Close()
+		if errClose != nil {
+			println(fmt.Sprintf("failed to close response body: %s", errClose.Error()))
+		}
+	}()
+
+	err = config.MetricsLogger.Log(&api.MetricsEvent{
+		Event: fmt.Sprintf(fetchDeferredCredentialViaPOSTReqEventText,
+			deferredCredential.DeferredCredentialEndpoint),
+		ParentEvent: requestDeferredCredentialEventText,
+		Duration:    time.Since(timeStartHTTPRequest),
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	responseBytes, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		retryAfter, isPending := checkForIssuancePending(responseBytes, response.Header)
+		if isPending {
+			return nil, retryAfter, nil
+		}
+
+		return nil, 0, fmt.Errorf("received status code [%d] with body [%s] from issuer's deferred "+
+			"credential endpoint", response.StatusCode, string(responseBytes))
+	}
+
+	if decrypter != nil {
+		responseBytes, err = decrypter.decrypt(responseBytes)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	var credentialResponse CredentialResponse
+
+	err = json.Unmarshal(responseBytes, &credentialResponse)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to unmarshal response from the issuer's deferred credential endpoint: %w",
+			err)
+	}
+
+	return &credentialResponse, 0, nil
+}
+
+func createDeferredCredentialHTTPRequest(ctx context.Context, deferredCredential *DeferredCredential,
+	decrypter *credentialResponseDecrypter,
+) (*http.Request, error) {
+	// Issuers that use acceptance tokens expect them to be used as the bearer token with no request body (unless an
+	// encrypted response is requested).
+	// Issuers that use transaction IDs instead expect them in the request body along with the regular access token.
+	bearerToken := deferredCredential.AcceptanceToken
+
+	deferredCredentialReq := deferredCredentialRequest{TransactionID: deferredCredential.TransactionID}
+
+	if deferredCredential.TransactionID != "" {
+		bearerToken = deferredCredential.AccessToken
+	}
+
+	if decrypter != nil {
+		deferredCredentialReq.CredentialResponseEncryption = decrypter.encryptionRequest()
+	}
+
+	var body []byte
+
+	if deferredCredentialReq != (deferredCredentialRequest{}) {
+		var err error
+
+		body, err = json.Marshal(deferredCredentialReq)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		deferredCredential.DeferredCredentialEndpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	if body != nil {
+		request.Header.Add("Content-Type", "application/json")
+	}
+
+	request.Header.Add("Authorization", "Bearer "+bearerToken)
+
+	return request, nil
+}
+
+// checkForIssuancePending checks whether the given error response from a deferred credential endpoint
+// indicates that the credential isn't ready yet. If so, then the time to wait before trying again is also returned.
+// The interval in the response body takes precedence over the Retry-After header.
+func checkForIssuancePending(responseBytes []byte, header http.Header) (time.Duration, bool) {
+	var errorResponse deferredCredentialErrorResponse
+
+	err := json.Unmarshal(responseBytes, &errorResponse)
+	if err != nil || errorResponse.Error != issuancePendingErrorCode {
+		return 0, false
+	}
+
+	intervalInSeconds := errorResponse.Interval
+
+	if intervalInSeconds <= 0 {
+		intervalInSeconds, err = strconv.Atoi(header.Get("Retry-After"))
+		if err != nil || intervalInSeconds <= 0 {
+			intervalInSeconds = defaultDeferredCredentialPollingInterval
+		}
+	}
+
+	return time.Duration(intervalInSeconds) * time.Second, true
+}
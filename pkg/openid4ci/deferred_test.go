@@ -0,0 +1,367 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/wallet-sdk/internal/testutil"
+	"github.com/trustbloc/wallet-sdk/pkg/openid4ci"
+)
+
+type mockDeferredCredentialHandler struct {
+	t                       *testing.T
+	statusCode              int
+	response                []byte
+	retryAfterHeader        string
+	receivedAuthorization   string
+	receivedTransactionID   string
+	receivedContentTypeJSON bool
+}
+
+func (m *mockDeferredCredentialHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	m.receivedAuthorization = request.Header.Get("Authorization")
+	m.receivedContentTypeJSON = request.Header.Get("Content-Type") == "application/json"
+
+	requestBody, err := io.ReadAll(request.Body)
+	require.NoError(m.t, err)
+
+	if len(requestBody) > 0 {
+		var deferredCredentialRequest map[string]string
+
+		require.NoError(m.t, json.Unmarshal(requestBody, &deferredCredentialRequest))
+
+		m.receivedTransactionID = deferredCredentialRequest["transaction_id"]
+	}
+
+	if m.retryAfterHeader != "" {
+		writer.Header().Set("Retry-After", m.retryAfterHeader)
+	}
+
+	if m.statusCode != 0 {
+		writer.WriteHeader(m.statusCode)
+	}
+
+	_, err = writer.Write(m.response)
+	require.NoError(m.t, err)
+}
+
+func TestInteraction_DeferredCredentials(t *testing.T) {
+	t.Run("Credential deferred by issuer", func(t *testing.T) {
+		issuerServerHandler := &mockIssuerServerHandler{
+			t:                  t,
+			credentialResponse: []byte(`{"acceptance_token":"acceptanceToken"}`),
+		}
+
+		server := httptest.NewServer(issuerServerHandler)
+		defer server.Close()
+
+		issuerServerHandler.openIDConfig = &openid4ci.OpenIDConfig{
+			TokenEndpoint: fmt.Sprintf("%s/oidc/token", server.URL),
+		}
+
+		issuerServerHandler.issuerMetadata = fmt.Sprintf(`{"credential_endpoint":"%s/credential",`+
+			`"deferred_credential_endpoint":"%s/deferred_credential"}`, server.URL, server.URL)
+
+		interaction := newInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, false))
+
+		require.Empty(t, interaction.DeferredCredentials())
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{
+			keyID: mockKeyID,
+		}, openid4ci.WithPIN("1234"))
+		require.NoError(t, err)
+		require.Empty(t, credentials)
+
+		deferredCredentials := interaction.DeferredCredentials()
+		require.Len(t, deferredCredentials, 1)
+		require.Equal(t, server.URL, deferredCredentials[0].IssuerURI)
+		require.Equal(t, server.URL+"/deferred_credential", deferredCredentials[0].DeferredCredentialEndpoint)
+		require.Equal(t, "jwt_vc_json", deferredCredentials[0].Format)
+		require.Equal(t, []string{"VerifiableCredential", "VerifiedEmployee"}, deferredCredentials[0].Types)
+		require.Equal(t, "acceptanceToken", deferredCredentials[0].AcceptanceToken)
+		require.Empty(t, deferredCredentials[0].TransactionID)
+		require.Empty(t, deferredCredentials[0].AccessToken)
+	})
+	t.Run("Credential deferred by issuer using a transaction ID", func(t *testing.T) {
+		issuerServerHandler := &mockIssuerServerHandler{
+			t:                  t,
+			credentialResponse: []byte(`{"transaction_id":"transactionID"}`),
+		}
+
+		server := httptest.NewServer(issuerServerHandler)
+		defer server.Close()
+
+		issuerServerHandler.openIDConfig = &openid4ci.OpenIDConfig{
+			TokenEndpoint: fmt.Sprintf("%s/oidc/token", server.URL),
+		}
+
+		issuerServerHandler.issuerMetadata = fmt.Sprintf(`{"credential_endpoint":"%s/credential",`+
+			`"deferred_credential_endpoint":"%s/deferred_credential"}`, server.URL, server.URL)
+
+		interaction := newInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, true))
+
+		authURL, err := interaction.CreateAuthorizationURL("clientID", "redirectURI")
+		require.NoError(t, err)
+
+		credentials, err := interaction.RequestCredentialWithAuth(&jwtSignerMock{
+			keyID: mockKeyID,
+		}, "redirectURI?code=1234&state="+getStateFromAuthURL(t, authURL))
+		require.NoError(t, err)
+		require.Empty(t, credentials)
+
+		deferredCredentials := interaction.DeferredCredentials()
+		require.Len(t, deferredCredentials, 1)
+		require.Equal(t, "transactionID", deferredCredentials[0].TransactionID)
+		require.Equal(t, "eyJhbGciOiJSUzI1NiIsInR5cCI6Ikp..sHQ", deferredCredentials[0].AccessToken)
+	})
+	t.Run("Issuer doesn't have a deferred credential endpoint", func(t *testing.T) {
+		issuerServerHandler := &mockIssuerServerHandler{
+			t:                  t,
+			credentialResponse: []byte(`{"acceptance_token":"acceptanceToken"}`),
+		}
+
+		server := httptest.NewServer(issuerServerHandler)
+		defer server.Close()
+
+		issuerServerHandler.openIDConfig = &openid4ci.OpenIDConfig{
+			TokenEndpoint: fmt.Sprintf("%s/oidc/token", server.URL),
+		}
+
+		issuerServerHandler.issuerMetadata = fmt.Sprintf(`{"credential_endpoint":"%s/credential"}`, server.URL)
+
+		interaction := newInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, false))
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{
+			keyID: mockKeyID,
+		}, openid4ci.WithPIN("1234"))
+		require.EqualError(t, err, "CREDENTIAL_FETCH_FAILED(OCI1-0010):the issuer deferred the credential at "+
+			"index 0, but their metadata doesn't specify a deferred credential endpoint")
+		require.Nil(t, credentials)
+	})
+}
+
+func TestRequestDeferredCredential(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		t.Run("Using acceptance token", func(t *testing.T) {
+			handler := &mockDeferredCredentialHandler{t: t, response: sampleCredentialResponse}
+
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			result, err := openid4ci.RequestDeferredCredential(&openid4ci.DeferredCredential{
+				DeferredCredentialEndpoint: server.URL,
+				AcceptanceToken:            "acceptanceToken",
+			}, getTestClientConfig(t))
+			require.NoError(t, err)
+			require.NotNil(t, result.Credential)
+			require.False(t, result.IssuancePending)
+			require.Equal(t, "Bearer acceptanceToken", handler.receivedAuthorization)
+			require.Empty(t, handler.receivedTransactionID)
+			require.False(t, handler.receivedContentTypeJSON)
+		})
+		t.Run("Using transaction ID", func(t *testing.T) {
+			handler := &mockDeferredCredentialHandler{t: t, response: sampleCredentialResponse}
+
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			result, err := openid4ci.RequestDeferredCredential(&openid4ci.DeferredCredential{
+				DeferredCredentialEndpoint: server.URL,
+				TransactionID:              "transactionID",
+				AccessToken:                "accessToken",
+			}, getTestClientConfig(t))
+			require.NoError(t, err)
+			require.NotNil(t, result.Credential)
+			require.Equal(t, "Bearer accessToken", handler.receivedAuthorization)
+			require.Equal(t, "transactionID", handler.receivedTransactionID)
+			require.True(t, handler.receivedContentTypeJSON)
+		})
+		t.Run("Deferred credential handle persisted as JSON", func(t *testing.T) {
+			handler := &mockDeferredCredentialHandler{t: t, response: sampleCredentialResponse}
+
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			deferredCredentialBytes, err := json.Marshal(&openid4ci.DeferredCredential{
+				DeferredCredentialEndpoint: server.URL,
+				AcceptanceToken:            "acceptanceToken",
+			})
+			require.NoError(t, err)
+
+			var deferredCredential openid4ci.DeferredCredential
+
+			require.NoError(t, json.Unmarshal(deferredCredentialBytes, &deferredCredential))
+
+			result, err := openid4ci.RequestDeferredCredential(&deferredCredential, getTestClientConfig(t))
+			require.NoError(t, err)
+			require.NotNil(t, result.Credential)
+		})
+	})
+	t.Run("Issuance pending", func(t *testing.T) {
+		t.Run("Interval in response body", func(t *testing.T) {
+			result := requestDeferredCredentialWithPendingIssuance(t, &mockDeferredCredentialHandler{
+				t:                t,
+				statusCode:       http.StatusBadRequest,
+				response:         []byte(`{"error":"issuance_pending","interval":10}`),
+				retryAfterHeader: "20",
+			})
+			require.Equal(t, 10*time.Second, result.RetryAfter)
+		})
+		t.Run("Retry-After header", func(t *testing.T) {
+			result := requestDeferredCredentialWithPendingIssuance(t, &mockDeferredCredentialHandler{
+				t:                t,
+				statusCode:       http.StatusBadRequest,
+				response:         []byte(`{"error":"issuance_pending"}`),
+				retryAfterHeader: "20",
+			})
+			require.Equal(t, 20*time.Second, result.RetryAfter)
+		})
+		t.Run("Default interval", func(t *testing.T) {
+			result := requestDeferredCredentialWithPendingIssuance(t, &mockDeferredCredentialHandler{
+				t:          t,
+				statusCode: http.StatusBadRequest,
+				response:   []byte(`{"error":"issuance_pending"}`),
+			})
+			require.Equal(t, 5*time.Second, result.RetryAfter)
+		})
+	})
+	t.Run("Invalid deferred credential", func(t *testing.T) {
+		testCases := []struct {
+			name               string
+			deferredCredential *openid4ci.DeferredCredential
+			expectedError      string
+		}{
+			{
+				name:          "Nil deferred credential",
+				expectedError: "no deferred credential provided",
+			},
+			{
+				name:               "Missing endpoint",
+				deferredCredential: &openid4ci.DeferredCredential{AcceptanceToken: "acceptanceToken"},
+				expectedError:      "deferred credential endpoint missing",
+			},
+			{
+				name:               "Missing acceptance token and transaction ID",
+				deferredCredential: &openid4ci.DeferredCredential{DeferredCredentialEndpoint: "example.com"},
+				expectedError:      "either an acceptance token or a transaction ID must be provided",
+			},
+			{
+				name: "Transaction ID without access token",
+				deferredCredential: &openid4ci.DeferredCredential{
+					DeferredCredentialEndpoint: "example.com",
+					TransactionID:              "transactionID",
+				},
+				expectedError: "an access token must be provided along with the transaction ID",
+			},
+		}
+
+		for _, testCase := range testCases {
+			t.Run(testCase.name, func(t *testing.T) {
+				result, err := openid4ci.RequestDeferredCredential(testCase.deferredCredential,
+					getTestClientConfig(t))
+				require.EqualError(t, err, "INVALID_DEFERRED_CREDENTIAL(OCI0-0014):"+testCase.expectedError)
+				require.Nil(t, result)
+			})
+		}
+	})
+	t.Run("Missing client config", func(t *testing.T) {
+		result, err := openid4ci.RequestDeferredCredential(&openid4ci.DeferredCredential{}, nil)
+		require.EqualError(t, err, "NO_CLIENT_CONFIG_PROVIDED(OCI0-0000):no client config provided")
+		require.Nil(t, result)
+	})
+	t.Run("Server error", func(t *testing.T) {
+		handler := &mockDeferredCredentialHandler{
+			t:          t,
+			statusCode: http.StatusBadRequest,
+			response:   []byte(`{"error":"invalid_transaction_id"}`),
+		}
+
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		result, err := openid4ci.RequestDeferredCredential(&openid4ci.DeferredCredential{
+			DeferredCredentialEndpoint: server.URL,
+			AcceptanceToken:            "acceptanceToken",
+		}, getTestClientConfig(t))
+		testutil.RequireErrorContains(t, err, "DEFERRED_CREDENTIAL_FETCH_FAILED(OCI1-0015):failed to get "+
+			"deferred credential response: received status code [400] with body "+
+			`[{"error":"invalid_transaction_id"}] from issuer's deferred credential endpoint`)
+		require.Nil(t, result)
+	})
+	t.Run("Fail to unmarshal response", func(t *testing.T) {
+		handler := &mockDeferredCredentialHandler{t: t, response: []byte("invalid")}
+
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		result, err := openid4ci.RequestDeferredCredential(&openid4ci.DeferredCredential{
+			DeferredCredentialEndpoint: server.URL,
+			AcceptanceToken:            "acceptanceToken",
+		}, getTestClientConfig(t))
+		testutil.RequireErrorContains(t, err, "failed to unmarshal response from the issuer's deferred "+
+			"credential endpoint")
+		require.Nil(t, result)
+	})
+	t.Run("Fail to parse credential", func(t *testing.T) {
+		handler := &mockDeferredCredentialHandler{t: t, response: []byte(`{"credential":"invalid"}`)}
+
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		result, err := openid4ci.RequestDeferredCredential(&openid4ci.DeferredCredential{
+			DeferredCredentialEndpoint: server.URL,
+			AcceptanceToken:            "acceptanceToken",
+		}, getTestClientConfig(t))
+		testutil.RequireErrorContains(t, err, "CREDENTIAL_PARSE_FAILED(OCI1-0012):failed to parse deferred "+
+			"credential")
+		require.Nil(t, result)
+	})
+	t.Run("Fail to log metrics event", func(t *testing.T) {
+		handler := &mockDeferredCredentialHandler{t: t, response: sampleCredentialResponse}
+
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		config := getTestClientConfig(t)
+		config.MetricsLogger = &failingMetricsLogger{}
+
+		result, err := openid4ci.RequestDeferredCredential(&openid4ci.DeferredCredential{
+			DeferredCredentialEndpoint: server.URL,
+			AcceptanceToken:            "acceptanceToken",
+		}, config)
+		testutil.RequireErrorContains(t, err, "failed to log event (Event=Fetch deferred credential via an "+
+			"HTTP POST request to "+server.URL+")")
+		require.Nil(t, result)
+	})
+}
+
+func requestDeferredCredentialWithPendingIssuance(t *testing.T, handler *mockDeferredCredentialHandler,
+) *openid4ci.DeferredCredentialResult {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	result, err := openid4ci.RequestDeferredCredential(&openid4ci.DeferredCredential{
+		DeferredCredentialEndpoint: server.URL,
+		AcceptanceToken:            "acceptanceToken",
+	}, getTestClientConfig(t))
+	require.NoError(t, err)
+	require.True(t, result.IssuancePending)
+	require.Nil(t, result.Credential)
+
+	return result
+}
@@ -0,0 +1,344 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hyperledger/aries-framework-go/component/kmscrypto/doc/jose"
+	"github.com/hyperledger/aries-framework-go/component/kmscrypto/doc/jose/jwk"
+	"github.com/hyperledger/aries-framework-go/component/models/jwt"
+	"github.com/hyperledger/aries-framework-go/component/models/signature/verifier"
+
+	"github.com/trustbloc/wallet-sdk/pkg/api"
+	"github.com/trustbloc/wallet-sdk/pkg/walleterror"
+)
+
+const (
+	dpopHeader          = "DPoP"
+	dpopNonceHeader     = "DPoP-Nonce"
+	dpopAuthScheme      = "DPoP"
+	bearerAuthScheme    = "Bearer"
+	dpopProofHeaderType = "dpop+jwt"
+
+	dpopNonceErrorCode = "use_dpop_nonce"
+)
+
+// DPoPConfig contains the key used to sender-constrain access tokens using DPoP, as defined in
+// https://datatracker.ietf.org/doc/html/rfc9449.
+// When DPoP is enabled, a DPoP proof is sent along with every request to the issuer's token, credential,
+// batch credential and deferred credential endpoints, and access tokens are always presented using the DPoP
+// authorization scheme. It should therefore only be enabled for issuers that issue DPoP-bound access tokens.
+type DPoPConfig struct {
+	// Signer is used to sign DPoP proofs.
+	Signer api.JWTSigner
+	// PublicKey is the public key corresponding to the Signer's private key. It gets embedded in every DPoP proof.
+	// It's checked against the Signer when the config is used, since the issuer would reject every DPoP proof
+	// otherwise.
+	PublicKey *jwk.JWK
+}
+
+// checkKeyMatchesSigner signs a test DPoP proof and checks that it can be verified using the public key.
+func (c *DPoPConfig) checkKeyMatchesSigner() error {
+	proofSigner := &dpopProofSigner{signer: c.Signer, publicKey: c.PublicKey}
+
+	token, err := jwt.NewSigned(&dpopProofClaims{ID: uuid.NewString(), IssuedAt: time.Now().Unix()}, jose.Headers{},
+		proofSigner)
+	if err != nil {
+		return fmt.Errorf("failed to sign test DPoP proof: %w", err)
+	}
+
+	proof, err := token.Serialize(false)
+	if err != nil {
+		return fmt.Errorf("failed to serialize test DPoP proof: %w", err)
+	}
+
+	publicKey := &verifier.PublicKey{JWK: c.PublicKey}
+
+	// The RSA signature verifiers only use the raw public key.
+	if rsaPublicKey, isRSA := c.PublicKey.Key.(*rsa.PublicKey); isRSA {
+		publicKey.Value = x509.MarshalPKCS1PublicKey(rsaPublicKey)
+	}
+
+	proofVerifier, err := jwt.GetVerifier(publicKey)
+	if err != nil {
+		return fmt.Errorf("unsupported DPoP public key: %w", err)
+	}
+
+	_, _, err = jwt.Parse(proof, jwt.WithSignatureVerifier(proofVerifier))
+	if err != nil {
+		return fmt.Errorf("the DPoP public key doesn't match the signer: %w", err)
+	}
+
+	return nil
+}
+
+type dpopProofClaims struct {
+	ID              string `json:"jti"`
+	HTTPMethod      string `json:"htm"`
+	HTTPURI         string `json:"htu"`
+	IssuedAt        int64  `json:"iat"`
+	Nonce           string `json:"nonce,omitempty"`
+	AccessTokenHash string `json:"ath,omitempty"`
+}
+
+// dpopProofSigner wraps the configured signer so that DPoP proofs get the headers required by RFC 9449.
+// The key ID is left out since the public key is embedded in the proof itself.
+type dpopProofSigner struct {
+	signer    api.JWTSigner
+	publicKey *jwk.JWK
+}
+
+func (d *dpopProofSigner) Sign(data []byte) ([]byte, error) {
+	return d.signer.Sign(data)
+}
+
+func (d *dpopProofSigner) Headers() jose.Headers {
+	return jose.Headers{
+		jose.HeaderType:       dpopProofHeaderType,
+		jose.HeaderAlgorithm:  d.signer.Headers()[jose.HeaderAlgorithm],
+		jose.HeaderJSONWebKey: d.publicKey,
+	}
+}
+
+// checkDPoPSupported fails if DPoP is enabled and the authorization server lists the algorithms it supports for
+// DPoP proofs, but the DPoP signer's algorithm isn't one of them. Authorization servers that don't advertise DPoP
+// support might still issue DPoP-bound access tokens, so that isn't treated as an error.
+func checkDPoPSupported(dpopConfig *DPoPConfig, openIDConfig *OpenIDConfig) error {
+	if dpopConfig == nil || !openIDConfig.DPoPSupported() {
+		return nil
+	}
+
+	algorithm, _ := dpopConfig.Signer.Headers()[jose.HeaderAlgorithm].(string)
+
+	if contains(openIDConfig.DPoPSigningAlgValuesSupported, algorithm) {
+		return nil
+	}
+
+	return walleterror.NewValidationError(
+		module,
+		InvalidDPoPConfigCode,
+		InvalidDPoPConfigError,
+		fmt.Errorf("the authorization server doesn't support the %s algorithm for DPoP proofs "+
+			"(supported algorithms: %s)", algorithm, strings.Join(openIDConfig.DPoPSigningAlgValuesSupported, ", ")))
+}
+
+// dpopRoundTripper adds DPoP proofs to POST requests (which covers all token and credential requests).
+// If the server responds with a DPoP-Nonce challenge, then the request is retried once with the new nonce.
+// Nonces are remembered per origin so that subsequent requests can use them right away.
+type dpopRoundTripper struct {
+	proofSigner *dpopProofSigner
+	next        http.RoundTripper
+	nonces      map[string]string
+	noncesLock  sync.Mutex
+}
+
+// newDPoPHTTPClient returns the HTTP client to use for the given config. If DPoP isn't enabled, then the
+// config's HTTP client is returned as-is. Otherwise, a copy of it is returned that adds DPoP proofs to requests.
+func newDPoPHTTPClient(config *ClientConfig) *http.Client {
+	if config.DPoP == nil {
+		return config.HTTPClient
+	}
+
+	next := config.HTTPClient.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	httpClient := *config.HTTPClient
+	httpClient.Transport = &dpopRoundTripper{
+		proofSigner: &dpopProofSigner{signer: config.DPoP.Signer, publicKey: config.DPoP.PublicKey},
+		next:        next,
+		nonces:      map[string]string{},
+	}
+
+	return &httpClient
+}
+
+func (d *dpopRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	if request.Method != http.MethodPost {
+		return d.next.RoundTrip(request)
+	}
+
+	origin := request.URL.Scheme + "://" + request.URL.Host
+	nonce := d.nonce(origin)
+
+	response, err := d.roundTripWithProof(request, request.Body, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	newNonce := response.Header.Get(dpopNonceHeader)
+	if newNonce == "" {
+		return response, nil
+	}
+
+	d.setNonce(origin, newNonce)
+
+	requestBodyCanBeResent := request.GetBody != nil || request.Body == nil || request.Body == http.NoBody
+
+	if !requestBodyCanBeResent {
+		return response, nil
+	}
+
+	isNonceChallenge, err := isDPoPNonceChallenge(response, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isNonceChallenge {
+		return response, nil
+	}
+
+	body := request.Body
+
+	if request.GetBody != nil {
+		body, err = request.GetBody()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	errClose := response.Body.Close()
+	if errClose != nil {
+		println(fmt.Sprintf("failed to close response body: %s", errClose.Error()))
+	}
+
+	return d.roundTripWithProof(request, body, newNonce)
+}
+
+func (d *dpopRoundTripper) roundTripWithProof(request *http.Request, body io.ReadCloser, nonce string,
+) (*http.Response, error) {
+	// Per the http.RoundTripper docs, the original request must not be modified.
+	clonedRequest := request.Clone(request.Context())
+	clonedRequest.Body = body
+
+	claims := &dpopProofClaims{
+		ID:         uuid.NewString(),
+		HTTPMethod: request.Method,
+		HTTPURI:    (&url.URL{Scheme: request.URL.Scheme, Host: request.URL.Host, Path: request.URL.Path}).String(),
+		IssuedAt:   time.Now().Unix(),
+		Nonce:      nonce,
+	}
+
+	accessToken := accessTokenFromAuthorizationHeader(request.Header.Get("Authorization"))
+	if accessToken != "" {
+		accessTokenHash := sha256.Sum256([]byte(accessToken))
+		claims.AccessTokenHash = base64.RawURLEncoding.EncodeToString(accessTokenHash[:])
+
+		clonedRequest.Header.Set("Authorization", dpopAuthScheme+" "+accessToken)
+	}
+
+	proof, err := d.createProof(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	clonedRequest.Header.Set(dpopHeader, proof)
+
+	return d.next.RoundTrip(clonedRequest)
+}
+
+func (d *dpopRoundTripper) createProof(claims *dpopProofClaims) (string, error) {
+	token, err := jwt.NewSigned(claims, jose.Headers{}, d.proofSigner)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign DPoP proof: %w", err)
+	}
+
+	proof, err := token.Serialize(false)
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize DPoP proof: %w", err)
+	}
+
+	return proof, nil
+}
+
+func (d *dpopRoundTripper) nonce(origin string) string {
+	d.noncesLock.Lock()
+	defer d.noncesLock.Unlock()
+
+	return d.nonces[origin]
+}
+
+func (d *dpopRoundTripper) setNonce(origin, nonce string) {
+	d.noncesLock.Lock()
+	defer d.noncesLock.Unlock()
+
+	d.nonces[origin] = nonce
+}
+
+// isDPoPNonceChallenge checks whether the server rejected the request because it requires a (new) nonce.
+// Authorization servers respond with a 400 status code and a use_dpop_nonce error in the body, while resource servers
+// respond with a 401 status code and a use_dpop_nonce error in the WWW-Authenticate header. Other errors may come with
+// a fresh nonce too, but retrying them wouldn't help. A challenge that repeats the nonce that was just used is not
+// retried either. The response body is left readable for the caller.
+func isDPoPNonceChallenge(response *http.Response, usedNonce string) (bool, error) {
+	if response.Header.Get(dpopNonceHeader) == usedNonce {
+		return false, nil
+	}
+
+	switch response.StatusCode {
+	case http.StatusBadRequest:
+		responseBytes, err := io.ReadAll(response.Body)
+		if err != nil {
+			return false, err
+		}
+
+		errClose := response.Body.Close()
+		if errClose != nil {
+			println(fmt.Sprintf("failed to close response body: %s", errClose.Error()))
+		}
+
+		response.Body = io.NopCloser(bytes.NewReader(responseBytes))
+
+		var errorResponse struct {
+			Error string `json:"error"`
+		}
+
+		// A body that isn't a JSON error response simply isn't a nonce challenge.
+		_ = json.Unmarshal(responseBytes, &errorResponse) //nolint:errcheck
+
+		return errorResponse.Error == dpopNonceErrorCode, nil
+	case http.StatusUnauthorized:
+		for _, challenge := range response.Header.Values("WWW-Authenticate") {
+			if strings.Contains(challenge, `error="`+dpopNonceErrorCode+`"`) {
+				return true, nil
+			}
+		}
+
+		return false, nil
+	default:
+		return false, nil
+	}
+}
+
+// accessTokenFromAuthorizationHeader returns the access token from an Authorization header that uses either the
+// Bearer or DPoP scheme. Other schemes (e.g. Basic client authentication at the token endpoint) are ignored.
+func accessTokenFromAuthorizationHeader(authorizationHeader string) string {
+	for _, scheme := range []string{bearerAuthScheme, dpopAuthScheme} {
+		prefix := scheme + " "
+
+		if len(authorizationHeader) > len(prefix) && strings.EqualFold(authorizationHeader[:len(prefix)], prefix) {
+			return authorizationHeader[len(prefix):]
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,400 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/component/kmscrypto/doc/jose"
+	"github.com/hyperledger/aries-framework-go/component/kmscrypto/doc/jose/jwk"
+	"github.com/hyperledger/aries-framework-go/component/kmscrypto/doc/jose/jwk/jwksupport"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/wallet-sdk/internal/testutil"
+	"github.com/trustbloc/wallet-sdk/pkg/openid4ci"
+)
+
+const (
+	sampleDPoPAccessToken = "dpopBoundAccessToken"
+	sampleDPoPNonce       = "serverNonce"
+)
+
+type receivedDPoPProof struct {
+	headers map[string]interface{}
+	claims  map[string]interface{}
+}
+
+// mockDPoPIssuerServerHandler mimics an issuer that requires DPoP-bound access tokens. Both the token endpoint and
+// the credential endpoint reject requests that don't use the server's nonce.
+type mockDPoPIssuerServerHandler struct {
+	t                    *testing.T
+	dpopSigningAlgValues string
+	// If set, then the token endpoint always responds with this error (along with a new nonce).
+	tokenErrorResponse           string
+	receivedTokenProofs          []*receivedDPoPProof
+	receivedCredentialProofs     []*receivedDPoPProof
+	receivedAuthorizationHeaders []string
+}
+
+func (m *mockDPoPIssuerServerHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	var err error
+
+	switch request.URL.Path {
+	case "/.well-known/openid-configuration":
+		if m.dpopSigningAlgValues == "" {
+			_, err = fmt.Fprintf(writer, `{"token_endpoint":"http://%s/oidc/token"}`, request.Host)
+
+			break
+		}
+
+		_, err = fmt.Fprintf(writer, `{"token_endpoint":"http://%s/oidc/token",`+
+			`"dpop_signing_alg_values_supported":%s}`, request.Host, m.dpopSigningAlgValues)
+	case "/.well-known/openid-credential-issuer":
+		_, err = fmt.Fprintf(writer, sampleDraft13IssuerMetadata, "http://"+request.Host)
+	case "/oidc/token":
+		require.NoError(m.t, request.ParseForm())
+
+		proof := parseDPoPProof(m.t, request.Header.Get("DPoP"))
+		m.receivedTokenProofs = append(m.receivedTokenProofs, proof)
+
+		if m.tokenErrorResponse != "" {
+			writer.Header().Set("DPoP-Nonce", fmt.Sprintf("rotatedNonce%d", len(m.receivedTokenProofs)))
+			writer.WriteHeader(http.StatusBadRequest)
+			_, err = writer.Write([]byte(m.tokenErrorResponse))
+
+			break
+		}
+
+		writer.Header().Set("DPoP-Nonce", sampleDPoPNonce)
+
+		if proof.claims["nonce"] != sampleDPoPNonce {
+			writer.WriteHeader(http.StatusBadRequest)
+			_, err = writer.Write([]byte(`{"error":"use_dpop_nonce"}`))
+
+			break
+		}
+
+		writer.Header().Set("Content-Type", "application/json")
+		_, err = fmt.Fprintf(writer, `{"access_token":"%s","token_type":"DPoP","c_nonce":"nonce"}`,
+			sampleDPoPAccessToken)
+	case "/credential":
+		proof := parseDPoPProof(m.t, request.Header.Get("DPoP"))
+		m.receivedCredentialProofs = append(m.receivedCredentialProofs, proof)
+		m.receivedAuthorizationHeaders = append(m.receivedAuthorizationHeaders, request.Header.Get("Authorization"))
+
+		if proof.claims["nonce"] != sampleDPoPNonce {
+			writer.Header().Set("DPoP-Nonce", sampleDPoPNonce)
+			writer.Header().Set("WWW-Authenticate", `DPoP error="use_dpop_nonce"`)
+			writer.WriteHeader(http.StatusUnauthorized)
+
+			break
+		}
+
+		_, err = writer.Write(sampleCredentialResponse)
+	}
+
+	require.NoError(m.t, err)
+}
+
+// ecdsaJWTSigner signs using a P-384 key, so that DPoP proofs can be verified against the DPoP config's public key.
+type ecdsaJWTSigner struct {
+	privateKey *ecdsa.PrivateKey
+	err        error
+}
+
+func newECDSAJWTSigner(t *testing.T) (*ecdsaJWTSigner, *jwk.JWK) {
+	t.Helper()
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+
+	publicJWK, err := jwksupport.JWKFromKey(&privateKey.PublicKey)
+	require.NoError(t, err)
+
+	return &ecdsaJWTSigner{privateKey: privateKey}, publicJWK
+}
+
+func (s *ecdsaJWTSigner) GetKeyID() string {
+	return "KeyID"
+}
+
+func (s *ecdsaJWTSigner) Sign(data []byte) ([]byte, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	hash := sha512.Sum384(data)
+
+	r, sigS, err := ecdsa.Sign(rand.Reader, s.privateKey, hash[:])
+	if err != nil {
+		return nil, err
+	}
+
+	const keySize = 48
+
+	signature := make([]byte, 2*keySize)
+	r.FillBytes(signature[:keySize])
+	sigS.FillBytes(signature[keySize:])
+
+	return signature, nil
+}
+
+func (s *ecdsaJWTSigner) Headers() jose.Headers {
+	return jose.Headers{
+		jose.HeaderKeyID:     "KeyID",
+		jose.HeaderAlgorithm: "ES384",
+	}
+}
+
+func TestInteraction_DPoP(t *testing.T) {
+	dpopSigner, publicJWK := newECDSAJWTSigner(t)
+
+	t.Run("Pre-auth flow with nonce challenges", func(t *testing.T) {
+		handler := &mockDPoPIssuerServerHandler{t: t}
+
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		config := getTestClientConfig(t)
+		config.DPoP = &openid4ci.DPoPConfig{Signer: dpopSigner, PublicKey: publicJWK}
+
+		interaction, err := openid4ci.NewInteraction(createDraft13CredentialOfferIssuanceURI(t, server.URL, nil),
+			config)
+		require.NoError(t, err)
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID})
+		require.NoError(t, err)
+		require.Len(t, credentials, 1)
+
+		// The first token request gets challenged, and the retry uses the server's nonce.
+		require.Len(t, handler.receivedTokenProofs, 2)
+		require.NotContains(t, handler.receivedTokenProofs[0].claims, "nonce")
+		require.Equal(t, sampleDPoPNonce, handler.receivedTokenProofs[1].claims["nonce"])
+
+		for _, proof := range handler.receivedTokenProofs {
+			requireValidDPoPProof(t, proof, server.URL+"/oidc/token", publicJWK)
+			require.NotContains(t, proof.claims, "ath")
+		}
+
+		require.NotEqual(t, handler.receivedTokenProofs[0].claims["jti"], handler.receivedTokenProofs[1].claims["jti"])
+
+		// The nonce from the token endpoint is remembered, so the credential request isn't challenged.
+		require.Len(t, handler.receivedCredentialProofs, 1)
+		requireValidDPoPProof(t, handler.receivedCredentialProofs[0], server.URL+"/credential", publicJWK)
+		require.Equal(t, sampleDPoPNonce, handler.receivedCredentialProofs[0].claims["nonce"])
+
+		accessTokenHash := sha256.Sum256([]byte(sampleDPoPAccessToken))
+		require.Equal(t, base64.RawURLEncoding.EncodeToString(accessTokenHash[:]),
+			handler.receivedCredentialProofs[0].claims["ath"])
+		require.Equal(t, []string{"DPoP " + sampleDPoPAccessToken}, handler.receivedAuthorizationHeaders)
+	})
+	t.Run("Auth flow", func(t *testing.T) {
+		handler := &mockDPoPIssuerServerHandler{t: t}
+
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		credentialOffer := createDraft13CredentialOffer(server.URL, nil)
+		credentialOffer.Grants = map[string]map[string]interface{}{"authorization_code": {}}
+
+		config := getTestClientConfig(t)
+		config.DPoP = &openid4ci.DPoPConfig{Signer: dpopSigner, PublicKey: publicJWK}
+
+		interaction, err := openid4ci.NewInteraction(toCredentialOfferIssuanceURI(t, credentialOffer), config)
+		require.NoError(t, err)
+
+		authURL, err := interaction.CreateAuthorizationURL("clientID", "redirectURI")
+		require.NoError(t, err)
+
+		parsedAuthURL, err := url.Parse(authURL)
+		require.NoError(t, err)
+
+		credentials, err := interaction.RequestCredentialWithAuth(&jwtSignerMock{keyID: mockKeyID},
+			"redirectURI?code=1234&state="+parsedAuthURL.Query().Get("state"))
+		require.NoError(t, err)
+		require.Len(t, credentials, 1)
+
+		require.Len(t, handler.receivedTokenProofs, 2)
+		require.Len(t, handler.receivedCredentialProofs, 1)
+		requireValidDPoPProof(t, handler.receivedCredentialProofs[0], server.URL+"/credential", publicJWK)
+		require.Equal(t, []string{"DPoP " + sampleDPoPAccessToken}, handler.receivedAuthorizationHeaders)
+	})
+	t.Run("Algorithm advertised by the authorization server", func(t *testing.T) {
+		handler := &mockDPoPIssuerServerHandler{t: t, dpopSigningAlgValues: `["ES256","ES384"]`}
+
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		config := getTestClientConfig(t)
+		config.DPoP = &openid4ci.DPoPConfig{Signer: dpopSigner, PublicKey: publicJWK}
+
+		interaction, err := openid4ci.NewInteraction(createDraft13CredentialOfferIssuanceURI(t, server.URL, nil),
+			config)
+		require.NoError(t, err)
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID})
+		require.NoError(t, err)
+		require.Len(t, credentials, 1)
+	})
+	t.Run("Algorithm not supported by the authorization server", func(t *testing.T) {
+		handler := &mockDPoPIssuerServerHandler{t: t, dpopSigningAlgValues: `["ES256"]`}
+
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		config := getTestClientConfig(t)
+		config.DPoP = &openid4ci.DPoPConfig{Signer: dpopSigner, PublicKey: publicJWK}
+
+		interaction, err := openid4ci.NewInteraction(createDraft13CredentialOfferIssuanceURI(t, server.URL, nil),
+			config)
+		require.NoError(t, err)
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID})
+		testutil.RequireErrorContains(t, err, "INVALID_DPOP_CONFIG(OCI0-0019):the authorization server doesn't "+
+			"support the ES384 algorithm for DPoP proofs (supported algorithms: ES256)")
+		require.Nil(t, credentials)
+		require.Empty(t, handler.receivedTokenProofs)
+	})
+	t.Run("Non-nonce errors aren't retried", func(t *testing.T) {
+		handler := &mockDPoPIssuerServerHandler{t: t, tokenErrorResponse: `{"error":"invalid_grant"}`}
+
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		config := getTestClientConfig(t)
+		config.DPoP = &openid4ci.DPoPConfig{Signer: dpopSigner, PublicKey: publicJWK}
+
+		interaction, err := openid4ci.NewInteraction(createDraft13CredentialOfferIssuanceURI(t, server.URL, nil),
+			config)
+		require.NoError(t, err)
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID})
+		testutil.RequireErrorContains(t, err, "invalid_grant")
+		require.Nil(t, credentials)
+		require.Len(t, handler.receivedTokenProofs, 1)
+	})
+	t.Run("Signer fails to sign DPoP proof", func(t *testing.T) {
+		server := httptest.NewServer(&mockDPoPIssuerServerHandler{t: t})
+		defer server.Close()
+
+		failingSigner, failingSignerPublicJWK := newECDSAJWTSigner(t)
+
+		config := getTestClientConfig(t)
+		config.DPoP = &openid4ci.DPoPConfig{Signer: failingSigner, PublicKey: failingSignerPublicJWK}
+
+		interaction, err := openid4ci.NewInteraction(createDraft13CredentialOfferIssuanceURI(t, server.URL, nil),
+			config)
+		require.NoError(t, err)
+
+		failingSigner.err = fmt.Errorf("signing failure")
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID})
+		testutil.RequireErrorContains(t, err, "TOKEN_FETCH_FAILED")
+		testutil.RequireErrorContains(t, err, "failed to sign DPoP proof")
+		require.Nil(t, credentials)
+	})
+	t.Run("Invalid DPoP config", func(t *testing.T) {
+		config := getTestClientConfig(t)
+		config.DPoP = &openid4ci.DPoPConfig{PublicKey: publicJWK}
+
+		interaction, err := openid4ci.NewInteraction(createDraft13CredentialOfferIssuanceURI(t, "example.com", nil),
+			config)
+		require.EqualError(t, err, "INVALID_DPOP_CONFIG(OCI0-0019):DPoP config must specify both a signer and "+
+			"a public key")
+		require.Nil(t, interaction)
+	})
+	t.Run("Public key doesn't match the signer", func(t *testing.T) {
+		_, otherPublicJWK := newECDSAJWTSigner(t)
+
+		config := getTestClientConfig(t)
+		config.DPoP = &openid4ci.DPoPConfig{Signer: dpopSigner, PublicKey: otherPublicJWK}
+
+		interaction, err := openid4ci.NewInteraction(createDraft13CredentialOfferIssuanceURI(t, "example.com", nil),
+			config)
+		testutil.RequireErrorContains(t, err, "INVALID_DPOP_CONFIG(OCI0-0019):the DPoP public key doesn't match "+
+			"the signer")
+		require.Nil(t, interaction)
+
+		ed25519PublicKey, _, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		ed25519PublicJWK, err := jwksupport.JWKFromKey(ed25519PublicKey)
+		require.NoError(t, err)
+
+		config.DPoP = &openid4ci.DPoPConfig{Signer: dpopSigner, PublicKey: ed25519PublicJWK}
+
+		interaction, err = openid4ci.NewInteraction(createDraft13CredentialOfferIssuanceURI(t, "example.com", nil),
+			config)
+		testutil.RequireErrorContains(t, err, "INVALID_DPOP_CONFIG(OCI0-0019):the DPoP public key doesn't match "+
+			"the signer")
+		require.Nil(t, interaction)
+	})
+	t.Run("Signer fails to sign the test DPoP proof", func(t *testing.T) {
+		config := getTestClientConfig(t)
+		config.DPoP = &openid4ci.DPoPConfig{
+			Signer:    &ecdsaJWTSigner{err: fmt.Errorf("signing failure")},
+			PublicKey: publicJWK,
+		}
+
+		interaction, err := openid4ci.NewInteraction(createDraft13CredentialOfferIssuanceURI(t, "example.com", nil),
+			config)
+		testutil.RequireErrorContains(t, err, "INVALID_DPOP_CONFIG(OCI0-0019):failed to sign test DPoP proof")
+		require.Nil(t, interaction)
+	})
+}
+
+func parseDPoPProof(t *testing.T, proof string) *receivedDPoPProof {
+	t.Helper()
+
+	parts := strings.Split(proof, ".")
+	require.Len(t, parts, 3)
+
+	receivedProof := &receivedDPoPProof{}
+
+	headersBytes, err := base64.RawURLEncoding.DecodeString(parts[0])
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(headersBytes, &receivedProof.headers))
+
+	claimsBytes, err := base64.RawURLEncoding.DecodeString(parts[1])
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(claimsBytes, &receivedProof.claims))
+
+	return receivedProof
+}
+
+func requireValidDPoPProof(t *testing.T, proof *receivedDPoPProof, expectedURI string, expectedJWK *jwk.JWK) {
+	t.Helper()
+
+	require.Equal(t, "dpop+jwt", proof.headers["typ"])
+	require.Equal(t, "ES384", proof.headers["alg"])
+	require.NotContains(t, proof.headers, "kid")
+
+	expectedJWKBytes, err := expectedJWK.MarshalJSON()
+	require.NoError(t, err)
+
+	receivedJWKBytes, err := json.Marshal(proof.headers["jwk"])
+	require.NoError(t, err)
+	require.JSONEq(t, string(expectedJWKBytes), string(receivedJWKBytes))
+
+	require.Equal(t, http.MethodPost, proof.claims["htm"])
+	require.Equal(t, expectedURI, proof.claims["htu"])
+	require.NotEmpty(t, proof.claims["jti"])
+	require.NotEmpty(t, proof.claims["iat"])
+}
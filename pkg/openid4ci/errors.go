@@ -24,6 +24,25 @@ const (
 	KeyIDNotContainDIDPartError               = "KEY_ID_NOT_CONTAIN_DID_PART"
 	CredentialParseError                      = "CREDENTIAL_PARSE_FAILED"                     //nolint:gosec,lll //false positive, can't shorten
 	StateInRedirectURINotMatchingAuthURLError = "STATE_IN_REDIRECT_URI_NOT_MATCHING_AUTH_URL" //nolint:gosec,lll //false positive, can't shorten
+	InvalidDeferredCredentialError            = "INVALID_DEFERRED_CREDENTIAL"                 //nolint:gosec //false positive
+	DeferredCredentialFetchFailedError        = "DEFERRED_CREDENTIAL_FETCH_FAILED"            //nolint:gosec //false positive
+	InvalidInteractionStateError              = "INVALID_INTERACTION_STATE"
+	InteractionStateExpiredError              = "INTERACTION_STATE_EXPIRED"
+	InvalidReissuanceTokenError               = "INVALID_REISSUANCE_TOKEN" //nolint:gosec //false positive
+	InvalidDPoPConfigError                    = "INVALID_DPOP_CONFIG"
+	PushedAuthorizationRequestFailedError     = "PUSHED_AUTHORIZATION_REQUEST_FAILED"
+	InvalidClientAuthenticationError          = "INVALID_CLIENT_AUTHENTICATION"
+	InvalidTokenError                         = "INVALID_TOKEN" //nolint:gosec //false positive
+	InvalidCredentialRequestError             = "INVALID_CREDENTIAL_REQUEST"
+	UnsupportedCredentialTypeError            = "UNSUPPORTED_CREDENTIAL_TYPE"
+	UnsupportedCredentialFormatError          = "UNSUPPORTED_CREDENTIAL_FORMAT"
+	InvalidProofError                         = "INVALID_PROOF"
+	InvalidEncryptionParametersError          = "INVALID_ENCRYPTION_PARAMETERS"
+	IncompatibleSignerError                   = "INCOMPATIBLE_SIGNER"
+	InvalidNotificationError                  = "INVALID_NOTIFICATION"
+	NotificationFailedError                   = "NOTIFICATION_FAILED"
+	IssuerTrustCheckFailedError               = "ISSUER_TRUST_CHECK_FAILED"
+	AuthorizationResponseFailedError          = "AUTHORIZATION_RESPONSE_FAILED"
 )
 
 // Constants' names and reasons are obvious so they do not require additional comments.
@@ -43,4 +62,23 @@ const (
 	KeyIDNotContainDIDPartCode
 	CredentialParseFailedCode
 	StateInRedirectURINotMatchingAuthURLCode
+	InvalidDeferredCredentialCode
+	DeferredCredentialFetchFailedCode
+	InvalidInteractionStateCode
+	InteractionStateExpiredCode
+	InvalidReissuanceTokenCode
+	InvalidDPoPConfigCode
+	PushedAuthorizationRequestFailedCode
+	InvalidClientAuthenticationCode
+	InvalidTokenCode
+	InvalidCredentialRequestCode
+	UnsupportedCredentialTypeCode
+	UnsupportedCredentialFormatCode
+	InvalidProofCode
+	InvalidEncryptionParametersCode
+	IncompatibleSignerCode
+	InvalidNotificationCode
+	NotificationFailedCode
+	IssuerTrustCheckFailedCode
+	AuthorizationResponseFailedCode
 )
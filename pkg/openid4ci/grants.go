@@ -6,12 +6,20 @@ SPDX-License-Identifier: Apache-2.0
 
 package openid4ci
 
-import "errors"
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/trustbloc/wallet-sdk/pkg/walleterror"
+)
 
 // PreAuthorizedCodeGrantParams represents an issuer's pre-authorized code grant parameters.
 type PreAuthorizedCodeGrantParams struct {
 	preAuthorizedCode string
 	userPINRequired   bool
+	txCode            *TxCode
 }
 
 // PINRequired indicates whether the issuer requires a PIN.
@@ -19,6 +27,25 @@ func (p *PreAuthorizedCodeGrantParams) PINRequired() bool {
 	return p.userPINRequired
 }
 
+// TxCode returns hints about the PIN (called a transaction code in draft 13 and later) that the issuer requires,
+// which can be used to show an appropriate input field to the user.
+// It returns nil if the issuer didn't provide any, which is always the case for issuers following draft 11.
+func (p *PreAuthorizedCodeGrantParams) TxCode() *TxCode {
+	return p.txCode
+}
+
+// TxCode represents the tx_code object in a pre-authorized code grant, as defined in
+// https://openid.net/specs/openid-4-verifiable-credential-issuance-1_0-13.html#section-4.1.1.
+// All fields are optional.
+type TxCode struct {
+	// InputMode is either "numeric" (the default if not specified by the issuer) or "text".
+	InputMode string `json:"input_mode,omitempty"`
+	// Length is the length of the transaction code. It's 0 if not specified by the issuer.
+	Length int `json:"length,omitempty"`
+	// Description is guidance for the user on how to obtain the transaction code.
+	Description string `json:"description,omitempty"`
+}
+
 // AuthorizationCodeGrantParams represents an issuer's authorization code grant parameters.
 type AuthorizationCodeGrantParams struct {
 	IssuerState *string
@@ -58,6 +85,60 @@ func determineIssuerGrantCapabilities(
 	return preAuthorizedCodeGrantParams, authorizationCodeGrantParams, nil
 }
 
+// determineGrantCapabilitiesFromMetadata is used when a credential offer doesn't specify any grants.
+// In that case, the supported grant types have to be determined using the authorization server's metadata
+// (falling back to the issuer's metadata). See
+// https://openid.net/specs/openid-4-verifiable-credential-issuance-1_0-11.html#section-4.1.1 for more info.
+// Only the authorization code grant type can be inferred this way. The pre-authorized code grant type can't be used
+// without a pre-authorized code, which only a credential offer can provide.
+// The fetched metadata is kept so that later steps in the flow don't need to fetch it again.
+func (i *Interaction) determineGrantCapabilitiesFromMetadata(ctx context.Context) error {
+	err := i.fetchIssuerMetadataIfNeeded(ctx)
+	if err != nil {
+		return err
+	}
+
+	openIDConfig, err := i.getOpenIDConfig(ctx)
+	if err != nil {
+		return walleterror.NewExecutionError(
+			module,
+			IssuerOpenIDConfigFetchFailedCode,
+			IssuerOpenIDConfigFetchFailedError,
+			fmt.Errorf("failed to fetch issuer's OpenID configuration: %w", err))
+	}
+
+	i.openIDConfig = openIDConfig
+
+	if !grantTypeSupported(authorizationCodeGrantType, openIDConfig, i.issuerMetadata.GrantTypesSupported) {
+		return errors.New("no supported grant types found: the credential offer doesn't specify any grants " +
+			"and the issuer's metadata doesn't indicate support for the authorization code grant type")
+	}
+
+	i.authorizationCodeGrantParams = &AuthorizationCodeGrantParams{}
+
+	return nil
+}
+
+func grantTypeSupported(grantType string, openIDConfig *OpenIDConfig, issuerGrantTypesSupported []string) bool {
+	grantTypesSupported := openIDConfig.GrantTypesSupported
+	if len(grantTypesSupported) == 0 {
+		grantTypesSupported = issuerGrantTypesSupported
+	}
+
+	// Per RFC 8414, if grant_types_supported is omitted, then the default value is ["authorization_code", "implicit"].
+	if len(grantTypesSupported) == 0 {
+		return grantType == authorizationCodeGrantType
+	}
+
+	for _, grantTypeSupported := range grantTypesSupported {
+		if grantTypeSupported == grantType {
+			return true
+		}
+	}
+
+	return false
+}
+
 func processPreAuthorizedCodeGrantParams(rawParams map[string]interface{}) (*PreAuthorizedCodeGrantParams, error) {
 	preAuthorizedCodeUntyped, exists := rawParams["pre-authorized_code"]
 	if !exists {
@@ -81,7 +162,47 @@ func processPreAuthorizedCodeGrantParams(rawParams map[string]interface{}) (*Pre
 		}
 	}
 
-	return &PreAuthorizedCodeGrantParams{preAuthorizedCode: preAuthorizedCode, userPINRequired: userPINRequired}, nil
+	txCode, err := processTxCode(rawParams)
+	if err != nil {
+		return nil, err
+	}
+
+	if txCode != nil {
+		userPINRequired = true
+	}
+
+	return &PreAuthorizedCodeGrantParams{
+		preAuthorizedCode: preAuthorizedCode,
+		userPINRequired:   userPINRequired,
+		txCode:            txCode,
+	}, nil
+}
+
+// processTxCode processes the tx_code object used by draft 13 (and later) in place of user_pin_required.
+// Its presence alone indicates that a transaction code (PIN) is required.
+func processTxCode(rawParams map[string]interface{}) (*TxCode, error) {
+	txCodeUntyped, exists := rawParams["tx_code"]
+	if !exists {
+		return nil, nil //nolint:nilnil // A nil TxCode means that no transaction code is required.
+	}
+
+	txCodeBytes, err := json.Marshal(txCodeUntyped)
+	if err != nil {
+		return nil, err
+	}
+
+	var txCode TxCode
+
+	err = json.Unmarshal(txCodeBytes, &txCode)
+	if err != nil {
+		return nil, fmt.Errorf("tx_code field value is invalid: %w", err)
+	}
+
+	if txCode.InputMode == "" {
+		txCode.InputMode = "numeric"
+	}
+
+	return &txCode, nil
 }
 
 func processAuthorizationCodeGrantParams(rawParams map[string]interface{}) (*AuthorizationCodeGrantParams, error) {
@@ -0,0 +1,287 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"github.com/trustbloc/wallet-sdk/pkg/models/issuer"
+	"github.com/trustbloc/wallet-sdk/pkg/walleterror"
+)
+
+const (
+	// interactionStateVersion must be changed whenever the interactionState structure changes in a way that
+	// makes previously serialized state incompatible.
+	interactionStateVersion = "v1"
+
+	minInteractionStateKeyLength = 32
+
+	interactionStatePartsSeparator = "."
+	numberOfInteractionStateParts  = 3
+)
+
+// interactionState holds the parts of an Interaction that need to survive a process restart.
+// Objects provided by the caller (DID resolver, loggers, HTTP client, etc.) are not included, and must be passed in
+// again when resuming.
+type interactionState struct {
+	ExpiresAt              int64                        `json:"expires_at"`
+	IssuerURI              string                       `json:"issuer_uri,omitempty"`
+	CredentialTypes        [][]string                   `json:"credential_types,omitempty"`
+	CredentialFormats      []string                     `json:"credential_formats,omitempty"`
+	CredentialConfigIDs    []string                     `json:"credential_configuration_ids,omitempty"`
+	ClientID               string                       `json:"client_id,omitempty"`
+	PreAuthorizedCodeGrant *preAuthorizedCodeGrantState `json:"pre_authorized_code_grant,omitempty"`
+	AuthorizationCodeGrant *authorizationCodeGrantState `json:"authorization_code_grant,omitempty"`
+	IssuerMetadata         *issuer.Metadata             `json:"issuer_metadata,omitempty"`
+	OpenIDConfig           *OpenIDConfig                `json:"openid_config,omitempty"`
+	OAuth2Config           *oAuth2ConfigState           `json:"oauth2_config,omitempty"`
+	AuthCodeURLState       string                       `json:"auth_code_url_state,omitempty"`
+	CodeVerifier           string                       `json:"code_verifier,omitempty"`
+}
+
+type preAuthorizedCodeGrantState struct {
+	PreAuthorizedCode string  `json:"pre-authorized_code,omitempty"`
+	UserPINRequired   bool    `json:"user_pin_required,omitempty"`
+	TxCode            *TxCode `json:"tx_code,omitempty"`
+}
+
+type authorizationCodeGrantState struct {
+	IssuerState *string `json:"issuer_state,omitempty"`
+}
+
+type oAuth2ConfigState struct {
+	ClientID    string   `json:"client_id,omitempty"`
+	RedirectURL string   `json:"redirect_url,omitempty"`
+	Scopes      []string `json:"scopes,omitempty"`
+	AuthURL     string   `json:"auth_url,omitempty"`
+	TokenURL    string   `json:"token_url,omitempty"`
+}
+
+// Serialize serializes the state of this Interaction into an opaque string that can be stored and later passed in to
+// ResumeInteraction in order to continue the flow, even in a different process. This is intended to be used after
+// CreateAuthorizationURL, so that RequestCredentialWithAuth can still be called if the app gets restarted while
+// the user is logging in with the issuer.
+// The serialized state is protected from tampering using the InteractionStateKey from the ClientConfig, and is only
+// valid for the InteractionStateLifetime from the ClientConfig. It contains the PKCE code verifier, so it should
+// still be stored securely.
+func (i *Interaction) Serialize() (string, error) {
+	err := validateInteractionStateKey(i.interactionStateKey)
+	if err != nil {
+		return "", err
+	}
+
+	stateBytes, err := json.Marshal(i.toInteractionState())
+	if err != nil {
+		return "", err
+	}
+
+	signedContent := interactionStateVersion + interactionStatePartsSeparator +
+		base64.RawURLEncoding.EncodeToString(stateBytes)
+
+	return signedContent + interactionStatePartsSeparator +
+		base64.RawURLEncoding.EncodeToString(computeInteractionStateMAC(signedContent, i.interactionStateKey)), nil
+}
+
+// ResumeInteraction recreates an Interaction from state previously produced by Interaction.Serialize.
+// The given ClientConfig is used in the same way as in NewInteraction, and must have the same InteractionStateKey
+// that was used when the state was serialized. An error is returned if the state has been modified,
+// was created by an incompatible version of this SDK, or has expired.
+func ResumeInteraction(state string, config *ClientConfig) (*Interaction, error) {
+	err := validateRequiredParameters(config)
+	if err != nil {
+		return nil, err
+	}
+
+	setDefaults(config)
+
+	err = validateInteractionStateKey(config.InteractionStateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	clientAuthentication, err := clientAuthenticationFromConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	parsedState, err := parseInteractionState(state, config.InteractionStateKey)
+	if err != nil {
+		return nil, walleterror.NewValidationError(
+			module,
+			InvalidInteractionStateCode,
+			InvalidInteractionStateError,
+			err)
+	}
+
+	if time.Now().Unix() > parsedState.ExpiresAt {
+		return nil, walleterror.NewValidationError(
+			module,
+			InteractionStateExpiredCode,
+			InteractionStateExpiredError,
+			fmt.Errorf("interaction state expired at %s", time.Unix(parsedState.ExpiresAt, 0).UTC()))
+	}
+
+	interaction := &Interaction{
+		issuerURI:                  parsedState.IssuerURI,
+		credentialTypes:            parsedState.CredentialTypes,
+		credentialFormats:          parsedState.CredentialFormats,
+		credentialConfigurationIDs: parsedState.CredentialConfigIDs,
+		clientID:                   parsedState.ClientID,
+		didResolver:                &didResolverWrapper{didResolver: config.DIDResolver},
+		activityLogger:             config.ActivityLogger,
+		metricsLogger:              config.MetricsLogger,
+		disableVCProofChecks:       config.DisableVCProofChecks,
+		documentLoader:             config.DocumentLoader,
+		issuerMetadata:             parsedState.IssuerMetadata,
+		openIDConfig:               parsedState.OpenIDConfig,
+		httpClient:                 newDPoPHTTPClient(config),
+		authCodeURLState:           parsedState.AuthCodeURLState,
+		codeVerifier:               parsedState.CodeVerifier,
+		interactionStateKey:        config.InteractionStateKey,
+		interactionStateLifetime:   *config.InteractionStateLifetime,
+		clientAuthentication:       clientAuthentication,
+		dpop:                       config.DPoP,
+	}
+
+	interaction.restoreGrantParamsAndOAuth2Config(parsedState)
+
+	return interaction, nil
+}
+
+func (i *Interaction) toInteractionState() *interactionState {
+	state := &interactionState{
+		ExpiresAt:           time.Now().Add(i.interactionStateLifetime).Unix(),
+		IssuerURI:           i.issuerURI,
+		CredentialTypes:     i.credentialTypes,
+		CredentialFormats:   i.credentialFormats,
+		CredentialConfigIDs: i.credentialConfigurationIDs,
+		ClientID:            i.clientID,
+		IssuerMetadata:      i.issuerMetadata,
+		OpenIDConfig:        i.openIDConfig,
+		AuthCodeURLState:    i.authCodeURLState,
+		CodeVerifier:        i.codeVerifier,
+	}
+
+	if i.preAuthorizedCodeGrantParams != nil {
+		state.PreAuthorizedCodeGrant = &preAuthorizedCodeGrantState{
+			PreAuthorizedCode: i.preAuthorizedCodeGrantParams.preAuthorizedCode,
+			UserPINRequired:   i.preAuthorizedCodeGrantParams.userPINRequired,
+			TxCode:            i.preAuthorizedCodeGrantParams.txCode,
+		}
+	}
+
+	if i.authorizationCodeGrantParams != nil {
+		state.AuthorizationCodeGrant = &authorizationCodeGrantState{
+			IssuerState: i.authorizationCodeGrantParams.IssuerState,
+		}
+	}
+
+	if i.oAuth2Config != nil {
+		state.OAuth2Config = &oAuth2ConfigState{
+			ClientID:    i.oAuth2Config.ClientID,
+			RedirectURL: i.oAuth2Config.RedirectURL,
+			Scopes:      i.oAuth2Config.Scopes,
+			AuthURL:     i.oAuth2Config.Endpoint.AuthURL,
+			TokenURL:    i.oAuth2Config.Endpoint.TokenURL,
+		}
+	}
+
+	return state
+}
+
+func (i *Interaction) restoreGrantParamsAndOAuth2Config(state *interactionState) {
+	if state.PreAuthorizedCodeGrant != nil {
+		i.preAuthorizedCodeGrantParams = &PreAuthorizedCodeGrantParams{
+			preAuthorizedCode: state.PreAuthorizedCodeGrant.PreAuthorizedCode,
+			userPINRequired:   state.PreAuthorizedCodeGrant.UserPINRequired,
+			txCode:            state.PreAuthorizedCodeGrant.TxCode,
+		}
+	}
+
+	if state.AuthorizationCodeGrant != nil {
+		i.authorizationCodeGrantParams = &AuthorizationCodeGrantParams{
+			IssuerState: state.AuthorizationCodeGrant.IssuerState,
+		}
+	}
+
+	if state.OAuth2Config != nil {
+		i.oAuth2Config = &oauth2.Config{
+			ClientID: state.OAuth2Config.ClientID,
+			Endpoint: oauth2.Endpoint{
+				AuthURL:   state.OAuth2Config.AuthURL,
+				TokenURL:  state.OAuth2Config.TokenURL,
+				AuthStyle: oauth2.AuthStyleInHeader,
+			},
+			RedirectURL: state.OAuth2Config.RedirectURL,
+			Scopes:      state.OAuth2Config.Scopes,
+		}
+	}
+}
+
+func parseInteractionState(state string, key []byte) (*interactionState, error) {
+	stateParts := strings.Split(state, interactionStatePartsSeparator)
+	if len(stateParts) != numberOfInteractionStateParts {
+		return nil, errors.New("interaction state is malformed")
+	}
+
+	if stateParts[0] != interactionStateVersion {
+		return nil, fmt.Errorf("unsupported interaction state version: %s", stateParts[0])
+	}
+
+	receivedMAC, err := base64.RawURLEncoding.DecodeString(stateParts[2])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode interaction state integrity check value: %w", err)
+	}
+
+	signedContent := stateParts[0] + interactionStatePartsSeparator + stateParts[1]
+
+	if !hmac.Equal(receivedMAC, computeInteractionStateMAC(signedContent, key)) {
+		return nil, errors.New("interaction state failed the integrity check. It may have been tampered with, " +
+			"or a different interaction state key was used to create it")
+	}
+
+	stateBytes, err := base64.RawURLEncoding.DecodeString(stateParts[1])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode interaction state: %w", err)
+	}
+
+	var parsedState interactionState
+
+	err = json.Unmarshal(stateBytes, &parsedState)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal interaction state: %w", err)
+	}
+
+	return &parsedState, nil
+}
+
+func computeInteractionStateMAC(signedContent string, key []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+
+	// Writes to a hash.Hash never return an error.
+	_, _ = mac.Write([]byte(signedContent))
+
+	return mac.Sum(nil)
+}
+
+func validateInteractionStateKey(key []byte) error {
+	if len(key) < minInteractionStateKeyLength {
+		return fmt.Errorf("an interaction state key of at least %d bytes must be provided in the client config",
+			minInteractionStateKeyLength)
+	}
+
+	return nil
+}
@@ -0,0 +1,188 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci_test
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/wallet-sdk/internal/testutil"
+	"github.com/trustbloc/wallet-sdk/pkg/openid4ci"
+)
+
+var testInteractionStateKey = []byte("0123456789abcdef0123456789abcdef")
+
+func TestInteraction_SerializeAndResume(t *testing.T) {
+	t.Run("Success - auth flow resumed after creating authorization URL", func(t *testing.T) {
+		issuerServerHandler := &mockIssuerServerHandler{
+			t:                  t,
+			credentialResponse: sampleCredentialResponse,
+		}
+
+		server := httptest.NewServer(issuerServerHandler)
+		defer server.Close()
+
+		issuerServerHandler.openIDConfig = &openid4ci.OpenIDConfig{
+			TokenEndpoint: fmt.Sprintf("%s/oidc/token", server.URL),
+		}
+
+		issuerServerHandler.issuerMetadata = fmt.Sprintf(`{"credential_endpoint":"%s/credential"}`,
+			server.URL)
+
+		config := getTestClientConfig(t)
+		config.InteractionStateKey = testInteractionStateKey
+
+		interaction, err := openid4ci.NewInteraction(createCredentialOfferIssuanceURI(t, server.URL, true), config)
+		require.NoError(t, err)
+
+		authURL, err := interaction.CreateAuthorizationURL("clientID", "redirectURI",
+			openid4ci.WithScopes([]string{"scope1"}))
+		require.NoError(t, err)
+
+		state, err := interaction.Serialize()
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(state, "v1."))
+
+		resumeConfig := getTestClientConfig(t)
+		resumeConfig.InteractionStateKey = testInteractionStateKey
+
+		resumedInteraction, err := openid4ci.ResumeInteraction(state, resumeConfig)
+		require.NoError(t, err)
+		require.Equal(t, server.URL, resumedInteraction.IssuerURI())
+		require.True(t, resumedInteraction.AuthorizationCodeGrantTypeSupported())
+		require.True(t, resumedInteraction.PreAuthorizedCodeGrantTypeSupported())
+
+		authorizationCodeGrantParams, err := resumedInteraction.AuthorizationCodeGrantParams()
+		require.NoError(t, err)
+		require.Equal(t, "1234", *authorizationCodeGrantParams.IssuerState)
+
+		preAuthorizedCodeGrantParams, err := resumedInteraction.PreAuthorizedCodeGrantParams()
+		require.NoError(t, err)
+		require.True(t, preAuthorizedCodeGrantParams.PINRequired())
+
+		credentials, err := resumedInteraction.RequestCredentialWithAuth(&jwtSignerMock{
+			keyID: mockKeyID,
+		}, "redirectURI?code=1234&state="+getStateFromAuthURL(t, authURL))
+		require.NoError(t, err)
+		require.Len(t, credentials, 1)
+	})
+	t.Run("Success - serialized before creating authorization URL", func(t *testing.T) {
+		config := getTestClientConfig(t)
+		config.InteractionStateKey = testInteractionStateKey
+
+		interaction, err := openid4ci.NewInteraction(createCredentialOfferIssuanceURI(t, "example.com", false),
+			config)
+		require.NoError(t, err)
+
+		state, err := interaction.Serialize()
+		require.NoError(t, err)
+
+		resumedInteraction, err := openid4ci.ResumeInteraction(state, config)
+		require.NoError(t, err)
+		require.False(t, resumedInteraction.AuthorizationCodeGrantTypeSupported())
+
+		credentials, err := resumedInteraction.RequestCredentialWithAuth(&jwtSignerMock{
+			keyID: mockKeyID,
+		}, "redirectURI?code=1234&state=1234")
+		require.EqualError(t, err, "authorization URL must be created first")
+		require.Nil(t, credentials)
+	})
+	t.Run("Missing interaction state key", func(t *testing.T) {
+		interaction := newInteraction(t, createCredentialOfferIssuanceURI(t, "example.com", false))
+
+		state, err := interaction.Serialize()
+		require.EqualError(t, err, "an interaction state key of at least 32 bytes must be provided in the "+
+			"client config")
+		require.Empty(t, state)
+
+		resumedInteraction, err := openid4ci.ResumeInteraction("state", getTestClientConfig(t))
+		require.EqualError(t, err, "an interaction state key of at least 32 bytes must be provided in the "+
+			"client config")
+		require.Nil(t, resumedInteraction)
+	})
+	t.Run("Missing client config", func(t *testing.T) {
+		resumedInteraction, err := openid4ci.ResumeInteraction("state", nil)
+		require.EqualError(t, err, "NO_CLIENT_CONFIG_PROVIDED(OCI0-0000):no client config provided")
+		require.Nil(t, resumedInteraction)
+	})
+	t.Run("Invalid state", func(t *testing.T) {
+		config := getTestClientConfig(t)
+		config.InteractionStateKey = testInteractionStateKey
+
+		interaction, err := openid4ci.NewInteraction(createCredentialOfferIssuanceURI(t, "example.com", false),
+			config)
+		require.NoError(t, err)
+
+		state, err := interaction.Serialize()
+		require.NoError(t, err)
+
+		stateParts := strings.Split(state, ".")
+
+		t.Run("Tampered with", func(t *testing.T) {
+			stateBytes, err := base64.RawURLEncoding.DecodeString(stateParts[1])
+			require.NoError(t, err)
+
+			tamperedState := strings.Replace(string(stateBytes), "example.com", "attacker.com", 1)
+
+			resumedInteraction, err := openid4ci.ResumeInteraction(stateParts[0]+"."+
+				base64.RawURLEncoding.EncodeToString([]byte(tamperedState))+"."+stateParts[2], config)
+			testutil.RequireErrorContains(t, err, "INVALID_INTERACTION_STATE(OCI0-0016):interaction state "+
+				"failed the integrity check")
+			require.Nil(t, resumedInteraction)
+		})
+		t.Run("Different key", func(t *testing.T) {
+			differentKeyConfig := getTestClientConfig(t)
+			differentKeyConfig.InteractionStateKey = []byte("abcdef0123456789abcdef0123456789")
+
+			resumedInteraction, err := openid4ci.ResumeInteraction(state, differentKeyConfig)
+			testutil.RequireErrorContains(t, err, "interaction state failed the integrity check")
+			require.Nil(t, resumedInteraction)
+		})
+		t.Run("Malformed", func(t *testing.T) {
+			resumedInteraction, err := openid4ci.ResumeInteraction("invalid", config)
+			require.EqualError(t, err, "INVALID_INTERACTION_STATE(OCI0-0016):interaction state is malformed")
+			require.Nil(t, resumedInteraction)
+		})
+		t.Run("Unsupported version", func(t *testing.T) {
+			resumedInteraction, err := openid4ci.ResumeInteraction("v0."+stateParts[1]+"."+stateParts[2],
+				config)
+			require.EqualError(t, err, "INVALID_INTERACTION_STATE(OCI0-0016):unsupported interaction state "+
+				"version: v0")
+			require.Nil(t, resumedInteraction)
+		})
+		t.Run("Integrity check value not base64url-encoded", func(t *testing.T) {
+			resumedInteraction, err := openid4ci.ResumeInteraction(stateParts[0]+"."+stateParts[1]+".!",
+				config)
+			testutil.RequireErrorContains(t, err, "failed to decode interaction state integrity check value")
+			require.Nil(t, resumedInteraction)
+		})
+	})
+	t.Run("Expired state", func(t *testing.T) {
+		config := getTestClientConfig(t)
+		config.InteractionStateKey = testInteractionStateKey
+
+		lifetime := -time.Minute
+		config.InteractionStateLifetime = &lifetime
+
+		interaction, err := openid4ci.NewInteraction(createCredentialOfferIssuanceURI(t, "example.com", false),
+			config)
+		require.NoError(t, err)
+
+		state, err := interaction.Serialize()
+		require.NoError(t, err)
+
+		resumedInteraction, err := openid4ci.ResumeInteraction(state, config)
+		testutil.RequireErrorContains(t, err, "INTERACTION_STATE_EXPIRED(OCI0-0017):interaction state expired at")
+		require.Nil(t, resumedInteraction)
+	})
+}
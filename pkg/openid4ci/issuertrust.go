@@ -0,0 +1,187 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	diderrors "github.com/trustbloc/wallet-sdk/pkg/did"
+	"github.com/trustbloc/wallet-sdk/pkg/did/wellknown"
+	"github.com/trustbloc/wallet-sdk/pkg/walleterror"
+)
+
+// The possible outcomes of an issuer trust check.
+const (
+	// IssuerTrustStatusVerified indicates that the issuer's DID is linked to the issuer's domain.
+	IssuerTrustStatusVerified = "verified"
+	// IssuerTrustStatusDomainMismatch indicates that the issuer's DID is linked to a domain, but it's not the
+	// issuer's domain. This could indicate that the issuer is impersonating someone else.
+	IssuerTrustStatusDomainMismatch = "domain_mismatch"
+	// IssuerTrustStatusNoLinkedDomains indicates that the issuer's DID document has no Linked Domains service (or the
+	// issuer isn't identified by a DID), so there's nothing to check.
+	IssuerTrustStatusNoLinkedDomains = "no_linked_domains"
+	// IssuerTrustStatusLinkedDomainUnverified indicates that the issuer's DID document has Linked Domains services,
+	// but none of their domains' DID configurations confirm the link.
+	IssuerTrustStatusLinkedDomainUnverified = "linked_domain_unverified"
+)
+
+// IssuerTrustInfo is the result of checking whether the DID that issued the credentials is linked (using a
+// Linked Domains service) to the domain of the issuer that the credential offer came from.
+type IssuerTrustInfo struct {
+	// DID is the ID of the issuer of the received credentials.
+	DID string
+	// IssuerDomain is the domain of the issuer URI from the credential offer.
+	IssuerDomain string
+	// LinkedDomain is the domain from the DID's Linked Domains services that was confirmed to be linked to the DID.
+	// If the issuer's domain is one of them, then that's the one used.
+	LinkedDomain string
+	// Status is one of the IssuerTrustStatus constants.
+	Status string
+}
+
+// IssuerTrustInfo checks whether the DID that signed the credentials received from the last call to
+// RequestCredentialWithPreAuth or RequestCredentialWithAuth is linked to the domain of the issuer URI from the
+// credential offer. The DID is resolved and the origins from its Linked Domains services (if any) are validated
+// against their well-known DID configurations. The result can be used to show the user whether the issuer can be
+// trusted. An error is returned if the credentials weren't all issued by the same DID.
+func (i *Interaction) IssuerTrustInfo() (*IssuerTrustInfo, error) {
+	return i.IssuerTrustInfoContext(context.Background())
+}
+
+// IssuerTrustInfoContext is the same as IssuerTrustInfo, except that the given context is used for resolving the
+// issuer's DID and fetching its DID configuration.
+func (i *Interaction) IssuerTrustInfoContext(ctx context.Context) (*IssuerTrustInfo, error) {
+	if len(i.credentialIssuerIDs) == 0 {
+		return nil, walleterror.NewExecutionError(
+			module,
+			IssuerTrustCheckFailedCode,
+			IssuerTrustCheckFailedError,
+			errors.New("no credentials have been received from the issuer"))
+	}
+
+	if len(i.credentialIssuerIDs) > 1 {
+		return nil, walleterror.NewExecutionError(
+			module,
+			IssuerTrustCheckFailedCode,
+			IssuerTrustCheckFailedError,
+			fmt.Errorf("the received credentials were issued by more than one issuer (%s)",
+				strings.Join(i.credentialIssuerIDs, ", ")))
+	}
+
+	credentialIssuerID := i.credentialIssuerIDs[0]
+
+	issuerURL, err := url.Parse(i.issuerURI)
+	if err != nil {
+		return nil, walleterror.NewExecutionError(
+			module,
+			IssuerTrustCheckFailedCode,
+			IssuerTrustCheckFailedError,
+			fmt.Errorf("failed to parse issuer URI: %w", err))
+	}
+
+	trustInfo := &IssuerTrustInfo{
+		DID:          credentialIssuerID,
+		IssuerDomain: issuerURL.Host,
+		Status:       IssuerTrustStatusNoLinkedDomains,
+	}
+
+	if !strings.HasPrefix(credentialIssuerID, "did:") {
+		return trustInfo, nil
+	}
+
+	linkedDomains, err := i.getLinkedDomains(ctx, credentialIssuerID, issuerURL.Host)
+	if err != nil {
+		if errors.Is(err, wellknown.ErrNoLinkedDomainsService) {
+			return trustInfo, nil
+		}
+
+		return nil, err
+	}
+
+	trustInfo.Status = IssuerTrustStatusLinkedDomainUnverified
+
+	// The issuer's own domain (if it's one of the linked domains) is checked first, so that a DID that's linked to
+	// several domains is only reported as a mismatch if none of them are the issuer's domain.
+	for _, linkedDomain := range linkedDomains {
+		err = wellknown.VerifyLinkedDomainContext(ctx, credentialIssuerID, linkedDomain.origin,
+			i.didResolver.didResolver, i.httpClient)
+		if err != nil {
+			var walletErr *walleterror.Error
+
+			if errors.As(err, &walletErr) && walletErr.Scenario == diderrors.DomainAndDidVerificationFailed {
+				continue
+			}
+
+			return nil, walleterror.NewExecutionError(
+				module,
+				IssuerTrustCheckFailedCode,
+				IssuerTrustCheckFailedError,
+				fmt.Errorf("failed to validate the issuer's linked domains: %w", err))
+		}
+
+		trustInfo.LinkedDomain = linkedDomain.host
+
+		if strings.EqualFold(linkedDomain.host, issuerURL.Host) {
+			trustInfo.Status = IssuerTrustStatusVerified
+		} else {
+			trustInfo.Status = IssuerTrustStatusDomainMismatch
+		}
+
+		break
+	}
+
+	return trustInfo, nil
+}
+
+type linkedDomain struct {
+	origin string
+	host   string
+}
+
+// getLinkedDomains returns the origins from all of the given DID's Linked Domains services, with any that are on the
+// issuer's domain first.
+func (i *Interaction) getLinkedDomains(ctx context.Context, did, issuerHost string) ([]linkedDomain, error) {
+	origins, err := wellknown.LinkedDomainOriginsContext(ctx, did, i.didResolver.didResolver)
+	if err != nil {
+		if errors.Is(err, wellknown.ErrNoLinkedDomainsService) {
+			return nil, err
+		}
+
+		return nil, walleterror.NewExecutionError(
+			module,
+			IssuerTrustCheckFailedCode,
+			IssuerTrustCheckFailedError,
+			fmt.Errorf("failed to validate the issuer's linked domains: %w", err))
+	}
+
+	linkedDomains := make([]linkedDomain, 0, len(origins))
+
+	for _, origin := range origins {
+		originURL, err := url.Parse(origin)
+		if err != nil {
+			return nil, walleterror.NewExecutionError(
+				module,
+				IssuerTrustCheckFailedCode,
+				IssuerTrustCheckFailedError,
+				fmt.Errorf("failed to parse linked domain: %w", err))
+		}
+
+		linkedDomains = append(linkedDomains, linkedDomain{origin: origin, host: originURL.Host})
+	}
+
+	sort.SliceStable(linkedDomains, func(a, b int) bool {
+		return strings.EqualFold(linkedDomains[a].host, issuerHost) &&
+			!strings.EqualFold(linkedDomains[b].host, issuerHost)
+	})
+
+	return linkedDomains, nil
+}
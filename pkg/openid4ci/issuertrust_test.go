@@ -0,0 +1,301 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci_test
+
+import (
+	_ "embed"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/component/models/did"
+	"github.com/hyperledger/aries-framework-go/component/models/did/endpoint"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/wallet-sdk/internal/testutil"
+	"github.com/trustbloc/wallet-sdk/pkg/openid4ci"
+)
+
+var (
+	//go:embed testdata/linked_domains_did_doc.json
+	linkedDomainsDIDDoc []byte
+
+	//go:embed testdata/linked_domains_did_configuration.json
+	linkedDomainsDIDConfiguration []byte
+)
+
+// The domain that the DID in linked_domains_did_doc.json is linked to.
+const linkedDomain = "did.rohitgulati.com"
+
+// mockLinkedDomainsServerHandler acts as both an issuer and the domain that the issuer's DID is linked to.
+type mockLinkedDomainsServerHandler struct {
+	*mockIssuerServerHandler
+	didConfiguration []byte
+	// If set, then these are returned (in order) from the credential endpoint instead of the credential response.
+	credentialResponses [][]byte
+}
+
+func (m *mockLinkedDomainsServerHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if request.URL.Path == "/credential" && len(m.credentialResponses) > 0 {
+		_, err := writer.Write(m.credentialResponses[0])
+		require.NoError(m.t, err)
+
+		m.credentialResponses = m.credentialResponses[1:]
+
+		return
+	}
+
+	if request.URL.Path != "/.well-known/did-configuration.json" {
+		m.mockIssuerServerHandler.ServeHTTP(writer, request)
+
+		return
+	}
+
+	if m.didConfiguration == nil {
+		writer.WriteHeader(http.StatusNotFound)
+
+		return
+	}
+
+	_, err := writer.Write(m.didConfiguration)
+	require.NoError(m.t, err)
+}
+
+// redirectingRoundTripper sends all requests to the given server, regardless of their original host.
+// It allows an issuer at any domain to be served by a local test server.
+type redirectingRoundTripper struct {
+	serverURL *url.URL
+}
+
+func (r *redirectingRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	request.URL.Scheme = r.serverURL.Scheme
+	request.URL.Host = r.serverURL.Host
+
+	return http.DefaultTransport.RoundTrip(request)
+}
+
+type staticDIDResolver struct {
+	didDoc *did.Doc
+}
+
+func (s *staticDIDResolver) Resolve(string) (*did.DocResolution, error) {
+	if s.didDoc == nil {
+		return nil, errors.New("DID not found")
+	}
+
+	return &did.DocResolution{DIDDocument: s.didDoc}, nil
+}
+
+func TestInteraction_IssuerTrustInfo(t *testing.T) {
+	linkedDomainsDID, err := did.ParseDocument(linkedDomainsDIDDoc)
+	require.NoError(t, err)
+
+	t.Run("Issuer's DID is linked to the issuer's domain", func(t *testing.T) {
+		trustInfo, err := requestCredentialAndGetIssuerTrustInfo(t, "https://"+linkedDomain, linkedDomainsDID.ID,
+			&staticDIDResolver{didDoc: linkedDomainsDID}, linkedDomainsDIDConfiguration)
+		require.NoError(t, err)
+		require.Equal(t, &openid4ci.IssuerTrustInfo{
+			DID:          linkedDomainsDID.ID,
+			IssuerDomain: linkedDomain,
+			LinkedDomain: linkedDomain,
+			Status:       openid4ci.IssuerTrustStatusVerified,
+		}, trustInfo)
+	})
+	t.Run("Issuer's DID is linked to a different domain", func(t *testing.T) {
+		trustInfo, err := requestCredentialAndGetIssuerTrustInfo(t, "https://issuer.example.com",
+			linkedDomainsDID.ID, &staticDIDResolver{didDoc: linkedDomainsDID}, linkedDomainsDIDConfiguration)
+		require.NoError(t, err)
+		require.Equal(t, &openid4ci.IssuerTrustInfo{
+			DID:          linkedDomainsDID.ID,
+			IssuerDomain: "issuer.example.com",
+			LinkedDomain: linkedDomain,
+			Status:       openid4ci.IssuerTrustStatusDomainMismatch,
+		}, trustInfo)
+	})
+	t.Run("Issuer's domain is one of several linked domains", func(t *testing.T) {
+		didDoc, err := did.ParseDocument(linkedDomainsDIDDoc)
+		require.NoError(t, err)
+
+		didDoc.Service = []did.Service{
+			{
+				ID:              "#other",
+				Type:            "LinkedDomains",
+				ServiceEndpoint: endpoint.NewDIDCoreEndpoint("https://issuer.example.com"),
+			},
+			{
+				ID:   "#linkeddomains",
+				Type: "LinkedDomains",
+				ServiceEndpoint: endpoint.NewDIDCoreEndpoint(map[string]interface{}{
+					"origins": []interface{}{"https://other.example.com", "https://" + linkedDomain},
+				}),
+			},
+		}
+
+		trustInfo, err := requestCredentialAndGetIssuerTrustInfo(t, "https://"+linkedDomain, didDoc.ID,
+			&staticDIDResolver{didDoc: didDoc}, linkedDomainsDIDConfiguration)
+		require.NoError(t, err)
+		require.Equal(t, &openid4ci.IssuerTrustInfo{
+			DID:          didDoc.ID,
+			IssuerDomain: linkedDomain,
+			LinkedDomain: linkedDomain,
+			Status:       openid4ci.IssuerTrustStatusVerified,
+		}, trustInfo)
+
+		t.Run("None of them confirm the link", func(t *testing.T) {
+			trustInfo, err := requestCredentialAndGetIssuerTrustInfo(t, "https://"+linkedDomain, didDoc.ID,
+				&staticDIDResolver{didDoc: didDoc}, nil)
+			require.NoError(t, err)
+			require.Equal(t, openid4ci.IssuerTrustStatusLinkedDomainUnverified, trustInfo.Status)
+			require.Empty(t, trustInfo.LinkedDomain)
+		})
+	})
+	t.Run("Linked domain doesn't confirm the link", func(t *testing.T) {
+		trustInfo, err := requestCredentialAndGetIssuerTrustInfo(t, "https://"+linkedDomain, linkedDomainsDID.ID,
+			&staticDIDResolver{didDoc: linkedDomainsDID}, nil)
+		require.NoError(t, err)
+		require.Equal(t, openid4ci.IssuerTrustStatusLinkedDomainUnverified, trustInfo.Status)
+		require.Empty(t, trustInfo.LinkedDomain)
+	})
+	t.Run("Issuer's DID has no Linked Domains service", func(t *testing.T) {
+		trustInfo, err := requestCredentialAndGetIssuerTrustInfo(t, "https://"+linkedDomain, "did:example:issuer",
+			&staticDIDResolver{didDoc: &did.Doc{ID: "did:example:issuer"}}, nil)
+		require.NoError(t, err)
+		require.Equal(t, &openid4ci.IssuerTrustInfo{
+			DID:          "did:example:issuer",
+			IssuerDomain: linkedDomain,
+			Status:       openid4ci.IssuerTrustStatusNoLinkedDomains,
+		}, trustInfo)
+	})
+	t.Run("Issuer isn't identified by a DID", func(t *testing.T) {
+		trustInfo, err := requestCredentialAndGetIssuerTrustInfo(t, "https://"+linkedDomain,
+			"https://"+linkedDomain, &staticDIDResolver{}, nil)
+		require.NoError(t, err)
+		require.Equal(t, openid4ci.IssuerTrustStatusNoLinkedDomains, trustInfo.Status)
+	})
+	t.Run("Fail to resolve the issuer's DID", func(t *testing.T) {
+		trustInfo, err := requestCredentialAndGetIssuerTrustInfo(t, "https://"+linkedDomain, linkedDomainsDID.ID,
+			&staticDIDResolver{}, nil)
+		testutil.RequireErrorContains(t, err, "ISSUER_TRUST_CHECK_FAILED(OCI1-0031):failed to validate the "+
+			"issuer's linked domains")
+		testutil.RequireErrorContains(t, err, "DID not found")
+		require.Nil(t, trustInfo)
+	})
+	t.Run("Credentials from more than one issuer", func(t *testing.T) {
+		handler := &mockLinkedDomainsServerHandler{
+			mockIssuerServerHandler: &mockIssuerServerHandler{t: t},
+			credentialResponses: [][]byte{
+				createCredentialResponseWithIssuer(t, linkedDomainsDID.ID),
+				createCredentialResponseWithIssuer(t, "did:example:other"),
+			},
+		}
+
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		handler.openIDConfig = &openid4ci.OpenIDConfig{TokenEndpoint: server.URL + "/oidc/token"}
+		handler.issuerMetadata = `{"credential_endpoint":"` + server.URL + `/credential"}`
+
+		config := getTestClientConfig(t)
+		config.DIDResolver = &staticDIDResolver{didDoc: linkedDomainsDID}
+
+		interaction, err := openid4ci.NewInteraction(createMultiCredentialOfferIssuanceURI(t, server.URL, false),
+			config)
+		require.NoError(t, err)
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+			openid4ci.WithPIN("1234"))
+		require.NoError(t, err)
+		require.Len(t, credentials, 2)
+
+		trustInfo, err := interaction.IssuerTrustInfo()
+		require.EqualError(t, err, "ISSUER_TRUST_CHECK_FAILED(OCI1-0031):the received credentials were issued by "+
+			"more than one issuer ("+linkedDomainsDID.ID+", did:example:other)")
+		require.Nil(t, trustInfo)
+	})
+	t.Run("No credentials received yet", func(t *testing.T) {
+		interaction := newInteraction(t, createCredentialOfferIssuanceURI(t, "https://"+linkedDomain, false))
+
+		trustInfo, err := interaction.IssuerTrustInfo()
+		require.EqualError(t, err, "ISSUER_TRUST_CHECK_FAILED(OCI1-0031):no credentials have been received "+
+			"from the issuer")
+		require.Nil(t, trustInfo)
+	})
+}
+
+func requestCredentialAndGetIssuerTrustInfo(t *testing.T, issuerURI, credentialIssuerID string,
+	didResolver *staticDIDResolver, didConfiguration []byte,
+) (*openid4ci.IssuerTrustInfo, error) {
+	t.Helper()
+
+	handler := &mockLinkedDomainsServerHandler{
+		mockIssuerServerHandler: &mockIssuerServerHandler{
+			t:                  t,
+			openIDConfig:       &openid4ci.OpenIDConfig{TokenEndpoint: issuerURI + "/oidc/token"},
+			issuerMetadata:     `{"credential_endpoint":"` + issuerURI + `/credential"}`,
+			credentialResponse: createCredentialResponseWithIssuer(t, credentialIssuerID),
+		},
+		didConfiguration: didConfiguration,
+	}
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	config := getTestClientConfig(t)
+	config.HTTPClient = &http.Client{Transport: &redirectingRoundTripper{serverURL: serverURL}}
+	config.DIDResolver = didResolver
+
+	interaction, err := openid4ci.NewInteraction(createCredentialOfferIssuanceURI(t, issuerURI, false), config)
+	require.NoError(t, err)
+
+	credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+		openid4ci.WithPIN("1234"))
+	require.NoError(t, err)
+	require.Len(t, credentials, 1)
+
+	return interaction.IssuerTrustInfo()
+}
+
+// createCredentialResponseWithIssuer returns the sample credential response, but with the credential's issuer
+// replaced. The credential's signature is left as-is, so it's only usable with VC proof checks disabled.
+func createCredentialResponseWithIssuer(t *testing.T, issuerID string) []byte {
+	t.Helper()
+
+	var credentialResponse map[string]interface{}
+
+	require.NoError(t, json.Unmarshal(sampleCredentialResponse, &credentialResponse))
+
+	credentialJWTParts := strings.Split(credentialResponse["credential"].(string), ".")
+	require.Len(t, credentialJWTParts, 3)
+
+	payloadBytes, err := base64.RawURLEncoding.DecodeString(credentialJWTParts[1])
+	require.NoError(t, err)
+
+	var payload map[string]interface{}
+
+	require.NoError(t, json.Unmarshal(payloadBytes, &payload))
+
+	payload["iss"] = issuerID
+
+	payloadBytes, err = json.Marshal(payload)
+	require.NoError(t, err)
+
+	credentialJWTParts[1] = base64.RawURLEncoding.EncodeToString(payloadBytes)
+	credentialResponse["credential"] = strings.Join(credentialJWTParts, ".")
+
+	credentialResponseBytes, err := json.Marshal(credentialResponse)
+	require.NoError(t, err)
+
+	return credentialResponseBytes
+}
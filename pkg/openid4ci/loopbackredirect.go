@@ -0,0 +1,279 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/aries-framework-go/component/models/verifiable"
+
+	"github.com/trustbloc/wallet-sdk/pkg/api"
+	"github.com/trustbloc/wallet-sdk/pkg/walleterror"
+)
+
+const (
+	loopbackRedirectReadHeaderTimeout = 10 * time.Second
+
+	loopbackRedirectSuccessPage = "<!DOCTYPE html><html><head><title>Authorization complete</title></head>" +
+		"<body><p>Authorization complete. You can close this window and return to your wallet.</p></body></html>"
+	loopbackRedirectFailurePage = "<!DOCTYPE html><html><head><title>Authorization failed</title></head>" +
+		"<body><p>Authorization failed. You can close this window and return to your wallet.</p></body></html>"
+)
+
+// LoopbackRedirectListener receives the authorization response for the authorization code flow using a loopback
+// redirect URI, as described in RFC 8252 section 7.3. It's intended for desktop and CLI wallets, which can't
+// register a custom URL scheme to receive the redirect. The listener only accepts connections on 127.0.0.1, using a
+// port chosen by the operating system, and only accepts a single authorization response whose state matches the one in
+// the authorization URL.
+// For the simplest usage, see the RequestCredentialWithLoopbackRedirect method instead.
+type LoopbackRedirectListener struct {
+	server      *http.Server
+	redirectURI string
+	path        string
+	timeout     time.Duration
+	responses   chan string
+
+	stateLock sync.RWMutex
+	state     string
+}
+
+// NewLoopbackRedirectListener starts listening for an authorization response on an ephemeral port on 127.0.0.1.
+// Pass RedirectURI in to CreateAuthorizationURL, pass the returned authorization URL in to SetAuthorizationURL, open
+// it in the user's browser, and then call WaitForRedirect to get the redirect URI to pass in to
+// RequestCredentialWithAuth.
+// The listener must be closed once it's no longer needed.
+func NewLoopbackRedirectListener(opts ...LoopbackRedirectOpt) (*LoopbackRedirectListener, error) {
+	processedOpts := processLoopbackRedirectOpts(opts)
+
+	path := processedOpts.path
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		return nil, walleterror.NewExecutionError(
+			module,
+			AuthorizationResponseFailedCode,
+			AuthorizationResponseFailedError,
+			fmt.Errorf("failed to start the loopback redirect listener: %w", err))
+	}
+
+	loopbackRedirectListener := &LoopbackRedirectListener{
+		redirectURI: fmt.Sprintf("http://%s%s", listener.Addr().String(), path),
+		path:        path,
+		timeout:     processedOpts.timeout,
+		responses:   make(chan string, 1),
+	}
+
+	loopbackRedirectListener.server = &http.Server{
+		Handler:           http.HandlerFunc(loopbackRedirectListener.handleRedirect),
+		ReadHeaderTimeout: loopbackRedirectReadHeaderTimeout,
+	}
+
+	go func() {
+		// Serve always returns an error once the server is closed, which isn't relevant to the caller.
+		_ = loopbackRedirectListener.server.Serve(listener) //nolint:errcheck
+	}()
+
+	return loopbackRedirectListener, nil
+}
+
+// RedirectURI returns the loopback redirect URI to use when creating the authorization URL
+// (e.g. http://127.0.0.1:51234/callback).
+func (l *LoopbackRedirectListener) RedirectURI() string {
+	return l.redirectURI
+}
+
+// SetAuthorizationURL tells the listener which authorization URL (as returned by CreateAuthorizationURL) the user is
+// being sent to. Only authorization responses with the same state as this URL are accepted, so this must be called
+// before the URL is opened in the user's browser.
+func (l *LoopbackRedirectListener) SetAuthorizationURL(authorizationURL string) error {
+	parsedURL, err := url.Parse(authorizationURL)
+	if err != nil {
+		return walleterror.NewValidationError(
+			module,
+			AuthorizationResponseFailedCode,
+			AuthorizationResponseFailedError,
+			fmt.Errorf("failed to parse the authorization URL: %w", err))
+	}
+
+	state := parsedURL.Query().Get("state")
+	if state == "" {
+		return walleterror.NewValidationError(
+			module,
+			AuthorizationResponseFailedCode,
+			AuthorizationResponseFailedError,
+			errors.New("authorization URL is missing a state value"))
+	}
+
+	l.stateLock.Lock()
+	defer l.stateLock.Unlock()
+
+	l.state = state
+
+	return nil
+}
+
+// WaitForRedirect waits for the authorization server to redirect the user's browser to the loopback redirect URI,
+// and returns the full redirect URI, including the authorization response parameters. The returned URI can be passed
+// in to RequestCredentialWithAuth as-is. An error is returned if the authorization server responded with an error,
+// if the timeout is reached, or if the given context is done first.
+func (l *LoopbackRedirectListener) WaitForRedirect(ctx context.Context) (string, error) {
+	timer := time.NewTimer(l.timeout)
+	defer timer.Stop()
+
+	select {
+	case redirectURIWithParams := <-l.responses:
+		return redirectURIWithParams, checkAuthorizationResponse(redirectURIWithParams)
+	case <-timer.C:
+		return "", walleterror.NewExecutionError(
+			module,
+			AuthorizationResponseFailedCode,
+			AuthorizationResponseFailedError,
+			fmt.Errorf("timed out after %s waiting for the authorization response", l.timeout))
+	case <-ctx.Done():
+		return "", walleterror.NewExecutionError(
+			module,
+			AuthorizationResponseFailedCode,
+			AuthorizationResponseFailedError,
+			fmt.Errorf("stopped waiting for the authorization response: %w", ctx.Err()))
+	}
+}
+
+// Close stops the listener.
+func (l *LoopbackRedirectListener) Close() error {
+	return l.server.Close()
+}
+
+func (l *LoopbackRedirectListener) handleRedirect(writer http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet || request.URL.Path != l.path {
+		http.NotFound(writer, request)
+
+		return
+	}
+
+	query := request.URL.Query()
+
+	// Anything that isn't an authorization response to our authorization URL (RFC 6749 section 4.1.2) is rejected
+	// without using up the slot for the real one.
+	if !l.isExpectedState(query.Get("state")) || (!query.Has("code") && !query.Has("error")) {
+		http.Error(writer, "invalid authorization response", http.StatusBadRequest)
+
+		return
+	}
+
+	redirectURIWithParams := strings.TrimSuffix(l.redirectURI, l.path) + request.URL.RequestURI()
+
+	select {
+	case l.responses <- redirectURIWithParams:
+	default:
+		// Only the first authorization response is used.
+		http.Error(writer, "an authorization response has already been received", http.StatusConflict)
+
+		return
+	}
+
+	page := loopbackRedirectSuccessPage
+	if query.Has("error") {
+		page = loopbackRedirectFailurePage
+	}
+
+	writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	_, _ = writer.Write([]byte(page)) //nolint:errcheck // Nothing can be done if the browser has gone away.
+}
+
+func (l *LoopbackRedirectListener) isExpectedState(state string) bool {
+	l.stateLock.RLock()
+	defer l.stateLock.RUnlock()
+
+	return l.state != "" && state == l.state
+}
+
+// RequestCredentialWithLoopbackRedirect goes through the whole authorization code flow using a loopback redirect URI
+// (see LoopbackRedirectListener). It starts a listener, creates an authorization URL that redirects to it, and passes
+// that URL to openAuthorizationURL, which should open it in the user's browser. Once the user has logged in and the
+// authorization server has redirected the browser back to the listener, the credential(s) are requested in the same
+// way as RequestCredentialWithAuth. The listener is closed before this method returns.
+// Use the WithLoopbackRedirectTimeout option to control how long to wait for the user to log in.
+func (i *Interaction) RequestCredentialWithLoopbackRedirect(ctx context.Context, jwtSigner api.JWTSigner,
+	clientID string, openAuthorizationURL func(authorizationURL string) error, opts ...LoopbackRedirectOpt,
+) ([]*verifiable.Credential, error) {
+	if openAuthorizationURL == nil {
+		return nil, errors.New("a function to open the authorization URL must be provided")
+	}
+
+	processedOpts := processLoopbackRedirectOpts(opts)
+
+	listener, err := NewLoopbackRedirectListener(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		_ = listener.Close() //nolint:errcheck // The listener is no longer needed either way.
+	}()
+
+	authorizationURL, err := i.CreateAuthorizationURLContext(ctx, clientID, listener.RedirectURI(),
+		processedOpts.authorizationURLOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	err = listener.SetAuthorizationURL(authorizationURL)
+	if err != nil {
+		return nil, err
+	}
+
+	err = openAuthorizationURL(authorizationURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the authorization URL: %w", err)
+	}
+
+	redirectURIWithParams, err := listener.WaitForRedirect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return i.RequestCredentialWithAuthContext(ctx, jwtSigner, redirectURIWithParams)
+}
+
+// checkAuthorizationResponse returns an error if the authorization server responded with an error
+// (RFC 6749 section 4.1.2.1).
+func checkAuthorizationResponse(redirectURIWithParams string) error {
+	parsedURI, err := url.Parse(redirectURIWithParams)
+	if err != nil {
+		return err
+	}
+
+	query := parsedURI.Query()
+
+	if !query.Has("error") {
+		return nil
+	}
+
+	errorMessage := fmt.Sprintf("authorization server returned an error: %s", query.Get("error"))
+
+	if description := query.Get("error_description"); description != "" {
+		errorMessage += ": " + description
+	}
+
+	return walleterror.NewExecutionError(
+		module,
+		AuthorizationResponseFailedCode,
+		AuthorizationResponseFailedError,
+		errors.New(errorMessage))
+}
@@ -0,0 +1,333 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/wallet-sdk/internal/testutil"
+	"github.com/trustbloc/wallet-sdk/pkg/openid4ci"
+)
+
+// mockAuthorizingIssuerHandler acts as both an issuer and its authorization server. Authorization requests are
+// approved (or denied) straight away by redirecting back to the redirect URI, as if the user had logged in.
+type mockAuthorizingIssuerHandler struct {
+	*mockIssuerServerHandler
+	authorizationError string
+}
+
+func (m *mockAuthorizingIssuerHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	if request.URL.Path != "/authorize" {
+		m.mockIssuerServerHandler.ServeHTTP(writer, request)
+
+		return
+	}
+
+	redirectURI, err := url.Parse(request.URL.Query().Get("redirect_uri"))
+	require.NoError(m.t, err)
+
+	query := redirectURI.Query()
+
+	if m.authorizationError != "" {
+		query.Set("error", m.authorizationError)
+		query.Set("error_description", "the user denied the request")
+	} else {
+		query.Set("code", "1234")
+	}
+
+	query.Set("state", request.URL.Query().Get("state"))
+
+	redirectURI.RawQuery = query.Encode()
+
+	http.Redirect(writer, request, redirectURI.String(), http.StatusFound)
+}
+
+func TestInteraction_RequestCredentialWithLoopbackRedirect(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		interaction := newLoopbackRedirectTestInteraction(t, "")
+
+		var authorizationResponsePage string
+
+		credentials, err := interaction.RequestCredentialWithLoopbackRedirect(context.Background(),
+			&jwtSignerMock{keyID: mockKeyID}, "clientID", func(authorizationURL string) error {
+				authorizationResponsePage = openInBrowser(t, authorizationURL)
+
+				return nil
+			}, openid4ci.WithLoopbackAuthorizationURLOpts(openid4ci.WithScopes([]string{"openid"})))
+		require.NoError(t, err)
+		require.Len(t, credentials, 1)
+		require.Contains(t, authorizationResponsePage, "Authorization complete")
+	})
+	t.Run("Authorization server returns an error", func(t *testing.T) {
+		interaction := newLoopbackRedirectTestInteraction(t, "access_denied")
+
+		var authorizationResponsePage string
+
+		credentials, err := interaction.RequestCredentialWithLoopbackRedirect(context.Background(),
+			&jwtSignerMock{keyID: mockKeyID}, "clientID", func(authorizationURL string) error {
+				authorizationResponsePage = openInBrowser(t, authorizationURL)
+
+				return nil
+			})
+		require.EqualError(t, err, "AUTHORIZATION_RESPONSE_FAILED(OCI1-0032):authorization server returned an "+
+			"error: access_denied: the user denied the request")
+		require.Nil(t, credentials)
+		require.Contains(t, authorizationResponsePage, "Authorization failed")
+	})
+	t.Run("Timed out waiting for the authorization response", func(t *testing.T) {
+		interaction := newLoopbackRedirectTestInteraction(t, "")
+
+		credentials, err := interaction.RequestCredentialWithLoopbackRedirect(context.Background(),
+			&jwtSignerMock{keyID: mockKeyID}, "clientID", func(string) error {
+				return nil
+			}, openid4ci.WithLoopbackRedirectTimeout(time.Millisecond))
+		require.EqualError(t, err, "AUTHORIZATION_RESPONSE_FAILED(OCI1-0032):timed out after 1ms waiting for "+
+			"the authorization response")
+		require.Nil(t, credentials)
+	})
+	t.Run("Context cancelled while waiting for the authorization response", func(t *testing.T) {
+		interaction := newLoopbackRedirectTestInteraction(t, "")
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		credentials, err := interaction.RequestCredentialWithLoopbackRedirect(ctx,
+			&jwtSignerMock{keyID: mockKeyID}, "clientID", func(string) error {
+				cancel()
+
+				return nil
+			})
+		testutil.RequireErrorContains(t, err, "AUTHORIZATION_RESPONSE_FAILED")
+		testutil.RequireErrorContains(t, err, context.Canceled.Error())
+		require.Nil(t, credentials)
+	})
+	t.Run("Fail to open the authorization URL", func(t *testing.T) {
+		interaction := newLoopbackRedirectTestInteraction(t, "")
+
+		credentials, err := interaction.RequestCredentialWithLoopbackRedirect(context.Background(),
+			&jwtSignerMock{keyID: mockKeyID}, "clientID", func(string) error {
+				return errors.New("no browser available")
+			})
+		require.EqualError(t, err, "failed to open the authorization URL: no browser available")
+		require.Nil(t, credentials)
+	})
+	t.Run("Issuer doesn't support the authorization code grant type", func(t *testing.T) {
+		interaction := newInteraction(t, createCredentialOfferIssuanceURI(t, "example.com", false))
+
+		credentials, err := interaction.RequestCredentialWithLoopbackRedirect(context.Background(),
+			&jwtSignerMock{keyID: mockKeyID}, "clientID", func(string) error {
+				return nil
+			})
+		require.EqualError(t, err, "issuer does not support the authorization code grant type")
+		require.Nil(t, credentials)
+	})
+	t.Run("No function to open the authorization URL", func(t *testing.T) {
+		interaction := newLoopbackRedirectTestInteraction(t, "")
+
+		credentials, err := interaction.RequestCredentialWithLoopbackRedirect(context.Background(),
+			&jwtSignerMock{keyID: mockKeyID}, "clientID", nil)
+		require.EqualError(t, err, "a function to open the authorization URL must be provided")
+		require.Nil(t, credentials)
+	})
+}
+
+func TestLoopbackRedirectListener(t *testing.T) {
+	t.Run("Used with CreateAuthorizationURL and RequestCredentialWithAuth", func(t *testing.T) {
+		interaction := newLoopbackRedirectTestInteraction(t, "")
+
+		listener, err := openid4ci.NewLoopbackRedirectListener(openid4ci.WithLoopbackRedirectPath("oauth/callback"))
+		require.NoError(t, err)
+
+		defer func() {
+			require.NoError(t, listener.Close())
+		}()
+
+		redirectURI, err := url.Parse(listener.RedirectURI())
+		require.NoError(t, err)
+		require.Equal(t, "http", redirectURI.Scheme)
+		require.Equal(t, "127.0.0.1", redirectURI.Hostname())
+		require.NotEmpty(t, redirectURI.Port())
+		require.Equal(t, "/oauth/callback", redirectURI.Path)
+
+		authorizationURL, err := interaction.CreateAuthorizationURL("clientID", listener.RedirectURI())
+		require.NoError(t, err)
+
+		require.NoError(t, listener.SetAuthorizationURL(authorizationURL))
+
+		openInBrowser(t, authorizationURL)
+
+		redirectURIWithParams, err := listener.WaitForRedirect(context.Background())
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(redirectURIWithParams, listener.RedirectURI()+"?"))
+
+		credentials, err := interaction.RequestCredentialWithAuth(&jwtSignerMock{keyID: mockKeyID},
+			redirectURIWithParams)
+		require.NoError(t, err)
+		require.Len(t, credentials, 1)
+	})
+	t.Run("Only the first authorization response is accepted", func(t *testing.T) {
+		listener, err := openid4ci.NewLoopbackRedirectListener()
+		require.NoError(t, err)
+
+		defer func() {
+			require.NoError(t, listener.Close())
+		}()
+
+		require.NoError(t, listener.SetAuthorizationURL("https://example.com/authorize?state=1"))
+
+		require.Equal(t, http.StatusOK, sendToListener(t, http.MethodGet, listener.RedirectURI()+"?code=1&state=1"))
+		require.Equal(t, http.StatusConflict,
+			sendToListener(t, http.MethodGet, listener.RedirectURI()+"?code=2&state=1"))
+
+		redirectURIWithParams, err := listener.WaitForRedirect(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, listener.RedirectURI()+"?code=1&state=1", redirectURIWithParams)
+	})
+	t.Run("Other requests are ignored", func(t *testing.T) {
+		listener, err := openid4ci.NewLoopbackRedirectListener(openid4ci.WithLoopbackRedirectTimeout(time.Millisecond))
+		require.NoError(t, err)
+
+		defer func() {
+			require.NoError(t, listener.Close())
+		}()
+
+		redirectURI, err := url.Parse(listener.RedirectURI())
+		require.NoError(t, err)
+
+		require.Equal(t, http.StatusNotFound,
+			sendToListener(t, http.MethodGet, "http://"+redirectURI.Host+"/favicon.ico"))
+		require.Equal(t, http.StatusNotFound, sendToListener(t, http.MethodPost, listener.RedirectURI()))
+
+		redirectURIWithParams, err := listener.WaitForRedirect(context.Background())
+		testutil.RequireErrorContains(t, err, "timed out")
+		require.Empty(t, redirectURIWithParams)
+	})
+	t.Run("Responses that don't match the authorization URL are rejected", func(t *testing.T) {
+		listener, err := openid4ci.NewLoopbackRedirectListener()
+		require.NoError(t, err)
+
+		defer func() {
+			require.NoError(t, listener.Close())
+		}()
+
+		require.Equal(t, http.StatusBadRequest,
+			sendToListener(t, http.MethodGet, listener.RedirectURI()+"?code=1&state=1"))
+
+		require.NoError(t, listener.SetAuthorizationURL("https://example.com/authorize?state=1"))
+
+		require.Equal(t, http.StatusBadRequest, sendToListener(t, http.MethodGet, listener.RedirectURI()))
+		require.Equal(t, http.StatusBadRequest,
+			sendToListener(t, http.MethodGet, listener.RedirectURI()+"?code=1"))
+		require.Equal(t, http.StatusBadRequest,
+			sendToListener(t, http.MethodGet, listener.RedirectURI()+"?code=1&state=2"))
+		require.Equal(t, http.StatusBadRequest,
+			sendToListener(t, http.MethodGet, listener.RedirectURI()+"?state=1"))
+		require.Equal(t, http.StatusOK,
+			sendToListener(t, http.MethodGet, listener.RedirectURI()+"?code=2&state=1"))
+
+		redirectURIWithParams, err := listener.WaitForRedirect(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, listener.RedirectURI()+"?code=2&state=1", redirectURIWithParams)
+	})
+	t.Run("Invalid authorization URL", func(t *testing.T) {
+		listener, err := openid4ci.NewLoopbackRedirectListener()
+		require.NoError(t, err)
+
+		defer func() {
+			require.NoError(t, listener.Close())
+		}()
+
+		err = listener.SetAuthorizationURL("https://example.com/authorize")
+		testutil.RequireErrorContains(t, err, "authorization URL is missing a state value")
+
+		err = listener.SetAuthorizationURL("%")
+		testutil.RequireErrorContains(t, err, "failed to parse the authorization URL")
+	})
+	t.Run("Non-positive timeout falls back to the default", func(t *testing.T) {
+		listener, err := openid4ci.NewLoopbackRedirectListener(openid4ci.WithLoopbackRedirectTimeout(0))
+		require.NoError(t, err)
+
+		defer func() {
+			require.NoError(t, listener.Close())
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		redirectURIWithParams, err := listener.WaitForRedirect(ctx)
+		testutil.RequireErrorContains(t, err, "stopped waiting for the authorization response")
+		require.Empty(t, redirectURIWithParams)
+	})
+}
+
+func newLoopbackRedirectTestInteraction(t *testing.T, authorizationError string) *openid4ci.Interaction {
+	t.Helper()
+
+	handler := &mockAuthorizingIssuerHandler{
+		mockIssuerServerHandler: &mockIssuerServerHandler{
+			t:                  t,
+			credentialResponse: sampleCredentialResponse,
+		},
+		authorizationError: authorizationError,
+	}
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	handler.openIDConfig = &openid4ci.OpenIDConfig{
+		AuthorizationEndpoint: server.URL + "/authorize",
+		TokenEndpoint:         server.URL + "/oidc/token",
+	}
+	handler.issuerMetadata = fmt.Sprintf(`{"credential_endpoint":"%s/credential"}`, server.URL)
+
+	return newInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, true))
+}
+
+// openInBrowser follows the authorization URL (and its redirects) the same way that a browser would, and returns the
+// page that's eventually shown to the user.
+func openInBrowser(t *testing.T, authorizationURL string) string {
+	t.Helper()
+
+	request, err := http.NewRequestWithContext(context.Background(), http.MethodGet, authorizationURL, http.NoBody)
+	require.NoError(t, err)
+
+	response, err := http.DefaultClient.Do(request)
+	require.NoError(t, err)
+
+	defer func() {
+		require.NoError(t, response.Body.Close())
+	}()
+
+	page, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+
+	return string(page)
+}
+
+func sendToListener(t *testing.T, method, uri string) int {
+	t.Helper()
+
+	request, err := http.NewRequestWithContext(context.Background(), method, uri, http.NoBody)
+	require.NoError(t, err)
+
+	response, err := http.DefaultClient.Do(request)
+	require.NoError(t, err)
+
+	require.NoError(t, response.Body.Close())
+
+	return response.StatusCode
+}
@@ -0,0 +1,67 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci
+
+import "time"
+
+const (
+	defaultLoopbackRedirectPath    = "/callback"
+	defaultLoopbackRedirectTimeout = 5 * time.Minute
+)
+
+type loopbackRedirectOpts struct {
+	path                 string
+	timeout              time.Duration
+	authorizationURLOpts []CreateAuthorizationURLOpt
+}
+
+// LoopbackRedirectOpt is an option for NewLoopbackRedirectListener and the RequestCredentialWithLoopbackRedirect
+// method.
+type LoopbackRedirectOpt func(opts *loopbackRedirectOpts)
+
+// WithLoopbackRedirectPath sets the path of the loopback redirect URI. If not set, then "/callback" is used.
+// Some authorization servers require the path to match the one registered for the client.
+func WithLoopbackRedirectPath(path string) LoopbackRedirectOpt {
+	return func(opts *loopbackRedirectOpts) {
+		opts.path = path
+	}
+}
+
+// WithLoopbackRedirectTimeout sets how long to wait for the authorization response before giving up.
+// If not set, or if the given timeout isn't positive, then a default of five minutes is used.
+func WithLoopbackRedirectTimeout(timeout time.Duration) LoopbackRedirectOpt {
+	return func(opts *loopbackRedirectOpts) {
+		opts.timeout = timeout
+	}
+}
+
+// WithLoopbackAuthorizationURLOpts sets the options to use when the RequestCredentialWithLoopbackRedirect method
+// creates the authorization URL (e.g. WithScopes). It has no effect on NewLoopbackRedirectListener.
+func WithLoopbackAuthorizationURLOpts(authorizationURLOpts ...CreateAuthorizationURLOpt) LoopbackRedirectOpt {
+	return func(opts *loopbackRedirectOpts) {
+		opts.authorizationURLOpts = authorizationURLOpts
+	}
+}
+
+func processLoopbackRedirectOpts(opts []LoopbackRedirectOpt) *loopbackRedirectOpts {
+	processedOpts := &loopbackRedirectOpts{
+		path:    defaultLoopbackRedirectPath,
+		timeout: defaultLoopbackRedirectTimeout,
+	}
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt(processedOpts)
+		}
+	}
+
+	if processedOpts.timeout <= 0 {
+		processedOpts.timeout = defaultLoopbackRedirectTimeout
+	}
+
+	return processedOpts
+}
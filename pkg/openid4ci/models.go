@@ -10,10 +10,13 @@ import "encoding/json"
 
 // CredentialOffer represents the Credential Offer object as defined in
 // https://openid.net/specs/openid-4-verifiable-credential-issuance-1_0-11.html#section-4.1.1.
+// Credential offers that follow draft 13 (or later) use CredentialConfigurationIDs instead of Credentials.
+// See https://openid.net/specs/openid-4-verifiable-credential-issuance-1_0-13.html#section-4.1.1.
 type CredentialOffer struct {
-	CredentialIssuer string                            `json:"credential_issuer,omitempty"`
-	Credentials      []Credentials                     `json:"credentials,omitempty"`
-	Grants           map[string]map[string]interface{} `json:"grants,omitempty"`
+	CredentialIssuer           string                            `json:"credential_issuer,omitempty"`
+	Credentials                []Credentials                     `json:"credentials,omitempty"`
+	CredentialConfigurationIDs []string                          `json:"credential_configuration_ids,omitempty"`
+	Grants                     map[string]map[string]interface{} `json:"grants,omitempty"`
 }
 
 // Credentials represents the credential format and types in a Credential Offer.
@@ -30,18 +33,38 @@ type AuthorizeResult struct {
 }
 
 type authorizationDetails struct {
-	Type      string   `json:"type,omitempty"`
-	Locations []string `json:"locations,omitempty"`
-	Types     []string `json:"types,omitempty"`
-	Format    string   `json:"format,omitempty"`
+	Type                      string   `json:"type,omitempty"`
+	Locations                 []string `json:"locations,omitempty"`
+	Types                     []string `json:"types,omitempty"`
+	Format                    string   `json:"format,omitempty"`
+	CredentialConfigurationID string   `json:"credential_configuration_id,omitempty"`
 }
 
-// OpenIDConfig represents an issuer's OpenID configuration.
+// tokenAuthorizationDetails is an entry in the authorization_details array that issuers following draft 13
+// (or later) may return in token responses.
+type tokenAuthorizationDetails struct {
+	Type                      string   `json:"type,omitempty"`
+	CredentialConfigurationID string   `json:"credential_configuration_id,omitempty"`
+	CredentialIdentifiers     []string `json:"credential_identifiers,omitempty"`
+}
+
+// OpenIDConfig represents the metadata of an issuer's authorization server. It may have been published either as an
+// OpenID configuration or as OAuth 2.0 authorization server metadata (RFC 8414).
 type OpenIDConfig struct {
-	AuthorizationEndpoint  string   `json:"authorization_endpoint,omitempty"`
-	ResponseTypesSupported []string `json:"response_types_supported,omitempty"`
-	TokenEndpoint          string   `json:"token_endpoint,omitempty"`
-	RegistrationEndpoint   *string  `json:"registration_endpoint,omitempty"`
+	Issuer                            string   `json:"issuer,omitempty"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint,omitempty"`
+	ResponseTypesSupported            []string `json:"response_types_supported,omitempty"`
+	TokenEndpoint                     string   `json:"token_endpoint,omitempty"`
+	RegistrationEndpoint              *string  `json:"registration_endpoint,omitempty"`
+	GrantTypesSupported               []string `json:"grant_types_supported,omitempty"`
+	ScopesSupported                   []string `json:"scopes_supported,omitempty"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported,omitempty"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported,omitempty"`
+	// PushedAuthorizationRequestEndpoint and RequirePushedAuthorizationRequests are defined in RFC 9126.
+	PushedAuthorizationRequestEndpoint string `json:"pushed_authorization_request_endpoint,omitempty"`
+	RequirePushedAuthorizationRequests bool   `json:"require_pushed_authorization_requests,omitempty"`
+	// DPoPSigningAlgValuesSupported is defined in RFC 9449.
+	DPoPSigningAlgValuesSupported []string `json:"dpop_signing_alg_values_supported,omitempty"`
 }
 
 // CredentialResponse is the object returned from the Client.Callback method.
@@ -49,6 +72,13 @@ type OpenIDConfig struct {
 type CredentialResponse struct {
 	Credential interface{} `json:"credential,omitempty"` // Optional for deferred credential flow.
 	Format     string      `json:"format,omitempty"`
+	// AcceptanceToken is set instead of Credential if the issuer deferred the credential.
+	AcceptanceToken string `json:"acceptance_token,omitempty"`
+	// TransactionID is set instead of Credential if the issuer deferred the credential.
+	// It's used by newer versions of the spec in place of AcceptanceToken.
+	TransactionID string `json:"transaction_id,omitempty"`
+	// NotificationID is used to notify the issuer about what happened to the credential. See NotificationHandle.
+	NotificationID string `json:"notification_id,omitempty"`
 }
 
 // SerializeToCredentialsBytes serializes underlying credential to proper bytes representation depending on
@@ -63,19 +93,53 @@ func (r *CredentialResponse) SerializeToCredentialsBytes() ([]byte, error) {
 	}
 }
 
+func (r *CredentialResponse) isDeferred() bool {
+	return r.Credential == nil && (r.AcceptanceToken != "" || r.TransactionID != "")
+}
+
 type preAuthTokenResponse struct {
-	AccessToken     string `json:"access_token,omitempty"`
-	TokenType       string `json:"token_type,omitempty"`
-	ExpiresIn       int    `json:"expires_in,omitempty"`
-	RefreshToken    string `json:"refresh_token,omitempty"`
-	CNonce          string `json:"c_nonce,omitempty"`
-	CNonceExpiresIn int    `json:"c_nonce_expires_in,omitempty"`
+	AccessToken          string                      `json:"access_token,omitempty"`
+	TokenType            string                      `json:"token_type,omitempty"`
+	ExpiresIn            int                         `json:"expires_in,omitempty"`
+	RefreshToken         string                      `json:"refresh_token,omitempty"`
+	CNonce               string                      `json:"c_nonce,omitempty"`
+	CNonceExpiresIn      int                         `json:"c_nonce_expires_in,omitempty"`
+	AuthorizationDetails []tokenAuthorizationDetails `json:"authorization_details,omitempty"`
 }
 
 type credentialRequest struct {
-	Types  []string `json:"types,omitempty"`
-	Format string   `json:"format,omitempty"`
-	Proof  proof    `json:"proof,omitempty"`
+	Types                []string              `json:"types,omitempty"`
+	Format               string                `json:"format,omitempty"`
+	CredentialDefinition *credentialDefinition `json:"credential_definition,omitempty"`
+	CredentialIdentifier string                `json:"credential_identifier,omitempty"`
+	Proof                proof                 `json:"proof,omitempty"`
+	// The fields below request an encrypted credential response. Draft 13 (and later) uses
+	// CredentialResponseEncryption in place of the other three fields.
+	CredentialEncryptionJWK         json.RawMessage                      `json:"credential_encryption_jwk,omitempty"`
+	CredentialResponseEncryptionAlg string                               `json:"credential_response_encryption_alg,omitempty"` //nolint:lll
+	CredentialResponseEncryptionEnc string                               `json:"credential_response_encryption_enc,omitempty"` //nolint:lll
+	CredentialResponseEncryption    *credentialResponseEncryptionRequest `json:"credential_response_encryption,omitempty"`
+}
+
+type credentialResponseEncryptionRequest struct {
+	JWK json.RawMessage `json:"jwk,omitempty"`
+	Alg string          `json:"alg,omitempty"`
+	Enc string          `json:"enc,omitempty"`
+}
+
+type credentialDefinition struct {
+	Types []string `json:"type,omitempty"`
+}
+
+type batchCredentialRequest struct {
+	CredentialRequests []credentialRequest `json:"credential_requests,omitempty"`
+}
+
+type batchCredentialResponse struct {
+	// Each entry is either a credential response or an error response for that credential.
+	CredentialResponses []json.RawMessage `json:"credential_responses,omitempty"`
+	CNonce              string            `json:"c_nonce,omitempty"`
+	CNonceExpiresIn     int               `json:"c_nonce_expires_in,omitempty"`
 }
 
 type proof struct {
@@ -84,3 +148,13 @@ type proof struct {
 	CNonce          string `json:"c_nonce,omitempty"`
 	CNonceExpiresIn int    `json:"c_nonce_expires_in,omitempty"`
 }
+
+type deferredCredentialRequest struct {
+	TransactionID                string                               `json:"transaction_id,omitempty"`
+	CredentialResponseEncryption *credentialResponseEncryptionRequest `json:"credential_response_encryption,omitempty"`
+}
+
+type deferredCredentialErrorResponse struct {
+	Error    string `json:"error,omitempty"`
+	Interval int    `json:"interval,omitempty"`
+}
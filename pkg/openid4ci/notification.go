@@ -0,0 +1,225 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hyperledger/aries-framework-go/component/models/verifiable"
+
+	"github.com/trustbloc/wallet-sdk/pkg/api"
+	"github.com/trustbloc/wallet-sdk/pkg/internal/httprequest"
+	"github.com/trustbloc/wallet-sdk/pkg/walleterror"
+)
+
+// The events that can be sent to an issuer's notification endpoint.
+const (
+	// NotificationEventCredentialAccepted indicates that the credential was successfully stored by the wallet.
+	NotificationEventCredentialAccepted = "credential_accepted"
+	// NotificationEventCredentialDeleted indicates that the credential was rejected or deleted by the user.
+	NotificationEventCredentialDeleted = "credential_deleted"
+	// NotificationEventCredentialFailure indicates that the credential couldn't be stored for any other reason.
+	NotificationEventCredentialFailure = "credential_failure"
+)
+
+const (
+	sendNotificationEventText = "Send notification to issuer"
+	//nolint:gosec //false positive
+	sendNotificationViaPOSTReqEventText = "Send notification via an HTTP POST request to %s"
+
+	notificationActivityLogOperation = "oidc-issuance-notification"
+)
+
+// NotificationHandle holds what's needed to notify an issuer about what happened to a credential it issued (e.g.
+// whether the wallet accepted it), using the issuer's notification endpoint. It's bound to a specific credential
+// (identified by CredentialID) from a specific issuer. It can be persisted (e.g. serialized to JSON) alongside the
+// credential, but should be treated as sensitive data, as it contains an access token.
+type NotificationHandle struct {
+	CredentialID         string `json:"credential_id,omitempty"`
+	IssuerURI            string `json:"issuer_uri,omitempty"`
+	NotificationEndpoint string `json:"notification_endpoint,omitempty"`
+	NotificationID       string `json:"notification_id,omitempty"`
+	AccessToken          string `json:"access_token,omitempty"`
+}
+
+type notificationRequest struct {
+	NotificationID   string `json:"notification_id"`
+	Event            string `json:"event"`
+	EventDescription string `json:"event_description,omitempty"`
+}
+
+// NotificationHandles returns a NotificationHandle for each credential returned from the last call to
+// RequestCredentialWithPreAuth or RequestCredentialWithAuth that the issuer wants to be notified about (i.e. that
+// the issuer provided a notification ID for). Use the CredentialID field to match them up with the credentials.
+// An empty slice is returned if the issuer doesn't have a notification endpoint.
+func (i *Interaction) NotificationHandles() []*NotificationHandle {
+	return i.notificationHandles
+}
+
+// SendNotification notifies the issuer about what happened to the credential that the given NotificationHandle is
+// bound to. The event must be one of the NotificationEvent constants. The event description is an optional
+// human-readable explanation of the event.
+func (i *Interaction) SendNotification(notificationHandle *NotificationHandle, event, eventDescription string,
+) error {
+	return i.SendNotificationContext(context.Background(), notificationHandle, event, eventDescription)
+}
+
+// SendNotificationContext is the same as SendNotification, except that the given context is used for the request
+// to the issuer.
+func (i *Interaction) SendNotificationContext(ctx context.Context, notificationHandle *NotificationHandle, event,
+	eventDescription string,
+) error {
+	return sendNotification(ctx, notificationHandle, event, eventDescription, i.httpClient, i.metricsLogger,
+		i.activityLogger)
+}
+
+// SendNotification notifies the issuer about what happened to the credential that the given NotificationHandle is
+// bound to, in the same way as Interaction.SendNotification. It can be used once the Interaction that created the
+// NotificationHandle is gone. The given ClientConfig is used in the same way as in NewInteraction.
+func SendNotification(notificationHandle *NotificationHandle, event, eventDescription string,
+	config *ClientConfig,
+) error {
+	return SendNotificationContext(context.Background(), notificationHandle, event, eventDescription, config)
+}
+
+// SendNotificationContext is the same as SendNotification, except that the given context is used for the request
+// to the issuer.
+func SendNotificationContext(ctx context.Context, notificationHandle *NotificationHandle, event,
+	eventDescription string, config *ClientConfig,
+) error {
+	err := validateRequiredParameters(config)
+	if err != nil {
+		return err
+	}
+
+	setDefaults(config)
+
+	return sendNotification(ctx, notificationHandle, event, eventDescription, newDPoPHTTPClient(config),
+		config.MetricsLogger, config.ActivityLogger)
+}
+
+func sendNotification(ctx context.Context, notificationHandle *NotificationHandle, event, eventDescription string,
+	httpClient *http.Client, metricsLogger api.MetricsLogger, activityLogger api.ActivityLogger,
+) error {
+	timeStartSendNotification := time.Now()
+
+	err := validateNotification(notificationHandle, event)
+	if err != nil {
+		return err
+	}
+
+	requestBytes, err := json.Marshal(&notificationRequest{
+		NotificationID:   notificationHandle.NotificationID,
+		Event:            event,
+		EventDescription: eventDescription,
+	})
+	if err != nil {
+		return err
+	}
+
+	// The notification endpoint responds with 204 No Content, but some issuers use 200 OK instead.
+	_, err = httprequest.New(httpClient, metricsLogger).DoWithHeadersAndExpectedStatusCodes(ctx,
+		http.MethodPost, notificationHandle.NotificationEndpoint, "application/json",
+		http.Header{"Authorization": {"Bearer " + notificationHandle.AccessToken}}, bytes.NewReader(requestBytes),
+		[]int{http.StatusNoContent, http.StatusOK},
+		fmt.Sprintf(sendNotificationViaPOSTReqEventText, notificationHandle.NotificationEndpoint),
+		sendNotificationEventText)
+	if err != nil {
+		return walleterror.NewExecutionError(
+			module,
+			NotificationFailedCode,
+			NotificationFailedError,
+			fmt.Errorf("issuer's notification endpoint: %w", err))
+	}
+
+	err = metricsLogger.Log(&api.MetricsEvent{
+		Event:    sendNotificationEventText,
+		Duration: time.Since(timeStartSendNotification),
+	})
+	if err != nil {
+		return err
+	}
+
+	return activityLogger.Log(&api.Activity{
+		ID:   uuid.New(),
+		Type: api.LogTypeCredentialActivity,
+		Time: time.Now(),
+		Data: api.Data{
+			Client:    notificationHandle.IssuerURI,
+			Operation: notificationActivityLogOperation,
+			Status:    api.ActivityLogStatusSuccess,
+			Params: map[string]interface{}{
+				"event":        event,
+				"credentialID": notificationHandle.CredentialID,
+			},
+		},
+	})
+}
+
+// createNotificationHandles creates a NotificationHandle for each issued credential that the issuer provided a
+// notification ID for. The given VCs must be in the same order as the credential responses, with nil in place of any
+// credentials that weren't issued (including deferred credentials, which have no VC yet). Those are skipped.
+func (i *Interaction) createNotificationHandles(credentialResponses []CredentialResponse,
+	vcs []*verifiable.Credential, accessToken string,
+) []*NotificationHandle {
+	if i.issuerMetadata.NotificationEndpoint == "" {
+		return nil
+	}
+
+	var notificationHandles []*NotificationHandle
+
+	for index, vc := range vcs {
+		if vc == nil || credentialResponses[index].NotificationID == "" {
+			continue
+		}
+
+		notificationHandles = append(notificationHandles, &NotificationHandle{
+			CredentialID:         vc.ID,
+			IssuerURI:            i.issuerURI,
+			NotificationEndpoint: i.issuerMetadata.NotificationEndpoint,
+			NotificationID:       credentialResponses[index].NotificationID,
+			AccessToken:          accessToken,
+		})
+	}
+
+	return notificationHandles
+}
+
+func validateNotification(notificationHandle *NotificationHandle, event string) error {
+	var err error
+
+	switch {
+	case notificationHandle == nil:
+		err = errors.New("no notification handle provided")
+	case notificationHandle.NotificationEndpoint == "":
+		err = errors.New("notification endpoint missing")
+	case notificationHandle.NotificationID == "":
+		err = errors.New("notification ID missing")
+	case notificationHandle.AccessToken == "":
+		err = errors.New("access token missing")
+	case event != NotificationEventCredentialAccepted && event != NotificationEventCredentialDeleted &&
+		event != NotificationEventCredentialFailure:
+		err = fmt.Errorf("unsupported notification event: %s", event)
+	}
+
+	if err != nil {
+		return walleterror.NewValidationError(
+			module,
+			InvalidNotificationCode,
+			InvalidNotificationError,
+			err)
+	}
+
+	return nil
+}
@@ -0,0 +1,268 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/wallet-sdk/internal/testutil"
+	"github.com/trustbloc/wallet-sdk/pkg/api"
+	"github.com/trustbloc/wallet-sdk/pkg/openid4ci"
+)
+
+type mockNotificationServerHandler struct {
+	t                       *testing.T
+	statusCode              int
+	receivedAuthorization   string
+	receivedNotificationReq map[string]interface{}
+}
+
+func (m *mockNotificationServerHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	require.Equal(m.t, "/notification", request.URL.Path)
+	require.Equal(m.t, http.MethodPost, request.Method)
+
+	m.receivedAuthorization = request.Header.Get("Authorization")
+
+	require.NoError(m.t, json.NewDecoder(request.Body).Decode(&m.receivedNotificationReq))
+
+	writer.WriteHeader(m.statusCode)
+}
+
+type recordingActivityLogger struct {
+	activities []*api.Activity
+}
+
+func (r *recordingActivityLogger) Log(activity *api.Activity) error {
+	r.activities = append(r.activities, activity)
+
+	return nil
+}
+
+func TestInteraction_NotificationHandles(t *testing.T) {
+	t.Run("Issuer provided a notification ID", func(t *testing.T) {
+		issuerServerHandler := &mockIssuerServerHandler{
+			t:                  t,
+			credentialResponse: createCredentialResponseWithNotificationID(t, "notificationID"),
+		}
+
+		server := httptest.NewServer(issuerServerHandler)
+		defer server.Close()
+
+		issuerServerHandler.openIDConfig = &openid4ci.OpenIDConfig{
+			TokenEndpoint: fmt.Sprintf("%s/oidc/token", server.URL),
+		}
+
+		issuerServerHandler.issuerMetadata = fmt.Sprintf(`{"credential_endpoint":"%[1]s/credential",`+
+			`"notification_endpoint":"%[1]s/notification"}`, server.URL)
+
+		interaction := newInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, false))
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{
+			keyID: mockKeyID,
+		}, openid4ci.WithPIN("1234"))
+		require.NoError(t, err)
+		require.Len(t, credentials, 1)
+
+		notificationHandles := interaction.NotificationHandles()
+		require.Len(t, notificationHandles, 1)
+		require.Equal(t, &openid4ci.NotificationHandle{
+			CredentialID:         credentials[0].ID,
+			IssuerURI:            server.URL,
+			NotificationEndpoint: server.URL + "/notification",
+			NotificationID:       "notificationID",
+			AccessToken:          "eyJhbGciOiJSUzI1NiIsInR5cCI6Ikp..sHQ",
+		}, notificationHandles[0])
+	})
+	t.Run("Issuer has no notification endpoint", func(t *testing.T) {
+		issuerServerHandler := &mockIssuerServerHandler{
+			t:                  t,
+			credentialResponse: createCredentialResponseWithNotificationID(t, "notificationID"),
+		}
+
+		server := httptest.NewServer(issuerServerHandler)
+		defer server.Close()
+
+		issuerServerHandler.openIDConfig = &openid4ci.OpenIDConfig{
+			TokenEndpoint: fmt.Sprintf("%s/oidc/token", server.URL),
+		}
+
+		issuerServerHandler.issuerMetadata = fmt.Sprintf(`{"credential_endpoint":"%s/credential"}`, server.URL)
+
+		interaction := newInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, false))
+
+		_, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{
+			keyID: mockKeyID,
+		}, openid4ci.WithPIN("1234"))
+		require.NoError(t, err)
+		require.Empty(t, interaction.NotificationHandles())
+	})
+}
+
+func TestInteraction_SendNotification(t *testing.T) {
+	handler := &mockNotificationServerHandler{t: t, statusCode: http.StatusNoContent}
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	interaction := newInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, false))
+
+	err := interaction.SendNotification(createTestNotificationHandle(server.URL),
+		openid4ci.NotificationEventCredentialDeleted, "")
+	require.NoError(t, err)
+
+	require.Equal(t, "Bearer accessToken", handler.receivedAuthorization)
+	require.Equal(t, map[string]interface{}{
+		"notification_id": "notificationID",
+		"event":           "credential_deleted",
+	}, handler.receivedNotificationReq)
+}
+
+func TestSendNotification(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		testCases := []struct {
+			name       string
+			statusCode int
+		}{
+			{name: "Issuer responds with 204 No Content", statusCode: http.StatusNoContent},
+			{name: "Issuer responds with 200 OK", statusCode: http.StatusOK},
+		}
+
+		for _, testCase := range testCases {
+			t.Run(testCase.name, func(t *testing.T) {
+				handler := &mockNotificationServerHandler{t: t, statusCode: testCase.statusCode}
+
+				server := httptest.NewServer(handler)
+				defer server.Close()
+
+				activityLogger := &recordingActivityLogger{}
+
+				config := getTestClientConfig(t)
+				config.ActivityLogger = activityLogger
+
+				err := openid4ci.SendNotification(createTestNotificationHandle(server.URL),
+					openid4ci.NotificationEventCredentialAccepted, "stored in the wallet", config)
+				require.NoError(t, err)
+
+				require.Equal(t, "Bearer accessToken", handler.receivedAuthorization)
+				require.Equal(t, map[string]interface{}{
+					"notification_id":   "notificationID",
+					"event":             "credential_accepted",
+					"event_description": "stored in the wallet",
+				}, handler.receivedNotificationReq)
+
+				require.Len(t, activityLogger.activities, 1)
+				require.Equal(t, server.URL, activityLogger.activities[0].Data.Client)
+				require.Equal(t, api.Params{
+					"event":        "credential_accepted",
+					"credentialID": sampleCredentialID,
+				}, activityLogger.activities[0].Data.Params)
+			})
+		}
+	})
+	t.Run("Missing client config", func(t *testing.T) {
+		err := openid4ci.SendNotification(createTestNotificationHandle("example.com"),
+			openid4ci.NotificationEventCredentialAccepted, "", nil)
+		require.EqualError(t, err, "NO_CLIENT_CONFIG_PROVIDED(OCI0-0000):no client config provided")
+	})
+	t.Run("Invalid notification", func(t *testing.T) {
+		testCases := []struct {
+			name               string
+			notificationHandle *openid4ci.NotificationHandle
+			event              string
+			expectedError      string
+		}{
+			{
+				name:          "Missing notification handle",
+				event:         openid4ci.NotificationEventCredentialAccepted,
+				expectedError: "no notification handle provided",
+			},
+			{
+				name: "Missing notification endpoint",
+				notificationHandle: &openid4ci.NotificationHandle{
+					NotificationID: "notificationID",
+					AccessToken:    "accessToken",
+				},
+				event:         openid4ci.NotificationEventCredentialAccepted,
+				expectedError: "notification endpoint missing",
+			},
+			{
+				name: "Missing notification ID",
+				notificationHandle: &openid4ci.NotificationHandle{
+					NotificationEndpoint: "example.com/notification",
+					AccessToken:          "accessToken",
+				},
+				event:         openid4ci.NotificationEventCredentialAccepted,
+				expectedError: "notification ID missing",
+			},
+			{
+				name: "Missing access token",
+				notificationHandle: &openid4ci.NotificationHandle{
+					NotificationEndpoint: "example.com/notification",
+					NotificationID:       "notificationID",
+				},
+				event:         openid4ci.NotificationEventCredentialAccepted,
+				expectedError: "access token missing",
+			},
+			{
+				name:               "Unsupported event",
+				notificationHandle: createTestNotificationHandle("example.com"),
+				event:              "credential_lost",
+				expectedError:      "unsupported notification event: credential_lost",
+			},
+		}
+
+		for _, testCase := range testCases {
+			t.Run(testCase.name, func(t *testing.T) {
+				err := openid4ci.SendNotification(testCase.notificationHandle, testCase.event, "",
+					getTestClientConfig(t))
+				testutil.RequireErrorContains(t, err, "INVALID_NOTIFICATION(OCI0-0029):"+testCase.expectedError)
+			})
+		}
+	})
+	t.Run("Issuer responds with an error", func(t *testing.T) {
+		handler := &mockNotificationServerHandler{t: t, statusCode: http.StatusBadRequest}
+
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		err := openid4ci.SendNotification(createTestNotificationHandle(server.URL),
+			openid4ci.NotificationEventCredentialFailure, "", getTestClientConfig(t))
+		testutil.RequireErrorContains(t, err, "NOTIFICATION_FAILED(OCI1-0030):issuer's notification endpoint: "+
+			"expected status code 204 or 200")
+	})
+}
+
+func createTestNotificationHandle(serverURL string) *openid4ci.NotificationHandle {
+	return &openid4ci.NotificationHandle{
+		CredentialID:         sampleCredentialID,
+		IssuerURI:            serverURL,
+		NotificationEndpoint: serverURL + "/notification",
+		NotificationID:       "notificationID",
+		AccessToken:          "accessToken",
+	}
+}
+
+func createCredentialResponseWithNotificationID(t *testing.T, notificationID string) []byte {
+	t.Helper()
+
+	var credentialResponse map[string]interface{}
+
+	require.NoError(t, json.Unmarshal(sampleCredentialResponse, &credentialResponse))
+
+	credentialResponse["notification_id"] = notificationID
+
+	credentialResponseBytes, err := json.Marshal(credentialResponse)
+	require.NoError(t, err)
+
+	return credentialResponseBytes
+}
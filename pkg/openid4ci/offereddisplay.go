@@ -0,0 +1,54 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci
+
+import (
+	"context"
+
+	"github.com/trustbloc/wallet-sdk/pkg/credentialschema"
+	"github.com/trustbloc/wallet-sdk/pkg/models/issuer"
+)
+
+// OfferedCredentialsDisplay returns display data for the issuer and the offered credentials, based on the issuer's
+// metadata. It can be used to show the user what they're about to receive before any credentials are requested.
+// The CredentialDisplays in the returned object are in the same order as the credentials in the credential offer.
+// Since the credentials haven't been issued yet, the claims only contain labels, without values.
+// If the issuer's metadata doesn't describe an offered credential, then a generic display is returned for it.
+// The issuer's metadata is fetched if needed.
+func (i *Interaction) OfferedCredentialsDisplay(preferredLocale string) (*credentialschema.ResolvedDisplayData,
+	error,
+) {
+	return i.OfferedCredentialsDisplayContext(context.Background(), preferredLocale)
+}
+
+// OfferedCredentialsDisplayContext is the same as OfferedCredentialsDisplay, except that the given context is used
+// for fetching the issuer's metadata.
+func (i *Interaction) OfferedCredentialsDisplayContext(ctx context.Context, preferredLocale string,
+) (*credentialschema.ResolvedDisplayData, error) {
+	err := i.fetchIssuerMetadataIfNeeded(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	offeredCredentials := make([]issuer.SupportedCredential, len(i.credentialTypes))
+
+	for index := range i.credentialTypes {
+		supportedCredential := i.supportedCredential(index)
+		if supportedCredential == nil {
+			offeredCredentials[index] = issuer.SupportedCredential{
+				Format: i.credentialFormats[index],
+				Types:  i.credentialTypes[index],
+			}
+
+			continue
+		}
+
+		offeredCredentials[index] = *supportedCredential
+	}
+
+	return credentialschema.ResolveOffered(i.issuerMetadata, offeredCredentials, preferredLocale), nil
+}
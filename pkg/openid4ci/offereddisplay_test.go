@@ -0,0 +1,94 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/wallet-sdk/internal/testutil"
+	"github.com/trustbloc/wallet-sdk/pkg/credentialschema"
+)
+
+func TestInteraction_OfferedCredentialsDisplay(t *testing.T) {
+	t.Run("Issuer metadata describes the offered credential", func(t *testing.T) {
+		server := newProofRequirementsTestServer(t, `{"credential_endpoint":"%[1]s/credential",`+
+			`"display":[{"name":"Example Issuer","locale":"en-US"},{"name":"Exemple","locale":"fr-FR"}],`+
+			`"credentials_supported":[{"format":"jwt_vc_json","types":["VerifiableCredential","VerifiedEmployee"],`+
+			`"display":[{"name":"Verified Employee","locale":"en-US","background_color":"#12107c",`+
+			`"text_color":"#FFFFFF","logo":{"url":"https://example.com/logo.png","alt_text":"logo"}},`+
+			`{"name":"Employé vérifié","locale":"fr-FR"}],`+
+			`"credentialSubject":{"displayName":{"display":[{"name":"Employee","locale":"en-US"},`+
+			`{"name":"Employé","locale":"fr-FR"}],"value_type":"string"},"photo":{"value_type":"image"}}}]}`)
+		defer server.Close()
+
+		interaction := newInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, false))
+
+		displayData, err := interaction.OfferedCredentialsDisplay("")
+		require.NoError(t, err)
+		require.Equal(t, &credentialschema.ResolvedDisplayData{
+			IssuerDisplay: &credentialschema.ResolvedIssuerDisplay{Name: "Example Issuer", Locale: "en-US"},
+			CredentialDisplays: []credentialschema.CredentialDisplay{
+				{
+					Overview: &credentialschema.CredentialOverview{
+						Name:            "Verified Employee",
+						Locale:          "en-US",
+						Logo:            &credentialschema.Logo{URL: "https://example.com/logo.png", AltText: "logo"},
+						BackgroundColor: "#12107c",
+						TextColor:       "#FFFFFF",
+					},
+					Claims: []credentialschema.ResolvedClaim{
+						{RawID: "displayName", Label: "Employee", ValueType: "string", Locale: "en-US"},
+					},
+				},
+			},
+		}, displayData)
+
+		displayData, err = interaction.OfferedCredentialsDisplay("fr-FR")
+		require.NoError(t, err)
+		require.Equal(t, "Exemple", displayData.IssuerDisplay.Name)
+		require.Equal(t, "Employé vérifié", displayData.CredentialDisplays[0].Overview.Name)
+		require.Equal(t, "Employé", displayData.CredentialDisplays[0].Claims[0].Label)
+	})
+	t.Run("Issuer metadata doesn't describe the offered credential", func(t *testing.T) {
+		server := newProofRequirementsTestServer(t, `{"credential_endpoint":"%[1]s/credential",`+
+			`"credentials_supported":[{"format":"ldp_vc","types":["VerifiableCredential","VerifiedEmployee"],`+
+			`"display":[{"name":"Verified Employee"}]}]}`)
+		defer server.Close()
+
+		interaction := newInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, false))
+
+		displayData, err := interaction.OfferedCredentialsDisplay("")
+		require.NoError(t, err)
+		require.Nil(t, displayData.IssuerDisplay)
+		require.Equal(t, []credentialschema.CredentialDisplay{
+			{Overview: &credentialschema.CredentialOverview{Name: "VerifiedEmployee"}},
+		}, displayData.CredentialDisplays)
+	})
+	t.Run("Draft 13 issuer metadata", func(t *testing.T) {
+		server := newProofRequirementsTestServer(t, `{"credential_issuer":"%[1]s",`+
+			`"credential_endpoint":"%[1]s/credential","credential_configurations_supported":`+
+			`{"VerifiedEmployee_JWT":{"format":"jwt_vc_json","display":[{"name":"Verified Employee"}],`+
+			`"credential_definition":{"type":["VerifiableCredential","VerifiedEmployee"]}}}}`)
+		defer server.Close()
+
+		interaction := newInteraction(t, createDraft13CredentialOfferIssuanceURI(t, server.URL, nil))
+
+		displayData, err := interaction.OfferedCredentialsDisplay("")
+		require.NoError(t, err)
+		require.Len(t, displayData.CredentialDisplays, 1)
+		require.Equal(t, "Verified Employee", displayData.CredentialDisplays[0].Overview.Name)
+	})
+	t.Run("Fail to fetch issuer metadata", func(t *testing.T) {
+		interaction := newInteraction(t, createCredentialOfferIssuanceURI(t, "example.com", false))
+
+		displayData, err := interaction.OfferedCredentialsDisplay("")
+		testutil.RequireErrorContains(t, err, "METADATA_FETCH_FAILED")
+		require.Nil(t, displayData)
+	})
+}
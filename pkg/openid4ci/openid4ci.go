@@ -31,6 +31,7 @@ import (
 	"golang.org/x/oauth2"
 
 	"github.com/trustbloc/wallet-sdk/pkg/api"
+	"github.com/trustbloc/wallet-sdk/pkg/internal/contextbound"
 	"github.com/trustbloc/wallet-sdk/pkg/internal/httprequest"
 	metadatafetcher "github.com/trustbloc/wallet-sdk/pkg/internal/issuermetadata"
 	"github.com/trustbloc/wallet-sdk/pkg/models/issuer"
@@ -53,8 +54,10 @@ const (
 	//nolint:gosec //false positive
 	fetchTokenViaPOSTReqEventText = "Fetch token via an HTTP POST request to %s"
 	//nolint:gosec //false positive
-	fetchCredentialViaGETReqEventText  = "Fetch credential %d of %d via an HTTP POST request to %s"
-	parseAndCheckProofCheckVCEventText = "Parsing and checking proof for received credential %d of %d"
+	fetchCredentialViaGETReqEventText = "Fetch credential %d of %d via an HTTP POST request to %s"
+	//nolint:gosec //false positive
+	fetchCredentialsViaBatchPOSTReqEventText = "Fetch %d credentials via an HTTP POST request to %s"
+	parseAndCheckProofCheckVCEventText       = "Parsing and checking proof for received credential %d of %d"
 
 	preAuthorizedGrantType     = "urn:ietf:params:oauth:grant-type:pre-authorized_code"
 	authorizationCodeGrantType = "authorization_code"
@@ -80,14 +83,33 @@ type Interaction struct {
 	openIDConfig                 *OpenIDConfig
 	oAuth2Config                 *oauth2.Config
 	authTokenResponse            *oauth2.Token
+	preAuthTokenResponse         *preAuthTokenResponse
 	httpClient                   *http.Client
 	authCodeURLState             string
 	codeVerifier                 string
+	credentialConfigurationIDs   []string
+	credentialIdentifiers        map[string][]string
+	deferredCredentials          []*DeferredCredential
+	reissuanceTokens             []*ReissuanceToken
+	notificationHandles          []*NotificationHandle
+	credentialIssuerIDs          []string
+	interactionStateKey          []byte
+	interactionStateLifetime     time.Duration
+	clientAuthentication         *ClientAuthentication
+	dpop                         *DPoPConfig
 }
 
 // NewInteraction creates a new OpenID4CI Interaction.
 // If no ActivityLogger is provided (via the ClientConfig object), then no activity logging will take place.
 func NewInteraction(initiateIssuanceURI string, config *ClientConfig) (*Interaction, error) {
+	return NewInteractionContext(context.Background(), initiateIssuanceURI, config)
+}
+
+// NewInteractionContext is the same as NewInteraction, except that the given context is used for any requests made
+// while creating the Interaction. The context isn't kept by the Interaction. To make later calls cancellable too,
+// use the Context variants of the Interaction's methods.
+func NewInteractionContext(ctx context.Context, initiateIssuanceURI string, config *ClientConfig,
+) (*Interaction, error) {
 	timeStartNewInteraction := time.Now()
 
 	err := validateRequiredParameters(config)
@@ -97,39 +119,58 @@ func NewInteraction(initiateIssuanceURI string, config *ClientConfig) (*Interact
 
 	setDefaults(config)
 
-	credentialOffer, err := getCredentialOffer(initiateIssuanceURI, config.HTTPClient, config.MetricsLogger)
+	clientAuthentication, err := clientAuthenticationFromConfig(config)
 	if err != nil {
 		return nil, err
 	}
 
-	// TODO Add support for determining grant types when no grants are specified.
-	// See https://openid.net/specs/openid-4-verifiable-credential-issuance-1_0-11.html#section-4.1.1 for more info.
-	preAuthorizedCodeGrantParams, authorizationCodeGrantParams, err := determineIssuerGrantCapabilities(credentialOffer)
+	credentialOffer, err := getCredentialOffer(ctx, initiateIssuanceURI, config.HTTPClient, config.MetricsLogger)
 	if err != nil {
 		return nil, err
 	}
 
-	credentialTypes, credentialFormats, err := determineCredentialTypesAndFormats(credentialOffer)
+	interaction := &Interaction{
+		issuerURI:                credentialOffer.CredentialIssuer,
+		didResolver:              &didResolverWrapper{didResolver: config.DIDResolver},
+		activityLogger:           config.ActivityLogger,
+		metricsLogger:            config.MetricsLogger,
+		disableVCProofChecks:     config.DisableVCProofChecks,
+		documentLoader:           config.DocumentLoader,
+		httpClient:               newDPoPHTTPClient(config),
+		interactionStateKey:      config.InteractionStateKey,
+		interactionStateLifetime: *config.InteractionStateLifetime,
+		clientAuthentication:     clientAuthentication,
+		dpop:                     config.DPoP,
+	}
+
+	// Credential offers that follow draft 13 (or later) refer to credential configurations in the issuer's metadata
+	// instead of specifying the credential formats and types directly.
+	if len(credentialOffer.CredentialConfigurationIDs) > 0 {
+		err = interaction.resolveCredentialConfigurationIDs(ctx, credentialOffer.CredentialConfigurationIDs)
+	} else {
+		interaction.credentialTypes, interaction.credentialFormats, err =
+			determineCredentialTypesAndFormats(credentialOffer)
+	}
+
 	if err != nil {
 		return nil, err
 	}
 
-	return &Interaction{
-			preAuthorizedCodeGrantParams: preAuthorizedCodeGrantParams,
-			authorizationCodeGrantParams: authorizationCodeGrantParams,
-			issuerURI:                    credentialOffer.CredentialIssuer,
-			credentialTypes:              credentialTypes,
-			credentialFormats:            credentialFormats,
-			didResolver:                  &didResolverWrapper{didResolver: config.DIDResolver},
-			activityLogger:               config.ActivityLogger,
-			metricsLogger:                config.MetricsLogger,
-			disableVCProofChecks:         config.DisableVCProofChecks,
-			documentLoader:               config.DocumentLoader,
-			httpClient:                   config.HTTPClient,
-		}, config.MetricsLogger.Log(&api.MetricsEvent{
-			Event:    newInteractionEventText,
-			Duration: time.Since(timeStartNewInteraction),
-		})
+	if len(credentialOffer.Grants) == 0 {
+		err = interaction.determineGrantCapabilitiesFromMetadata(ctx)
+	} else {
+		interaction.preAuthorizedCodeGrantParams, interaction.authorizationCodeGrantParams, err =
+			determineIssuerGrantCapabilities(credentialOffer)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return interaction, config.MetricsLogger.Log(&api.MetricsEvent{
+		Event:    newInteractionEventText,
+		Duration: time.Since(timeStartNewInteraction),
+	})
 }
 
 // CreateAuthorizationURL creates an authorization URL that can be opened in a browser to proceed to the login page.
@@ -138,8 +179,19 @@ func NewInteraction(initiateIssuanceURI string, config *ClientConfig) (*Interact
 // This method can only be used if the issuer supports authorization code grants.
 // Check the issuer's capabilities first using the Capabilities method.
 // If scopes are needed, pass them in using the WithScopes option.
+// If the authorization server supports pushed authorization requests (or the WithPushedAuthorizationRequest option
+// is used), then the authorization request parameters are sent directly to the authorization server, and the
+// returned URL only contains the client ID and the request URI received from the authorization server.
 func (i *Interaction) CreateAuthorizationURL(clientID, redirectURI string,
 	opts ...CreateAuthorizationURLOpt,
+) (string, error) {
+	return i.CreateAuthorizationURLContext(context.Background(), clientID, redirectURI, opts...)
+}
+
+// CreateAuthorizationURLContext is the same as CreateAuthorizationURL, except that the given context is used for
+// fetching metadata and sending the pushed authorization request (if one is used).
+func (i *Interaction) CreateAuthorizationURLContext(ctx context.Context, clientID, redirectURI string,
+	opts ...CreateAuthorizationURLOpt,
 ) (string, error) {
 	if !i.AuthorizationCodeGrantTypeSupported() {
 		return "", errors.New("issuer does not support the authorization code grant type")
@@ -149,8 +201,8 @@ func (i *Interaction) CreateAuthorizationURL(clientID, redirectURI string,
 
 	var err error
 
-	i.issuerMetadata, err = metadatafetcher.Get(i.issuerURI, i.httpClient, i.metricsLogger,
-		"Authorization")
+	i.issuerMetadata, err = metadatafetcher.Get(ctx, i.issuerURI, i.httpClient, i.metricsLogger,
+		authorizationEventText)
 	if err != nil {
 		return "", walleterror.NewExecutionError(
 			module,
@@ -159,6 +211,13 @@ func (i *Interaction) CreateAuthorizationURL(clientID, redirectURI string,
 			fmt.Errorf("failed to get issuer metadata: %w", err))
 	}
 
+	// The authorization server's metadata isn't strictly needed until the token request, so failing to fetch it here
+	// is only treated as an error if a pushed authorization request is required.
+	openIDConfig, openIDConfigErr := i.getOpenIDConfig(ctx)
+	if openIDConfigErr == nil {
+		i.openIDConfig = openIDConfig
+	}
+
 	i.instantiateOAuth2Config(clientID, redirectURI, processedOpts.scopes)
 
 	err = i.instantiateCodeVerifier()
@@ -177,7 +236,19 @@ func (i *Interaction) CreateAuthorizationURL(clientID, redirectURI string,
 
 	i.clientID = clientID
 
-	return i.oAuth2Config.AuthCodeURL(i.authCodeURLState, authCodeOptions...), nil
+	authURL := i.oAuth2Config.AuthCodeURL(i.authCodeURLState, authCodeOptions...)
+
+	pushedAuthorizationRequestEndpoint, err := i.pushedAuthorizationRequestEndpoint(
+		processedOpts.usePushedAuthorizationRequest, openIDConfigErr)
+	if err != nil {
+		return "", err
+	}
+
+	if pushedAuthorizationRequestEndpoint == "" {
+		return authURL, nil
+	}
+
+	return i.pushAuthorizationRequest(ctx, pushedAuthorizationRequestEndpoint, authURL)
 }
 
 // RequestCredentialWithPreAuth requests credential(s) from the issuer. This method can only be used for the
@@ -185,11 +256,23 @@ func (i *Interaction) CreateAuthorizationURL(clientID, redirectURI string,
 // For the equivalent method for the authorization code flow, see RequestCredentialWithAuth instead.
 // If a PIN is required (which can be checked via the Capabilities method), then it must be passed
 // into this method via the WithPIN option.
+// If the issuer deferred any of the credentials, then they won't be in the returned slice. Use the
+// DeferredCredentials method afterwards to get the handles needed to retrieve them later.
+// If some, but not all, of the offered credentials can't be issued, then the ones that were issued are returned
+// along with a *PartialIssuanceError that reports the error for each of the others.
 func (i *Interaction) RequestCredentialWithPreAuth(jwtSigner api.JWTSigner, opts ...RequestCredentialWithPreAuthOpt,
+) ([]*verifiable.Credential, error) {
+	return i.RequestCredentialWithPreAuthContext(context.Background(), jwtSigner, opts...)
+}
+
+// RequestCredentialWithPreAuthContext is the same as RequestCredentialWithPreAuth, except that the given context is
+// used for all requests made to the issuer and for resolving DIDs while verifying the received credentials.
+func (i *Interaction) RequestCredentialWithPreAuthContext(ctx context.Context, jwtSigner api.JWTSigner,
+	opts ...RequestCredentialWithPreAuthOpt,
 ) ([]*verifiable.Credential, error) {
 	processedOpts := processRequestCredentialWithPreAuthOpts(opts)
 
-	return i.requestCredential(jwtSigner, processedOpts.pin)
+	return i.requestCredential(ctx, jwtSigner, processedOpts.pin)
 }
 
 // RequestCredentialWithAuth requests credential(s) from the issuer. This method can only be used for the
@@ -199,14 +282,42 @@ func (i *Interaction) RequestCredentialWithPreAuth(jwtSigner api.JWTSigner, opts
 // RequestCredentialWithAuth should be called only once all authorization pre-requisite steps have been completed.
 // The redirect URI that you pass in here should look like the redirect URI that you passed in to the
 // CreateAuthorizationURL, except that now it has some URL query parameters appended to it.
+// If the issuer deferred any of the credentials, then they won't be in the returned slice. Use the
+// DeferredCredentials method afterwards to get the handles needed to retrieve them later.
+// If some, but not all, of the offered credentials can't be issued, then the ones that were issued are returned
+// along with a *PartialIssuanceError that reports the error for each of the others.
 func (i *Interaction) RequestCredentialWithAuth(jwtSigner api.JWTSigner, redirectURIWithParams string,
 ) ([]*verifiable.Credential, error) {
-	err := i.requestAccessToken(redirectURIWithParams)
+	return i.RequestCredentialWithAuthContext(context.Background(), jwtSigner, redirectURIWithParams)
+}
+
+// RequestCredentialWithAuthContext is the same as RequestCredentialWithAuth, except that the given context is used
+// for all requests made to the issuer and for resolving DIDs while verifying the received credentials.
+func (i *Interaction) RequestCredentialWithAuthContext(ctx context.Context, jwtSigner api.JWTSigner,
+	redirectURIWithParams string,
+) ([]*verifiable.Credential, error) {
+	err := i.requestAccessToken(ctx, redirectURIWithParams)
 	if err != nil {
 		return nil, err
 	}
 
-	return i.requestCredential(jwtSigner, "")
+	return i.requestCredential(ctx, jwtSigner, "")
+}
+
+// DeferredCredentials returns handles for the credentials that the issuer deferred during the last call to
+// RequestCredentialWithPreAuth or RequestCredentialWithAuth. Each handle can be persisted (e.g. by serializing it
+// to JSON) and passed in to RequestDeferredCredential later on to retrieve the credential once it's ready.
+// If no credentials were deferred, then an empty slice is returned.
+func (i *Interaction) DeferredCredentials() []*DeferredCredential {
+	return i.deferredCredentials
+}
+
+// ReissuanceTokens returns a ReissuanceToken for each credential that was returned from the last call to
+// RequestCredentialWithPreAuth or RequestCredentialWithAuth, in the same order. They can be persisted alongside the
+// credentials and later passed in to ReissueCredential to get a new copy of a credential without a new offer.
+// An empty slice is returned if the issuer didn't provide a refresh token.
+func (i *Interaction) ReissuanceTokens() []*ReissuanceToken {
+	return i.reissuanceTokens
 }
 
 // IssuerURI returns the issuer's URI from the initiation request. It's useful to store this somewhere in case
@@ -251,9 +362,15 @@ func (i *Interaction) AuthorizationCodeGrantParams() (*AuthorizationCodeGrantPar
 
 // DynamicClientRegistrationSupported indicates whether the issuer supports dynamic client registration.
 func (i *Interaction) DynamicClientRegistrationSupported() (bool, error) {
+	return i.DynamicClientRegistrationSupportedContext(context.Background())
+}
+
+// DynamicClientRegistrationSupportedContext is the same as DynamicClientRegistrationSupported, except that the given
+// context is used for fetching the issuer's OpenID configuration.
+func (i *Interaction) DynamicClientRegistrationSupportedContext(ctx context.Context) (bool, error) {
 	var err error
 
-	i.openIDConfig, err = i.getOpenIDConfig()
+	i.openIDConfig, err = i.getOpenIDConfig(ctx)
 	if err != nil {
 		return false, walleterror.NewExecutionError(
 			module,
@@ -270,9 +387,15 @@ func (i *Interaction) DynamicClientRegistrationSupported() (bool, error) {
 // if DynamicClientRegistrationSupported returns true.
 // This method will return an error if the issuer does not support dynamic client registration.
 func (i *Interaction) DynamicClientRegistrationEndpoint() (string, error) {
+	return i.DynamicClientRegistrationEndpointContext(context.Background())
+}
+
+// DynamicClientRegistrationEndpointContext is the same as DynamicClientRegistrationEndpoint, except that the given
+// context is used for fetching the issuer's OpenID configuration.
+func (i *Interaction) DynamicClientRegistrationEndpointContext(ctx context.Context) (string, error) {
 	var err error
 
-	i.openIDConfig, err = i.getOpenIDConfig()
+	i.openIDConfig, err = i.getOpenIDConfig(ctx)
 	if err != nil {
 		return "", walleterror.NewExecutionError(
 			module,
@@ -288,7 +411,7 @@ func (i *Interaction) DynamicClientRegistrationEndpoint() (string, error) {
 	return *i.openIDConfig.RegistrationEndpoint, nil
 }
 
-func (i *Interaction) requestAccessToken(redirectURIWithAuthCode string) error {
+func (i *Interaction) requestAccessToken(ctx context.Context, redirectURIWithAuthCode string) error {
 	if i.oAuth2Config == nil {
 		return errors.New("authorization URL must be created first")
 	}
@@ -317,7 +440,7 @@ func (i *Interaction) requestAccessToken(redirectURIWithAuthCode string) error {
 			errors.New("state in redirect URI does not match the state from the authorization URL"))
 	}
 
-	i.openIDConfig, err = i.getOpenIDConfig()
+	i.openIDConfig, err = i.getOpenIDConfig(ctx)
 	if err != nil {
 		return walleterror.NewExecutionError(
 			module,
@@ -326,21 +449,45 @@ func (i *Interaction) requestAccessToken(redirectURIWithAuthCode string) error {
 			fmt.Errorf("failed to fetch issuer's OpenID configuration: %w", err))
 	}
 
+	err = checkDPoPSupported(i.dpop, i.openIDConfig)
+	if err != nil {
+		return err
+	}
+
 	i.oAuth2Config.Endpoint.TokenURL = i.openIDConfig.TokenEndpoint
 
-	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, i.httpClient)
+	exchangeOptions := []oauth2.AuthCodeOption{oauth2.SetAuthURLParam("code_verifier", i.codeVerifier)}
+
+	if i.clientAuthentication != nil {
+		clientAuthOptions, errClientAuth := i.clientAuthentication.configureOAuth2Exchange(i.oAuth2Config,
+			i.openIDConfig)
+		if errClientAuth != nil {
+			return errClientAuth
+		}
+
+		exchangeOptions = append(exchangeOptions, clientAuthOptions...)
+	}
+
+	oAuth2Ctx := context.WithValue(ctx, oauth2.HTTPClient, i.httpClient)
 
-	i.authTokenResponse, err = i.oAuth2Config.Exchange(ctx, parsedURI.Query().Get("code"),
-		oauth2.SetAuthURLParam("code_verifier", i.codeVerifier))
+	i.authTokenResponse, err = i.oAuth2Config.Exchange(oAuth2Ctx, parsedURI.Query().Get("code"), exchangeOptions...)
 
 	return err
 }
 
 func (i *Interaction) instantiateOAuth2Config(clientID, redirectURI string, scopes []string) {
+	// Older issuers put the authorization endpoint itself in the authorization_server field of their metadata,
+	// so that's used if the authorization server's own metadata doesn't specify one.
+	authURL := i.issuerMetadata.AuthorizationServer
+
+	if i.openIDConfig != nil && i.openIDConfig.AuthorizationEndpoint != "" {
+		authURL = i.openIDConfig.AuthorizationEndpoint
+	}
+
 	i.oAuth2Config = &oauth2.Config{
 		ClientID: clientID,
 		Endpoint: oauth2.Endpoint{
-			AuthURL:   i.issuerMetadata.AuthorizationServer,
+			AuthURL:   authURL,
 			AuthStyle: oauth2.AuthStyleInHeader,
 		},
 		RedirectURL: redirectURI,
@@ -373,20 +520,28 @@ func (i *Interaction) generateCodeChallenge() string {
 	return codeChallenge
 }
 
+// generateAuthorizationDetails creates an authorization_details array with one entry for each credential
+// in the offer, so that authorization for all of them can be obtained at once.
 func (i *Interaction) generateAuthorizationDetails() ([]byte, error) {
-	// TODO: Add support for requesting multiple credentials at once (by sending an array).
-	// Currently we always use the first credential type specified in the offer.
-	authorizationDetails := &authorizationDetails{
-		Type:   "openid_credential",
-		Types:  i.credentialTypes[0],
-		Format: i.credentialFormats[0],
-	}
+	allAuthorizationDetails := make([]authorizationDetails, len(i.credentialTypes))
 
-	if i.issuerMetadata.AuthorizationServer != "" {
-		authorizationDetails.Locations = []string{i.issuerMetadata.CredentialIssuer}
+	for index := range i.credentialTypes {
+		allAuthorizationDetails[index] = authorizationDetails{Type: "openid_credential"}
+
+		// Draft 13 (and later) identifies credentials using credential configuration IDs.
+		if credentialConfigurationID := i.credentialConfigurationID(index); credentialConfigurationID != "" {
+			allAuthorizationDetails[index].CredentialConfigurationID = credentialConfigurationID
+		} else {
+			allAuthorizationDetails[index].Types = i.credentialTypes[index]
+			allAuthorizationDetails[index].Format = i.credentialFormats[index]
+		}
+
+		if i.issuerMetadata.AuthorizationServer != "" {
+			allAuthorizationDetails[index].Locations = []string{i.issuerMetadata.CredentialIssuer}
+		}
 	}
 
-	authorizationDetailsBytes, err := json.Marshal(authorizationDetails)
+	authorizationDetailsBytes, err := json.Marshal(allAuthorizationDetails)
 	if err != nil {
 		return nil, err
 	}
@@ -409,7 +564,7 @@ func (i *Interaction) generateAuthCodeOptions(authorizationDetails []byte) []oau
 	return authCodeOptions
 }
 
-func (i *Interaction) requestCredential(jwtSigner api.JWTSigner, //nolint:funlen
+func (i *Interaction) requestCredential(ctx context.Context, jwtSigner api.JWTSigner, //nolint:funlen
 	pin string,
 ) ([]*verifiable.Credential, error) {
 	timeStartRequestCredential := time.Now()
@@ -424,24 +579,26 @@ func (i *Interaction) requestCredential(jwtSigner api.JWTSigner, //nolint:funlen
 		return nil, err
 	}
 
+	err = i.validateSignerMeetsProofRequirements(ctx, jwtSigner)
+	if err != nil {
+		return nil, err
+	}
+
 	var credentialResponses []CredentialResponse
 
+	var fetchErrs map[int]error
+
 	if grantType == preAuthorizedGrantType {
-		credentialResponses, err = i.getCredentialResponsesUsingPreAuth(pin, jwtSigner)
+		credentialResponses, fetchErrs, err = i.getCredentialResponsesUsingPreAuth(ctx, pin, jwtSigner)
 	} else {
-		credentialResponses, err = i.getCredentialResponsesUsingAuth(jwtSigner)
+		credentialResponses, fetchErrs, err = i.getCredentialResponsesUsingAuth(ctx, jwtSigner)
 	}
 
 	if err != nil {
-		return nil,
-			walleterror.NewExecutionError(
-				module,
-				CredentialFetchFailedCode,
-				CredentialFetchFailedError,
-				fmt.Errorf("failed to get credential response: %w", err))
+		return nil, newCredentialFetchError(err)
 	}
 
-	vcs, err := i.getVCsFromCredentialResponses(credentialResponses)
+	offerVCs, parseErrs, err := i.getVCsFromCredentialResponses(ctx, credentialResponses, fetchErrs)
 	if err != nil {
 		return nil, walleterror.NewExecutionError(
 			module,
@@ -449,6 +606,39 @@ func (i *Interaction) requestCredential(jwtSigner api.JWTSigner, //nolint:funlen
 			CredentialParseError, err)
 	}
 
+	switch {
+	case len(parseErrs) == len(credentialResponses):
+		return nil, walleterror.NewExecutionError(
+			module,
+			CredentialParseFailedCode,
+			CredentialParseError,
+			joinCredentialErrors(parseErrs, "failed to parse credential from credential response at index %d: %w"))
+	case len(fetchErrs)+len(parseErrs) == len(credentialResponses):
+		// None of the credentials were issued, but some of them were received and couldn't be parsed.
+		for index, parseErr := range parseErrs {
+			fetchErrs[index] = fmt.Errorf("failed to parse credential: %w", parseErr)
+		}
+
+		return nil, newCredentialFetchError(joinCredentialErrors(fetchErrs, "credential at index %d: %w"))
+	}
+
+	partialIssuanceErr := newPartialIssuanceError(fetchErrs, parseErrs)
+
+	i.deferredCredentials, err = i.getDeferredCredentials(credentialResponses, i.accessToken(grantType))
+	if err != nil {
+		return nil, walleterror.NewExecutionError(
+			module,
+			CredentialFetchFailedCode,
+			CredentialFetchFailedError, err)
+	}
+
+	i.reissuanceTokens = i.createReissuanceTokens(offerVCs, i.refreshToken(grantType))
+	i.notificationHandles = i.createNotificationHandles(credentialResponses, offerVCs, i.accessToken(grantType))
+
+	vcs := issuedVCs(offerVCs)
+
+	i.credentialIssuerIDs = getIssuerIDs(vcs)
+
 	subjectIDs, err := getSubjectIDs(vcs)
 	if err != nil {
 		return nil, err
@@ -462,7 +652,7 @@ func (i *Interaction) requestCredential(jwtSigner api.JWTSigner, //nolint:funlen
 		return nil, err
 	}
 
-	return vcs, i.activityLogger.Log(&api.Activity{
+	err = i.activityLogger.Log(&api.Activity{
 		ID:   uuid.New(),
 		Type: api.LogTypeCredentialActivity,
 		Time: time.Now(),
@@ -473,6 +663,15 @@ func (i *Interaction) requestCredential(jwtSigner api.JWTSigner, //nolint:funlen
 			Params:    map[string]interface{}{"subjectIDs": subjectIDs},
 		},
 	})
+	if err != nil {
+		return vcs, err
+	}
+
+	if partialIssuanceErr != nil {
+		return vcs, partialIssuanceErr
+	}
+
+	return vcs, nil
 }
 
 // Based on the current state of this Interaction so far, as well as the PIN passed in by the caller,
@@ -535,166 +734,307 @@ func (i *Interaction) onlyAuthorizationCodeGrantSupported() bool {
 	return i.AuthorizationCodeGrantTypeSupported() && !i.PreAuthorizedCodeGrantTypeSupported()
 }
 
-func (i *Interaction) getCredentialResponsesUsingPreAuth(pin string, //nolint:funlen // Difficult to decompose
+func (i *Interaction) getCredentialResponsesUsingPreAuth(ctx context.Context, pin string,
 	signer api.JWTSigner,
-) ([]CredentialResponse, error) {
+) ([]CredentialResponse, map[int]error, error) {
 	var err error
 
-	i.openIDConfig, err = i.getOpenIDConfig()
+	// The issuer's metadata (fetched up front by validateSignerMeetsProofRequirements) may name a separate
+	// authorization server.
+	i.openIDConfig, err = i.getOpenIDConfig(ctx)
 	if err != nil {
-		return nil, walleterror.NewExecutionError(
+		return nil, nil, walleterror.NewExecutionError(
 			module,
 			IssuerOpenIDConfigFetchFailedCode,
 			IssuerOpenIDConfigFetchFailedError,
 			fmt.Errorf("failed to fetch issuer's OpenID configuration: %w", err))
 	}
 
-	tokenResponse, err := i.getPreAuthTokenResponse(pin)
+	err = checkDPoPSupported(i.dpop, i.openIDConfig)
 	if err != nil {
-		return nil, walleterror.NewExecutionError(
+		return nil, nil, err
+	}
+
+	tokenResponse, err := i.getPreAuthTokenResponse(ctx, pin)
+	if err != nil {
+		return nil, nil, walleterror.NewExecutionError(
 			module,
 			TokenFetchFailedCode,
 			TokenFetchFailedError,
 			fmt.Errorf("failed to get token response: %w", err))
 	}
 
-	proofJWT, err := i.createClaimsProof(tokenResponse.CNonce, signer)
+	i.preAuthTokenResponse = tokenResponse
+
+	i.setCredentialIdentifiers(tokenResponse.AuthorizationDetails)
+
+	proof := &credentialProof{issuerURI: i.issuerURI, clientID: i.clientID, signer: signer}
+
+	err = proof.sign(tokenResponse.CNonce)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	i.issuerMetadata, err = metadatafetcher.Get(i.issuerURI, i.httpClient, i.metricsLogger,
-		requestCredentialEventText)
+	return i.getCredentialResponses(ctx, proof, tokenResponse.AccessToken, i.httpClient)
+}
+
+func createProofJWT(issuerURI, clientID string, nonce interface{}, signer api.JWTSigner) (string, error) {
+	claims := map[string]interface{}{
+		"aud":   issuerURI,
+		"iat":   time.Now().Unix(),
+		"nonce": nonce,
+	}
+
+	if clientID != "" {
+		claims["iss"] = clientID // Only used in the authorization code flow.
+	}
+
+	proofJWT, err := signToken(claims, signer)
 	if err != nil {
-		return nil, walleterror.NewExecutionError(
+		return "", walleterror.NewExecutionError(
 			module,
-			MetadataFetchFailedCode,
-			MetadataFetchFailedError,
-			fmt.Errorf("failed to get issuer metadata: %w", err))
+			JWTSigningFailedCode,
+			JWTSigningFailedError,
+			fmt.Errorf("failed to create JWT: %w", err))
 	}
 
-	credentialResponses := make([]CredentialResponse, len(i.credentialTypes))
+	return proofJWT, nil
+}
 
-	for index := range i.credentialTypes {
-		request, err := i.createCredentialRequestWithoutAccessToken(proofJWT, index)
-		if err != nil {
-			return nil, err
-		}
+func (i *Interaction) getCredentialResponsesUsingAuth(ctx context.Context, signer api.JWTSigner,
+) ([]CredentialResponse, map[int]error, error) {
+	authorizationDetails, err := i.authorizationDetailsFromOAuth2Token()
+	if err != nil {
+		return nil, nil, walleterror.NewExecutionError(
+			module,
+			TokenFetchFailedCode,
+			TokenFetchFailedError,
+			err)
+	}
+
+	i.setCredentialIdentifiers(authorizationDetails)
+
+	proof := &credentialProof{issuerURI: i.issuerURI, clientID: i.clientID, signer: signer}
+
+	err = proof.sign(i.authTokenResponse.Extra("c_nonce"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The access token header will be injected automatically by the OAuth HTTP client, so there's no need to
+	// explicitly set it on the credential request(s).
+	return i.getCredentialResponses(ctx, proof, "", i.createOAuthHTTPClient(ctx))
+}
+
+// getCredentialResponses gets a credential response for each credential in the offer.
+// If more than one credential was offered and the issuer has a batch credential endpoint, then they're all
+// requested in a single call to that endpoint. Otherwise, they're requested from the credential endpoint one at a time.
+// If accessToken is blank, then the given HTTP client is expected to set the access token on requests by itself.
+// If only some of the credentials can't be fetched, then the error for each of them is returned in a map keyed by the
+// credential's index, and their credential responses are left empty. If none of them can be fetched, then an error
+// is returned instead.
+// If the issuer rejects the proof and provides a fresh c_nonce, then the proof is re-signed and the request retried.
+func (i *Interaction) getCredentialResponses(ctx context.Context, proof *credentialProof, accessToken string,
+	httpClient *http.Client,
+) ([]CredentialResponse, map[int]error, error) {
+	var credentialResponses []CredentialResponse
 
-		request.Header.Add("Authorization", "Bearer "+tokenResponse.AccessToken)
+	var credentialErrs map[int]error
 
-		fetchCredentialResponseEventText := fmt.Sprintf(fetchCredentialViaGETReqEventText, index+1,
-			len(i.credentialTypes), i.issuerMetadata.CredentialEndpoint)
+	if i.issuerMetadata.BatchCredentialEndpoint != "" && len(i.credentialTypes) > 1 {
+		var err error
 
-		responseBytes, err := i.getRawCredentialResponse(request, fetchCredentialResponseEventText, i.httpClient)
+		credentialResponses, credentialErrs, err = i.getCredentialResponsesFromBatchEndpoint(ctx, proof,
+			accessToken, httpClient)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
+	} else {
+		credentialResponses = make([]CredentialResponse, len(i.credentialTypes))
+		credentialErrs = make(map[int]error)
 
-		var credentialResponse CredentialResponse
+		for index := range i.credentialTypes {
+			credentialResponse, err := i.getCredentialResponseFromCredentialEndpoint(ctx, proof, accessToken, index,
+				httpClient)
+			if err != nil {
+				credentialErrs[index] = err
 
-		err = json.Unmarshal(responseBytes, &credentialResponse)
-		if err != nil {
-			return nil, fmt.Errorf("failed to unmarshal response from the issuer's credential endpoint: %w", err)
+				continue
+			}
+
+			credentialResponses[index] = *credentialResponse
 		}
+	}
 
-		credentialResponses[index] = credentialResponse
+	if len(credentialErrs) == len(i.credentialTypes) {
+		return nil, nil, joinCredentialErrors(credentialErrs, "credential at index %d: %w")
 	}
 
-	return credentialResponses, nil
+	return credentialResponses, credentialErrs, nil
 }
 
-func (i *Interaction) createClaimsProof(nonce interface{}, signer api.JWTSigner) (string, error) {
-	claims := map[string]interface{}{
-		"aud":   i.issuerURI,
-		"iat":   time.Now().Unix(),
-		"nonce": nonce,
+func (i *Interaction) getCredentialResponseFromCredentialEndpoint(ctx context.Context, proof *credentialProof,
+	accessToken string, credentialFormatAndTypesIndex int, httpClient *http.Client,
+) (*CredentialResponse, error) {
+	responseBytes, err := doWithProofRetry(proof, func() ([]byte, error) {
+		return i.sendCredentialRequest(ctx, proof.jwt, accessToken, credentialFormatAndTypesIndex, httpClient)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	if i.clientID != "" {
-		claims["iss"] = i.clientID // Only used in the authorization code flow.
-	}
+	var credentialResponse CredentialResponse
 
-	proofJWT, err := signToken(claims, signer)
+	err = json.Unmarshal(responseBytes, &credentialResponse)
 	if err != nil {
-		return "", walleterror.NewExecutionError(
-			module,
-			JWTSigningFailedCode,
-			JWTSigningFailedError,
-			fmt.Errorf("failed to create JWT: %w", err))
+		return nil, fmt.Errorf("failed to unmarshal response from the issuer's credential endpoint: %w", err)
 	}
 
-	return proofJWT, nil
+	return &credentialResponse, nil
 }
 
-func (i *Interaction) getCredentialResponsesUsingAuth(signer api.JWTSigner) ([]CredentialResponse, error) {
-	proofJWT, err := i.createClaimsProof(i.authTokenResponse.Extra("c_nonce"), signer)
+// sendCredentialRequest sends a request to the issuer's credential endpoint and returns the (decrypted, if
+// applicable) response.
+func (i *Interaction) sendCredentialRequest(ctx context.Context, proofJWT, accessToken string,
+	credentialFormatAndTypesIndex int, httpClient *http.Client,
+) ([]byte, error) {
+	decrypter, err := newCredentialResponseDecrypter(i.credentialResponseEncryption())
 	if err != nil {
 		return nil, err
 	}
 
-	credentialResponses := make([]CredentialResponse, len(i.credentialTypes))
+	credentialReq := i.createCredentialRequest(proofJWT, credentialFormatAndTypesIndex)
 
-	oAuthHTTPClient := i.createOAuthHTTPClient()
+	if decrypter != nil {
+		decrypter.addToCredentialRequest(credentialReq, len(i.credentialConfigurationIDs) > 0)
+	}
 
-	for index := range i.credentialTypes {
-		request, err := i.createCredentialRequestWithoutAccessToken(proofJWT, index)
-		if err != nil {
-			return nil, err
-		}
+	request, err := createHTTPRequest(ctx, i.issuerMetadata.CredentialEndpoint, credentialReq, accessToken)
+	if err != nil {
+		return nil, err
+	}
 
-		// The access token header will be injected automatically by the OAuth HTTP client, so there's no need to
-		// explicitly set it on the request object generated by the method call above.
+	fetchCredentialResponseEventText := fmt.Sprintf(fetchCredentialViaGETReqEventText,
+		credentialFormatAndTypesIndex+1, len(i.credentialTypes), i.issuerMetadata.CredentialEndpoint)
 
-		fetchCredentialResponseEventText := fmt.Sprintf(fetchCredentialViaGETReqEventText, index+1,
-			len(i.credentialTypes), i.issuerMetadata.CredentialEndpoint)
+	responseBytes, err := getRawCredentialResponse(request, httpClient, i.metricsLogger,
+		fetchCredentialResponseEventText, requestCredentialEventText)
+	if err != nil {
+		return nil, err
+	}
 
-		responseBytes, err := i.getRawCredentialResponse(request, fetchCredentialResponseEventText, oAuthHTTPClient)
-		if err != nil {
-			return nil, err
-		}
+	if decrypter != nil {
+		return decrypter.decrypt(responseBytes)
+	}
 
-		var credentialResponse CredentialResponse
+	return responseBytes, nil
+}
+
+// getCredentialResponsesFromBatchEndpoint returns the credential responses from the issuer's batch credential
+// endpoint, along with errors for any entries in the batch response that don't contain a credential (or a transaction
+// ID for a deferred one).
+func (i *Interaction) getCredentialResponsesFromBatchEndpoint(ctx context.Context, proof *credentialProof,
+	accessToken string, httpClient *http.Client,
+) ([]CredentialResponse, map[int]error, error) {
+	responseBytes, err := doWithProofRetry(proof, func() ([]byte, error) {
+		return i.sendBatchCredentialRequest(ctx, proof.jwt, accessToken, httpClient)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var batchResponse batchCredentialResponse
+
+	err = json.Unmarshal(responseBytes, &batchResponse)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal response from the issuer's batch credential endpoint: %w",
+			err)
+	}
+
+	if len(batchResponse.CredentialResponses) != len(i.credentialTypes) {
+		return nil, nil, fmt.Errorf("expected %d credential responses from the issuer's batch credential endpoint "+
+			"but received %d", len(i.credentialTypes), len(batchResponse.CredentialResponses))
+	}
+
+	credentialResponses := make([]CredentialResponse, len(batchResponse.CredentialResponses))
+
+	credentialErrs := make(map[int]error)
+
+	for index, rawCredentialResponse := range batchResponse.CredentialResponses {
+		requestErr := newCredentialRequestError(0, rawCredentialResponse)
+
+		if requestErr.ErrorCode != "" {
+			credentialErrs[index] = requestErr
 
-		err = json.Unmarshal(responseBytes, &credentialResponse)
+			continue
+		}
+
+		err = json.Unmarshal(rawCredentialResponse, &credentialResponses[index])
 		if err != nil {
-			return nil, fmt.Errorf("failed to unmarshal response from the issuer's credential endpoint: %w", err)
+			credentialErrs[index] = fmt.Errorf("failed to unmarshal credential response from the issuer's batch "+
+				"credential endpoint: %w", err)
+
+			continue
 		}
 
-		credentialResponses[index] = credentialResponse
+		if credentialResponses[index].Credential == nil && !credentialResponses[index].isDeferred() {
+			credentialErrs[index] = errors.New("the issuer's batch credential endpoint didn't return a credential")
+		}
 	}
 
-	return credentialResponses, nil
+	return credentialResponses, credentialErrs, nil
 }
 
-// getOpenIDConfig fetches the OpenID configuration from the issuer. If the OpenID configuration has already been
-// fetched before, then it's returned without making an additional call.
-func (i *Interaction) getOpenIDConfig() (*OpenIDConfig, error) {
-	if i.openIDConfig != nil {
-		return i.openIDConfig, nil
+// sendBatchCredentialRequest sends a request for all offered credentials to the issuer's batch credential endpoint
+// and returns the (decrypted, if applicable) response.
+func (i *Interaction) sendBatchCredentialRequest(ctx context.Context, proofJWT, accessToken string,
+	httpClient *http.Client,
+) ([]byte, error) {
+	// A single key is used for the whole batch, and the issuer is expected to encrypt the batch response as a whole.
+	decrypter, err := newCredentialResponseDecrypter(i.credentialResponseEncryption())
+	if err != nil {
+		return nil, err
 	}
 
-	openIDConfigEndpoint := i.issuerURI + "/.well-known/openid-configuration"
+	batchRequest := batchCredentialRequest{
+		CredentialRequests: make([]credentialRequest, len(i.credentialTypes)),
+	}
 
-	responseBytes, err := httprequest.New(i.httpClient, i.metricsLogger).Do(
-		http.MethodGet, openIDConfigEndpoint, "", nil,
-		fmt.Sprintf(fetchOpenIDConfigViaGETReqEventText, openIDConfigEndpoint), requestCredentialEventText)
+	for index := range i.credentialTypes {
+		credentialReq := i.createCredentialRequest(proofJWT, index)
+
+		if decrypter != nil {
+			decrypter.addToCredentialRequest(credentialReq, len(i.credentialConfigurationIDs) > 0)
+		}
+
+		batchRequest.CredentialRequests[index] = *credentialReq
+	}
+
+	request, err := createHTTPRequest(ctx, i.issuerMetadata.BatchCredentialEndpoint, batchRequest, accessToken)
 	if err != nil {
-		return nil, fmt.Errorf("openid configuration endpoint: %w", err)
+		return nil, err
 	}
 
-	var config OpenIDConfig
+	fetchCredentialsEventText := fmt.Sprintf(fetchCredentialsViaBatchPOSTReqEventText, len(i.credentialTypes),
+		i.issuerMetadata.BatchCredentialEndpoint)
 
-	err = json.Unmarshal(responseBytes, &config)
+	responseBytes, err := getRawCredentialResponse(request, httpClient, i.metricsLogger,
+		fetchCredentialsEventText, requestCredentialEventText)
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response from the issuer's "+
-			"OpenID configuration endpoint: %w", err)
+		return nil, err
 	}
 
-	return &config, nil
+	if decrypter != nil {
+		return decrypter.decrypt(responseBytes)
+	}
+
+	return responseBytes, nil
 }
 
-func (i *Interaction) getRawCredentialResponse(credentialReq *http.Request, eventText string, httpClient *http.Client,
+// getRawCredentialResponse sends the given credential request using the given HTTP client and returns the response
+// body. Any status code other than 200 is returned as a credential request error.
+func getRawCredentialResponse(credentialReq *http.Request, httpClient *http.Client, metricsLogger api.MetricsLogger,
+	eventText, parentEventText string,
 ) ([]byte, error) {
 	timeStartHTTPRequest := time.Now()
 
@@ -703,9 +1043,9 @@ func (i *Interaction) getRawCredentialResponse(credentialReq *http.Request, even
 		return nil, err
 	}
 
-	err = i.metricsLogger.Log(&api.MetricsEvent{
+	err = metricsLogger.Log(&api.MetricsEvent{
 		Event:       eventText,
-		ParentEvent: requestCredentialEventText,
+		ParentEvent: parentEventText,
 		Duration:    time.Since(timeStartHTTPRequest),
 	})
 	if err != nil {
@@ -718,8 +1058,7 @@ func (i *Interaction) getRawCredentialResponse(credentialReq *http.Request, even
 	}
 
 	if response.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("received status code [%d] with body [%s] from issuer's credential endpoint",
-			response.StatusCode, string(responseBytes))
+		return nil, newCredentialRequestError(response.StatusCode, responseBytes)
 	}
 
 	defer func() {
@@ -732,62 +1071,61 @@ func (i *Interaction) getRawCredentialResponse(credentialReq *http.Request, even
 	return responseBytes, nil
 }
 
-// The returned *http.Request will not have the access token set on it. The caller must ensure that it's set
-// before sending the request to the server.
-func (i *Interaction) createCredentialRequestWithoutAccessToken(proofJWT string, credentialFormatAndTypesIndex int,
-) (*http.Request, error) {
-	credentialReq := &credentialRequest{
-		Types:  i.credentialTypes[credentialFormatAndTypesIndex],
-		Format: i.credentialFormats[credentialFormatAndTypesIndex],
-		Proof: proof{
-			ProofType: "jwt", // TODO: https://github.com/trustbloc/wallet-sdk/issues/159 support other proof types
-			JWT:       proofJWT,
-		},
-	}
+func (i *Interaction) createCredentialRequest(proofJWT string, credentialFormatAndTypesIndex int,
+) *credentialRequest {
+	return newCredentialRequest(i.credentialFormats[credentialFormatAndTypesIndex],
+		i.credentialTypes[credentialFormatAndTypesIndex], i.credentialConfigurationID(credentialFormatAndTypesIndex),
+		i.credentialIdentifier(credentialFormatAndTypesIndex), proofJWT)
+}
 
-	credentialReqBytes, err := json.Marshal(credentialReq)
+// createHTTPRequest creates a POST request to the given endpoint with the given body serialized as JSON.
+// If accessToken is blank, then the caller must ensure that it gets set before the request is sent to the server.
+func createHTTPRequest(ctx context.Context, endpoint string, body interface{}, accessToken string,
+) (*http.Request, error) {
+	bodyBytes, err := json.Marshal(body)
 	if err != nil {
 		return nil, err
 	}
 
-	request, err := http.NewRequest(http.MethodPost, //nolint: noctx
-		i.issuerMetadata.CredentialEndpoint, bytes.NewReader(credentialReqBytes))
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(bodyBytes))
 	if err != nil {
 		return nil, err
 	}
 
 	request.Header.Add("Content-Type", "application/json")
 
+	if accessToken != "" {
+		request.Header.Add("Authorization", "Bearer "+accessToken)
+	}
+
 	return request, nil
 }
 
-func (i *Interaction) getVCsFromCredentialResponses(
-	credentialResponses []CredentialResponse,
-) ([]*verifiable.Credential, error) {
-	var vcs []*verifiable.Credential
-
-	vdrKeyResolver := verifiable.NewVDRKeyResolver(i.didResolver)
+// getVCsFromCredentialResponses parses the credentials from the given credential responses. The returned VCs are in
+// the same order as the credential responses, with nil in place of deferred credentials and of the credentials in
+// failedCredentials, which are skipped. The error for each credential that couldn't be parsed is returned in a map
+// keyed by the credential's index.
+func (i *Interaction) getVCsFromCredentialResponses(ctx context.Context,
+	credentialResponses []CredentialResponse, failedCredentials map[int]error,
+) ([]*verifiable.Credential, map[int]error, error) {
+	vcs := make([]*verifiable.Credential, len(credentialResponses))
 
-	credentialOpts := []verifiable.CredentialOpt{
-		verifiable.WithJSONLDDocumentLoader(i.documentLoader),
-		verifiable.WithPublicKeyFetcher(vdrKeyResolver.PublicKeyFetcher()),
-	}
+	credentialOpts := credentialParseOpts(ctx, i.didResolver.didResolver, i.documentLoader, i.disableVCProofChecks)
 
-	if i.disableVCProofChecks {
-		credentialOpts = append(credentialOpts, verifiable.WithDisabledProofCheck())
-	}
+	parseErrs := make(map[int]error)
 
 	for j := range credentialResponses {
+		if _, failed := failedCredentials[j]; failed || credentialResponses[j].isDeferred() {
+			continue // Deferred credentials are handled separately by getDeferredCredentials.
+		}
+
 		timeStartParseCredential := time.Now()
 
-		credentialResponseBytes, err := credentialResponses[j].SerializeToCredentialsBytes()
+		vc, err := parseCredentialFromCredentialResponse(&credentialResponses[j], credentialOpts)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse credential from credential response at index %d: %w", j, err)
-		}
+			parseErrs[j] = err
 
-		vc, err := verifiable.ParseCredential(credentialResponseBytes, credentialOpts...)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse credential from credential response at index %d: %w", j, err)
+			continue
 		}
 
 		err = i.metricsLogger.Log(&api.MetricsEvent{
@@ -796,28 +1134,86 @@ func (i *Interaction) getVCsFromCredentialResponses(
 			Duration:    time.Since(timeStartParseCredential),
 		})
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
-		vcs = append(vcs, vc)
+		vcs[j] = vc
 	}
 
-	return vcs, nil
+	return vcs, parseErrs, nil
+}
+
+// issuedVCs returns the given VCs without the nil entries for credentials that weren't issued.
+func issuedVCs(vcs []*verifiable.Credential) []*verifiable.Credential {
+	var issued []*verifiable.Credential
+
+	for _, vc := range vcs {
+		if vc != nil {
+			issued = append(issued, vc)
+		}
+	}
+
+	return issued
+}
+
+// credentialParseOpts returns the options for parsing received credentials. Any DID resolution done while checking
+// their proofs uses the given context.
+func credentialParseOpts(ctx context.Context, didResolver api.DIDResolver, documentLoader ld.DocumentLoader,
+	disableVCProofChecks bool,
+) []verifiable.CredentialOpt {
+	vdrKeyResolver := verifiable.NewVDRKeyResolver(
+		&didResolverWrapper{didResolver: contextbound.DIDResolver(ctx, didResolver)})
+
+	credentialOpts := []verifiable.CredentialOpt{
+		verifiable.WithJSONLDDocumentLoader(documentLoader),
+		verifiable.WithPublicKeyFetcher(vdrKeyResolver.PublicKeyFetcher()),
+	}
+
+	if disableVCProofChecks {
+		credentialOpts = append(credentialOpts, verifiable.WithDisabledProofCheck())
+	}
+
+	return credentialOpts
 }
 
-func (i *Interaction) getPreAuthTokenResponse(pin string) (*preAuthTokenResponse, error) {
+func parseCredentialFromCredentialResponse(credentialResponse *CredentialResponse,
+	credentialOpts []verifiable.CredentialOpt,
+) (*verifiable.Credential, error) {
+	credentialResponseBytes, err := credentialResponse.SerializeToCredentialsBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	return verifiable.ParseCredential(credentialResponseBytes, credentialOpts...)
+}
+
+func (i *Interaction) getPreAuthTokenResponse(ctx context.Context, pin string) (*preAuthTokenResponse, error) {
 	params := url.Values{}
 	params.Add("grant_type", preAuthorizedGrantType)
 	params.Add("pre-authorized_code", i.preAuthorizedCodeGrantParams.preAuthorizedCode)
 
 	if pin != "" {
-		params.Add("user_pin", pin)
+		// Draft 13 (and later) renamed the user_pin parameter to tx_code.
+		if len(i.credentialConfigurationIDs) > 0 || i.preAuthorizedCodeGrantParams.txCode != nil {
+			params.Add("tx_code", pin)
+		} else {
+			params.Add("user_pin", pin)
+		}
+	}
+
+	headers := http.Header{}
+
+	if i.clientAuthentication != nil {
+		err := i.clientAuthentication.addToTokenRequest(params, headers, i.openIDConfig)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	paramsReader := strings.NewReader(params.Encode())
 
-	responseBytes, err := httprequest.New(i.httpClient, i.metricsLogger).Do(
-		http.MethodPost, i.openIDConfig.TokenEndpoint, "application/x-www-form-urlencoded", paramsReader,
+	responseBytes, err := httprequest.New(i.httpClient, i.metricsLogger).DoWithHeaders(ctx,
+		http.MethodPost, i.openIDConfig.TokenEndpoint, "application/x-www-form-urlencoded", headers, paramsReader,
 		fmt.Sprintf(fetchTokenViaPOSTReqEventText, i.openIDConfig.TokenEndpoint), requestCredentialEventText)
 	if err != nil {
 		return nil, fmt.Errorf("issuer's token endpoint: %w", err)
@@ -836,8 +1232,8 @@ func (i *Interaction) getPreAuthTokenResponse(pin string) (*preAuthTokenResponse
 // createOAuthHTTPClient creates the OAuth2 client wrapper using the OAuth2 library.
 // Due to some peculiarities with the OAuth2 library, we need to do some things here to ensure our custom HTTP client
 // settings get preserved. Check the comments in the method below for more details.
-func (i *Interaction) createOAuthHTTPClient() *http.Client {
-	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, i.httpClient)
+func (i *Interaction) createOAuthHTTPClient(ctx context.Context) *http.Client {
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, i.httpClient)
 
 	// The HTTP client below only retains the Transport, so we have to set the timeout again.
 	// The docs say that the returned client shouldn't be modified, but there doesn't seem to be a clear reason
@@ -852,7 +1248,8 @@ func (i *Interaction) createOAuthHTTPClient() *http.Client {
 	return oAuthHTTPClient
 }
 
-func getCredentialOffer(initiateIssuanceURI string, httpClient *http.Client, metricsLogger api.MetricsLogger,
+func getCredentialOffer(ctx context.Context, initiateIssuanceURI string, httpClient *http.Client,
+	metricsLogger api.MetricsLogger,
 ) (*CredentialOffer, error) {
 	requestURIParsed, err := url.Parse(initiateIssuanceURI)
 	if err != nil {
@@ -871,7 +1268,7 @@ func getCredentialOffer(initiateIssuanceURI string, httpClient *http.Client, met
 	case requestURIParsed.Query().Has("credential_offer_uri"):
 		credentialOfferURI := requestURIParsed.Query().Get("credential_offer_uri")
 
-		credentialOfferJSON, err = getCredentialOfferJSONFromCredentialOfferURI(
+		credentialOfferJSON, err = getCredentialOfferJSONFromCredentialOfferURI(ctx,
 			credentialOfferURI, httpClient, metricsLogger)
 		if err != nil {
 			return nil, err
@@ -899,10 +1296,10 @@ func getCredentialOffer(initiateIssuanceURI string, httpClient *http.Client, met
 	return &credentialOffer, nil
 }
 
-func getCredentialOfferJSONFromCredentialOfferURI(credentialOfferURI string,
+func getCredentialOfferJSONFromCredentialOfferURI(ctx context.Context, credentialOfferURI string,
 	httpClient *http.Client, metricsLogger api.MetricsLogger,
 ) ([]byte, error) {
-	responseBytes, err := httprequest.New(httpClient, metricsLogger).Do(
+	responseBytes, err := httprequest.New(httpClient, metricsLogger).Do(ctx,
 		http.MethodGet, credentialOfferURI, "", nil,
 		fmt.Sprintf(fetchCredOfferViaGETReqEventText, credentialOfferURI), newInteractionEventText)
 	if err != nil {
@@ -924,16 +1321,9 @@ func determineCredentialTypesAndFormats(credentialOffer *CredentialOffer) ([][]s
 	credentialFormats := make([]string, len(credentialOffer.Credentials))
 
 	for i := 0; i < len(credentialOffer.Credentials); i++ {
-		if credentialOffer.Credentials[i].Format != jwtVCJSONCredentialFormat &&
-			credentialOffer.Credentials[i].Format != jwtVCJSONLDCredentialFormat &&
-			credentialOffer.Credentials[i].Format != ldpVCCredentialFormat {
-			return nil, nil, walleterror.NewValidationError(
-				module,
-				UnsupportedCredentialTypeInOfferCode,
-				UnsupportedCredentialTypeInOfferError,
-				fmt.Errorf("unsupported credential type (%s) in credential offer at index %d of "+
-					"credentials object (must be jwt_vc_json or jwt_vc_json-ld)",
-					credentialOffer.Credentials[i].Format, i))
+		err := validateCredentialFormat(credentialOffer.Credentials[i].Format, i, "credentials")
+		if err != nil {
+			return nil, nil, err
 		}
 
 		credentialTypes[i] = credentialOffer.Credentials[i].Types
@@ -943,7 +1333,28 @@ func determineCredentialTypesAndFormats(credentialOffer *CredentialOffer) ([][]s
 	return credentialTypes, credentialFormats, nil
 }
 
+func validateCredentialFormat(format string, index int, credentialOfferFieldName string) error {
+	if format != jwtVCJSONCredentialFormat &&
+		format != jwtVCJSONLDCredentialFormat &&
+		format != ldpVCCredentialFormat {
+		return walleterror.NewValidationError(
+			module,
+			UnsupportedCredentialTypeInOfferCode,
+			UnsupportedCredentialTypeInOfferError,
+			fmt.Errorf("unsupported credential type (%s) in credential offer at index %d of "+
+				"%s object (must be jwt_vc_json or jwt_vc_json-ld)",
+				format, index, credentialOfferFieldName))
+	}
+
+	return nil
+}
+
 func validateSignerKeyID(jwtSigner api.JWTSigner) error {
+	// Signers whose key isn't associated with a DID identify it using a jwk or x5c header instead.
+	if keyBindingMethod(jwtSigner) != "" {
+		return nil
+	}
+
 	kidParts := strings.Split(jwtSigner.GetKeyID(), "#")
 	if len(kidParts) < 2 { //nolint: gomnd
 		return walleterror.NewExecutionError(
@@ -973,6 +1384,19 @@ func getSubjectIDs(vcs []*verifiable.Credential) ([]string, error) {
 	return subjectIDs, nil
 }
 
+// getIssuerIDs returns the distinct issuer IDs of the given credentials, in the order in which they first appear.
+func getIssuerIDs(vcs []*verifiable.Credential) []string {
+	var issuerIDs []string
+
+	for _, vc := range vcs {
+		if !contains(issuerIDs, vc.Issuer.ID) {
+			issuerIDs = append(issuerIDs, vc.Issuer.ID)
+		}
+	}
+
+	return issuerIDs
+}
+
 func signToken(claims interface{}, signer api.JWTSigner) (string, error) {
 	headers := jose.Headers{}
 	headers["typ"] = "openid4vci-proof+jwt"
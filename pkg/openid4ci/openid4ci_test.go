@@ -24,7 +24,9 @@ import (
 
 	"github.com/trustbloc/wallet-sdk/internal/testutil"
 	"github.com/trustbloc/wallet-sdk/pkg/api"
+	"github.com/trustbloc/wallet-sdk/pkg/common"
 	"github.com/trustbloc/wallet-sdk/pkg/localkms"
+	"github.com/trustbloc/wallet-sdk/pkg/models"
 	"github.com/trustbloc/wallet-sdk/pkg/openid4ci"
 )
 
@@ -53,9 +55,11 @@ type mockIssuerServerHandler struct {
 	issuerMetadata                                          string
 	tokenRequestShouldFail                                  bool
 	tokenRequestShouldGiveUnmarshallableResponse            bool
+	tokenResponse                                           string
 	credentialRequestShouldFail                             bool
 	credentialRequestShouldGiveUnmarshallableResponse       bool
 	credentialResponse                                      []byte
+	batchCredentialResponse                                 []byte
 }
 
 //nolint:gocyclo // test file
@@ -104,6 +108,9 @@ func (m *mockIssuerServerHandler) ServeHTTP(writer http.ResponseWriter, request
 			_, err = writer.Write([]byte("test failure"))
 		case m.tokenRequestShouldGiveUnmarshallableResponse:
 			_, err = writer.Write([]byte("invalid"))
+		case m.tokenResponse != "":
+			writer.Header().Set("Content-Type", "application/json")
+			_, err = writer.Write([]byte(m.tokenResponse))
 		default:
 			writer.Header().Set("Content-Type", "application/json")
 			_, err = writer.Write([]byte(sampleTokenResponse))
@@ -118,6 +125,8 @@ func (m *mockIssuerServerHandler) ServeHTTP(writer http.ResponseWriter, request
 		default:
 			_, err = writer.Write(m.credentialResponse)
 		}
+	case "/batch_credential":
+		_, err = writer.Write(m.batchCredentialResponse)
 	}
 
 	require.NoError(m.t, err)
@@ -229,7 +238,9 @@ func TestNewInteraction(t *testing.T) {
 		})
 	})
 	t.Run("No supported grant types found", func(t *testing.T) {
-		credentialOffer := openid4ci.CredentialOffer{}
+		credentialOffer := createSampleCredentialOffer(t, false)
+
+		credentialOffer.Grants = map[string]map[string]interface{}{"UnsupportedGrantType": {}}
 
 		credentialOfferBytes, err := json.Marshal(credentialOffer)
 		require.NoError(t, err)
@@ -347,9 +358,38 @@ func TestInteraction_CreateAuthorizationURL(t *testing.T) {
 		authorizationURL, err := interaction.CreateAuthorizationURL("clientID", "redirectURI")
 		require.NoError(t, err)
 		require.Contains(t, authorizationURL, authorizationServerURL+
-			"?authorization_details=%7B%22type%22%3A%22openid_credential%22%2C%22locations"+
+			"?authorization_details=%5B%7B%22type%22%3A%22openid_credential%22%2C%22locations"+
 			"%22%3A%5B%22%22%5D%2C%22types%22%3A%5B%22VerifiableCredential%22%2C%22VerifiedEmployee%22%5D%2C%22"+
-			"format%22%3A%22jwt_vc_json%22%7D&client_id=clientID")
+			"format%22%3A%22jwt_vc_json%22%7D%5D&client_id=clientID")
+	})
+	t.Run("Success with multiple credentials in the offer", func(t *testing.T) {
+		issuerServerHandler := &mockIssuerServerHandler{t: t}
+
+		server := httptest.NewServer(issuerServerHandler)
+		defer server.Close()
+
+		issuerServerHandler.issuerMetadata = fmt.Sprintf(`{"credential_endpoint":"%s/credential"}`, server.URL)
+
+		interaction := newInteraction(t, createMultiCredentialOfferIssuanceURI(t, server.URL, true))
+
+		authorizationURL, err := interaction.CreateAuthorizationURL("clientID", "redirectURI")
+		require.NoError(t, err)
+
+		parsedAuthorizationURL, err := url.Parse(authorizationURL)
+		require.NoError(t, err)
+
+		var authorizationDetails []map[string]interface{}
+
+		err = json.Unmarshal([]byte(parsedAuthorizationURL.Query().Get("authorization_details")),
+			&authorizationDetails)
+		require.NoError(t, err)
+		require.Len(t, authorizationDetails, 2)
+
+		for _, authorizationDetail := range authorizationDetails {
+			require.Equal(t, "openid_credential", authorizationDetail["type"])
+			require.Equal(t, "jwt_vc_json", authorizationDetail["format"])
+			require.Nil(t, authorizationDetail["locations"])
+		}
 	})
 	t.Run("Fail to get issuer metadata", func(t *testing.T) {
 		interaction := newInteraction(t, createCredentialOfferIssuanceURI(t, "example.com", true))
@@ -437,6 +477,60 @@ func TestInteraction_RequestCredential(t *testing.T) {
 				require.Len(t, credentials, 1)
 				require.NotEmpty(t, credentials[0])
 			})
+			t.Run("Multiple credentials using the batch credential endpoint", func(t *testing.T) {
+				issuerServerHandler := &mockIssuerServerHandler{
+					t:                       t,
+					batchCredentialResponse: createBatchCredentialResponse(t, 2),
+					// The regular credential endpoint should not be called.
+					credentialRequestShouldFail: true,
+				}
+
+				server := httptest.NewServer(issuerServerHandler)
+				defer server.Close()
+
+				issuerServerHandler.openIDConfig = &openid4ci.OpenIDConfig{
+					TokenEndpoint: fmt.Sprintf("%s/oidc/token", server.URL),
+				}
+
+				issuerServerHandler.issuerMetadata = fmt.Sprintf(`{"credential_endpoint":"%s/credential",`+
+					`"batch_credential_endpoint":"%s/batch_credential"}`, server.URL, server.URL)
+
+				interaction := newInteraction(t, createMultiCredentialOfferIssuanceURI(t, server.URL, false))
+
+				credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{
+					keyID: mockKeyID,
+				}, openid4ci.WithPIN("1234"))
+				require.NoError(t, err)
+				require.Len(t, credentials, 2)
+				require.NotEmpty(t, credentials[0])
+				require.NotEmpty(t, credentials[1])
+			})
+			t.Run("Multiple credentials without a batch credential endpoint", func(t *testing.T) {
+				issuerServerHandler := &mockIssuerServerHandler{
+					t:                  t,
+					credentialResponse: sampleCredentialResponse,
+				}
+
+				server := httptest.NewServer(issuerServerHandler)
+				defer server.Close()
+
+				issuerServerHandler.openIDConfig = &openid4ci.OpenIDConfig{
+					TokenEndpoint: fmt.Sprintf("%s/oidc/token", server.URL),
+				}
+
+				issuerServerHandler.issuerMetadata = fmt.Sprintf(`{"credential_endpoint":"%s/credential"}`,
+					server.URL)
+
+				interaction := newInteraction(t, createMultiCredentialOfferIssuanceURI(t, server.URL, false))
+
+				credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{
+					keyID: mockKeyID,
+				}, openid4ci.WithPIN("1234"))
+				require.NoError(t, err)
+				require.Len(t, credentials, 2)
+				require.NotEmpty(t, credentials[0])
+				require.NotEmpty(t, credentials[1])
+			})
 		})
 		t.Run("Missing PIN", func(t *testing.T) {
 			config := getTestClientConfig(t)
@@ -451,17 +545,35 @@ func TestInteraction_RequestCredential(t *testing.T) {
 				"the credential offer requires a user PIN, but none was provided")
 			require.Nil(t, credentials)
 		})
-		t.Run("Fail to fetch issuer's OpenID configuration", func(t *testing.T) {
+		t.Run("Fail to fetch issuer metadata", func(t *testing.T) {
 			requestURI := createCredentialOfferIssuanceURI(t, "BadURL", false)
 
 			interaction := newInteraction(t, requestURI)
 
+			credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{
+				keyID: mockKeyID,
+			}, openid4ci.WithPIN("1234"))
+			require.Contains(t, err.Error(), "METADATA_FETCH_FAILED(OCI1-0007):failed to get issuer metadata: "+
+				`openid configuration endpoint: `+
+				`Get "BadURL/.well-known/openid-credential-issuer": unsupported protocol scheme ""`)
+			require.Nil(t, credentials)
+		})
+		t.Run("Fail to fetch issuer's OpenID configuration", func(t *testing.T) {
+			issuerServerHandler := &mockIssuerServerHandler{t: t, openIDConfigEndpointShouldFail: true}
+			server := httptest.NewServer(issuerServerHandler)
+			defer server.Close()
+
+			issuerServerHandler.issuerMetadata = fmt.Sprintf(`{"credential_endpoint":"%s/credential"}`, server.URL)
+
+			requestURI := createCredentialOfferIssuanceURI(t, server.URL, false)
+
+			interaction := newInteraction(t, requestURI)
+
 			credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{
 				keyID: mockKeyID,
 			}, openid4ci.WithPIN("1234"))
 			require.Contains(t, err.Error(), "ISSUER_OPENID_FETCH_FAILED(OCI1-0006):failed to fetch issuer's "+
-				`OpenID configuration: openid configuration endpoint: `+
-				`Get "BadURL/.well-known/openid-configuration": unsupported protocol scheme ""`)
+				"OpenID configuration: ")
 			require.Nil(t, credentials)
 		})
 		t.Run("Fail to reach issuer token endpoint", func(t *testing.T) {
@@ -470,6 +582,9 @@ func TestInteraction_RequestCredential(t *testing.T) {
 				openIDConfig: &openid4ci.OpenIDConfig{TokenEndpoint: "http://BadURL"},
 			}
 			server := httptest.NewServer(issuerServerHandler)
+			defer server.Close()
+
+			issuerServerHandler.issuerMetadata = fmt.Sprintf(`{"credential_endpoint":"%s/credential"}`, server.URL)
 
 			requestURI := createCredentialOfferIssuanceURI(t, server.URL, false)
 
@@ -491,6 +606,8 @@ func TestInteraction_RequestCredential(t *testing.T) {
 				TokenEndpoint: fmt.Sprintf("%s/oidc/token", server.URL),
 			}
 
+			issuerServerHandler.issuerMetadata = fmt.Sprintf(`{"credential_endpoint":"%s/credential"}`, server.URL)
+
 			requestURI := createCredentialOfferIssuanceURI(t, server.URL, false)
 
 			interaction := newInteraction(t, requestURI)
@@ -511,6 +628,8 @@ func TestInteraction_RequestCredential(t *testing.T) {
 				TokenEndpoint: fmt.Sprintf("%s/oidc/token", server.URL),
 			}
 
+			issuerServerHandler.issuerMetadata = fmt.Sprintf(`{"credential_endpoint":"%s/credential"}`, server.URL)
+
 			requestURI := createCredentialOfferIssuanceURI(t, server.URL, false)
 
 			interaction := newInteraction(t, requestURI)
@@ -544,6 +663,78 @@ func TestInteraction_RequestCredential(t *testing.T) {
 				"with body [test failure] from issuer's credential endpoint")
 			require.Nil(t, credentials)
 		})
+		t.Run("Fail to get credential responses: server failure for multiple credentials", func(t *testing.T) {
+			issuerServerHandler := &mockIssuerServerHandler{t: t, credentialRequestShouldFail: true}
+			server := httptest.NewServer(issuerServerHandler)
+			defer server.Close()
+
+			issuerServerHandler.openIDConfig = &openid4ci.OpenIDConfig{
+				TokenEndpoint: fmt.Sprintf("%s/oidc/token", server.URL),
+			}
+
+			issuerServerHandler.issuerMetadata = fmt.Sprintf(`{"credential_endpoint":"%s/credential"}`, server.URL)
+
+			interaction := newInteraction(t, createMultiCredentialOfferIssuanceURI(t, server.URL, false))
+
+			credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{
+				keyID: mockKeyID,
+			}, openid4ci.WithPIN("1234"))
+			testutil.RequireErrorContains(t, err, "credential at index 0: received status code [500] "+
+				"with body [test failure] from issuer's credential endpoint")
+			testutil.RequireErrorContains(t, err, "credential at index 1: received status code [500] "+
+				"with body [test failure] from issuer's credential endpoint")
+			require.Nil(t, credentials)
+		})
+		t.Run("Fail to get credential responses from batch credential endpoint", func(t *testing.T) {
+			t.Run("Unexpected number of credential responses", func(t *testing.T) {
+				issuerServerHandler := &mockIssuerServerHandler{
+					t:                       t,
+					batchCredentialResponse: createBatchCredentialResponse(t, 1),
+				}
+				server := httptest.NewServer(issuerServerHandler)
+				defer server.Close()
+
+				issuerServerHandler.openIDConfig = &openid4ci.OpenIDConfig{
+					TokenEndpoint: fmt.Sprintf("%s/oidc/token", server.URL),
+				}
+
+				issuerServerHandler.issuerMetadata = fmt.Sprintf(`{"credential_endpoint":"%s/credential",`+
+					`"batch_credential_endpoint":"%s/batch_credential"}`, server.URL, server.URL)
+
+				interaction := newInteraction(t, createMultiCredentialOfferIssuanceURI(t, server.URL, false))
+
+				credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{
+					keyID: mockKeyID,
+				}, openid4ci.WithPIN("1234"))
+				testutil.RequireErrorContains(t, err, "expected 2 credential responses from the issuer's batch "+
+					"credential endpoint but received 1")
+				require.Nil(t, credentials)
+			})
+			t.Run("Fail to unmarshal response", func(t *testing.T) {
+				issuerServerHandler := &mockIssuerServerHandler{
+					t:                       t,
+					batchCredentialResponse: []byte("invalid"),
+				}
+				server := httptest.NewServer(issuerServerHandler)
+				defer server.Close()
+
+				issuerServerHandler.openIDConfig = &openid4ci.OpenIDConfig{
+					TokenEndpoint: fmt.Sprintf("%s/oidc/token", server.URL),
+				}
+
+				issuerServerHandler.issuerMetadata = fmt.Sprintf(`{"credential_endpoint":"%s/credential",`+
+					`"batch_credential_endpoint":"%s/batch_credential"}`, server.URL, server.URL)
+
+				interaction := newInteraction(t, createMultiCredentialOfferIssuanceURI(t, server.URL, false))
+
+				credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{
+					keyID: mockKeyID,
+				}, openid4ci.WithPIN("1234"))
+				testutil.RequireErrorContains(t, err, "failed to unmarshal response from the issuer's batch "+
+					"credential endpoint: invalid character 'i' looking for beginning of value")
+				require.Nil(t, credentials)
+			})
+		})
 		t.Run("Fail to get credential response: signature error", func(t *testing.T) {
 			issuerServerHandler := &mockIssuerServerHandler{t: t, credentialRequestShouldFail: true}
 			server := httptest.NewServer(issuerServerHandler)
@@ -596,6 +787,8 @@ func TestInteraction_RequestCredential(t *testing.T) {
 				TokenEndpoint: fmt.Sprintf("%s/oidc/token", server.URL),
 			}
 
+			issuerServerHandler.issuerMetadata = fmt.Sprintf(`{"credential_endpoint":"%s/credential"}`, server.URL)
+
 			requestURI := createCredentialOfferIssuanceURI(t, server.URL, false)
 
 			interaction := newInteraction(t, requestURI)
@@ -705,8 +898,10 @@ func TestInteraction_RequestCredential(t *testing.T) {
 				TokenEndpoint: fmt.Sprintf("%s/oidc/token", server.URL),
 			}
 
+			issuerServerHandler.issuerMetadata = fmt.Sprintf(`{"credential_endpoint":"%s/credential"}`, server.URL)
+
 			config := getTestClientConfig(t)
-			config.MetricsLogger = &failingMetricsLogger{attemptFailNumber: 1}
+			config.MetricsLogger = &failingMetricsLogger{attemptFailNumber: 2}
 
 			interaction, err := openid4ci.NewInteraction(createCredentialOfferIssuanceURI(t, server.URL, false), config)
 			require.NoError(t, err)
@@ -731,8 +926,10 @@ func TestInteraction_RequestCredential(t *testing.T) {
 				TokenEndpoint: fmt.Sprintf("%s/oidc/token", server.URL),
 			}
 
+			issuerServerHandler.issuerMetadata = fmt.Sprintf(`{"credential_endpoint":"%s/credential"}`, server.URL)
+
 			config := getTestClientConfig(t)
-			config.MetricsLogger = &failingMetricsLogger{attemptFailNumber: 2}
+			config.MetricsLogger = &failingMetricsLogger{attemptFailNumber: 3}
 
 			interaction, err := openid4ci.NewInteraction(createCredentialOfferIssuanceURI(t, server.URL, false), config)
 			require.NoError(t, err)
@@ -756,8 +953,10 @@ func TestInteraction_RequestCredential(t *testing.T) {
 				TokenEndpoint: fmt.Sprintf("%s/oidc/token", server.URL),
 			}
 
+			issuerServerHandler.issuerMetadata = fmt.Sprintf(`{"credential_endpoint":"%s/credential"}`, server.URL)
+
 			config := getTestClientConfig(t)
-			config.MetricsLogger = &failingMetricsLogger{attemptFailNumber: 3}
+			config.MetricsLogger = &failingMetricsLogger{attemptFailNumber: 1}
 
 			interaction, err := openid4ci.NewInteraction(createCredentialOfferIssuanceURI(t, server.URL, false), config)
 			require.NoError(t, err)
@@ -796,7 +995,7 @@ func TestInteraction_RequestCredential(t *testing.T) {
 				keyID: mockKeyID,
 			}, openid4ci.WithPIN("1234"))
 			require.Contains(t, err.Error(), "CREDENTIAL_FETCH_FAILED(OCI1-0010):failed to get credential "+
-				"response: failed to log event (Event=Fetch credential 1 of 1 via an HTTP POST request to "+
+				"response: credential at index 0: failed to log event (Event=Fetch credential 1 of 1 via an HTTP POST request to "+
 				"http://127.0.0.1:")
 			require.Nil(t, credentials)
 		})
@@ -833,6 +1032,35 @@ func TestInteraction_RequestCredential(t *testing.T) {
 			require.Len(t, credentials, 1)
 			require.NotEmpty(t, credentials[0])
 		})
+		t.Run("Success with multiple credentials using the batch credential endpoint", func(t *testing.T) {
+			issuerServerHandler := &mockIssuerServerHandler{
+				t:                       t,
+				batchCredentialResponse: createBatchCredentialResponse(t, 2),
+			}
+
+			server := httptest.NewServer(issuerServerHandler)
+			defer server.Close()
+
+			issuerServerHandler.openIDConfig = &openid4ci.OpenIDConfig{
+				TokenEndpoint: fmt.Sprintf("%s/oidc/token", server.URL),
+			}
+
+			issuerServerHandler.issuerMetadata = fmt.Sprintf(`{"credential_endpoint":"%s/credential",`+
+				`"batch_credential_endpoint":"%s/batch_credential"}`, server.URL, server.URL)
+
+			interaction := newInteraction(t, createMultiCredentialOfferIssuanceURI(t, server.URL, true))
+
+			authURL, err := interaction.CreateAuthorizationURL("clientID", "redirectURI")
+			require.NoError(t, err)
+
+			redirectURIWithParams := "redirectURI?code=1234&state=" + getStateFromAuthURL(t, authURL)
+
+			credentials, err := interaction.RequestCredentialWithAuth(&jwtSignerMock{
+				keyID: mockKeyID,
+			}, redirectURIWithParams)
+			require.NoError(t, err)
+			require.Len(t, credentials, 2)
+		})
 		t.Run("Authorization URL not created first", func(t *testing.T) {
 			interaction := newInteraction(t, createCredentialOfferIssuanceURI(t, "example.com", false))
 
@@ -1054,6 +1282,89 @@ func TestInteraction_RequestCredential(t *testing.T) {
 	})
 }
 
+func TestInteraction_RequestCredential_KeyBoundSigner(t *testing.T) {
+	localKMS, err := localkms.NewLocalKMS(localkms.Config{Storage: localkms.NewMemKMSStore()})
+	require.NoError(t, err)
+
+	_, publicKeyJWK, err := localKMS.Create(arieskms.ED25519Type)
+	require.NoError(t, err)
+
+	vm := &models.VerificationMethod{Type: common.JSONWebKey2020, Key: models.VerificationKey{JSONWebKey: publicKeyJWK}}
+
+	jwkBoundSigner, err := common.NewJWKBoundSigner(vm, localKMS.GetCrypto())
+	require.NoError(t, err)
+
+	x5cBoundSigner, err := common.NewX5CBoundSigner(vm, []string{"MIIBtjCCAVugAwIBAgITBmyf1XSXNmY"},
+		localKMS.GetCrypto())
+	require.NoError(t, err)
+
+	newServer := func(bindingMethods string) (*httptest.Server, *mockCredentialErrorIssuerServerHandler) {
+		handler := &mockCredentialErrorIssuerServerHandler{
+			t: t,
+			issuerMetadata: `{"credential_endpoint":"%[1]s/credential","credentials_supported":[` +
+				`{"format":"jwt_vc_json","types":["VerifiableCredential","VerifiedEmployee"],` +
+				`"cryptographic_binding_methods_supported":` + bindingMethods + `}]}`,
+		}
+
+		return httptest.NewServer(handler), handler
+	}
+
+	t.Run("JWK binding", func(t *testing.T) {
+		server, handler := newServer(`["jwk"]`)
+		defer server.Close()
+
+		interaction := newInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, false))
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(jwkBoundSigner, openid4ci.WithPIN("1234"))
+		require.NoError(t, err)
+		require.Len(t, credentials, 1)
+
+		require.Len(t, handler.receivedProofHeaders, 1)
+
+		proofHeaders := handler.receivedProofHeaders[0]
+		require.Equal(t, "openid4vci-proof+jwt", proofHeaders["typ"])
+		require.Equal(t, "EdDSA", proofHeaders["alg"])
+		require.NotContains(t, proofHeaders, "kid")
+		require.NotContains(t, proofHeaders, "x5c")
+
+		proofJWK, ok := proofHeaders["jwk"].(map[string]interface{})
+		require.True(t, ok)
+		require.Equal(t, "OKP", proofJWK["kty"])
+		require.Equal(t, "Ed25519", proofJWK["crv"])
+	})
+	t.Run("x5c binding", func(t *testing.T) {
+		server, handler := newServer(`["jwk"]`)
+		defer server.Close()
+
+		interaction := newInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, false))
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(x5cBoundSigner, openid4ci.WithPIN("1234"))
+		require.NoError(t, err)
+		require.Len(t, credentials, 1)
+
+		require.Len(t, handler.receivedProofHeaders, 1)
+
+		proofHeaders := handler.receivedProofHeaders[0]
+		require.Equal(t, "openid4vci-proof+jwt", proofHeaders["typ"])
+		require.Equal(t, []interface{}{"MIIBtjCCAVugAwIBAgITBmyf1XSXNmY"}, proofHeaders["x5c"])
+		require.NotContains(t, proofHeaders, "kid")
+		require.NotContains(t, proofHeaders, "jwk")
+	})
+	t.Run("Key binding isn't accepted by the issuer", func(t *testing.T) {
+		server, handler := newServer(`["did:key","did:ion"]`)
+		defer server.Close()
+
+		interaction := newInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, false))
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(jwkBoundSigner, openid4ci.WithPIN("1234"))
+		require.EqualError(t, err, "INCOMPATIBLE_SIGNER(OCI0-0028):the signer's binding method (jwk) isn't one "+
+			"of the accepted binding methods [did:key did:ion]")
+		require.Nil(t, credentials)
+
+		require.Empty(t, handler.receivedProofHeaders)
+	})
+}
+
 func TestInteraction_GrantTypes(t *testing.T) {
 	interaction := newInteraction(t, createCredentialOfferIssuanceURI(t, "example.com", false))
 
@@ -1083,6 +1394,99 @@ func TestInteraction_GrantTypes(t *testing.T) {
 	require.Equal(t, "1234", *authorizationCodeGrantParams.IssuerState)
 }
 
+func TestInteraction_GrantTypesFromMetadata(t *testing.T) {
+	t.Run("Authorization server metadata doesn't specify grant types", func(t *testing.T) {
+		issuerServerHandler := &mockIssuerServerHandler{t: t}
+
+		server := httptest.NewServer(issuerServerHandler)
+		defer server.Close()
+
+		issuerServerHandler.openIDConfig = &openid4ci.OpenIDConfig{
+			TokenEndpoint: fmt.Sprintf("%s/oidc/token", server.URL),
+		}
+		issuerServerHandler.issuerMetadata = fmt.Sprintf(`{"credential_endpoint":"%s/credential"}`, server.URL)
+
+		interaction := newInteraction(t, createCredentialOfferIssuanceURIWithoutGrants(t, server.URL))
+
+		require.False(t, interaction.PreAuthorizedCodeGrantTypeSupported())
+		require.True(t, interaction.AuthorizationCodeGrantTypeSupported())
+
+		authorizationCodeGrantParams, err := interaction.AuthorizationCodeGrantParams()
+		require.NoError(t, err)
+		require.Nil(t, authorizationCodeGrantParams.IssuerState)
+	})
+	t.Run("Authorization server metadata specifies the authorization code grant type", func(t *testing.T) {
+		issuerServerHandler := &mockIssuerServerHandler{t: t}
+
+		server := httptest.NewServer(issuerServerHandler)
+		defer server.Close()
+
+		issuerServerHandler.openIDConfig = &openid4ci.OpenIDConfig{
+			GrantTypesSupported: []string{"authorization_code",
+				"urn:ietf:params:oauth:grant-type:pre-authorized_code"},
+		}
+		issuerServerHandler.issuerMetadata = `{"grant_types_supported":["urn:ietf:params:oauth:grant-type:` +
+			`pre-authorized_code"]}`
+
+		interaction := newInteraction(t, createCredentialOfferIssuanceURIWithoutGrants(t, server.URL))
+
+		require.False(t, interaction.PreAuthorizedCodeGrantTypeSupported())
+		require.True(t, interaction.AuthorizationCodeGrantTypeSupported())
+	})
+	t.Run("Issuer metadata specifies the authorization code grant type", func(t *testing.T) {
+		issuerServerHandler := &mockIssuerServerHandler{t: t}
+
+		server := httptest.NewServer(issuerServerHandler)
+		defer server.Close()
+
+		issuerServerHandler.openIDConfig = &openid4ci.OpenIDConfig{}
+		issuerServerHandler.issuerMetadata = `{"grant_types_supported":["authorization_code"]}`
+
+		interaction := newInteraction(t, createCredentialOfferIssuanceURIWithoutGrants(t, server.URL))
+
+		require.True(t, interaction.AuthorizationCodeGrantTypeSupported())
+	})
+	t.Run("Authorization code grant type not supported", func(t *testing.T) {
+		issuerServerHandler := &mockIssuerServerHandler{t: t}
+
+		server := httptest.NewServer(issuerServerHandler)
+		defer server.Close()
+
+		issuerServerHandler.openIDConfig = &openid4ci.OpenIDConfig{
+			GrantTypesSupported: []string{"urn:ietf:params:oauth:grant-type:pre-authorized_code"},
+		}
+		issuerServerHandler.issuerMetadata = "{}"
+
+		interaction, err := openid4ci.NewInteraction(createCredentialOfferIssuanceURIWithoutGrants(t, server.URL),
+			getTestClientConfig(t))
+		require.EqualError(t, err, "no supported grant types found: the credential offer doesn't specify any "+
+			"grants and the issuer's metadata doesn't indicate support for the authorization code grant type")
+		require.Nil(t, interaction)
+	})
+	t.Run("Fail to get issuer metadata", func(t *testing.T) {
+		interaction, err := openid4ci.NewInteraction(createCredentialOfferIssuanceURIWithoutGrants(t, "example.com"),
+			getTestClientConfig(t))
+		testutil.RequireErrorContains(t, err, "METADATA_FETCH_FAILED(OCI1-0007):failed to get issuer metadata")
+		require.Nil(t, interaction)
+	})
+	t.Run("Fail to get OpenID configuration", func(t *testing.T) {
+		issuerServerHandler := &mockIssuerServerHandler{
+			t:                              t,
+			issuerMetadata:                 "{}",
+			openIDConfigEndpointShouldFail: true,
+		}
+
+		server := httptest.NewServer(issuerServerHandler)
+		defer server.Close()
+
+		interaction, err := openid4ci.NewInteraction(createCredentialOfferIssuanceURIWithoutGrants(t, server.URL),
+			getTestClientConfig(t))
+		testutil.RequireErrorContains(t, err, "ISSUER_OPENID_FETCH_FAILED(OCI1-0006):failed to fetch issuer's "+
+			"OpenID configuration")
+		require.Nil(t, interaction)
+	})
+}
+
 func TestInteraction_DynamicClientRegistration(t *testing.T) {
 	t.Run("Fail to get OpenID configuration", func(t *testing.T) {
 		interaction := newInteraction(t, createCredentialOfferIssuanceURI(t, "example.com", false))
@@ -1249,6 +1653,51 @@ func createCredentialOfferIssuanceURI(t *testing.T, issuerURL string, includeAut
 	return "openid-vc://?credential_offer=" + credentialOfferEscaped
 }
 
+func createCredentialOfferIssuanceURIWithoutGrants(t *testing.T, issuerURL string) string {
+	t.Helper()
+
+	credentialOffer := createCredentialOffer(t, issuerURL, false)
+
+	credentialOffer.Grants = nil
+
+	credentialOfferBytes, err := json.Marshal(credentialOffer)
+	require.NoError(t, err)
+
+	return "openid-vc://?credential_offer=" + url.QueryEscape(string(credentialOfferBytes))
+}
+
+// createMultiCredentialOfferIssuanceURI creates an issuance URI with a credential offer that offers the sample
+// credential twice.
+func createMultiCredentialOfferIssuanceURI(t *testing.T, issuerURL string, includeAuthCodeGrant bool) string {
+	t.Helper()
+
+	credentialOffer := createCredentialOffer(t, issuerURL, includeAuthCodeGrant)
+
+	credentialOffer.Credentials = append(credentialOffer.Credentials, credentialOffer.Credentials[0])
+
+	credentialOfferBytes, err := json.Marshal(credentialOffer)
+	require.NoError(t, err)
+
+	return "openid-vc://?credential_offer=" + url.QueryEscape(string(credentialOfferBytes))
+}
+
+func createBatchCredentialResponse(t *testing.T, numberOfCredentialResponses int) []byte {
+	t.Helper()
+
+	credentialResponses := make([]json.RawMessage, numberOfCredentialResponses)
+
+	for i := range credentialResponses {
+		credentialResponses[i] = sampleCredentialResponse
+	}
+
+	batchCredentialResponse, err := json.Marshal(map[string]interface{}{
+		"credential_responses": credentialResponses,
+	})
+	require.NoError(t, err)
+
+	return batchCredentialResponse
+}
+
 func createCredentialOffer(t *testing.T, issuerURL string, includeAuthCodeGrant bool) *openid4ci.CredentialOffer {
 	t.Helper()
 
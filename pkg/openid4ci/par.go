@@ -0,0 +1,134 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/trustbloc/wallet-sdk/pkg/internal/httprequest"
+	"github.com/trustbloc/wallet-sdk/pkg/walleterror"
+)
+
+const (
+	authorizationEventText = "Authorization"
+	//nolint:gosec //false positive
+	pushAuthorizationRequestViaPOSTReqEventText = "Push authorization request via an HTTP POST request to %s"
+)
+
+// pushedAuthorizationResponse is the response from a pushed authorization request endpoint, as defined in
+// https://datatracker.ietf.org/doc/html/rfc9126#section-2.2.
+type pushedAuthorizationResponse struct {
+	RequestURI string `json:"request_uri"`
+	ExpiresIn  int    `json:"expires_in"`
+}
+
+// pushedAuthorizationRequestEndpoint returns the authorization server's pushed authorization request endpoint,
+// or an empty string if a pushed authorization request shouldn't be used. If a pushed authorization request is
+// required (either by the caller or by the authorization server) but no endpoint is available, then an error is
+// returned. openIDConfigErr is the error (if any) from fetching the authorization server's metadata. Unless the caller
+// requires a pushed authorization request, it's ignored.
+func (i *Interaction) pushedAuthorizationRequestEndpoint(required bool, openIDConfigErr error) (string, error) {
+	if openIDConfigErr != nil {
+		if !required {
+			return "", nil
+		}
+
+		return "", walleterror.NewExecutionError(
+			module,
+			IssuerOpenIDConfigFetchFailedCode,
+			IssuerOpenIDConfigFetchFailedError,
+			fmt.Errorf("failed to fetch issuer's OpenID configuration: %w", openIDConfigErr))
+	}
+
+	if !i.openIDConfig.PushedAuthorizationRequestsSupported() &&
+		(required || i.openIDConfig.RequirePushedAuthorizationRequests) {
+		return "", walleterror.NewExecutionError(
+			module,
+			PushedAuthorizationRequestFailedCode,
+			PushedAuthorizationRequestFailedError,
+			errors.New("a pushed authorization request is required, but the authorization server's metadata "+
+				"doesn't specify a pushed authorization request endpoint"))
+	}
+
+	return i.openIDConfig.PushedAuthorizationRequestEndpoint, nil
+}
+
+// pushAuthorizationRequest sends the parameters from the given authorization URL to the pushed authorization
+// request endpoint, and then returns a new authorization URL that refers to them using the request URI returned by
+// the authorization server.
+func (i *Interaction) pushAuthorizationRequest(ctx context.Context, pushedAuthorizationRequestEndpoint,
+	authURL string,
+) (string, error) {
+	parsedAuthURL, err := url.Parse(authURL)
+	if err != nil {
+		return "", err
+	}
+
+	params := parsedAuthURL.Query()
+	headers := http.Header{}
+
+	// Per https://datatracker.ietf.org/doc/html/rfc9126#section-2.1, confidential clients authenticate at the pushed
+	// authorization request endpoint in the same way as at the token endpoint.
+	if i.clientAuthentication != nil {
+		err = i.clientAuthentication.addToTokenRequest(params, headers, i.openIDConfig)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	responseBytes, err := httprequest.New(i.httpClient, i.metricsLogger).DoWithHeadersAndExpectedStatusCodes(ctx,
+		http.MethodPost, pushedAuthorizationRequestEndpoint, "application/x-www-form-urlencoded", headers,
+		strings.NewReader(params.Encode()), []int{http.StatusCreated, http.StatusOK},
+		fmt.Sprintf(pushAuthorizationRequestViaPOSTReqEventText, pushedAuthorizationRequestEndpoint),
+		authorizationEventText)
+	if err != nil {
+		return "", walleterror.NewExecutionError(
+			module,
+			PushedAuthorizationRequestFailedCode,
+			PushedAuthorizationRequestFailedError,
+			fmt.Errorf("pushed authorization request endpoint: %w", err))
+	}
+
+	var response pushedAuthorizationResponse
+
+	err = json.Unmarshal(responseBytes, &response)
+	if err != nil {
+		return "", walleterror.NewExecutionError(
+			module,
+			PushedAuthorizationRequestFailedCode,
+			PushedAuthorizationRequestFailedError,
+			fmt.Errorf("failed to unmarshal response from the pushed authorization request endpoint: %w", err))
+	}
+
+	if response.RequestURI == "" {
+		return "", walleterror.NewExecutionError(
+			module,
+			PushedAuthorizationRequestFailedCode,
+			PushedAuthorizationRequestFailedError,
+			errors.New("response from the pushed authorization request endpoint is missing the request URI"))
+	}
+
+	// Any query parameters that are part of the authorization endpoint itself must be retained.
+	parsedAuthEndpoint, err := url.Parse(i.oAuth2Config.Endpoint.AuthURL)
+	if err != nil {
+		return "", err
+	}
+
+	query := parsedAuthEndpoint.Query()
+	query.Set("client_id", i.clientID)
+	query.Set("request_uri", response.RequestURI)
+
+	parsedAuthEndpoint.RawQuery = query.Encode()
+
+	return parsedAuthEndpoint.String(), nil
+}
@@ -0,0 +1,381 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci_test
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/wallet-sdk/internal/testutil"
+	"github.com/trustbloc/wallet-sdk/pkg/openid4ci"
+)
+
+type mockPARIssuerServerHandler struct {
+	t                         *testing.T
+	openIDConfig              string
+	parResponseStatusCode     int
+	parResponse               string
+	receivedPARRequestForm    url.Values
+	receivedPARAuthorization  string
+	receivedTokenRequestForms []url.Values
+}
+
+func (m *mockPARIssuerServerHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	var err error
+
+	switch request.URL.Path {
+	case "/.well-known/openid-configuration":
+		if m.openIDConfig == "" {
+			writer.WriteHeader(http.StatusInternalServerError)
+
+			break
+		}
+
+		_, err = fmt.Fprintf(writer, m.openIDConfig, "http://"+request.Host)
+	case "/.well-known/openid-credential-issuer":
+		_, err = fmt.Fprintf(writer, sampleDraft13IssuerMetadata, "http://"+request.Host)
+	case "/oidc/par":
+		require.NoError(m.t, request.ParseForm())
+
+		m.receivedPARRequestForm = request.PostForm
+		m.receivedPARAuthorization = request.Header.Get("Authorization")
+
+		writer.WriteHeader(m.parResponseStatusCode)
+		_, err = writer.Write([]byte(m.parResponse))
+	case "/oidc/token":
+		require.NoError(m.t, request.ParseForm())
+
+		m.receivedTokenRequestForms = append(m.receivedTokenRequestForms, request.PostForm)
+
+		writer.Header().Set("Content-Type", "application/json")
+		_, err = writer.Write([]byte(`{"access_token":"accessToken","token_type":"bearer","c_nonce":"nonce"}`))
+	case "/credential":
+		_, err = writer.Write(sampleCredentialResponse)
+	}
+
+	require.NoError(m.t, err)
+}
+
+func TestInteraction_CreateAuthorizationURL_PAR(t *testing.T) {
+	const (
+		openIDConfigWithPAR = `{"token_endpoint":"%[1]s/oidc/token",` +
+			`"pushed_authorization_request_endpoint":"%[1]s/oidc/par"}`
+		openIDConfigWithoutPAR = `{"token_endpoint":"%[1]s/oidc/token"}`
+		sampleRequestURI       = "urn:ietf:params:oauth:request_uri:6esc_11ACC5bwc014ltc14eY22c"
+	)
+
+	t.Run("Authorization server supports PAR", func(t *testing.T) {
+		handler := &mockPARIssuerServerHandler{
+			t:                     t,
+			openIDConfig:          openIDConfigWithPAR,
+			parResponseStatusCode: http.StatusCreated,
+			parResponse:           fmt.Sprintf(`{"request_uri":"%s","expires_in":60}`, sampleRequestURI),
+		}
+
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		interaction := newInteraction(t, toCredentialOfferIssuanceURI(t, createDraft13AuthCodeCredentialOffer(
+			server.URL)))
+
+		authURL, err := interaction.CreateAuthorizationURL("clientID", "redirectURI",
+			openid4ci.WithScopes([]string{"scope1"}))
+		require.NoError(t, err)
+
+		parsedAuthURL, err := url.Parse(authURL)
+		require.NoError(t, err)
+		require.Equal(t, server.URL+"/oidc/authorize", parsedAuthURL.Scheme+"://"+parsedAuthURL.Host+
+			parsedAuthURL.Path)
+		require.Equal(t, url.Values{"client_id": {"clientID"}, "request_uri": {sampleRequestURI}},
+			parsedAuthURL.Query())
+
+		parRequestForm := handler.receivedPARRequestForm
+		require.Equal(t, "clientID", parRequestForm.Get("client_id"))
+		require.Equal(t, "code", parRequestForm.Get("response_type"))
+		require.Equal(t, "redirectURI", parRequestForm.Get("redirect_uri"))
+		require.Equal(t, "scope1", parRequestForm.Get("scope"))
+		require.Equal(t, "S256", parRequestForm.Get("code_challenge_method"))
+		require.NotEmpty(t, parRequestForm.Get("code_challenge"))
+		require.Contains(t, parRequestForm.Get("authorization_details"), sampleCredentialConfigurationID)
+		require.NotEmpty(t, parRequestForm.Get("state"))
+
+		credentials, err := interaction.RequestCredentialWithAuth(&jwtSignerMock{keyID: mockKeyID},
+			"redirectURI?code=1234&state="+parRequestForm.Get("state"))
+		require.NoError(t, err)
+		require.Len(t, credentials, 1)
+
+		require.Len(t, handler.receivedTokenRequestForms, 1)
+		require.NotEmpty(t, handler.receivedTokenRequestForms[0].Get("code_verifier"))
+	})
+	t.Run("PAR endpoint responds with 200 instead of 201", func(t *testing.T) {
+		server := httptest.NewServer(&mockPARIssuerServerHandler{
+			t:                     t,
+			openIDConfig:          openIDConfigWithPAR,
+			parResponseStatusCode: http.StatusOK,
+			parResponse:           fmt.Sprintf(`{"request_uri":"%s","expires_in":60}`, sampleRequestURI),
+		})
+		defer server.Close()
+
+		interaction := newInteraction(t, toCredentialOfferIssuanceURI(t, createDraft13AuthCodeCredentialOffer(
+			server.URL)))
+
+		authURL, err := interaction.CreateAuthorizationURL("clientID", "redirectURI")
+		require.NoError(t, err)
+		require.Contains(t, authURL, url.QueryEscape(sampleRequestURI))
+	})
+	t.Run("Client authentication at the PAR endpoint", func(t *testing.T) {
+		const clientSecret = "clientSecret"
+
+		attestation := createTestClientAttestation(t, `{"iss":"https://wallet-provider.example.com",`+
+			`"sub":"clientID","cnf":{"jwk":{"kty":"OKP","crv":"Ed25519","x":"abc"}}}`)
+
+		testCases := []struct {
+			name                  string
+			configure             func(config *openid4ci.ClientConfig)
+			expectedAuthorization string
+			checkForm             func(t *testing.T, form url.Values)
+		}{
+			{
+				name: "client_secret_basic",
+				configure: func(config *openid4ci.ClientConfig) {
+					config.ClientAuthentication = &openid4ci.ClientAuthentication{
+						ClientID:     "clientID",
+						Method:       openid4ci.ClientAuthMethodClientSecretBasic,
+						ClientSecret: clientSecret,
+					}
+				},
+				expectedAuthorization: "Basic " + base64.StdEncoding.EncodeToString([]byte("clientID:"+clientSecret)),
+				checkForm: func(t *testing.T, form url.Values) {
+					t.Helper()
+
+					require.False(t, form.Has("client_secret"))
+				},
+			},
+			{
+				name: "client_secret_post",
+				configure: func(config *openid4ci.ClientConfig) {
+					config.ClientAuthentication = &openid4ci.ClientAuthentication{
+						ClientID:     "clientID",
+						Method:       openid4ci.ClientAuthMethodClientSecretPost,
+						ClientSecret: clientSecret,
+					}
+				},
+				checkForm: func(t *testing.T, form url.Values) {
+					t.Helper()
+
+					require.Equal(t, clientSecret, form.Get("client_secret"))
+				},
+			},
+			{
+				name: "private_key_jwt",
+				configure: func(config *openid4ci.ClientConfig) {
+					config.ClientAuthentication = &openid4ci.ClientAuthentication{
+						ClientID: "clientID",
+						Method:   openid4ci.ClientAuthMethodPrivateKeyJWT,
+						Signer:   &jwtSignerMock{keyID: mockKeyID},
+					}
+				},
+				checkForm: func(t *testing.T, form url.Values) {
+					t.Helper()
+
+					require.Equal(t, "urn:ietf:params:oauth:client-assertion-type:jwt-bearer",
+						form.Get("client_assertion_type"))
+
+					assertion := parseDPoPProof(t, form.Get("client_assertion"))
+					require.Equal(t, "clientID", assertion.claims["sub"])
+				},
+			},
+			{
+				name: "attest_jwt_client_auth",
+				configure: func(config *openid4ci.ClientConfig) {
+					config.ClientAttestation = &openid4ci.ClientAttestationConfig{
+						Attestation: attestation,
+						Signer:      &jwtSignerMock{keyID: mockKeyID},
+					}
+				},
+				checkForm: func(t *testing.T, form url.Values) {
+					t.Helper()
+
+					require.Equal(t, "urn:ietf:params:oauth:client-assertion-type:jwt-client-attestation",
+						form.Get("client_assertion_type"))
+					require.True(t, strings.HasPrefix(form.Get("client_assertion"), attestation+"~"))
+				},
+			},
+		}
+
+		for _, testCase := range testCases {
+			t.Run(testCase.name, func(t *testing.T) {
+				handler := &mockPARIssuerServerHandler{
+					t:                     t,
+					openIDConfig:          openIDConfigWithPAR,
+					parResponseStatusCode: http.StatusCreated,
+					parResponse:           fmt.Sprintf(`{"request_uri":"%s","expires_in":60}`, sampleRequestURI),
+				}
+
+				server := httptest.NewServer(handler)
+				defer server.Close()
+
+				config := getTestClientConfig(t)
+				testCase.configure(config)
+
+				interaction, err := openid4ci.NewInteraction(toCredentialOfferIssuanceURI(t,
+					createDraft13AuthCodeCredentialOffer(server.URL)), config)
+				require.NoError(t, err)
+
+				_, err = interaction.CreateAuthorizationURL("clientID", "redirectURI")
+				require.NoError(t, err)
+
+				require.Equal(t, testCase.expectedAuthorization, handler.receivedPARAuthorization)
+				require.Equal(t, "clientID", handler.receivedPARRequestForm.Get("client_id"))
+				require.Equal(t, "code", handler.receivedPARRequestForm.Get("response_type"))
+				testCase.checkForm(t, handler.receivedPARRequestForm)
+			})
+		}
+	})
+	t.Run("Client authentication method not supported by the authorization server", func(t *testing.T) {
+		handler := &mockPARIssuerServerHandler{
+			t: t,
+			openIDConfig: `{"token_endpoint":"%[1]s/oidc/token",` +
+				`"pushed_authorization_request_endpoint":"%[1]s/oidc/par",` +
+				`"token_endpoint_auth_methods_supported":["private_key_jwt"]}`,
+			parResponseStatusCode: http.StatusCreated,
+			parResponse:           fmt.Sprintf(`{"request_uri":"%s","expires_in":60}`, sampleRequestURI),
+		}
+
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		interaction := newInteractionWithClientAuth(t,
+			toCredentialOfferIssuanceURI(t, createDraft13AuthCodeCredentialOffer(server.URL)),
+			&openid4ci.ClientAuthentication{
+				ClientID:     "clientID",
+				Method:       openid4ci.ClientAuthMethodClientSecretBasic,
+				ClientSecret: "clientSecret",
+			})
+
+		authURL, err := interaction.CreateAuthorizationURL("clientID", "redirectURI")
+		require.EqualError(t, err, "INVALID_CLIENT_AUTHENTICATION(OCI0-0021):the authorization server doesn't "+
+			"support the client_secret_basic token endpoint authentication method (supported methods: private_key_jwt)")
+		require.Empty(t, authURL)
+		require.Nil(t, handler.receivedPARRequestForm)
+	})
+	t.Run("Authorization server doesn't support PAR", func(t *testing.T) {
+		handler := &mockPARIssuerServerHandler{t: t, openIDConfig: openIDConfigWithoutPAR}
+
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		interaction := newInteraction(t, toCredentialOfferIssuanceURI(t, createDraft13AuthCodeCredentialOffer(
+			server.URL)))
+
+		authURL, err := interaction.CreateAuthorizationURL("clientID", "redirectURI")
+		require.NoError(t, err)
+		require.Contains(t, authURL, "authorization_details=")
+		require.Nil(t, handler.receivedPARRequestForm)
+
+		t.Run("PAR required by caller", func(t *testing.T) {
+			authURL, err := interaction.CreateAuthorizationURL("clientID", "redirectURI",
+				openid4ci.WithPushedAuthorizationRequest())
+			require.EqualError(t, err, "PUSHED_AUTHORIZATION_REQUEST_FAILED(OCI1-0020):a pushed authorization "+
+				"request is required, but the authorization server's metadata doesn't specify a pushed "+
+				"authorization request endpoint")
+			require.Empty(t, authURL)
+		})
+	})
+	t.Run("PAR required by authorization server but endpoint missing", func(t *testing.T) {
+		server := httptest.NewServer(&mockPARIssuerServerHandler{
+			t:            t,
+			openIDConfig: `{"token_endpoint":"%[1]s/oidc/token","require_pushed_authorization_requests":true}`,
+		})
+		defer server.Close()
+
+		interaction := newInteraction(t, toCredentialOfferIssuanceURI(t, createDraft13AuthCodeCredentialOffer(
+			server.URL)))
+
+		authURL, err := interaction.CreateAuthorizationURL("clientID", "redirectURI")
+		testutil.RequireErrorContains(t, err, "PUSHED_AUTHORIZATION_REQUEST_FAILED")
+		require.Empty(t, authURL)
+	})
+	t.Run("PAR required by caller but OpenID config fetch fails", func(t *testing.T) {
+		server := httptest.NewServer(&mockPARIssuerServerHandler{t: t})
+		defer server.Close()
+
+		interaction := newInteraction(t, toCredentialOfferIssuanceURI(t, createDraft13AuthCodeCredentialOffer(
+			server.URL)))
+
+		authURL, err := interaction.CreateAuthorizationURL("clientID", "redirectURI",
+			openid4ci.WithPushedAuthorizationRequest())
+		testutil.RequireErrorContains(t, err, "ISSUER_OPENID_FETCH_FAILED")
+		require.Empty(t, authURL)
+	})
+	t.Run("PAR endpoint returns an error", func(t *testing.T) {
+		server := httptest.NewServer(&mockPARIssuerServerHandler{
+			t:                     t,
+			openIDConfig:          openIDConfigWithPAR,
+			parResponseStatusCode: http.StatusBadRequest,
+			parResponse:           `{"error":"invalid_request"}`,
+		})
+		defer server.Close()
+
+		interaction := newInteraction(t, toCredentialOfferIssuanceURI(t, createDraft13AuthCodeCredentialOffer(
+			server.URL)))
+
+		authURL, err := interaction.CreateAuthorizationURL("clientID", "redirectURI")
+		require.EqualError(t, err, "PUSHED_AUTHORIZATION_REQUEST_FAILED(OCI1-0020):pushed authorization "+
+			"request endpoint: expected status code 201 or 200 but got status code 400 with response body "+
+			`{"error":"invalid_request"} instead`)
+		require.Empty(t, authURL)
+	})
+	t.Run("PAR endpoint returns an invalid response", func(t *testing.T) {
+		server := httptest.NewServer(&mockPARIssuerServerHandler{
+			t:                     t,
+			openIDConfig:          openIDConfigWithPAR,
+			parResponseStatusCode: http.StatusCreated,
+			parResponse:           "invalid",
+		})
+		defer server.Close()
+
+		interaction := newInteraction(t, toCredentialOfferIssuanceURI(t, createDraft13AuthCodeCredentialOffer(
+			server.URL)))
+
+		authURL, err := interaction.CreateAuthorizationURL("clientID", "redirectURI")
+		testutil.RequireErrorContains(t, err, "failed to unmarshal response from the pushed authorization "+
+			"request endpoint")
+		require.Empty(t, authURL)
+	})
+	t.Run("PAR endpoint response is missing the request URI", func(t *testing.T) {
+		server := httptest.NewServer(&mockPARIssuerServerHandler{
+			t:                     t,
+			openIDConfig:          openIDConfigWithPAR,
+			parResponseStatusCode: http.StatusCreated,
+			parResponse:           `{"expires_in":60}`,
+		})
+		defer server.Close()
+
+		interaction := newInteraction(t, toCredentialOfferIssuanceURI(t, createDraft13AuthCodeCredentialOffer(
+			server.URL)))
+
+		authURL, err := interaction.CreateAuthorizationURL("clientID", "redirectURI")
+		require.EqualError(t, err, "PUSHED_AUTHORIZATION_REQUEST_FAILED(OCI1-0020):response from the pushed "+
+			"authorization request endpoint is missing the request URI")
+		require.Empty(t, authURL)
+	})
+}
+
+func createDraft13AuthCodeCredentialOffer(issuerURL string) *openid4ci.CredentialOffer {
+	credentialOffer := createDraft13CredentialOffer(issuerURL, nil)
+	credentialOffer.Grants = map[string]map[string]interface{}{"authorization_code": {}}
+
+	return credentialOffer
+}
@@ -0,0 +1,361 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/aries-framework-go/component/kmscrypto/doc/jose"
+	"github.com/hyperledger/aries-framework-go/component/models/did"
+	arieskms "github.com/hyperledger/aries-framework-go/spi/kms"
+
+	"github.com/trustbloc/wallet-sdk/pkg/api"
+	"github.com/trustbloc/wallet-sdk/pkg/did/creator"
+	metadatafetcher "github.com/trustbloc/wallet-sdk/pkg/internal/issuermetadata"
+	"github.com/trustbloc/wallet-sdk/pkg/models/issuer"
+	"github.com/trustbloc/wallet-sdk/pkg/walleterror"
+)
+
+const (
+	// anyDIDBindingMethod is used by issuers to indicate that any DID method is accepted.
+	anyDIDBindingMethod = "did"
+	jwkBindingMethod    = "jwk"
+	x5cBindingMethod    = "x5c"
+	jwtProofType        = "jwt"
+)
+
+// The DID methods that ProofRequirements.CreateDID can create, in order of preference.
+var creatableDIDMethods = []string{ //nolint:gochecknoglobals // read-only
+	creator.DIDMethodKey, creator.DIDMethodJWK, creator.DIDMethodIon,
+}
+
+// The key types to use for each proof signing algorithm when creating a DID.
+var keyTypesForAlgorithms = map[string]arieskms.KeyType{ //nolint:gochecknoglobals // read-only
+	"EdDSA":  arieskms.ED25519Type,
+	"ES256":  arieskms.ECDSAP256TypeIEEEP1363,
+	"ES384":  arieskms.ECDSAP384TypeIEEEP1363,
+	"ES256K": arieskms.ECDSASecp256k1TypeIEEEP1363,
+}
+
+// ProofRequirements describes the cryptographic binding methods and proof signing algorithms that an issuer accepts
+// for the offered credentials. Since a single proof is used for all of them, these are the requirements that all
+// offered credentials have in common.
+type ProofRequirements struct {
+	// BindingMethods are the accepted cryptographic binding methods, e.g. "did:key", "did:ion" or "jwk".
+	// "did" means that any DID method is accepted. An empty list means that the issuer didn't restrict them.
+	BindingMethods []string
+	// SigningAlgorithms are the accepted proof signing algorithms, e.g. "EdDSA" or "ES256".
+	// An empty list means that the issuer didn't restrict them.
+	SigningAlgorithms []string
+}
+
+// ProofRequirements returns the cryptographic binding methods and proof signing algorithms that the issuer accepts
+// for the offered credentials, as specified in the issuer's metadata. The issuer's metadata is fetched if needed.
+func (i *Interaction) ProofRequirements() (*ProofRequirements, error) {
+	return i.ProofRequirementsContext(context.Background())
+}
+
+// ProofRequirementsContext is the same as ProofRequirements, except that the given context is used for fetching the
+// issuer's metadata.
+func (i *Interaction) ProofRequirementsContext(ctx context.Context) (*ProofRequirements, error) {
+	err := i.fetchIssuerMetadataIfNeeded(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return i.proofRequirements()
+}
+
+// SupportsDIDMethod indicates whether the given DID method (e.g. "key" or "ion") is accepted.
+func (r *ProofRequirements) SupportsDIDMethod(didMethod string) bool {
+	return r.SupportsBindingMethod("did:" + didMethod)
+}
+
+// SupportsBindingMethod indicates whether the given cryptographic binding method (e.g. "did:key" or "jwk") is
+// accepted. Since an X.509 certificate chain conveys a public key, "x5c" is accepted wherever "jwk" is.
+func (r *ProofRequirements) SupportsBindingMethod(bindingMethod string) bool {
+	if len(r.BindingMethods) == 0 {
+		return true
+	}
+
+	for _, acceptedBindingMethod := range r.BindingMethods {
+		if acceptedBindingMethod == bindingMethod ||
+			(acceptedBindingMethod == anyDIDBindingMethod && strings.HasPrefix(bindingMethod, "did:")) ||
+			(acceptedBindingMethod == jwkBindingMethod && bindingMethod == x5cBindingMethod) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SupportsAlgorithm indicates whether the given proof signing algorithm (e.g. "EdDSA" or "ES256") is accepted.
+func (r *ProofRequirements) SupportsAlgorithm(algorithm string) bool {
+	return len(r.SigningAlgorithms) == 0 || contains(r.SigningAlgorithms, algorithm)
+}
+
+// DIDMethod returns a DID method that's accepted and that Wallet-SDK can create, or an empty string if there's
+// no such method.
+func (r *ProofRequirements) DIDMethod() string {
+	for _, didMethod := range creatableDIDMethods {
+		if r.SupportsDIDMethod(didMethod) {
+			return didMethod
+		}
+	}
+
+	return ""
+}
+
+// KeyType returns a key type that can be used to create a DID whose key is accepted for signing proofs. An empty
+// string is returned if the issuer didn't restrict the algorithms, in which case the DID creator's default is fine.
+// An error is returned if none of the accepted algorithms are supported by Wallet-SDK.
+func (r *ProofRequirements) KeyType() (arieskms.KeyType, error) {
+	if len(r.SigningAlgorithms) == 0 {
+		return "", nil
+	}
+
+	for _, algorithm := range r.SigningAlgorithms {
+		if keyType, found := keyTypesForAlgorithms[algorithm]; found {
+			return keyType, nil
+		}
+	}
+
+	return "", fmt.Errorf("none of the accepted proof signing algorithms %v are supported", r.SigningAlgorithms)
+}
+
+// CreateDID creates a DID using a DID method and key type that satisfy these requirements.
+func (r *ProofRequirements) CreateDID(didCreator api.DIDCreator, metricsLogger api.MetricsLogger,
+) (*did.DocResolution, error) {
+	didMethod := r.DIDMethod()
+	if didMethod == "" {
+		return nil, walleterror.NewValidationError(
+			module,
+			IncompatibleSignerCode,
+			IncompatibleSignerError,
+			fmt.Errorf("none of the accepted binding methods %v can be created", r.BindingMethods))
+	}
+
+	keyType, err := r.KeyType()
+	if err != nil {
+		return nil, walleterror.NewValidationError(
+			module,
+			IncompatibleSignerCode,
+			IncompatibleSignerError,
+			err)
+	}
+
+	return didCreator.Create(didMethod, &api.CreateDIDOpts{KeyType: keyType, MetricsLogger: metricsLogger})
+}
+
+// validateSignerMeetsProofRequirements checks the signer's binding method and algorithm against the issuer's proof
+// requirements, so that an incompatible signer is caught before any requests are made to the issuer's token or
+// credential endpoints.
+func (i *Interaction) validateSignerMeetsProofRequirements(ctx context.Context, signer api.JWTSigner) error {
+	if i.issuerMetadata == nil {
+		var err error
+
+		i.issuerMetadata, err = metadatafetcher.Get(ctx, i.issuerURI, i.httpClient, i.metricsLogger,
+			requestCredentialEventText)
+		if err != nil {
+			return walleterror.NewExecutionError(
+				module,
+				MetadataFetchFailedCode,
+				MetadataFetchFailedError,
+				fmt.Errorf("failed to get issuer metadata: %w", err))
+		}
+	}
+
+	requirements, err := i.proofRequirements()
+	if err != nil {
+		return err
+	}
+
+	var incompatibilityErr error
+
+	bindingMethod := keyBindingMethod(signer)
+	if bindingMethod == "" {
+		// validateSignerKeyID has already checked that the key ID is a DID URL.
+		bindingMethod = "did:" + strings.Split(strings.TrimPrefix(signer.GetKeyID(), "did:"), ":")[0]
+	}
+
+	if !requirements.SupportsBindingMethod(bindingMethod) {
+		incompatibilityErr = fmt.Errorf("the signer's binding method (%s) isn't one of the accepted binding "+
+			"methods %v", bindingMethod, requirements.BindingMethods)
+	} else if algorithm, ok := signer.Headers()[jose.HeaderAlgorithm].(string); ok &&
+		!requirements.SupportsAlgorithm(algorithm) {
+		incompatibilityErr = fmt.Errorf("the signer's algorithm (%s) isn't one of the accepted proof signing "+
+			"algorithms %v", algorithm, requirements.SigningAlgorithms)
+	}
+
+	if incompatibilityErr != nil {
+		return walleterror.NewValidationError(
+			module,
+			IncompatibleSignerCode,
+			IncompatibleSignerError,
+			incompatibilityErr)
+	}
+
+	return nil
+}
+
+// keyBindingMethod returns the cryptographic binding method used by a signer whose key isn't associated with a DID
+// ("jwk" or "x5c", depending on how the key is identified in its JWS headers), or an empty string if the signer uses
+// a DID URL as its key ID.
+func keyBindingMethod(signer api.JWTSigner) string {
+	headers := signer.Headers()
+
+	if _, found := headers[jose.HeaderX509CertificateChain]; found {
+		return x5cBindingMethod
+	}
+
+	if _, found := headers[jose.HeaderJSONWebKey]; found {
+		return jwkBindingMethod
+	}
+
+	return ""
+}
+
+func (i *Interaction) proofRequirements() (*ProofRequirements, error) {
+	requirements := &ProofRequirements{}
+
+	for index := range i.credentialTypes {
+		supportedCredential := i.supportedCredential(index)
+		if supportedCredential == nil {
+			continue
+		}
+
+		bindingMethods := supportedCredential.CryptographicBindingMethodsSupported
+		signingAlgorithms := proofSigningAlgorithms(supportedCredential, len(i.credentialConfigurationIDs) > 0)
+
+		if len(bindingMethods) > 0 {
+			requirements.BindingMethods = intersectBindingMethods(requirements.BindingMethods, bindingMethods)
+			if len(requirements.BindingMethods) == 0 {
+				return nil, newConflictingProofRequirementsError("cryptographic binding methods")
+			}
+		}
+
+		if len(signingAlgorithms) > 0 {
+			requirements.SigningAlgorithms = intersect(requirements.SigningAlgorithms, signingAlgorithms)
+			if len(requirements.SigningAlgorithms) == 0 {
+				return nil, newConflictingProofRequirementsError("proof signing algorithms")
+			}
+		}
+	}
+
+	return requirements, nil
+}
+
+// supportedCredential returns the issuer's metadata for the offered credential at the given index, or nil if it
+// isn't listed in the metadata.
+func (i *Interaction) supportedCredential(index int) *issuer.SupportedCredential {
+	if len(i.credentialConfigurationIDs) > 0 {
+		supportedCredential, _ := i.issuerMetadata.SupportedCredential(i.credentialConfigurationIDs[index])
+
+		return supportedCredential
+	}
+
+	for j := range i.issuerMetadata.CredentialsSupported {
+		supportedCredential := &i.issuerMetadata.CredentialsSupported[j]
+
+		if supportedCredential.Format == i.credentialFormats[index] &&
+			sameElements(supportedCredential.Types, i.credentialTypes[index]) {
+			return supportedCredential
+		}
+	}
+
+	return nil
+}
+
+// proofSigningAlgorithms returns the proof signing algorithms that the issuer accepts for the given credential.
+// For draft 13 (and later), they're in the jwt proof type's metadata. For draft 11, cryptographic_suites_supported is
+// used, but only for JWT credential formats since for other formats the values are suite names, not algorithms.
+func proofSigningAlgorithms(supportedCredential *issuer.SupportedCredential, draft13 bool) []string {
+	if draft13 {
+		return supportedCredential.ProofTypesSupported[jwtProofType].ProofSigningAlgValuesSupported
+	}
+
+	if strings.HasPrefix(supportedCredential.Format, "jwt_vc") {
+		return supportedCredential.CryptographicSuitesSupported
+	}
+
+	return nil
+}
+
+func newConflictingProofRequirementsError(requirement string) error {
+	return walleterror.NewValidationError(
+		module,
+		InvalidCredentialOfferCode,
+		InvalidCredentialOfferError,
+		errors.New("the offered credentials don't have any accepted "+requirement+" in common"))
+}
+
+// intersect returns the values in b that are also in a. If a is nil, then b is returned as-is.
+func intersect(a, b []string) []string {
+	if a == nil {
+		return b
+	}
+
+	intersection := []string{}
+
+	for _, value := range b {
+		if contains(a, value) {
+			intersection = append(intersection, value)
+		}
+	}
+
+	return intersection
+}
+
+// intersectBindingMethods works like intersect, except that "did" (any DID method) matches any specific DID method.
+func intersectBindingMethods(a, b []string) []string {
+	if a == nil {
+		return b
+	}
+
+	intersection := intersect(a, b)
+
+	for _, value := range a {
+		if strings.HasPrefix(value, "did:") && contains(b, anyDIDBindingMethod) && !contains(intersection, value) {
+			intersection = append(intersection, value)
+		}
+	}
+
+	for _, value := range b {
+		if strings.HasPrefix(value, "did:") && contains(a, anyDIDBindingMethod) && !contains(intersection, value) {
+			intersection = append(intersection, value)
+		}
+	}
+
+	return intersection
+}
+
+func sameElements(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for _, value := range a {
+		if !contains(b, value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
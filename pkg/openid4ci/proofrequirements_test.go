@@ -0,0 +1,300 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/component/models/did"
+	arieskms "github.com/hyperledger/aries-framework-go/spi/kms"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/wallet-sdk/internal/testutil"
+	"github.com/trustbloc/wallet-sdk/pkg/api"
+	"github.com/trustbloc/wallet-sdk/pkg/openid4ci"
+)
+
+type didCreatorMock struct {
+	receivedMethod string
+	receivedOpts   *api.CreateDIDOpts
+}
+
+func (d *didCreatorMock) Create(method string, createDIDOpts *api.CreateDIDOpts) (*did.DocResolution, error) {
+	d.receivedMethod = method
+	d.receivedOpts = createDIDOpts
+
+	return &did.DocResolution{DIDDocument: &did.Doc{ID: "did:" + method + ":123"}}, nil
+}
+
+func TestInteraction_ProofRequirements(t *testing.T) {
+	t.Run("Draft 11 issuer metadata", func(t *testing.T) {
+		server := newProofRequirementsTestServer(t, `{"credential_endpoint":"%[1]s/credential",`+
+			`"credentials_supported":[{"format":"jwt_vc_json","types":["VerifiableCredential","VerifiedEmployee"],`+
+			`"cryptographic_binding_methods_supported":["did:ion","did:key"],`+
+			`"cryptographic_suites_supported":["ES256","EdDSA"]}]}`)
+		defer server.Close()
+
+		interaction := newInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, false))
+
+		requirements, err := interaction.ProofRequirements()
+		require.NoError(t, err)
+		require.Equal(t, []string{"did:ion", "did:key"}, requirements.BindingMethods)
+		require.Equal(t, []string{"ES256", "EdDSA"}, requirements.SigningAlgorithms)
+
+		require.True(t, requirements.SupportsDIDMethod("key"))
+		require.False(t, requirements.SupportsDIDMethod("jwk"))
+		require.True(t, requirements.SupportsAlgorithm("EdDSA"))
+		require.False(t, requirements.SupportsAlgorithm("ES384"))
+	})
+	t.Run("Draft 11 issuer metadata with cryptographic suites for a non-JWT format", func(t *testing.T) {
+		credentialOffer := createCredentialOffer(t, "", false)
+		credentialOffer.Credentials[0].Format = "ldp_vc"
+
+		server := newProofRequirementsTestServer(t, `{"credential_endpoint":"%[1]s/credential",`+
+			`"credentials_supported":[{"format":"ldp_vc","types":["VerifiableCredential","VerifiedEmployee"],`+
+			`"cryptographic_binding_methods_supported":["did"],`+
+			`"cryptographic_suites_supported":["Ed25519Signature2018"]}]}`)
+		defer server.Close()
+
+		credentialOffer.CredentialIssuer = server.URL
+
+		interaction := newInteraction(t, createDraft11CredentialOfferIssuanceURI(t, credentialOffer))
+
+		requirements, err := interaction.ProofRequirements()
+		require.NoError(t, err)
+		require.Equal(t, []string{"did"}, requirements.BindingMethods)
+		require.Empty(t, requirements.SigningAlgorithms)
+		require.True(t, requirements.SupportsDIDMethod("ion"))
+	})
+	t.Run("Draft 13 issuer metadata", func(t *testing.T) {
+		server := newProofRequirementsTestServer(t, `{"credential_issuer":"%[1]s",`+
+			`"credential_endpoint":"%[1]s/credential","credential_configurations_supported":`+
+			`{"VerifiedEmployee_JWT":{"format":"jwt_vc_json",`+
+			`"credential_definition":{"type":["VerifiableCredential","VerifiedEmployee"]},`+
+			`"cryptographic_binding_methods_supported":["jwk","did:jwk"],`+
+			`"credential_signing_alg_values_supported":["RS256"],`+
+			`"proof_types_supported":{"jwt":{"proof_signing_alg_values_supported":["ES384"]}}}}}`)
+		defer server.Close()
+
+		interaction := newInteraction(t, createDraft13CredentialOfferIssuanceURI(t, server.URL, nil))
+
+		requirements, err := interaction.ProofRequirements()
+		require.NoError(t, err)
+		require.Equal(t, []string{"jwk", "did:jwk"}, requirements.BindingMethods)
+		// The credential signing algorithms are about the issuer's signature, not the proof.
+		require.Equal(t, []string{"ES384"}, requirements.SigningAlgorithms)
+		require.Equal(t, "jwk", requirements.DIDMethod())
+	})
+	t.Run("Issuer doesn't restrict anything", func(t *testing.T) {
+		server := newProofRequirementsTestServer(t, `{"credential_endpoint":"%[1]s/credential"}`)
+		defer server.Close()
+
+		interaction := newInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, false))
+
+		requirements, err := interaction.ProofRequirements()
+		require.NoError(t, err)
+		require.Empty(t, requirements.BindingMethods)
+		require.Empty(t, requirements.SigningAlgorithms)
+		require.True(t, requirements.SupportsDIDMethod("example"))
+		require.True(t, requirements.SupportsAlgorithm("ES384"))
+	})
+	t.Run("Multiple offered credentials", func(t *testing.T) {
+		credentialOffer := createCredentialOffer(t, "", false)
+		credentialOffer.Credentials = append(credentialOffer.Credentials, credentialOffer.Credentials[0])
+		credentialOffer.Credentials[1].Types = []string{"VerifiableCredential", "PermanentResidentCard"}
+
+		t.Run("Requirements in common", func(t *testing.T) {
+			server := newProofRequirementsTestServer(t, `{"credential_endpoint":"%[1]s/credential",`+
+				`"credentials_supported":[`+
+				`{"format":"jwt_vc_json","types":["VerifiableCredential","VerifiedEmployee"],`+
+				`"cryptographic_binding_methods_supported":["did"],`+
+				`"cryptographic_suites_supported":["ES256","EdDSA"]},`+
+				`{"format":"jwt_vc_json","types":["PermanentResidentCard","VerifiableCredential"],`+
+				`"cryptographic_binding_methods_supported":["did:key","jwk"],`+
+				`"cryptographic_suites_supported":["EdDSA","ES384"]}]}`)
+			defer server.Close()
+
+			credentialOffer.CredentialIssuer = server.URL
+
+			interaction := newInteraction(t, createDraft11CredentialOfferIssuanceURI(t, credentialOffer))
+
+			requirements, err := interaction.ProofRequirements()
+			require.NoError(t, err)
+			require.Equal(t, []string{"did:key"}, requirements.BindingMethods)
+			require.Equal(t, []string{"EdDSA"}, requirements.SigningAlgorithms)
+		})
+		t.Run("No requirements in common", func(t *testing.T) {
+			server := newProofRequirementsTestServer(t, `{"credential_endpoint":"%[1]s/credential",`+
+				`"credentials_supported":[`+
+				`{"format":"jwt_vc_json","types":["VerifiableCredential","VerifiedEmployee"],`+
+				`"cryptographic_binding_methods_supported":["did:ion"]},`+
+				`{"format":"jwt_vc_json","types":["VerifiableCredential","PermanentResidentCard"],`+
+				`"cryptographic_binding_methods_supported":["did:key"]}]}`)
+			defer server.Close()
+
+			credentialOffer.CredentialIssuer = server.URL
+
+			interaction := newInteraction(t, createDraft11CredentialOfferIssuanceURI(t, credentialOffer))
+
+			requirements, err := interaction.ProofRequirements()
+			require.EqualError(t, err, "INVALID_CREDENTIAL_OFFER(OCI0-0003):the offered credentials don't have "+
+				"any accepted cryptographic binding methods in common")
+			require.Nil(t, requirements)
+		})
+	})
+	t.Run("Fail to fetch issuer metadata", func(t *testing.T) {
+		interaction := newInteraction(t, createCredentialOfferIssuanceURI(t, "example.com", false))
+
+		requirements, err := interaction.ProofRequirements()
+		testutil.RequireErrorContains(t, err, "METADATA_FETCH_FAILED")
+		require.Nil(t, requirements)
+	})
+}
+
+func TestInteraction_RequestCredential_IncompatibleSigner(t *testing.T) {
+	testCases := []struct {
+		name           string
+		issuerMetadata string
+		expectedErr    string
+	}{
+		{
+			name: "DID method isn't accepted",
+			issuerMetadata: `{"credential_endpoint":"%[1]s/credential","credentials_supported":[` +
+				`{"format":"jwt_vc_json","types":["VerifiableCredential","VerifiedEmployee"],` +
+				`"cryptographic_binding_methods_supported":["did:key","did:ion"]}]}`,
+			expectedErr: "INCOMPATIBLE_SIGNER(OCI0-0028):the signer's binding method (did:example) isn't one of " +
+				"the accepted binding methods [did:key did:ion]",
+		},
+		{
+			name: "Algorithm isn't accepted",
+			issuerMetadata: `{"credential_endpoint":"%[1]s/credential","credentials_supported":[` +
+				`{"format":"jwt_vc_json","types":["VerifiableCredential","VerifiedEmployee"],` +
+				`"cryptographic_binding_methods_supported":["did"],` +
+				`"cryptographic_suites_supported":["EdDSA","ES256"]}]}`,
+			expectedErr: "INCOMPATIBLE_SIGNER(OCI0-0028):the signer's algorithm (ES384) isn't one of the " +
+				"accepted proof signing algorithms [EdDSA ES256]",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			issuerServerHandler := &mockIssuerServerHandler{
+				t: t,
+				// The check should happen before the token endpoint is called.
+				tokenRequestShouldFail: true,
+			}
+
+			server := httptest.NewServer(issuerServerHandler)
+			defer server.Close()
+
+			issuerServerHandler.issuerMetadata = fmt.Sprintf(testCase.issuerMetadata, server.URL)
+
+			interaction := newInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, false))
+
+			credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{keyID: mockKeyID},
+				openid4ci.WithPIN("1234"))
+			require.EqualError(t, err, testCase.expectedErr)
+			require.Nil(t, credentials)
+		})
+	}
+}
+
+func TestProofRequirements_SupportsBindingMethod(t *testing.T) {
+	requirements := &openid4ci.ProofRequirements{BindingMethods: []string{"did", "jwk"}}
+
+	require.True(t, requirements.SupportsBindingMethod("did:key"))
+	require.True(t, requirements.SupportsBindingMethod("jwk"))
+	require.True(t, requirements.SupportsBindingMethod("x5c"))
+	require.False(t, requirements.SupportsBindingMethod("cose_key"))
+
+	requirements = &openid4ci.ProofRequirements{BindingMethods: []string{"did:ion"}}
+
+	require.True(t, requirements.SupportsBindingMethod("did:ion"))
+	require.False(t, requirements.SupportsBindingMethod("did:key"))
+	require.False(t, requirements.SupportsBindingMethod("jwk"))
+
+	require.True(t, (&openid4ci.ProofRequirements{}).SupportsBindingMethod("jwk"))
+}
+
+func TestProofRequirements_CreateDID(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		testCases := []struct {
+			name            string
+			requirements    *openid4ci.ProofRequirements
+			expectedMethod  string
+			expectedKeyType arieskms.KeyType
+		}{
+			{
+				name:           "No restrictions",
+				requirements:   &openid4ci.ProofRequirements{},
+				expectedMethod: "key",
+			},
+			{
+				name: "Restricted binding methods and algorithms",
+				requirements: &openid4ci.ProofRequirements{
+					BindingMethods:    []string{"did:web", "did:ion"},
+					SigningAlgorithms: []string{"PS256", "ES256"},
+				},
+				expectedMethod:  "ion",
+				expectedKeyType: arieskms.ECDSAP256TypeIEEEP1363,
+			},
+		}
+
+		for _, testCase := range testCases {
+			t.Run(testCase.name, func(t *testing.T) {
+				didCreator := &didCreatorMock{}
+
+				didDoc, err := testCase.requirements.CreateDID(didCreator, nil)
+				require.NoError(t, err)
+				require.Equal(t, "did:"+testCase.expectedMethod+":123", didDoc.DIDDocument.ID)
+				require.Equal(t, testCase.expectedMethod, didCreator.receivedMethod)
+				require.Equal(t, testCase.expectedKeyType, didCreator.receivedOpts.KeyType)
+			})
+		}
+	})
+	t.Run("No creatable DID method", func(t *testing.T) {
+		requirements := &openid4ci.ProofRequirements{BindingMethods: []string{"did:web"}}
+
+		didDoc, err := requirements.CreateDID(&didCreatorMock{}, nil)
+		require.EqualError(t, err, "INCOMPATIBLE_SIGNER(OCI0-0028):none of the accepted binding methods "+
+			"[did:web] can be created")
+		require.Nil(t, didDoc)
+	})
+	t.Run("No supported algorithm", func(t *testing.T) {
+		requirements := &openid4ci.ProofRequirements{SigningAlgorithms: []string{"PS256"}}
+
+		didDoc, err := requirements.CreateDID(&didCreatorMock{}, nil)
+		require.EqualError(t, err, "INCOMPATIBLE_SIGNER(OCI0-0028):none of the accepted proof signing "+
+			"algorithms [PS256] are supported")
+		require.Nil(t, didDoc)
+	})
+}
+
+func newProofRequirementsTestServer(t *testing.T, issuerMetadata string) *httptest.Server {
+	t.Helper()
+
+	issuerServerHandler := &mockIssuerServerHandler{t: t}
+
+	server := httptest.NewServer(issuerServerHandler)
+
+	issuerServerHandler.issuerMetadata = fmt.Sprintf(issuerMetadata, server.URL)
+
+	return server
+}
+
+func createDraft11CredentialOfferIssuanceURI(t *testing.T, credentialOffer *openid4ci.CredentialOffer) string {
+	t.Helper()
+
+	credentialOfferBytes, err := json.Marshal(credentialOffer)
+	require.NoError(t, err)
+
+	return "openid-vc://?credential_offer=" + url.QueryEscape(string(credentialOfferBytes))
+}
@@ -0,0 +1,331 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hyperledger/aries-framework-go/component/models/verifiable"
+
+	"github.com/trustbloc/wallet-sdk/pkg/api"
+	"github.com/trustbloc/wallet-sdk/pkg/internal/httprequest"
+	"github.com/trustbloc/wallet-sdk/pkg/models/issuer"
+	"github.com/trustbloc/wallet-sdk/pkg/walleterror"
+)
+
+const (
+	reissueCredentialEventText = "Reissue credential from issuer"
+	//nolint:gosec //false positive
+	fetchTokenUsingRefreshTokenViaPOSTReqEventText = "Fetch token using refresh token via an HTTP POST request to %s"
+	//nolint:gosec //false positive
+	fetchReissuedCredentialViaPOSTReqEventText = "Fetch reissued credential via an HTTP POST request to %s"
+
+	refreshTokenGrantType = "refresh_token"
+)
+
+// ReissuanceToken holds a refresh token that an issuer provided along with a credential, plus everything else
+// needed to request a new copy of that credential later on without needing a new credential offer.
+// It's bound to a specific credential (identified by CredentialID) from a specific issuer.
+// It can be persisted (e.g. serialized to JSON) alongside the credential, but should be treated as sensitive data.
+type ReissuanceToken struct {
+	CredentialID       string   `json:"credential_id,omitempty"`
+	IssuerURI          string   `json:"issuer_uri,omitempty"`
+	TokenEndpoint      string   `json:"token_endpoint,omitempty"`
+	CredentialEndpoint string   `json:"credential_endpoint,omitempty"`
+	ClientID           string   `json:"client_id,omitempty"`
+	Format             string   `json:"format,omitempty"`
+	Types              []string `json:"types,omitempty"`
+	RefreshToken       string   `json:"refresh_token,omitempty"`
+	// CredentialConfigurationID is only set for issuers following draft 13 (or later).
+	CredentialConfigurationID string `json:"credential_configuration_id,omitempty"`
+	// CredentialResponseEncryption is the issuer's support for encrypted credential responses at the time the
+	// credential was issued. It's nil if the issuer didn't advertise any.
+	CredentialResponseEncryption *issuer.CredentialResponseEncryption `json:"credential_response_encryption,omitempty"`
+}
+
+// ReissueCredential uses the refresh token in the given ReissuanceToken to get a fresh access token from the issuer,
+// and then requests a new copy of the credential the ReissuanceToken is bound to.
+// Issuers may rotate refresh tokens, so the returned ReissuanceToken should replace the one passed in. Once used,
+// the old one may no longer be valid.
+// The given ClientConfig is used in the same way as in NewInteraction.
+func ReissueCredential(reissuanceToken *ReissuanceToken, jwtSigner api.JWTSigner, config *ClientConfig,
+) (*verifiable.Credential, *ReissuanceToken, error) {
+	return ReissueCredentialContext(context.Background(), reissuanceToken, jwtSigner, config)
+}
+
+// ReissueCredentialContext is the same as ReissueCredential, except that the given context is used for all requests
+// made to the issuer and for resolving DIDs while verifying the received credential.
+func ReissueCredentialContext(ctx context.Context, reissuanceToken *ReissuanceToken, jwtSigner api.JWTSigner,
+	config *ClientConfig,
+) (*verifiable.Credential, *ReissuanceToken, error) {
+	timeStartReissueCredential := time.Now()
+
+	err := validateRequiredParameters(config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = validateReissuanceToken(reissuanceToken)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = validateSignerKeyID(jwtSigner)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	setDefaults(config)
+
+	// The same HTTP client is used for both requests so that any DPoP nonce received from the issuer is reused.
+	httpClient := newDPoPHTTPClient(config)
+
+	tokenResponse, err := getTokenResponseUsingRefreshToken(ctx, reissuanceToken, httpClient, config)
+	if err != nil {
+		return nil, nil, walleterror.NewExecutionError(
+			module,
+			TokenFetchFailedCode,
+			TokenFetchFailedError,
+			fmt.Errorf("failed to get token response: %w", err))
+	}
+
+	proof := &credentialProof{
+		issuerURI: reissuanceToken.IssuerURI,
+		clientID:  reissuanceToken.ClientID,
+		signer:    jwtSigner,
+	}
+
+	err = proof.sign(tokenResponse.CNonce)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	credentialResponse, err := getReissuedCredentialResponse(ctx, reissuanceToken, proof, tokenResponse.AccessToken,
+		httpClient, config)
+	if err != nil {
+		return nil, nil, newCredentialFetchError(err)
+	}
+
+	vc, err := parseCredentialFromCredentialResponse(credentialResponse,
+		credentialParseOpts(ctx, config.DIDResolver, config.DocumentLoader, config.DisableVCProofChecks))
+	if err != nil {
+		return nil, nil, walleterror.NewExecutionError(
+			module,
+			CredentialParseFailedCode,
+			CredentialParseError,
+			fmt.Errorf("failed to parse reissued credential: %w", err))
+	}
+
+	subjectIDs, err := getSubjectIDs([]*verifiable.Credential{vc})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = config.MetricsLogger.Log(&api.MetricsEvent{
+		Event:    reissueCredentialEventText,
+		Duration: time.Since(timeStartReissueCredential),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	updatedReissuanceToken := *reissuanceToken
+	updatedReissuanceToken.CredentialID = vc.ID
+
+	if tokenResponse.RefreshToken != "" {
+		updatedReissuanceToken.RefreshToken = tokenResponse.RefreshToken
+	}
+
+	return vc, &updatedReissuanceToken, config.ActivityLogger.Log(&api.Activity{
+		ID:   uuid.New(),
+		Type: api.LogTypeCredentialActivity,
+		Time: time.Now(),
+		Data: api.Data{
+			Client:    reissuanceToken.IssuerURI,
+			Operation: activityLogOperation,
+			Status:    api.ActivityLogStatusSuccess,
+			Params:    map[string]interface{}{"subjectIDs": subjectIDs},
+		},
+	})
+}
+
+// createReissuanceTokens creates a ReissuanceToken for each issued credential. The given VCs must be in the same order
+// as the offered credentials, with nil in place of any that weren't issued (including deferred credentials, which have
+// no VC yet). Those are skipped.
+func (i *Interaction) createReissuanceTokens(vcs []*verifiable.Credential, refreshToken string) []*ReissuanceToken {
+	if refreshToken == "" {
+		return nil
+	}
+
+	reissuanceTokens := make([]*ReissuanceToken, 0, len(vcs))
+
+	for index, vc := range vcs {
+		if vc == nil {
+			continue
+		}
+
+		reissuanceTokens = append(reissuanceTokens, &ReissuanceToken{
+			CredentialID:                 vc.ID,
+			IssuerURI:                    i.issuerURI,
+			TokenEndpoint:                i.openIDConfig.TokenEndpoint,
+			CredentialEndpoint:           i.issuerMetadata.CredentialEndpoint,
+			ClientID:                     i.clientID,
+			Format:                       i.credentialFormats[index],
+			Types:                        i.credentialTypes[index],
+			RefreshToken:                 refreshToken,
+			CredentialConfigurationID:    i.credentialConfigurationID(index),
+			CredentialResponseEncryption: i.credentialResponseEncryption(),
+		})
+	}
+
+	return reissuanceTokens
+}
+
+func (i *Interaction) refreshToken(grantType string) string {
+	if grantType == preAuthorizedGrantType {
+		return i.preAuthTokenResponse.RefreshToken
+	}
+
+	return i.authTokenResponse.RefreshToken
+}
+
+func validateReissuanceToken(reissuanceToken *ReissuanceToken) error {
+	var err error
+
+	switch {
+	case reissuanceToken == nil:
+		err = errors.New("no reissuance token provided")
+	case reissuanceToken.RefreshToken == "":
+		err = errors.New("refresh token missing")
+	case reissuanceToken.TokenEndpoint == "":
+		err = errors.New("token endpoint missing")
+	case reissuanceToken.CredentialEndpoint == "":
+		err = errors.New("credential endpoint missing")
+	}
+
+	if err != nil {
+		return walleterror.NewValidationError(
+			module,
+			InvalidReissuanceTokenCode,
+			InvalidReissuanceTokenError,
+			err)
+	}
+
+	return nil
+}
+
+func getTokenResponseUsingRefreshToken(ctx context.Context, reissuanceToken *ReissuanceToken,
+	httpClient *http.Client, config *ClientConfig,
+) (*preAuthTokenResponse, error) {
+	params := url.Values{}
+	params.Add("grant_type", refreshTokenGrantType)
+	params.Add("refresh_token", reissuanceToken.RefreshToken)
+
+	headers := http.Header{}
+
+	clientAuthentication, err := clientAuthenticationFromConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case clientAuthentication != nil:
+		// The authorization server's metadata isn't available here, so the token endpoint from the reissuance token
+		// is used as the audience of any client assertion.
+		err = clientAuthentication.addToTokenRequest(params, headers,
+			&OpenIDConfig{TokenEndpoint: reissuanceToken.TokenEndpoint})
+		if err != nil {
+			return nil, err
+		}
+	case reissuanceToken.ClientID != "":
+		params.Add("client_id", reissuanceToken.ClientID)
+	}
+
+	responseBytes, err := httprequest.New(httpClient, config.MetricsLogger).DoWithHeaders(ctx,
+		http.MethodPost, reissuanceToken.TokenEndpoint, "application/x-www-form-urlencoded", headers,
+		strings.NewReader(params.Encode()),
+		fmt.Sprintf(fetchTokenUsingRefreshTokenViaPOSTReqEventText, reissuanceToken.TokenEndpoint),
+		reissueCredentialEventText)
+	if err != nil {
+		return nil, fmt.Errorf("issuer's token endpoint: %w", err)
+	}
+
+	var tokenResponse preAuthTokenResponse
+
+	err = json.Unmarshal(responseBytes, &tokenResponse)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response from the issuer's token endpoint: %w", err)
+	}
+
+	return &tokenResponse, nil
+}
+
+// getReissuedCredentialResponse requests a new copy of the credential from the issuer's credential endpoint. As with
+// the initial issuance, the proof is re-signed and the request retried if the issuer rejects the proof.
+func getReissuedCredentialResponse(ctx context.Context, reissuanceToken *ReissuanceToken, proof *credentialProof,
+	accessToken string, httpClient *http.Client, config *ClientConfig,
+) (*CredentialResponse, error) {
+	responseBytes, err := doWithProofRetry(proof, func() ([]byte, error) {
+		return sendReissuedCredentialRequest(ctx, reissuanceToken, proof.jwt, accessToken, httpClient, config)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var credentialResponse CredentialResponse
+
+	err = json.Unmarshal(responseBytes, &credentialResponse)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response from the issuer's credential endpoint: %w", err)
+	}
+
+	return &credentialResponse, nil
+}
+
+// sendReissuedCredentialRequest sends a request to the issuer's credential endpoint and returns the (decrypted, if
+// applicable) response.
+func sendReissuedCredentialRequest(ctx context.Context, reissuanceToken *ReissuanceToken, proofJWT,
+	accessToken string, httpClient *http.Client, config *ClientConfig,
+) ([]byte, error) {
+	decrypter, err := newCredentialResponseDecrypter(reissuanceToken.CredentialResponseEncryption)
+	if err != nil {
+		return nil, err
+	}
+
+	credentialReq := newCredentialRequest(reissuanceToken.Format, reissuanceToken.Types,
+		reissuanceToken.CredentialConfigurationID, "", proofJWT)
+
+	if decrypter != nil {
+		decrypter.addToCredentialRequest(credentialReq, reissuanceToken.CredentialConfigurationID != "")
+	}
+
+	request, err := createHTTPRequest(ctx, reissuanceToken.CredentialEndpoint, credentialReq, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	responseBytes, err := getRawCredentialResponse(request, httpClient, config.MetricsLogger,
+		fmt.Sprintf(fetchReissuedCredentialViaPOSTReqEventText, reissuanceToken.CredentialEndpoint),
+		reissueCredentialEventText)
+	if err != nil {
+		return nil, err
+	}
+
+	if decrypter != nil {
+		return decrypter.decrypt(responseBytes)
+	}
+
+	return responseBytes, nil
+}
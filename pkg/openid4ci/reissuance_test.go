@@ -0,0 +1,417 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4ci_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/wallet-sdk/internal/testutil"
+	"github.com/trustbloc/wallet-sdk/pkg/models/issuer"
+	"github.com/trustbloc/wallet-sdk/pkg/openid4ci"
+)
+
+const (
+	sampleTokenResponseWithRefreshToken = `{"access_token":"eyJhbGciOiJSUzI1NiIsInR5cCI6Ikp..sHQ",` +
+		`"token_type":"bearer","expires_in":86400,"c_nonce":"tZignsnFbp","c_nonce_expires_in":86400,` +
+		`"refresh_token":"refreshToken"}`
+	sampleCredentialID = "urn:uuid:bda86236-d99e-4530-b6bf-e779d5aef54c"
+)
+
+type mockReissuanceServerHandler struct {
+	t                           *testing.T
+	tokenResponse               string
+	tokenRequestShouldFail      bool
+	credentialRequestShouldFail bool
+	credentialResponse          []byte
+	receivedRefreshToken        string
+	receivedClientID            string
+	receivedTokenRequestForm    url.Values
+	receivedTokenAuthorization  string
+	receivedAuthorization       string
+	receivedCredentialRequest   map[string]interface{}
+}
+
+func (m *mockReissuanceServerHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	var err error
+
+	switch request.URL.Path {
+	case "/oidc/token":
+		require.NoError(m.t, request.ParseForm())
+		require.Equal(m.t, "refresh_token", request.PostForm.Get("grant_type"))
+
+		m.receivedRefreshToken = request.PostForm.Get("refresh_token")
+		m.receivedClientID = request.PostForm.Get("client_id")
+		m.receivedTokenRequestForm = request.PostForm
+		m.receivedTokenAuthorization = request.Header.Get("Authorization")
+
+		if m.tokenRequestShouldFail {
+			writer.WriteHeader(http.StatusBadRequest)
+			_, err = writer.Write([]byte(`{"error":"invalid_grant"}`))
+
+			break
+		}
+
+		_, err = writer.Write([]byte(m.tokenResponse))
+	case "/credential":
+		m.receivedAuthorization = request.Header.Get("Authorization")
+
+		require.NoError(m.t, json.NewDecoder(request.Body).Decode(&m.receivedCredentialRequest))
+
+		if m.credentialRequestShouldFail {
+			writer.WriteHeader(http.StatusInternalServerError)
+			_, err = writer.Write([]byte("test failure"))
+
+			break
+		}
+
+		_, err = writer.Write(m.credentialResponse)
+	}
+
+	require.NoError(m.t, err)
+}
+
+func TestInteraction_ReissuanceTokens(t *testing.T) {
+	t.Run("Issuer provided a refresh token", func(t *testing.T) {
+		issuerServerHandler := &mockIssuerServerHandler{
+			t:                  t,
+			credentialResponse: sampleCredentialResponse,
+			tokenResponse:      sampleTokenResponseWithRefreshToken,
+		}
+
+		server := httptest.NewServer(issuerServerHandler)
+		defer server.Close()
+
+		issuerServerHandler.openIDConfig = &openid4ci.OpenIDConfig{
+			TokenEndpoint: fmt.Sprintf("%s/oidc/token", server.URL),
+		}
+
+		issuerServerHandler.issuerMetadata = fmt.Sprintf(`{"credential_endpoint":"%s/credential"}`, server.URL)
+
+		interaction := newInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, false))
+
+		credentials, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{
+			keyID: mockKeyID,
+		}, openid4ci.WithPIN("1234"))
+		require.NoError(t, err)
+		require.Len(t, credentials, 1)
+
+		reissuanceTokens := interaction.ReissuanceTokens()
+		require.Len(t, reissuanceTokens, 1)
+		require.Equal(t, &openid4ci.ReissuanceToken{
+			CredentialID:       credentials[0].ID,
+			IssuerURI:          server.URL,
+			TokenEndpoint:      server.URL + "/oidc/token",
+			CredentialEndpoint: server.URL + "/credential",
+			Format:             "jwt_vc_json",
+			Types:              []string{"VerifiableCredential", "VerifiedEmployee"},
+			RefreshToken:       "refreshToken",
+		}, reissuanceTokens[0])
+	})
+	t.Run("Issuer advertises credential response encryption", func(t *testing.T) {
+		issuerServerHandler := &mockIssuerServerHandler{
+			t:                  t,
+			credentialResponse: sampleCredentialResponse,
+			tokenResponse:      sampleTokenResponseWithRefreshToken,
+		}
+
+		server := httptest.NewServer(issuerServerHandler)
+		defer server.Close()
+
+		issuerServerHandler.openIDConfig = &openid4ci.OpenIDConfig{
+			TokenEndpoint: fmt.Sprintf("%s/oidc/token", server.URL),
+		}
+
+		// None of the algorithms are supported by the wallet, and encryption isn't required, so the credential is
+		// issued in cleartext. The issuer's encryption support is still kept for reissuance.
+		issuerServerHandler.issuerMetadata = fmt.Sprintf(`{"credential_endpoint":"%s/credential",`+
+			`"credential_response_encryption_alg_values_supported":["RSA-OAEP"]}`, server.URL)
+
+		interaction := newInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, false))
+
+		_, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{
+			keyID: mockKeyID,
+		}, openid4ci.WithPIN("1234"))
+		require.NoError(t, err)
+
+		reissuanceTokens := interaction.ReissuanceTokens()
+		require.Len(t, reissuanceTokens, 1)
+		require.Equal(t, &issuer.CredentialResponseEncryption{AlgValuesSupported: []string{"RSA-OAEP"}},
+			reissuanceTokens[0].CredentialResponseEncryption)
+	})
+	t.Run("Issuer didn't provide a refresh token", func(t *testing.T) {
+		issuerServerHandler := &mockIssuerServerHandler{
+			t:                  t,
+			credentialResponse: sampleCredentialResponse,
+		}
+
+		server := httptest.NewServer(issuerServerHandler)
+		defer server.Close()
+
+		issuerServerHandler.openIDConfig = &openid4ci.OpenIDConfig{
+			TokenEndpoint: fmt.Sprintf("%s/oidc/token", server.URL),
+		}
+
+		issuerServerHandler.issuerMetadata = fmt.Sprintf(`{"credential_endpoint":"%s/credential"}`, server.URL)
+
+		interaction := newInteraction(t, createCredentialOfferIssuanceURI(t, server.URL, false))
+
+		_, err := interaction.RequestCredentialWithPreAuth(&jwtSignerMock{
+			keyID: mockKeyID,
+		}, openid4ci.WithPIN("1234"))
+		require.NoError(t, err)
+		require.Empty(t, interaction.ReissuanceTokens())
+	})
+}
+
+func TestReissueCredential(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		t.Run("Issuer rotates the refresh token", func(t *testing.T) {
+			handler := &mockReissuanceServerHandler{
+				t: t,
+				tokenResponse: `{"access_token":"newAccessToken","c_nonce":"nonce",` +
+					`"refresh_token":"newRefreshToken"}`,
+				credentialResponse: sampleCredentialResponse,
+			}
+
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			reissuanceToken := createTestReissuanceToken(server.URL)
+			reissuanceToken.ClientID = "clientID"
+
+			vc, updatedReissuanceToken, err := openid4ci.ReissueCredential(reissuanceToken,
+				&jwtSignerMock{keyID: mockKeyID}, getTestClientConfig(t))
+			require.NoError(t, err)
+			require.NotNil(t, vc)
+			require.Equal(t, "newRefreshToken", updatedReissuanceToken.RefreshToken)
+			require.Equal(t, sampleCredentialID, updatedReissuanceToken.CredentialID)
+			require.Equal(t, "oldRefreshToken", reissuanceToken.RefreshToken)
+
+			require.Equal(t, "oldRefreshToken", handler.receivedRefreshToken)
+			require.Equal(t, "clientID", handler.receivedClientID)
+			require.Equal(t, "Bearer newAccessToken", handler.receivedAuthorization)
+			require.Equal(t, "jwt_vc_json", handler.receivedCredentialRequest["format"])
+			require.Equal(t, []interface{}{"VerifiableCredential", "VerifiedEmployee"},
+				handler.receivedCredentialRequest["types"])
+		})
+		t.Run("Issuer doesn't rotate the refresh token", func(t *testing.T) {
+			handler := &mockReissuanceServerHandler{
+				t:                  t,
+				tokenResponse:      `{"access_token":"newAccessToken","c_nonce":"nonce"}`,
+				credentialResponse: sampleCredentialResponse,
+			}
+
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			vc, updatedReissuanceToken, err := openid4ci.ReissueCredential(createTestReissuanceToken(server.URL),
+				&jwtSignerMock{keyID: mockKeyID}, getTestClientConfig(t))
+			require.NoError(t, err)
+			require.NotNil(t, vc)
+			require.Equal(t, "oldRefreshToken", updatedReissuanceToken.RefreshToken)
+			require.Empty(t, handler.receivedClientID)
+		})
+	})
+	t.Run("Client authentication", func(t *testing.T) {
+		t.Run("client_secret_basic", func(t *testing.T) {
+			handler := &mockReissuanceServerHandler{
+				t:                  t,
+				tokenResponse:      `{"access_token":"newAccessToken","c_nonce":"nonce"}`,
+				credentialResponse: sampleCredentialResponse,
+			}
+
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			config := getTestClientConfig(t)
+			config.ClientAuthentication = &openid4ci.ClientAuthentication{
+				ClientID:     "clientID",
+				Method:       openid4ci.ClientAuthMethodClientSecretBasic,
+				ClientSecret: "clientSecret",
+			}
+
+			_, _, err := openid4ci.ReissueCredential(createTestReissuanceToken(server.URL),
+				&jwtSignerMock{keyID: mockKeyID}, config)
+			require.NoError(t, err)
+			require.Equal(t, "Basic "+base64.StdEncoding.EncodeToString([]byte("clientID:clientSecret")),
+				handler.receivedTokenAuthorization)
+		})
+		t.Run("attest_jwt_client_auth", func(t *testing.T) {
+			handler := &mockReissuanceServerHandler{
+				t:                  t,
+				tokenResponse:      `{"access_token":"newAccessToken","c_nonce":"nonce"}`,
+				credentialResponse: sampleCredentialResponse,
+			}
+
+			server := httptest.NewServer(handler)
+			defer server.Close()
+
+			attestation := createTestClientAttestation(t, `{"iss":"https://wallet-provider.example.com",`+
+				`"sub":"`+sampleWalletInstanceID+`","cnf":{"jwk":{"kty":"OKP","crv":"Ed25519","x":"abc"}}}`)
+
+			config := getTestClientConfig(t)
+			config.ClientAttestation = &openid4ci.ClientAttestationConfig{
+				Attestation: attestation,
+				Signer:      &jwtSignerMock{keyID: mockKeyID},
+			}
+
+			_, _, err := openid4ci.ReissueCredential(createTestReissuanceToken(server.URL),
+				&jwtSignerMock{keyID: mockKeyID}, config)
+			require.NoError(t, err)
+			requireValidClientAttestationAssertion(t, handler.receivedTokenRequestForm, attestation,
+				server.URL+"/oidc/token")
+		})
+		t.Run("Client authentication and client attestation both set", func(t *testing.T) {
+			config := getTestClientConfig(t)
+			config.ClientAuthentication = &openid4ci.ClientAuthentication{
+				ClientID: "clientID",
+				Method:   openid4ci.ClientAuthMethodNone,
+			}
+			config.ClientAttestation = &openid4ci.ClientAttestationConfig{}
+
+			vc, _, err := openid4ci.ReissueCredential(createTestReissuanceToken("example.com"),
+				&jwtSignerMock{keyID: mockKeyID}, config)
+			testutil.RequireErrorContains(t, err, "client authentication and client attestation can't both be set")
+			require.Nil(t, vc)
+		})
+	})
+	t.Run("Missing client config", func(t *testing.T) {
+		vc, updatedReissuanceToken, err := openid4ci.ReissueCredential(createTestReissuanceToken("example.com"),
+			&jwtSignerMock{keyID: mockKeyID}, nil)
+		require.EqualError(t, err, "NO_CLIENT_CONFIG_PROVIDED(OCI0-0000):no client config provided")
+		require.Nil(t, vc)
+		require.Nil(t, updatedReissuanceToken)
+	})
+	t.Run("Invalid reissuance token", func(t *testing.T) {
+		testCases := []struct {
+			name            string
+			reissuanceToken *openid4ci.ReissuanceToken
+			expectedError   string
+		}{
+			{
+				name:          "Nil reissuance token",
+				expectedError: "no reissuance token provided",
+			},
+			{
+				name:            "Missing refresh token",
+				reissuanceToken: &openid4ci.ReissuanceToken{},
+				expectedError:   "refresh token missing",
+			},
+			{
+				name:            "Missing token endpoint",
+				reissuanceToken: &openid4ci.ReissuanceToken{RefreshToken: "refreshToken"},
+				expectedError:   "token endpoint missing",
+			},
+			{
+				name: "Missing credential endpoint",
+				reissuanceToken: &openid4ci.ReissuanceToken{
+					RefreshToken:  "refreshToken",
+					TokenEndpoint: "example.com",
+				},
+				expectedError: "credential endpoint missing",
+			},
+		}
+
+		for _, testCase := range testCases {
+			t.Run(testCase.name, func(t *testing.T) {
+				vc, updatedReissuanceToken, err := openid4ci.ReissueCredential(testCase.reissuanceToken,
+					&jwtSignerMock{keyID: mockKeyID}, getTestClientConfig(t))
+				require.EqualError(t, err, "INVALID_REISSUANCE_TOKEN(OCI0-0018):"+testCase.expectedError)
+				require.Nil(t, vc)
+				require.Nil(t, updatedReissuanceToken)
+			})
+		}
+	})
+	t.Run("Key ID missing the DID part", func(t *testing.T) {
+		vc, updatedReissuanceToken, err := openid4ci.ReissueCredential(createTestReissuanceToken("example.com"),
+			&jwtSignerMock{keyID: "noDIDPart"}, getTestClientConfig(t))
+		require.EqualError(t, err, "KEY_ID_NOT_CONTAIN_DID_PART(OCI1-0011):key ID (noDIDPart) is missing the "+
+			"DID part")
+		require.Nil(t, vc)
+		require.Nil(t, updatedReissuanceToken)
+	})
+	t.Run("Refresh token rejected", func(t *testing.T) {
+		handler := &mockReissuanceServerHandler{t: t, tokenRequestShouldFail: true}
+
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		vc, updatedReissuanceToken, err := openid4ci.ReissueCredential(createTestReissuanceToken(server.URL),
+			&jwtSignerMock{keyID: mockKeyID}, getTestClientConfig(t))
+		testutil.RequireErrorContains(t, err, "TOKEN_FETCH_FAILED(OCI1-0008):failed to get token response: "+
+			"issuer's token endpoint: expected status code 200 but got status code 400 with response body "+
+			`{"error":"invalid_grant"} instead`)
+		require.Nil(t, vc)
+		require.Nil(t, updatedReissuanceToken)
+	})
+	t.Run("Fail to unmarshal token response", func(t *testing.T) {
+		handler := &mockReissuanceServerHandler{t: t, tokenResponse: "invalid"}
+
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		vc, updatedReissuanceToken, err := openid4ci.ReissueCredential(createTestReissuanceToken(server.URL),
+			&jwtSignerMock{keyID: mockKeyID}, getTestClientConfig(t))
+		testutil.RequireErrorContains(t, err, "failed to unmarshal response from the issuer's token endpoint")
+		require.Nil(t, vc)
+		require.Nil(t, updatedReissuanceToken)
+	})
+	t.Run("Credential request fails", func(t *testing.T) {
+		handler := &mockReissuanceServerHandler{
+			t:                           t,
+			tokenResponse:               `{"access_token":"newAccessToken","c_nonce":"nonce"}`,
+			credentialRequestShouldFail: true,
+		}
+
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		vc, updatedReissuanceToken, err := openid4ci.ReissueCredential(createTestReissuanceToken(server.URL),
+			&jwtSignerMock{keyID: mockKeyID}, getTestClientConfig(t))
+		testutil.RequireErrorContains(t, err, "CREDENTIAL_FETCH_FAILED(OCI1-0010):failed to get credential "+
+			"response: received status code [500] with body [test failure] from issuer's credential endpoint")
+		require.Nil(t, vc)
+		require.Nil(t, updatedReissuanceToken)
+	})
+	t.Run("Fail to parse credential", func(t *testing.T) {
+		handler := &mockReissuanceServerHandler{
+			t:                  t,
+			tokenResponse:      `{"access_token":"newAccessToken","c_nonce":"nonce"}`,
+			credentialResponse: []byte(`{"credential":"invalid"}`),
+		}
+
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		vc, updatedReissuanceToken, err := openid4ci.ReissueCredential(createTestReissuanceToken(server.URL),
+			&jwtSignerMock{keyID: mockKeyID}, getTestClientConfig(t))
+		testutil.RequireErrorContains(t, err, "CREDENTIAL_PARSE_FAILED(OCI1-0012):failed to parse reissued "+
+			"credential")
+		require.Nil(t, vc)
+		require.Nil(t, updatedReissuanceToken)
+	})
+}
+
+func createTestReissuanceToken(serverURL string) *openid4ci.ReissuanceToken {
+	return &openid4ci.ReissuanceToken{
+		CredentialID:       sampleCredentialID,
+		IssuerURI:          serverURL,
+		TokenEndpoint:      serverURL + "/oidc/token",
+		CredentialEndpoint: serverURL + "/credential",
+		Format:             "jwt_vc_json",
+		Types:              []string{"VerifiableCredential", "VerifiedEmployee"},
+		RefreshToken:       "oldRefreshToken",
+	}
+}
@@ -0,0 +1,264 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4vp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/hyperledger/aries-framework-go/component/models/jwt"
+
+	"github.com/trustbloc/wallet-sdk/pkg/internal/httprequest"
+)
+
+const (
+	requestURIMethodGet  = "get"
+	requestURIMethodPost = "post"
+
+	requestObjectMediaType = "application/oauth-authz-req+jwt"
+
+	fetchRequestObjectViaPostEventText = "Fetch request object via an HTTP POST request to %s"
+)
+
+// legacyRequestURIPrefix is the prefix of the authorization requests that older verifiers send, which only have a
+// request_uri parameter. Its value isn't always URL-encoded, so everything after the prefix is the request URI.
+const legacyRequestURIPrefix = "openid-vc://?request_uri="
+
+// The URI schemes that authorization requests can use.
+var authorizationRequestSchemes = []string{ //nolint:gochecknoglobals // read-only
+	"openid-vc", "openid4vp", "haip", "mdoc-openid4vp",
+}
+
+// The authorization request parameters whose values are JSON objects rather than strings.
+var jsonAuthorizationRequestParameters = []string{ //nolint:gochecknoglobals // read-only
+	"presentation_definition", "dcql_query", "client_metadata", "registration", "claims",
+}
+
+// authorizationRequestURI is an authorization request URI (e.g. openid4vp://?client_id=...&request_uri=...) split
+// into its parameters. The request object is either passed by reference (request_uri), by value (request) or not at
+// all, in which case the parameters themselves make up the request.
+type authorizationRequestURI struct {
+	clientID         string
+	request          string
+	requestURI       string
+	requestURIMethod string
+	parameters       url.Values
+}
+
+// parseAuthorizationRequestURI parses the given authorization request. If it isn't a URI using one of the
+// authorization request schemes, then it's assumed to be a request object, and nil is returned.
+func parseAuthorizationRequestURI(authorizationRequest string) (*authorizationRequestURI, error) {
+	scheme, _, found := strings.Cut(authorizationRequest, "://")
+	if !found || !contains(authorizationRequestSchemes, scheme) {
+		return nil, nil //nolint:nilnil // not a URI, so there's nothing to parse
+	}
+
+	requestURL, err := url.Parse(authorizationRequest)
+	if err != nil {
+		return nil, fmt.Errorf("parse authorization request URI: %w", err)
+	}
+
+	parameters, err := url.ParseQuery(requestURL.RawQuery)
+	if err != nil {
+		return nil, fmt.Errorf("parse authorization request parameters: %w", err)
+	}
+
+	if isLegacyAuthorizationRequestURI(authorizationRequest, parameters) {
+		parameters = url.Values{"request_uri": {strings.TrimPrefix(authorizationRequest, legacyRequestURIPrefix)}}
+	}
+
+	return &authorizationRequestURI{
+		clientID:         parameters.Get("client_id"),
+		request:          parameters.Get("request"),
+		requestURI:       parameters.Get("request_uri"),
+		requestURIMethod: strings.ToLower(parameters.Get("request_uri_method")),
+		parameters:       parameters,
+	}, nil
+}
+
+// isLegacyAuthorizationRequestURI returns true if the given authorization request only has an unencoded request_uri
+// parameter, whose own query parameters (if any) were mistaken for authorization request parameters.
+func isLegacyAuthorizationRequestURI(authorizationRequest string, parameters url.Values) bool {
+	if !strings.HasPrefix(authorizationRequest, legacyRequestURIPrefix) {
+		return false
+	}
+
+	for _, parameter := range []string{"client_id", "request", "request_uri_method"} {
+		if parameters.Has(parameter) {
+			return false
+		}
+	}
+
+	return strings.Contains(strings.TrimPrefix(authorizationRequest, legacyRequestURIPrefix), "://")
+}
+
+// walletMetadata describes the wallet's capabilities to the verifier, so that it can create a request object that the
+// wallet supports. It's sent when the verifier asks for the request object to be fetched via an HTTP POST request.
+type walletMetadata struct {
+	VPFormats       map[string]interface{} `json:"vp_formats_supported"`                          //nolint: tagliatelle
+	ClientIDSchemes []string               `json:"client_id_schemes_supported"`                   //nolint: tagliatelle
+	ResponseModes   []string               `json:"response_modes_supported"`                      //nolint: tagliatelle
+	EncryptionAlgs  []string               `json:"authorization_encryption_alg_values_supported"` //nolint: tagliatelle
+	EncryptionEncs  []string               `json:"authorization_encryption_enc_values_supported"` //nolint: tagliatelle
+}
+
+func newWalletMetadata() *walletMetadata {
+	return &walletMetadata{
+		VPFormats: map[string]interface{}{
+			"jwt_vp": struct{}{},
+			"jwt_vc": struct{}{},
+			"ldp_vc": struct{}{},
+		},
+		ClientIDSchemes: []string{
+			ClientIDSchemePreRegistered, ClientIDSchemeRedirectURI, ClientIDSchemeDID, ClientIDSchemeX509SANDNS,
+			ClientIDSchemeX509SANURI, ClientIDSchemeVerifierAttestation,
+		},
+		ResponseModes:  []string{"direct_post", responseModeDirectPostJWT},
+		EncryptionAlgs: supportedEncryptedResponseAlgs,
+		EncryptionEncs: supportedEncryptedResponseEncs,
+	}
+}
+
+func (o *Interaction) fetchRequestObject(ctx context.Context) (string, error) {
+	requestURI, err := parseAuthorizationRequestURI(o.authorizationRequest)
+	if err != nil {
+		return "", err
+	}
+
+	if requestURI == nil {
+		return o.authorizationRequest, nil
+	}
+
+	o.authorizationRequestClientID = requestURI.clientID
+
+	switch {
+	case requestURI.request != "":
+		return requestURI.request, nil
+	case requestURI.requestURI == "":
+		return requestObjectFromParameters(requestURI.parameters)
+	}
+
+	switch requestURI.requestURIMethod {
+	case "", requestURIMethodGet:
+		respBytes, err := httprequest.New(o.httpClient, o.metricsLogger).Do(ctx, http.MethodGet,
+			requestURI.requestURI, "", nil, fmt.Sprintf(fetchRequestObjectEventText, requestURI.requestURI),
+			getQueryEventText)
+		if err != nil {
+			return "", err
+		}
+
+		return string(respBytes), nil
+	case requestURIMethodPost:
+		return o.fetchRequestObjectViaPost(ctx, requestURI.requestURI)
+	default:
+		return "", fmt.Errorf("unsupported request_uri_method: %s", requestURI.requestURIMethod)
+	}
+}
+
+// fetchRequestObjectViaPost fetches the request object by posting the wallet's metadata and a nonce to the
+// request_uri. The verifier must put the nonce in the request object's wallet_nonce claim, which is checked once the
+// request object has been verified.
+func (o *Interaction) fetchRequestObjectViaPost(ctx context.Context, requestURI string) (string, error) {
+	walletNonce, err := newWalletNonce()
+	if err != nil {
+		return "", fmt.Errorf("generate wallet nonce: %w", err)
+	}
+
+	walletMetadataBytes, err := json.Marshal(newWalletMetadata())
+	if err != nil {
+		return "", fmt.Errorf("marshal wallet metadata: %w", err)
+	}
+
+	data := url.Values{}
+	data.Set("wallet_metadata", string(walletMetadataBytes))
+	data.Set("wallet_nonce", walletNonce)
+
+	respBytes, err := httprequest.New(o.httpClient, o.metricsLogger).DoWithHeaders(ctx, http.MethodPost,
+		requestURI, "application/x-www-form-urlencoded", http.Header{"Accept": {requestObjectMediaType}},
+		strings.NewReader(data.Encode()), fmt.Sprintf(fetchRequestObjectViaPostEventText, requestURI),
+		getQueryEventText)
+	if err != nil {
+		return "", err
+	}
+
+	o.walletNonce = walletNonce
+
+	return string(respBytes), nil
+}
+
+// checkRequestObjectBinding checks that the verified request object matches the authorization request it was fetched
+// for: its client_id must be the one in the authorization request URI (if there was one), and its wallet_nonce must
+// be the one that was sent when fetching it via an HTTP POST request.
+func (o *Interaction) checkRequestObjectBinding(requestObject *requestObject) error {
+	if o.authorizationRequestClientID != "" && requestObject.ClientID != o.authorizationRequestClientID {
+		return fmt.Errorf("request object client ID %s does not match the authorization request's client ID %s",
+			requestObject.ClientID, o.authorizationRequestClientID)
+	}
+
+	if o.walletNonce != "" && requestObject.WalletNonce != o.walletNonce {
+		return errors.New("request object wallet_nonce does not match the wallet nonce that was sent")
+	}
+
+	return nil
+}
+
+// requestObjectFromParameters creates an unsigned request object from authorization request parameters that were
+// passed by value. Since it's unsigned, it's only accepted if the verifier uses the redirect_uri client ID scheme.
+func requestObjectFromParameters(parameters url.Values) (string, error) {
+	claims := map[string]interface{}{}
+
+	for name := range parameters {
+		value := parameters.Get(name)
+
+		if !contains(jsonAuthorizationRequestParameters, name) {
+			claims[name] = value
+
+			continue
+		}
+
+		var jsonValue interface{}
+
+		err := json.Unmarshal([]byte(value), &jsonValue)
+		if err != nil {
+			return "", fmt.Errorf("decode %s parameter: %w", name, err)
+		}
+
+		claims[name] = jsonValue
+	}
+
+	if presentationDefinition, ok := claims["presentation_definition"]; ok && claims["claims"] == nil {
+		claims["claims"] = map[string]interface{}{
+			"vp_token": map[string]interface{}{"presentation_definition": presentationDefinition},
+		}
+	}
+
+	token, err := jwt.NewUnsecured(claims, nil)
+	if err != nil {
+		return "", fmt.Errorf("create request object from parameters: %w", err)
+	}
+
+	return token.Serialize(false)
+}
+
+func newWalletNonce() (string, error) {
+	const randomBytesToGenerate = 32
+	randomBytes := make([]byte, randomBytesToGenerate)
+
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(randomBytes), nil
+}
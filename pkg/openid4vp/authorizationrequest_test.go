@@ -0,0 +1,256 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4vp //nolint: testpackage
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/wallet-sdk/internal/testutil"
+)
+
+type mockRequestURIHandler struct {
+	t *testing.T
+	// createRequestObject creates the request object to return, given the wallet nonce that was posted (if any).
+	createRequestObject func(walletNonce string) string
+
+	receivedMethod         string
+	receivedWalletMetadata *walletMetadata
+}
+
+func (m *mockRequestURIHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	m.receivedMethod = request.Method
+
+	var walletNonce string
+
+	if request.Method == http.MethodPost {
+		require.Equal(m.t, requestObjectMediaType, request.Header.Get("Accept"))
+		require.NoError(m.t, request.ParseForm())
+
+		m.receivedWalletMetadata = &walletMetadata{}
+		require.NoError(m.t, json.Unmarshal([]byte(request.PostForm.Get("wallet_metadata")), m.receivedWalletMetadata))
+
+		walletNonce = request.PostForm.Get("wallet_nonce")
+		require.NotEmpty(m.t, walletNonce)
+	}
+
+	_, err := writer.Write([]byte(m.createRequestObject(walletNonce)))
+	require.NoError(m.t, err)
+}
+
+func TestOpenID4VP_GetQuery_AuthorizationRequestURI(t *testing.T) {
+	redirectURIRequestObject := func(walletNonce string) string {
+		claims := testRequestObjectClaims(testVerifierRedirectURI, ClientIDSchemeRedirectURI)
+		claims["wallet_nonce"] = walletNonce
+
+		return newUnsignedTestJWT(t, claims)
+	}
+
+	t.Run("Request object fetched via an HTTP GET request", func(t *testing.T) {
+		handler := &mockRequestURIHandler{t: t, createRequestObject: func(string) string { return requestObjectJWT }}
+
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		instance := New("openid4vp://authorize?"+url.Values{
+			"client_id":   {verifierDID},
+			"request_uri": {server.URL},
+		}.Encode(), &jwtSignatureVerifierMock{}, nil, nil, nil)
+
+		query, err := instance.GetQuery()
+		require.NoError(t, err)
+		require.NotNil(t, query)
+		require.Equal(t, http.MethodGet, handler.receivedMethod)
+	})
+
+	t.Run("Request object fetched via an HTTP POST request", func(t *testing.T) {
+		handler := &mockRequestURIHandler{t: t, createRequestObject: redirectURIRequestObject}
+
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		instance := New("haip://?"+url.Values{
+			"client_id":          {testVerifierRedirectURI},
+			"request_uri":        {server.URL},
+			"request_uri_method": {"post"},
+		}.Encode(), &jwtSignatureVerifierMock{}, nil, nil, nil)
+
+		_, err := instance.GetQuery()
+		require.NoError(t, err)
+		require.Equal(t, http.MethodPost, handler.receivedMethod)
+		require.Contains(t, handler.receivedWalletMetadata.ClientIDSchemes, ClientIDSchemeRedirectURI)
+		require.Contains(t, handler.receivedWalletMetadata.ResponseModes, responseModeDirectPostJWT)
+		require.NotEmpty(t, handler.receivedWalletMetadata.VPFormats)
+	})
+
+	t.Run("Legacy request URI that isn't URL-encoded", func(t *testing.T) {
+		var receivedQuery string
+
+		server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			receivedQuery = request.URL.RawQuery
+
+			_, err := writer.Write([]byte(requestObjectJWT))
+			require.NoError(t, err)
+		}))
+		defer server.Close()
+
+		instance := New("openid-vc://?request_uri="+server.URL+"/x?a=1&b=2", &jwtSignatureVerifierMock{},
+			nil, nil, nil)
+
+		query, err := instance.GetQuery()
+		require.NoError(t, err)
+		require.NotNil(t, query)
+		require.Equal(t, "a=1&b=2", receivedQuery)
+	})
+
+	t.Run("Request object passed by value", func(t *testing.T) {
+		instance := New("openid4vp://?request="+url.QueryEscape(requestObjectJWT), &jwtSignatureVerifierMock{},
+			nil, nil, nil)
+
+		query, err := instance.GetQuery()
+		require.NoError(t, err)
+		require.NotNil(t, query)
+	})
+
+	t.Run("Parameters passed by value", func(t *testing.T) {
+		instance := New("mdoc-openid4vp://?"+url.Values{
+			"client_id":               {testVerifierRedirectURI},
+			"client_id_scheme":        {ClientIDSchemeRedirectURI},
+			"redirect_uri":            {testVerifierRedirectURI},
+			"response_type":           {"vp_token"},
+			"nonce":                   {"nonce"},
+			"presentation_definition": {`{"id":"test-definition","input_descriptors":[]}`},
+			"client_metadata":         {`{"client_name":"Test Verifier"}`},
+		}.Encode(), &jwtSignatureVerifierMock{}, nil, nil, testutil.DocumentLoader(t))
+
+		query, err := instance.GetQuery()
+		require.NoError(t, err)
+		require.Equal(t, "test-definition", query.ID)
+
+		displayData, err := instance.VerifierDisplayData()
+		require.NoError(t, err)
+		require.Equal(t, "Test Verifier", displayData.Name)
+		require.Equal(t, ClientIDSchemeRedirectURI, displayData.ClientIDScheme)
+		require.False(t, displayData.ClientIDVerified)
+	})
+
+	t.Run("Failures", func(t *testing.T) {
+		handler := &mockRequestURIHandler{t: t, createRequestObject: redirectURIRequestObject}
+
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		wrongNonceServer := httptest.NewServer(&mockRequestURIHandler{t: t, createRequestObject: func(string) string {
+			return redirectURIRequestObject("another-nonce")
+		}})
+		defer wrongNonceServer.Close()
+
+		testCases := []struct {
+			name                 string
+			authorizationRequest string
+			expectedErr          []string
+		}{
+			{
+				name: "Wallet nonce doesn't match",
+				authorizationRequest: "openid4vp://?" + url.Values{
+					"request_uri":        {wrongNonceServer.URL},
+					"request_uri_method": {"post"},
+				}.Encode(),
+				expectedErr: []string{
+					"VERIFY_AUTHORIZATION_REQUEST_FAILED",
+					"request object wallet_nonce does not match the wallet nonce that was sent",
+				},
+			},
+			{
+				name: "Unsupported request_uri_method",
+				authorizationRequest: "openid4vp://?" + url.Values{
+					"request_uri":        {server.URL},
+					"request_uri_method": {"put"},
+				}.Encode(),
+				expectedErr: []string{"REQUEST_OBJECT_FETCH_FAILED", "unsupported request_uri_method: put"},
+			},
+			{
+				name: "Client ID doesn't match the request object",
+				authorizationRequest: "openid4vp://?" + url.Values{
+					"client_id":   {"https://other.example.com"},
+					"request_uri": {server.URL},
+				}.Encode(),
+				expectedErr: []string{
+					"VERIFY_AUTHORIZATION_REQUEST_FAILED",
+					"request object client ID " + testVerifierRedirectURI + " does not match the authorization " +
+						"request's client ID https://other.example.com",
+				},
+			},
+			{
+				name: "Invalid JSON parameter",
+				authorizationRequest: "openid4vp://?" + url.Values{
+					"client_id":               {testVerifierRedirectURI},
+					"presentation_definition": {"{"},
+				}.Encode(),
+				expectedErr: []string{"REQUEST_OBJECT_FETCH_FAILED", "decode presentation_definition parameter"},
+			},
+			{
+				name: "Parameters passed by value without the redirect_uri client ID scheme",
+				authorizationRequest: "openid4vp://?" + url.Values{
+					"client_id":    {verifierDID},
+					"redirect_uri": {testVerifierRedirectURI},
+				}.Encode(),
+				expectedErr: []string{
+					"VERIFY_AUTHORIZATION_REQUEST_FAILED",
+					"unsigned request objects are only accepted for the redirect_uri client ID scheme",
+				},
+			},
+			{
+				name:                 "Invalid parameters",
+				authorizationRequest: "openid4vp://?request_uri=%zz",
+				expectedErr: []string{
+					"REQUEST_OBJECT_FETCH_FAILED", "parse authorization request parameters",
+				},
+			},
+		}
+
+		for _, testCase := range testCases {
+			t.Run(testCase.name, func(t *testing.T) {
+				instance := New(testCase.authorizationRequest, &jwtSignatureVerifierMock{}, nil, nil, nil)
+
+				query, err := instance.GetQuery()
+				for _, expectedErr := range testCase.expectedErr {
+					testutil.RequireErrorContains(t, err, expectedErr)
+				}
+
+				require.Nil(t, query)
+			})
+		}
+	})
+}
+
+func TestParseAuthorizationRequestURI(t *testing.T) {
+	t.Run("Legacy request URI that isn't URL-encoded", func(t *testing.T) {
+		requestURI, err := parseAuthorizationRequestURI("openid-vc://?request_uri=https://v/x?a=1&b=2")
+		require.NoError(t, err)
+		require.Equal(t, "https://v/x?a=1&b=2", requestURI.requestURI)
+		require.Empty(t, requestURI.clientID)
+		require.Equal(t, url.Values{"request_uri": {"https://v/x?a=1&b=2"}}, requestURI.parameters)
+	})
+	t.Run("Legacy request URI that's URL-encoded", func(t *testing.T) {
+		requestURI, err := parseAuthorizationRequestURI("openid-vc://?request_uri=" +
+			url.QueryEscape("https://v/x?a=1&b=2"))
+		require.NoError(t, err)
+		require.Equal(t, "https://v/x?a=1&b=2", requestURI.requestURI)
+	})
+	t.Run("Request URI with other parameters", func(t *testing.T) {
+		requestURI, err := parseAuthorizationRequestURI("openid-vc://?request_uri=https://v/x&client_id=verifier")
+		require.NoError(t, err)
+		require.Equal(t, "https://v/x", requestURI.requestURI)
+		require.Equal(t, "verifier", requestURI.clientID)
+	})
+}
@@ -0,0 +1,323 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4vp
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	gojose "github.com/go-jose/go-jose/v3"
+	"github.com/hyperledger/aries-framework-go/component/kmscrypto/doc/jose"
+	"github.com/hyperledger/aries-framework-go/component/models/jwt"
+
+	"github.com/trustbloc/wallet-sdk/pkg/api"
+	"github.com/trustbloc/wallet-sdk/pkg/common"
+)
+
+// The client ID schemes that define how the verifier's client_id relates to the key that signed the request object.
+const (
+	// ClientIDSchemeRedirectURI means that the client_id is the verifier's redirect URI. Requests using this
+	// scheme can't be signed, so the verifier isn't authenticated.
+	ClientIDSchemeRedirectURI = "redirect_uri"
+	// ClientIDSchemeX509SANDNS means that the request object is signed using the key of an X.509 certificate
+	// (in the x5c header) that has the client_id as a DNS name in its subject alternative names.
+	ClientIDSchemeX509SANDNS = "x509_san_dns"
+	// ClientIDSchemeX509SANURI means that the request object is signed using the key of an X.509 certificate
+	// (in the x5c header) that has the client_id as a URI in its subject alternative names.
+	ClientIDSchemeX509SANURI = "x509_san_uri"
+	// ClientIDSchemeDID means that the client_id is a DID, and the request object is signed using one of the keys
+	// in its DID document.
+	ClientIDSchemeDID = "did"
+	// ClientIDSchemeVerifierAttestation means that the request object comes with a verifier attestation JWT
+	// (in the jwt header), issued to the client_id by a trusted party, that contains the key used for signing the
+	// request object.
+	ClientIDSchemeVerifierAttestation = "verifier_attestation"
+	// ClientIDSchemePreRegistered means that the client_id is known to the wallet in advance. Request objects
+	// using this scheme, or no scheme at all, are verified using the signature verifier passed in to New.
+	ClientIDSchemePreRegistered = "pre-registered"
+)
+
+const (
+	verifierAttestationHeader = "jwt"
+	verifierAttestationType   = "verifier-attestation+jwt"
+)
+
+type requestObjectClientID struct {
+	ClientID       string `json:"client_id"`        //nolint: tagliatelle
+	ClientIDScheme string `json:"client_id_scheme"` //nolint: tagliatelle
+	RedirectURI    string `json:"redirect_uri"`     //nolint: tagliatelle
+}
+
+// requestObjectVerifier verifies a request object's signature according to its client_id_scheme. Since the scheme
+// is in the request object itself, it's read from the payload before the signature is checked, and is only trusted
+// once the signature has been verified.
+type requestObjectVerifier struct {
+	signatureVerifier jwtSignatureVerifier
+	didResolver       api.DIDResolver
+	trustAnchors      *x509.CertPool
+
+	// clientIDVerified is set once the request object is verified, if the signature proves that the request
+	// object comes from the client_id.
+	clientIDVerified bool
+}
+
+func (v *requestObjectVerifier) Verify(joseHeaders jose.Headers, payload, signingInput, signature []byte) error {
+	clientID := &requestObjectClientID{}
+
+	err := json.Unmarshal(payload, clientID)
+	if err != nil {
+		return fmt.Errorf("decode client ID: %w", err)
+	}
+
+	alg, _ := joseHeaders.Algorithm()
+
+	if alg == jwt.AlgorithmNone {
+		return verifyUnsignedRequestObject(clientID, signature)
+	}
+
+	switch clientID.ClientIDScheme {
+	case "", ClientIDSchemePreRegistered:
+		err = v.signatureVerifier.Verify(joseHeaders, payload, signingInput, signature)
+		if err != nil {
+			return err
+		}
+
+		kid, _ := joseHeaders.KeyID()
+
+		v.clientIDVerified = strings.HasPrefix(kid, clientID.ClientID+"#")
+
+		return nil
+	case ClientIDSchemeRedirectURI:
+		return errors.New("request objects using the redirect_uri client ID scheme must not be signed")
+	case ClientIDSchemeDID:
+		err = v.verifyDID(clientID, joseHeaders, payload, signingInput, signature)
+	case ClientIDSchemeX509SANDNS, ClientIDSchemeX509SANURI:
+		err = v.verifyX509(clientID, signingInput, signature)
+	case ClientIDSchemeVerifierAttestation:
+		err = v.verifyVerifierAttestation(clientID, joseHeaders, signingInput, signature)
+	default:
+		return fmt.Errorf("unsupported client ID scheme: %s", clientID.ClientIDScheme)
+	}
+
+	if err != nil {
+		return fmt.Errorf("%s client ID scheme: %w", clientID.ClientIDScheme, err)
+	}
+
+	v.clientIDVerified = true
+
+	return nil
+}
+
+func verifyUnsignedRequestObject(clientID *requestObjectClientID, signature []byte) error {
+	if clientID.ClientIDScheme != ClientIDSchemeRedirectURI {
+		return errors.New("unsigned request objects are only accepted for the redirect_uri client ID scheme")
+	}
+
+	if len(signature) > 0 {
+		return errors.New("unsigned request object has a signature")
+	}
+
+	if clientID.ClientID != clientID.RedirectURI {
+		return fmt.Errorf("client ID %s does not match the redirect URI %s", clientID.ClientID, clientID.RedirectURI)
+	}
+
+	return nil
+}
+
+func (v *requestObjectVerifier) verifyDID(clientID *requestObjectClientID, joseHeaders jose.Headers,
+	payload, signingInput, signature []byte,
+) error {
+	if v.didResolver == nil {
+		return errors.New("no DID resolver provided")
+	}
+
+	kid, _ := joseHeaders.KeyID()
+
+	if !strings.HasPrefix(kid, clientID.ClientID+"#") {
+		return fmt.Errorf("key ID %s does not belong to the client ID %s", kid, clientID.ClientID)
+	}
+
+	didVerifier := jwt.NewVerifier(jwt.KeyResolverFunc(common.NewVDRKeyResolver(v.didResolver).PublicKeyFetcher()))
+
+	return didVerifier.Verify(joseHeaders, payload, signingInput, signature)
+}
+
+func (v *requestObjectVerifier) verifyX509(clientID *requestObjectClientID, signingInput, signature []byte) error {
+	requestObjectJWS, err := parseJWS(signingInput, signature)
+	if err != nil {
+		return err
+	}
+
+	leafCertificate, err := v.verifyCertificateChain(requestObjectJWS)
+	if err != nil {
+		return err
+	}
+
+	if clientID.ClientIDScheme == ClientIDSchemeX509SANDNS {
+		err = checkX509SANDNS(leafCertificate, clientID)
+	} else {
+		err = checkX509SANURI(leafCertificate, clientID)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	_, err = requestObjectJWS.Verify(leafCertificate.PublicKey)
+	if err != nil {
+		return fmt.Errorf("verify signature: %w", err)
+	}
+
+	return nil
+}
+
+// checkX509SANDNS checks that the client ID is a DNS name in the certificate, and that the redirect URI is on the
+// same host, so that the response can't be sent to a different party.
+func checkX509SANDNS(certificate *x509.Certificate, clientID *requestObjectClientID) error {
+	if !contains(certificate.DNSNames, clientID.ClientID) {
+		return fmt.Errorf("client ID %s is not a DNS name in the certificate", clientID.ClientID)
+	}
+
+	redirectURI, err := url.Parse(clientID.RedirectURI)
+	if err != nil {
+		return fmt.Errorf("parse redirect URI: %w", err)
+	}
+
+	if redirectURI.Hostname() != clientID.ClientID {
+		return fmt.Errorf("redirect URI %s is not on the client ID's host %s", clientID.RedirectURI,
+			clientID.ClientID)
+	}
+
+	return nil
+}
+
+// checkX509SANURI checks that the client ID is a URI in the certificate, and that it's also the redirect URI.
+func checkX509SANURI(certificate *x509.Certificate, clientID *requestObjectClientID) error {
+	var uris []string
+
+	for _, uri := range certificate.URIs {
+		uris = append(uris, uri.String())
+	}
+
+	if !contains(uris, clientID.ClientID) {
+		return fmt.Errorf("client ID %s is not a URI in the certificate", clientID.ClientID)
+	}
+
+	if clientID.RedirectURI != clientID.ClientID {
+		return fmt.Errorf("client ID %s does not match the redirect URI %s", clientID.ClientID, clientID.RedirectURI)
+	}
+
+	return nil
+}
+
+type verifierAttestationClaims struct {
+	Sub string `json:"sub"`
+	Exp int64  `json:"exp"`
+	Cnf struct {
+		JWK *gojose.JSONWebKey `json:"jwk"`
+	} `json:"cnf"`
+}
+
+func (v *requestObjectVerifier) verifyVerifierAttestation(clientID *requestObjectClientID, joseHeaders jose.Headers,
+	signingInput, signature []byte,
+) error {
+	attestationJWT, ok := joseHeaders[verifierAttestationHeader].(string)
+	if !ok {
+		return errors.New("verifier attestation JWT is missing from the jwt header")
+	}
+
+	attestationJWS, err := gojose.ParseSigned(attestationJWT)
+	if err != nil {
+		return fmt.Errorf("parse verifier attestation: %w", err)
+	}
+
+	attestationType, _ := attestationJWS.Signatures[0].Protected.ExtraHeaders[gojose.HeaderType].(string)
+	if attestationType != verifierAttestationType {
+		return fmt.Errorf("verifier attestation has type %q, not %s", attestationType, verifierAttestationType)
+	}
+
+	attestationIssuerCertificate, err := v.verifyCertificateChain(attestationJWS)
+	if err != nil {
+		return fmt.Errorf("verifier attestation: %w", err)
+	}
+
+	attestationPayload, err := attestationJWS.Verify(attestationIssuerCertificate.PublicKey)
+	if err != nil {
+		return fmt.Errorf("verify verifier attestation signature: %w", err)
+	}
+
+	attestation := &verifierAttestationClaims{}
+
+	err = json.Unmarshal(attestationPayload, attestation)
+	if err != nil {
+		return fmt.Errorf("decode verifier attestation: %w", err)
+	}
+
+	if attestation.Sub != clientID.ClientID {
+		return fmt.Errorf("verifier attestation was issued to %s, not to the client ID %s", attestation.Sub,
+			clientID.ClientID)
+	}
+
+	if attestation.Exp == 0 {
+		return errors.New("verifier attestation has no expiration time")
+	}
+
+	if time.Now().Unix() > attestation.Exp {
+		return errors.New("verifier attestation has expired")
+	}
+
+	if attestation.Cnf.JWK == nil {
+		return errors.New("verifier attestation has no cnf.jwk")
+	}
+
+	requestObjectJWS, err := parseJWS(signingInput, signature)
+	if err != nil {
+		return err
+	}
+
+	_, err = requestObjectJWS.Verify(attestation.Cnf.JWK.Public().Key)
+	if err != nil {
+		return fmt.Errorf("verify signature: %w", err)
+	}
+
+	return nil
+}
+
+// verifyCertificateChain verifies the certificate chain in the JWS's x5c header against the trust anchors, and
+// returns the certificate that the JWS should be signed with.
+func (v *requestObjectVerifier) verifyCertificateChain(jws *gojose.JSONWebSignature) (*x509.Certificate, error) {
+	if v.trustAnchors == nil {
+		return nil, errors.New("no X.509 trust anchors configured")
+	}
+
+	chains, err := jws.Signatures[0].Protected.Certificates(x509.VerifyOptions{
+		Roots:     v.trustAnchors,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("verify certificate chain: %w", err)
+	}
+
+	return chains[0][0], nil
+}
+
+// parseJWS puts a compact JWS back together from its signing input and signature, so that it can be verified using
+// go-jose.
+func parseJWS(signingInput, signature []byte) (*gojose.JSONWebSignature, error) {
+	jws, err := gojose.ParseSigned(string(signingInput) + "." + base64.RawURLEncoding.EncodeToString(signature))
+	if err != nil {
+		return nil, fmt.Errorf("parse request object JWS: %w", err)
+	}
+
+	return jws, nil
+}
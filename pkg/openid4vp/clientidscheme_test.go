@@ -0,0 +1,387 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4vp //nolint: testpackage
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/url"
+	"testing"
+	"time"
+
+	gojose "github.com/go-jose/go-jose/v3"
+	"github.com/hyperledger/aries-framework-go/component/models/did"
+	"github.com/hyperledger/aries-framework-go/component/models/jwt"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/wallet-sdk/internal/testutil"
+)
+
+const (
+	testVerifierHost        = "verifier.example.com"
+	testVerifierRedirectURI = "https://verifier.example.com/response"
+	testVerifierDID         = "did:example:verifier"
+)
+
+func TestOpenID4VP_GetQuery_ClientIDScheme(t *testing.T) {
+	rootKey, rootCertificate := newTestCertificate(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "Test Root CA"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil, nil)
+
+	verifierKey, verifierCertificate := newTestCertificate(t, &x509.Certificate{
+		Subject:  pkix.Name{CommonName: testVerifierHost},
+		DNSNames: []string{testVerifierHost},
+		URIs:     []*url.URL{{Scheme: "https", Host: testVerifierHost, Path: "/response"}},
+	}, rootCertificate, rootKey)
+
+	_, untrustedRootCertificate := newTestCertificate(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "Untrusted Root CA"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil, nil)
+
+	didPublicKey, didPrivateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	verifierDIDResolver := &didResolverMock{ResolveValue: &did.DocResolution{DIDDocument: &did.Doc{
+		ID:      testVerifierDID,
+		Context: []string{did.ContextV1},
+		VerificationMethod: []did.VerificationMethod{*did.NewVerificationMethodFromBytes(
+			testVerifierDID+"#key-1", "Ed25519VerificationKey2018", testVerifierDID, didPublicKey)},
+	}}}
+
+	attestationRequestKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	// newCustomAttestation creates a verifier attestation with the given type, where the claims can be changed using
+	// the given function.
+	newCustomAttestation := func(t *testing.T, typ string, changeClaims func(claims map[string]interface{})) string {
+		t.Helper()
+
+		claims := map[string]interface{}{
+			"iss": "https://attestation-issuer.example.com",
+			"sub": testVerifierHost,
+			"exp": time.Now().Add(time.Hour).Unix(),
+			"cnf": map[string]interface{}{
+				"jwk": gojose.JSONWebKey{Key: &attestationRequestKey.PublicKey},
+			},
+		}
+
+		changeClaims(claims)
+
+		headers := map[gojose.HeaderKey]interface{}{
+			"x5c": []string{base64.StdEncoding.EncodeToString(verifierCertificate.Raw)},
+		}
+
+		if typ != "" {
+			headers[gojose.HeaderType] = typ
+		}
+
+		return signTestJWT(t, claims, gojose.ES256, verifierKey, headers)
+	}
+
+	newAttestation := func(t *testing.T, sub string) string {
+		t.Helper()
+
+		return newCustomAttestation(t, "verifier-attestation+jwt", func(claims map[string]interface{}) {
+			claims["sub"] = sub
+		})
+	}
+
+	newAttestationRequestObject := func(t *testing.T, attestation string) string {
+		t.Helper()
+
+		return signTestJWT(t, testRequestObjectClaims(testVerifierHost, ClientIDSchemeVerifierAttestation),
+			gojose.ES256, attestationRequestKey, map[gojose.HeaderKey]interface{}{"jwt": attestation})
+	}
+
+	x5cHeader := map[gojose.HeaderKey]interface{}{
+		"x5c": []string{base64.StdEncoding.EncodeToString(verifierCertificate.Raw)},
+	}
+
+	testCases := []struct {
+		name             string
+		requestObject    string
+		opts             []Opt
+		expectedScheme   string
+		expectedVerified bool
+		expectedErr      string
+	}{
+		{
+			name: "x509_san_dns",
+			requestObject: signTestJWT(t, testRequestObjectClaims(testVerifierHost, ClientIDSchemeX509SANDNS),
+				gojose.ES256, verifierKey, x5cHeader),
+			opts:             []Opt{WithX509TrustAnchors(rootCertificate)},
+			expectedScheme:   ClientIDSchemeX509SANDNS,
+			expectedVerified: true,
+		},
+		{
+			name: "x509_san_uri",
+			requestObject: signTestJWT(t,
+				testRequestObjectClaims(testVerifierRedirectURI, ClientIDSchemeX509SANURI),
+				gojose.ES256, verifierKey, x5cHeader),
+			opts:             []Opt{WithX509TrustAnchors(rootCertificate)},
+			expectedScheme:   ClientIDSchemeX509SANURI,
+			expectedVerified: true,
+		},
+		{
+			name: "x509_san_dns without trust anchors",
+			requestObject: signTestJWT(t, testRequestObjectClaims(testVerifierHost, ClientIDSchemeX509SANDNS),
+				gojose.ES256, verifierKey, x5cHeader),
+			expectedErr: "x509_san_dns client ID scheme: no X.509 trust anchors configured",
+		},
+		{
+			name: "x509_san_dns with an untrusted certificate",
+			requestObject: signTestJWT(t, testRequestObjectClaims(testVerifierHost, ClientIDSchemeX509SANDNS),
+				gojose.ES256, verifierKey, x5cHeader),
+			opts:        []Opt{WithX509TrustAnchors(untrustedRootCertificate)},
+			expectedErr: "verify certificate chain",
+		},
+		{
+			name: "x509_san_dns with a client ID that isn't in the certificate",
+			requestObject: signTestJWT(t, testRequestObjectClaims("other.example.com", ClientIDSchemeX509SANDNS),
+				gojose.ES256, verifierKey, x5cHeader),
+			opts:        []Opt{WithX509TrustAnchors(rootCertificate)},
+			expectedErr: "client ID other.example.com is not a DNS name in the certificate",
+		},
+		{
+			name: "x509_san_dns with a redirect URI on another host",
+			requestObject: signTestJWT(t, withRedirectURI(
+				testRequestObjectClaims(testVerifierHost, ClientIDSchemeX509SANDNS), "https://attacker.example.com"),
+				gojose.ES256, verifierKey, x5cHeader),
+			opts:        []Opt{WithX509TrustAnchors(rootCertificate)},
+			expectedErr: "redirect URI https://attacker.example.com is not on the client ID's host",
+		},
+		{
+			name: "x509_san_uri with a client ID that isn't in the certificate",
+			requestObject: signTestJWT(t,
+				testRequestObjectClaims("https://other.example.com", ClientIDSchemeX509SANURI),
+				gojose.ES256, verifierKey, x5cHeader),
+			opts:        []Opt{WithX509TrustAnchors(rootCertificate)},
+			expectedErr: "client ID https://other.example.com is not a URI in the certificate",
+		},
+		{
+			name: "did",
+			requestObject: signTestJWT(t, testRequestObjectClaims(testVerifierDID, ClientIDSchemeDID),
+				gojose.EdDSA, didPrivateKey, map[gojose.HeaderKey]interface{}{"kid": testVerifierDID + "#key-1"}),
+			expectedScheme:   ClientIDSchemeDID,
+			expectedVerified: true,
+		},
+		{
+			name: "did with a key from another DID",
+			requestObject: signTestJWT(t, testRequestObjectClaims(testVerifierDID, ClientIDSchemeDID),
+				gojose.EdDSA, didPrivateKey, map[gojose.HeaderKey]interface{}{"kid": "did:example:other#key-1"}),
+			expectedErr: "key ID did:example:other#key-1 does not belong to the client ID " + testVerifierDID,
+		},
+		{
+			name: "verifier_attestation",
+			requestObject: signTestJWT(t,
+				testRequestObjectClaims(testVerifierHost, ClientIDSchemeVerifierAttestation),
+				gojose.ES256, attestationRequestKey,
+				map[gojose.HeaderKey]interface{}{"jwt": newAttestation(t, testVerifierHost)}),
+			opts:             []Opt{WithX509TrustAnchors(rootCertificate)},
+			expectedScheme:   ClientIDSchemeVerifierAttestation,
+			expectedVerified: true,
+		},
+		{
+			name: "verifier_attestation issued to another client",
+			requestObject: signTestJWT(t,
+				testRequestObjectClaims(testVerifierHost, ClientIDSchemeVerifierAttestation),
+				gojose.ES256, attestationRequestKey,
+				map[gojose.HeaderKey]interface{}{"jwt": newAttestation(t, "other.example.com")}),
+			opts:        []Opt{WithX509TrustAnchors(rootCertificate)},
+			expectedErr: "verifier attestation was issued to other.example.com, not to the client ID " + testVerifierHost,
+		},
+		{
+			name: "verifier_attestation signed with a key other than the attested one",
+			requestObject: signTestJWT(t,
+				testRequestObjectClaims(testVerifierHost, ClientIDSchemeVerifierAttestation),
+				gojose.ES256, verifierKey,
+				map[gojose.HeaderKey]interface{}{"jwt": newAttestation(t, testVerifierHost)}),
+			opts:        []Opt{WithX509TrustAnchors(rootCertificate)},
+			expectedErr: "verify signature",
+		},
+		{
+			name: "verifier_attestation without an expiration time",
+			requestObject: newAttestationRequestObject(t, newCustomAttestation(t, "verifier-attestation+jwt",
+				func(claims map[string]interface{}) { delete(claims, "exp") })),
+			opts:        []Opt{WithX509TrustAnchors(rootCertificate)},
+			expectedErr: "verifier attestation has no expiration time",
+		},
+		{
+			name: "expired verifier_attestation",
+			requestObject: newAttestationRequestObject(t, newCustomAttestation(t, "verifier-attestation+jwt",
+				func(claims map[string]interface{}) { claims["exp"] = time.Now().Add(-time.Hour).Unix() })),
+			opts:        []Opt{WithX509TrustAnchors(rootCertificate)},
+			expectedErr: "verifier attestation has expired",
+		},
+		{
+			name: "verifier_attestation without a type",
+			requestObject: newAttestationRequestObject(t, newCustomAttestation(t, "",
+				func(map[string]interface{}) {})),
+			opts:        []Opt{WithX509TrustAnchors(rootCertificate)},
+			expectedErr: `verifier attestation has type "", not verifier-attestation+jwt`,
+		},
+		{
+			name: "verifier_attestation with the wrong type",
+			requestObject: newAttestationRequestObject(t, newCustomAttestation(t, "JWT",
+				func(map[string]interface{}) {})),
+			opts:        []Opt{WithX509TrustAnchors(rootCertificate)},
+			expectedErr: `verifier attestation has type "JWT", not verifier-attestation+jwt`,
+		},
+		{
+			name: "verifier_attestation without an attestation",
+			requestObject: signTestJWT(t,
+				testRequestObjectClaims(testVerifierHost, ClientIDSchemeVerifierAttestation),
+				gojose.ES256, attestationRequestKey, nil),
+			opts:        []Opt{WithX509TrustAnchors(rootCertificate)},
+			expectedErr: "verifier attestation JWT is missing from the jwt header",
+		},
+		{
+			name: "redirect_uri",
+			requestObject: newUnsignedTestJWT(t,
+				testRequestObjectClaims(testVerifierRedirectURI, ClientIDSchemeRedirectURI)),
+			expectedScheme:   ClientIDSchemeRedirectURI,
+			expectedVerified: false,
+		},
+		{
+			name: "redirect_uri with a client ID that isn't the redirect URI",
+			requestObject: newUnsignedTestJWT(t,
+				testRequestObjectClaims("https://other.example.com", ClientIDSchemeRedirectURI)),
+			expectedErr: "client ID https://other.example.com does not match the redirect URI",
+		},
+		{
+			name: "redirect_uri with a signed request object",
+			requestObject: signTestJWT(t, testRequestObjectClaims(testVerifierRedirectURI, ClientIDSchemeRedirectURI),
+				gojose.ES256, verifierKey, x5cHeader),
+			expectedErr: "request objects using the redirect_uri client ID scheme must not be signed",
+		},
+		{
+			name:          "Unsigned request object with another client ID scheme",
+			requestObject: newUnsignedTestJWT(t, testRequestObjectClaims(testVerifierDID, ClientIDSchemeDID)),
+			expectedErr:   "unsigned request objects are only accepted for the redirect_uri client ID scheme",
+		},
+		{
+			name: "Unsupported client ID scheme",
+			requestObject: signTestJWT(t, testRequestObjectClaims(testVerifierHost, "entity_id"),
+				gojose.ES256, verifierKey, x5cHeader),
+			expectedErr: "unsupported client ID scheme: entity_id",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			instance := New(testCase.requestObject, &jwtSignatureVerifierMock{}, verifierDIDResolver, nil,
+				testutil.DocumentLoader(t), testCase.opts...)
+
+			_, err := instance.GetQuery()
+			if testCase.expectedErr != "" {
+				testutil.RequireErrorContains(t, err, "VERIFY_AUTHORIZATION_REQUEST_FAILED")
+				testutil.RequireErrorContains(t, err, testCase.expectedErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			displayData, err := instance.VerifierDisplayData()
+			require.NoError(t, err)
+			require.Equal(t, testCase.expectedScheme, displayData.ClientIDScheme)
+			require.Equal(t, testCase.expectedVerified, displayData.ClientIDVerified)
+		})
+	}
+}
+
+func testRequestObjectClaims(clientID, clientIDScheme string) map[string]interface{} {
+	return map[string]interface{}{
+		"client_id":        clientID,
+		"client_id_scheme": clientIDScheme,
+		"redirect_uri":     testVerifierRedirectURI,
+		"response_type":    "vp_token",
+		"response_mode":    "direct_post",
+		"nonce":            "nonce",
+		"state":            "state",
+	}
+}
+
+func withRedirectURI(claims map[string]interface{}, redirectURI string) map[string]interface{} {
+	claims["redirect_uri"] = redirectURI
+
+	return claims
+}
+
+func signTestJWT(t *testing.T, claims interface{}, alg gojose.SignatureAlgorithm, key crypto.PrivateKey,
+	headers map[gojose.HeaderKey]interface{},
+) string {
+	t.Helper()
+
+	signer, err := gojose.NewSigner(gojose.SigningKey{Algorithm: alg, Key: key},
+		&gojose.SignerOptions{ExtraHeaders: headers})
+	require.NoError(t, err)
+
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+
+	jws, err := signer.Sign(payload)
+	require.NoError(t, err)
+
+	compactJWS, err := jws.CompactSerialize()
+	require.NoError(t, err)
+
+	return compactJWS
+}
+
+func newUnsignedTestJWT(t *testing.T, claims interface{}) string {
+	t.Helper()
+
+	token, err := jwt.NewUnsecured(claims, nil)
+	require.NoError(t, err)
+
+	serializedToken, err := token.Serialize(false)
+	require.NoError(t, err)
+
+	return serializedToken
+}
+
+// newTestCertificate creates a certificate from the template, signed by the given parent certificate and key.
+// If no parent is given, then the certificate is self-signed.
+func newTestCertificate(t *testing.T, template, parent *x509.Certificate,
+	parentKey *ecdsa.PrivateKey,
+) (*ecdsa.PrivateKey, *x509.Certificate) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+
+	certificateDER, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+
+	certificate, err := x509.ParseCertificate(certificateDER)
+	require.NoError(t, err)
+
+	return key, certificate
+}
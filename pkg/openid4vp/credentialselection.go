@@ -0,0 +1,313 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4vp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hyperledger/aries-framework-go/component/models/presexch"
+	"github.com/hyperledger/aries-framework-go/component/models/verifiable"
+
+	"github.com/trustbloc/wallet-sdk/pkg/api"
+	"github.com/trustbloc/wallet-sdk/pkg/internal/contextbound"
+	"github.com/trustbloc/wallet-sdk/pkg/walleterror"
+)
+
+// PresentCredentialWithSelection presents credentials to redirect uri from request object, using the credential that
+// the user chose for each input descriptor instead of letting the presentation definition decide.
+// The requirements are the ones returned by credentialquery.Instance.GetSubmissionRequirements for the query from
+// GetQuery, and the selection maps input descriptor IDs to the credential to submit for that descriptor. Each selected
+// credential must be one of the descriptor's MatchedVCs. Descriptors that aren't in the selection are left out of
+// the submission. The selection must satisfy the rules (all/pick, count, min and max) of every submission
+// requirement, otherwise an INVALID_CREDENTIAL_SELECTION error is returned and nothing is sent to the verifier.
+func (o *Interaction) PresentCredentialWithSelection(requirements []*presexch.MatchedSubmissionRequirement,
+	selection map[string]*verifiable.Credential,
+) error {
+	return o.PresentCredentialWithSelectionContext(context.Background(), requirements, selection)
+}
+
+// PresentCredentialWithSelectionContext is the same as PresentCredentialWithSelection, except that the given context
+// is used for resolving the holder's DID and sending the authorized response.
+func (o *Interaction) PresentCredentialWithSelectionContext(ctx context.Context,
+	requirements []*presexch.MatchedSubmissionRequirement, selection map[string]*verifiable.Credential,
+) error {
+	timeStartPresentCredential := time.Now()
+
+	if o.requestObject == nil {
+		return walleterror.NewExecutionError(
+			module,
+			NotInitializedProperlyErrorCode,
+			NotInitializedProperlyError,
+			fmt.Errorf("call GetQuery first"))
+	}
+
+	if o.requestObject.DCQLQuery != nil {
+		return errDCQLQueryNeedsDCQLPresentation()
+	}
+
+	err := validateCredentialSelection(requirements, selection)
+	if err != nil {
+		return walleterror.NewValidationError(
+			module,
+			InvalidCredentialSelectionCode,
+			InvalidCredentialSelectionError,
+			err)
+	}
+
+	response, err := createAuthorizedResponseForSelection(requirements, selection, o.requestObject,
+		contextbound.DIDResolver(ctx, o.didResolver), o.crypto, o.holderSigner)
+	if err != nil {
+		return walleterror.NewExecutionError(
+			module,
+			CreateAuthorizedResponseFailedCode,
+			CreateAuthorizedResponseFailedError,
+			fmt.Errorf("create authorized response failed: %w", err))
+	}
+
+	return o.submitAuthorizedResponse(ctx, response, timeStartPresentCredential)
+}
+
+func validateCredentialSelection(requirements []*presexch.MatchedSubmissionRequirement,
+	selection map[string]*verifiable.Credential,
+) error {
+	if len(requirements) == 0 {
+		return errors.New("no submission requirements provided")
+	}
+
+	if len(selection) == 0 {
+		return errors.New("no credentials selected")
+	}
+
+	descriptors := map[string]*presexch.MatchedInputDescriptor{}
+
+	collectDescriptors(requirements, descriptors)
+
+	descriptorIDs := make([]string, 0, len(selection))
+
+	for descriptorID := range selection {
+		descriptorIDs = append(descriptorIDs, descriptorID)
+	}
+
+	sort.Strings(descriptorIDs)
+
+	for _, descriptorID := range descriptorIDs {
+		descriptor, ok := descriptors[descriptorID]
+		if !ok {
+			return fmt.Errorf("input descriptor %s is not part of the submission requirements", descriptorID)
+		}
+
+		credential := selection[descriptorID]
+		if credential == nil {
+			return fmt.Errorf("no credential selected for input descriptor %s", descriptorID)
+		}
+
+		if !containsCredential(descriptor.MatchedVCs, credential) {
+			return fmt.Errorf("the credential selected for input descriptor %s does not match it", descriptorID)
+		}
+	}
+
+	for _, requirement := range requirements {
+		err := checkRequirementSatisfied(requirement, selection)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func collectDescriptors(requirements []*presexch.MatchedSubmissionRequirement,
+	descriptors map[string]*presexch.MatchedInputDescriptor,
+) {
+	for _, requirement := range requirements {
+		for _, descriptor := range requirement.Descriptors {
+			descriptors[descriptor.ID] = descriptor
+		}
+
+		collectDescriptors(requirement.Nested, descriptors)
+	}
+}
+
+func containsCredential(credentials []*verifiable.Credential, credential *verifiable.Credential) bool {
+	for _, matchedVC := range credentials {
+		if matchedVC == credential || (credential.ID != "" && matchedVC.ID == credential.ID) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkRequirementSatisfied checks the selection against the requirement's rule. For a requirement with input
+// descriptors, the number of selected descriptors is counted. For a requirement with nested requirements, the number
+// of satisfied nested requirements is counted instead.
+func checkRequirementSatisfied(requirement *presexch.MatchedSubmissionRequirement,
+	selection map[string]*verifiable.Credential,
+) error {
+	var selected, total int
+
+	if len(requirement.Descriptors) > 0 {
+		total = len(requirement.Descriptors)
+
+		for _, descriptor := range requirement.Descriptors {
+			if _, ok := selection[descriptor.ID]; ok {
+				selected++
+			}
+		}
+	} else {
+		total = len(requirement.Nested)
+
+		for _, nested := range requirement.Nested {
+			if checkRequirementSatisfied(nested, selection) == nil {
+				selected++
+			}
+		}
+	}
+
+	name := requirement.Name
+	if name == "" {
+		name = "(unnamed)"
+	}
+
+	switch {
+	case requirement.Rule == presexch.All && selected != total:
+		return fmt.Errorf("submission requirement %s requires all %d of its items, but %d were selected",
+			name, total, selected)
+	case requirement.Count > 0 && selected != requirement.Count:
+		return fmt.Errorf("submission requirement %s requires exactly %d items, but %d were selected",
+			name, requirement.Count, selected)
+	case requirement.Min > 0 && selected < requirement.Min:
+		return fmt.Errorf("submission requirement %s requires at least %d items, but %d were selected",
+			name, requirement.Min, selected)
+	case requirement.Max > 0 && selected > requirement.Max:
+		return fmt.Errorf("submission requirement %s allows at most %d items, but %d were selected",
+			name, requirement.Max, selected)
+	}
+
+	return nil
+}
+
+// selectedDescriptorIDs returns the IDs of the selected input descriptors, in the order that they appear in the
+// submission requirements.
+func selectedDescriptorIDs(requirements []*presexch.MatchedSubmissionRequirement,
+	selection map[string]*verifiable.Credential, added map[string]bool,
+) []string {
+	var descriptorIDs []string
+
+	for _, requirement := range requirements {
+		for _, descriptor := range requirement.Descriptors {
+			if _, ok := selection[descriptor.ID]; ok && !added[descriptor.ID] {
+				added[descriptor.ID] = true
+
+				descriptorIDs = append(descriptorIDs, descriptor.ID)
+			}
+		}
+
+		descriptorIDs = append(descriptorIDs, selectedDescriptorIDs(requirement.Nested, selection, added)...)
+	}
+
+	return descriptorIDs
+}
+
+// createAuthorizedResponseForSelection builds the presentation submission directly from the selection. If only one
+// distinct credential was selected, then it's presented in a single presentation. Otherwise, each credential is
+// presented in its own presentation, in the same way as createAuthorizedResponseMultiCred.
+func createAuthorizedResponseForSelection(
+	requirements []*presexch.MatchedSubmissionRequirement,
+	selection map[string]*verifiable.Credential,
+	requestObject *requestObject,
+	didResolver api.DIDResolver,
+	crypto api.Crypto,
+	holderSigner api.JWTSigner,
+) (*authorizedResponse, error) {
+	var credentials []*verifiable.Credential
+
+	credentialIndexes := map[*verifiable.Credential]int{}
+
+	var descriptorMap []*presexch.InputDescriptorMapping
+
+	for _, descriptorID := range selectedDescriptorIDs(requirements, selection, map[string]bool{}) {
+		credential := selection[descriptorID]
+
+		if _, ok := credentialIndexes[credential]; !ok {
+			credentials = append(credentials, credential)
+			credentialIndexes[credential] = len(credentials) - 1
+		}
+
+		vcFormat := presexch.FormatLDPVC
+		if credential.JWT != "" {
+			vcFormat = presexch.FormatJWTVC
+		}
+
+		descriptorMap = append(descriptorMap, &presexch.InputDescriptorMapping{
+			ID:     descriptorID,
+			Format: presexch.FormatJWTVP,
+			Path:   fmt.Sprintf("$[%d]", credentialIndexes[credential]),
+			PathNested: &presexch.InputDescriptorMapping{
+				ID:     descriptorID,
+				Format: vcFormat,
+				Path:   "$.verifiableCredential[0]",
+			},
+		})
+	}
+
+	submission := &presexch.PresentationSubmission{
+		ID:            uuid.NewString(),
+		DefinitionID:  requestObject.Claims.VPToken.PresentationDefinition.ID,
+		DescriptorMap: descriptorMap,
+	}
+
+	if len(credentials) == 1 {
+		for _, descriptor := range descriptorMap {
+			descriptor.Path = "$"
+		}
+
+		presentation, err := newSubmissionPresentation(credentials[0])
+		if err != nil {
+			return nil, err
+		}
+
+		holderDID, signer, err := getPresentationSigner(credentials[0], holderSigner, didResolver, crypto)
+		if err != nil {
+			return nil, err
+		}
+
+		return createAuthorizedResponseForVP(presentation, submission, requestObject, holderDID, signer)
+	}
+
+	presentations := make([]*verifiable.Presentation, len(credentials))
+
+	for i, credential := range credentials {
+		presentation, err := newSubmissionPresentation(credential)
+		if err != nil {
+			return nil, err
+		}
+
+		presentations[i] = presentation
+	}
+
+	return createAuthorizedResponseForVPArray(presentations, submission, requestObject, didResolver, crypto,
+		holderSigner)
+}
+
+func newSubmissionPresentation(credential *verifiable.Credential) (*verifiable.Presentation, error) {
+	presentation, err := verifiable.NewPresentation(verifiable.WithCredentials(credential))
+	if err != nil {
+		return nil, err
+	}
+
+	presentation.Context = append(presentation.Context, presexch.PresentationSubmissionJSONLDContextIRI)
+	presentation.Type = append(presentation.Type, presexch.PresentationSubmissionJSONLDType)
+	presentation.ID = uuid.NewString()
+
+	return presentation, nil
+}
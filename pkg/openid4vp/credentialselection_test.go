@@ -0,0 +1,313 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4vp //nolint: testpackage
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/component/models/presexch"
+	"github.com/hyperledger/aries-framework-go/component/models/verifiable"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/wallet-sdk/internal/testutil"
+	"github.com/trustbloc/wallet-sdk/pkg/internal/mock"
+)
+
+func TestOpenID4VP_PresentCredentialWithSelection(t *testing.T) {
+	lddl := testutil.DocumentLoader(t)
+
+	var credentials []*verifiable.Credential
+
+	var rawCreds []json.RawMessage
+
+	require.NoError(t, json.Unmarshal(credentialsJSONLD, &rawCreds))
+
+	for _, credBytes := range rawCreds {
+		cred, credErr := verifiable.ParseCredential(
+			credBytes,
+			verifiable.WithDisabledProofCheck(),
+			verifiable.WithJSONLDDocumentLoader(lddl),
+		)
+		require.NoError(t, credErr)
+
+		credentials = append(credentials, cred)
+	}
+
+	mockDoc := mockResolution(t, mockDID)
+
+	newInstance := func(t *testing.T, httpClient *mock.HTTPClientMock) (*Interaction,
+		[]*presexch.MatchedSubmissionRequirement,
+	) {
+		t.Helper()
+
+		instance := New(
+			requestObjectJWT,
+			&jwtSignatureVerifierMock{},
+			&didResolverMock{ResolveValue: mockDoc},
+			&cryptoMock{SignVal: []byte(testSignature)},
+			lddl,
+			WithHTTPClient(httpClient),
+		)
+
+		query, err := instance.GetQuery()
+		require.NoError(t, err)
+
+		requirements, err := query.MatchSubmissionRequirement(credentials, lddl,
+			presexch.WithSDCredentialOptions(verifiable.WithDisabledProofCheck(),
+				verifiable.WithJSONLDDocumentLoader(lddl)))
+		require.NoError(t, err)
+		require.Len(t, requirements, 1)
+		require.Len(t, requirements[0].Descriptors, 1)
+		require.NotEmpty(t, requirements[0].Descriptors[0].MatchedVCs)
+
+		return instance, requirements
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		httpClient := &mock.HTTPClientMock{StatusCode: 200}
+
+		instance, requirements := newInstance(t, httpClient)
+
+		descriptor := requirements[0].Descriptors[0]
+		chosenVC := descriptor.MatchedVCs[len(descriptor.MatchedVCs)-1]
+
+		err := instance.PresentCredentialWithSelection(requirements,
+			map[string]*verifiable.Credential{descriptor.ID: chosenVC})
+		require.NoError(t, err)
+
+		data, err := url.ParseQuery(string(httpClient.SentBody))
+		require.NoError(t, err)
+
+		_, idTokenClaims := decodeTestJWT(t, data.Get("id_token"))
+		_, vpTokenClaims := decodeTestJWT(t, data.Get("vp_token"))
+
+		submissionBytes, err := json.Marshal(
+			idTokenClaims["_vp_token"].(map[string]interface{})["presentation_submission"])
+		require.NoError(t, err)
+
+		var submission presexch.PresentationSubmission
+
+		require.NoError(t, json.Unmarshal(submissionBytes, &submission))
+		require.Len(t, submission.DescriptorMap, 1)
+		require.Equal(t, descriptor.ID, submission.DescriptorMap[0].ID)
+		require.Equal(t, "$", submission.DescriptorMap[0].Path)
+		require.Equal(t, "$.verifiableCredential[0]", submission.DescriptorMap[0].PathNested.Path)
+
+		presentedVCs := vpTokenClaims["vp"].(map[string]interface{})["verifiableCredential"].([]interface{})
+		require.Len(t, presentedVCs, 1)
+
+		if chosenVC.JWT != "" {
+			require.Equal(t, chosenVC.JWT, presentedVCs[0])
+		} else {
+			require.Equal(t, chosenVC.ID, presentedVCs[0].(map[string]interface{})["id"])
+		}
+	})
+
+	t.Run("Invalid selection", func(t *testing.T) {
+		httpClient := &mock.HTTPClientMock{StatusCode: 200}
+
+		instance, requirements := newInstance(t, httpClient)
+
+		err := instance.PresentCredentialWithSelection(requirements,
+			map[string]*verifiable.Credential{"unknown": credentials[0]})
+		require.EqualError(t, err, "INVALID_CREDENTIAL_SELECTION(OVP0-0005):input descriptor unknown is not part "+
+			"of the submission requirements")
+		require.Nil(t, httpClient.SentBody)
+	})
+
+	t.Run("Context cancelled", func(t *testing.T) {
+		httpClient := &mock.HTTPClientMock{StatusCode: 200}
+
+		instance, requirements := newInstance(t, httpClient)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		descriptor := requirements[0].Descriptors[0]
+
+		err := instance.PresentCredentialWithSelectionContext(ctx, requirements,
+			map[string]*verifiable.Credential{descriptor.ID: descriptor.MatchedVCs[0]})
+		testutil.RequireErrorContains(t, err, "CREATE_AUTHORIZED_RESPONSE")
+		testutil.RequireErrorContains(t, err, context.Canceled.Error())
+		require.Nil(t, httpClient.SentBody)
+	})
+
+	t.Run("GetQuery not called", func(t *testing.T) {
+		instance := New(requestObjectJWT, &jwtSignatureVerifierMock{}, &didResolverMock{ResolveValue: mockDoc},
+			&cryptoMock{SignVal: []byte(testSignature)}, lddl)
+
+		err := instance.PresentCredentialWithSelection(nil, nil)
+		testutil.RequireErrorContains(t, err, "NOT_INITIALIZED_PROPERLY")
+	})
+
+	t.Run("Multiple credentials are presented in separate presentations", func(t *testing.T) {
+		requirements := []*presexch.MatchedSubmissionRequirement{
+			{
+				Rule:  presexch.All,
+				Count: 3,
+				Descriptors: []*presexch.MatchedInputDescriptor{
+					{ID: "a", MatchedVCs: credentials[:2]},
+					{ID: "b", MatchedVCs: credentials[1:3]},
+					{ID: "c", MatchedVCs: credentials[2:]},
+				},
+			},
+		}
+
+		response, err := createAuthorizedResponseForSelection(requirements,
+			map[string]*verifiable.Credential{"a": credentials[1], "b": credentials[2], "c": credentials[2]},
+			&requestObject{Claims: requestObjectClaims{VPToken: vpToken{
+				PresentationDefinition: &presexch.PresentationDefinition{ID: "pd"},
+			}}},
+			&didResolverMock{ResolveValue: mockDoc},
+			&cryptoMock{SignVal: []byte(testSignature)},
+			nil,
+		)
+		require.NoError(t, err)
+
+		var vpTokens []string
+
+		require.NoError(t, json.Unmarshal([]byte(response.VPTokenJWS), &vpTokens))
+		require.Len(t, vpTokens, 2)
+
+		_, idTokenClaims := decodeTestJWT(t, response.IDTokenJWS)
+
+		submission := idTokenClaims["_vp_token"].(map[string]interface{})["presentation_submission"]
+		descriptorMap := submission.(map[string]interface{})["descriptor_map"].([]interface{})
+		require.Len(t, descriptorMap, 3)
+
+		for i, expectedPath := range []string{"$[0]", "$[1]", "$[1]"} {
+			require.Equal(t, expectedPath, descriptorMap[i].(map[string]interface{})["path"])
+		}
+	})
+}
+
+func TestValidateCredentialSelection(t *testing.T) {
+	vcA := &verifiable.Credential{ID: "vc-a"}
+	vcB := &verifiable.Credential{ID: "vc-b"}
+	vcC := &verifiable.Credential{ID: "vc-c"}
+
+	pickOneOf := func(name string, descriptorIDs ...string) *presexch.MatchedSubmissionRequirement {
+		requirement := &presexch.MatchedSubmissionRequirement{Name: name, Rule: presexch.Pick, Count: 1}
+
+		for _, descriptorID := range descriptorIDs {
+			requirement.Descriptors = append(requirement.Descriptors, &presexch.MatchedInputDescriptor{
+				ID:         descriptorID,
+				MatchedVCs: []*verifiable.Credential{vcA, vcB},
+			})
+		}
+
+		return requirement
+	}
+
+	allOf := []*presexch.MatchedSubmissionRequirement{
+		{
+			Name:  "identity",
+			Rule:  presexch.All,
+			Count: 2,
+			Descriptors: []*presexch.MatchedInputDescriptor{
+				{ID: "passport", MatchedVCs: []*verifiable.Credential{vcA, vcB}},
+				{ID: "diploma", MatchedVCs: []*verifiable.Credential{vcC}},
+			},
+		},
+	}
+
+	nested := []*presexch.MatchedSubmissionRequirement{
+		{
+			Name: "nested",
+			Rule: presexch.Pick,
+			Min:  1,
+			Max:  1,
+			Nested: []*presexch.MatchedSubmissionRequirement{
+				pickOneOf("first", "a1", "a2"),
+				pickOneOf("second", "b1"),
+			},
+		},
+	}
+
+	testCases := []struct {
+		name         string
+		requirements []*presexch.MatchedSubmissionRequirement
+		selection    map[string]*verifiable.Credential
+		expectedErr  string
+	}{
+		{
+			name:         "All rule satisfied",
+			requirements: allOf,
+			selection:    map[string]*verifiable.Credential{"passport": vcB, "diploma": vcC},
+		},
+		{
+			name:         "All rule not satisfied",
+			requirements: allOf,
+			selection:    map[string]*verifiable.Credential{"passport": vcB},
+			expectedErr:  "submission requirement identity requires all 2 of its items, but 1 were selected",
+		},
+		{
+			name:         "Credential doesn't match the descriptor",
+			requirements: allOf,
+			selection:    map[string]*verifiable.Credential{"passport": vcC, "diploma": vcC},
+			expectedErr:  "the credential selected for input descriptor passport does not match it",
+		},
+		{
+			name:         "Credential is nil",
+			requirements: allOf,
+			selection:    map[string]*verifiable.Credential{"passport": nil},
+			expectedErr:  "no credential selected for input descriptor passport",
+		},
+		{
+			name:         "Pick count satisfied",
+			requirements: []*presexch.MatchedSubmissionRequirement{pickOneOf("pick", "a1", "a2")},
+			selection:    map[string]*verifiable.Credential{"a2": vcA},
+		},
+		{
+			name:         "Pick count exceeded",
+			requirements: []*presexch.MatchedSubmissionRequirement{pickOneOf("pick", "a1", "a2")},
+			selection:    map[string]*verifiable.Credential{"a1": vcA, "a2": vcB},
+			expectedErr:  "submission requirement pick requires exactly 1 items, but 2 were selected",
+		},
+		{
+			name:         "Nested requirement satisfied",
+			requirements: nested,
+			selection:    map[string]*verifiable.Credential{"b1": vcB},
+		},
+		{
+			name:         "Too many nested requirements satisfied",
+			requirements: nested,
+			selection:    map[string]*verifiable.Credential{"a1": vcA, "b1": vcB},
+			expectedErr:  "submission requirement nested allows at most 1 items, but 2 were selected",
+		},
+		{
+			name:         "No nested requirements satisfied",
+			requirements: nested,
+			selection:    map[string]*verifiable.Credential{"a1": vcA, "a2": vcA},
+			expectedErr:  "submission requirement nested requires at least 1 items, but 0 were selected",
+		},
+		{
+			name:        "No submission requirements",
+			selection:   map[string]*verifiable.Credential{"a1": vcA},
+			expectedErr: "no submission requirements provided",
+		},
+		{
+			name:         "Nothing selected",
+			requirements: allOf,
+			expectedErr:  "no credentials selected",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			err := validateCredentialSelection(testCase.requirements, testCase.selection)
+			if testCase.expectedErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, testCase.expectedErr)
+			}
+		})
+	}
+}
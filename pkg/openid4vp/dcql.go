@@ -0,0 +1,222 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4vp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hyperledger/aries-framework-go/component/models/verifiable"
+
+	"github.com/trustbloc/wallet-sdk/pkg/api"
+	"github.com/trustbloc/wallet-sdk/pkg/credentialquery"
+	"github.com/trustbloc/wallet-sdk/pkg/internal/contextbound"
+	"github.com/trustbloc/wallet-sdk/pkg/walleterror"
+)
+
+// The query languages that verifiers can use for asking for credentials.
+const (
+	// QueryLanguagePresentationExchange means that the verifier sent a DIF Presentation Exchange presentation
+	// definition, which is returned by GetQuery.
+	QueryLanguagePresentationExchange = "presentation_exchange"
+	// QueryLanguageDCQL means that the verifier sent a Digital Credentials Query Language (DCQL) query, which is
+	// returned by DCQLQuery.
+	QueryLanguageDCQL = "dcql"
+)
+
+// QueryLanguage returns the query language that the verifier used for asking for credentials (either
+// QueryLanguagePresentationExchange or QueryLanguageDCQL). It returns an empty string if GetQuery hasn't been
+// called yet.
+func (o *Interaction) QueryLanguage() string {
+	switch {
+	case o.requestObject == nil:
+		return ""
+	case o.requestObject.DCQLQuery != nil:
+		return QueryLanguageDCQL
+	default:
+		return QueryLanguagePresentationExchange
+	}
+}
+
+// DCQLQuery returns the verifier's DCQL query, or nil if GetQuery hasn't been called yet or the verifier didn't use
+// DCQL.
+func (o *Interaction) DCQLQuery() *credentialquery.DCQLQuery {
+	if o.requestObject == nil {
+		return nil
+	}
+
+	return o.requestObject.DCQLQuery
+}
+
+// PresentDCQLCredentials presents credentials in response to a DCQL query. The selection maps credential query IDs
+// to the credential chosen for that query, which can be one of the MatchedVCs returned by
+// credentialquery.Instance.GetDCQLMatches. The vp_token that's sent to the verifier is a JSON object with a
+// presentation for each selected credential, keyed by its credential query ID. If the selection doesn't satisfy the
+// DCQL query, then an INVALID_CREDENTIAL_SELECTION error is returned and nothing is sent to the verifier.
+func (o *Interaction) PresentDCQLCredentials(selection map[string]*verifiable.Credential) error {
+	return o.PresentDCQLCredentialsContext(context.Background(), selection)
+}
+
+// PresentDCQLCredentialsContext is the same as PresentDCQLCredentials, except that the given context is used for
+// resolving the holder's DID and sending the authorized response.
+func (o *Interaction) PresentDCQLCredentialsContext(ctx context.Context,
+	selection map[string]*verifiable.Credential,
+) error {
+	timeStartPresentCredential := time.Now()
+
+	if o.requestObject == nil {
+		return walleterror.NewExecutionError(
+			module,
+			NotInitializedProperlyErrorCode,
+			NotInitializedProperlyError,
+			fmt.Errorf("call GetQuery first"))
+	}
+
+	if o.requestObject.DCQLQuery == nil {
+		return walleterror.NewValidationError(
+			module,
+			InvalidCredentialSelectionCode,
+			InvalidCredentialSelectionError,
+			errors.New("the verifier's request doesn't have a DCQL query"))
+	}
+
+	err := o.requestObject.DCQLQuery.ValidateSelection(selection)
+	if err != nil {
+		return walleterror.NewValidationError(
+			module,
+			InvalidCredentialSelectionCode,
+			InvalidCredentialSelectionError,
+			err)
+	}
+
+	response, err := createAuthorizedResponseForDCQL(selection, o.requestObject,
+		contextbound.DIDResolver(ctx, o.didResolver), o.crypto, o.holderSigner)
+	if err != nil {
+		return walleterror.NewExecutionError(
+			module,
+			CreateAuthorizedResponseFailedCode,
+			CreateAuthorizedResponseFailedError,
+			fmt.Errorf("create authorized response failed: %w", err))
+	}
+
+	return o.submitAuthorizedResponse(ctx, response, timeStartPresentCredential)
+}
+
+// errDCQLQueryNeedsDCQLPresentation is returned when credentials are presented for a presentation definition, but
+// the verifier sent a DCQL query.
+func errDCQLQueryNeedsDCQLPresentation() error {
+	return walleterror.NewValidationError(
+		module,
+		InvalidCredentialSelectionCode,
+		InvalidCredentialSelectionError,
+		errors.New("the verifier's request has a DCQL query, use PresentDCQLCredentials instead"))
+}
+
+// checkQuery checks the verifier's query. A request object can have a presentation definition or a DCQL query, but
+// not both.
+func checkQuery(requestObject *requestObject) error {
+	if requestObject.DCQLQuery == nil {
+		return nil
+	}
+
+	if requestObject.Claims.VPToken.PresentationDefinition != nil {
+		return errors.New("request object has both a presentation definition and a DCQL query")
+	}
+
+	err := requestObject.DCQLQuery.Validate()
+	if err != nil {
+		return fmt.Errorf("invalid DCQL query: %w", err)
+	}
+
+	return nil
+}
+
+// createAuthorizedResponseForDCQL creates an authorized response where the vp_token is a JSON object that maps each
+// selected credential query ID to a presentation of the selected credential, signed by its holder. An id_token is
+// only included if the verifier asked for one.
+func createAuthorizedResponseForDCQL(
+	selection map[string]*verifiable.Credential,
+	requestObject *requestObject,
+	didResolver api.DIDResolver,
+	crypto api.Crypto,
+	holderSigner api.JWTSigner,
+) (*authorizedResponse, error) {
+	vpTokens := map[string]string{}
+
+	var (
+		idTokenSigningDID string
+		idTokenSigner     api.JWTSigner
+	)
+
+	// Credential queries are handled in the order of the query, so that the id_token is always signed by the same
+	// holder for the same selection.
+	for _, credentialQuery := range requestObject.DCQLQuery.Credentials {
+		credential, ok := selection[credentialQuery.ID]
+		if !ok {
+			continue
+		}
+
+		holderDID, signer, err := getPresentationSigner(credential, holderSigner, didResolver, crypto)
+		if err != nil {
+			return nil, err
+		}
+
+		if idTokenSigner == nil {
+			idTokenSigningDID, idTokenSigner = holderDID, signer
+		}
+
+		presentation, err := verifiable.NewPresentation(verifiable.WithCredentials(credential))
+		if err != nil {
+			return nil, err
+		}
+
+		presentation.ID = uuid.NewString()
+
+		vpTok := vpTokenClaims{
+			VP:    presentation,
+			Nonce: requestObject.Nonce,
+			Exp:   time.Now().Unix() + tokenLiveTimeSec,
+			Iss:   holderDID,
+			Aud:   requestObject.ClientID,
+			Nbf:   time.Now().Unix(),
+			Iat:   time.Now().Unix(),
+			Jti:   uuid.NewString(),
+		}
+
+		vpTokJWS, err := signToken(vpTok, signer)
+		if err != nil {
+			return nil, fmt.Errorf("sign vp_token: %w", err)
+		}
+
+		vpTokens[credentialQuery.ID] = vpTokJWS
+	}
+
+	vpTokenJSON, err := json.Marshal(vpTokens)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &authorizedResponse{
+		VPTokenJWS: string(vpTokenJSON),
+		State:      requestObject.State,
+		Signer:     idTokenSigner,
+	}
+
+	if strings.Contains(requestObject.ResponseType, "id_token") {
+		response.IDTokenJWS, err = createIDToken(requestObject, nil, idTokenSigningDID, idTokenSigner)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return response, nil
+}
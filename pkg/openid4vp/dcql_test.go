@@ -0,0 +1,245 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4vp //nolint: testpackage
+
+import (
+	"encoding/json"
+	"net/url"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go/component/models/verifiable"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/wallet-sdk/internal/testutil"
+	"github.com/trustbloc/wallet-sdk/pkg/internal/mock"
+)
+
+const testDCQLQuery = `{"credentials": [
+	{"id": "ldp_degree", "format": "ldp_vc",
+		"claims": [{"path": ["credentialSubject", "degree", "type"], "values": ["BachelorDegree"]}]},
+	{"id": "jwt_degree", "format": "jwt_vc_json",
+		"meta": {"type_values": [["UniversityDegreeCredential"]]}}
+]}`
+
+func TestOpenID4VP_DCQL(t *testing.T) {
+	lddl := testutil.DocumentLoader(t)
+
+	var credentials []*verifiable.Credential
+
+	var rawCreds []json.RawMessage
+
+	require.NoError(t, json.Unmarshal(credentialsJSONLD, &rawCreds))
+
+	for _, credBytes := range rawCreds[:2] {
+		cred, credErr := verifiable.ParseCredential(credBytes, verifiable.WithDisabledProofCheck(),
+			verifiable.WithJSONLDDocumentLoader(lddl))
+		require.NoError(t, credErr)
+
+		credentials = append(credentials, cred)
+	}
+
+	ldpCredential, jwtCredential := credentials[0], credentials[1]
+
+	mockDoc := mockResolution(t, mockDID)
+
+	newAuthorizationRequest := func(parameters url.Values) string {
+		authorizationRequest := url.Values{
+			"client_id":        {testVerifierRedirectURI},
+			"client_id_scheme": {ClientIDSchemeRedirectURI},
+			"redirect_uri":     {testVerifierRedirectURI},
+			"response_type":    {"vp_token"},
+			"nonce":            {"nonce"},
+			"state":            {"state"},
+			"dcql_query":       {testDCQLQuery},
+		}
+
+		for name, values := range parameters {
+			authorizationRequest[name] = values
+		}
+
+		return "openid4vp://?" + authorizationRequest.Encode()
+	}
+
+	newInstance := func(t *testing.T, authorizationRequest string, httpClient *mock.HTTPClientMock) *Interaction {
+		t.Helper()
+
+		instance := New(authorizationRequest, &jwtSignatureVerifierMock{}, &didResolverMock{ResolveValue: mockDoc},
+			&cryptoMock{SignVal: []byte(testSignature)}, lddl, WithHTTPClient(httpClient))
+
+		require.Empty(t, instance.QueryLanguage())
+		require.Nil(t, instance.DCQLQuery())
+
+		query, err := instance.GetQuery()
+		require.NoError(t, err)
+		require.Nil(t, query)
+
+		return instance
+	}
+
+	t.Run("Present credentials", func(t *testing.T) {
+		httpClient := &mock.HTTPClientMock{StatusCode: 200}
+
+		instance := newInstance(t, newAuthorizationRequest(nil), httpClient)
+		require.Equal(t, QueryLanguageDCQL, instance.QueryLanguage())
+		require.Len(t, instance.DCQLQuery().Credentials, 2)
+
+		err := instance.PresentDCQLCredentials(map[string]*verifiable.Credential{
+			"ldp_degree": ldpCredential,
+			"jwt_degree": jwtCredential,
+		})
+		require.NoError(t, err)
+
+		data, err := url.ParseQuery(string(httpClient.SentBody))
+		require.NoError(t, err)
+		require.NotContains(t, data, "id_token")
+		require.Equal(t, "state", data.Get("state"))
+
+		var vpTokens map[string]string
+
+		require.NoError(t, json.Unmarshal([]byte(data.Get("vp_token")), &vpTokens))
+		require.Len(t, vpTokens, 2)
+
+		_, ldpVPClaims := decodeTestJWT(t, vpTokens["ldp_degree"])
+		require.Equal(t, "nonce", ldpVPClaims["nonce"])
+
+		presentedVCs := ldpVPClaims["vp"].(map[string]interface{})["verifiableCredential"].([]interface{})
+		require.Len(t, presentedVCs, 1)
+		require.Equal(t, ldpCredential.ID, presentedVCs[0].(map[string]interface{})["id"])
+
+		_, jwtVPClaims := decodeTestJWT(t, vpTokens["jwt_degree"])
+
+		presentedVCs = jwtVPClaims["vp"].(map[string]interface{})["verifiableCredential"].([]interface{})
+		require.Equal(t, []interface{}{jwtCredential.JWT}, presentedVCs)
+	})
+
+	t.Run("Present credentials with an id_token", func(t *testing.T) {
+		httpClient := &mock.HTTPClientMock{StatusCode: 200}
+
+		instance := newInstance(t, newAuthorizationRequest(url.Values{"response_type": {"vp_token id_token"}}),
+			httpClient)
+
+		err := instance.PresentDCQLCredentials(map[string]*verifiable.Credential{
+			"ldp_degree": ldpCredential,
+			"jwt_degree": jwtCredential,
+		})
+		require.NoError(t, err)
+
+		data, err := url.ParseQuery(string(httpClient.SentBody))
+		require.NoError(t, err)
+
+		_, idTokenClaims := decodeTestJWT(t, data.Get("id_token"))
+		require.Equal(t, testVerifierRedirectURI, idTokenClaims["aud"])
+		require.NotContains(t, idTokenClaims, "_vp_token")
+	})
+
+	t.Run("Present credentials using direct_post.jwt", func(t *testing.T) {
+		httpClient := &mock.HTTPClientMock{StatusCode: 200}
+
+		instance := newInstance(t,
+			newAuthorizationRequest(url.Values{"response_mode": {responseModeDirectPostJWT}}), httpClient)
+
+		err := instance.PresentDCQLCredentials(map[string]*verifiable.Credential{
+			"ldp_degree": ldpCredential,
+			"jwt_degree": jwtCredential,
+		})
+		require.NoError(t, err)
+
+		data, err := url.ParseQuery(string(httpClient.SentBody))
+		require.NoError(t, err)
+
+		_, responseClaims := decodeTestJWT(t, data.Get("response"))
+		require.NotContains(t, responseClaims, "id_token")
+
+		vpTokens, ok := responseClaims["vp_token"].(map[string]interface{})
+		require.True(t, ok)
+		require.Contains(t, vpTokens, "ldp_degree")
+		require.Contains(t, vpTokens, "jwt_degree")
+	})
+
+	t.Run("Invalid selection", func(t *testing.T) {
+		httpClient := &mock.HTTPClientMock{StatusCode: 200}
+
+		instance := newInstance(t, newAuthorizationRequest(nil), httpClient)
+
+		err := instance.PresentDCQLCredentials(map[string]*verifiable.Credential{"ldp_degree": ldpCredential})
+		require.EqualError(t, err, "INVALID_CREDENTIAL_SELECTION(OVP0-0005):no credential for credential query "+
+			"jwt_degree")
+		require.Nil(t, httpClient.SentBody)
+	})
+
+	t.Run("Request doesn't have a DCQL query", func(t *testing.T) {
+		httpClient := &mock.HTTPClientMock{StatusCode: 200}
+
+		instance := New(requestObjectJWT, &jwtSignatureVerifierMock{}, nil, nil, lddl, WithHTTPClient(httpClient))
+
+		_, err := instance.GetQuery()
+		require.NoError(t, err)
+		require.Equal(t, QueryLanguagePresentationExchange, instance.QueryLanguage())
+		require.Nil(t, instance.DCQLQuery())
+
+		err = instance.PresentDCQLCredentials(map[string]*verifiable.Credential{"ldp_degree": ldpCredential})
+		require.EqualError(t, err, "INVALID_CREDENTIAL_SELECTION(OVP0-0005):the verifier's request doesn't have a "+
+			"DCQL query")
+		require.Nil(t, httpClient.SentBody)
+	})
+
+	t.Run("Presentation definition methods called for a DCQL query", func(t *testing.T) {
+		httpClient := &mock.HTTPClientMock{StatusCode: 200}
+
+		instance := newInstance(t, newAuthorizationRequest(nil), httpClient)
+
+		expectedErr := "INVALID_CREDENTIAL_SELECTION(OVP0-0005):the verifier's request has a DCQL query, " +
+			"use PresentDCQLCredentials instead"
+
+		err := instance.PresentCredential([]*verifiable.Credential{ldpCredential})
+		require.EqualError(t, err, expectedErr)
+
+		err = instance.PresentCredentialWithSelection(nil, map[string]*verifiable.Credential{"ldp_degree": ldpCredential})
+		require.EqualError(t, err, expectedErr)
+		require.Nil(t, httpClient.SentBody)
+	})
+
+	t.Run("GetQuery not called", func(t *testing.T) {
+		instance := New(newAuthorizationRequest(nil), &jwtSignatureVerifierMock{}, nil, nil, lddl)
+
+		err := instance.PresentDCQLCredentials(map[string]*verifiable.Credential{"ldp_degree": ldpCredential})
+		testutil.RequireErrorContains(t, err, "NOT_INITIALIZED_PROPERLY")
+	})
+
+	t.Run("Invalid query", func(t *testing.T) {
+		testCases := []struct {
+			name        string
+			parameters  url.Values
+			expectedErr string
+		}{
+			{
+				name: "Both a presentation definition and a DCQL query",
+				parameters: url.Values{
+					"presentation_definition": {`{"id":"test-definition","input_descriptors":[]}`},
+				},
+				expectedErr: "request object has both a presentation definition and a DCQL query",
+			},
+			{
+				name:        "Invalid DCQL query",
+				parameters:  url.Values{"dcql_query": {`{"credentials": []}`}},
+				expectedErr: "invalid DCQL query: DCQL query has no credential queries",
+			},
+		}
+
+		for _, testCase := range testCases {
+			t.Run(testCase.name, func(t *testing.T) {
+				instance := New(newAuthorizationRequest(testCase.parameters), &jwtSignatureVerifierMock{}, nil,
+					nil, lddl)
+
+				query, err := instance.GetQuery()
+				testutil.RequireErrorContains(t, err, "VERIFY_AUTHORIZATION_REQUEST_FAILED")
+				testutil.RequireErrorContains(t, err, testCase.expectedErr)
+				require.Nil(t, query)
+			})
+		}
+	})
+}
@@ -12,4 +12,11 @@ type VerifierDisplayData struct {
 	Name    string
 	Purpose string
 	LogoURI string
+	// ClientIDScheme is the verifier's client_id_scheme (e.g. x509_san_dns or did), or an empty string if the
+	// request object didn't specify one.
+	ClientIDScheme string
+	// ClientIDVerified is true if the request object's signature proves that it came from the verifier identified
+	// by DID (the client_id), according to the client ID scheme. It's false for the redirect_uri scheme, since
+	// those requests aren't signed.
+	ClientIDVerified bool
 }
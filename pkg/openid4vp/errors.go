@@ -15,6 +15,7 @@ const (
 	CreateAuthorizedResponseFailedError   = "CREATE_AUTHORIZED_RESPONSE"
 	SendAuthorizedResponseFailedError     = "SEND_AUTHORIZED_RESPONSE"
 	NotInitializedProperlyError           = "NOT_INITIALIZED_PROPERLY"
+	InvalidCredentialSelectionError       = "INVALID_CREDENTIAL_SELECTION"
 )
 
 // Constants' names and reasons are obvious so they do not require additional comments.
@@ -25,4 +26,5 @@ const (
 	CreateAuthorizedResponseFailedCode
 	SendAuthorizedResponseFailedCode
 	NotInitializedProperlyErrorCode
+	InvalidCredentialSelectionCode
 )
@@ -0,0 +1,242 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4vp
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	gojose "github.com/go-jose/go-jose/v3"
+
+	"github.com/trustbloc/wallet-sdk/pkg/api"
+	"github.com/trustbloc/wallet-sdk/pkg/internal/httprequest"
+)
+
+const (
+	responseModeDirectPostJWT = "direct_post.jwt"
+
+	// defaultEncryptedResponseEnc is the content encryption algorithm to use if the verifier specifies a key
+	// management algorithm but no content encryption algorithm, as specified by JARM.
+	defaultEncryptedResponseEnc = string(gojose.A128CBC_HS256)
+
+	fetchVerifierJWKSEventText = "Fetch verifier's JWKS via an HTTP GET request to %s"
+)
+
+// The key management algorithms and content encryption algorithms that the wallet supports for encrypted
+// authorization responses.
+var (
+	supportedEncryptedResponseAlgs = []string{ //nolint:gochecknoglobals // read-only
+		string(gojose.ECDH_ES), string(gojose.ECDH_ES_A128KW), string(gojose.ECDH_ES_A192KW),
+		string(gojose.ECDH_ES_A256KW), string(gojose.RSA_OAEP), string(gojose.RSA_OAEP_256),
+	}
+	supportedEncryptedResponseEncs = []string{ //nolint:gochecknoglobals // read-only
+		string(gojose.A128GCM), string(gojose.A192GCM), string(gojose.A256GCM),
+		string(gojose.A128CBC_HS256), string(gojose.A192CBC_HS384), string(gojose.A256CBC_HS512),
+	}
+)
+
+// createJARMResponse creates the response JWT for the direct_post.jwt response mode, as described in JARM
+// (JWT Secured Authorization Response Mode for OAuth 2.0). The response is signed if the verifier's metadata has an
+// authorization_signed_response_alg, and encrypted to one of the verifier's keys if it has an
+// authorization_encrypted_response_alg. If it has both, then the response is signed first and then encrypted.
+// If it has neither, then the response is signed, since JARM doesn't allow a response that's neither signed nor
+// encrypted.
+func (o *Interaction) createJARMResponse(ctx context.Context, response *authorizedResponse) (string, error) {
+	metadata := o.requestObject.clientMetadata()
+
+	claims := &jarmResponseClaims{
+		Iss:     selfIssuedIssuer,
+		Aud:     o.requestObject.ClientID,
+		Exp:     time.Now().Unix() + tokenLiveTimeSec,
+		IDToken: response.IDTokenJWS,
+		VPToken: vpTokenClaim(response.VPTokenJWS),
+		State:   response.State,
+	}
+
+	sign := metadata.SignedResponseAlg != "" || metadata.EncryptedResponseAlg == ""
+
+	if !sign {
+		claimsBytes, err := json.Marshal(claims)
+		if err != nil {
+			return "", fmt.Errorf("marshal response claims: %w", err)
+		}
+
+		return o.encryptJARMResponse(ctx, claimsBytes, "")
+	}
+
+	signedResponse, err := signJARMResponse(claims, response.Signer, metadata.SignedResponseAlg)
+	if err != nil {
+		return "", err
+	}
+
+	if metadata.EncryptedResponseAlg == "" {
+		return signedResponse, nil
+	}
+
+	return o.encryptJARMResponse(ctx, []byte(signedResponse), "JWT")
+}
+
+func signJARMResponse(claims *jarmResponseClaims, signer api.JWTSigner, requiredAlg string) (string, error) {
+	if requiredAlg != "" {
+		signerAlg, _ := signer.Headers().Algorithm()
+
+		if signerAlg != requiredAlg {
+			return "", fmt.Errorf("the verifier requires the response to be signed using %s, but the holder's "+
+				"key uses %s", requiredAlg, signerAlg)
+		}
+	}
+
+	signedResponse, err := signToken(claims, signer)
+	if err != nil {
+		return "", fmt.Errorf("sign response: %w", err)
+	}
+
+	return signedResponse, nil
+}
+
+// encryptJARMResponse encrypts the given payload to the verifier's key. If the payload is a signed JWT, then
+// contentType should be "JWT", so that the verifier knows that it's a nested JWT.
+func (o *Interaction) encryptJARMResponse(ctx context.Context, payload []byte, contentType string) (string, error) {
+	metadata := o.requestObject.clientMetadata()
+
+	alg := metadata.EncryptedResponseAlg
+	if !contains(supportedEncryptedResponseAlgs, alg) {
+		return "", fmt.Errorf("unsupported response encryption algorithm (alg): %s", alg)
+	}
+
+	enc := metadata.EncryptedResponseEnc
+	if enc == "" {
+		enc = defaultEncryptedResponseEnc
+	}
+
+	if !contains(supportedEncryptedResponseEncs, enc) {
+		return "", fmt.Errorf("unsupported response content encryption algorithm (enc): %s", enc)
+	}
+
+	keySet, err := o.verifierKeySet(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	key, err := selectEncryptionKey(keySet, alg)
+	if err != nil {
+		return "", err
+	}
+
+	encrypterOpts := &gojose.EncrypterOptions{}
+	if contentType != "" {
+		encrypterOpts = encrypterOpts.WithContentType(gojose.ContentType(contentType))
+	}
+
+	encrypter, err := gojose.NewEncrypter(gojose.ContentEncryption(enc),
+		gojose.Recipient{Algorithm: gojose.KeyAlgorithm(alg), Key: key.Public().Key, KeyID: key.KeyID},
+		encrypterOpts)
+	if err != nil {
+		return "", fmt.Errorf("create response encrypter: %w", err)
+	}
+
+	encryptedResponse, err := encrypter.Encrypt(payload)
+	if err != nil {
+		return "", fmt.Errorf("encrypt response: %w", err)
+	}
+
+	return encryptedResponse.CompactSerialize()
+}
+
+// verifierKeySet returns the verifier's keys, either from the jwks in its metadata or else by fetching them from its
+// jwks_uri.
+func (o *Interaction) verifierKeySet(ctx context.Context) (*gojose.JSONWebKeySet, error) {
+	metadata := o.requestObject.clientMetadata()
+
+	if metadata.JWKS != nil {
+		return metadata.JWKS, nil
+	}
+
+	if metadata.JWKSURI == "" {
+		return nil, errors.New("the verifier requires an encrypted response but its metadata has neither " +
+			"jwks nor jwks_uri")
+	}
+
+	responseBytes, err := httprequest.New(o.httpClient, o.metricsLogger).Do(ctx, http.MethodGet, metadata.JWKSURI,
+		"", nil, fmt.Sprintf(fetchVerifierJWKSEventText, metadata.JWKSURI), presentCredentialEventText)
+	if err != nil {
+		return nil, fmt.Errorf("fetch verifier's JWKS: %w", err)
+	}
+
+	keySet := &gojose.JSONWebKeySet{}
+
+	err = json.Unmarshal(responseBytes, keySet)
+	if err != nil {
+		return nil, fmt.Errorf("parse verifier's JWKS: %w", err)
+	}
+
+	return keySet, nil
+}
+
+// selectEncryptionKey returns the first key in the set that can be used for encrypting with the given algorithm.
+func selectEncryptionKey(keySet *gojose.JSONWebKeySet, alg string) (*gojose.JSONWebKey, error) {
+	for i := range keySet.Keys {
+		key := &keySet.Keys[i]
+
+		if key.Use != "" && key.Use != "enc" {
+			continue
+		}
+
+		if key.Algorithm != "" && key.Algorithm != alg {
+			continue
+		}
+
+		switch key.Public().Key.(type) {
+		case *ecdsa.PublicKey:
+			if strings.HasPrefix(alg, "ECDH-ES") {
+				return key, nil
+			}
+		case *rsa.PublicKey:
+			if strings.HasPrefix(alg, "RSA-OAEP") {
+				return key, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("the verifier has no key that can be used for encrypting with %s", alg)
+}
+
+// vpTokenClaim returns the vp_token to put in the response JWT. If several presentations are being sent, then the
+// vp_token is a JSON array, and for DCQL queries it's a JSON object. These are kept as they are rather than being
+// put in as a string.
+func vpTokenClaim(vpToken string) interface{} {
+	var vpTokens []string
+
+	if json.Unmarshal([]byte(vpToken), &vpTokens) == nil {
+		return vpTokens
+	}
+
+	var keyedVPTokens map[string]string
+
+	if json.Unmarshal([]byte(vpToken), &keyedVPTokens) == nil {
+		return keyedVPTokens
+	}
+
+	return vpToken
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,232 @@
+/*
+Copyright Gen Digital Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package openid4vp //nolint: testpackage
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	gojose "github.com/go-jose/go-jose/v3"
+	"github.com/hyperledger/aries-framework-go/component/models/verifiable"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/wallet-sdk/internal/testutil"
+)
+
+type mockJARMVerifierHandler struct {
+	t            *testing.T
+	jwks         *gojose.JSONWebKeySet
+	responseForm url.Values
+}
+
+func (m *mockJARMVerifierHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	switch request.URL.Path {
+	case "/jwks":
+		require.NoError(m.t, json.NewEncoder(writer).Encode(m.jwks))
+	case "/response":
+		body, err := io.ReadAll(request.Body)
+		require.NoError(m.t, err)
+
+		m.responseForm, err = url.ParseQuery(string(body))
+		require.NoError(m.t, err)
+	default:
+		http.NotFound(writer, request)
+	}
+}
+
+func TestOpenID4VP_PresentCredential_DirectPostJWT(t *testing.T) {
+	lddl := testutil.DocumentLoader(t)
+
+	var rawCreds []json.RawMessage
+
+	require.NoError(t, json.Unmarshal(credentialsJSONLD, &rawCreds))
+
+	credential, err := verifiable.ParseCredential(rawCreds[0], verifiable.WithDisabledProofCheck(),
+		verifiable.WithJSONLDDocumentLoader(lddl))
+	require.NoError(t, err)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	verifierKeys := &gojose.JSONWebKeySet{Keys: []gojose.JSONWebKey{
+		{Key: &ecKey.PublicKey, KeyID: "signing-key", Use: "sig"},
+		{Key: &ecKey.PublicKey, KeyID: "ec-key", Use: "enc"},
+		{Key: &rsaKey.PublicKey, KeyID: "rsa-key"},
+	}}
+
+	mockDoc := mockResolution(t, mockDID)
+
+	// presentCredential presents the credential to a mock verifier with the given JARM metadata, and returns the
+	// response parameter that the verifier received.
+	presentCredential := func(t *testing.T, metadata jarmMetadata) (string, error) {
+		t.Helper()
+
+		handler := &mockJARMVerifierHandler{t: t, jwks: verifierKeys}
+
+		server := httptest.NewServer(handler)
+		defer server.Close()
+
+		if metadata.JWKSURI != "" {
+			metadata.JWKSURI = server.URL + metadata.JWKSURI
+		}
+
+		instance := New(requestObjectJWT, &jwtSignatureVerifierMock{}, &didResolverMock{ResolveValue: mockDoc},
+			&cryptoMock{SignVal: []byte(testSignature)}, lddl)
+
+		_, err := instance.GetQuery()
+		require.NoError(t, err)
+
+		instance.requestObject.ResponseMode = responseModeDirectPostJWT
+		instance.requestObject.RedirectURI = server.URL + "/response"
+		instance.requestObject.ClientMetadata = &requestObjectRegistration{jarmMetadata: metadata}
+
+		err = instance.PresentCredential([]*verifiable.Credential{credential})
+		if err != nil {
+			return "", err
+		}
+
+		require.NotContains(t, handler.responseForm, "vp_token")
+		require.NotContains(t, handler.responseForm, "id_token")
+
+		return handler.responseForm.Get("response"), nil
+	}
+
+	t.Run("Encrypted response", func(t *testing.T) {
+		response, err := presentCredential(t, jarmMetadata{
+			JWKS:                 verifierKeys,
+			EncryptedResponseAlg: "ECDH-ES",
+			EncryptedResponseEnc: "A256GCM",
+		})
+		require.NoError(t, err)
+
+		encryptedResponse, err := gojose.ParseEncrypted(response)
+		require.NoError(t, err)
+		require.Equal(t, "ec-key", encryptedResponse.Header.KeyID)
+
+		decryptedResponse, err := encryptedResponse.Decrypt(ecKey)
+		require.NoError(t, err)
+
+		var claims map[string]interface{}
+
+		require.NoError(t, json.Unmarshal(decryptedResponse, &claims))
+		requireJARMResponseClaims(t, claims)
+	})
+
+	t.Run("Signed response", func(t *testing.T) {
+		response, err := presentCredential(t, jarmMetadata{SignedResponseAlg: "EdDSA"})
+		require.NoError(t, err)
+
+		headers, claims := decodeTestJWT(t, response)
+		require.Equal(t, "EdDSA", headers["alg"])
+		requireJARMResponseClaims(t, claims)
+	})
+
+	t.Run("Signed by default", func(t *testing.T) {
+		response, err := presentCredential(t, jarmMetadata{})
+		require.NoError(t, err)
+
+		_, claims := decodeTestJWT(t, response)
+		requireJARMResponseClaims(t, claims)
+	})
+
+	t.Run("Signed and encrypted response, using the verifier's jwks_uri", func(t *testing.T) {
+		response, err := presentCredential(t, jarmMetadata{
+			JWKSURI:              "/jwks",
+			SignedResponseAlg:    "EdDSA",
+			EncryptedResponseAlg: "RSA-OAEP-256",
+		})
+		require.NoError(t, err)
+
+		encryptedResponse, err := gojose.ParseEncrypted(response)
+		require.NoError(t, err)
+		require.Equal(t, "rsa-key", encryptedResponse.Header.KeyID)
+		require.Equal(t, "JWT", encryptedResponse.Header.ExtraHeaders[gojose.HeaderContentType])
+		require.Equal(t, "A128CBC-HS256", encryptedResponse.Header.ExtraHeaders["enc"])
+
+		decryptedResponse, err := encryptedResponse.Decrypt(rsaKey)
+		require.NoError(t, err)
+
+		_, claims := decodeTestJWT(t, string(decryptedResponse))
+		requireJARMResponseClaims(t, claims)
+	})
+
+	t.Run("Failures", func(t *testing.T) {
+		testCases := []struct {
+			name        string
+			metadata    jarmMetadata
+			expectedErr string
+		}{
+			{
+				name:        "Holder's key doesn't use the required signing algorithm",
+				metadata:    jarmMetadata{SignedResponseAlg: "ES256"},
+				expectedErr: "the verifier requires the response to be signed using ES256, but the holder's key uses EdDSA",
+			},
+			{
+				name:        "Unsupported key management algorithm",
+				metadata:    jarmMetadata{JWKS: verifierKeys, EncryptedResponseAlg: "dir"},
+				expectedErr: "unsupported response encryption algorithm (alg): dir",
+			},
+			{
+				name: "Unsupported content encryption algorithm",
+				metadata: jarmMetadata{
+					JWKS:                 verifierKeys,
+					EncryptedResponseAlg: "ECDH-ES",
+					EncryptedResponseEnc: "XYZ",
+				},
+				expectedErr: "unsupported response content encryption algorithm (enc): XYZ",
+			},
+			{
+				name:        "No keys",
+				metadata:    jarmMetadata{EncryptedResponseAlg: "ECDH-ES"},
+				expectedErr: "the verifier requires an encrypted response but its metadata has neither jwks nor jwks_uri",
+			},
+			{
+				name: "No suitable key",
+				metadata: jarmMetadata{
+					JWKS:                 &gojose.JSONWebKeySet{Keys: verifierKeys.Keys[:1]},
+					EncryptedResponseAlg: "ECDH-ES",
+				},
+				expectedErr: "the verifier has no key that can be used for encrypting with ECDH-ES",
+			},
+			{
+				name:        "Fail to fetch the verifier's keys",
+				metadata:    jarmMetadata{JWKSURI: "/unknown", EncryptedResponseAlg: "ECDH-ES"},
+				expectedErr: "fetch verifier's JWKS",
+			},
+		}
+
+		for _, testCase := range testCases {
+			t.Run(testCase.name, func(t *testing.T) {
+				response, err := presentCredential(t, testCase.metadata)
+				testutil.RequireErrorContains(t, err, "CREATE_AUTHORIZED_RESPONSE")
+				testutil.RequireErrorContains(t, err, testCase.expectedErr)
+				require.Empty(t, response)
+			})
+		}
+	})
+}
+
+func requireJARMResponseClaims(t *testing.T, claims map[string]interface{}) {
+	t.Helper()
+
+	require.Equal(t, verifierDID, claims["aud"])
+	require.Equal(t, "636df28459a07d50cc4b657e", claims["state"])
+	require.NotEmpty(t, claims["id_token"])
+	require.NotEmpty(t, claims["vp_token"])
+	require.NotEmpty(t, claims["exp"])
+}
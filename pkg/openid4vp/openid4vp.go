@@ -9,13 +9,14 @@ package openid4vp
 
 import (
 	"bytes"
+	"context"
 	"crypto/rand"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"math/big"
 	"net/http"
 	"net/url"
-	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -29,14 +30,15 @@ import (
 
 	"github.com/trustbloc/wallet-sdk/pkg/api"
 	"github.com/trustbloc/wallet-sdk/pkg/common"
+	"github.com/trustbloc/wallet-sdk/pkg/internal/contextbound"
 	"github.com/trustbloc/wallet-sdk/pkg/internal/httprequest"
 	"github.com/trustbloc/wallet-sdk/pkg/models"
 	"github.com/trustbloc/wallet-sdk/pkg/walleterror"
 )
 
 const (
-	requestURIPrefix = "openid-vc://?request_uri="
 	tokenLiveTimeSec = 600
+	selfIssuedIssuer = "https://self-issued.me/v2/openid-vc"
 
 	activityLogOperation = "oidc-presentation"
 
@@ -65,14 +67,25 @@ type Interaction struct {
 	didResolver          api.DIDResolver
 	crypto               api.Crypto
 	documentLoader       ld.DocumentLoader
+	holderSigner         api.JWTSigner
+	trustAnchors         *x509.CertPool
 
-	requestObject *requestObject
+	requestObject    *requestObject
+	clientIDVerified bool
+
+	// authorizationRequestClientID and walletNonce are set while fetching the request object, and are checked
+	// against the request object once it's been verified.
+	authorizationRequestClientID string
+	walletNonce                  string
 }
 
 type authorizedResponse struct {
 	IDTokenJWS string
 	VPTokenJWS string
 	State      string
+	// Signer is the signer that was used for the ID token. It's also used for signing the response itself when
+	// using the direct_post.jwt response mode.
+	Signer api.JWTSigner
 }
 
 // New creates new openid4vp instance.
@@ -85,25 +98,34 @@ func New(
 	documentLoader ld.DocumentLoader,
 	opts ...Opt,
 ) *Interaction {
-	client, activityLogger, metricsLogger := processOpts(opts)
+	processedOpts := processOpts(opts)
 
 	return &Interaction{
 		authorizationRequest: authorizationRequest,
 		signatureVerifier:    signatureVerifier,
-		httpClient:           client,
-		activityLogger:       activityLogger,
-		metricsLogger:        metricsLogger,
+		httpClient:           processedOpts.httpClient,
+		activityLogger:       processedOpts.activityLogger,
+		metricsLogger:        processedOpts.metricsLogger,
 		didResolver:          didResolver,
 		crypto:               crypto,
 		documentLoader:       documentLoader,
+		holderSigner:         processedOpts.holderSigner,
+		trustAnchors:         processedOpts.trustAnchors,
 	}
 }
 
 // GetQuery creates query based on authorization request data.
+// If the verifier sent a DCQL query instead of a presentation definition, then the returned presentation definition
+// is nil, and the query is available from the DCQLQuery method (see QueryLanguage).
 func (o *Interaction) GetQuery() (*presexch.PresentationDefinition, error) {
+	return o.GetQueryContext(context.Background())
+}
+
+// GetQueryContext is the same as GetQuery, except that the given context is used for fetching the request object.
+func (o *Interaction) GetQueryContext(ctx context.Context) (*presexch.PresentationDefinition, error) {
 	timeStartGetQuery := time.Now()
 
-	rawRequestObject, err := o.fetchRequestObject()
+	rawRequestObject, err := o.fetchRequestObject(ctx)
 	if err != nil {
 		return nil, walleterror.NewExecutionError(
 			module,
@@ -112,7 +134,31 @@ func (o *Interaction) GetQuery() (*presexch.PresentationDefinition, error) {
 			fmt.Errorf("fetch request object: %w", err))
 	}
 
-	requestObject, err := verifyAuthorizationRequestAndDecodeClaims(rawRequestObject, o.signatureVerifier)
+	verifier := &requestObjectVerifier{signatureVerifier: o.signatureVerifier, trustAnchors: o.trustAnchors}
+
+	if o.didResolver != nil {
+		verifier.didResolver = contextbound.DIDResolver(ctx, o.didResolver)
+	}
+
+	requestObject, err := verifyAuthorizationRequestAndDecodeClaims(rawRequestObject, verifier)
+	if err != nil {
+		return nil, walleterror.NewExecutionError(
+			module,
+			VerifyAuthorizationRequestFailedCode,
+			VerifyAuthorizationRequestFailedError,
+			fmt.Errorf("verify authorization request: %w", err))
+	}
+
+	err = o.checkRequestObjectBinding(requestObject)
+	if err != nil {
+		return nil, walleterror.NewExecutionError(
+			module,
+			VerifyAuthorizationRequestFailedCode,
+			VerifyAuthorizationRequestFailedError,
+			fmt.Errorf("verify authorization request: %w", err))
+	}
+
+	err = checkQuery(requestObject)
 	if err != nil {
 		return nil, walleterror.NewExecutionError(
 			module,
@@ -122,6 +168,7 @@ func (o *Interaction) GetQuery() (*presexch.PresentationDefinition, error) {
 	}
 
 	o.requestObject = requestObject
+	o.clientIDVerified = verifier.clientIDVerified
 
 	return requestObject.Claims.VPToken.PresentationDefinition,
 		o.metricsLogger.Log(&api.MetricsEvent{
@@ -140,16 +187,26 @@ func (o *Interaction) VerifierDisplayData() (*VerifierDisplayData, error) {
 			fmt.Errorf("call GetQuery first"))
 	}
 
+	metadata := o.requestObject.clientMetadata()
+
 	return &VerifierDisplayData{
-		DID:     o.requestObject.ClientID,
-		Name:    o.requestObject.Registration.ClientName,
-		Purpose: o.requestObject.Registration.ClientPurpose,
-		LogoURI: o.requestObject.Registration.ClientLogoURI,
+		DID:              o.requestObject.ClientID,
+		Name:             metadata.ClientName,
+		Purpose:          metadata.ClientPurpose,
+		LogoURI:          metadata.ClientLogoURI,
+		ClientIDScheme:   o.requestObject.ClientIDScheme,
+		ClientIDVerified: o.clientIDVerified,
 	}, nil
 }
 
 // PresentCredential presents credentials to redirect uri from request object.
 func (o *Interaction) PresentCredential(credentials []*verifiable.Credential) error {
+	return o.PresentCredentialContext(context.Background(), credentials)
+}
+
+// PresentCredentialContext is the same as PresentCredential, except that the given context is used for resolving
+// the holder's DID and sending the authorized response.
+func (o *Interaction) PresentCredentialContext(ctx context.Context, credentials []*verifiable.Credential) error {
 	timeStartPresentCredential := time.Now()
 
 	if o.requestObject == nil {
@@ -160,7 +217,12 @@ func (o *Interaction) PresentCredential(credentials []*verifiable.Credential) er
 			fmt.Errorf("call GetQuery first"))
 	}
 
-	response, err := createAuthorizedResponse(credentials, o.requestObject, o.didResolver, o.crypto, o.documentLoader)
+	if o.requestObject.DCQLQuery != nil {
+		return errDCQLQueryNeedsDCQLPresentation()
+	}
+
+	response, err := createAuthorizedResponse(credentials, o.requestObject, contextbound.DIDResolver(ctx, o.didResolver),
+		o.crypto, o.documentLoader, o.holderSigner)
 	if err != nil {
 		return walleterror.NewExecutionError(
 			module,
@@ -169,12 +231,36 @@ func (o *Interaction) PresentCredential(credentials []*verifiable.Credential) er
 			fmt.Errorf("create authorized response failed: %w", err))
 	}
 
+	return o.submitAuthorizedResponse(ctx, response, timeStartPresentCredential)
+}
+
+// submitAuthorizedResponse sends the authorized response to the verifier and logs the presentation.
+func (o *Interaction) submitAuthorizedResponse(ctx context.Context, response *authorizedResponse,
+	timeStartPresentCredential time.Time,
+) error {
 	data := url.Values{}
-	data.Set("id_token", response.IDTokenJWS)
-	data.Set("vp_token", response.VPTokenJWS)
-	data.Set("state", response.State)
 
-	err = o.sendAuthorizedResponse(data.Encode())
+	if o.requestObject.ResponseMode == responseModeDirectPostJWT {
+		responseJWT, err := o.createJARMResponse(ctx, response)
+		if err != nil {
+			return walleterror.NewExecutionError(
+				module,
+				CreateAuthorizedResponseFailedCode,
+				CreateAuthorizedResponseFailedError,
+				fmt.Errorf("create JARM response failed: %w", err))
+		}
+
+		data.Set("response", responseJWT)
+	} else {
+		if response.IDTokenJWS != "" {
+			data.Set("id_token", response.IDTokenJWS)
+		}
+
+		data.Set("vp_token", response.VPTokenJWS)
+		data.Set("state", response.State)
+	}
+
+	err := o.sendAuthorizedResponse(ctx, data.Encode())
 	if err != nil {
 		return err
 	}
@@ -192,31 +278,15 @@ func (o *Interaction) PresentCredential(credentials []*verifiable.Credential) er
 		Type: api.LogTypeCredentialActivity,
 		Time: time.Now(),
 		Data: api.Data{
-			Client:    o.requestObject.Registration.ClientName,
+			Client:    o.requestObject.clientMetadata().ClientName,
 			Operation: activityLogOperation,
 			Status:    api.ActivityLogStatusSuccess,
 		},
 	})
 }
 
-func (o *Interaction) fetchRequestObject() (string, error) {
-	if !strings.HasPrefix(o.authorizationRequest, requestURIPrefix) {
-		return o.authorizationRequest, nil
-	}
-
-	endpointURL := strings.TrimPrefix(o.authorizationRequest, requestURIPrefix)
-
-	respBytes, err := httprequest.New(o.httpClient, o.metricsLogger).Do(http.MethodGet, endpointURL, "", nil,
-		fmt.Sprintf(fetchRequestObjectEventText, endpointURL), getQueryEventText)
-	if err != nil {
-		return "", err
-	}
-
-	return string(respBytes), nil
-}
-
-func (o *Interaction) sendAuthorizedResponse(responseBody string) error {
-	_, err := httprequest.New(o.httpClient, o.metricsLogger).Do(http.MethodPost,
+func (o *Interaction) sendAuthorizedResponse(ctx context.Context, responseBody string) error {
+	_, err := httprequest.New(o.httpClient, o.metricsLogger).Do(ctx, http.MethodPost,
 		o.requestObject.RedirectURI, "application/x-www-form-urlencoded",
 		bytes.NewBuffer([]byte(responseBody)),
 		fmt.Sprintf(sendAuthorizedResponseEventText, o.requestObject.RedirectURI),
@@ -234,7 +304,7 @@ func (o *Interaction) sendAuthorizedResponse(responseBody string) error {
 
 func verifyAuthorizationRequestAndDecodeClaims(
 	rawRequestObject string,
-	signatureVerifier jwtSignatureVerifier,
+	signatureVerifier jose.SignatureVerifier,
 ) (*requestObject, error) {
 	requestObject := &requestObject{}
 
@@ -266,14 +336,17 @@ func createAuthorizedResponse(
 	didResolver api.DIDResolver,
 	crypto api.Crypto,
 	documentLoader ld.DocumentLoader,
+	holderSigner api.JWTSigner,
 ) (*authorizedResponse, error) {
 	switch len(credentials) {
 	case 0:
 		return nil, fmt.Errorf("expected at least one credential to present to verifier")
 	case 1:
-		return createAuthorizedResponseOneCred(credentials[0], requestObject, didResolver, crypto, documentLoader)
+		return createAuthorizedResponseOneCred(credentials[0], requestObject, didResolver, crypto, documentLoader,
+			holderSigner)
 	default:
-		return createAuthorizedResponseMultiCred(credentials, requestObject, didResolver, crypto, documentLoader)
+		return createAuthorizedResponseMultiCred(credentials, requestObject, didResolver, crypto, documentLoader,
+			holderSigner)
 	}
 }
 
@@ -283,12 +356,12 @@ func createAuthorizedResponseOneCred( //nolint:funlen
 	didResolver api.DIDResolver,
 	crypto api.Crypto,
 	documentLoader ld.DocumentLoader,
+	holderSigner api.JWTSigner,
 ) (*authorizedResponse, error) {
 	var (
-		err        error
-		vpTokenJWS string
-		did        string
-		signer     api.JWTSigner
+		err    error
+		did    string
+		signer api.JWTSigner
 	)
 
 	var presentation *verifiable.Presentation
@@ -313,19 +386,35 @@ func createAuthorizedResponseOneCred( //nolint:funlen
 
 	did, err = verifiable.SubjectID(credential.Subject)
 	if err != nil || did == "" {
-		return nil, fmt.Errorf("presentation VC does not have a subject ID")
-	}
+		if holderSigner == nil {
+			return nil, fmt.Errorf("presentation VC does not have a subject ID")
+		}
 
-	signer, err = getHolderSigner(did, didResolver, crypto)
-	if err != nil {
-		return nil, err
+		did, signer = holderSigner.GetKeyID(), holderSigner
+	} else {
+		signer, err = getHolderSigner(did, didResolver, crypto)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	presentationSubmission := presentation.CustomFields["presentation_submission"]
 
 	presentation.CustomFields["presentation_submission"] = nil
 
-	idTokenJWS, err := createIDToken(requestObject, presentationSubmission, did, signer)
+	return createAuthorizedResponseForVP(presentation, presentationSubmission, requestObject, did, signer)
+}
+
+// createAuthorizedResponseForVP creates an authorized response where the vp_token is a single presentation, signed
+// by the holder.
+func createAuthorizedResponseForVP(
+	presentation *verifiable.Presentation,
+	submission interface{},
+	requestObject *requestObject,
+	holderDID string,
+	signer api.JWTSigner,
+) (*authorizedResponse, error) {
+	idTokenJWS, err := createIDToken(requestObject, submission, holderDID, signer)
 	if err != nil {
 		return nil, err
 	}
@@ -334,19 +423,24 @@ func createAuthorizedResponseOneCred( //nolint:funlen
 		VP:    presentation,
 		Nonce: requestObject.Nonce,
 		Exp:   time.Now().Unix() + tokenLiveTimeSec,
-		Iss:   did,
+		Iss:   holderDID,
 		Aud:   requestObject.ClientID,
 		Nbf:   time.Now().Unix(),
 		Iat:   time.Now().Unix(),
 		Jti:   uuid.NewString(),
 	}
 
-	vpTokenJWS, err = signToken(vpTok, signer)
+	vpTokenJWS, err := signToken(vpTok, signer)
 	if err != nil {
 		return nil, fmt.Errorf("sign vp_token: %w", err)
 	}
 
-	return &authorizedResponse{IDTokenJWS: idTokenJWS, VPTokenJWS: vpTokenJWS, State: requestObject.State}, nil
+	return &authorizedResponse{
+		IDTokenJWS: idTokenJWS,
+		VPTokenJWS: vpTokenJWS,
+		State:      requestObject.State,
+		Signer:     signer,
+	}, nil
 }
 
 func createAuthorizedResponseMultiCred( //nolint:funlen
@@ -355,6 +449,7 @@ func createAuthorizedResponseMultiCred( //nolint:funlen
 	didResolver api.DIDResolver,
 	crypto api.Crypto,
 	documentLoader ld.DocumentLoader,
+	holderSigner api.JWTSigner,
 ) (*authorizedResponse, error) {
 	pd := requestObject.Claims.VPToken.PresentationDefinition
 
@@ -373,17 +468,27 @@ func createAuthorizedResponseMultiCred( //nolint:funlen
 		return nil, err
 	}
 
+	return createAuthorizedResponseForVPArray(presentations, submission, requestObject, didResolver, crypto,
+		holderSigner)
+}
+
+// createAuthorizedResponseForVPArray creates an authorized response where the vp_token is a list of presentations,
+// each one signed by the holder of the credential in it.
+func createAuthorizedResponseForVPArray(
+	presentations []*verifiable.Presentation,
+	submission *presexch.PresentationSubmission,
+	requestObject *requestObject,
+	didResolver api.DIDResolver,
+	crypto api.Crypto,
+	holderSigner api.JWTSigner,
+) (*authorizedResponse, error) {
 	var vpTokens []string
 
 	signers := map[string]api.JWTSigner{}
 
 	for _, presentation := range presentations {
-		holderDID, e := getSubjectID(presentation.Credentials()[0])
-		if e != nil {
-			return nil, e
-		}
-
-		signer, e := getHolderSigner(holderDID, didResolver, crypto)
+		holderDID, signer, e := getPresentationSigner(presentation.Credentials()[0], holderSigner, didResolver,
+			crypto)
 		if e != nil {
 			return nil, e
 		}
@@ -428,6 +533,7 @@ func createAuthorizedResponseMultiCred( //nolint:funlen
 		IDTokenJWS: idTokenJWS,
 		VPTokenJWS: string(vpTokenListJSON),
 		State:      requestObject.State,
+		Signer:     signers[idTokenSigningDID],
 	}, nil
 }
 
@@ -438,12 +544,9 @@ func createIDToken(
 	signer api.JWTSigner,
 ) (string, error) {
 	idToken := &idTokenClaims{
-		VPToken: idTokenVPToken{
-			PresentationSubmission: submission,
-		},
 		Nonce: req.Nonce,
 		Exp:   time.Now().Unix() + tokenLiveTimeSec,
-		Iss:   "https://self-issued.me/v2/openid-vc",
+		Iss:   selfIssuedIssuer,
 		Sub:   signingDID,
 		Aud:   req.ClientID,
 		Nbf:   time.Now().Unix(),
@@ -451,6 +554,14 @@ func createIDToken(
 		Jti:   uuid.NewString(),
 	}
 
+	// DCQL responses have no presentation submission.
+	if submission != nil {
+		idToken.VPToken = &idTokenVPToken{PresentationSubmission: submission}
+	}
+
+	// A holder without a DID is identified by its key, as in a self-issued ID token.
+	idToken.SubJWK = signer.Headers()[jose.HeaderJSONWebKey]
+
 	idTokenJWS, err := signToken(idToken, signer)
 	if err != nil {
 		return "", fmt.Errorf("sign id_token: %w", err)
@@ -490,6 +601,28 @@ func getHolderSigner(holderDID string, didResolver api.DIDResolver, crypto api.C
 	return common.NewJWSSigner(models.VerificationMethodFromDoc(&signingVM), crypto)
 }
 
+// getPresentationSigner returns the holder's identifier and the signer to use for presenting the given credential.
+// If the credential has no subject ID, then the holder signer is used, if there is one.
+func getPresentationSigner(vc interface{}, holderSigner api.JWTSigner, didResolver api.DIDResolver,
+	crypto api.Crypto,
+) (string, api.JWTSigner, error) {
+	holderDID, err := getSubjectID(vc)
+	if err != nil {
+		if holderSigner == nil {
+			return "", nil, err
+		}
+
+		return holderSigner.GetKeyID(), holderSigner, nil
+	}
+
+	signer, err := getHolderSigner(holderDID, didResolver, crypto)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return holderDID, signer, nil
+}
+
 func getSubjectID(vc interface{}) (string, error) {
 	var (
 		err    error
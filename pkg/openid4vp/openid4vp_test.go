@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package openid4vp //nolint: testpackage
 
 import (
+	"context"
 	"crypto/ed25519"
 	"crypto/rand"
 	_ "embed" //nolint:gci // required for go:embed
@@ -14,6 +15,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
 	"net/url"
 	"strings"
 	"testing"
@@ -27,7 +29,9 @@ import (
 
 	"github.com/trustbloc/wallet-sdk/internal/testutil"
 	"github.com/trustbloc/wallet-sdk/pkg/api"
+	"github.com/trustbloc/wallet-sdk/pkg/common"
 	"github.com/trustbloc/wallet-sdk/pkg/internal/mock"
+	"github.com/trustbloc/wallet-sdk/pkg/models"
 )
 
 var (
@@ -137,6 +141,19 @@ func TestOpenID4VP_GetQuery(t *testing.T) {
 			"failed to log event (Event=Fetch request object via an HTTP GET request to https://request-object)")
 		require.Nil(t, query)
 	})
+
+	t.Run("Context cancelled", func(t *testing.T) {
+		instance := New("openid-vc://?request_uri=https://request-object", &jwtSignatureVerifierMock{}, nil, nil,
+			nil, WithHTTPClient(&http.Client{}))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		query, err := instance.GetQueryContext(ctx)
+		testutil.RequireErrorContains(t, err, "REQUEST_OBJECT_FETCH_FAILED")
+		testutil.RequireErrorContains(t, err, context.Canceled.Error())
+		require.Nil(t, query)
+	})
 }
 
 func TestOpenID4VP_PresentCredential(t *testing.T) {
@@ -284,6 +301,32 @@ func TestOpenID4VP_PresentCredential(t *testing.T) {
 		require.NoError(t, err)
 	})
 
+	t.Run("Context cancelled", func(t *testing.T) {
+		httpClient := &mock.HTTPClientMock{
+			StatusCode: 200,
+		}
+
+		instance := New(
+			requestObjectJWT,
+			&jwtSignatureVerifierMock{},
+			&didResolverMock{ResolveValue: mockDoc},
+			&cryptoMock{SignVal: []byte(testSignature)},
+			lddl,
+			WithHTTPClient(httpClient),
+		)
+
+		_, err := instance.GetQuery()
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err = instance.PresentCredentialContext(ctx, credentials)
+		testutil.RequireErrorContains(t, err, "CREATE_AUTHORIZED_RESPONSE")
+		testutil.RequireErrorContains(t, err, context.Canceled.Error())
+		require.Nil(t, httpClient.SentBody)
+	})
+
 	t.Run("GetQuery not called", func(t *testing.T) {
 		httpClient := &mock.HTTPClientMock{
 			StatusCode: 200,
@@ -314,6 +357,7 @@ func TestOpenID4VP_PresentCredential(t *testing.T) {
 			&didResolverMock{ResolveValue: mockDoc},
 			&cryptoMock{SignVal: []byte(testSignature)},
 			lddl,
+			nil,
 		)
 
 		require.NoError(t, err)
@@ -386,6 +430,7 @@ func TestOpenID4VP_PresentCredential(t *testing.T) {
 					&didResolverMock{ResolveValue: mockDoc},
 					&cryptoMock{},
 					lddl,
+					nil,
 				)
 
 				require.Error(t, err)
@@ -394,15 +439,53 @@ func TestOpenID4VP_PresentCredential(t *testing.T) {
 		}
 	})
 
+	t.Run("no subject ID found, holder signer provided", func(t *testing.T) {
+		publicKey, _, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+
+		holderSigner, err := common.NewJWKBoundSigner(&models.VerificationMethod{
+			Type: common.Ed25519VerificationKey2018,
+			Key:  models.VerificationKey{Raw: publicKey},
+		}, &cryptoMock{SignVal: []byte(testSignature)})
+		require.NoError(t, err)
+
+		keyBoundCredential := &verifiable.Credential{
+			ID:      "foo",
+			Context: []string{verifiable.ContextURI},
+			Types:   []string{verifiable.VCType},
+		}
+
+		for _, vcs := range [][]*verifiable.Credential{
+			{keyBoundCredential},
+			{keyBoundCredential, keyBoundCredential},
+		} {
+			response, err := createAuthorizedResponse(
+				vcs,
+				mockRequestObject,
+				&didResolverMock{ResolveErr: errors.New("shouldn't be called")},
+				&cryptoMock{},
+				lddl,
+				holderSigner,
+			)
+			require.NoError(t, err)
+
+			idTokenHeaders, idTokenClaims := decodeTestJWT(t, response.IDTokenJWS)
+			require.Equal(t, holderSigner.GetKeyID(), idTokenClaims["sub"])
+			require.Contains(t, idTokenClaims, "sub_jwk")
+			require.Contains(t, idTokenHeaders, "jwk")
+			require.NotContains(t, idTokenHeaders, "kid")
+		}
+	})
+
 	t.Run("fail to resolve signing DID", func(t *testing.T) {
 		expectErr := errors.New("resolve failed")
 
 		_, err := createAuthorizedResponse(singleCred, mockRequestObject,
-			&didResolverMock{ResolveErr: expectErr}, &cryptoMock{}, lddl)
+			&didResolverMock{ResolveErr: expectErr}, &cryptoMock{}, lddl, nil)
 		require.ErrorIs(t, err, expectErr)
 
 		_, err = createAuthorizedResponse(credentials, mockRequestObject,
-			&didResolverMock{ResolveErr: expectErr}, &cryptoMock{}, lddl)
+			&didResolverMock{ResolveErr: expectErr}, &cryptoMock{}, lddl, nil)
 
 		require.ErrorIs(t, err, expectErr)
 	})
@@ -410,7 +493,7 @@ func TestOpenID4VP_PresentCredential(t *testing.T) {
 	t.Run("signing DID has no signing key", func(t *testing.T) {
 		_, err := createAuthorizedResponse(singleCred, mockRequestObject, &didResolverMock{ResolveValue: &did.DocResolution{
 			DIDDocument: &did.Doc{},
-		}}, &cryptoMock{}, lddl)
+		}}, &cryptoMock{}, lddl, nil)
 
 		require.Error(t, err)
 		require.Contains(t, err.Error(), "no assertion method for signing")
@@ -425,12 +508,32 @@ func TestOpenID4VP_PresentCredential(t *testing.T) {
 			&didResolverMock{ResolveValue: mockDoc},
 			&cryptoMock{SignErr: expectErr},
 			lddl,
+			nil,
 		)
 
 		require.ErrorIs(t, err, expectErr)
 	})
 }
 
+func decodeTestJWT(t *testing.T, jws string) (map[string]interface{}, map[string]interface{}) {
+	t.Helper()
+
+	parts := strings.Split(jws, ".")
+	require.Len(t, parts, 3)
+
+	var headers, claims map[string]interface{}
+
+	headersBytes, err := base64.RawURLEncoding.DecodeString(parts[0])
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(headersBytes, &headers))
+
+	claimsBytes, err := base64.RawURLEncoding.DecodeString(parts[1])
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(claimsBytes, &claims))
+
+	return headers, claims
+}
+
 func TestResolverAdapter(t *testing.T) {
 	mockDoc := mockResolution(t, mockDID)
 	adapter := wrapResolver(&didResolverMock{ResolveValue: mockDoc})
@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package openid4vp
 
 import (
+	"crypto/x509"
 	"net/http"
 
 	noopactivitylogger "github.com/trustbloc/wallet-sdk/pkg/activitylogger/noop"
@@ -18,6 +19,8 @@ type opts struct {
 	httpClient     httpClient
 	activityLogger api.ActivityLogger
 	metricsLogger  api.MetricsLogger
+	holderSigner   api.JWTSigner
+	trustAnchors   *x509.CertPool
 }
 
 // An Opt is a single option for an OpenID4VP instance.
@@ -49,7 +52,32 @@ func WithMetricsLogger(metricsLogger api.MetricsLogger) Opt {
 	}
 }
 
-func processOpts(options []Opt) (httpClient, api.ActivityLogger, api.MetricsLogger) {
+// WithHolderSigner is an option for an OpenID4VP instance that allows a caller to specify a signer to use for
+// presenting credentials that have no subject ID, such as credentials that are bound to a key (via a jwk or x5c
+// proof) rather than to a DID. The signer's key ID is used to identify the holder in the tokens sent to the verifier.
+// Credentials that do have a subject ID are still signed using the holder DID's assertion method.
+func WithHolderSigner(signer api.JWTSigner) Opt {
+	return func(opts *opts) {
+		opts.holderSigner = signer
+	}
+}
+
+// WithX509TrustAnchors is an option for an OpenID4VP instance that allows a caller to specify the root certificates
+// to trust when verifying request objects that use the x509_san_dns, x509_san_uri or verifier_attestation client ID
+// schemes. If not specified, then request objects using those schemes are rejected.
+func WithX509TrustAnchors(certificates ...*x509.Certificate) Opt {
+	return func(opts *opts) {
+		if opts.trustAnchors == nil {
+			opts.trustAnchors = x509.NewCertPool()
+		}
+
+		for _, certificate := range certificates {
+			opts.trustAnchors.AddCert(certificate)
+		}
+	}
+}
+
+func processOpts(options []Opt) *opts {
 	opts := mergeOpts(options)
 
 	if opts.httpClient == nil {
@@ -64,7 +92,7 @@ func processOpts(options []Opt) (httpClient, api.ActivityLogger, api.MetricsLogg
 		opts.metricsLogger = noopmetricslogger.NewMetricsLogger()
 	}
 
-	return opts.httpClient, opts.activityLogger, opts.metricsLogger
+	return opts
 }
 
 func mergeOpts(options []Opt) *opts {
@@ -6,21 +6,41 @@ SPDX-License-Identifier: Apache-2.0
 
 package openid4vp
 
-import "github.com/hyperledger/aries-framework-go/component/models/presexch"
+import (
+	gojose "github.com/go-jose/go-jose/v3"
+	"github.com/hyperledger/aries-framework-go/component/models/presexch"
+
+	"github.com/trustbloc/wallet-sdk/pkg/credentialquery"
+)
 
 type requestObject struct {
-	JTI          string                    `json:"jti"`
-	IAT          int64                     `json:"iat"`
-	ResponseType string                    `json:"response_type"` //nolint: tagliatelle
-	ResponseMode string                    `json:"response_mode"` //nolint: tagliatelle
-	Scope        string                    `json:"scope"`
-	Nonce        string                    `json:"nonce"`
-	ClientID     string                    `json:"client_id"`    //nolint: tagliatelle
-	RedirectURI  string                    `json:"redirect_uri"` //nolint: tagliatelle
-	State        string                    `json:"state"`
-	Exp          int64                     `json:"exp"`
-	Registration requestObjectRegistration `json:"registration"`
-	Claims       requestObjectClaims       `json:"claims"`
+	JTI            string                    `json:"jti"`
+	IAT            int64                     `json:"iat"`
+	ResponseType   string                    `json:"response_type"` //nolint: tagliatelle
+	ResponseMode   string                    `json:"response_mode"` //nolint: tagliatelle
+	Scope          string                    `json:"scope"`
+	Nonce          string                    `json:"nonce"`
+	ClientID       string                    `json:"client_id"`        //nolint: tagliatelle
+	ClientIDScheme string                    `json:"client_id_scheme"` //nolint: tagliatelle
+	RedirectURI    string                    `json:"redirect_uri"`     //nolint: tagliatelle
+	State          string                    `json:"state"`
+	Exp            int64                     `json:"exp"`
+	WalletNonce    string                    `json:"wallet_nonce"` //nolint: tagliatelle
+	Registration   requestObjectRegistration `json:"registration"`
+	Claims         requestObjectClaims       `json:"claims"`
+
+	ClientMetadata *requestObjectRegistration `json:"client_metadata,omitempty"` //nolint: tagliatelle
+	DCQLQuery      *credentialquery.DCQLQuery `json:"dcql_query,omitempty"`      //nolint: tagliatelle
+}
+
+// clientMetadata returns the verifier's metadata. Newer verifiers send it as client_metadata, while older ones use
+// registration.
+func (r *requestObject) clientMetadata() *requestObjectRegistration {
+	if r.ClientMetadata != nil {
+		return r.ClientMetadata
+	}
+
+	return &r.Registration
 }
 
 type requestObjectRegistration struct {
@@ -29,6 +49,17 @@ type requestObjectRegistration struct {
 	VPFormats                   *presexch.Format `json:"vp_formats"`                     //nolint: tagliatelle
 	ClientPurpose               string           `json:"client_purpose"`                 //nolint: tagliatelle
 	ClientLogoURI               string           `json:"logo_uri"`                       //nolint: tagliatelle
+
+	jarmMetadata
+}
+
+// jarmMetadata is the part of the verifier's metadata that's used for the direct_post.jwt response mode (JARM).
+type jarmMetadata struct {
+	JWKS                 *gojose.JSONWebKeySet `json:"jwks"`
+	JWKSURI              string                `json:"jwks_uri"`                             //nolint: tagliatelle
+	SignedResponseAlg    string                `json:"authorization_signed_response_alg"`    //nolint: tagliatelle
+	EncryptedResponseAlg string                `json:"authorization_encrypted_response_alg"` //nolint: tagliatelle
+	EncryptedResponseEnc string                `json:"authorization_encrypted_response_enc"` //nolint: tagliatelle
 }
 
 type requestObjectClaims struct {
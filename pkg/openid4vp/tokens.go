@@ -15,15 +15,16 @@ type idTokenVPToken struct {
 }
 
 type idTokenClaims struct {
-	VPToken idTokenVPToken `json:"_vp_token"` //nolint: tagliatelle
-	Nonce   string         `json:"nonce"`
-	Exp     int64          `json:"exp"`
-	Iss     string         `json:"iss"`
-	Sub     string         `json:"sub"`
-	Aud     string         `json:"aud"`
-	Nbf     int64          `json:"nbf"`
-	Iat     int64          `json:"iat"`
-	Jti     string         `json:"jti"`
+	VPToken *idTokenVPToken `json:"_vp_token,omitempty"` //nolint: tagliatelle
+	Nonce   string          `json:"nonce"`
+	Exp     int64           `json:"exp"`
+	Iss     string          `json:"iss"`
+	Sub     string          `json:"sub"`
+	SubJWK  interface{}     `json:"sub_jwk,omitempty"` //nolint: tagliatelle
+	Aud     string          `json:"aud"`
+	Nbf     int64           `json:"nbf"`
+	Iat     int64           `json:"iat"`
+	Jti     string          `json:"jti"`
 }
 
 type vpTokenClaims struct {
@@ -36,3 +37,13 @@ type vpTokenClaims struct {
 	Iat   int64                    `json:"iat"`
 	Jti   string                   `json:"jti"`
 }
+
+// jarmResponseClaims are the claims of the response JWT that's sent when using the direct_post.jwt response mode.
+type jarmResponseClaims struct {
+	Iss     string      `json:"iss"`
+	Aud     string      `json:"aud"`
+	Exp     int64       `json:"exp"`
+	IDToken string      `json:"id_token,omitempty"` //nolint: tagliatelle
+	VPToken interface{} `json:"vp_token"`           //nolint: tagliatelle
+	State   string      `json:"state,omitempty"`
+}